@@ -0,0 +1,52 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "time"
+)
+
+// requestHandoff asks the active instance bound to addr to release its
+// listener, authenticating with adminKey. The active instance keeps
+// draining in-flight requests after answering, so the port frees up almost
+// immediately.
+func requestHandoff(addr, adminKey string) error {
+    host, port, err := net.SplitHostPort(addr)
+    if err != nil { return err }
+    if host == "" || host == "0.0.0.0" || host == "::" { host = "127.0.0.1" }
+    client := &http.Client{Timeout: 10 * time.Second}
+    req, err := http.NewRequest(http.MethodPost, "http://"+net.JoinHostPort(host, port)+"/admin/handoff", nil)
+    if err != nil { return err }
+    req.Header.Set("Authorization", "Bearer "+adminKey)
+    resp, err := client.Do(req)
+    if err != nil { return fmt.Errorf("contact active instance: %w", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted {
+        return fmt.Errorf("active instance refused handoff: %s", resp.Status)
+    }
+    return nil
+}
+
+// listenWithRetry keeps trying to bind addr until the previous owner has
+// closed its listener or the deadline passes.
+func listenWithRetry(addr string, wait time.Duration) (net.Listener, error) {
+    deadline := time.Now().Add(wait)
+    for {
+        ln, err := net.Listen("tcp", addr)
+        if err == nil { return ln, nil }
+        if time.Now().After(deadline) { return nil, err }
+        time.Sleep(50 * time.Millisecond)
+    }
+}
+
+// takeOver performs the standby side of a handoff and returns the listener.
+func takeOver(addr, adminKey string) (net.Listener, error) {
+    log.Printf("standby: requesting handoff from active instance at %s", addr)
+    if err := requestHandoff(addr, adminKey); err != nil { return nil, err }
+    ln, err := listenWithRetry(addr, 15*time.Second)
+    if err != nil { return nil, fmt.Errorf("bind after handoff: %w", err) }
+    log.Printf("standby: took over %s", ln.Addr().String())
+    return ln, nil
+}
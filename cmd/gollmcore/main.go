@@ -2,27 +2,84 @@ package main
 
 import (
     "context"
+    "encoding/json"
     "flag"
+    "fmt"
     "log"
     "net/http"
     "net"
     "os"
     "os/signal"
     "path/filepath"
+    "strings"
     "syscall"
     "time"
 
+    "golang.org/x/net/http2"
+    "golang.org/x/net/http2/h2c"
+
+    "gollmcore/internal/audit"
     "gollmcore/internal/config"
+    "gollmcore/internal/models"
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/resources"
+    "gollmcore/internal/scratch"
     "gollmcore/internal/server"
+    "gollmcore/internal/services/audioclass"
     "gollmcore/internal/services/embeddings"
-    ttsvc "gollmcore/internal/services/tts"
+    "gollmcore/internal/services/speaker"
     "gollmcore/internal/services/stt"
+    "gollmcore/internal/sessions"
+    "gollmcore/internal/tracing"
+    "gollmcore/internal/usage"
 )
 
+// printUsage prints the server flags and the subcommands to stderr.
+func printUsage() {
+    fmt.Fprintf(flag.CommandLine.Output(), `Usage:
+  gollmcore [flags]            run the server
+  gollmcore <command> [flags]  run a command; "gollmcore <command> -h" lists its flags
+
+Commands:
+  transcribe, speak, embed, chat  one-shot local inference
+  mcp                             serve the services to an MCP client over stdio
+  models                          list, verify or update downloaded models
+  install-service, uninstall-service
+
+Flags:
+`)
+    flag.PrintDefaults()
+}
+
 func main() {
-    var cfgPath string
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "install-service", "uninstall-service":
+            if err := serviceCommand(os.Args[1], os.Args[2:]); err != nil { log.Fatalf("%s: %v", os.Args[1], err) }
+            return
+        case "models":
+            if err := modelsCommand(os.Args[2:]); err != nil { log.Fatalf("models: %v", err) }
+            return
+        case "transcribe", "speak", "embed", "chat", "mcp":
+            if err := toolCommand(os.Args[1], os.Args[2:]); err != nil { log.Fatalf("%s: %v", os.Args[1], err) }
+            return
+        }
+    }
+
+    var cfgPath, dataDirFlag string
+    var standby, printConfig bool
     flag.StringVar(&cfgPath, "config", "config.json", "Path to config file")
+    flag.StringVar(&dataDirFlag, "data-dir", "", "Data directory (overrides server.data_dir)")
+    flag.BoolVar(&standby, "standby", false, "Start as warm standby and take over the listener of the running instance")
+    flag.BoolVar(&printConfig, "print-config", false, "Print the effective configuration as JSON (secrets masked) and exit")
+    flag.Usage = printUsage
     flag.Parse()
+    if flag.NArg() > 0 {
+        fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+        printUsage()
+        os.Exit(2)
+    }
 
     c, err := config.Load(cfgPath)
     if err != nil {
@@ -30,67 +87,318 @@ func main() {
     }
 
     dataDir := c.Server.DataDir
+    if dataDirFlag != "" { dataDir = dataDirFlag }
     if dataDir == "" { dataDir = defaultDataDir() }
-    if err := os.MkdirAll(dataDir, 0o755); err != nil {
+    if printConfig {
+        b, err := effectiveConfig(c, dataDir)
+        if err != nil { log.Fatalf("print config: %v", err) }
+        os.Stdout.Write(append(b, '\n'))
+        return
+    }
+    if standby {
+        // The active instance owns the data dir; a standby only reads it.
+        if _, err := os.Stat(dataDir); err != nil {
+            log.Fatalf("standby requires an existing data dir: %v", err)
+        }
+        if c.Server.Port == 0 {
+            log.Fatalf("standby requires a fixed port")
+        }
+        if len(c.Server.AdminKeys) == 0 {
+            log.Fatalf("standby requires server.admin_keys to authenticate the handoff")
+        }
+    } else if err := os.MkdirAll(dataDir, 0o755); err != nil {
         log.Fatalf("failed creating data dir: %v", err)
     }
 
+    lock, err := models.Open(dataDir)
+    if err != nil { log.Fatalf("%v", err) }
+    models.DisableDownloads(c.ReadOnly.Downloads)
+    if err := setONNXDefaults(c); err != nil { log.Fatalf("%v", err) }
+    events := server.NewEvents()
+    models.OnProgress(func(p models.Progress) {
+        events.Publish("download.progress", p)
+        if p.Done { events.Publish("download.finished", p) }
+    })
+    onnxrt.OnSession(func(name string, opened bool) {
+        typ := "model.loaded"
+        if !opened { typ = "model.unloaded" }
+        events.Publish(typ, map[string]string{"name": name, "runtime": "onnx"})
+    })
+    if err := scratch.SetDir(scratchDir(c, dataDir)); err != nil { log.Fatalf("failed creating scratch dir: %v", err) }
+    // A standby shares the active instance's scratch dir; only the active
+    // instance sweeps it.
+    if !standby {
+        n, err := scratch.Sweep(time.Duration(c.Server.ScratchMaxAgeMins) * time.Minute)
+        if err != nil { log.Printf("scratch sweep: %v", err) }
+        if n > 0 { log.Printf("Removed %d stale temporary files from %s", n, scratch.Dir()) }
+    }
+
     ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
     defer cancel()
+    ctx = runAsService(ctx, dataDir)
 
     // Initialize services as requested
     var sttSvc *stt.STTService
     var embSvc embeddings.Service
-    var ttsSvc *ttsvc.Service
+    var ttsSvc server.TTSService
+    var llmSvc server.LLMService
+    var llmUps []server.LLMUpstream
 
     if c.Services.STT.Enabled {
-        sttSvc = stt.New(filepath.Join(dataDir, "bin"), filepath.Join(dataDir, "models", "whisper"))
         // Lazy downloads happen on first request.
-        log.Printf("STT service enabled with model: %s", c.Services.STT.Model)
+        var err error
+        if sttSvc, err = newSTT(c, dataDir); err != nil { log.Fatalf("failed to init STT: %v", err) }
+        if err := stt.CheckPrompt(sttPrompt(c)); err != nil { log.Fatalf("services.stt: %v", err) }
+        if c.Services.STT.Postprocess && !c.Services.LLM.Enabled { log.Fatalf("services.stt.postprocess needs services.llm to be enabled") }
+        log.Printf("STT service enabled with model: %s%s", c.Services.STT.Model, backendNote(c.Services.STT.Backend))
+    }
+
+    var classifier audioclass.Service
+    if c.Services.AudioClassify.Enabled {
+        classifier, err = newAudioClassifier(dataDir)
+        if err != nil { log.Fatalf("failed to init audio classifier (Silero VAD ONNX): %v", err) }
+        log.Printf("Audio classification enabled")
+    }
+
+    var speakers *speaker.Service
+    if c.Services.Speakers.Enabled {
+        speakers, err = newSpeakers(c, dataDir)
+        if err != nil { log.Fatalf("failed to init speakers (WeSpeaker ONNX): %v", err) }
+        log.Printf("Speaker identification enabled (%d enrolled)", len(speakers.List()))
     }
 
     if c.Services.Embeddings.Enabled {
-        // Use real MiniLM ONNX-backed embeddings
-        modelDir := filepath.Join(dataDir, "models", "embeddings", "all-MiniLM-L6-v2")
-        svc, err := embeddings.NewMiniLM(modelDir)
-        if err != nil {
-            log.Fatalf("failed to init embeddings (MiniLM ONNX): %v", err)
-        }
-        embSvc = svc
-        log.Printf("Embeddings service enabled with model: %s", "all-MiniLM-L6-v2")
+        embSvc, err = newEmbeddings(c, dataDir)
+        if err != nil { log.Fatalf("failed to init embeddings (MiniLM ONNX): %v", err) }
+        variant := ""
+        if c.Services.Embeddings.Quantized { variant = " (int8)" }
+        log.Printf("Embeddings service enabled with model: %s%s%s", c.Services.Embeddings.Model, variant, backendNote(c.Services.Embeddings.Backend))
     }
 
     if c.Services.TTS.Enabled {
-        ttsSvc = ttsvc.New(filepath.Join(dataDir, "bin"), filepath.Join(dataDir, "models", "tts"), filepath.Join(dataDir, "tts"))
-        log.Printf("TTS service enabled with voice: %s", c.Services.TTS.Voice)
+        ttsSvc, err = newTTS(c, dataDir)
+        if err != nil { log.Fatalf("%v", err) }
+        log.Printf("TTS service enabled with engine %s, voice: %s", ttsEngine(c), c.Services.TTS.Voice)
+    }
+
+    if c.Services.LLM.Enabled {
+        if err := llmDefaults(c).Check(); err != nil { log.Fatalf("services.llm.defaults: %v", err) }
+        if c.Services.LLM.SemanticCache.Enabled && !c.Services.Embeddings.Enabled { log.Fatalf("services.llm.semantic_cache needs services.embeddings to be enabled") }
+        if err := checkFailover(c); err != nil { log.Fatalf("%v", err) }
+        llmSvc = newLLM(c)
+        log.Printf("LLM service enabled via %s (model=%s)%s", c.Services.LLM.URL, c.Services.LLM.Model, backendNote(c.Services.LLM.Backend))
+        if f := c.Services.LLM.Fallback; c.Services.LLM.Backend == "remote" && f.URL != "" { log.Printf("LLM fallback %s (model=%s) serves chats while the remote is unreachable", f.URL, f.Model) }
+        for _, f := range c.Services.LLM.Failover { log.Printf("LLM failover to %s (model=%s)", f.URL, f.Model) }
+        var err error
+        if llmUps, err = llmUpstreams(c); err != nil { log.Fatalf("%v", err) }
+        for _, up := range llmUps { log.Printf("LLM upstream %s serving %s", up.Name, strings.Join(up.Models, ", ")) }
+    }
+
+    // Keys mapped to tenants are API keys too.
+    apiKeys := append([]string(nil), c.Server.APIKeys...)
+    for key, name := range c.Tenants.Keys {
+        if err := server.CheckTenantName(name); err != nil { log.Fatalf("tenants.keys: %v", err) }
+        apiKeys = append(apiKeys, key)
     }
 
     // Start HTTP server
     mux := http.NewServeMux()
-    server.RegisterRoutes(mux, server.Dependencies{
-        STT:             sttSvc,
-        STTDefaultModel: c.Services.STT.Model,
-        Embeddings:      embSvc,
-        TTS:             ttsSvc,
-    })
+    deps := server.Dependencies{
+        STT:               sttSvc,
+        STTDefaultModel:   c.Services.STT.Model,
+        STTPrompt:         sttPrompt(c),
+        STTPreprocess:     stt.Preprocess{Normalize: c.Services.STT.Normalize, Denoise: c.Services.STT.Denoise},
+        STTPostprocess:    server.Postprocess{Default: c.Services.STT.Postprocess, Prompt: c.Services.STT.PostprocessPrompt},
+        LongAudio: server.LongAudio{
+            After:   time.Duration(max(0, c.Services.STT.SplitAfterMins)) * time.Minute,
+            Chunk:   time.Duration(c.Services.STT.ChunkMins) * time.Minute,
+            Workers: c.Services.STT.ChunkWorkers,
+        },
+        AudioClassifier:   classifier,
+        Speakers:          speakers,
+        Embeddings:        embSvc,
+        TTS:               ttsSvc,
+        LLM:               llmSvc,
+        LLMUpstreams:      llmUps,
+        VoiceSystemPrompt: c.VoiceChat.SystemPrompt,
+        VoiceGreeting:     c.VoiceChat.Greeting,
+        APIKeys:           apiKeys,
+        AdminKeys:         c.Server.AdminKeys,
+        PolicyPrompt:      c.Services.LLM.PolicyPrompt,
+        LLMDefaults:       llmDefaults(c),
+        ChatCache:         chatCache(c),
+        LoRA:              loraAdapters(c),
+        Vision:            visionRoute(c),
+        MCP:               c.MCP.Enabled,
+        ReadOnly:          server.ReadOnly{Downloads: c.ReadOnly.Downloads, Sessions: c.ReadOnly.Sessions, Speakers: c.ReadOnly.Speakers, Admin: c.ReadOnly.Admin},
+        Moderation: server.Moderation{
+            Categories:   c.Services.LLM.Moderation.Categories,
+            Threshold:    c.Services.LLM.Moderation.Threshold,
+            ScreenInput:  c.Services.LLM.Moderation.ScreenInput,
+            ScreenOutput: c.Services.LLM.Moderation.ScreenOutput,
+        },
+        IdempotencyTTL:    time.Duration(c.Server.IdempotencyTTLSecs) * time.Second,
+        MaxJSONBytes:      int64(c.Server.MaxJSONBodyMB) << 20,
+        Caps:              caps(c),
+        Timeouts: server.Timeouts{
+            STT:        time.Duration(c.Services.STT.TimeoutSecs) * time.Second,
+            TTS:        time.Duration(c.Services.TTS.TimeoutSecs) * time.Second,
+            LLM:        time.Duration(c.Services.LLM.TimeoutSecs) * time.Second,
+            Embeddings: time.Duration(c.Services.Embeddings.TimeoutSecs) * time.Second,
+        },
+        Limits: server.Limits{
+            STT:         server.Limit{MaxConcurrent: c.Services.STT.MaxConcurrent, MaxQueue: c.Services.STT.MaxQueue},
+            TTS:         server.Limit{MaxConcurrent: c.Services.TTS.MaxConcurrent, MaxQueue: c.Services.TTS.MaxQueue},
+            LLM:         server.Limit{MaxConcurrent: c.Services.LLM.MaxConcurrent, MaxQueue: c.Services.LLM.MaxQueue},
+            Embeddings:  server.Limit{MaxConcurrent: c.Services.Embeddings.MaxConcurrent, MaxQueue: c.Services.Embeddings.MaxQueue},
+            Interactive: server.PriorityClass{Weight: c.Priorities.Interactive.Weight, MaxQueue: c.Priorities.Interactive.MaxQueue},
+            Background:  server.PriorityClass{Weight: c.Priorities.Background.Weight, MaxQueue: c.Priorities.Background.MaxQueue},
+        },
+        Uploads: server.UploadPolicy{
+            Sniff:        c.Uploads.Sniff,
+            AllowedTypes: c.Uploads.AllowedTypes,
+            ScanCommand:  c.Uploads.ScanCommand,
+        },
+        Resumable: server.ResumableUploads{
+            Dir:      filepath.Join(dataDir, "uploads"),
+            MaxBytes: int64(c.Uploads.MaxResumableMB) << 20,
+            Expiry:   time.Duration(c.Uploads.ExpiryHours) * time.Hour,
+        },
+        JobsDB:   filepath.Join(dataDir, "jobs.db"),
+        Webhooks: server.Webhooks{URLs: c.Webhooks.URLs, Secret: c.Webhooks.Secret, PerJob: c.Webhooks.AllowPerJob, AllowPrivate: c.Webhooks.AllowPrivate},
+    }
+    if (len(c.Webhooks.URLs) > 0 || c.Webhooks.AllowPerJob) && c.Webhooks.Secret == "" { log.Printf("Warning: webhooks.secret is not set; job webhooks are sent unsigned") }
+    if c.Sessions.Enabled {
+        opts := sessions.Options{
+            MaxHistoryMessages: c.Sessions.MaxHistoryMessages,
+            MaxHistoryChars:    c.Sessions.MaxHistoryChars,
+        }
+        if cc := c.Sessions.Compression; cc.Enabled && llmSvc != nil {
+            opts.CompressAfterTokens, opts.KeepRecent, opts.SummaryPrompt = cc.ThresholdTokens, cc.KeepRecent, cc.SummaryPrompt
+        }
+        deps.Sessions = sessions.New(filepath.Join(dataDir, "sessions.db"), opts)
+        defer deps.Sessions.Close()
+    }
+    if deps.Search, err = webSearch(c); err != nil { log.Fatalf("invalid tools.search config: %v", err) }
+    if llmSvc != nil {
+        tpls := make(map[string]prompts.Template, len(c.Prompts))
+        for name, p := range c.Prompts {
+            tpls[name] = prompts.Template{Description: p.Description, Role: p.Role, Template: p.Template}
+        }
+        deps.Prompts, err = prompts.New(filepath.Join(dataDir, "prompts.json"), tpls)
+        if err != nil { log.Fatalf("invalid prompts: %v", err) }
+        if deps.Agent, err = agentTools(c, deps.Search); err != nil { log.Fatalf("invalid tools config: %v", err) }
+    }
+    if c.Usage.Enabled {
+        deps.Usage, err = usage.Open(filepath.Join(dataDir, "usage.json"), time.Duration(c.Usage.RetentionDays)*24*time.Hour)
+        if err != nil { log.Fatalf("usage stats: %v", err) }
+        go deps.Usage.Run(time.Minute, ctx.Done())
+    }
+    monitor := resources.New(time.Duration(c.Resources.IntervalSecs) * time.Second)
+    go monitor.Run(ctx)
+    deps.Resources = server.ResourceGuard{Monitor: monitor, Adaptive: c.Resources.Adaptive, Reserve: uint64(c.Resources.ReserveMB) << 20}
+    deps.Events = events
+    if llmSvc != nil { go events.WatchLLM(ctx, llmSvc, 10*time.Second) }
+    deps.Status = server.StatusOptions{DataDir: dataDir, Lock: lock, LLM: llmSvc, EmbeddingsModel: c.Services.Embeddings.Model, EmbeddingsNote: embeddingsNote(c), TTSEngine: ttsEngine(c), TTSVoice: c.Services.TTS.Voice}
+    deps = server.WithResources(server.WithUpstreams(deps))
+    if c.Tracing.Endpoint != "" {
+        stopTracing := tracing.Configure(tracing.Options{Endpoint: c.Tracing.Endpoint, ServiceName: c.Tracing.ServiceName, Headers: c.Tracing.Headers})
+        defer stopTracing()
+    }
+    var auditLog *audit.Logger
+    if c.Audit.Enabled {
+        path := c.Audit.Path
+        if path == "" { path = filepath.Join(dataDir, "audit.jsonl") }
+        auditLog, err = audit.Open(audit.Options{Path: path, MaxBytes: int64(c.Audit.MaxSizeMB) << 20, MaxFiles: c.Audit.MaxFiles, Redact: c.Audit.Redact})
+        if err != nil { log.Fatalf("audit log: %v", err) }
+        defer auditLog.Close()
+        deps = server.WithAudit(deps)
+    }
+    if len(c.Pipelines) > 0 {
+        deps.Pipelines = make(map[string]server.Pipeline, len(c.Pipelines))
+        for name, p := range c.Pipelines {
+            steps := make([]server.PipelineStep, len(p.Steps))
+            for i, st := range p.Steps {
+                steps[i] = server.PipelineStep{Type: st.Type, Model: st.Model, Voice: st.Voice, Prompt: st.Prompt}
+            }
+            deps.Pipelines[name] = server.Pipeline{Steps: steps}
+        }
+        if err := server.ValidatePipelines(deps); err != nil {
+            log.Fatalf("invalid pipelines config: %v", err)
+        }
+    }
+    deps.WebSocket = server.WSOptions{
+        Enable:             c.WebSocket.Enabled,
+        PathPrefix:         c.WebSocket.PathPrefix,
+        PingInterval:       time.Duration(c.WebSocket.PingIntervalSecs) * time.Second,
+        IdleTimeout:        time.Duration(c.WebSocket.IdleTimeoutSecs) * time.Second,
+        MaxMessageBytes:    c.WebSocket.MaxMessageBytes,
+        MaxConnections:     c.WebSocket.MaxConnections,
+        MaxInflightPerConn: c.WebSocket.MaxInflightPerConn,
+        AllowedOrigins:     c.WebSocket.AllowedOrigins,
+    }
+    if c.IOLog.Enabled {
+        if deps.IOLog, err = server.NewIOLog(c.IOLog.MaxChars, c.IOLog.Redact); err != nil { log.Fatalf("invalid io_log config: %v", err) }
+        log.Printf("Logging model inputs and outputs (truncated, %d redaction patterns)", len(c.IOLog.Redact))
+    }
+    deps = server.WithCoalescing(server.WithLimits(server.WithTracing(server.WithUsage(server.WithIOLog(deps)))))
+    server.RegisterRoutes(mux, deps)
 
     // Optional WebSocket endpoints
-    server.RegisterWSRoutes(mux, server.Dependencies{
-        STT:             sttSvc,
-        STTDefaultModel: c.Services.STT.Model,
-        Embeddings:      embSvc,
-        TTS:             ttsSvc,
-    }, server.WSOptions{Enable: c.WebSocket.Enabled, PathPrefix: c.WebSocket.PathPrefix})
+    server.RegisterWSRoutes(mux, deps, deps.WebSocket)
 
     // Optional Test UI
     if c.TestUI.Enabled {
         server.RegisterTestUI(mux)
     }
 
+    // Admin endpoints: prompt templates, usage stats, status, diagnostics and warm standby handoff
+    handoff := make(chan struct{})
+    adminOpts := server.AdminOptions{Prompts: deps.Prompts, Usage: deps.Usage, Debug: c.Server.Debug, Resources: monitor, LLM: llmSvc, LoRA: deps.LoRA, ReadOnly: c.ReadOnly.Admin}
+    if c.Server.AllowHandoff {
+        if len(c.Server.AdminKeys) == 0 { log.Fatalf("server.allow_handoff requires server.admin_keys") }
+        adminOpts.OnHandoff = func() { close(handoff) }
+    }
+    server.RegisterAdminRoutes(mux, adminOpts)
+
     // Bind explicitly so we can support port=0 and log the actual port
-    ln, err := net.Listen("tcp", c.Server.Host+":"+itoa(c.Server.Port))
+    addr := c.Server.Host + ":" + itoa(c.Server.Port)
+    var ln net.Listener
+    if standby {
+        ln, err = takeOver(addr, c.Server.AdminKeys[0])
+    } else {
+        ln, err = net.Listen("tcp", addr)
+    }
     if err != nil { log.Fatalf("listen error: %v", err) }
-    srv := &http.Server{Handler: mux}
+    drainer := server.NewDrainer()
+    tenants := server.TenantOptions{Keys: c.Tenants.Keys, Header: c.Tenants.Header}
+    compress := server.CompressOptions{Enabled: c.Server.Compress, MinBytes: c.Server.CompressMinBytes}
+    deadlines := server.DeadlineOptions{Read: seconds(c.Server.ReadTimeoutSecs), Write: seconds(c.Server.WriteTimeoutSecs)}
+    wsPrefix := ""
+    if c.WebSocket.Enabled { wsPrefix = c.WebSocket.PathPrefix }
+    srv := &http.Server{
+        Handler:           server.Chain(mux,
+            server.With(server.Deadlines, deadlines),
+            drainer.Handler,
+            server.Timing,
+            server.Trace,
+            server.With(server.Tenants, tenants),
+            server.With(server.Audit, auditLog),
+            func(h http.Handler) http.Handler { return server.RequireAPIKeyWS(h, apiKeys, c.Server.AdminKeys, wsPrefix) },
+            server.With(server.Compress, compress),
+            server.Recover,
+            server.Prioritize,
+        ),
+        BaseContext:       drainer.BaseContext,
+        ReadHeaderTimeout: seconds(c.Server.ReadHeaderTimeoutSecs),
+        IdleTimeout:       seconds(c.Server.IdleTimeoutSecs),
+    }
+    if !c.Server.DisableHTTP2 {
+        // h2c: HTTP/2 without TLS, by prior knowledge or an Upgrade header.
+        h2 := &http2.Server{IdleTimeout: srv.IdleTimeout}
+        if err := http2.ConfigureServer(srv, h2); err != nil { log.Fatalf("http2: %v", err) }
+        srv.Handler = h2c.NewHandler(srv.Handler, h2)
+    }
 
     // Startup summary log
     sttStatus := "disabled"
@@ -105,9 +413,22 @@ func main() {
     if c.WebSocket.Enabled { wsStatus = "enabled (prefix=" + c.WebSocket.PathPrefix + ")" }
     ttsStatus := "disabled"
     if ttsSvc != nil {
-        ttsStatus = "enabled (voice=" + c.Services.TTS.Voice + ")"
+        ttsStatus = "enabled (engine=" + ttsEngine(c) + ", voice=" + c.Services.TTS.Voice + ")"
+    }
+    llmStatus := "disabled"
+    if llmSvc != nil {
+        llmStatus = "enabled (url=" + c.Services.LLM.URL + ", model=" + c.Services.LLM.Model + ")"
+    }
+    log.Printf("Startup summary:\n  Address: %s\n  DataDir: %s\n  STT: %s\n  Embeddings: %s\n  TTS: %s\n  LLM: %s\n  WebSocket: %s", ln.Addr().String(), dataDir, sttStatus, embStatus, ttsStatus, llmStatus, wsStatus)
+    if c.Server.LogConfig {
+        if b, err := effectiveConfig(c, dataDir); err != nil {
+            log.Printf("effective config: %v", err)
+        } else {
+            log.Printf("Effective config (secrets masked):\n%s", b)
+        }
     }
-    log.Printf("Startup summary:\n  Address: %s\n  DataDir: %s\n  STT: %s\n  Embeddings: %s\n  TTS: %s\n  WebSocket: %s", ln.Addr().String(), dataDir, sttStatus, embStatus, ttsStatus, wsStatus)
+
+    if c.Server.MDNS { advertise(ctx, c, ln.Addr(), server.AdvertisedTXT(deps)) }
 
     go func() {
         if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
@@ -115,14 +436,20 @@ func main() {
         }
     }()
 
-    <-ctx.Done()
-    log.Printf("shutting down...")
+    select {
+    case <-ctx.Done():
+        log.Printf("shutting down...")
+    case <-handoff:
+        log.Printf("handoff requested, releasing listener and draining...")
+    }
 
-    shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
-    defer cancelShutdown()
-    _ = srv.Shutdown(shutdownCtx)
+    drainer.Shutdown(srv, time.Duration(c.Server.DrainTimeoutSecs)*time.Second)
+    if deps.Usage != nil { _ = deps.Usage.Flush() }
 }
 
+// seconds converts a config timeout; negative values disable it.
+func seconds(n int) time.Duration { return time.Duration(max(0, n)) * time.Second }
+
 func defaultDataDir() string {
     if dir, err := os.UserConfigDir(); err == nil {
         return filepath.Join(dir, "gollmcore")
@@ -130,6 +457,20 @@ func defaultDataDir() string {
     return filepath.Join(".", ".gollmcore")
 }
 
+// scratchDir is server.scratch_dir, or tmp under the data dir.
+func scratchDir(c config.Config, dataDir string) string {
+    if c.Server.ScratchDir != "" { return c.Server.ScratchDir }
+    return filepath.Join(dataDir, "tmp")
+}
+
+// effectiveConfig is c with defaults and flags applied and secrets masked,
+// as indented JSON.
+func effectiveConfig(c config.Config, dataDir string) ([]byte, error) {
+    c.Server.DataDir = dataDir
+    c.Server.ScratchDir = scratchDir(c, dataDir)
+    return json.MarshalIndent(c.Masked(), "", "  ")
+}
+
 func itoa(n int) string { return fmtInt(n) }
 
 // tiny helper to avoid importing strconv across files
@@ -0,0 +1,35 @@
+package main
+
+import (
+    "context"
+    "log"
+    "net"
+    "os"
+
+    "gollmcore/internal/config"
+    "gollmcore/internal/mdns"
+)
+
+// advertise announces the server as _gollmcore._tcp on the LAN until ctx
+// is done. A server bound to loopback is not reachable from other hosts,
+// so it is not advertised.
+func advertise(ctx context.Context, c config.Config, addr net.Addr, txt []string) {
+    tcp, ok := addr.(*net.TCPAddr)
+    if !ok { return }
+    if tcp.IP.IsLoopback() {
+        log.Printf("mdns: not advertising, server is bound to loopback (%s); set server.host to 0.0.0.0 or a LAN address", tcp)
+        return
+    }
+    ips := []net.IP{tcp.IP}
+    if tcp.IP.IsUnspecified() { ips = mdns.LocalIPv4() }
+    host, _ := os.Hostname()
+    if host == "" { host = "gollmcore" }
+    name := c.Server.MDNSName
+    if name == "" { name = "gollmcore on " + host }
+    r, err := mdns.New(mdns.Service{Instance: name, Type: "_gollmcore._tcp", Host: host, Port: tcp.Port, IPs: ips, TXT: txt})
+    if err != nil { log.Printf("mdns: %v", err); return }
+    go func() {
+        if err := r.Run(ctx); err != nil { log.Printf("mdns: %v", err) }
+    }()
+    log.Printf("mdns: advertising %q as _gollmcore._tcp on port %d", name, tcp.Port)
+}
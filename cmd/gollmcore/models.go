@@ -0,0 +1,88 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "gollmcore/internal/config"
+    "gollmcore/internal/models"
+)
+
+// -------- models subcommand --------
+//
+//   gollmcore models list             show models.lock
+//   gollmcore models verify           check local files against it
+//   gollmcore models update [path...] re-resolve sources and re-pin
+
+func modelsCommand(args []string) error {
+    if len(args) == 0 { return errors.New("usage: gollmcore models <list|verify|update> [--config file] [--data-dir dir] [path...]") }
+    sub := args[0]
+    fs := flag.NewFlagSet("models "+sub, flag.ExitOnError)
+    cfgPath := fs.String("config", "config.json", "Path to config file")
+    dataDirFlag := fs.String("data-dir", "", "Data directory (overrides server.data_dir)")
+    _ = fs.Parse(args[1:])
+
+    dataDir := *dataDirFlag
+    if dataDir == "" {
+        if c, err := config.Load(*cfgPath); err == nil { dataDir = c.Server.DataDir }
+    }
+    if dataDir == "" { dataDir = defaultDataDir() }
+    lock, err := models.Load(dataDir)
+    if err != nil { return err }
+    arts := lock.Artifacts()
+    if len(arts) == 0 { fmt.Printf("no artifacts recorded in %s/models.lock\n", dataDir); return nil }
+
+    switch sub {
+    case "list":
+        for _, a := range arts {
+            fmt.Printf("%s\n  url:     %s\n  version: %s\n  sha256:  %s (%d bytes)\n", a.Path, a.URL, orDash(a.Version), a.SHA256, a.Size)
+        }
+        return nil
+    case "verify":
+        bad := 0
+        for _, a := range arts {
+            switch err := lock.Verify(a); {
+            case err == nil:
+                fmt.Println("ok      ", a.Path)
+            case errors.Is(err, os.ErrNotExist):
+                fmt.Println("absent  ", a.Path, "(removed after extraction or not yet downloaded)")
+            default:
+                bad++
+                fmt.Println("FAILED  ", a.Path+":", err)
+            }
+        }
+        if bad > 0 { return fmt.Errorf("%d artifact(s) do not match models.lock", bad) }
+        return nil
+    case "update":
+        only := fs.Args()
+        for _, a := range arts {
+            if len(only) > 0 && !matchesAny(a.Path, only) { continue }
+            // Files still on disk are replaced; for absent ones (extracted
+            // archives) only the pin is refreshed.
+            _, statErr := os.Stat(lock.LocalPath(a))
+            n, err := lock.Refetch(a, statErr == nil, 10*time.Minute)
+            if err != nil { return fmt.Errorf("%s: %w", a.Path, err) }
+            state := "unchanged"
+            if n.SHA256 != a.SHA256 { state = "updated" }
+            fmt.Printf("%-10s %s %s\n", state, a.Path, orDash(n.Version))
+        }
+        return nil
+    }
+    return fmt.Errorf("unknown models command %q (want list, verify or update)", sub)
+}
+
+func matchesAny(path string, patterns []string) bool {
+    for _, p := range patterns {
+        if strings.Contains(path, p) { return true }
+    }
+    return false
+}
+
+func orDash(s string) string {
+    if s == "" { return "-" }
+    return s
+}
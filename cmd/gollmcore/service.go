@@ -0,0 +1,82 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    "gollmcore/internal/config"
+)
+
+// -------- Service manager integration --------
+//
+// install-service registers the daemon with the platform service manager
+// (systemd, launchd or the Windows SCM) so it starts at boot and restarts
+// on failure; uninstall-service stops and removes it again.
+
+// serviceSpec is everything a platform needs to register the daemon.
+type serviceSpec struct {
+    Name    string
+    Exe     string // absolute path of this binary
+    Config  string // absolute config path
+    DataDir string
+    User    bool // per-user unit/agent instead of a system-wide one
+    DryRun  bool // print the generated unit instead of installing it
+}
+
+func serviceCommand(cmd string, args []string) error {
+    fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+    var spec serviceSpec
+    fs.StringVar(&spec.Name, "name", "gollmcore", "Service name")
+    fs.StringVar(&spec.Config, "config", "config.json", "Path to config file (install-service)")
+    fs.BoolVar(&spec.User, "user", os.Geteuid() != 0, "Install a per-user service (systemd --user / LaunchAgent); ignored on Windows")
+    fs.BoolVar(&spec.DryRun, "dry-run", false, "Print the generated service definition without installing it")
+    _ = fs.Parse(args)
+
+    if cmd == "uninstall-service" { return uninstallService(spec) }
+
+    var err error
+    if spec.Config, err = filepath.Abs(spec.Config); err != nil { return err }
+    c, err := config.Load(spec.Config)
+    if err != nil { return err }
+    if spec.Exe, err = os.Executable(); err != nil { return err }
+    if spec.Exe, err = filepath.EvalSymlinks(spec.Exe); err != nil { return err }
+    // Resolve the data dir now: a system service runs with another user's
+    // home, so the default location would differ from the interactive one.
+    spec.DataDir = c.Server.DataDir
+    if spec.DataDir == "" { spec.DataDir = defaultDataDir() }
+    if spec.DataDir, err = filepath.Abs(spec.DataDir); err != nil { return err }
+    if !spec.DryRun {
+        if err := os.MkdirAll(spec.DataDir, 0o755); err != nil { return fmt.Errorf("create data dir: %w", err) }
+    }
+    return installService(spec)
+}
+
+// serviceArgs are the daemon arguments recorded in the service definition.
+func (s serviceSpec) serviceArgs() []string {
+    return []string{"--config", s.Config, "--data-dir", s.DataDir}
+}
+
+// runCommand runs a service manager command, echoing it first.
+func runCommand(name string, args ...string) error {
+    fmt.Println("+", name, strings.Join(args, " "))
+    cmd := exec.Command(name, args...)
+    cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+    if err := cmd.Run(); err != nil { return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err) }
+    return nil
+}
+
+// writeServiceFile writes a unit/plist, or prints it for --dry-run.
+func writeServiceFile(spec serviceSpec, path, content string) error {
+    if spec.DryRun {
+        fmt.Printf("# %s\n%s", path, content)
+        return nil
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return err }
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil { return err }
+    fmt.Println("wrote", path)
+    return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+    "fmt"
+    "html"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// launchd: a LaunchDaemon in /Library/LaunchDaemons or a per-user
+// LaunchAgent in ~/Library/LaunchAgents, kept alive after crashes.
+
+func plistPath(spec serviceSpec) (string, error) {
+    if !spec.User { return filepath.Join("/Library/LaunchDaemons", spec.Name+".plist"), nil }
+    home, err := os.UserHomeDir()
+    if err != nil { return "", err }
+    return filepath.Join(home, "Library", "LaunchAgents", spec.Name+".plist"), nil
+}
+
+func launchdPlist(spec serviceSpec) string {
+    esc := html.EscapeString
+    var args strings.Builder
+    for _, a := range append([]string{spec.Exe}, spec.serviceArgs()...) {
+        fmt.Fprintf(&args, "        <string>%s</string>\n", esc(a))
+    }
+    logPath := filepath.Join(spec.DataDir, spec.Name+".log")
+    return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>%s</string>
+    <key>ProgramArguments</key>
+    <array>
+%s    </array>
+    <key>WorkingDirectory</key>
+    <string>%s</string>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>KeepAlive</key>
+    <dict>
+        <key>SuccessfulExit</key>
+        <false/>
+    </dict>
+    <key>StandardOutPath</key>
+    <string>%s</string>
+    <key>StandardErrorPath</key>
+    <string>%s</string>
+</dict>
+</plist>
+`, esc(spec.Name), args.String(), esc(spec.DataDir), esc(logPath), esc(logPath))
+}
+
+func installService(spec serviceSpec) error {
+    path, err := plistPath(spec)
+    if err != nil { return err }
+    if err := writeServiceFile(spec, path, launchdPlist(spec)); err != nil { return err }
+    if spec.DryRun { return nil }
+    if err := runCommand("launchctl", "load", "-w", path); err != nil { return err }
+    fmt.Printf("installed %s; logs: %s\n", spec.Name, filepath.Join(spec.DataDir, spec.Name+".log"))
+    return nil
+}
+
+func uninstallService(spec serviceSpec) error {
+    path, err := plistPath(spec)
+    if err != nil { return err }
+    if spec.DryRun { fmt.Println("would remove", path); return nil }
+    if _, err := os.Stat(path); err != nil { return fmt.Errorf("service %s is not installed: %w", spec.Name, err) }
+    _ = runCommand("launchctl", "unload", "-w", path)
+    if err := os.Remove(path); err != nil { return err }
+    fmt.Println("removed", path)
+    return nil
+}
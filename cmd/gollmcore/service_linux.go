@@ -0,0 +1,84 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "os/user"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// systemd: a system unit in /etc/systemd/system (run as the sudo caller
+// when there is one) or a user unit in ~/.config/systemd/user with
+// lingering enabled so it starts at boot without a login.
+
+func unitPath(spec serviceSpec) (string, error) {
+    if !spec.User { return filepath.Join("/etc/systemd/system", spec.Name+".service"), nil }
+    dir, err := os.UserConfigDir()
+    if err != nil { return "", err }
+    return filepath.Join(dir, "systemd", "user", spec.Name+".service"), nil
+}
+
+// systemdQuote quotes one ExecStart argument.
+func systemdQuote(s string) string {
+    return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(s) + `"`
+}
+
+func systemdUnit(spec serviceSpec, runAs string) string {
+    exec := []string{systemdQuote(spec.Exe)}
+    for _, a := range spec.serviceArgs() { exec = append(exec, systemdQuote(a)) }
+    var b strings.Builder
+    fmt.Fprintf(&b, "[Unit]\nDescription=gollmcore local AI backend (%s)\nAfter=network-online.target\nWants=network-online.target\n\n", spec.Name)
+    fmt.Fprintf(&b, "[Service]\nType=simple\nExecStart=%s\nWorkingDirectory=%s\nRestart=on-failure\nRestartSec=5\n", strings.Join(exec, " "), spec.DataDir)
+    if runAs != "" { fmt.Fprintf(&b, "User=%s\n", runAs) }
+    target := "multi-user.target"
+    if spec.User { target = "default.target" }
+    fmt.Fprintf(&b, "\n[Install]\nWantedBy=%s\n", target)
+    return b.String()
+}
+
+func systemctl(spec serviceSpec, args ...string) error {
+    if spec.User { args = append([]string{"--user"}, args...) }
+    return runCommand("systemctl", args...)
+}
+
+func installService(spec serviceSpec) error {
+    path, err := unitPath(spec)
+    if err != nil { return err }
+    // A system unit installed through sudo runs as the calling user, who
+    // also owns the data dir, rather than as root.
+    runAs := ""
+    if !spec.User { runAs = os.Getenv("SUDO_USER") }
+    if runAs != "" && !spec.DryRun {
+        u, err := user.Lookup(runAs)
+        if err != nil { return err }
+        uid, _ := strconv.Atoi(u.Uid)
+        gid, _ := strconv.Atoi(u.Gid)
+        if err := os.Chown(spec.DataDir, uid, gid); err != nil { return fmt.Errorf("chown data dir: %w", err) }
+    }
+    if err := writeServiceFile(spec, path, systemdUnit(spec, runAs)); err != nil { return err }
+    if spec.DryRun { return nil }
+    if err := systemctl(spec, "daemon-reload"); err != nil { return err }
+    if err := systemctl(spec, "enable", "--now", spec.Name+".service"); err != nil { return err }
+    if spec.User {
+        if u, err := user.Current(); err == nil {
+            if err := runCommand("loginctl", "enable-linger", u.Username); err != nil {
+                fmt.Println("warning: lingering not enabled; the service starts at login instead of at boot:", err)
+            }
+        }
+    }
+    fmt.Printf("installed %s; logs: journalctl %s-u %s\n", spec.Name, map[bool]string{true: "--user ", false: ""}[spec.User], spec.Name)
+    return nil
+}
+
+func uninstallService(spec serviceSpec) error {
+    path, err := unitPath(spec)
+    if err != nil { return err }
+    if spec.DryRun { fmt.Println("would remove", path); return nil }
+    if _, err := os.Stat(path); err != nil { return fmt.Errorf("service %s is not installed: %w", spec.Name, err) }
+    _ = systemctl(spec, "disable", "--now", spec.Name+".service")
+    if err := os.Remove(path); err != nil { return err }
+    fmt.Println("removed", path)
+    return systemctl(spec, "daemon-reload")
+}
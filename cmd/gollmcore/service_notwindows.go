@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "context"
+
+// runAsService is a no-op outside Windows: systemd and launchd stop the
+// daemon with SIGTERM, which main already handles.
+func runAsService(ctx context.Context, dataDir string) context.Context { return ctx }
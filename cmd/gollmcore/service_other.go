@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+    "fmt"
+    "runtime"
+)
+
+func installService(serviceSpec) error {
+    return fmt.Errorf("service installation is not supported on %s", runtime.GOOS)
+}
+
+func uninstallService(serviceSpec) error {
+    return fmt.Errorf("service installation is not supported on %s", runtime.GOOS)
+}
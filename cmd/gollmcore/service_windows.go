@@ -0,0 +1,98 @@
+//go:build windows
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "golang.org/x/sys/windows/svc"
+    "golang.org/x/sys/windows/svc/mgr"
+)
+
+// Windows: an automatic-start service registered with the SCM that is
+// restarted after failures. When started by the SCM the daemon answers its
+// control requests (see runAsService) and logs to <data_dir>\gollmcore.log.
+
+func installService(spec serviceSpec) error {
+    if spec.DryRun {
+        fmt.Printf("would register service %q: %s %s\n", spec.Name, spec.Exe, strings.Join(spec.serviceArgs(), " "))
+        return nil
+    }
+    m, err := mgr.Connect()
+    if err != nil { return fmt.Errorf("connect to service manager (run as administrator): %w", err) }
+    defer m.Disconnect()
+    if s, err := m.OpenService(spec.Name); err == nil {
+        s.Close()
+        return fmt.Errorf("service %s already exists", spec.Name)
+    }
+    s, err := m.CreateService(spec.Name, spec.Exe, mgr.Config{
+        DisplayName: "gollmcore (" + spec.Name + ")",
+        Description: "gollmcore local AI backend",
+        StartType:   mgr.StartAutomatic,
+    }, spec.serviceArgs()...)
+    if err != nil { return err }
+    defer s.Close()
+    restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+    if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, 24*60*60); err != nil { return err }
+    if err := s.Start(); err != nil { return fmt.Errorf("start service: %w", err) }
+    fmt.Printf("installed %s; logs: %s\n", spec.Name, filepath.Join(spec.DataDir, "gollmcore.log"))
+    return nil
+}
+
+func uninstallService(spec serviceSpec) error {
+    if spec.DryRun { fmt.Printf("would remove service %q\n", spec.Name); return nil }
+    m, err := mgr.Connect()
+    if err != nil { return fmt.Errorf("connect to service manager (run as administrator): %w", err) }
+    defer m.Disconnect()
+    s, err := m.OpenService(spec.Name)
+    if err != nil { return fmt.Errorf("service %s is not installed: %w", spec.Name, err) }
+    defer s.Close()
+    if st, err := s.Control(svc.Stop); err == nil {
+        for deadline := time.Now().Add(20 * time.Second); st.State != svc.Stopped && time.Now().Before(deadline); {
+            time.Sleep(300 * time.Millisecond)
+            if st, err = s.Query(); err != nil { break }
+        }
+    }
+    if err := s.Delete(); err != nil { return err }
+    fmt.Println("removed service", spec.Name)
+    return nil
+}
+
+// runAsService hooks the daemon into the SCM when started as a service:
+// the returned context is cancelled on Stop or Shutdown.
+func runAsService(ctx context.Context, dataDir string) context.Context {
+    isService, err := svc.IsWindowsService()
+    if err != nil || !isService { return ctx }
+    if f, err := os.OpenFile(filepath.Join(dataDir, "gollmcore.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+        log.SetOutput(f)
+    }
+    ctx, cancel := context.WithCancel(ctx)
+    go func() {
+        if err := svc.Run("", scmHandler{cancel: cancel}); err != nil { log.Printf("service: %v", err) }
+        cancel()
+    }()
+    return ctx
+}
+
+type scmHandler struct{ cancel context.CancelFunc }
+
+func (h scmHandler) Execute(_ []string, reqs <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+    status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+    for req := range reqs {
+        switch req.Cmd {
+        case svc.Interrogate:
+            status <- req.CurrentStatus
+        case svc.Stop, svc.Shutdown:
+            status <- svc.Status{State: svc.StopPending}
+            h.cancel()
+            return false, 0
+        }
+    }
+    return false, 0
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+    "fmt"
+    "net/url"
+    "path/filepath"
+    "time"
+
+    "gollmcore/internal/config"
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/semcache"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/audioclass"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/speaker"
+    ttsvc "gollmcore/internal/services/tts"
+    "gollmcore/internal/services/stt"
+    "gollmcore/internal/websearch"
+)
+
+// Service constructors shared by the server and the one-shot subcommands,
+// so both use the same data dir layout.
+
+func newSTT(c config.Config, dataDir string) (*stt.STTService, error) {
+    if c.Services.STT.Backend == "mock" { return stt.NewMock(), nil }
+    source, err := stt.ParseBinarySource(c.Services.STT.BinarySource)
+    if err != nil { return nil, fmt.Errorf("services.stt: %w", err) }
+    gpu, err := stt.ParseGPU(c.Services.STT.GPU)
+    if err != nil { return nil, fmt.Errorf("services.stt: %w", err) }
+    b := stt.BinaryOptions{Source: source, URL: c.Services.STT.BinaryURL, Version: c.Services.STT.WhisperVersion, GPU: gpu}
+    return stt.NewWithBinary(filepath.Join(dataDir, "bin"), filepath.Join(dataDir, "models", "whisper"), b), nil
+}
+
+// sttPrompt is the default whisper prompt built from services.stt.
+func sttPrompt(c config.Config) string {
+    return stt.BuildPrompt(c.Services.STT.Prompt, c.Services.STT.Vocabulary)
+}
+
+func newAudioClassifier(dataDir string) (audioclass.Service, error) {
+    return audioclass.NewONNX(filepath.Join(dataDir, "models", "audioclass"))
+}
+
+func newSpeakers(c config.Config, dataDir string) (*speaker.Service, error) {
+    emb, err := speaker.NewONNX(filepath.Join(dataDir, "models", "speaker"))
+    if err != nil { return nil, err }
+    return speaker.New(emb, filepath.Join(dataDir, "speakers.json"), c.Services.Speakers.Threshold)
+}
+
+func newEmbeddings(c config.Config, dataDir string) (embeddings.Service, error) {
+    e := c.Services.Embeddings
+    pooling, err := embeddings.ParsePooling(e.Pooling)
+    if err != nil { return nil, fmt.Errorf("services.embeddings: %w", err) }
+    if e.Backend == "mock" { return embeddings.New(embeddings.Config{Defaults: embeddings.Options{Pooling: pooling, Normalize: e.Normalize}}), nil }
+    runtime := onnxrt.Options(e.ONNX)
+    if err := runtime.Check(); err != nil { return nil, fmt.Errorf("services.embeddings.onnx: %w", err) }
+    return embeddings.NewONNX(e.Model, filepath.Join(dataDir, "models", "embeddings", e.Model), embeddings.ONNXOptions{Defaults: embeddings.Options{Pooling: pooling, Normalize: e.Normalize}, Quantized: e.Quantized, Runtime: runtime})
+}
+
+// setONNXDefaults applies the top-level onnx options to every ONNX session
+// opened afterwards.
+func setONNXDefaults(c config.Config) error {
+    if err := onnxrt.SetDefaults(onnxrt.Options(c.ONNX)); err != nil { return fmt.Errorf("onnx: %w", err) }
+    return nil
+}
+
+// backendNote marks services running the mock or remote backend in log
+// lines.
+func backendNote(backend string) string {
+    if backend == "mock" || backend == "remote" { return " (" + backend + " backend)" }
+    return ""
+}
+
+// ttsEngine is the engine /v1/status reports.
+func ttsEngine(c config.Config) string {
+    if c.Services.TTS.Backend == "mock" { return "mock" }
+    return c.Services.TTS.Engine
+}
+
+// embeddingsNote is the /v1/status note on the embeddings model.
+func embeddingsNote(c config.Config) string {
+    if c.Services.Embeddings.Quantized { return embeddings.QuantizedNote }
+    return ""
+}
+
+func newTTS(c config.Config, dataDir string) (server.TTSService, error) {
+    if c.Services.TTS.Backend == "mock" { return ttsvc.NewMock(), nil }
+    return ttsvc.NewEngine(c.Services.TTS.Engine, filepath.Join(dataDir, "bin"), filepath.Join(dataDir, "models"), filepath.Join(dataDir, "tts"))
+}
+
+func newLLM(c config.Config) server.LLMService {
+    l := c.Services.LLM
+    if l.Backend == "mock" { return llm.NewMock(l.Model) }
+    primary := llm.New(l.URL, l.Model, l.APIKey)
+    chain := []*llm.Service{primary}
+    if l.Backend == "remote" && l.Fallback.URL != "" { chain = append(chain, llm.New(l.Fallback.URL, l.Fallback.Model, l.Fallback.APIKey)) }
+    for _, f := range l.Failover { chain = append(chain, llm.New(f.URL, f.Model, f.APIKey)) }
+    if len(chain) == 1 { return primary }
+    return llm.NewFailover(chain...)
+}
+
+// checkFailover rejects failover entries missing a url.
+func checkFailover(c config.Config) error {
+    for i, f := range c.Services.LLM.Failover {
+        if f.URL == "" { return fmt.Errorf("services.llm.failover[%d]: url is required", i) }
+    }
+    return nil
+}
+
+// llmUpstreams builds the extra LLM servers of services.llm.upstreams.
+// The mock backend serves every model itself.
+func llmUpstreams(c config.Config) ([]server.LLMUpstream, error) {
+    if c.Services.LLM.Backend == "mock" { return nil, nil }
+    var out []server.LLMUpstream
+    for i, u := range c.Services.LLM.Upstreams {
+        if u.URL == "" || len(u.Models) == 0 { return nil, fmt.Errorf("services.llm.upstreams[%d]: url and models are required", i) }
+        key := u.APIKey
+        if key == "" { key = c.Services.LLM.APIKey }
+        name := u.URL
+        if p, err := url.Parse(u.URL); err == nil && p.Host != "" { name = p.Host }
+        out = append(out, server.LLMUpstream{Name: name, Models: u.Models, LLM: llm.New(u.URL, u.Models[0], key)})
+    }
+    return out, nil
+}
+
+// llmDefaults converts services.llm.defaults.
+func llmDefaults(c config.Config) server.LLMDefaults {
+    gen := func(g config.GenerationDefaults) server.GenerationDefaults {
+        return server.GenerationDefaults{SystemPrompt: g.SystemPrompt, Temperature: g.Temperature, MaxTokens: g.MaxTokens, Stop: g.Stop}
+    }
+    d := c.Services.LLM.Defaults
+    out := server.LLMDefaults{GenerationDefaults: gen(d.GenerationDefaults)}
+    if len(d.Models) > 0 {
+        out.Models = make(map[string]server.GenerationDefaults, len(d.Models))
+        for name, g := range d.Models { out.Models[name] = gen(g) }
+    }
+    return out
+}
+
+// caps converts the per-request caps; negative values turn one off.
+func caps(c config.Config) server.Caps {
+    return server.Caps{
+        EmbeddingInputs: max(0, c.Services.Embeddings.MaxInputs),
+        TTSChars:        max(0, c.Services.TTS.MaxChars),
+        MaxTokens:       max(0, c.Services.LLM.MaxOutputTokens),
+        AudioDuration:   time.Duration(max(0, c.Services.STT.MaxAudioMins)) * time.Minute,
+    }
+}
+
+// chatCache builds the semantic chat cache, or nil when it is off.
+func chatCache(c config.Config) *semcache.Cache {
+    sc := c.Services.LLM.SemanticCache
+    if !sc.Enabled || !c.Services.LLM.Enabled { return nil }
+    return semcache.New(semcache.Options{Threshold: sc.Threshold, TTL: time.Duration(sc.TTLSecs) * time.Second, MaxEntries: sc.MaxEntries})
+}
+
+// loraAdapters converts services.llm.lora.
+func loraAdapters(c config.Config) map[string]server.LoRAAdapter {
+    if len(c.Services.LLM.LoRA) == 0 { return nil }
+    out := make(map[string]server.LoRAAdapter, len(c.Services.LLM.LoRA))
+    for name, a := range c.Services.LLM.LoRA { out[name] = server.LoRAAdapter{ID: a.ID, Scale: a.Scale} }
+    return out
+}
+
+// visionRoute sends image chats to services.llm.vision_model, on its own
+// server when vision_url is set.
+func visionRoute(c config.Config) server.VisionRoute {
+    l := c.Services.LLM
+    if !l.Enabled || l.VisionURL == "" { return server.VisionRoute{Model: l.VisionModel} }
+    return server.VisionRoute{Model: l.VisionModel, LLM: llm.New(l.VisionURL, l.VisionModel, l.APIKey)}
+}
+
+// agentTools builds the agent mode tools from the tools config.
+func agentTools(c config.Config, search server.WebSearch) (server.AgentOptions, error) {
+    opts := server.AgentOptions{MaxSteps: c.Tools.MaxSteps, Tools: map[string]server.Tool{}}
+    for _, name := range c.Tools.Builtin {
+        if name == "web_search" {
+            if search.Provider == nil { return opts, fmt.Errorf("the web_search tool needs tools.search") }
+            opts.Tools[name] = server.SearchTool(search)
+            continue
+        }
+        t, err := server.BuiltinTool(name)
+        if err != nil { return opts, err }
+        opts.Tools[name] = t
+    }
+    for name, h := range c.Tools.Webhooks {
+        if _, dup := opts.Tools[name]; dup { return opts, fmt.Errorf("tool %q is both built-in and a webhook", name) }
+        if h.URL == "" { return opts, fmt.Errorf("webhook tool %q has no url", name) }
+        opts.Tools[name] = server.WebhookTool{Description: h.Description, Parameters: h.Parameters, URL: h.URL, Headers: h.Headers, Timeout: time.Duration(h.TimeoutSecs) * time.Second}.Tool()
+    }
+    return opts, nil
+}
+
+// webSearch builds the tools.search provider; it is off without one.
+func webSearch(c config.Config) (server.WebSearch, error) {
+    s := c.Tools.Search
+    if s.Provider == "" { return server.WebSearch{}, nil }
+    p, err := websearch.New(websearch.Options{Provider: s.Provider, URL: s.URL, APIKey: s.APIKey, Timeout: time.Duration(s.TimeoutSecs) * time.Second})
+    if err != nil { return server.WebSearch{}, err }
+    return server.WebSearch{Provider: p, MaxResults: s.MaxResults}, nil
+}
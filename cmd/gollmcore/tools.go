@@ -0,0 +1,272 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strings"
+    "time"
+
+    "gollmcore/internal/config"
+    "gollmcore/internal/models"
+    "gollmcore/internal/scratch"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+)
+
+// -------- One-shot subcommands --------
+//
+// transcribe, speak, embed and chat run a single service in-process, with
+// the same config, data dir and downloads as the server, but without
+// starting it. Results go to stdout; progress and download logs to stderr.
+// mcp serves the same services to an MCP client over stdio.
+
+// toolFlags are the flags every subcommand accepts.
+type toolFlags struct {
+    fs      *flag.FlagSet
+    config  string
+    dataDir string
+}
+
+func newToolFlags(name string) *toolFlags {
+    t := &toolFlags{fs: flag.NewFlagSet(name, flag.ExitOnError)}
+    t.fs.StringVar(&t.config, "config", "config.json", "Path to config file (optional; defaults apply when the default file is missing)")
+    t.fs.StringVar(&t.dataDir, "data-dir", "", "Data directory (overrides server.data_dir)")
+    return t
+}
+
+// load parses args and prepares the config and data dir.
+func (t *toolFlags) load(args []string) (config.Config, string, error) {
+    _ = t.fs.Parse(args)
+    explicit := false
+    t.fs.Visit(func(f *flag.Flag) { if f.Name == "config" { explicit = true } })
+    c, err := config.Load(t.config)
+    if err != nil {
+        if explicit { return c, "", err }
+        if _, statErr := os.Stat(t.config); statErr == nil { return c, "", err }
+        c = config.Default()
+    }
+    dataDir := c.Server.DataDir
+    if t.dataDir != "" { dataDir = t.dataDir }
+    if dataDir == "" { dataDir = defaultDataDir() }
+    if err := os.MkdirAll(dataDir, 0o755); err != nil { return c, "", err }
+    if _, err := models.Open(dataDir); err != nil { return c, "", err }
+    models.DisableDownloads(c.ReadOnly.Downloads)
+    if err := setONNXDefaults(c); err != nil { return c, "", err }
+    if err := scratch.SetDir(scratchDir(c, dataDir)); err != nil { return c, "", err }
+    return c, dataDir, nil
+}
+
+func toolCommand(name string, args []string) error {
+    ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer cancel()
+    switch name {
+    case "transcribe":
+        return transcribeCommand(ctx, args)
+    case "speak":
+        return speakCommand(ctx, args)
+    case "embed":
+        return embedCommand(ctx, args)
+    case "chat":
+        return chatCommand(ctx, args)
+    case "mcp":
+        return mcpCommand(ctx, args)
+    }
+    return fmt.Errorf("unknown command %q", name)
+}
+
+// gollmcore transcribe [--model base] [--prompt text] [--normalize] [--denoise] file...
+func transcribeCommand(ctx context.Context, args []string) error {
+    t := newToolFlags("transcribe")
+    model := t.fs.String("model", "", "Whisper model (default services.stt.model)")
+    prompt := t.fs.String("prompt", "", "Whisper initial prompt (default from services.stt.prompt and vocabulary)")
+    normalize := t.fs.Bool("normalize", false, "Normalize the loudness of WAV input (default services.stt.normalize)")
+    denoise := t.fs.Bool("denoise", false, "Gate the noise floor of WAV input (default services.stt.denoise)")
+    c, dataDir, err := t.load(args)
+    if err != nil { return err }
+    if t.fs.NArg() == 0 { return errors.New("usage: gollmcore transcribe [--model base] [--prompt text] [--normalize] [--denoise] file...") }
+    if *model == "" { *model = c.Services.STT.Model }
+    if *prompt == "" { *prompt = sttPrompt(c) }
+    if err := stt.CheckPrompt(*prompt); err != nil { return err }
+    opts := stt.Options{Prompt: *prompt, Preprocess: stt.Preprocess{Normalize: c.Services.STT.Normalize, Denoise: c.Services.STT.Denoise}}
+    t.fs.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "normalize": opts.Preprocess.Normalize = *normalize
+        case "denoise": opts.Preprocess.Denoise = *denoise
+        }
+    })
+    svc, err := newSTT(c, dataDir)
+    if err != nil { return err }
+    for _, path := range t.fs.Args() {
+        text, err := svc.TranscribeFile(ctx, path, *model, opts)
+        if err != nil { return fmt.Errorf("%s: %w", path, err) }
+        if t.fs.NArg() > 1 { fmt.Printf("==> %s <==\n", path) }
+        fmt.Println(strings.TrimSpace(text))
+    }
+    return nil
+}
+
+// gollmcore speak [--voice v] [-o out.wav] "text" (or text on stdin)
+func speakCommand(ctx context.Context, args []string) error {
+    t := newToolFlags("speak")
+    voice := t.fs.String("voice", "", "Voice (default services.tts.voice)")
+    out := t.fs.String("o", "speech.wav", `Output WAV file ("-" for stdout)`)
+    c, dataDir, err := t.load(args)
+    if err != nil { return err }
+    text := strings.Join(t.fs.Args(), " ")
+    if text == "" || text == "-" {
+        b, err := io.ReadAll(os.Stdin)
+        if err != nil { return err }
+        text = string(b)
+    }
+    if strings.TrimSpace(text) == "" { return errors.New(`usage: gollmcore speak [--voice v] [-o out.wav] "text"`) }
+    if *voice == "" { *voice = c.Services.TTS.Voice }
+    svc, err := newTTS(c, dataDir)
+    if err != nil { return err }
+    audio, err := svc.Synthesize(ctx, strings.TrimSpace(text), *voice)
+    if err != nil { return err }
+    if *out == "-" { _, err = os.Stdout.Write(audio); return err }
+    if err := os.WriteFile(*out, audio, 0o644); err != nil { return err }
+    fmt.Fprintf(os.Stderr, "wrote %s (%d bytes)\n", *out, len(audio))
+    return nil
+}
+
+// gollmcore embed [--model m] [-f texts.txt] [text...]: one JSON object per input line.
+func embedCommand(ctx context.Context, args []string) error {
+    t := newToolFlags("embed")
+    file := t.fs.String("f", "", `File with one text per line ("-" for stdin)`)
+    model := t.fs.String("model", "", "Embeddings model (default services.embeddings.model)")
+    c, dataDir, err := t.load(args)
+    if err != nil { return err }
+    if *model != "" { c.Services.Embeddings.Model = *model }
+    inputs := t.fs.Args()
+    if *file != "" {
+        r := io.Reader(os.Stdin)
+        if *file != "-" {
+            f, err := os.Open(*file)
+            if err != nil { return err }
+            defer f.Close()
+            r = f
+        }
+        sc := bufio.NewScanner(r)
+        sc.Buffer(make([]byte, 1<<20), 1<<20)
+        for sc.Scan() {
+            if line := strings.TrimSpace(sc.Text()); line != "" { inputs = append(inputs, line) }
+        }
+        if err := sc.Err(); err != nil { return err }
+    }
+    if len(inputs) == 0 { return errors.New("usage: gollmcore embed [--model m] [-f texts.txt] [text...]") }
+    svc, err := newEmbeddings(c, dataDir)
+    if err != nil { return err }
+    enc := json.NewEncoder(os.Stdout)
+    const batch = 32
+    for start := 0; start < len(inputs); start += batch {
+        end := start + batch
+        if end > len(inputs) { end = len(inputs) }
+        vecs, model, err := svc.Embed(ctx, inputs[start:end])
+        if err != nil { return err }
+        for i, v := range vecs {
+            if err := enc.Encode(map[string]any{"index": start + i, "text": inputs[start+i], "model": model, "embedding": v}); err != nil { return err }
+        }
+    }
+    return nil
+}
+
+// gollmcore chat [--model m] [--system prompt]: an interactive REPL that
+// streams replies. /reset clears the conversation, /exit quits.
+func chatCommand(ctx context.Context, args []string) error {
+    t := newToolFlags("chat")
+    model := t.fs.String("model", "", "Model (default services.llm.model)")
+    system := t.fs.String("system", "", "System prompt")
+    c, _, err := t.load(args)
+    if err != nil { return err }
+    svc := newLLM(c)
+    var history []llm.Message
+    if *system != "" { history = append(history, llm.Message{Role: "system", Content: *system}) }
+    base := len(history)
+    in := bufio.NewScanner(os.Stdin)
+    in.Buffer(make([]byte, 1<<20), 1<<20)
+    for {
+        fmt.Print("> ")
+        if !in.Scan() { fmt.Println(); return in.Err() }
+        line := strings.TrimSpace(in.Text())
+        switch line {
+        case "":
+            continue
+        case "/exit", "/quit":
+            return nil
+        case "/reset":
+            history = history[:base]
+            fmt.Println("(conversation cleared)")
+            continue
+        }
+        history = append(history, llm.Message{Role: "user", Content: line})
+        resp, err := svc.ChatStream(ctx, llm.ChatRequest{Model: *model, Messages: history}, func(ch llm.ChatChunk) error {
+            for _, choice := range ch.Choices { fmt.Print(choice.Delta.Content) }
+            return nil
+        })
+        fmt.Println()
+        if err != nil {
+            if ctx.Err() != nil { return nil }
+            fmt.Fprintln(os.Stderr, "error:", err)
+            history = history[:len(history)-1]
+            continue
+        }
+        history = append(history, llm.Message{Role: "assistant", Content: resp.Text()})
+    }
+}
+
+// gollmcore mcp: an MCP server on stdin/stdout for clients that spawn their
+// tools, such as Claude Desktop. It offers the services enabled in the
+// config; services.llm, when enabled, post-processes transcripts.
+func mcpCommand(ctx context.Context, args []string) error {
+    t := newToolFlags("mcp")
+    c, dataDir, err := t.load(args)
+    if err != nil { return err }
+    // stdout carries the protocol; whisper and everything else that
+    // prints there is sent to stderr instead.
+    out := os.Stdout
+    os.Stdout = os.Stderr
+    d := server.Dependencies{
+        STTDefaultModel: c.Services.STT.Model,
+        STTPrompt:       sttPrompt(c),
+        STTPreprocess:   stt.Preprocess{Normalize: c.Services.STT.Normalize, Denoise: c.Services.STT.Denoise},
+        STTPostprocess:  server.Postprocess{Default: c.Services.STT.Postprocess, Prompt: c.Services.STT.PostprocessPrompt},
+        LongAudio:       server.LongAudio{After: time.Duration(max(0, c.Services.STT.SplitAfterMins)) * time.Minute, Chunk: time.Duration(c.Services.STT.ChunkMins) * time.Minute, Workers: c.Services.STT.ChunkWorkers},
+        Uploads:         server.UploadPolicy{Sniff: c.Uploads.Sniff, AllowedTypes: c.Uploads.AllowedTypes, ScanCommand: c.Uploads.ScanCommand},
+        Caps:            caps(c),
+        Timeouts: server.Timeouts{
+            STT:        time.Duration(c.Services.STT.TimeoutSecs) * time.Second,
+            TTS:        time.Duration(c.Services.TTS.TimeoutSecs) * time.Second,
+            LLM:        time.Duration(c.Services.LLM.TimeoutSecs) * time.Second,
+            Embeddings: time.Duration(c.Services.Embeddings.TimeoutSecs) * time.Second,
+        },
+        // Clients may call tools concurrently; the server's limits apply.
+        Limits: server.Limits{
+            STT:        server.Limit{MaxConcurrent: c.Services.STT.MaxConcurrent, MaxQueue: c.Services.STT.MaxQueue},
+            TTS:        server.Limit{MaxConcurrent: c.Services.TTS.MaxConcurrent, MaxQueue: c.Services.TTS.MaxQueue},
+            Embeddings: server.Limit{MaxConcurrent: c.Services.Embeddings.MaxConcurrent, MaxQueue: c.Services.Embeddings.MaxQueue},
+        },
+    }
+    if c.Services.STT.Enabled {
+        if d.STT, err = newSTT(c, dataDir); err != nil { return err }
+    }
+    if c.Services.TTS.Enabled {
+        if d.TTS, err = newTTS(c, dataDir); err != nil { return err }
+    }
+    if c.Services.Embeddings.Enabled {
+        if d.Embeddings, err = newEmbeddings(c, dataDir); err != nil { return err }
+    }
+    if c.Services.LLM.Enabled { d.LLM = newLLM(c) }
+    if d.Search, err = webSearch(c); err != nil { return fmt.Errorf("tools.search: %w", err) }
+    if d.STT == nil && d.TTS == nil && d.Embeddings == nil && d.Search.Provider == nil { return errors.New("no services enabled; enable stt, tts or embeddings in the config") }
+    fmt.Fprintf(os.Stderr, "gollmcore MCP server ready on stdio\n")
+    return server.ServeMCP(ctx, server.WithLimits(d), os.Stdin, out)
+}
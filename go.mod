@@ -1,7 +1,13 @@
 module gollmcore
 
-go 1.21
+go 1.22
 
 require github.com/gorilla/websocket v1.5.3
 
-require github.com/yalue/onnxruntime_go v1.21.0
+require (
+	github.com/yalue/onnxruntime_go v1.21.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/net v0.21.0
+	golang.org/x/sys v0.17.0
+	golang.org/x/text v0.14.0
+)
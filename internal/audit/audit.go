@@ -0,0 +1,173 @@
+// Package audit appends one JSON line per API request to a size-rotated
+// log: who called which route, the model and token counts involved, how
+// long it took and a hash of the (truncated) input, with configurable
+// redaction of fields an operator does not want on disk.
+package audit
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Entry is one audit record. Fields emptied by redaction are omitted.
+type Entry struct {
+    Time             time.Time `json:"time"`
+    Key              string    `json:"key,omitempty"`
+    Tenant           string    `json:"tenant,omitempty"`
+    RemoteAddr       string    `json:"remote_addr,omitempty"`
+    Method           string    `json:"method"`
+    Route            string    `json:"route"`
+    Status           int       `json:"status"`
+    DurationMs       int64     `json:"duration_ms"`
+    Service          string    `json:"service,omitempty"`
+    Model            string    `json:"model,omitempty"`
+    PromptTokens     int       `json:"prompt_tokens,omitempty"`
+    CompletionTokens int       `json:"completion_tokens,omitempty"`
+    InputBytes       int64     `json:"input_bytes,omitempty"`
+    InputSHA256      string    `json:"input_sha256,omitempty"`
+    TraceID          string    `json:"trace_id,omitempty"`
+    // QueueMs and StagesMs break DurationMs down: time waiting for a
+    // service slot and time per service stage (tokenize, inference, ...).
+    QueueMs          float64   `json:"queue_ms,omitempty"`
+    StagesMs         map[string]float64 `json:"stages_ms,omitempty"`
+
+    mu sync.Mutex
+}
+
+// Options configures a Logger.
+type Options struct {
+    Path     string
+    MaxBytes int64 // rotate once the file exceeds this (default 100 MiB)
+    MaxFiles int   // rotated files kept as <path>.1 … <path>.N (default 5)
+    // Redact lists JSON field names that are never written, e.g. "key",
+    // "remote_addr" or "input_sha256".
+    Redact   []string
+}
+
+// Logger writes entries to the current file and rotates it by size.
+type Logger struct {
+    opts    Options
+    redact  map[string]bool
+    mu      sync.Mutex
+    f       *os.File
+    size    int64
+    tenants map[string]*Logger
+}
+
+// Open creates or appends to opts.Path.
+func Open(opts Options) (*Logger, error) {
+    if opts.MaxBytes <= 0 { opts.MaxBytes = 100 << 20 }
+    if opts.MaxFiles <= 0 { opts.MaxFiles = 5 }
+    l := &Logger{opts: opts, redact: map[string]bool{}}
+    for _, f := range opts.Redact { l.redact[strings.TrimSpace(f)] = true }
+    if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil { return nil, err }
+    if err := l.open(); err != nil { return nil, err }
+    return l, nil
+}
+
+func (l *Logger) open() error {
+    f, err := os.OpenFile(l.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+    if err != nil { return fmt.Errorf("open audit log: %w", err) }
+    info, err := f.Stat()
+    if err != nil { f.Close(); return err }
+    l.f, l.size = f, info.Size()
+    return nil
+}
+
+// Write appends e as one line, rotating first if the file is full.
+func (l *Logger) Write(e *Entry) error {
+    e.mu.Lock()
+    b, err := json.Marshal(e)
+    e.mu.Unlock()
+    if err != nil { return err }
+    if len(l.redact) > 0 {
+        var m map[string]any
+        if err := json.Unmarshal(b, &m); err != nil { return err }
+        for f := range l.redact { delete(m, f) }
+        if b, err = json.Marshal(m); err != nil { return err }
+    }
+    b = append(b, '\n')
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.f == nil { return os.ErrClosed }
+    if l.size > 0 && l.size+int64(len(b)) > l.opts.MaxBytes {
+        if err := l.rotate(); err != nil { return err }
+    }
+    n, err := l.f.Write(b)
+    l.size += int64(n)
+    return err
+}
+
+// rotate shifts <path>.i to <path>.i+1, dropping the oldest, and starts a
+// new file.
+func (l *Logger) rotate() error {
+    l.f.Close()
+    l.f = nil
+    p := l.opts.Path
+    _ = os.Remove(fmt.Sprintf("%s.%d", p, l.opts.MaxFiles))
+    for i := l.opts.MaxFiles - 1; i >= 1; i-- {
+        _ = os.Rename(fmt.Sprintf("%s.%d", p, i), fmt.Sprintf("%s.%d", p, i+1))
+    }
+    if err := os.Rename(p, p+".1"); err != nil { return err }
+    return l.open()
+}
+
+// Tenant returns the log of the named tenant: a file of the same name in
+// tenants/<name>/ beside this one, opened on first use with the same
+// options. The empty name is l itself.
+func (l *Logger) Tenant(name string) (*Logger, error) {
+    if name == "" { return l, nil }
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if t := l.tenants[name]; t != nil { return t, nil }
+    if l.f == nil { return nil, os.ErrClosed }
+    opts := l.opts
+    opts.Path = filepath.Join(filepath.Dir(l.opts.Path), "tenants", name, filepath.Base(l.opts.Path))
+    t, err := Open(opts)
+    if err != nil { return nil, err }
+    if l.tenants == nil { l.tenants = map[string]*Logger{} }
+    l.tenants[name] = t
+    return t, nil
+}
+
+// Close flushes and closes the current file and those of its tenants.
+func (l *Logger) Close() error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    var err error
+    for _, t := range l.tenants { err = errors.Join(err, t.Close()) }
+    if l.f == nil { return err }
+    err = errors.Join(err, l.f.Close())
+    l.f = nil
+    return err
+}
+
+// -------- Per-request entry --------
+
+type ctxKey struct{}
+
+// WithEntry returns ctx carrying e so service calls can annotate it.
+func WithEntry(ctx context.Context, e *Entry) context.Context {
+    return context.WithValue(ctx, ctxKey{}, e)
+}
+
+// Note records the service, model and token counts of a call made while
+// handling the request in ctx. Counts add up across calls; the first
+// service and model named are kept. It is a no-op outside an audited request.
+func Note(ctx context.Context, service, model string, promptTokens, completionTokens int) {
+    e, _ := ctx.Value(ctxKey{}).(*Entry)
+    if e == nil { return }
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if e.Service == "" { e.Service = service }
+    if e.Model == "" { e.Model = model }
+    e.PromptTokens += promptTokens
+    e.CompletionTokens += completionTokens
+}
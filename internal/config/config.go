@@ -7,42 +7,396 @@ import (
 )
 
 type Server struct {
-    Host    string `json:"host"`
-    Port    int    `json:"port"`
-    DataDir string `json:"data_dir"`
+    Host               string   `json:"host"`
+    Port               int      `json:"port"`
+    DataDir            string   `json:"data_dir"`
+    // AllowHandoff exposes POST /admin/handoff (loopback and an admin key)
+    // so a process started with --standby can take over the listener.
+    AllowHandoff       bool     `json:"allow_handoff"`
+    // APIKeys, when set, must accompany every request except /healthz.
+    APIKeys            []string `json:"api_keys"`
+    // AdminKeys are accepted like APIKeys and may skip the LLM policy prompt.
+    AdminKeys          []string `json:"admin_keys"`
+    // IdempotencyTTLSecs is how long Idempotency-Key results are replayed (default 600).
+    IdempotencyTTLSecs int      `json:"idempotency_ttl_seconds"`
+    // Debug exposes /debug/pprof/ and /debug/vars to loopback and admin callers.
+    Debug              bool     `json:"debug"`
+    // LogConfig adds the effective configuration, secrets masked, to the
+    // startup summary.
+    LogConfig          bool     `json:"log_config"`
+    // MDNS advertises the server on the LAN as _gollmcore._tcp under
+    // MDNSName (default "gollmcore on <hostname>").
+    MDNS               bool     `json:"mdns"`
+    MDNSName           string   `json:"mdns_name"`
+    // DrainTimeoutSecs is how long shutdown waits for in-flight requests,
+    // streams and WebSocket work before cancelling them (default 30).
+    DrainTimeoutSecs   int      `json:"drain_timeout_seconds"`
+    // ScratchDir holds the temporary files of requests (default
+    // <data_dir>/tmp). Files there older than ScratchMaxAgeMins (default
+    // 60) are deleted at startup.
+    ScratchDir         string   `json:"scratch_dir"`
+    ScratchMaxAgeMins  int      `json:"scratch_max_age_minutes"`
+    // MaxJSONBodyMB bounds JSON request bodies (default 32, negative
+    // disables); larger ones get 413.
+    MaxJSONBodyMB      int      `json:"max_json_body_mb"`
+    // Compress gzips (or deflates) JSON responses of at least
+    // CompressMinBytes (default 1024) for clients that accept it.
+    Compress           bool     `json:"compress"`
+    CompressMinBytes   int      `json:"compress_min_bytes"`
+    // ReadHeaderTimeoutSecs bounds reading request headers (default 10),
+    // ReadTimeoutSecs the request body (default 300) and WriteTimeoutSecs
+    // each write or flush of a response (default 60), so long streams are
+    // not cut off; IdleTimeoutSecs closes idle keep-alive connections
+    // (default 120). -1 disables one.
+    ReadHeaderTimeoutSecs int   `json:"read_header_timeout_seconds"`
+    ReadTimeoutSecs    int      `json:"read_timeout_seconds"`
+    WriteTimeoutSecs   int      `json:"write_timeout_seconds"`
+    IdleTimeoutSecs    int      `json:"idle_timeout_seconds"`
+    // DisableHTTP2 turns off HTTP/2 over cleartext (h2c), which is served
+    // beside HTTP/1.1 on the same port.
+    DisableHTTP2       bool     `json:"disable_http2"`
 }
 
+// Every service accepts:
+//   - TimeoutSecs: bound on a single call; longer calls fail with 504.
+//     Zero uses the default (stt 600, tts 120, llm 300, embeddings 120).
+//   - MaxConcurrent: calls allowed to run at once (0 = unlimited); up to
+//     MaxQueue more wait (default 16), the rest get 429.
+//   - Backend: "mock" replaces the model with a deterministic stand-in
+//     that downloads nothing (canned LLM replies, a sine-wave voice, a
+//     transcript describing the audio, hash embeddings) for CI and the
+//     test suites of client apps. The LLM also takes "remote": a hosted
+//     OpenAI-compatible API at URL, with Fallback used while it is down.
+
 type STT struct {
-    Enabled bool   `json:"enabled"`
-    Model   string `json:"model"`
+    Enabled           bool     `json:"enabled"`
+    Backend           string   `json:"backend"`
+    Model             string   `json:"model"`
+    // BinarySource is where whisper.cpp is installed from on first use:
+    // "mirror" (default, prebuilt archives), "official" (whisper.cpp
+    // GitHub release binaries, Windows only) or "build" (compile the
+    // release source with cmake). BinaryURL, a zip archive holding
+    // whisper-cli, overrides it; WhisperVersion is the release tag
+    // (default v1.7.6).
+    BinarySource      string   `json:"binary_source"`
+    BinaryURL         string   `json:"binary_url"`
+    WhisperVersion    string   `json:"whisper_version"`
+    // GPU is "auto" (default: CUDA or Metal when detected), "cuda",
+    // "metal" or "off". It picks the build installed and whether whisper
+    // runs on the GPU; a failing GPU falls back to the CPU.
+    GPU               string   `json:"gpu"`
+    // Prompt and Vocabulary form whisper's default initial prompt, used to
+    // suggest the spelling of product names and jargon; requests may
+    // replace it with their own prompt.
+    Prompt            string   `json:"prompt"`
+    Vocabulary        []string `json:"vocabulary"`
+    // Normalize and Denoise clean up PCM WAV uploads before whisper runs
+    // (gain normalization and a noise gate); requests may override them.
+    Normalize         bool     `json:"normalize"`
+    Denoise           bool     `json:"denoise"`
+    // Postprocess cleans transcripts up with the LLM by default, using
+    // PostprocessPrompt (or the built-in clean-up prompt); requests may
+    // override it.
+    Postprocess       bool     `json:"postprocess"`
+    PostprocessPrompt string   `json:"postprocess_prompt"`
+    // PCM WAV recordings longer than SplitAfterMins (default 30, negative
+    // disables) are cut at silences into ChunkMins pieces (default 10)
+    // transcribed by up to ChunkWorkers whisper runs at once.
+    SplitAfterMins    int      `json:"split_after_minutes"`
+    ChunkMins         int      `json:"chunk_minutes"`
+    ChunkWorkers      int      `json:"chunk_workers"`
+    // MaxAudioMins refuses longer PCM WAV recordings (default 180,
+    // negative disables).
+    MaxAudioMins      int      `json:"max_audio_minutes"`
+    TimeoutSecs       int      `json:"timeout_seconds"`
+    MaxConcurrent     int      `json:"max_concurrent"`
+    MaxQueue          int      `json:"max_queue"`
 }
 
 type Embeddings struct {
-    Enabled bool   `json:"enabled"`
-    Model   string `json:"model"`
+    Enabled       bool   `json:"enabled"`
+    Backend       string `json:"backend"`
+    Model         string `json:"model"`
+    // Pooling combines the model's token vectors: mean (default), cls or
+    // max. Normalize false returns vectors without L2 normalization.
+    // Requests may override both.
+    Pooling       string `json:"pooling"`
+    Normalize     *bool  `json:"normalize"`
+    // Quantized uses the int8 export of the model: a quarter of the
+    // memory and faster on small CPUs, at a small cost in accuracy.
+    Quantized     bool   `json:"quantized"`
+    // MaxInputs caps the texts in one call (default 2048, negative
+    // disables).
+    MaxInputs     int    `json:"max_inputs"`
+    // ONNX overrides the top-level onnx options for this session.
+    ONNX          ONNX   `json:"onnx"`
+    TimeoutSecs   int    `json:"timeout_seconds"`
+    MaxConcurrent int    `json:"max_concurrent"`
+    MaxQueue      int    `json:"max_queue"`
+}
+
+// ONNX tunes the ONNX Runtime sessions (embeddings, Kokoro, Silero VAD,
+// WeSpeaker); unset fields keep ORT's defaults of one thread per core and
+// a memory arena. Fewer threads and no arena suit small boards; graph
+// optimization is disabled, basic, extended or all (ORT's default).
+type ONNX struct {
+    IntraOpThreads    int    `json:"intra_op_threads"`
+    InterOpThreads    int    `json:"inter_op_threads"`
+    MemoryArena       *bool  `json:"memory_arena"`
+    GraphOptimization string `json:"graph_optimization"`
+}
+
+// AudioClassify enables /v1/audio/classify (Silero VAD plus heuristics;
+// language ID additionally needs STT).
+type AudioClassify struct {
+    Enabled bool `json:"enabled"`
+}
+
+// Speakers enables /v1/speakers (WeSpeaker ONNX embeddings). Threshold is
+// the cosine similarity a recording needs to match a speaker (default 0.5).
+type Speakers struct {
+    Enabled   bool    `json:"enabled"`
+    Threshold float64 `json:"threshold"`
 }
 
 type TTS struct {
-    Enabled bool   `json:"enabled"`
-    Voice   string `json:"voice"` // e.g., en_US-amy-medium
+    Enabled       bool   `json:"enabled"`
+    Backend       string `json:"backend"`
+    Engine        string `json:"engine"` // piper (default) | kokoro
+    Voice         string `json:"voice"`  // e.g., en_US-amy-medium (piper), af_heart (kokoro)
+    // MaxChars caps the text of one request (default 4096, negative
+    // disables).
+    MaxChars      int    `json:"max_chars"`
+    TimeoutSecs   int    `json:"timeout_seconds"`
+    MaxConcurrent int    `json:"max_concurrent"`
+    MaxQueue      int    `json:"max_queue"`
+}
+
+type LLM struct {
+    Enabled       bool                   `json:"enabled"`
+    Backend       string                 `json:"backend"`
+    URL           string                 `json:"url"`     // OpenAI-compatible base URL, e.g., http://127.0.0.1:11434/v1
+    Model         string                 `json:"model"`
+    APIKey        string                 `json:"api_key"` // optional, sent as a bearer token upstream
+    // PolicyPrompt is always prepended as a system message; only requests
+    // with an admin key can turn it off.
+    PolicyPrompt  string                 `json:"policy_prompt"`
+    // Defaults fill in chat requests that leave these fields out.
+    Defaults      LLMDefaults            `json:"defaults"`
+    // SemanticCache needs services.embeddings.
+    SemanticCache SemanticCache          `json:"semantic_cache"`
+    Moderation    Moderation             `json:"moderation"`
+    // VisionModel serves chat requests with images that name no model;
+    // VisionURL, when set, is a separate OpenAI-compatible server for it.
+    VisionModel   string                 `json:"vision_model"`
+    VisionURL     string                 `json:"vision_url"`
+    // LoRA names adapters loaded by llama-server (--lora); requests pick
+    // one with model "base:name".
+    LoRA          map[string]LoRAAdapter `json:"lora"`
+    // Upstreams are more OpenAI-compatible servers, each serving Models
+    // (e.g. one llama-server per GGUF file). Chats naming one of those
+    // models are balanced over the upstreams serving it; url counts as one
+    // for model.
+    Upstreams     []LLMUpstream          `json:"upstreams"`
+    // Fallback serves chats while a "remote" backend's url cannot be
+    // reached, e.g. a local llama-server behind a hosted API.
+    Fallback      LLMFallback            `json:"fallback"`
+    // Failover lists more servers tried in order, each with a circuit
+    // breaker, when url (then the fallback) is down or failing.
+    Failover      []LLMFallback          `json:"failover"`
+    // MaxOutputTokens is the largest max_tokens a request may ask for,
+    // and the value used when it sets none (default 4096, negative
+    // disables).
+    MaxOutputTokens int                  `json:"max_output_tokens"`
+    TimeoutSecs   int                    `json:"timeout_seconds"`
+    MaxConcurrent int                    `json:"max_concurrent"`
+    MaxQueue      int                    `json:"max_queue"`
+}
+
+type LLMUpstream struct {
+    URL    string   `json:"url"`
+    Models []string `json:"models"`
+    APIKey string   `json:"api_key"` // default services.llm.api_key
+}
+
+type LLMFallback struct {
+    URL    string `json:"url"` // empty: no fallback
+    Model  string `json:"model"`
+    APIKey string `json:"api_key"`
+}
+
+type LoRAAdapter struct {
+    ID    int     `json:"id"`    // position of the --lora flag, from 0
+    Scale float64 `json:"scale"` // default 1
+}
+
+// Moderation configures /v1/moderations and optional chat screening.
+type Moderation struct {
+    Categories   []string `json:"categories"`
+    Threshold    float64  `json:"threshold"` // default 0.5
+    ScreenInput  bool     `json:"screen_input"`
+    ScreenOutput bool     `json:"screen_output"`
 }
 
+type SemanticCache struct {
+    Enabled    bool    `json:"enabled"`
+    Threshold  float64 `json:"threshold"`   // cosine similarity, default 0.95
+    TTLSecs    int     `json:"ttl_seconds"` // default 3600
+    MaxEntries int     `json:"max_entries"` // default 1000
+}
+
+type GenerationDefaults struct {
+    SystemPrompt string   `json:"system_prompt"`
+    Temperature  *float64 `json:"temperature"`
+    MaxTokens    *int     `json:"max_tokens"`
+    Stop         []string `json:"stop"`
+}
+
+// LLMDefaults may be overridden per requested model name in Models.
+type LLMDefaults struct {
+    GenerationDefaults
+    Models map[string]GenerationDefaults `json:"models"`
+}
+
+type VoiceChat struct {
+    SystemPrompt string `json:"system_prompt"`
+    // Greeting is spoken (with the default TTS voice) as soon as a
+    // /ws/voice session opens; its audio is synthesized once at startup.
+    Greeting     string `json:"greeting"`
+}
+
+// WebSocket limits left at zero fall back to the server defaults (30s ping,
+// 300s idle timeout, 32 MiB messages, unlimited connections, 1 in-flight chat).
 type WebSocket struct {
-    Enabled    bool   `json:"enabled"`
-    PathPrefix string `json:"path_prefix"`
+    Enabled            bool     `json:"enabled"`
+    PathPrefix         string   `json:"path_prefix"`
+    PingIntervalSecs   int      `json:"ping_interval_seconds"`
+    IdleTimeoutSecs    int      `json:"idle_timeout_seconds"`
+    MaxMessageBytes    int64    `json:"max_message_bytes"`
+    MaxConnections     int      `json:"max_connections"`
+    MaxInflightPerConn int      `json:"max_inflight_per_conn"`
+    // AllowedOrigins restricts browser connections; empty allows any origin.
+    AllowedOrigins     []string `json:"allowed_origins"`
+}
+
+type Sessions struct {
+    Enabled            bool        `json:"enabled"`
+    MaxHistoryMessages int         `json:"max_history_messages"` // default 50
+    MaxHistoryChars    int         `json:"max_history_chars"`    // 0 = no limit
+    Compression        Compression `json:"compression"`
+}
+
+// Compression summarizes older session turns with the LLM once the history
+// passes ThresholdTokens (estimated at ~4 characters per token).
+type Compression struct {
+    Enabled         bool   `json:"enabled"`
+    ThresholdTokens int    `json:"threshold_tokens"` // default 3000
+    KeepRecent      int    `json:"keep_recent"`      // messages kept verbatim, default 6
+    SummaryPrompt   string `json:"summary_prompt"`
+}
+
+// Usage keeps hourly per-model call statistics in <data_dir>/usage.json,
+// readable via GET /admin/usage.
+type Usage struct {
+    Enabled       bool `json:"enabled"`
+    RetentionDays int  `json:"retention_days"` // default 30
+}
+
+// Audit appends one JSON line per API request (caller key fingerprint,
+// route, model, token counts, duration, input hash) to Path, by default
+// <data_dir>/audit.jsonl, rotating it by size.
+type Audit struct {
+    Enabled   bool     `json:"enabled"`
+    Path      string   `json:"path"`
+    MaxSizeMB int      `json:"max_size_mb"` // default 100
+    MaxFiles  int      `json:"max_files"`   // rotated files kept, default 5
+    Redact    []string `json:"redact"`      // entry fields never written, e.g. "key", "remote_addr"
+}
+
+// IOLog logs model inputs and outputs (prompts, replies, transcripts,
+// synthesized text, embedding inputs) to help diagnose quality problems:
+// each as its length, a short SHA-256 and its first MaxChars characters
+// (default 80), with matches of the Redact regexes replaced.
+type IOLog struct {
+    Enabled  bool     `json:"enabled"`
+    MaxChars int      `json:"max_chars"`
+    Redact   []string `json:"redact"`
+}
+
+// Webhooks POST a notification to URLs when an async job (upload
+// transcription, pipeline, chat batch) succeeds or fails, signed with an
+// HMAC of Secret. AllowPerJob lets requests add their own webhook_url;
+// those may not reach loopback or private addresses unless AllowPrivate.
+type Webhooks struct {
+    URLs         []string `json:"urls"`
+    Secret       string   `json:"secret"`
+    AllowPerJob  bool     `json:"allow_per_job"`
+    AllowPrivate bool     `json:"allow_private"`
+}
+
+// Tracing exports request spans to an OpenTelemetry collector over
+// OTLP/HTTP (JSON), e.g. Jaeger or Tempo on http://127.0.0.1:4318.
+type Tracing struct {
+    Endpoint    string            `json:"endpoint"` // empty disables tracing
+    ServiceName string            `json:"service_name"`
+    Headers     map[string]string `json:"headers"`
+}
+
+// Resources samples RAM, CPU and NVIDIA VRAM for GET /admin/status. With
+// Adaptive set, LLM models a local Ollama would have to load are refused
+// when they do not fit and whisper falls back to a smaller model under
+// memory pressure, always leaving ReserveMB free.
+type Resources struct {
+    IntervalSecs int  `json:"interval_seconds"` // default 5
+    Adaptive     bool `json:"adaptive"`
+    ReserveMB    int  `json:"reserve_mb"`       // default 512
+}
+
+// Priorities tune the per-service queues (see max_concurrent) for each
+// request priority. Weight is the share of freed slots a class gets while
+// both wait; MaxQueue defaults to the service's max_queue for interactive
+// calls and four times that for background ones.
+type Priorities struct {
+    Interactive PriorityClass `json:"interactive"` // weight default 4
+    Background  PriorityClass `json:"background"`  // weight default 1
+}
+
+type PriorityClass struct {
+    Weight   int `json:"weight"`
+    MaxQueue int `json:"max_queue"`
 }
 
 type Services struct {
-    STT        STT        `json:"stt"`
-    Embeddings Embeddings `json:"embeddings"`
-    TTS        TTS        `json:"tts"`
+    STT           STT           `json:"stt"`
+    AudioClassify AudioClassify `json:"audio_classify"`
+    Speakers      Speakers      `json:"speakers"`
+    Embeddings    Embeddings    `json:"embeddings"`
+    TTS           TTS           `json:"tts"`
+    LLM           LLM           `json:"llm"`
 }
 
 type Config struct {
-    Server    Server    `json:"server"`
-    Services  Services  `json:"services"`
-    WebSocket WebSocket `json:"websocket"`
-    TestUI    TestUI    `json:"test_ui"`
+    Server     Server              `json:"server"`
+    Services   Services            `json:"services"`
+    Priorities Priorities          `json:"priorities"`
+    WebSocket  WebSocket           `json:"websocket"`
+    TestUI     TestUI              `json:"test_ui"`
+    Uploads    Uploads             `json:"uploads"`
+    VoiceChat  VoiceChat           `json:"voice_chat"`
+    Sessions   Sessions            `json:"sessions"`
+    Usage      Usage               `json:"usage"`
+    Tracing    Tracing             `json:"tracing"`
+    Audit      Audit               `json:"audit"`
+    IOLog      IOLog               `json:"io_log"`
+    Webhooks   Webhooks            `json:"webhooks"`
+    Resources  Resources           `json:"resources"`
+    ONNX       ONNX                `json:"onnx"`
+    Pipelines  map[string]Pipeline `json:"pipelines"`
+    Prompts    map[string]Prompt   `json:"prompts"`
+    Tools      Tools               `json:"tools"`
+    MCP        MCP                 `json:"mcp"`
+    Tenants    Tenants             `json:"tenants"`
+    ReadOnly   ReadOnly            `json:"read_only"`
 }
 
 func Load(path string) (Config, error) {
@@ -50,15 +404,140 @@ func Load(path string) (Config, error) {
     b, err := os.ReadFile(path)
     if err != nil { return c, fmt.Errorf("read config: %w", err) }
     if err := json.Unmarshal(b, &c); err != nil { return c, fmt.Errorf("parse config: %w", err) }
+    if err := checkBackends(c); err != nil { return c, err }
+    return withDefaults(c), nil
+}
+
+// checkBackends rejects services.*.backend values other than "" and "mock",
+// and "remote" for the LLM, and TTS engines other than piper and kokoro.
+func checkBackends(c Config) error {
+    if e := c.Services.TTS.Engine; e != "" && e != "piper" && e != "kokoro" { return fmt.Errorf("services.tts.engine: unknown engine %q (supported: piper, kokoro)", e) }
+    if b := c.Services.LLM.Backend; b != "" && b != "mock" && b != "remote" { return fmt.Errorf("services.llm.backend: unknown backend %q (supported: mock, remote)", b) }
+    for name, b := range map[string]string{"stt": c.Services.STT.Backend, "embeddings": c.Services.Embeddings.Backend, "tts": c.Services.TTS.Backend} {
+        if b != "" && b != "mock" { return fmt.Errorf("services.%s.backend: unknown backend %q (supported: mock)", name, b) }
+    }
+    return nil
+}
+
+// Default is the configuration used when no config file exists.
+func Default() Config { return withDefaults(Config{}) }
+
+func withDefaults(c Config) Config {
     if c.Server.Host == "" { c.Server.Host = "127.0.0.1" }
     if c.Server.Port == 0 { c.Server.Port = 8080 }
+    if c.Server.DrainTimeoutSecs == 0 { c.Server.DrainTimeoutSecs = 30 }
+    if c.Server.ScratchMaxAgeMins == 0 { c.Server.ScratchMaxAgeMins = 60 }
+    if c.Server.CompressMinBytes == 0 { c.Server.CompressMinBytes = 1024 }
+    if c.Server.ReadHeaderTimeoutSecs == 0 { c.Server.ReadHeaderTimeoutSecs = 10 }
+    if c.Server.ReadTimeoutSecs == 0 { c.Server.ReadTimeoutSecs = 300 }
+    if c.Server.WriteTimeoutSecs == 0 { c.Server.WriteTimeoutSecs = 60 }
+    if c.Server.IdleTimeoutSecs == 0 { c.Server.IdleTimeoutSecs = 120 }
     if c.WebSocket.PathPrefix == "" { c.WebSocket.PathPrefix = "/ws" }
     if c.Services.STT.Model == "" { c.Services.STT.Model = "base" }
+    if c.Services.STT.SplitAfterMins == 0 { c.Services.STT.SplitAfterMins = 30 }
+    if c.Services.STT.ChunkMins == 0 { c.Services.STT.ChunkMins = 10 }
     if c.Services.Embeddings.Model == "" { c.Services.Embeddings.Model = "all-MiniLM-L6-v2" }
-    if c.Services.TTS.Voice == "" { c.Services.TTS.Voice = "en_US-amy-medium" }
-    return c, nil
+    if c.Services.STT.MaxAudioMins == 0 { c.Services.STT.MaxAudioMins = 180 }
+    if c.Services.Embeddings.MaxInputs == 0 { c.Services.Embeddings.MaxInputs = 2048 }
+    if c.Services.TTS.MaxChars == 0 { c.Services.TTS.MaxChars = 4096 }
+    if c.Services.LLM.MaxOutputTokens == 0 { c.Services.LLM.MaxOutputTokens = 4096 }
+    if c.Sessions.MaxHistoryMessages == 0 { c.Sessions.MaxHistoryMessages = 50 }
+    if c.Sessions.Compression.ThresholdTokens == 0 { c.Sessions.Compression.ThresholdTokens = 3000 }
+    if c.Sessions.Compression.KeepRecent == 0 { c.Sessions.Compression.KeepRecent = 6 }
+    if c.Usage.RetentionDays == 0 { c.Usage.RetentionDays = 30 }
+    if c.Resources.IntervalSecs == 0 { c.Resources.IntervalSecs = 5 }
+    if c.Resources.ReserveMB == 0 { c.Resources.ReserveMB = 512 }
+    if c.Services.LLM.URL == "" { c.Services.LLM.URL = "http://127.0.0.1:11434/v1" }
+    if c.VoiceChat.SystemPrompt == "" { c.VoiceChat.SystemPrompt = "You are a helpful voice assistant. Keep replies short and conversational; they will be spoken aloud." }
+    if c.Services.TTS.Engine == "" { c.Services.TTS.Engine = "piper" }
+    if c.Services.TTS.Voice == "" {
+        c.Services.TTS.Voice = "en_US-amy-medium"
+        if c.Services.TTS.Engine == "kokoro" { c.Services.TTS.Voice = "af_heart" }
+    }
+    return c
+}
+
+// Tools are run by the server for chat completions in agent mode: the
+// Builtin ones by name ("time", "calculator") and Webhooks, which receive
+// the arguments as a JSON POST and answer with the result.
+type Tools struct {
+    MaxSteps int                    `json:"max_steps"` // model calls per request, default 8
+    Builtin  []string               `json:"builtin"`
+    Webhooks map[string]ToolWebhook `json:"webhooks"`
+    // Search enables /v1/tools/search; list "web_search" in Builtin to let
+    // the model search too.
+    Search   Search                 `json:"search"`
+}
+
+type Search struct {
+    Provider    string `json:"provider"`        // searxng | brave | tavily; empty disables search
+    URL         string `json:"url"`             // required for searxng
+    APIKey      string `json:"api_key"`         // brave and tavily
+    MaxResults  int    `json:"max_results"`     // default 5
+    TimeoutSecs int    `json:"timeout_seconds"` // default 15
+}
+
+type ToolWebhook struct {
+    Description string            `json:"description"`
+    URL         string            `json:"url"`
+    Parameters  json.RawMessage   `json:"parameters"` // JSON Schema of the arguments
+    Headers     map[string]string `json:"headers"`
+    TimeoutSecs int               `json:"timeout_seconds"` // default 30
+}
+
+// Tenants keep the sessions, audit logs and cached replies of applications
+// sharing the server apart. Keys maps API keys to tenant names; they are
+// accepted like server.api_keys. Header trusts a request header (e.g.
+// X-Tenant-ID) to name the tenant of other requests; only set it behind a
+// proxy that overwrites the header.
+type Tenants struct {
+    Keys   map[string]string `json:"keys"`
+    Header string            `json:"header"`
+}
+
+// ReadOnly locks parts of a shipped instance: they keep working but cannot
+// be changed through the API.
+type ReadOnly struct {
+    Downloads bool `json:"downloads"` // only installed models and binaries are used; nothing is fetched
+    Sessions  bool `json:"sessions"`  // sessions are readable and usable as chat context only
+    Speakers  bool `json:"speakers"`  // no enrollment or deletion
+    Admin     bool `json:"admin"`     // /admin/prompts and /admin/lora are readable only
+}
+
+// MCP serves the Model Context Protocol over HTTP at /mcp (streamable) and
+// /mcp/sse. `gollmcore mcp` serves stdio regardless of this setting.
+type MCP struct {
+    Enabled bool `json:"enabled"`
 }
 
 type TestUI struct {
     Enabled bool `json:"enabled"`
 }
+
+type Uploads struct {
+    Sniff        bool     `json:"sniff"`
+    AllowedTypes []string `json:"allowed_types"`
+    ScanCommand  []string `json:"scan_command"` // e.g., ["clamdscan", "--no-summary"]
+    // Resumable uploads (/v1/uploads) are stored under <data_dir>/uploads.
+    MaxResumableMB int    `json:"max_resumable_mb"` // default 4096
+    ExpiryHours    int    `json:"expiry_hours"`     // default 24
+}
+
+type PipelineStep struct {
+    Type   string `json:"type"` // transcribe | chat | summarize | synthesize | embed
+    Model  string `json:"model"`
+    Voice  string `json:"voice"`
+    Prompt string `json:"prompt"`
+}
+
+type Pipeline struct {
+    Steps []PipelineStep `json:"steps"`
+}
+
+// Prompt is a named template; {{name}} placeholders are filled from the
+// request's prompt_variables.
+type Prompt struct {
+    Description string `json:"description"`
+    Role        string `json:"role"` // system (default) | user
+    Template    string `json:"template"`
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+    "fmt"
+    "net/url"
+    "sort"
+    "strings"
+)
+
+// mask replaces a secret in Masked.
+const mask = "********"
+
+// Masked returns a copy of c that is safe to log: API keys, the webhook
+// secret, header values and URL query values are replaced by a mask and
+// URL passwords are dropped. Tenant keys become key1, key2, ... in key order.
+func (c Config) Masked() Config {
+    c.Server.APIKeys = maskAll(c.Server.APIKeys)
+    c.Server.AdminKeys = maskAll(c.Server.AdminKeys)
+
+    l := &c.Services.LLM
+    l.URL, l.VisionURL, l.APIKey = maskURL(l.URL), maskURL(l.VisionURL), maskOne(l.APIKey)
+    l.Upstreams = append([]LLMUpstream(nil), l.Upstreams...)
+    for i := range l.Upstreams {
+        l.Upstreams[i].URL, l.Upstreams[i].APIKey = maskURL(l.Upstreams[i].URL), maskOne(l.Upstreams[i].APIKey)
+    }
+    l.Fallback = maskFallback(l.Fallback)
+    l.Failover = append([]LLMFallback(nil), l.Failover...)
+    for i := range l.Failover { l.Failover[i] = maskFallback(l.Failover[i]) }
+
+    c.Webhooks.Secret = maskOne(c.Webhooks.Secret)
+    if c.Webhooks.URLs != nil {
+        urls := make([]string, len(c.Webhooks.URLs))
+        for i, u := range c.Webhooks.URLs { urls[i] = maskURL(u) }
+        c.Webhooks.URLs = urls
+    }
+
+    c.Tracing.Endpoint, c.Tracing.Headers = maskURL(c.Tracing.Endpoint), maskValues(c.Tracing.Headers)
+    c.Tools.Search.URL, c.Tools.Search.APIKey = maskURL(c.Tools.Search.URL), maskOne(c.Tools.Search.APIKey)
+    if c.Tools.Webhooks != nil {
+        hooks := make(map[string]ToolWebhook, len(c.Tools.Webhooks))
+        for name, h := range c.Tools.Webhooks {
+            h.URL, h.Headers = maskURL(h.URL), maskValues(h.Headers)
+            hooks[name] = h
+        }
+        c.Tools.Webhooks = hooks
+    }
+
+    if c.Tenants.Keys != nil {
+        keys := make([]string, 0, len(c.Tenants.Keys))
+        for k := range c.Tenants.Keys { keys = append(keys, k) }
+        sort.Strings(keys)
+        tenants := make(map[string]string, len(keys))
+        for i, k := range keys { tenants[fmt.Sprintf("key%d", i+1)] = c.Tenants.Keys[k] }
+        c.Tenants.Keys = tenants
+    }
+    return c
+}
+
+func maskOne(s string) string {
+    if s == "" { return "" }
+    return mask
+}
+
+func maskAll(ss []string) []string {
+    if ss == nil { return nil }
+    out := make([]string, len(ss))
+    for i, s := range ss { out[i] = maskOne(s) }
+    return out
+}
+
+func maskValues(m map[string]string) map[string]string {
+    if m == nil { return nil }
+    out := make(map[string]string, len(m))
+    for k, v := range m { out[k] = maskOne(v) }
+    return out
+}
+
+func maskFallback(f LLMFallback) LLMFallback {
+    f.URL, f.APIKey = maskURL(f.URL), maskOne(f.APIKey)
+    return f
+}
+
+// maskURL keeps the scheme, host and path of raw, which are what tell
+// servers apart, drops its password and masks its query values.
+func maskURL(raw string) string {
+    if raw == "" { return "" }
+    u, err := url.Parse(raw)
+    if err != nil { return mask }
+    if _, ok := u.User.Password(); ok { u.User = url.User(u.User.Username()) }
+    if u.RawQuery != "" {
+        var names []string
+        for k := range u.Query() { names = append(names, url.QueryEscape(k)+"="+mask) }
+        sort.Strings(names)
+        u.RawQuery = strings.Join(names, "&")
+    }
+    return u.String()
+}
@@ -0,0 +1,47 @@
+// Package dsp holds the small signal-processing helpers shared by the audio
+// services.
+package dsp
+
+import (
+    "math"
+    "math/cmplx"
+)
+
+// FFT is an in-place radix-2 FFT; len(a) must be a power of two.
+func FFT(a []complex128) {
+    n := len(a)
+    for i, j := 1, 0; i < n; i++ {
+        bit := n >> 1
+        for ; j&bit != 0; bit >>= 1 { j ^= bit }
+        j ^= bit
+        if i < j { a[i], a[j] = a[j], a[i] }
+    }
+    for size := 2; size <= n; size <<= 1 {
+        step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+        for start := 0; start < n; start += size {
+            tw := complex(1, 0)
+            for k := 0; k < size/2; k++ {
+                u, v := a[start+k], a[start+k+size/2]*tw
+                a[start+k], a[start+k+size/2] = u+v, u-v
+                tw *= step
+            }
+        }
+    }
+}
+
+// Resample converts x from one sample rate to another by linear
+// interpolation.
+func Resample(x []float32, rate, to int) []float32 {
+    if rate == to || len(x) == 0 { return x }
+    n := int(int64(len(x)) * int64(to) / int64(rate))
+    out := make([]float32, n)
+    step := float64(rate) / float64(to)
+    for i := range out {
+        pos := float64(i) * step
+        j := int(pos)
+        if j+1 >= len(x) { out[i] = x[len(x)-1]; continue }
+        f := float32(pos - float64(j))
+        out[i] = x[j]*(1-f) + x[j+1]*f
+    }
+    return out
+}
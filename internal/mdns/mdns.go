@@ -0,0 +1,251 @@
+// Package mdns advertises a service on the local network with multicast
+// DNS / DNS-SD (RFC 6762, 6763), so clients can find the server without
+// configuration. It answers PTR, SRV, TXT and A queries for one instance
+// and announces itself on start and with a goodbye on stop.
+package mdns
+
+import (
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "strings"
+    "time"
+)
+
+const (
+    typeA   = 1
+    typePTR = 12
+    typeTXT = 16
+    typeSRV = 33
+    typeANY = 255
+
+    classIN    = 1
+    cacheFlush = 0x8000
+    unicastQ   = 0x8000
+
+    defaultTTL = 120
+)
+
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Service describes the instance to advertise.
+type Service struct {
+    Instance string   // e.g. "gollmcore on studio"
+    Type     string   // e.g. "_gollmcore._tcp"
+    Host     string   // host name without ".local"
+    Port     int
+    IPs      []net.IP // IPv4 addresses the service is reachable on
+    TXT      []string // key=value pairs
+}
+
+// Responder answers queries for one Service.
+type Responder struct {
+    svc Service
+}
+
+// New validates s and returns a Responder for it.
+func New(s Service) (*Responder, error) {
+    if s.Instance == "" || s.Type == "" || s.Host == "" || s.Port <= 0 { return nil, errors.New("mdns: instance, type, host and port are required") }
+    var v4 []net.IP
+    for _, ip := range s.IPs {
+        if ip4 := ip.To4(); ip4 != nil { v4 = append(v4, ip4) }
+    }
+    if len(v4) == 0 { return nil, errors.New("mdns: no IPv4 address to advertise") }
+    s.IPs = v4
+    // Only the first label of the host name is used under .local.
+    s.Host, _, _ = strings.Cut(s.Host, ".")
+    return &Responder{svc: s}, nil
+}
+
+// Names are label lists: the instance label may itself contain dots.
+func (r *Responder) serviceName() []string  { return append(splitName(r.svc.Type), "local") }
+func (r *Responder) instanceName() []string { return append([]string{r.svc.Instance}, r.serviceName()...) }
+func (r *Responder) hostName() []string     { return []string{r.svc.Host, "local"} }
+
+var metaQuery = []string{"_services", "_dns-sd", "_udp", "local"}
+
+// Run joins the multicast group, announces the service and answers queries
+// until ctx is done, then sends a goodbye.
+func (r *Responder) Run(ctx context.Context) error {
+    conn, err := net.ListenMulticastUDP("udp4", nil, groupAddr)
+    if err != nil { return fmt.Errorf("mdns: %w", err) }
+    defer conn.Close()
+    go func() {
+        <-ctx.Done()
+        _, _ = conn.WriteToUDP(r.response(0, nil, 0), groupAddr)
+        conn.Close()
+    }()
+    // Announce twice, one second apart, as RFC 6762 §8.3 recommends.
+    for i := 0; i < 2; i++ {
+        if i > 0 { time.Sleep(time.Second) }
+        if _, err := conn.WriteToUDP(r.response(0, nil, defaultTTL), groupAddr); err != nil { log.Printf("mdns: announce: %v", err) }
+    }
+    buf := make([]byte, 9000)
+    for {
+        n, src, err := conn.ReadFromUDP(buf)
+        if err != nil {
+            if ctx.Err() != nil { return nil }
+            return fmt.Errorf("mdns: %w", err)
+        }
+        resp, unicast := r.Respond(buf[:n])
+        if resp == nil { continue }
+        // Legacy (non-5353) queriers and QU questions get a unicast reply.
+        dst := groupAddr
+        if unicast || src.Port != groupAddr.Port { dst = src }
+        _, _ = conn.WriteToUDP(resp, dst)
+    }
+}
+
+// Respond returns the reply to an mDNS query packet, or nil when the query
+// does not concern this service. unicast reports whether the querier asked
+// for a unicast response.
+func (r *Responder) Respond(packet []byte) (resp []byte, unicast bool) {
+    if len(packet) < 12 { return nil, false }
+    id := binary.BigEndian.Uint16(packet[0:2])
+    flags := binary.BigEndian.Uint16(packet[2:4])
+    if flags&0x8000 != 0 { return nil, false } // a response, not a query
+    qd := int(binary.BigEndian.Uint16(packet[4:6]))
+    off := 12
+    match := false
+    var questions [][]byte
+    for i := 0; i < qd; i++ {
+        name, next, err := readName(packet, off)
+        if err != nil || next+4 > len(packet) { return nil, false }
+        qtype := binary.BigEndian.Uint16(packet[next : next+2])
+        qclass := binary.BigEndian.Uint16(packet[next+2 : next+4])
+        questions = append(questions, packet[off:next+4])
+        off = next + 4
+        if qclass&unicastQ != 0 { unicast = true }
+        if r.answers(name, qtype) { match = true }
+    }
+    if !match { return nil, false }
+    // Legacy unicast queries expect the id and question echoed back.
+    var echo [][]byte
+    if unicast || id != 0 { echo = questions }
+    return r.response(id, echo, defaultTTL), unicast
+}
+
+func (r *Responder) answers(name []string, qtype uint16) bool {
+    all := qtype == typeANY
+    switch {
+    case sameName(name, metaQuery), sameName(name, r.serviceName()):
+        return all || qtype == typePTR
+    case sameName(name, r.instanceName()):
+        return all || qtype == typeSRV || qtype == typeTXT
+    case sameName(name, r.hostName()):
+        return all || qtype == typeA
+    }
+    return false
+}
+
+func sameName(a, b []string) bool {
+    if len(a) != len(b) { return false }
+    for i := range a {
+        if !strings.EqualFold(a[i], b[i]) { return false }
+    }
+    return true
+}
+
+// response builds a packet carrying the full record set, so one reply
+// resolves the instance. ttl 0 is a goodbye.
+func (r *Responder) response(id uint16, questions [][]byte, ttl uint32) []byte {
+    var b []byte
+    b = binary.BigEndian.AppendUint16(b, id)
+    b = binary.BigEndian.AppendUint16(b, 0x8400) // response, authoritative
+    b = binary.BigEndian.AppendUint16(b, uint16(len(questions)))
+    b = binary.BigEndian.AppendUint16(b, uint16(3+len(r.svc.IPs)))
+    b = binary.BigEndian.AppendUint16(b, 0)
+    b = binary.BigEndian.AppendUint16(b, 0)
+    for _, q := range questions { b = append(b, q...) }
+
+    b = appendRecord(b, r.serviceName(), typePTR, classIN, ttl, appendName(nil, r.instanceName()))
+    srv := binary.BigEndian.AppendUint16(nil, 0) // priority
+    srv = binary.BigEndian.AppendUint16(srv, 0)  // weight
+    srv = binary.BigEndian.AppendUint16(srv, uint16(r.svc.Port))
+    srv = appendName(srv, r.hostName())
+    b = appendRecord(b, r.instanceName(), typeSRV, classIN|cacheFlush, ttl, srv)
+    var txt []byte
+    for _, kv := range r.svc.TXT {
+        if len(kv) > 255 { kv = kv[:255] }
+        txt = append(txt, byte(len(kv)))
+        txt = append(txt, kv...)
+    }
+    if len(txt) == 0 { txt = []byte{0} }
+    b = appendRecord(b, r.instanceName(), typeTXT, classIN|cacheFlush, ttl, txt)
+    for _, ip := range r.svc.IPs {
+        b = appendRecord(b, r.hostName(), typeA, classIN|cacheFlush, ttl, ip)
+    }
+    return b
+}
+
+func appendRecord(b []byte, name []string, rtype, class uint16, ttl uint32, data []byte) []byte {
+    b = appendName(b, name)
+    b = binary.BigEndian.AppendUint16(b, rtype)
+    b = binary.BigEndian.AppendUint16(b, class)
+    b = binary.BigEndian.AppendUint32(b, ttl)
+    b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+    return append(b, data...)
+}
+
+// appendName encodes a name without compression.
+func appendName(b []byte, name []string) []byte {
+    for _, label := range name {
+        if len(label) > 63 { label = label[:63] }
+        b = append(b, byte(len(label)))
+        b = append(b, label...)
+    }
+    return append(b, 0)
+}
+
+func splitName(name string) []string {
+    name = strings.TrimSuffix(name, ".")
+    var labels []string
+    for _, l := range strings.Split(name, ".") {
+        if l != "" { labels = append(labels, l) }
+    }
+    return labels
+}
+
+// readName decodes a possibly compressed name at off and returns its labels
+// and the offset just past it.
+func readName(p []byte, off int) ([]string, int, error) {
+    var labels []string
+    end := -1
+    for hops := 0; hops < 16; {
+        if off >= len(p) { return nil, 0, errors.New("mdns: truncated name") }
+        l := int(p[off])
+        switch {
+        case l == 0:
+            if end < 0 { end = off + 1 }
+            return labels, end, nil
+        case l&0xC0 == 0xC0:
+            if off+1 >= len(p) { return nil, 0, errors.New("mdns: truncated pointer") }
+            if end < 0 { end = off + 2 }
+            off = int(binary.BigEndian.Uint16(p[off:off+2]) & 0x3FFF)
+            hops++
+        default:
+            if off+1+l > len(p) { return nil, 0, errors.New("mdns: truncated label") }
+            labels = append(labels, string(p[off+1:off+1+l]))
+            off += 1 + l
+        }
+    }
+    return nil, 0, errors.New("mdns: too many compression pointers")
+}
+
+// LocalIPv4 lists the non-loopback IPv4 addresses of interfaces that are up
+// and support multicast.
+func LocalIPv4() []net.IP {
+    var ips []net.IP
+    ifaces, _ := net.Interfaces()
+    for _, ifc := range ifaces {
+        if ifc.Flags&net.FlagUp == 0 || ifc.Flags&net.FlagMulticast == 0 || ifc.Flags&net.FlagLoopback != 0 { continue }
+        addrs, _ := ifc.Addrs()
+        for _, a := range addrs {
+            if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil { ips = append(ips, n.IP.To4()) }
+        }
+    }
+    return ips
+}
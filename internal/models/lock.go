@@ -0,0 +1,250 @@
+// Package models downloads model and runtime artifacts and keeps a
+// models.lock manifest of them: the exact URL, upstream revision, size and
+// SHA-256 of every file fetched. With a lock in place, later downloads
+// (on this or another machine) use the pinned URL and must match the
+// recorded checksum, so installs are reproducible.
+package models
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Artifact is one downloaded file.
+type Artifact struct {
+    // Path identifies the file: relative to the data dir as "$DATA/...",
+    // or to the temp dir as "$TMP/...", so locks are portable.
+    Path      string    `json:"path"`
+    // URL is the pinned download location; Source is the URL originally
+    // requested (e.g. a branch rather than a commit), used by update.
+    URL       string    `json:"url"`
+    Source    string    `json:"source,omitempty"`
+    // Version is the upstream revision when known (Hugging Face commit or
+    // ETag), otherwise empty; release URLs carry their version themselves.
+    Version   string    `json:"version,omitempty"`
+    SHA256    string    `json:"sha256"`
+    Size      int64     `json:"size"`
+    FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Lock is the manifest stored at <data_dir>/models.lock.
+type Lock struct {
+    path      string
+    dataDir   string
+    mu        sync.Mutex
+    artifacts map[string]Artifact
+}
+
+type lockFile struct {
+    Version   int        `json:"version"`
+    Artifacts []Artifact `json:"artifacts"`
+}
+
+var (
+    active   *Lock
+    activeMu sync.RWMutex
+)
+
+// Open loads (or starts) the lock for dataDir and makes it the one Fetch
+// records into.
+func Open(dataDir string) (*Lock, error) {
+    l, err := Load(dataDir)
+    if err != nil { return nil, err }
+    activeMu.Lock()
+    active = l
+    activeMu.Unlock()
+    return l, nil
+}
+
+// Close stops recording into l.
+func (l *Lock) Close() {
+    activeMu.Lock()
+    if active == l { active = nil }
+    activeMu.Unlock()
+}
+
+// Load reads <dataDir>/models.lock without activating it.
+func Load(dataDir string) (*Lock, error) {
+    l := &Lock{path: filepath.Join(dataDir, "models.lock"), dataDir: dataDir, artifacts: map[string]Artifact{}}
+    b, err := os.ReadFile(l.path)
+    if errors.Is(err, os.ErrNotExist) { return l, nil }
+    if err != nil { return nil, fmt.Errorf("read models.lock: %w", err) }
+    var f lockFile
+    if err := json.Unmarshal(b, &f); err != nil { return nil, fmt.Errorf("parse models.lock: %w", err) }
+    for _, a := range f.Artifacts { l.artifacts[a.Path] = a }
+    return l, nil
+}
+
+// Artifacts returns the locked artifacts sorted by path.
+func (l *Lock) Artifacts() []Artifact {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    out := make([]Artifact, 0, len(l.artifacts))
+    for _, a := range l.artifacts { out = append(out, a) }
+    sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+    return out
+}
+
+func (l *Lock) get(key string) (Artifact, bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    a, ok := l.artifacts[key]
+    return a, ok
+}
+
+func (l *Lock) put(a Artifact) error {
+    l.mu.Lock()
+    l.artifacts[a.Path] = a
+    l.mu.Unlock()
+    return l.save()
+}
+
+func (l *Lock) save() error {
+    b, err := json.MarshalIndent(lockFile{Version: 1, Artifacts: l.Artifacts()}, "", "  ")
+    if err != nil { return err }
+    tmp := l.path + ".tmp"
+    if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil { return err }
+    return os.Rename(tmp, l.path)
+}
+
+// key makes dst portable across machines.
+func (l *Lock) key(dst string) string {
+    abs, err := filepath.Abs(dst)
+    if err != nil { abs = dst }
+    for _, r := range [][2]string{{"$DATA", l.dataDir}, {"$TMP", os.TempDir()}} {
+        prefix, root := r[0], r[1]
+        if root == "" { continue }
+        if root, err = filepath.Abs(root); err != nil { continue }
+        if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+            return prefix + "/" + filepath.ToSlash(rel)
+        }
+    }
+    return filepath.ToSlash(abs)
+}
+
+// LocalPath resolves an artifact path on this machine.
+func (l *Lock) LocalPath(a Artifact) string {
+    p := a.Path
+    switch {
+    case strings.HasPrefix(p, "$DATA/"):
+        return filepath.Join(l.dataDir, filepath.FromSlash(p[len("$DATA/"):]))
+    case strings.HasPrefix(p, "$TMP/"):
+        return filepath.Join(os.TempDir(), filepath.FromSlash(p[len("$TMP/"):]))
+    }
+    return filepath.FromSlash(p)
+}
+
+// -------- Downloads --------
+
+// ErrChecksum reports a download that does not match models.lock.
+var ErrChecksum = errors.New("checksum does not match models.lock")
+
+// ErrDownloadsDisabled reports a missing file that Fetch may not download.
+var ErrDownloadsDisabled = errors.New("downloads are disabled")
+
+var noDownloads atomic.Bool
+
+// DisableDownloads makes Fetch fail with ErrDownloadsDisabled, so only
+// files already installed are used.
+func DisableDownloads(off bool) { noDownloads.Store(off) }
+
+// Fetch downloads url to dst. When the active lock pins dst, the pinned URL
+// is used instead and the file must match its checksum; otherwise the new
+// file is recorded in the lock.
+func Fetch(url, dst string, timeout time.Duration) error {
+    if noDownloads.Load() { return fmt.Errorf("%s is not installed: %w", filepath.Base(dst), ErrDownloadsDisabled) }
+    activeMu.RLock()
+    l := active
+    activeMu.RUnlock()
+    if l == nil { _, err := download(url, dst, timeout, ""); return err }
+    key := l.key(dst)
+    if pinned, ok := l.get(key); ok {
+        _, err := download(pinned.URL, dst, timeout, pinned.SHA256)
+        if err != nil { return fmt.Errorf("%s: %w", key, err) }
+        return nil
+    }
+    a, err := download(url, dst, timeout, "")
+    if err != nil { return err }
+    a.Path = key
+    return l.put(a)
+}
+
+// Refetch downloads a's source again (for models update), records the new
+// revision and checksum, and keeps the file only if keep is true.
+func (l *Lock) Refetch(a Artifact, keep bool, timeout time.Duration) (Artifact, error) {
+    src := a.Source
+    if src == "" { src = a.URL }
+    dst := l.LocalPath(a)
+    if !keep { dst = filepath.Join(os.TempDir(), fmt.Sprintf("gollmcore-update-%d", time.Now().UnixNano())) }
+    if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil { return a, err }
+    n, err := download(src, dst, timeout, "")
+    if !keep { _ = os.Remove(dst) }
+    if err != nil { return a, err }
+    n.Path = a.Path
+    return n, l.put(n)
+}
+
+// Verify hashes the local copy of a. It returns os.ErrNotExist when the
+// file is absent (e.g. an archive removed after extraction).
+func (l *Lock) Verify(a Artifact) error {
+    f, err := os.Open(l.LocalPath(a))
+    if err != nil { return err }
+    defer f.Close()
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil { return err }
+    if got := hex.EncodeToString(h.Sum(nil)); got != a.SHA256 {
+        return fmt.Errorf("%w: have %s, locked %s", ErrChecksum, got, a.SHA256)
+    }
+    return nil
+}
+
+func download(url, dst string, timeout time.Duration, wantSHA string) (Artifact, error) {
+    req, err := http.NewRequest(http.MethodGet, url, nil)
+    if err != nil { return Artifact{}, err }
+    req.Header.Set("User-Agent", "GoLLMCore/1.0")
+    req.Header.Set("Accept", "application/octet-stream")
+    client := &http.Client{Timeout: timeout}
+    resp, err := client.Do(req)
+    if err != nil { return Artifact{}, err }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 { return Artifact{}, fmt.Errorf("bad status: %s", resp.Status) }
+    tmp := dst + ".part"
+    out, err := os.Create(tmp)
+    if err != nil { return Artifact{}, err }
+    h := sha256.New()
+    pr := newProgressReader(resp.Body, filepath.Base(dst), url, resp.ContentLength)
+    n, err := io.Copy(io.MultiWriter(out, h), pr)
+    out.Close()
+    pr.finish(err)
+    if err != nil { os.Remove(tmp); return Artifact{}, err }
+    sum := hex.EncodeToString(h.Sum(nil))
+    if wantSHA != "" && sum != wantSHA {
+        os.Remove(tmp)
+        return Artifact{}, fmt.Errorf("%w: downloaded %s, locked %s (run `gollmcore models update` to accept the new version)", ErrChecksum, sum, wantSHA)
+    }
+    if err := os.Rename(tmp, dst); err != nil { return Artifact{}, err }
+    a := Artifact{URL: url, SHA256: sum, Size: n, FetchedAt: time.Now().UTC()}
+    // Hugging Face names the commit a branch URL resolved to; pin to it.
+    if commit := resp.Header.Get("X-Repo-Commit"); commit != "" {
+        a.Version = commit
+        if strings.Contains(url, "/resolve/main/") {
+            a.Source = url
+            a.URL = strings.Replace(url, "/resolve/main/", "/resolve/"+commit+"/", 1)
+        }
+    } else if etag := strings.Trim(resp.Header.Get("ETag"), `"`); etag != "" {
+        a.Version = etag
+    }
+    return a, nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+    "io"
+    "sync"
+    "time"
+)
+
+// -------- Progress --------
+//
+// First-use downloads of models and binaries can take minutes. Every
+// download reports its progress to the functions registered with
+// OnProgress: once when it starts, at most every progressInterval while it
+// runs, and once when it ends, so a UI can draw a bar and an ETA.
+
+// progressInterval is the least time between two reports of a download.
+const progressInterval = 500 * time.Millisecond
+
+// Progress is the state of one download.
+type Progress struct {
+    Asset   string  `json:"asset"` // file name, e.g. ggml-base.bin
+    URL     string  `json:"url"`
+    Bytes   int64   `json:"bytes"`
+    Total   int64   `json:"total,omitempty"`   // 0 when the server sends no length
+    Percent float64 `json:"percent,omitempty"` // of Total
+    // ETASecs is the estimated time left at the average rate so far.
+    ETASecs float64 `json:"eta_seconds,omitempty"`
+    Done    bool    `json:"done"`
+    Error   string  `json:"error,omitempty"`
+}
+
+var (
+    progressMu  sync.RWMutex
+    progressFns = map[int]func(Progress){}
+    progressID  int
+)
+
+// OnProgress calls fn with the progress of every download until the
+// returned function is called. fn must not block.
+func OnProgress(fn func(Progress)) (remove func()) {
+    progressMu.Lock()
+    progressID++
+    id := progressID
+    progressFns[id] = fn
+    progressMu.Unlock()
+    return func() {
+        progressMu.Lock()
+        delete(progressFns, id)
+        progressMu.Unlock()
+    }
+}
+
+func reportProgress(p Progress) {
+    progressMu.RLock()
+    defer progressMu.RUnlock()
+    for _, fn := range progressFns { fn(p) }
+}
+
+// progressReader counts the bytes read through it and reports them.
+type progressReader struct {
+    r     io.Reader
+    p     Progress
+    start time.Time
+    last  time.Time
+}
+
+func newProgressReader(r io.Reader, asset, url string, total int64) *progressReader {
+    pr := &progressReader{r: r, p: Progress{Asset: asset, URL: url, Total: max(0, total)}, start: time.Now()}
+    pr.last = pr.start
+    reportProgress(pr.p)
+    return pr
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+    n, err := pr.r.Read(b)
+    pr.p.Bytes += int64(n)
+    if now := time.Now(); now.Sub(pr.last) >= progressInterval {
+        pr.last = now
+        pr.report(now)
+    }
+    return n, err
+}
+
+// finish reports the end of the download, failed when err is set.
+func (pr *progressReader) finish(err error) {
+    pr.p.Done = true
+    if err != nil { pr.p.Error = err.Error() }
+    pr.report(time.Now())
+}
+
+func (pr *progressReader) report(now time.Time) {
+    p := pr.p
+    if p.Total > 0 {
+        p.Percent = float64(int(1000*float64(p.Bytes)/float64(p.Total))) / 10
+        if elapsed := now.Sub(pr.start).Seconds(); p.Bytes > 0 && !p.Done {
+            p.ETASecs = float64(int(10*elapsed*float64(p.Total-p.Bytes)/float64(p.Bytes))) / 10
+        }
+    }
+    reportProgress(p)
+}
@@ -0,0 +1,101 @@
+package onnxrt
+
+import (
+    "fmt"
+    "sync"
+
+    ort "github.com/yalue/onnxruntime_go"
+)
+
+// Options tune an ONNX Runtime session; zero values keep ORT's defaults,
+// which size the thread pools to every core and keep a memory arena.
+type Options struct {
+    // IntraOpThreads parallelizes a single operator, InterOpThreads runs
+    // independent operators side by side.
+    IntraOpThreads    int
+    InterOpThreads    int
+    // MemoryArena false makes ORT return freed buffers to the system
+    // instead of keeping them for the next run: less RAM, a little slower.
+    MemoryArena       *bool
+    // GraphOptimization is disabled, basic, extended or all.
+    GraphOptimization string
+}
+
+var graphLevels = map[string]ort.GraphOptimizationLevel{
+    "disabled": ort.GraphOptimizationLevelDisableAll,
+    "basic":    ort.GraphOptimizationLevelEnableBasic,
+    "extended": ort.GraphOptimizationLevelEnableExtended,
+    "all":      ort.GraphOptimizationLevelEnableAll,
+}
+
+var (
+    defaultsMu sync.Mutex
+    defaults   Options
+)
+
+// Check reports an invalid field of o.
+func (o Options) Check() error {
+    if o.IntraOpThreads < 0 { return fmt.Errorf("intra_op_threads: must not be negative") }
+    if o.InterOpThreads < 0 { return fmt.Errorf("inter_op_threads: must not be negative") }
+    if _, ok := graphLevels[o.GraphOptimization]; o.GraphOptimization != "" && !ok {
+        return fmt.Errorf("graph_optimization: unknown level %q (supported: disabled, basic, extended, all)", o.GraphOptimization)
+    }
+    return nil
+}
+
+// SetDefaults sets the options of every session opened afterwards; fields
+// a service sets itself take precedence.
+func SetDefaults(o Options) error {
+    if err := o.Check(); err != nil { return err }
+    defaultsMu.Lock()
+    defaults = o
+    defaultsMu.Unlock()
+    return nil
+}
+
+// SessionOptions returns ORT options for o laid over the defaults, or nil
+// (ORT's defaults) when neither sets anything. Call it after Init; the
+// caller destroys the result once the session is created.
+func SessionOptions(o Options) (*ort.SessionOptions, error) {
+    if err := o.Check(); err != nil { return nil, err }
+    defaultsMu.Lock()
+    d := defaults
+    defaultsMu.Unlock()
+    if o.IntraOpThreads == 0 { o.IntraOpThreads = d.IntraOpThreads }
+    if o.InterOpThreads == 0 { o.InterOpThreads = d.InterOpThreads }
+    if o.MemoryArena == nil { o.MemoryArena = d.MemoryArena }
+    if o.GraphOptimization == "" { o.GraphOptimization = d.GraphOptimization }
+    if o == (Options{}) { return nil, nil }
+
+    so, err := ort.NewSessionOptions()
+    if err != nil { return nil, err }
+    if err := apply(so, o); err != nil {
+        so.Destroy()
+        return nil, err
+    }
+    return so, nil
+}
+
+func apply(so *ort.SessionOptions, o Options) error {
+    if o.IntraOpThreads > 0 {
+        if err := so.SetIntraOpNumThreads(o.IntraOpThreads); err != nil { return err }
+    }
+    if o.InterOpThreads > 0 {
+        if err := so.SetInterOpNumThreads(o.InterOpThreads); err != nil { return err }
+    }
+    if o.MemoryArena != nil {
+        if err := so.SetCpuMemArena(*o.MemoryArena); err != nil { return err }
+    }
+    if o.GraphOptimization != "" {
+        if err := so.SetGraphOptimizationLevel(graphLevels[o.GraphOptimization]); err != nil { return err }
+    }
+    return nil
+}
+
+// NewSession opens a session for modelPath with SessionOptions(o).
+func NewSession(modelPath string, inputs, outputs []string, o Options) (*ort.DynamicAdvancedSession, error) {
+    so, err := SessionOptions(o)
+    if err != nil { return nil, err }
+    if so != nil { defer so.Destroy() }
+    return ort.NewDynamicAdvancedSession(modelPath, inputs, outputs, so)
+}
@@ -0,0 +1,190 @@
+// Package onnxrt downloads the ONNX Runtime shared library on demand and
+// initializes the process-wide ORT environment shared by all ONNX services.
+package onnxrt
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "compress/gzip"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "runtime"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    ort "github.com/yalue/onnxruntime_go"
+
+    "gollmcore/internal/models"
+)
+
+var (
+    initOnce sync.Once
+    initErr  error
+
+    sessionsMu sync.Mutex
+    sessions   = map[string]int{}
+    onSession  func(name string, opened bool)
+)
+
+// OnSession calls fn whenever a session is opened or closed; fn must not
+// block. It replaces any earlier fn.
+func OnSession(fn func(name string, opened bool)) {
+    sessionsMu.Lock()
+    onSession = fn
+    sessionsMu.Unlock()
+}
+
+// SessionOpened records an ONNX session created for name (e.g. "kokoro").
+func SessionOpened(name string) {
+    sessionsMu.Lock()
+    sessions[name]++
+    fn := onSession
+    sessionsMu.Unlock()
+    if fn != nil { fn(name, true) }
+}
+
+// SessionClosed records that a session for name was destroyed.
+func SessionClosed(name string) {
+    sessionsMu.Lock()
+    if sessions[name]--; sessions[name] <= 0 { delete(sessions, name) }
+    fn := onSession
+    sessionsMu.Unlock()
+    if fn != nil { fn(name, false) }
+}
+
+// Sessions returns the number of live ONNX sessions per name.
+func Sessions() map[string]int {
+    sessionsMu.Lock()
+    defer sessionsMu.Unlock()
+    out := make(map[string]int, len(sessions))
+    for k, v := range sessions { out[k] = v }
+    return out
+}
+
+// Init prepares ONNX Runtime once per process. Later calls return the
+// result of the first attempt.
+func Init() error {
+    initOnce.Do(func() {
+        libPath, err := ensureSharedLib()
+        if err != nil { initErr = fmt.Errorf("onnxruntime lib: %w", err); return }
+        // Point onnxruntime_go to the shared library
+        ort.SetSharedLibraryPath(libPath)
+        initErr = ort.InitializeEnvironment()
+    })
+    return initErr
+}
+
+func ensureSharedLib() (string, error) {
+    baseDir := filepath.Join(os.TempDir(), "onnxruntime")
+    ortVersion := "v1.22.0"
+    versionDir := filepath.Join(baseDir, ortVersion)
+    if err := os.MkdirAll(versionDir, 0o755); err != nil { return "", err }
+    switch runtime.GOOS {
+    case "windows":
+        dll := filepath.Join(versionDir, "onnxruntime.dll")
+        if fileExists(dll) { return dll, nil }
+        urls := []string{
+            "https://github.com/microsoft/onnxruntime/releases/download/"+ortVersion+"/onnxruntime-win-x64-"+strings.TrimPrefix(ortVersion, "v")+".zip",
+        }
+        zipPath := filepath.Join(versionDir, "ort.zip")
+        if err := TryDownload(urls, zipPath, 3, 240*time.Second); err != nil { return "", err }
+        if err := unzipOne(zipPath, versionDir, "onnxruntime.dll"); err != nil { return "", err }
+        return dll, nil
+    case "darwin":
+        dylib := filepath.Join(versionDir, "libonnxruntime.dylib")
+        if fileExists(dylib) { return dylib, nil }
+        // arm64 vs x64 both extract libonnxruntime.dylib
+        urls := []string{
+            "https://github.com/microsoft/onnxruntime/releases/download/"+ortVersion+"/onnxruntime-osx-universal2-"+strings.TrimPrefix(ortVersion, "v")+".tgz",
+            "https://github.com/microsoft/onnxruntime/releases/download/"+ortVersion+"/onnxruntime-osx-arm64-"+strings.TrimPrefix(ortVersion, "v")+".tgz",
+            "https://github.com/microsoft/onnxruntime/releases/download/"+ortVersion+"/onnxruntime-osx-x64-"+strings.TrimPrefix(ortVersion, "v")+".tgz",
+        }
+        tgz := filepath.Join(versionDir, "ort.tgz")
+        if err := TryDownload(urls, tgz, 3, 240*time.Second); err != nil { return "", err }
+        if err := untarSelect(tgz, versionDir, []string{"libonnxruntime.dylib"}); err != nil { return "", err }
+        return dylib, nil
+    case "linux":
+        so := filepath.Join(versionDir, "libonnxruntime.so")
+        if fileExists(so) { return so, nil }
+        urls := []string{
+            "https://github.com/microsoft/onnxruntime/releases/download/"+ortVersion+"/onnxruntime-linux-x64-"+strings.TrimPrefix(ortVersion, "v")+".tgz",
+        }
+        tgz := filepath.Join(versionDir, "ort.tgz")
+        if err := TryDownload(urls, tgz, 3, 240*time.Second); err != nil { return "", err }
+        if err := untarSelect(tgz, versionDir, []string{"libonnxruntime.so"}); err != nil { return "", err }
+        return so, nil
+    default:
+        return "", fmt.Errorf("unsupported platform for ORT: %s", runtime.GOOS)
+    }
+}
+
+// TryDownload fetches the first reachable URL from urls into dst.
+func TryDownload(urls []string, dst string, retries int, timeout time.Duration) error {
+    var last error
+    for i, u := range urls {
+        log.Printf("Downloading: %s (%d/%d)", u, i+1, len(urls))
+        if err := downloadFile(u, dst, timeout); err != nil {
+            last = err
+            continue
+        }
+        return nil
+    }
+    return last
+}
+
+// downloadFile fetches url into dst, pinned and recorded by models.lock.
+func downloadFile(url, dst string, timeout time.Duration) error { return models.Fetch(url, dst, timeout) }
+
+func fileExists(p string) bool { _, err := os.Stat(p); return err == nil }
+
+// unzipOne extracts a specific file from a zip archive to dstDir
+func unzipOne(zipPath, dstDir, wanted string) error {
+    r, err := zip.OpenReader(zipPath)
+    if err != nil { return err }
+    defer r.Close()
+    for _, f := range r.File {
+        if filepath.Base(f.Name) == wanted {
+            rc, err := f.Open(); if err != nil { return err }
+            defer rc.Close()
+            out := filepath.Join(dstDir, wanted)
+            fo, err := os.Create(out); if err != nil { return err }
+            if _, err := io.Copy(fo, rc); err != nil { fo.Close(); return err }
+            fo.Close()
+            if runtime.GOOS != "windows" { _ = os.Chmod(out, 0o755) }
+            return nil
+        }
+    }
+    return fmt.Errorf("file %s not found in zip", wanted)
+}
+
+// untarSelect extracts specific files from a .tgz into dstDir
+func untarSelect(tgzPath, dstDir string, names []string) error {
+    set := make(map[string]bool)
+    for _, n := range names { set[n] = true }
+    f, err := os.Open(tgzPath); if err != nil { return err }
+    defer f.Close()
+    gz, err := gzip.NewReader(f); if err != nil { return err }
+    defer gz.Close()
+    tr := tar.NewReader(gz)
+    for {
+        hdr, err := tr.Next(); if err == io.EOF { break }; if err != nil { return err }
+        base := filepath.Base(hdr.Name)
+        if !set[base] || hdr.FileInfo().IsDir() { continue }
+        out := filepath.Join(dstDir, base)
+        of, err := os.Create(out); if err != nil { return err }
+        if _, err := io.Copy(of, tr); err != nil { of.Close(); return err }
+        of.Close()
+        if runtime.GOOS != "windows" { _ = os.Chmod(out, 0o755) }
+        delete(set, base)
+        if len(set) == 0 { break }
+    }
+    if len(set) > 0 { return fmt.Errorf("missing files: %v", keys(set)) }
+    return nil
+}
+
+func keys(m map[string]bool) []string { ks := make([]string, 0, len(m)); for k := range m { ks = append(ks, k) }; sort.Strings(ks); return ks }
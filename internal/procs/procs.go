@@ -0,0 +1,89 @@
+// Package procs tracks the child processes (whisper, piper, espeak-ng) the
+// services spawn, so the admin diagnostics can list what is running, and
+// ties them to this process so they do not outlive it: on Windows each
+// child is placed in a kill-on-close job object, on Linux it gets a parent
+// death signal, and on Unix systems it runs in its own process group so a
+// kill reaches anything it started.
+package procs
+
+import (
+    "os"
+    "os/exec"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Info describes one running child process.
+type Info struct {
+    PID     int       `json:"pid"`
+    Name    string    `json:"name"`
+    Args    []string  `json:"args"`
+    Started time.Time `json:"started"`
+}
+
+type entry struct {
+    Info
+    proc *os.Process
+}
+
+var (
+    mu      sync.Mutex
+    running = map[int]entry{}
+)
+
+// Run starts cmd, records it under name while it runs and waits for it.
+func Run(name string, cmd *exec.Cmd) error {
+    done, err := Start(name, cmd)
+    if err != nil { return err }
+    defer done()
+    return cmd.Wait()
+}
+
+// Start starts cmd bound to the lifetime of this process and records it
+// under name; call the returned func after Wait.
+func Start(name string, cmd *exec.Cmd) (func(), error) {
+    prepare(cmd)
+    // CommandContext only kills the direct child; take its group with it.
+    if cmd.Cancel != nil { cmd.Cancel = func() error { return kill(cmd.Process) } }
+    if err := cmd.Start(); err != nil { return nil, err }
+    if err := attach(cmd.Process); err != nil {
+        _ = kill(cmd.Process)
+        _ = cmd.Wait()
+        return nil, err
+    }
+    return Track(name, cmd), nil
+}
+
+// Track records an already started cmd; call the returned func after Wait.
+func Track(name string, cmd *exec.Cmd) func() {
+    if cmd.Process == nil { return func() {} }
+    pid := cmd.Process.Pid
+    mu.Lock()
+    running[pid] = entry{Info{PID: pid, Name: name, Args: cmd.Args[1:], Started: time.Now()}, cmd.Process}
+    mu.Unlock()
+    return func() {
+        mu.Lock()
+        delete(running, pid)
+        mu.Unlock()
+    }
+}
+
+// List returns the running child processes, oldest first.
+func List() []Info {
+    mu.Lock()
+    out := make([]Info, 0, len(running))
+    for _, p := range running { out = append(out, p.Info) }
+    mu.Unlock()
+    sort.Slice(out, func(i, j int) bool { return out[i].Started.Before(out[j].Started) })
+    return out
+}
+
+// KillAll kills every tracked child process and returns how many there
+// were. Used on shutdown once the drain timeout has passed.
+func KillAll() int {
+    mu.Lock()
+    defer mu.Unlock()
+    for _, p := range running { _ = kill(p.proc) }
+    return len(running)
+}
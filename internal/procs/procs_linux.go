@@ -0,0 +1,23 @@
+package procs
+
+import (
+    "os"
+    "os/exec"
+    "syscall"
+)
+
+// prepare puts the child in its own process group and has the kernel
+// SIGKILL it when gollmcore dies, even if gollmcore itself is SIGKILLed.
+func prepare(cmd *exec.Cmd) {
+    if cmd.SysProcAttr == nil { cmd.SysProcAttr = &syscall.SysProcAttr{} }
+    cmd.SysProcAttr.Setpgid = true
+    cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+}
+
+func attach(*os.Process) error { return nil }
+
+// kill signals the child's whole process group.
+func kill(p *os.Process) error {
+    if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil { return p.Kill() }
+    return nil
+}
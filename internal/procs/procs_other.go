@@ -0,0 +1,14 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package procs
+
+import (
+    "os"
+    "os/exec"
+)
+
+func prepare(*exec.Cmd) {}
+
+func attach(*os.Process) error { return nil }
+
+func kill(p *os.Process) error { return p.Kill() }
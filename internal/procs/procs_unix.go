@@ -0,0 +1,25 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package procs
+
+import (
+    "os"
+    "os/exec"
+    "syscall"
+)
+
+// prepare puts the child in its own process group. These systems have no
+// parent death signal, so children are reaped by KillAll and context
+// cancellation on shutdown but can outlive a SIGKILLed gollmcore.
+func prepare(cmd *exec.Cmd) {
+    if cmd.SysProcAttr == nil { cmd.SysProcAttr = &syscall.SysProcAttr{} }
+    cmd.SysProcAttr.Setpgid = true
+}
+
+func attach(*os.Process) error { return nil }
+
+// kill signals the child's whole process group.
+func kill(p *os.Process) error {
+    if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil { return p.Kill() }
+    return nil
+}
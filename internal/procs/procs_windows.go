@@ -0,0 +1,54 @@
+package procs
+
+import (
+    "fmt"
+    "os"
+    "os/exec"
+    "sync"
+    "unsafe"
+
+    "golang.org/x/sys/windows"
+)
+
+// Every child is assigned to one job object created with
+// KILL_ON_JOB_CLOSE. The only handle to it is held by this process, so
+// when gollmcore exits for any reason (including TerminateProcess from
+// Task Manager) Windows closes the handle and kills the whole job,
+// grandchildren included.
+var (
+    jobOnce sync.Once
+    job     windows.Handle
+    jobErr  error
+)
+
+func childJob() (windows.Handle, error) {
+    jobOnce.Do(func() {
+        h, err := windows.CreateJobObject(nil, nil)
+        if err != nil { jobErr = fmt.Errorf("create job object: %w", err); return }
+        info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+            BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE},
+        }
+        if _, err := windows.SetInformationJobObject(h, windows.JobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+            _ = windows.CloseHandle(h)
+            jobErr = fmt.Errorf("configure job object: %w", err)
+            return
+        }
+        job = h
+    })
+    return job, jobErr
+}
+
+func prepare(*exec.Cmd) {}
+
+// attach assigns a started child to the kill-on-close job.
+func attach(p *os.Process) error {
+    j, err := childJob()
+    if err != nil { return err }
+    h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.Pid))
+    if err != nil { return fmt.Errorf("open child process: %w", err) }
+    defer windows.CloseHandle(h)
+    if err := windows.AssignProcessToJobObject(j, h); err != nil { return fmt.Errorf("assign child to job: %w", err) }
+    return nil
+}
+
+func kill(p *os.Process) error { return p.Kill() }
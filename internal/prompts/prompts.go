@@ -0,0 +1,135 @@
+// Package prompts keeps named prompt templates with {{variable}}
+// placeholders that clients reference by name instead of shipping prompts.
+package prompts
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+)
+
+var ErrNotFound = errors.New("prompt template not found")
+
+type Template struct {
+    Name        string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Role        string `json:"role,omitempty"` // system (default) | user
+    Template    string `json:"template"`
+}
+
+// Store holds templates from the config plus ones added at runtime, which
+// are saved to path so they survive restarts. Runtime entries override
+// config entries of the same name.
+type Store struct {
+    path      string
+    mu        sync.RWMutex
+    templates map[string]Template
+}
+
+func New(path string, fromConfig map[string]Template) (*Store, error) {
+    s := &Store{path: path, templates: map[string]Template{}}
+    for name, t := range fromConfig {
+        t.Name = name
+        if err := validate(t); err != nil { return nil, err }
+        s.templates[name] = t
+    }
+    if path == "" { return s, nil }
+    b, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) { return s, nil }
+    if err != nil { return nil, fmt.Errorf("read prompts: %w", err) }
+    var saved map[string]Template
+    if err := json.Unmarshal(b, &saved); err != nil { return nil, fmt.Errorf("parse prompts: %w", err) }
+    for name, t := range saved { t.Name = name; s.templates[name] = t }
+    return s, nil
+}
+
+func (s *Store) Get(name string) (Template, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    t, ok := s.templates[name]
+    if !ok { return Template{}, ErrNotFound }
+    return t, nil
+}
+
+// List returns all templates sorted by name.
+func (s *Store) List() []Template {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    out := make([]Template, 0, len(s.templates))
+    for _, t := range s.templates { out = append(out, t) }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out
+}
+
+func (s *Store) Put(t Template) error {
+    if err := validate(t); err != nil { return err }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    prev, had := s.templates[t.Name]
+    s.templates[t.Name] = t
+    if err := s.saveLocked(); err != nil {
+        if had { s.templates[t.Name] = prev } else { delete(s.templates, t.Name) }
+        return err
+    }
+    return nil
+}
+
+func (s *Store) Delete(name string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    prev, ok := s.templates[name]
+    if !ok { return ErrNotFound }
+    delete(s.templates, name)
+    if err := s.saveLocked(); err != nil { s.templates[name] = prev; return err }
+    return nil
+}
+
+func (s *Store) saveLocked() error {
+    if s.path == "" { return nil }
+    b, err := json.MarshalIndent(s.templates, "", "  ")
+    if err != nil { return err }
+    tmp := s.path + ".tmp"
+    if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil { return err }
+    if err := os.WriteFile(tmp, b, 0o644); err != nil { return err }
+    return os.Rename(tmp, s.path)
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func validate(t Template) error {
+    if !validName.MatchString(t.Name) { return fmt.Errorf("invalid prompt name %q", t.Name) }
+    if strings.TrimSpace(t.Template) == "" { return fmt.Errorf("prompt %q has an empty template", t.Name) }
+    if t.Role != "" && t.Role != "system" && t.Role != "user" { return fmt.Errorf("prompt %q: role must be system or user", t.Name) }
+    return nil
+}
+
+// Variables lists the placeholder names used by a template.
+func (t Template) Variables() []string {
+    seen := map[string]bool{}
+    var out []string
+    for _, m := range placeholder.FindAllStringSubmatch(t.Template, -1) {
+        if !seen[m[1]] { seen[m[1]] = true; out = append(out, m[1]) }
+    }
+    return out
+}
+
+// Render substitutes vars into the template. Every placeholder must have a value.
+func (t Template) Render(vars map[string]string) (string, error) {
+    var missing []string
+    out := placeholder.ReplaceAllStringFunc(t.Template, func(m string) string {
+        name := placeholder.FindStringSubmatch(m)[1]
+        v, ok := vars[name]
+        if !ok { missing = append(missing, name) }
+        return v
+    })
+    if len(missing) > 0 { return "", fmt.Errorf("prompt %q: missing variables: %s", t.Name, strings.Join(missing, ", ")) }
+    return out, nil
+}
@@ -0,0 +1,5 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package resources
+
+func Disk(path string) (total, free uint64, ok bool) { return 0, 0, false }
@@ -0,0 +1,13 @@
+//go:build linux || darwin || freebsd
+
+package resources
+
+import "golang.org/x/sys/unix"
+
+// Disk reports the size of the filesystem holding path and the space
+// available to unprivileged users.
+func Disk(path string) (total, free uint64, ok bool) {
+    var st unix.Statfs_t
+    if err := unix.Statfs(path, &st); err != nil { return 0, 0, false }
+    return uint64(st.Blocks) * uint64(st.Bsize), uint64(st.Bavail) * uint64(st.Bsize), true
+}
@@ -0,0 +1,12 @@
+package resources
+
+import "golang.org/x/sys/windows"
+
+// Disk reports the size of the volume holding path and the space
+// available to the current user.
+func Disk(path string) (total, free uint64, ok bool) {
+    p, err := windows.UTF16PtrFromString(path)
+    if err != nil { return 0, 0, false }
+    if err := windows.GetDiskFreeSpaceEx(p, &free, &total, nil); err != nil { return 0, 0, false }
+    return total, free, true
+}
@@ -0,0 +1,131 @@
+// Package resources samples host memory, CPU load and NVIDIA GPU memory in
+// the background so the server can refuse work that will not fit and fall
+// back to smaller models under memory pressure.
+package resources
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "os/exec"
+    "runtime"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// GPU is one NVIDIA device as reported by nvidia-smi.
+type GPU struct {
+    Name        string `json:"name"`
+    MemoryTotal uint64 `json:"memory_total_bytes"`
+    MemoryUsed  uint64 `json:"memory_used_bytes"`
+}
+
+// Reading is one sample of the host's resources. Zero memory values mean
+// the platform could not report them; CPUPercent is -1 until two samples
+// have been taken or when unsupported.
+type Reading struct {
+    Time            time.Time `json:"time"`
+    MemoryTotal     uint64    `json:"memory_total_bytes"`
+    MemoryAvailable uint64    `json:"memory_available_bytes"`
+    CPUPercent      float64   `json:"cpu_percent"`
+    NumCPU          int       `json:"num_cpu"`
+    GPUs            []GPU     `json:"gpus,omitempty"`
+}
+
+// Known reports whether the memory figures are usable.
+func (r Reading) Known() bool { return r.MemoryTotal > 0 }
+
+// VRAMFree sums the free memory of all GPUs.
+func (r Reading) VRAMFree() uint64 {
+    var n uint64
+    for _, g := range r.GPUs {
+        if g.MemoryTotal > g.MemoryUsed { n += g.MemoryTotal - g.MemoryUsed }
+    }
+    return n
+}
+
+// Monitor keeps the latest Reading.
+type Monitor struct {
+    interval time.Duration
+    smi      string // nvidia-smi path, empty when not installed
+
+    mu       sync.Mutex
+    last     Reading
+    prevIdle uint64
+    prevAll  uint64
+}
+
+// New takes a first sample and returns a monitor that refreshes every
+// interval (default 5s) once Run is started.
+func New(interval time.Duration) *Monitor {
+    if interval <= 0 { interval = 5 * time.Second }
+    m := &Monitor{interval: interval}
+    m.smi, _ = exec.LookPath("nvidia-smi")
+    m.sample()
+    return m
+}
+
+// Run refreshes the reading until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+    t := time.NewTicker(m.interval)
+    defer t.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-t.C:
+            m.sample()
+        }
+    }
+}
+
+// Read returns the latest reading. A nil monitor returns an empty one.
+func (m *Monitor) Read() Reading {
+    if m == nil { return Reading{CPUPercent: -1} }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    r := m.last
+    r.GPUs = append([]GPU(nil), r.GPUs...)
+    return r
+}
+
+func (m *Monitor) sample() {
+    r := Reading{Time: time.Now(), NumCPU: runtime.NumCPU(), CPUPercent: -1}
+    r.MemoryTotal, r.MemoryAvailable = memory()
+    if m.smi != "" { r.GPUs = queryGPUs(m.smi) }
+    idle, all, ok := cpuTimes()
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if ok && m.prevAll > 0 && all > m.prevAll {
+        busy := float64((all-m.prevAll)-(idle-m.prevIdle)) / float64(all-m.prevAll)
+        r.CPUPercent = float64(int(busy*1000+0.5)) / 10
+    }
+    if ok { m.prevIdle, m.prevAll = idle, all }
+    m.last = r
+}
+
+func queryGPUs(smi string) []GPU {
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+    defer cancel()
+    out, err := exec.CommandContext(ctx, smi, "--query-gpu=name,memory.total,memory.used", "--format=csv,noheader,nounits").Output()
+    if err != nil { return nil }
+    var gpus []GPU
+    sc := bufio.NewScanner(bytes.NewReader(out))
+    for sc.Scan() {
+        f := strings.Split(sc.Text(), ",")
+        if len(f) != 3 { continue }
+        total, _ := strconv.ParseUint(strings.TrimSpace(f[1]), 10, 64)
+        used, _ := strconv.ParseUint(strings.TrimSpace(f[2]), 10, 64)
+        gpus = append(gpus, GPU{Name: strings.TrimSpace(f[0]), MemoryTotal: total << 20, MemoryUsed: used << 20})
+    }
+    return gpus
+}
+
+// FormatBytes renders n in GiB (or MiB below 1 GiB) for error messages.
+func FormatBytes(n uint64) string {
+    if n < 1<<30 { return strconv.FormatUint(n>>20, 10) + " MiB" }
+    return strconv.FormatFloat(float64(n)/(1<<30), 'f', 1, 64) + " GiB"
+}
@@ -0,0 +1,37 @@
+package resources
+
+import (
+    "context"
+    "encoding/binary"
+    "os/exec"
+    "regexp"
+    "strconv"
+    "syscall"
+    "time"
+)
+
+var vmStatLine = regexp.MustCompile(`Pages (free|inactive|speculative|purgeable):\s+(\d+)`)
+var vmStatPage = regexp.MustCompile(`page size of (\d+) bytes`)
+
+// memory uses hw.memsize for the total and counts free, inactive,
+// speculative and purgeable pages from vm_stat as available.
+func memory() (total, available uint64) {
+    if s, err := syscall.Sysctl("hw.memsize"); err == nil {
+        b := []byte(s)
+        for len(b) < 8 { b = append(b, 0) } // Sysctl strips the trailing NUL byte
+        total = binary.LittleEndian.Uint64(b[:8])
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+    defer cancel()
+    out, err := exec.CommandContext(ctx, "vm_stat").Output()
+    if err != nil { return total, 0 }
+    page := uint64(4096)
+    if m := vmStatPage.FindSubmatch(out); m != nil { page, _ = strconv.ParseUint(string(m[1]), 10, 64) }
+    for _, m := range vmStatLine.FindAllSubmatch(out, -1) {
+        n, _ := strconv.ParseUint(string(m[2]), 10, 64)
+        available += n * page
+    }
+    return total, available
+}
+
+func cpuTimes() (idle, all uint64, ok bool) { return 0, 0, false }
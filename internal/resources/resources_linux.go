@@ -0,0 +1,44 @@
+package resources
+
+import (
+    "bufio"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// memory reads MemTotal and MemAvailable from /proc/meminfo.
+func memory() (total, available uint64) {
+    f, err := os.Open("/proc/meminfo")
+    if err != nil { return 0, 0 }
+    defer f.Close()
+    sc := bufio.NewScanner(f)
+    for sc.Scan() {
+        fields := strings.Fields(sc.Text())
+        if len(fields) < 2 { continue }
+        kb, _ := strconv.ParseUint(fields[1], 10, 64)
+        switch fields[0] {
+        case "MemTotal:": total = kb << 10
+        case "MemAvailable:": available = kb << 10
+        }
+    }
+    return total, available
+}
+
+// cpuTimes returns the idle and total jiffies of the aggregate cpu line in
+// /proc/stat.
+func cpuTimes() (idle, all uint64, ok bool) {
+    f, err := os.Open("/proc/stat")
+    if err != nil { return 0, 0, false }
+    defer f.Close()
+    sc := bufio.NewScanner(f)
+    if !sc.Scan() { return 0, 0, false }
+    fields := strings.Fields(sc.Text())
+    if len(fields) < 5 || fields[0] != "cpu" { return 0, 0, false }
+    for i, s := range fields[1:] {
+        v, _ := strconv.ParseUint(s, 10, 64)
+        all += v
+        if i == 3 || i == 4 { idle += v } // idle, iowait
+    }
+    return idle, all, true
+}
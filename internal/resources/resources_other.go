@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package resources
+
+func memory() (total, available uint64) { return 0, 0 }
+
+func cpuTimes() (idle, all uint64, ok bool) { return 0, 0, false }
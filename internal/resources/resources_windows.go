@@ -0,0 +1,39 @@
+package resources
+
+import (
+    "unsafe"
+
+    "golang.org/x/sys/windows"
+)
+
+var (
+    kernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+    procGlobalMemoryStatusEx = kernel32.NewProc("GlobalMemoryStatusEx")
+    procGetSystemTimes       = kernel32.NewProc("GetSystemTimes")
+)
+
+type memoryStatusEx struct {
+    Length               uint32
+    MemoryLoad           uint32
+    TotalPhys            uint64
+    AvailPhys            uint64
+    TotalPageFile        uint64
+    AvailPageFile        uint64
+    TotalVirtual         uint64
+    AvailVirtual         uint64
+    AvailExtendedVirtual uint64
+}
+
+func memory() (total, available uint64) {
+    st := memoryStatusEx{Length: uint32(unsafe.Sizeof(memoryStatusEx{}))}
+    if r, _, _ := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&st))); r == 0 { return 0, 0 }
+    return st.TotalPhys, st.AvailPhys
+}
+
+// cpuTimes uses GetSystemTimes; kernel time includes idle time.
+func cpuTimes() (idle, all uint64, ok bool) {
+    var i, k, u windows.Filetime
+    if r, _, _ := procGetSystemTimes.Call(uintptr(unsafe.Pointer(&i)), uintptr(unsafe.Pointer(&k)), uintptr(unsafe.Pointer(&u))); r == 0 { return 0, 0, false }
+    ft := func(f windows.Filetime) uint64 { return uint64(f.HighDateTime)<<32 | uint64(f.LowDateTime) }
+    return ft(i), ft(k) + ft(u), true
+}
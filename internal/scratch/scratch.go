@@ -0,0 +1,74 @@
+// Package scratch owns the directory for the short-lived files requests
+// and child processes work with: uploaded audio, whisper transcripts and
+// piper output. It is the system temp dir until the server points it
+// under its data dir, where files left behind by a crash can be swept on
+// the next start without touching anything else.
+package scratch
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+var (
+    mu  sync.RWMutex
+    dir string
+)
+
+// prefixes start the names of every file the server puts in the scratch
+// dir; Sweep deletes nothing else.
+var prefixes = []string{"whisper_out_", "piper_out_", "stt-", "voice-", "ws-audio-", "ws-voice-", "pipeline-", "mcp-stt-", "realtime-", "classify-", "speaker-"}
+
+// Dir returns the scratch directory.
+func Dir() string {
+    mu.RLock()
+    defer mu.RUnlock()
+    if dir == "" { return os.TempDir() }
+    return dir
+}
+
+// SetDir creates d (private to this user) and makes it the scratch
+// directory.
+func SetDir(d string) error {
+    if err := os.MkdirAll(d, 0o700); err != nil { return err }
+    mu.Lock()
+    dir = d
+    mu.Unlock()
+    return nil
+}
+
+// Sweep deletes the server's files in the scratch directory that were last
+// modified more than maxAge ago and returns how many it removed. Use it at
+// startup, before requests could be creating new ones.
+func Sweep(maxAge time.Duration) (int, error) {
+    d := Dir()
+    entries, err := os.ReadDir(d)
+    if err != nil { return 0, err }
+    cutoff := time.Now().Add(-maxAge)
+    n := 0
+    var errs []error
+    for _, e := range entries {
+        if !e.Type().IsRegular() || !ours(e.Name()) { continue }
+        info, err := e.Info()
+        if err != nil || info.ModTime().After(cutoff) { continue }
+        if err := os.Remove(filepath.Join(d, e.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+            errs = append(errs, err)
+            continue
+        }
+        n++
+    }
+    return n, errors.Join(errs...)
+}
+
+func ours(name string) bool {
+    for _, p := range prefixes { if strings.HasPrefix(name, p) { return true } }
+    return false
+}
+
+// Create makes a new file in the scratch directory named prefix, a random
+// string and ext, so concurrent callers never share a path.
+func Create(prefix, ext string) (*os.File, error) { return os.CreateTemp(Dir(), prefix+"*"+ext) }
@@ -0,0 +1,90 @@
+// Package semcache is an in-memory cache keyed by meaning: a lookup hits
+// when a stored prompt's embedding is close enough to the new one, so
+// rephrasings of a common question share one answer.
+package semcache
+
+import (
+    "math"
+    "sync"
+    "time"
+)
+
+// DefaultThreshold is the cosine similarity a lookup needs to hit.
+const DefaultThreshold = 0.95
+
+// Options left at zero fall back to DefaultThreshold, a one hour TTL and
+// 1000 entries.
+type Options struct {
+    Threshold  float64
+    TTL        time.Duration
+    MaxEntries int
+}
+
+// Cache holds values under a scope (everything that must match exactly,
+// e.g. model and conversation so far) and an embedding (the part that may
+// vary in wording).
+type Cache struct {
+    opts    Options
+    mu      sync.Mutex
+    entries []entry
+}
+
+type entry struct {
+    scope   string
+    vec     []float32
+    value   []byte
+    expires time.Time
+}
+
+func New(opts Options) *Cache {
+    if opts.Threshold <= 0 { opts.Threshold = DefaultThreshold }
+    if opts.TTL <= 0 { opts.TTL = time.Hour }
+    if opts.MaxEntries <= 0 { opts.MaxEntries = 1000 }
+    return &Cache{opts: opts}
+}
+
+// Get returns the value of the most similar live entry in scope, if its
+// similarity to vec reaches the threshold.
+func (c *Cache) Get(scope string, vec []float32) (value []byte, score float64, ok bool) {
+    now := time.Now()
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.expireLocked(now)
+    best := -1
+    for i, e := range c.entries {
+        if e.scope != scope { continue }
+        if s := cosine(vec, e.vec); s > score || best < 0 { best, score = i, s }
+    }
+    if best < 0 || score < c.opts.Threshold { return nil, score, false }
+    return c.entries[best].value, score, true
+}
+
+// Put stores value, evicting the oldest entry when the cache is full.
+func (c *Cache) Put(scope string, vec []float32, value []byte) {
+    now := time.Now()
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.expireLocked(now)
+    if len(c.entries) >= c.opts.MaxEntries { c.entries = c.entries[1:] }
+    c.entries = append(c.entries, entry{scope: scope, vec: vec, value: value, expires: now.Add(c.opts.TTL)})
+}
+
+// expireLocked drops expired entries; entries are in insertion order and
+// share one TTL, so they expire front to back.
+func (c *Cache) expireLocked(now time.Time) {
+    i := 0
+    for i < len(c.entries) && now.After(c.entries[i].expires) { i++ }
+    if i > 0 { c.entries = append(c.entries[:0:0], c.entries[i:]...) }
+}
+
+func cosine(a, b []float32) float64 {
+    if len(a) != len(b) || len(a) == 0 { return 0 }
+    var dot, na, nb float64
+    for i := range a {
+        dot += float64(a[i]) * float64(b[i])
+        na += float64(a[i]) * float64(a[i])
+        nb += float64(b[i]) * float64(b[i])
+    }
+    if na == 0 || nb == 0 { return 0 }
+    return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+    "encoding/json"
+    "net"
+    "net/http"
+    "net/http/pprof"
+    "sync"
+
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/resources"
+    "gollmcore/internal/usage"
+)
+
+type AdminOptions struct {
+    // OnHandoff is invoked once when a standby instance asks this one to
+    // release its listener. Nil disables the handoff endpoint, which takes
+    // both a loopback caller and an admin key: either alone would let any
+    // local process, or a proxy on the host, stop the server.
+    OnHandoff func()
+    // Prompts enables the /admin/prompts CRUD API.
+    Prompts   *prompts.Store
+    // Usage enables GET /admin/usage.
+    Usage     *usage.Recorder
+    // Debug enables /debug/pprof/ and /debug/vars.
+    Debug     bool
+    // Resources enables GET /admin/status.
+    Resources *resources.Monitor
+    // LLM and LoRA enable /admin/lora when the LLM is llama-server and
+    // adapters are configured.
+    LLM       LLMService
+    LoRA      map[string]LoRAAdapter
+    // ReadOnly leaves prompts and LoRA scales readable only.
+    ReadOnly  bool
+}
+
+// RegisterAdminRoutes mounts administrative endpoints under /admin/.
+// They are reachable from loopback addresses, and otherwise only with an
+// admin key.
+func RegisterAdminRoutes(mux *http.ServeMux, o AdminOptions) {
+    rt := newRouter(mux)
+    defer rt.done()
+    if o.Prompts != nil {
+        with := func(h func(http.ResponseWriter, *http.Request, *prompts.Store)) http.HandlerFunc {
+            return adminOnly(readOnlyGuard(o.ReadOnly, "prompt templates", func(w http.ResponseWriter, r *http.Request) { h(w, r, o.Prompts) }))
+        }
+        rt.handle("GET /admin/prompts", with(handleListPrompts))
+        rt.handle("GET /admin/prompts/{name}", with(handleGetPrompt))
+        rt.handle("PUT /admin/prompts/{name}", with(handlePutPrompt))
+        rt.handle("DELETE /admin/prompts/{name}", with(handleDeletePrompt))
+    }
+    if o.Usage != nil {
+        rt.handle("GET /admin/usage", adminOnly(func(w http.ResponseWriter, r *http.Request) { handleAdminUsage(w, r, o.Usage) }))
+    }
+    if o.Resources != nil {
+        rt.handle("GET /admin/status", adminOnly(func(w http.ResponseWriter, r *http.Request) { handleAdminStatus(w, r, o.Resources) }))
+    }
+    if up, ok := o.LLM.(loraUpstream); ok && len(o.LoRA) > 0 {
+        lora := adminOnly(readOnlyGuard(o.ReadOnly, "LoRA scales", func(w http.ResponseWriter, r *http.Request) { handleAdminLoRA(w, r, up, o.LoRA) }))
+        rt.handle("GET /admin/lora", lora)
+        rt.handle("POST /admin/lora", lora)
+    }
+    if o.Debug {
+        // pprof serves its own subtree and takes POST on /symbol.
+        mux.HandleFunc("/debug/pprof/", adminOnly(pprof.Index))
+        mux.HandleFunc("/debug/pprof/cmdline", adminOnly(pprof.Cmdline))
+        mux.HandleFunc("/debug/pprof/profile", adminOnly(pprof.Profile))
+        mux.HandleFunc("/debug/pprof/symbol", adminOnly(pprof.Symbol))
+        mux.HandleFunc("/debug/pprof/trace", adminOnly(pprof.Trace))
+        rt.handle("GET /debug/vars", adminOnly(handleDebugVars))
+    }
+    if o.OnHandoff != nil {
+        var once sync.Once
+        rt.handle("POST /admin/handoff", func(w http.ResponseWriter, r *http.Request) {
+            if !isLoopback(r.RemoteAddr) || !isAdmin(r.Context()) { writeError(w, "forbidden", http.StatusForbidden); return }
+            w.Header().Set("Content-Type", "application/json")
+            w.WriteHeader(http.StatusAccepted)
+            _ = json.NewEncoder(w).Encode(map[string]any{"status": "releasing"})
+            if f, ok := w.(http.Flusher); ok { f.Flush() }
+            once.Do(func() { go o.OnHandoff() })
+        })
+    }
+}
+
+// adminOnly restricts h to loopback callers and admin keys.
+func adminOnly(h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if !isLoopback(r.RemoteAddr) && !isAdmin(r.Context()) { writeError(w, "forbidden", http.StatusForbidden); return }
+        h(w, r)
+    }
+}
+
+func isLoopback(remoteAddr string) bool {
+    host, _, err := net.SplitHostPort(remoteAddr)
+    if err != nil { host = remoteAddr }
+    ip := net.ParseIP(host)
+    return ip != nil && ip.IsLoopback()
+}
+
+func handleListPrompts(w http.ResponseWriter, r *http.Request, store *prompts.Store) {
+    writeJSON(w, http.StatusOK, map[string]any{"prompts": store.List()})
+}
+
+func handleGetPrompt(w http.ResponseWriter, r *http.Request, store *prompts.Store) {
+    t, err := store.Get(r.PathValue("name"))
+    if err != nil { writeServiceError(w, err, http.StatusNotFound); return }
+    writeJSON(w, http.StatusOK, map[string]any{"prompt": t, "variables": t.Variables()})
+}
+
+func handlePutPrompt(w http.ResponseWriter, r *http.Request, store *prompts.Store) {
+    var t prompts.Template
+    if !decodeJSON(w, r, &t) { return }
+    t.Name = r.PathValue("name")
+    if err := store.Put(t); err != nil { writeServiceError(w, err, http.StatusBadRequest); return }
+    writeJSON(w, http.StatusOK, map[string]any{"prompt": t, "variables": t.Variables()})
+}
+
+func handleDeletePrompt(w http.ResponseWriter, r *http.Request, store *prompts.Store) {
+    if err := store.Delete(r.PathValue("name")); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    w.WriteHeader(http.StatusNoContent)
+}
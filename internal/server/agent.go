@@ -0,0 +1,247 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Agent mode --------
+//
+// A chat completion with "agent": true runs the server's tools itself:
+// whenever the model calls one, the server executes it, appends the result
+// and asks the model again, until it answers in text or MaxSteps model
+// calls are used up. Streams carry the answer as usual chunks plus
+// "event: tool" events for each call and result, so thin clients get agent
+// behavior from one request. A call to a tool the client defined ends the
+// loop and is returned as a normal tool call.
+
+// AgentOptions configures agent mode; it is off without tools.
+type AgentOptions struct {
+    Tools    map[string]Tool
+    MaxSteps int // model calls per request (default 8)
+}
+
+const defaultAgentSteps = 8
+
+// agentStep is one tool call made by the server, with its result.
+type agentStep struct {
+    Type      string `json:"type"` // tool_call | tool_result
+    ID        string `json:"id"`
+    Name      string `json:"name"`
+    Arguments string `json:"arguments,omitempty"`
+    Output    string `json:"output,omitempty"`
+    Error     string `json:"error,omitempty"`
+}
+
+type agentResponse struct {
+    *llm.ChatResponse
+    AgentSteps []agentStep `json:"agent_steps"`
+}
+
+// agentTools selects the named tools, or all of them when names is empty.
+func (d Dependencies) agentTools(names []string) (map[string]Tool, error) {
+    if len(d.Agent.Tools) == 0 { return nil, fmt.Errorf("agent mode is not enabled (no server tools configured)") }
+    if len(names) == 0 { return d.Agent.Tools, nil }
+    out := make(map[string]Tool, len(names))
+    for _, n := range names {
+        t, ok := d.Agent.Tools[n]
+        if !ok { return nil, fmt.Errorf("unknown server tool %q", n) }
+        out[n] = t
+    }
+    return out, nil
+}
+
+// toolDefinitions appends the OpenAI function definitions of tools to the
+// client's own tools.
+func toolDefinitions(client json.RawMessage, tools map[string]Tool) (json.RawMessage, error) {
+    var defs []json.RawMessage
+    if len(client) > 0 && string(client) != "null" {
+        if err := json.Unmarshal(client, &defs); err != nil { return nil, fmt.Errorf("tools must be an array") }
+    }
+    names := make([]string, 0, len(tools))
+    for n := range tools { names = append(names, n) }
+    sort.Strings(names)
+    for _, n := range names {
+        b, err := json.Marshal(map[string]any{"type": "function", "function": map[string]any{"name": n, "description": tools[n].Description, "parameters": tools[n].Parameters}})
+        if err != nil { return nil, err }
+        defs = append(defs, b)
+    }
+    return json.Marshal(defs)
+}
+
+// runAgent runs the tool loop. onChunk, when set, streams the model's text;
+// onStep reports tool calls and results as they happen.
+func (d Dependencies) runAgent(ctx context.Context, req llm.ChatRequest, tools map[string]Tool, onChunk func(llm.ChatChunk) error, onStep func(agentStep) error) (*llm.ChatResponse, []agentStep, error) {
+    defs, err := toolDefinitions(req.Tools, tools)
+    if err != nil { return nil, nil, err }
+    maxSteps := d.Agent.MaxSteps
+    if maxSteps <= 0 { maxSteps = defaultAgentSteps }
+    msgs := append([]llm.Message(nil), req.Messages...)
+    var (
+        steps []agentStep
+        usage llm.Usage
+    )
+    for round := 0; ; round++ {
+        r := req
+        r.Messages, r.Tools = msgs, defs
+        last := round == maxSteps-1
+        if last { r.ToolChoice = json.RawMessage(`"none"`) }
+        var resp *llm.ChatResponse
+        if onChunk != nil {
+            resp, err = d.LLM.ChatStream(ctx, r, onChunk)
+        } else {
+            resp, err = d.LLM.Chat(ctx, r)
+        }
+        if err != nil { return nil, steps, err }
+        if resp.Usage != nil {
+            usage.PromptTokens += resp.Usage.PromptTokens
+            usage.CompletionTokens += resp.Usage.CompletionTokens
+            usage.TotalTokens += resp.Usage.TotalTokens
+            resp.Usage = &usage
+        }
+        if len(resp.Choices) == 0 { return resp, steps, nil }
+        msg := resp.Choices[0].Message
+        calls, err := msg.DecodeToolCalls()
+        if err != nil { return nil, steps, err }
+        if len(calls) == 0 { return resp, steps, nil }
+        for _, c := range calls {
+            if _, ok := tools[c.Function.Name]; !ok { return resp, steps, nil }
+        }
+        if last {
+            // The model ignored tool_choice "none"; stop rather than loop on.
+            resp.Choices[0].Message.ToolCalls = nil
+            resp.Choices[0].FinishReason = "length"
+            return resp, steps, nil
+        }
+
+        msgs = append(msgs, llm.Message{Role: "assistant", Content: msg.Content, ToolCalls: msg.ToolCalls})
+        for _, c := range calls {
+            call := agentStep{Type: "tool_call", ID: c.ID, Name: c.Function.Name, Arguments: c.Function.Arguments}
+            steps = append(steps, call)
+            if onStep != nil { if err := onStep(call); err != nil { return nil, steps, err } }
+            res := d.callTool(ctx, tools[c.Function.Name], c)
+            steps = append(steps, res)
+            if onStep != nil { if err := onStep(res); err != nil { return nil, steps, err } }
+            content := res.Output
+            if res.Error != "" { content = "error: " + res.Error }
+            msgs = append(msgs, llm.Message{Role: "tool", ToolCallID: c.ID, Name: c.Function.Name, Content: content})
+        }
+    }
+}
+
+// callTool runs one call; failures become the result the model sees.
+func (d Dependencies) callTool(ctx context.Context, t Tool, c llm.ToolCall) agentStep {
+    res := agentStep{Type: "tool_result", ID: c.ID, Name: c.Function.Name}
+    args := json.RawMessage(c.Function.Arguments)
+    if strings.TrimSpace(c.Function.Arguments) == "" { args = json.RawMessage("{}") }
+    start := time.Now()
+    var (
+        out string
+        err error
+    )
+    if !json.Valid(args) {
+        err = fmt.Errorf("arguments are not valid JSON")
+    } else {
+        out, err = t.Call(ctx, args)
+    }
+    status := "ok"
+    if err != nil { status = "error" }
+    metrics.add("gollmcore_agent_tool_calls_total", "Tool calls run by the server in agent mode.", `tool="`+c.Function.Name+`",result="`+status+`"`, 1)
+    if err != nil {
+        log.Printf("agent tool %s failed after %s: %v", c.Function.Name, time.Since(start).Round(time.Millisecond), err)
+        res.Error = err.Error()
+        return res
+    }
+    if len(out) > maxToolOutput {
+        out = out[:maxToolOutput]
+        for !utf8.ValidString(out) { out = out[:len(out)-1] }
+        out += "\n[output truncated]"
+    }
+    res.Output = out
+    return res
+}
+
+// handleAgentChat answers req in agent mode; remember records the final
+// reply like a normal completion.
+func handleAgentChat(w http.ResponseWriter, r *http.Request, d Dependencies, req llm.ChatRequest, tools map[string]Tool, remember func(*llm.ChatResponse)) {
+    if !req.Stream {
+        resp, steps, err := d.runAgent(r.Context(), req, tools, nil, nil)
+        if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+        remember(resp)
+        if steps == nil { steps = []agentStep{} }
+        writeJSON(w, http.StatusOK, agentResponse{ChatResponse: resp, AgentSteps: steps})
+        return
+    }
+
+    flusher, ok := w.(http.Flusher)
+    if !ok { writeError(w, "streaming unsupported", http.StatusInternalServerError); return }
+    id, created := newID("chatcmpl"), time.Now().Unix()
+    started := false
+    start := func() {
+        if started { return }
+        w.Header().Set("Content-Type", "text/event-stream")
+        w.Header().Set("Cache-Control", "no-cache")
+        w.Header().Set("Connection", "keep-alive")
+        started = true
+    }
+    // Only the text reaches the client; tool calls are the server's business
+    // and every round but the last would end with finish_reason tool_calls.
+    onChunk := func(c llm.ChatChunk) error {
+        text := false
+        for i := range c.Choices {
+            c.Choices[i].Delta.ToolCalls, c.Choices[i].FinishReason = nil, nil
+            text = text || c.Choices[i].Delta.Content != ""
+        }
+        if !text { return nil }
+        start()
+        c.ID, c.Created = id, created
+        b, err := json.Marshal(c)
+        if err != nil { return err }
+        if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil { return err }
+        flusher.Flush()
+        return nil
+    }
+    onStep := func(s agentStep) error {
+        start()
+        b, err := json.Marshal(s)
+        if err != nil { return err }
+        if _, err := fmt.Fprintf(w, "event: tool\ndata: %s\n\n", b); err != nil { return err }
+        flusher.Flush()
+        return nil
+    }
+    resp, _, err := d.runAgent(r.Context(), req, tools, onChunk, onStep)
+    if err != nil {
+        if !started { writeServiceError(w, err, http.StatusBadGateway); return }
+        writeSSEError(w, err)
+        flusher.Flush()
+        log.Printf("agent stream error: %v", err)
+        return
+    }
+    remember(resp)
+    start()
+    finish := "stop"
+    var delta llm.Message
+    if len(resp.Choices) > 0 {
+        if resp.Choices[0].FinishReason != "" { finish = resp.Choices[0].FinishReason }
+        // Client tool calls are passed on whole, indexed like stream deltas.
+        calls, _ := resp.Choices[0].Message.DecodeToolCalls()
+        if len(calls) > 0 {
+            indexed := make([]map[string]any, len(calls))
+            for i, c := range calls { indexed[i] = map[string]any{"index": i, "id": c.ID, "type": c.Type, "function": c.Function} }
+            delta.ToolCalls, _ = json.Marshal(indexed)
+        }
+    }
+    b, _ := json.Marshal(llm.ChatChunk{ID: id, Object: "chat.completion.chunk", Created: created, Model: resp.Model,
+        Choices: []llm.ChunkChoice{{Delta: delta, FinishReason: &finish}}})
+    fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", b)
+    flusher.Flush()
+}
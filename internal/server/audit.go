@@ -0,0 +1,154 @@
+package server
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "hash"
+    "io"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "gollmcore/internal/audit"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/tracing"
+)
+
+// -------- Audit log --------
+//
+// Audit writes one entry per request; WithAudit annotates it with the
+// model and token counts of each service call made on its behalf. A
+// WebSocket connection is one entry, written when it closes.
+
+// auditInputLimit bounds how much of a request body is hashed.
+const auditInputLimit = 64 << 10
+
+// Audit wraps h so every API request is recorded in l, or in the log of
+// its tenant (see Tenants). Health checks, metrics, the API description and
+// the test UI are not recorded. Place it outside RequireAPIKey so rejected
+// requests are logged too.
+func Audit(h http.Handler, l *audit.Logger) http.Handler {
+    if l == nil { return h }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch {
+        case r.URL.Path == "/healthz", r.URL.Path == "/metrics", r.URL.Path == "/openapi.json", r.URL.Path == "/docs", strings.HasPrefix(r.URL.Path, "/test/"):
+            h.ServeHTTP(w, r)
+            return
+        }
+        start := time.Now()
+        e := &audit.Entry{Time: start, Method: r.Method, Route: r.URL.Path, RemoteAddr: r.RemoteAddr, TraceID: tracing.TraceID(r.Context())}
+        key := requestAPIKey(r)
+        if key == "" { key = r.URL.Query().Get("token") }
+        if key != "" { e.Key = keyFingerprint(key) }
+        e.Tenant = tenantOf(r.Context())
+        body := &hashingReader{ReadCloser: r.Body, h: sha256.New()}
+        if r.Body != nil && r.Body != http.NoBody { r.Body = body }
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        h.ServeHTTP(rec, r.WithContext(audit.WithEntry(r.Context(), e)))
+        e.Status = rec.status
+        e.DurationMs = time.Since(start).Milliseconds()
+        if t := tracing.TimingsFrom(r.Context()); t != nil {
+            e.QueueMs = millis(t.Queue())
+            for _, st := range t.Stages() {
+                if e.StagesMs == nil { e.StagesMs = map[string]float64{} }
+                e.StagesMs[st.Name] = millis(st.Duration)
+            }
+        }
+        if body.n > 0 {
+            e.InputBytes = body.n
+            e.InputSHA256 = hex.EncodeToString(body.h.Sum(nil))
+        }
+        tl, err := l.Tenant(e.Tenant)
+        if err != nil {
+            log.Printf("audit: tenant %s: %v", e.Tenant, err)
+            tl = l
+        }
+        if err := tl.Write(e); err != nil { log.Printf("audit: %v", err) }
+    })
+}
+
+// keyFingerprint identifies an API key without recording it.
+func keyFingerprint(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// hashingReader hashes the first auditInputLimit bytes read from a body.
+type hashingReader struct {
+    io.ReadCloser
+    h hash.Hash
+    n int64
+}
+
+func (b *hashingReader) Read(p []byte) (int, error) {
+    n, err := b.ReadCloser.Read(p)
+    if room := auditInputLimit - b.n; room > 0 {
+        if int64(n) < room { room = int64(n) }
+        b.h.Write(p[:room])
+    }
+    b.n += int64(n)
+    return n, err
+}
+
+// WithAudit wraps the LLM, TTS and embeddings services so their model and
+// token counts are added to the request's audit entry.
+func WithAudit(d Dependencies) Dependencies {
+    if d.LLM != nil { d.LLM = &auditLLM{next: d.LLM} }
+    if d.TTS != nil { d.TTS = &auditTTS{next: d.TTS} }
+    if d.Embeddings != nil { d.Embeddings = &auditEmbeddings{next: d.Embeddings} }
+    return d
+}
+
+type auditLLM struct{ next LLMService }
+
+func (a *auditLLM) note(ctx context.Context, req llm.ChatRequest, resp *llm.ChatResponse) {
+    model := req.Model
+    if resp != nil && resp.Model != "" { model = resp.Model }
+    if model == "" { model = a.Model() }
+    in, out := 0, 0
+    if resp != nil && resp.Usage != nil && resp.Usage.TotalTokens > 0 {
+        in, out = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+    } else {
+        for _, m := range req.Messages { in += estimateTokens(len(m.Content)) }
+        if resp != nil { out = estimateTokens(len(resp.Text())) }
+    }
+    audit.Note(ctx, "llm", model, in, out)
+}
+
+func (a *auditLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    resp, err := a.next.Chat(ctx, req)
+    a.note(ctx, req, resp)
+    return resp, err
+}
+
+func (a *auditLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    resp, err := a.next.ChatStream(ctx, req, onChunk)
+    a.note(ctx, req, resp)
+    return resp, err
+}
+
+func (a *auditLLM) Model() string {
+    if m, ok := a.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type auditTTS struct{ next TTSService }
+
+func (a *auditTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    audio, err := a.next.Synthesize(ctx, text, voice)
+    audit.Note(ctx, "tts", voice, estimateTokens(len(text)), 0)
+    return audio, err
+}
+
+type auditEmbeddings struct{ next embeddings.Service }
+
+func (a *auditEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    vecs, model, err := a.next.Embed(ctx, inputs)
+    audit.Note(ctx, "embeddings", model, a.CountTokens(inputs), 0)
+    return vecs, model, err
+}
+
+func (a *auditEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(a.next, inputs) }
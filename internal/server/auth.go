@@ -0,0 +1,78 @@
+package server
+
+import (
+    "context"
+    "crypto/subtle"
+    "net/http"
+    "strings"
+
+    "github.com/gorilla/websocket"
+)
+
+// -------- API key auth --------
+
+// RequireAPIKey rejects requests that do not carry one of keys, either as
+// "Authorization: Bearer <key>" or "X-API-Key: <key>". Admin keys are
+// accepted everywhere a regular key is and additionally unlock admin-only
+// request options (see policyOverride). With no keys of either kind it
+// returns next unchanged. Health checks, the API description (/openapi.json
+// and /docs) and WebSocket upgrades under the default /ws prefix are let
+// through; WS handlers authenticate
+// themselves so browsers can pass the key in the URL or the first frame.
+func RequireAPIKey(next http.Handler, keys, adminKeys []string) http.Handler {
+    return RequireAPIKeyWS(next, keys, adminKeys, "/ws")
+}
+
+// RequireAPIKeyWS is RequireAPIKey with the WebSocket routes under
+// wsPrefix; an empty prefix lets no upgrade through. Upgrade requests for
+// any other path need a key like plain ones, or the Upgrade headers alone
+// would open every route.
+func RequireAPIKeyWS(next http.Handler, keys, adminKeys []string, wsPrefix string) http.Handler {
+    if len(keys) == 0 && len(adminKeys) == 0 { return next }
+    wsPrefix = strings.TrimSuffix(wsPrefix, "/")
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/healthz", "/openapi.json", "/docs":
+            next.ServeHTTP(w, r)
+            return
+        }
+        if wsPrefix != "" && strings.HasPrefix(r.URL.Path, wsPrefix+"/") && websocket.IsWebSocketUpgrade(r) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        key := requestAPIKey(r)
+        if validAPIKey(key, adminKeys) {
+            next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminCtxKey{}, true)))
+            return
+        }
+        if len(keys) > 0 && !validAPIKey(key, keys) {
+            w.Header().Set("WWW-Authenticate", `Bearer realm="gollmcore"`)
+            writeError(w, "missing or invalid API key", http.StatusUnauthorized)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+type adminCtxKey struct{}
+
+// isAdmin reports whether the request was authenticated with an admin key.
+func isAdmin(ctx context.Context) bool {
+    v, _ := ctx.Value(adminCtxKey{}).(bool)
+    return v
+}
+
+// requestAPIKey extracts a key from the request headers.
+func requestAPIKey(r *http.Request) string {
+    if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "bearer ") { return strings.TrimSpace(h[7:]) }
+    return r.Header.Get("X-API-Key")
+}
+
+func validAPIKey(key string, keys []string) bool {
+    if key == "" { return false }
+    ok := false
+    for _, k := range keys {
+        if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 { ok = true }
+    }
+    return ok
+}
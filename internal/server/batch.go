@@ -0,0 +1,138 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net/http"
+    "sync"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Batch Chat Completions --------
+//
+// /v1/chat/completions/batch takes an array of chat requests and answers
+// them with a bounded number running at once, so offline pipelines need one
+// HTTP call instead of hundreds. Results come back in input order; a failed
+// item carries its error and does not fail the batch. With "async": true the
+// batch runs as a job polled at /v1/jobs/{id}.
+
+const (
+    maxBatchRequests    = 500
+    defaultBatchWorkers = 4
+    maxBatchWorkers     = 16
+)
+
+type batchItem struct {
+    CustomID string `json:"custom_id,omitempty"`
+    llm.ChatRequest
+}
+
+type batchRequest struct {
+    Requests    []batchItem `json:"requests" validate:"required"`
+    Concurrency int         `json:"concurrency"` // default 4, at most 16
+    Async       bool        `json:"async"`
+    Priority    string      `json:"priority"` // default background
+    WebhookURL  string      `json:"webhook_url"` // async only; see Webhooks
+}
+
+type batchResult struct {
+    Index    int               `json:"index"`
+    CustomID string            `json:"custom_id,omitempty"`
+    Response *llm.ChatResponse `json:"response,omitempty"`
+    Error    *apiError         `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+    Object    string        `json:"object"` // "chat.completion.batch"
+    Results   []batchResult `json:"results"`
+    Succeeded int           `json:"succeeded"`
+    Failed    int           `json:"failed"`
+}
+
+func handleChatBatch(w http.ResponseWriter, r *http.Request, d Dependencies, jobs *jobStore) {
+    var req batchRequest
+    if !decodeCompatJSON(w, r, &req) { return }
+    r, err := requestPriority(r, req.Priority)
+    if err != nil { writeParamError(w, "priority", err.Error()); return }
+    if len(req.Requests) == 0 { writeParamError(w, "requests", "requests must not be empty"); return }
+    if len(req.Requests) > maxBatchRequests { writeParamError(w, "requests", fmt.Sprintf("at most %d requests per batch", maxBatchRequests)); return }
+    if req.Concurrency < 0 || req.Concurrency > maxBatchWorkers { writeParamError(w, "concurrency", fmt.Sprintf("concurrency must be between 1 and %d", maxBatchWorkers)); return }
+    for i, it := range req.Requests {
+        if len(it.Messages) == 0 { writeParamError(w, "requests", fmt.Sprintf("requests[%d]: messages must not be empty", i)); return }
+        if err := checkImages(it.Messages); err != nil { writeParamError(w, "requests", fmt.Sprintf("requests[%d]: %v", i, err)); return }
+        if it.Stream { writeParamError(w, "requests", fmt.Sprintf("requests[%d]: stream is not supported in a batch", i)); return }
+    }
+
+    if err := d.Webhooks.checkURL(req.WebhookURL); err != nil { writeParamError(w, "webhook_url", err.Error()); return }
+    if req.WebhookURL != "" && !req.Async { writeParamError(w, "webhook_url", "webhook_url requires async"); return }
+
+    ctx := withPriority(r.Context(), priorityOf(r.Context(), Background))
+    if !req.Async {
+        writeJSON(w, http.StatusOK, runBatch(ctx, d, req, nil))
+        return
+    }
+    job := jobs.add(jobContext(r), Job{Kind: "chat_batch", WebhookURL: req.WebhookURL})
+    // Detached from the request but keeping its values (e.g. the policy override).
+    ctx = context.WithoutCancel(ctx)
+    go func() {
+        jobs.update(job.ID, func(j *Job) { j.Status = "running" })
+        out := runBatch(ctx, d, req, func(done int) {
+            jobs.update(job.ID, func(j *Job) { j.Result = map[string]int{"completed": done, "total": len(req.Requests)} })
+        })
+        jobs.update(job.ID, func(j *Job) { j.Status, j.Result = "succeeded", out })
+        log.Printf("chat batch %s: %d succeeded, %d failed", job.ID, out.Succeeded, out.Failed)
+    }()
+
+    snap, _ := jobs.get(job.ID)
+    w.Header().Set("Location", "/v1/jobs/"+job.ID)
+    writeJSON(w, http.StatusAccepted, snap)
+}
+
+// runBatch answers every request, calling progress (if set) with the
+// number finished after each one; calls are serialized.
+func runBatch(ctx context.Context, d Dependencies, req batchRequest, progress func(done int)) batchResponse {
+    n := len(req.Requests)
+    workers := req.Concurrency
+    if workers == 0 { workers = defaultBatchWorkers }
+    workers = min(workers, n)
+
+    out := batchResponse{Object: "chat.completion.batch", Results: make([]batchResult, n)}
+    var (
+        mu   sync.Mutex
+        done int
+        wg   sync.WaitGroup
+    )
+    next := make(chan int)
+    for k := 0; k < workers; k++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range next {
+                it := req.Requests[i]
+                res := batchResult{Index: i, CustomID: it.CustomID}
+                resp, err := d.LLM.Chat(ctx, it.ChatRequest)
+                if err != nil {
+                    status, code := statusFor(err, http.StatusBadGateway)
+                    e := newAPIError(status, code, "", err.Error())
+                    res.Error = &e
+                } else {
+                    res.Response = resp
+                }
+                mu.Lock()
+                out.Results[i] = res
+                done++
+                if err != nil { out.Failed++ } else { out.Succeeded++ }
+                if progress != nil { progress(done) }
+                mu.Unlock()
+            }
+        }()
+    }
+    for i := 0; i < n; i++ { next <- i }
+    close(next)
+    wg.Wait()
+    metrics.add("gollmcore_chat_batch_requests_total", "Chat completions answered through the batch endpoint.", `result="succeeded"`, int64(out.Succeeded))
+    metrics.add("gollmcore_chat_batch_requests_total", "Chat completions answered through the batch endpoint.", `result="failed"`, int64(out.Failed))
+    return out
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+    "encoding/json"
+    "net/http"
+    "sort"
+    "strings"
+)
+
+// -------- Capabilities --------
+//
+// /v1/capabilities lets generic clients feature-detect instead of probing
+// endpoints and failing at runtime. Everything here is derived from the
+// registered dependencies, so it always matches the routes actually served.
+
+func capabilities(d Dependencies) map[string]any {
+    voice := d.STT != nil && d.LLM != nil && d.TTS != nil
+    pipelines := make([]string, 0, len(d.Pipelines))
+    for name := range d.Pipelines { pipelines = append(pipelines, name) }
+    sort.Strings(pipelines)
+
+    caps := map[string]any{
+        "services": map[string]any{
+            "stt":            d.STT != nil,
+            "audio_classify": d.AudioClassifier != nil,
+            "speakers":       d.Speakers != nil,
+            "embeddings":     d.Embeddings != nil,
+            "tts":            d.TTS != nil,
+            "llm":            d.LLM != nil,
+            "voice_chat":     voice,
+            "pipelines":      pipelines,
+            "sessions":       d.Sessions != nil,
+            "web_search":     d.Search.Provider != nil,
+            "mcp":            d.MCP,
+        },
+        "auth": map[string]any{
+            "required":  len(d.APIKeys) > 0,
+            "methods":   []string{"bearer", "x-api-key"},
+            "websocket": []string{"bearer", "x-api-key", "query:token", "auth_frame"},
+        },
+    }
+
+    caps["read_only"] = d.ReadOnly.capabilities()
+
+    streaming := map[string]any{}
+    if d.STT != nil { streaming["transcriptions"] = []string{"sse"} }
+    if d.LLM != nil { streaming["chat_completions"] = []string{"sse"} }
+    if d.Events != nil { streaming["events"] = []string{"sse"} }
+    caps["streaming"] = streaming
+
+    if d.STT != nil {
+        // whisper ".en" models are English-only; the others detect the language.
+        langs := []string{"auto"}
+        if strings.HasSuffix(d.STTDefaultModel, ".en") { langs = []string{"en"} }
+        caps["stt"] = map[string]any{"default_model": d.STTDefaultModel, "languages": langs}
+    }
+    if d.TTS != nil { caps["tts"] = map[string]any{"formats": []string{"audio/wav"}} }
+    if d.LLM != nil {
+        llmCaps := map[string]any{"tool_calling": true, "json_mode": true, "batch": true, "policy_enforced": d.PolicyPrompt != "", "utilities": []string{"summarize", "extract", "classify", "translate"}}
+        if m, ok := d.LLM.(interface{ Model() string }); ok { llmCaps["default_model"] = m.Model() }
+        llmCaps["vision"] = d.Vision.Model != "" || d.Vision.LLM != nil
+        llmCaps["semantic_cache"] = d.ChatCache != nil && d.Embeddings != nil
+        tools := make([]string, 0, len(d.Agent.Tools))
+        for name := range d.Agent.Tools { tools = append(tools, name) }
+        sort.Strings(tools)
+        llmCaps["agent_tools"] = tools
+        llmCaps["moderation"] = map[string]any{"screen_input": d.Moderation.ScreenInput, "screen_output": d.Moderation.ScreenOutput}
+        caps["llm"] = llmCaps
+    }
+
+    limits := map[string]any{"upload_bytes": nil}
+    if d.Embeddings != nil { limits["similarity_inputs"] = maxSimilarityInputs }
+    if voice { limits["voice_history_messages"] = maxVoiceHistory }
+    if d.Embeddings != nil && d.Caps.EmbeddingInputs > 0 { limits["embedding_inputs"] = d.Caps.EmbeddingInputs }
+    if d.TTS != nil && d.Caps.TTSChars > 0 { limits["tts_chars"] = d.Caps.TTSChars }
+    if d.LLM != nil && d.Caps.MaxTokens > 0 { limits["max_tokens"] = d.Caps.MaxTokens }
+    if d.STT != nil && d.Caps.AudioDuration > 0 { limits["audio_seconds"] = int(d.Caps.AudioDuration.Seconds()) }
+    if d.WebSocket.Enable {
+        ws := d.WebSocket.withDefaults()
+        limits["ws_message_bytes"] = ws.MaxMessageBytes
+        limits["ws_inflight_per_conn"] = ws.MaxInflightPerConn
+        if d.LLM != nil { limits["realtime_audio_buffer_bytes"] = maxRealtimeBuffer }
+        var endpoints []string
+        if d.Embeddings != nil { endpoints = append(endpoints, ws.PathPrefix+"/embeddings") }
+        if d.STT != nil { endpoints = append(endpoints, ws.PathPrefix+"/stt") }
+        if d.TTS != nil { endpoints = append(endpoints, ws.PathPrefix+"/tts") }
+        if voice { endpoints = append(endpoints, ws.PathPrefix+"/voice") }
+        if d.LLM != nil { endpoints = append(endpoints, ws.PathPrefix+"/chat", ws.PathPrefix+"/realtime") }
+        streaming["websocket"] = endpoints
+    }
+    caps["limits"] = limits
+    return caps
+}
+
+// AdvertisedTXT summarizes the capabilities as mDNS TXT key=value pairs,
+// so LAN clients can pick a server before connecting to it.
+func AdvertisedTXT(d Dependencies) []string {
+    flag := func(b bool) string { if b { return "1" }; return "0" }
+    txt := []string{
+        "path=/v1",
+        "stt=" + flag(d.STT != nil),
+        "tts=" + flag(d.TTS != nil),
+        "llm=" + flag(d.LLM != nil),
+        "embeddings=" + flag(d.Embeddings != nil),
+        "auth=" + flag(len(d.APIKeys) > 0),
+    }
+    if d.WebSocket.Enable { txt = append(txt, "ws="+d.WebSocket.withDefaults().PathPrefix) }
+    return txt
+}
+
+func handleCapabilities(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(capabilities(d))
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+    "unicode/utf8"
+
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+)
+
+// -------- Request caps --------
+//
+// Concurrency limits bound how many calls run at once, but one call can
+// still tie up a service for minutes: ten thousand texts to embed, a novel
+// to read aloud, an unbounded generation or a day-long recording. Caps
+// refuse such calls up front with a 400 (limit_exceeded). They are checked
+// around the services, so REST, WebSocket, MCP and pipelines share them.
+
+var errLimitExceeded = errors.New("request exceeds a server limit")
+
+// Caps bound the size of a single call. Zero leaves a cap off.
+type Caps struct {
+    EmbeddingInputs int           // texts per embeddings call
+    TTSChars        int           // characters per synthesis
+    // MaxTokens is the largest max_tokens a chat request may ask for; it
+    // is also used for requests that set none.
+    MaxTokens       int
+    // AudioDuration is the longest recording transcribed. Only PCM WAV
+    // files are measured; other formats are bounded by the STT timeout.
+    AudioDuration   time.Duration
+}
+
+func (c Caps) checkInputs(n int) error {
+    if c.EmbeddingInputs > 0 && n > c.EmbeddingInputs { return fmt.Errorf("%w: too many inputs: %d (max %d)", errLimitExceeded, n, c.EmbeddingInputs) }
+    return nil
+}
+
+func (c Caps) checkText(text string) error {
+    if c.TTSChars <= 0 { return nil }
+    if n := utf8.RuneCountInString(text); n > c.TTSChars { return fmt.Errorf("%w: text too long: %d characters (max %d)", errLimitExceeded, n, c.TTSChars) }
+    return nil
+}
+
+// applyMaxTokens fills in or checks req.MaxTokens.
+func (c Caps) applyMaxTokens(req llm.ChatRequest) (llm.ChatRequest, error) {
+    if c.MaxTokens <= 0 { return req, nil }
+    if req.MaxTokens == nil { n := c.MaxTokens; req.MaxTokens = &n; return req, nil }
+    if *req.MaxTokens > c.MaxTokens { return req, fmt.Errorf("%w: max_tokens %d is above the server's limit of %d", errLimitExceeded, *req.MaxTokens, c.MaxTokens) }
+    return req, nil
+}
+
+// checkAudio refuses a PCM WAV recording at path longer than AudioDuration.
+func (c Caps) checkAudio(path string) error {
+    if c.AudioDuration <= 0 { return nil }
+    d, err := stt.WAVDuration(path)
+    if err != nil { return nil }
+    if d > c.AudioDuration { return fmt.Errorf("%w: audio too long: %s (max %s)", errLimitExceeded, d.Round(time.Second), c.AudioDuration) }
+    return nil
+}
+
+// withCaps wraps the LLM, TTS and embeddings services with d.Caps. STT is
+// checked in transcribe.
+func (d Dependencies) withCaps() Dependencies {
+    c := d.Caps
+    if d.LLM != nil && c.MaxTokens > 0 { d.LLM = &cappedLLM{next: d.LLM, caps: c} }
+    if d.TTS != nil && c.TTSChars > 0 { d.TTS = &cappedTTS{next: d.TTS, caps: c} }
+    if d.Embeddings != nil && c.EmbeddingInputs > 0 { d.Embeddings = &cappedEmbeddings{next: d.Embeddings, caps: c} }
+    return d
+}
+
+type cappedLLM struct {
+    next LLMService
+    caps Caps
+}
+
+func (s *cappedLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    req, err := s.caps.applyMaxTokens(req)
+    if err != nil { return nil, err }
+    return s.next.Chat(ctx, req)
+}
+
+func (s *cappedLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    req, err := s.caps.applyMaxTokens(req)
+    if err != nil { return nil, err }
+    return s.next.ChatStream(ctx, req, onChunk)
+}
+
+func (s *cappedLLM) Model() string {
+    if m, ok := s.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type cappedTTS struct {
+    next TTSService
+    caps Caps
+}
+
+func (s *cappedTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    if err := s.caps.checkText(text); err != nil { return nil, err }
+    return s.next.Synthesize(ctx, text, voice)
+}
+
+type cappedEmbeddings struct {
+    next embeddings.Service
+    caps Caps
+}
+
+func (s *cappedEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    if err := s.caps.checkInputs(len(inputs)); err != nil { return nil, "", err }
+    return s.next.Embed(ctx, inputs)
+}
+
+func (s *cappedEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(s.next, inputs) }
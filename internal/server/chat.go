@@ -0,0 +1,147 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/sessions"
+)
+
+// -------- Chat Completions Handler --------
+
+func handleChatCompletions(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var body struct {
+        llm.ChatRequest
+        // SessionID prepends the stored history and records this turn.
+        SessionID       string            `json:"session_id"`
+        // PromptTemplate names a server-side template rendered with
+        // PromptVariables into a system (or trailing user) message.
+        PromptTemplate  string            `json:"prompt_template"`
+        PromptVariables map[string]string `json:"prompt_variables"`
+        // Priority is "interactive" (default) or "background".
+        Priority        string            `json:"priority"`
+        // Agent runs server tools (all, or those in AgentTools) until the
+        // model answers; see agent.go.
+        Agent           bool              `json:"agent"`
+        AgentTools      []string          `json:"agent_tools"`
+    }
+    if !decodeCompatJSON(w, r, &body) { return }
+    req := body.ChatRequest
+    r, err := requestPriority(r, body.Priority)
+    if err != nil { writeParamError(w, "priority", err.Error()); return }
+    var tools map[string]Tool
+    if body.Agent {
+        if tools, err = d.agentTools(body.AgentTools); err != nil { writeParamError(w, "agent", err.Error()); return }
+    }
+    if body.PromptTemplate != "" {
+        if d.Prompts == nil { writeParamError(w, "prompt_template", "prompt templates are not enabled"); return }
+        tpl, err := d.Prompts.Get(body.PromptTemplate)
+        if err != nil { writeServiceError(w, err, http.StatusNotFound); return }
+        text, err := tpl.Render(body.PromptVariables)
+        if err != nil { writeParamError(w, "prompt_variables", err.Error()); return }
+        if tpl.Role == "user" {
+            req.Messages = append(req.Messages, llm.Message{Role: "user", Content: text})
+        } else {
+            req.Messages = append([]llm.Message{{Role: "system", Content: text}}, req.Messages...)
+        }
+    }
+    if len(req.Messages) == 0 { writeParamError(w, "messages", "messages must not be empty"); return }
+    if err := checkImages(req.Messages); err != nil { writeParamError(w, "messages", err.Error()); return }
+    turn := req.Messages
+    if body.SessionID != "" {
+        if d.Sessions == nil { writeParamError(w, "session_id", "sessions are not enabled"); return }
+        var (
+            sess sessions.Session
+            err  error
+        )
+        if d.ReadOnly.Sessions {
+            sess, err = d.sessionStore(r.Context()).Get(body.SessionID)
+        } else {
+            sess, _, err = d.sessionStore(r.Context()).Compress(r.Context(), body.SessionID, func(ctx context.Context, msgs []llm.Message) (string, error) {
+                return summarizeMessages(ctx, d, msgs)
+            })
+        }
+        if errors.Is(err, sessions.ErrNotFound) { writeServiceError(w, err, http.StatusInternalServerError); return }
+        if err != nil {
+            // Fall back to plain trimming; the next turn retries compression.
+            log.Printf("session %s: compression failed: %v", body.SessionID, err)
+            if sess, err = d.sessionStore(r.Context()).Get(body.SessionID); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+        }
+        req.Messages = append(d.Sessions.History(sess), turn...)
+    }
+    // Tool results change with the world, so agent replies are not cached.
+    var (
+        cached *llm.ChatResponse
+        store  func(*llm.ChatResponse)
+    )
+    if !body.Agent { cached, store = d.cachedChat(w, r, req) }
+    remember := func(resp *llm.ChatResponse) {
+        if store != nil { store(resp) }
+        if body.SessionID == "" || d.ReadOnly.Sessions || len(resp.Choices) == 0 { return }
+        msgs := append(append([]llm.Message{}, turn...), resp.Choices[0].Message)
+        if _, err := d.sessionStore(r.Context()).Append(body.SessionID, msgs...); err != nil { log.Printf("session %s: %v", body.SessionID, err) }
+    }
+    if cached != nil {
+        remember(cached)
+        if req.Stream { replayChat(w, cached); return }
+        writeJSON(w, http.StatusOK, cached)
+        return
+    }
+    if body.Agent { handleAgentChat(w, r, d, req, tools, remember); return }
+
+    if !req.Stream {
+        resp, err := d.LLM.Chat(r.Context(), req)
+        if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+        remember(resp)
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(resp)
+        return
+    }
+
+    flusher, ok := w.(http.Flusher)
+    if !ok { writeError(w, "streaming unsupported", http.StatusInternalServerError); return }
+    started := false
+    resp, err := d.LLM.ChatStream(r.Context(), req, func(c llm.ChatChunk) error {
+        if !started {
+            w.Header().Set("Content-Type", "text/event-stream")
+            w.Header().Set("Cache-Control", "no-cache")
+            w.Header().Set("Connection", "keep-alive")
+            started = true
+        }
+        b, err := json.Marshal(c)
+        if err != nil { return err }
+        if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil { return err }
+        flusher.Flush()
+        return nil
+    })
+    if err != nil {
+        if !started { writeServiceError(w, err, http.StatusBadGateway); return }
+        log.Printf("chat stream error: %v", err)
+        return
+    }
+    remember(resp)
+    if !started { w.Header().Set("Content-Type", "text/event-stream") }
+    fmt.Fprintf(w, "data: [DONE]\n\n")
+    flusher.Flush()
+}
+
+// summarizeMessages asks the LLM for a summary of msgs, used to compress
+// long session histories.
+func summarizeMessages(ctx context.Context, d Dependencies, msgs []llm.Message) (string, error) {
+    var transcript strings.Builder
+    for _, m := range msgs { fmt.Fprintf(&transcript, "%s: %s\n", m.Role, m.Content) }
+    resp, err := d.LLM.Chat(ctx, llm.ChatRequest{Messages: []llm.Message{
+        {Role: "system", Content: d.Sessions.SummaryPrompt()},
+        {Role: "user", Content: transcript.String()},
+    }})
+    if err != nil { return "", err }
+    summary := strings.TrimSpace(resp.Text())
+    if summary == "" { return "", errors.New("empty summary") }
+    return summary, nil
+}
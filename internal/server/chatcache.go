@@ -0,0 +1,102 @@
+package server
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Semantic cache --------
+//
+// With d.ChatCache set, chat completions are cached by the meaning of the
+// last user message: a new request whose last user message embeds close
+// enough to a cached one, with everything before it and all parameters
+// identical, gets the cached reply. Clients opt out per request with
+// "X-Semantic-Cache: bypass" or Cache-Control no-cache (skip lookup) /
+// no-store (do not store). Responses say what happened in X-Semantic-Cache.
+
+// chatCacheKey splits req into the exact-match scope and the text to embed;
+// ok is false for requests that cannot be cached.
+func chatCacheKey(req llm.ChatRequest) (scope, text string, ok bool) {
+    n := len(req.Messages)
+    if n == 0 || req.Messages[n-1].Role != "user" || strings.TrimSpace(req.Messages[n-1].Content) == "" { return "", "", false }
+    // Only the text is embedded, so a new image would hit an old answer.
+    if req.Messages[n-1].HasImages() { return "", "", false }
+    text = req.Messages[n-1].Content
+    req.Messages = req.Messages[:n-1]
+    req.Stream = false
+    b, err := json.Marshal(req)
+    if err != nil { return "", "", false }
+    sum := sha256.Sum256(b)
+    return hex.EncodeToString(sum[:]), text, true
+}
+
+// cachedChat looks req up in d.ChatCache. On a hit it returns the cached
+// response; otherwise it returns a function that stores a fresh response,
+// or nil when this request must not be stored.
+func (d Dependencies) cachedChat(w http.ResponseWriter, r *http.Request, req llm.ChatRequest) (*llm.ChatResponse, func(*llm.ChatResponse)) {
+    if d.ChatCache == nil || d.Embeddings == nil { return nil, nil }
+    cc := strings.ToLower(r.Header.Get("Cache-Control"))
+    bypass := strings.EqualFold(r.Header.Get("X-Semantic-Cache"), "bypass")
+    // An admin skipping the policy prompt gets answers others must not see.
+    if off, _ := r.Context().Value(policyOffCtxKey{}).(bool); off { bypass = true }
+    scope, text, ok := chatCacheKey(req)
+    // Tenants only ever see their own cached answers.
+    if t := tenantOf(r.Context()); t != "" { scope = t + ":" + scope }
+    if bypass || !ok {
+        w.Header().Set("X-Semantic-Cache", "bypass")
+        return nil, nil
+    }
+    vecs, _, err := d.Embeddings.Embed(r.Context(), []string{text})
+    if err != nil || len(vecs) != 1 {
+        log.Printf("semantic cache: embed: %v", err)
+        w.Header().Set("X-Semantic-Cache", "bypass")
+        return nil, nil
+    }
+    if !strings.Contains(cc, "no-cache") {
+        if b, score, hit := d.ChatCache.Get(scope, vecs[0]); hit {
+            var resp llm.ChatResponse
+            if json.Unmarshal(b, &resp) == nil {
+                metrics.add("gollmcore_semantic_cache_total", "Chat completion lookups in the semantic cache.", `result="hit"`, 1)
+                w.Header().Set("X-Semantic-Cache", "hit")
+                w.Header().Set("X-Semantic-Cache-Score", strconv.FormatFloat(score, 'f', 4, 64))
+                resp.ID, resp.Created = newID("chatcmpl"), time.Now().Unix()
+                return &resp, nil
+            }
+        }
+        metrics.add("gollmcore_semantic_cache_total", "Chat completion lookups in the semantic cache.", `result="miss"`, 1)
+    }
+    w.Header().Set("X-Semantic-Cache", "miss")
+    if strings.Contains(cc, "no-store") { return nil, nil }
+    return nil, func(resp *llm.ChatResponse) {
+        if resp == nil || len(resp.Choices) == 0 { return }
+        // Nothing worth replaying.
+        if m := resp.Choices[0].Message; m.Content == "" && len(m.ToolCalls) == 0 { return }
+        if fr := resp.Choices[0].FinishReason; fr != "" && fr != "stop" && fr != "tool_calls" { return }
+        b, err := json.Marshal(resp)
+        if err == nil { d.ChatCache.Put(scope, vecs[0], b) }
+    }
+}
+
+// replayChat streams a cached response as a single chunk.
+func replayChat(w http.ResponseWriter, resp *llm.ChatResponse) {
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    finish := "stop"
+    var msg llm.Message
+    if len(resp.Choices) > 0 {
+        msg = resp.Choices[0].Message
+        if resp.Choices[0].FinishReason != "" { finish = resp.Choices[0].FinishReason }
+    }
+    b, _ := json.Marshal(llm.ChatChunk{ID: resp.ID, Object: "chat.completion.chunk", Created: resp.Created, Model: resp.Model,
+        Choices: []llm.ChunkChoice{{Delta: msg, FinishReason: &finish}}})
+    _, _ = w.Write([]byte("data: " + string(b) + "\n\ndata: [DONE]\n\n"))
+    if f, ok := w.(http.Flusher); ok { f.Flush() }
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "os"
+
+    "gollmcore/internal/services/audioclass"
+    "gollmcore/internal/services/stt"
+)
+
+// -------- Audio classification --------
+
+// minSpeechForLanguage is the share of speech below which no language is
+// detected: whisper would be guessing from music or noise.
+const minSpeechForLanguage = 0.1
+
+type classification struct {
+    audioclass.Result
+    Language            string  `json:"language,omitempty"`
+    LanguageProbability float64 `json:"language_probability,omitempty"`
+}
+
+// handleAudioClassify labels an uploaded PCM WAV recording and, when STT is
+// enabled and the audio holds speech, names its language using whisper
+// (language=false skips that).
+func handleAudioClassify(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    detect := d.STT != nil
+    switch r.FormValue("language") {
+    case "", "true":
+    case "false":
+        detect = false
+    default:
+        writeParamError(w, "language", "language must be true or false")
+        return
+    }
+    model := r.FormValue("model")
+    if model == "" { model = d.STTDefaultModel }
+
+    path, cleanup, ok := formAudio(w, r, d, "classify")
+    if !ok { return }
+    defer cleanup()
+    wav, err := os.ReadFile(path)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+
+    res, err := d.AudioClassifier.Classify(r.Context(), wav)
+    if errors.Is(err, stt.ErrNotPCMWAV) { writeParamError(w, "file", err.Error()); return }
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    out := classification{Result: res}
+    if detect && res.Scores["speech"] >= minSpeechForLanguage {
+        model = d.fitWhisperModel(model)
+        end := d.track(r.Context(), "stt", model)
+        err = withDeadline(r.Context(), "stt", d.Timeouts.withDefaults().STT, func(ctx context.Context) error {
+            release, err := d.sttLimiter.acquire(ctx)
+            if err != nil { return err }
+            defer release()
+            out.Language, out.LanguageProbability, err = d.STT.DetectLanguage(ctx, path, model)
+            return err
+        })
+        end(0, 0, err)
+        if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+
+    "gollmcore/internal/services/embeddings"
+)
+
+// -------- Request coalescing --------
+//
+// Identical concurrent TTS and embedding requests (same text and voice, or
+// the same input batch) share one computation; the followers receive the
+// leader's result. Each caller gets its own copy of the result, so one may
+// modify what it receives (truncate vectors, say) without the others
+// seeing it.
+
+type flight struct {
+    done chan struct{}
+    val  any
+    err  error
+}
+
+type coalescer struct {
+    kind  string
+    mu    sync.Mutex
+    calls map[[32]byte]*flight
+}
+
+func newCoalescer(kind string) *coalescer { return &coalescer{kind: kind, calls: map[[32]byte]*flight{}} }
+
+func (c *coalescer) do(ctx context.Context, key string, fn func() (any, error)) (any, error) {
+    k := sha256.Sum256([]byte(key))
+    for {
+        c.mu.Lock()
+        if f, ok := c.calls[k]; ok {
+            c.mu.Unlock()
+            select {
+            case <-f.done:
+            case <-ctx.Done():
+                return nil, ctx.Err()
+            }
+            // The leader's client went away; recompute rather than fail.
+            if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) { continue }
+            metrics.add("gollmcore_coalesced_requests_total", "Requests served by another identical in-flight request.", `kind="`+c.kind+`"`, 1)
+            return f.val, f.err
+        }
+        f := &flight{done: make(chan struct{})}
+        c.calls[k] = f
+        c.mu.Unlock()
+
+        metrics.add("gollmcore_coalesce_leader_requests_total", "Requests that ran their own computation.", `kind="`+c.kind+`"`, 1)
+        return c.lead(k, f, fn)
+    }
+}
+
+// lead runs fn for f and releases its followers however fn ends; a panic
+// becomes the flight's error and keeps unwinding to Recover.
+func (c *coalescer) lead(k [32]byte, f *flight, fn func() (any, error)) (any, error) {
+    panicked := true
+    defer func() {
+        if panicked { f.val, f.err = nil, fmt.Errorf("%s computation failed", c.kind) }
+        c.mu.Lock()
+        delete(c.calls, k)
+        c.mu.Unlock()
+        close(f.done)
+    }()
+    f.val, f.err = fn()
+    panicked = false
+    return f.val, f.err
+}
+
+type coalescingTTS struct {
+    next TTSService
+    c    *coalescer
+}
+
+func (t *coalescingTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    v, err := t.c.do(ctx, voice+"\x00"+text, func() (any, error) { return t.next.Synthesize(ctx, text, voice) })
+    b, _ := v.([]byte)
+    return bytes.Clone(b), err
+}
+
+type coalescingEmbeddings struct {
+    next embeddings.Service
+    c    *coalescer
+}
+
+type embedResult struct {
+    vecs  [][]float32
+    model string
+}
+
+func (e *coalescingEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    key := embeddings.OptionsFrom(ctx).String() + "\x00" + strings.Join(inputs, "\x00")
+    v, err := e.c.do(ctx, key, func() (any, error) {
+        vecs, model, err := e.next.Embed(ctx, inputs)
+        return embedResult{vecs, model}, err
+    })
+    res, _ := v.(embedResult)
+    return copyVectors(res.vecs), res.model, err
+}
+
+func copyVectors(vecs [][]float32) [][]float32 {
+    if vecs == nil { return nil }
+    out := make([][]float32, len(vecs))
+    for i, v := range vecs { out[i] = append([]float32(nil), v...) }
+    return out
+}
+
+func (e *coalescingEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(e.next, inputs) }
+
+// WithCoalescing wraps the TTS and embeddings services so identical
+// concurrent calls are computed once. Apply it once, before registering
+// routes, so REST and WebSocket callers share the same groups.
+func WithCoalescing(d Dependencies) Dependencies {
+    if d.TTS != nil { d.TTS = &coalescingTTS{next: d.TTS, c: newCoalescer("tts")} }
+    if d.Embeddings != nil { d.Embeddings = &coalescingEmbeddings{next: d.Embeddings, c: newCoalescer("embeddings")} }
+    return d
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+    "bufio"
+    "compress/gzip"
+    "compress/zlib"
+    "io"
+    "mime"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// -------- Compression --------
+//
+// Embeddings and batch responses can be megabytes of JSON, which gzip
+// shrinks several times over. Compress encodes JSON responses of at least
+// MinBytes for clients that send Accept-Encoding gzip or deflate. Smaller
+// responses, other content (SSE, audio, the test UI) and WebSocket
+// upgrades pass through unchanged.
+
+// CompressOptions configures Compress.
+type CompressOptions struct {
+    Enabled  bool
+    MinBytes int // smaller responses are sent as is (default 1024)
+}
+
+// Compress wraps next with response compression; when disabled it returns
+// next unchanged.
+func Compress(next http.Handler, o CompressOptions) http.Handler {
+    if !o.Enabled { return next }
+    if o.MinBytes <= 0 { o.MinBytes = 1024 }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        enc := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+        if enc == "" || r.Method == http.MethodHead { next.ServeHTTP(w, r); return }
+        cw := &compressWriter{ResponseWriter: w, enc: enc, min: o.MinBytes, status: http.StatusOK}
+        defer cw.finish()
+        next.ServeHTTP(cw, r)
+    })
+}
+
+// acceptedEncoding picks gzip, else deflate, from an Accept-Encoding
+// header, or "" when the client accepts neither.
+func acceptedEncoding(header string) string {
+    var deflate bool
+    for _, part := range strings.Split(header, ",") {
+        name, params, _ := strings.Cut(part, ";")
+        if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 { continue }
+        }
+        switch strings.ToLower(strings.TrimSpace(name)) {
+        case "gzip", "x-gzip", "*": return "gzip"
+        case "deflate": deflate = true
+        }
+    }
+    if deflate { return "deflate" }
+    return ""
+}
+
+// compressibleType reports whether a Content-Type is worth compressing.
+func compressibleType(contentType string) bool {
+    mt, _, _ := mime.ParseMediaType(contentType)
+    return mt == "application/json" || mt == "application/x-ndjson" || strings.HasSuffix(mt, "+json")
+}
+
+// encoder is what gzip.Writer and zlib.Writer have in common.
+type encoder interface {
+    io.WriteCloser
+    Flush() error
+    Reset(io.Writer)
+}
+
+var encoders = map[string]*sync.Pool{
+    "gzip":    {New: func() any { return gzip.NewWriter(io.Discard) }},
+    "deflate": {New: func() any { return zlib.NewWriter(io.Discard) }},
+}
+
+// compressWriter holds back the first min bytes of a JSON response; once
+// that much has been written it switches to compressing, otherwise the
+// buffer goes out as is when the handler returns or flushes.
+type compressWriter struct {
+    http.ResponseWriter
+    enc         string
+    min         int
+    status      int
+    wroteHeader bool
+    decided     bool
+    buf         []byte
+    zw          encoder // nil when passing through
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+    if c.wroteHeader { return }
+    c.wroteHeader, c.status = true, code
+    h := c.Header()
+    if !compressibleType(h.Get("Content-Type")) || h.Get("Content-Encoding") != "" || code < 200 || code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+        _ = c.start(false)
+        return
+    }
+    h.Add("Vary", "Accept-Encoding")
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+    if !c.wroteHeader { c.WriteHeader(http.StatusOK) }
+    if !c.decided {
+        c.buf = append(c.buf, b...)
+        if len(c.buf) < c.min { return len(b), nil }
+        if err := c.start(true); err != nil { return 0, err }
+        return len(b), nil
+    }
+    if c.zw != nil { return c.zw.Write(b) }
+    return c.ResponseWriter.Write(b)
+}
+
+// start sends the header, switching to compression when compress is set,
+// and then whatever was held back.
+func (c *compressWriter) start(compress bool) error {
+    c.decided = true
+    if compress {
+        c.Header().Set("Content-Encoding", c.enc)
+        c.Header().Del("Content-Length")
+        c.zw = encoders[c.enc].Get().(encoder)
+        c.zw.Reset(c.ResponseWriter)
+    }
+    c.ResponseWriter.WriteHeader(c.status)
+    buf := c.buf
+    c.buf = nil
+    if len(buf) == 0 { return nil }
+    var err error
+    if c.zw != nil { _, err = c.zw.Write(buf) } else { _, err = c.ResponseWriter.Write(buf) }
+    return err
+}
+
+// finish sends a response that stayed below the threshold, or ends the
+// compressed stream.
+func (c *compressWriter) finish() {
+    if !c.wroteHeader { return }
+    if !c.decided { _ = c.start(false); return }
+    if c.zw == nil { return }
+    _ = c.zw.Close()
+    encoders[c.enc].Put(c.zw)
+    c.zw = nil
+}
+
+func (c *compressWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+// Flush sends what is held back uncompressed: a handler that flushes is
+// streaming, and waiting for the threshold would delay it.
+func (c *compressWriter) Flush() {
+    if !c.wroteHeader { c.WriteHeader(http.StatusOK) }
+    if !c.decided { _ = c.start(false) }
+    if c.zw != nil { _ = c.zw.Flush() }
+    if f, ok := c.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
+
+func (c *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    c.decided = true
+    return http.NewResponseController(c.ResponseWriter).Hijack()
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+    "bufio"
+    "io"
+    "net"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// -------- Connection deadlines --------
+//
+// http.Server's ReadTimeout and WriteTimeout count from the start of the
+// request, so any value short enough to shed slow clients also cuts off
+// long SSE streams, generations and uploads. Deadlines applies them per
+// request instead: the body must arrive within the read timeout, and each
+// write or flush must complete within the write timeout, while the time a
+// handler spends working between writes is not counted. WebSocket upgrades
+// are left without deadlines.
+
+// DeadlineOptions are the per-request timeouts; zero disables one.
+type DeadlineOptions struct {
+    Read  time.Duration // to receive the request body
+    Write time.Duration // for each write or flush of the response
+}
+
+// Deadlines wraps next with the read and write deadlines of o. Place it
+// outermost, directly around the connection's ResponseWriter.
+func Deadlines(next http.Handler, o DeadlineOptions) http.Handler {
+    if o.Read <= 0 && o.Write <= 0 { return next }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        rc := http.NewResponseController(w)
+        dw := &deadlineWriter{ResponseWriter: w, rc: rc, write: o.Write}
+        // A keep-alive connection still carries the previous response's
+        // deadline.
+        if o.Write > 0 { _ = rc.SetWriteDeadline(time.Time{}) }
+        if o.Read > 0 && r.Body != nil && r.Body != http.NoBody {
+            _ = rc.SetReadDeadline(time.Now().Add(o.Read))
+            dw.reading = true
+            r.Body = &deadlineBody{ReadCloser: r.Body, done: dw.bodyRead}
+        }
+        next.ServeHTTP(dw, r)
+        // The server flushes what is still buffered after the handler
+        // returns.
+        if !dw.hijacked { dw.extend() }
+    })
+}
+
+type deadlineWriter struct {
+    http.ResponseWriter
+    rc       *http.ResponseController
+    write    time.Duration
+    reading  bool // a read deadline was set
+    readDone sync.Once
+    hijacked bool
+}
+
+// bodyRead lifts the read deadline once the body is in, so it does not
+// cancel the request while the handler works or streams.
+func (d *deadlineWriter) bodyRead() {
+    if d.reading { d.readDone.Do(func() { _ = d.rc.SetReadDeadline(time.Time{}) }) }
+}
+
+func (d *deadlineWriter) extend() {
+    if d.write > 0 { _ = d.rc.SetWriteDeadline(time.Now().Add(d.write)) }
+}
+
+func (d *deadlineWriter) WriteHeader(code int) {
+    d.bodyRead()
+    d.extend()
+    d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *deadlineWriter) Write(b []byte) (int, error) {
+    d.bodyRead()
+    d.extend()
+    return d.ResponseWriter.Write(b)
+}
+
+func (d *deadlineWriter) Unwrap() http.ResponseWriter { return d.ResponseWriter }
+
+func (d *deadlineWriter) Flush() {
+    d.extend()
+    _ = d.rc.Flush()
+}
+
+func (d *deadlineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    conn, rw, err := d.rc.Hijack()
+    if err != nil { return nil, nil, err }
+    d.hijacked = true
+    _ = conn.SetDeadline(time.Time{})
+    return conn, rw, nil
+}
+
+// deadlineBody reports the end of the request body.
+type deadlineBody struct {
+    io.ReadCloser
+    done func()
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+    n, err := b.ReadCloser.Read(p)
+    if err != nil { b.done() }
+    return n, err
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+    "net/http"
+    "runtime"
+    "time"
+
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/procs"
+)
+
+// -------- Runtime diagnostics --------
+
+var processStart = time.Now()
+
+func handleDebugVars(w http.ResponseWriter, r *http.Request) {
+    var m runtime.MemStats
+    runtime.ReadMemStats(&m)
+    writeJSON(w, http.StatusOK, map[string]any{
+        "go_version":     runtime.Version(),
+        "uptime_seconds": int64(time.Since(processStart).Seconds()),
+        "goroutines":     runtime.NumGoroutine(),
+        "num_cpu":        runtime.NumCPU(),
+        "gomaxprocs":     runtime.GOMAXPROCS(0),
+        "memory": map[string]any{
+            "alloc_bytes":       m.Alloc,
+            "heap_inuse_bytes":  m.HeapInuse,
+            "heap_objects":      m.HeapObjects,
+            "sys_bytes":         m.Sys,
+            "num_gc":            m.NumGC,
+            "gc_pause_total_ns": m.PauseTotalNs,
+        },
+        "onnx_sessions":   onnxrt.Sessions(),
+        "child_processes": procs.List(),
+    })
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+    "context"
+    "fmt"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Generation defaults --------
+//
+// Deployments with a fixed persona set a default system prompt and sampling
+// parameters once instead of in every client. Defaults only fill in what a
+// request leaves out: a request with its own system message, temperature,
+// max_tokens or stop keeps them. They are applied before the policy prompt,
+// which still comes first.
+
+// GenerationDefaults are chat request fields used when a request omits them.
+type GenerationDefaults struct {
+    SystemPrompt string
+    Temperature  *float64
+    MaxTokens    *int
+    Stop         []string
+}
+
+// LLMDefaults are the deployment-wide defaults plus overrides per model
+// name; a model's override replaces each field it sets.
+type LLMDefaults struct {
+    GenerationDefaults
+    Models map[string]GenerationDefaults
+}
+
+func (l LLMDefaults) empty() bool {
+    g := l.GenerationDefaults
+    return g.SystemPrompt == "" && g.Temperature == nil && g.MaxTokens == nil && len(g.Stop) == 0 && len(l.Models) == 0
+}
+
+// Check rejects values no upstream would accept.
+func (l LLMDefaults) Check() error {
+    check := func(name string, g GenerationDefaults) error {
+        if g.Temperature != nil && (*g.Temperature < 0 || *g.Temperature > 2) { return fmt.Errorf("%stemperature must be between 0 and 2", name) }
+        if g.MaxTokens != nil && *g.MaxTokens <= 0 { return fmt.Errorf("%smax_tokens must be positive", name) }
+        return nil
+    }
+    if err := check("", l.GenerationDefaults); err != nil { return err }
+    for model, g := range l.Models {
+        if err := check("models."+model+".", g); err != nil { return err }
+    }
+    return nil
+}
+
+// forModel resolves the defaults for model.
+func (l LLMDefaults) forModel(model string) GenerationDefaults {
+    g := l.GenerationDefaults
+    m, ok := l.Models[model]
+    if !ok { return g }
+    if m.SystemPrompt != "" { g.SystemPrompt = m.SystemPrompt }
+    if m.Temperature != nil { g.Temperature = m.Temperature }
+    if m.MaxTokens != nil { g.MaxTokens = m.MaxTokens }
+    if len(m.Stop) > 0 { g.Stop = m.Stop }
+    return g
+}
+
+type defaultsLLM struct {
+    next     LLMService
+    defaults LLMDefaults
+}
+
+// withLLMDefaults wraps d.LLM so requests pick up d.LLMDefaults. It must be
+// the outermost wrapper: the policy prompt is not a client system message.
+func (d Dependencies) withLLMDefaults() Dependencies {
+    if d.LLM == nil || d.LLMDefaults.empty() { return d }
+    d.LLM = &defaultsLLM{next: d.LLM, defaults: d.LLMDefaults}
+    return d
+}
+
+func (l *defaultsLLM) apply(req llm.ChatRequest) llm.ChatRequest {
+    model := req.Model
+    if model == "" { model = l.Model() }
+    g := l.defaults.forModel(model)
+    if req.Temperature == nil { req.Temperature = g.Temperature }
+    if req.MaxTokens == nil { req.MaxTokens = g.MaxTokens }
+    if len(req.Stop) == 0 { req.Stop = g.Stop }
+    if g.SystemPrompt == "" { return req }
+    for _, m := range req.Messages {
+        if m.Role == "system" { return req }
+    }
+    req.Messages = append([]llm.Message{{Role: "system", Content: g.SystemPrompt}}, req.Messages...)
+    return req
+}
+
+func (l *defaultsLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    return l.next.Chat(ctx, l.apply(req))
+}
+
+func (l *defaultsLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    return l.next.ChatStream(ctx, l.apply(req), onChunk)
+}
+
+// Model reports the upstream default model, when known.
+func (l *defaultsLLM) Model() string {
+    if m, ok := l.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+    "fmt"
+    "math"
+    "net/http"
+    "strings"
+    "unicode"
+)
+
+// -------- Document Embeddings Handler --------
+//
+// POST /v1/embeddings/document splits a long text into overlapping chunks,
+// embeds each chunk and can pool them into one document vector. Chunk
+// offsets are in characters (Unicode code points) of the input.
+
+const (
+    defaultChunkSize    = 1000
+    defaultChunkOverlap = 200
+    maxDocumentChars    = 1 << 20
+)
+
+type documentRequest struct {
+    Input        string `json:"input" validate:"required"`
+    // ChunkSize is the target chunk length in characters (default 1000).
+    ChunkSize    int    `json:"chunk_size,omitempty"`
+    // ChunkOverlap is how many characters consecutive chunks share
+    // (default 200, or a fifth of a smaller chunk_size).
+    ChunkOverlap *int   `json:"chunk_overlap,omitempty"`
+    // Pool adds the length-weighted mean of the chunk vectors, normalized.
+    Pool         bool   `json:"pool,omitempty"`
+    // Dimensions truncates the chunk and document vectors (see embeddingsRequest).
+    Dimensions   int    `json:"dimensions,omitempty"`
+}
+
+type documentChunk struct {
+    Index     int       `json:"index"`
+    Start     int       `json:"start"`
+    End       int       `json:"end"`
+    Text      string    `json:"text"`
+    Embedding []float32 `json:"embedding"`
+}
+
+type documentResponse struct {
+    Model     string          `json:"model"`
+    Chunks    []documentChunk `json:"chunks"`
+    Embedding []float32       `json:"embedding,omitempty"`
+    Usage     embeddingsUsage `json:"usage"`
+}
+
+func handleDocumentEmbeddings(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req documentRequest
+    if !decodeJSON(w, r, &req) { return }
+    text := []rune(req.Input)
+    if strings.TrimSpace(req.Input) == "" { writeParamError(w, "input", "missing input"); return }
+    if len(text) > maxDocumentChars { writeParamError(w, "input", fmt.Sprintf("input too long: %d characters (max %d)", len(text), maxDocumentChars)); return }
+    size := req.ChunkSize
+    if size == 0 { size = defaultChunkSize }
+    if size < 16 { writeParamError(w, "chunk_size", "chunk_size must be at least 16"); return }
+    overlap := defaultChunkOverlap
+    if size < defaultChunkSize { overlap = size / 5 }
+    if req.ChunkOverlap != nil { overlap = *req.ChunkOverlap }
+    if overlap < 0 || overlap >= size { writeParamError(w, "chunk_overlap", "chunk_overlap must be between 0 and chunk_size-1"); return }
+    if req.Dimensions < 0 { writeParamError(w, "dimensions", "dimensions must be positive"); return }
+
+    spans := chunkText(text, size, overlap)
+    chunks := make([]documentChunk, len(spans))
+    inputs := make([]string, len(spans))
+    for i, s := range spans {
+        inputs[i] = string(text[s[0]:s[1]])
+        chunks[i] = documentChunk{Index: i, Start: s[0], End: s[1], Text: inputs[i]}
+    }
+    model, err := d.embedBatches(r.Context(), inputs, 0, func(b embeddingsBatch) error {
+        for k, v := range b.Embeddings { chunks[b.Index+k].Embedding = v }
+        return nil
+    })
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    resp := documentResponse{Model: model, Chunks: chunks, Usage: d.chargeEmbeddings(r, inputs)}
+    // Pool the full vectors, then truncate everything.
+    vecs := make([][]float32, 0, len(chunks)+1)
+    for _, c := range chunks { vecs = append(vecs, c.Embedding) }
+    if req.Pool { vecs = append(vecs, poolChunks(chunks)) }
+    vecs, err = truncateVectors(vecs, req.Dimensions)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    for i := range chunks { chunks[i].Embedding = vecs[i] }
+    if req.Pool { resp.Embedding = vecs[len(chunks)] }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// chunkText returns [start, end) rune spans of about size characters that
+// overlap by about overlap. Ends move back to a whitespace boundary when
+// one lies in the last fifth of the chunk, and starts move forward to the
+// next word, so words are not cut.
+func chunkText(text []rune, size, overlap int) [][2]int {
+    var spans [][2]int
+    start := 0
+    for start < len(text) && unicode.IsSpace(text[start]) { start++ }
+    for start < len(text) {
+        end := start + size
+        if end >= len(text) {
+            end = len(text)
+        } else {
+            for cut := end; cut > end-size/5; cut-- {
+                if unicode.IsSpace(text[cut]) { end = cut; break }
+            }
+        }
+        trimmed := end
+        for trimmed > start && unicode.IsSpace(text[trimmed-1]) { trimmed-- }
+        spans = append(spans, [2]int{start, trimmed})
+        if end >= len(text) { break }
+        next := end - overlap
+        if next <= start { next = start + 1 }
+        // Start on a word boundary, but never skip past the previous end.
+        for next < end && next > 0 && !unicode.IsSpace(text[next-1]) { next++ }
+        for next < len(text) && unicode.IsSpace(text[next]) { next++ }
+        start = next
+    }
+    return spans
+}
+
+// poolChunks averages the chunk vectors weighted by chunk length and
+// normalizes the result to unit length.
+func poolChunks(chunks []documentChunk) []float32 {
+    if len(chunks) == 0 || len(chunks[0].Embedding) == 0 { return nil }
+    sum := make([]float64, len(chunks[0].Embedding))
+    for _, c := range chunks {
+        weight := float64(c.End - c.Start)
+        for i, x := range c.Embedding { sum[i] += weight * float64(x) }
+    }
+    var n float64
+    for _, x := range sum { n += x * x }
+    out := make([]float32, len(sum))
+    if n == 0 { return out }
+    n = math.Sqrt(n)
+    for i, x := range sum { out[i] = float32(x / n) }
+    return out
+}
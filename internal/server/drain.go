@@ -0,0 +1,118 @@
+package server
+
+import (
+    "context"
+    "log"
+    "net"
+    "net/http"
+    "sync"
+    "time"
+
+    "gollmcore/internal/procs"
+)
+
+// -------- Graceful shutdown --------
+//
+// Shutdown runs in three steps. First the listener closes and /healthz
+// starts answering 503 so load balancers move away; idle WebSocket
+// connections are closed with 1001 (going away) while connections with
+// work in progress finish it first. Then in-flight requests, streams and
+// generations get up to the drain timeout to complete. Finally everything
+// still running is cancelled through the request base context, which kills
+// child processes started with exec.CommandContext, and any child process
+// left over is killed outright.
+
+// Drainer tracks in-flight requests and coordinates shutdown.
+type Drainer struct {
+    base     context.Context
+    cancel   context.CancelFunc
+    draining chan struct{}
+    once     sync.Once
+    mu       sync.Mutex
+    inflight int
+    idle     chan struct{} // closed whenever inflight drops to zero
+}
+
+type drainerCtxKey struct{}
+
+func NewDrainer() *Drainer {
+    d := &Drainer{draining: make(chan struct{}), idle: make(chan struct{})}
+    close(d.idle)
+    d.base, d.cancel = context.WithCancel(context.Background())
+    d.base = context.WithValue(d.base, drainerCtxKey{}, d)
+    return d
+}
+
+// BaseContext is the http.Server BaseContext hook: request contexts derive
+// from it, so cancelling it aborts all remaining work.
+func (d *Drainer) BaseContext(net.Listener) context.Context { return d.base }
+
+// drainingFrom returns the draining signal of the request's server, or nil
+// when it has no Drainer.
+func drainingFrom(ctx context.Context) <-chan struct{} {
+    if d, ok := ctx.Value(drainerCtxKey{}).(*Drainer); ok { return d.draining }
+    return nil
+}
+
+// Handler counts requests in flight (including open WebSockets) and
+// reports 503 on /healthz once draining has started.
+func (d *Drainer) Handler(h http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/healthz" && d.isDraining() {
+            writeError(w, "server is shutting down", http.StatusServiceUnavailable)
+            return
+        }
+        d.add(1)
+        defer d.add(-1)
+        h.ServeHTTP(w, r)
+    })
+}
+
+func (d *Drainer) add(n int) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    if d.inflight == 0 && n > 0 { d.idle = make(chan struct{}) }
+    d.inflight += n
+    if d.inflight == 0 { close(d.idle) }
+}
+
+func (d *Drainer) wait() <-chan struct{} {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.idle
+}
+
+func (d *Drainer) isDraining() bool {
+    select {
+    case <-d.draining:
+        return true
+    default:
+        return false
+    }
+}
+
+// Shutdown stops srv and drains in-flight work for up to timeout, then
+// cancels what is left and kills remaining child processes.
+func (d *Drainer) Shutdown(srv *http.Server, timeout time.Duration) {
+    d.once.Do(func() { close(d.draining) })
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+    go func() { _ = srv.Shutdown(ctx) }()
+    select {
+    case <-d.wait():
+        log.Printf("drained all in-flight requests")
+    case <-ctx.Done():
+        d.mu.Lock()
+        n := d.inflight
+        d.mu.Unlock()
+        log.Printf("drain timeout (%s) reached with %d request(s) in flight; cancelling them", timeout, n)
+    }
+    d.cancel()
+    if n := procs.KillAll(); n > 0 { log.Printf("killed %d child process(es)", n) }
+    // Give cancelled handlers a moment to unwind and flush their errors.
+    select {
+    case <-d.wait():
+    case <-time.After(2 * time.Second):
+    }
+    _ = srv.Close()
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/services/speaker"
+    "gollmcore/internal/sessions"
+)
+
+// -------- Errors --------
+//
+// Every REST and WebSocket handler reports failures in the OpenAI error
+// shape: {"error": {"message", "type", "code", "param"}}. Type is derived
+// from the HTTP status; code names the specific failure where one is known.
+
+type apiError struct {
+    Message string  `json:"message"`
+    Type    string  `json:"type"`
+    Code    string  `json:"code"`
+    Param   *string `json:"param"`
+}
+
+type errorEnvelope struct {
+    Error apiError `json:"error"`
+}
+
+// errorKinds maps statuses to the error type and default code.
+var errorKinds = map[int][2]string{
+    http.StatusBadRequest:            {"invalid_request_error", "invalid_request"},
+    http.StatusUnauthorized:          {"authentication_error", "invalid_api_key"},
+    http.StatusForbidden:             {"permission_error", "forbidden"},
+    http.StatusNotFound:              {"not_found_error", "not_found"},
+    http.StatusMethodNotAllowed:      {"invalid_request_error", "method_not_allowed"},
+    http.StatusConflict:              {"conflict_error", "conflict"},
+    http.StatusRequestEntityTooLarge: {"invalid_request_error", "payload_too_large"},
+    http.StatusUnsupportedMediaType:  {"invalid_request_error", "unsupported_media_type"},
+    http.StatusUnprocessableEntity:   {"invalid_request_error", "unprocessable_input"},
+    http.StatusTooManyRequests:       {"rate_limit_error", "rate_limited"},
+    statusCancelled:                  {"invalid_request_error", "cancelled"},
+    http.StatusBadGateway:            {"upstream_error", "upstream_failed"},
+    http.StatusServiceUnavailable:    {"server_error", "unavailable"},
+    http.StatusGatewayTimeout:        {"timeout_error", "timeout"},
+}
+
+// errorCodes names known internal error categories; see statusFor.
+var errorCodes = []struct {
+    err    error
+    status int
+    code   string
+}{
+    {errUploadRejected, http.StatusUnsupportedMediaType, "upload_rejected"},
+    {errNoSpeech, http.StatusUnprocessableEntity, "no_speech"},
+    {errOverloaded, http.StatusTooManyRequests, "overloaded"},
+    {errInsufficientMemory, http.StatusServiceUnavailable, "insufficient_memory"},
+    {errInvalidDimensions, http.StatusBadRequest, "invalid_dimensions"},
+    {errLimitExceeded, http.StatusBadRequest, "limit_exceeded"},
+    {sessions.ErrNotFound, http.StatusNotFound, "session_not_found"},
+    {prompts.ErrNotFound, http.StatusNotFound, "prompt_not_found"},
+    {speaker.ErrNotFound, http.StatusNotFound, "speaker_not_found"},
+    {speaker.ErrTooShort, http.StatusUnprocessableEntity, "audio_too_short"},
+    {errSchemaMismatch, http.StatusBadGateway, "invalid_model_output"},
+    {errContentFlagged, http.StatusBadRequest, "content_flagged"},
+    {errReadOnly, http.StatusForbidden, "read_only"},
+    {models.ErrDownloadsDisabled, http.StatusServiceUnavailable, "model_not_installed"},
+    {context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+}
+
+func newAPIError(status int, code, param, msg string) apiError {
+    kind, ok := errorKinds[status]
+    if !ok {
+        kind = [2]string{"invalid_request_error", "invalid_request"}
+        if status >= 500 { kind = [2]string{"server_error", "internal_error"} }
+    }
+    if code == "" { code = kind[1] }
+    e := apiError{Message: msg, Type: kind[0], Code: code}
+    if param != "" { e.Param = &param }
+    return e
+}
+
+func writeAPIError(w http.ResponseWriter, status int, e apiError) {
+    h := w.Header()
+    h.Del("Content-Length")
+    h.Set("Content-Type", "application/json")
+    h.Set("X-Content-Type-Options", "nosniff")
+    if status == http.StatusTooManyRequests { h.Set("Retry-After", "1") }
+    w.WriteHeader(status)
+    _ = json.NewEncoder(w).Encode(errorEnvelope{Error: e})
+}
+
+// writeError is the JSON counterpart of http.Error.
+func writeError(w http.ResponseWriter, msg string, status int) {
+    writeAPIError(w, status, newAPIError(status, "", "", msg))
+}
+
+// writeParamError reports an invalid or missing request field.
+func writeParamError(w http.ResponseWriter, param, msg string) {
+    writeAPIError(w, http.StatusBadRequest, newAPIError(http.StatusBadRequest, "", param, msg))
+}
+
+// statusFor maps err to an HTTP status and code, using fallback for errors
+// outside the known categories.
+func statusFor(err error, fallback int) (int, string) {
+    for _, c := range errorCodes {
+        if errors.Is(err, c.err) { return c.status, c.code }
+    }
+    return fallback, ""
+}
+
+// writeServiceError reports err with the status of its category.
+func writeServiceError(w http.ResponseWriter, err error, fallback int) {
+    status, code := statusFor(err, fallback)
+    writeAPIError(w, status, newAPIError(status, code, "", err.Error()))
+}
+
+// writeSSEError reports err on an event stream that has already started.
+func writeSSEError(w http.ResponseWriter, err error) {
+    status, code := statusFor(err, http.StatusInternalServerError)
+    b, _ := json.Marshal(errorEnvelope{Error: newAPIError(status, code, "", err.Error())})
+    fmt.Fprintf(w, "event: error\ndata: %s\n\n", b)
+}
+
+// wsError is the WebSocket message for a failed request.
+func wsError(status int, msg string) map[string]any {
+    return map[string]any{"ok": false, "error": newAPIError(status, "", "", msg)}
+}
+
+// wsServiceError is wsError for err, categorized like writeServiceError.
+func wsServiceError(err error, fallback int) map[string]any {
+    status, code := statusFor(err, fallback)
+    return map[string]any{"ok": false, "error": newAPIError(status, code, "", err.Error())}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// -------- Event stream --------
+//
+// GET /v1/events is a server-sent event stream of what the server is doing
+// in the background, so dashboards can follow its state without polling:
+//   - download.progress and download.finished: model and binary downloads
+//     (see models.Progress).
+//   - model.loaded and model.unloaded: ONNX models opened and released.
+//   - llm.unreachable and llm.reachable: the LLM upstream going away and
+//     coming back, e.g. a llama-server restart (see WatchLLM).
+//   - queue.saturated and queue.cleared: a service's queue filling up so
+//     calls get 429, and emptying again.
+// Each event is sent as "event: <type>" with a JSON envelope {"type",
+// "time", "data"}. Events are not stored: a client sees those published
+// while it is connected. A client that falls behind loses events rather
+// than slowing the server.
+
+// eventBuffer is how many events a slow subscriber may fall behind.
+const eventBuffer = 64
+
+// Event is one entry on the event stream.
+type Event struct {
+    Type string    `json:"type"`
+    Time time.Time `json:"time"`
+    Data any       `json:"data"`
+}
+
+// Events broadcasts events to the clients of /v1/events.
+type Events struct {
+    mu   sync.Mutex
+    subs map[chan Event]struct{}
+}
+
+func NewEvents() *Events { return &Events{subs: map[chan Event]struct{}{}} }
+
+// Publish sends an event of type typ to every subscriber; it never blocks.
+func (e *Events) Publish(typ string, data any) {
+    if e == nil { return }
+    ev := Event{Type: typ, Time: time.Now().UTC(), Data: data}
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    for ch := range e.subs {
+        select {
+        case ch <- ev:
+        default:
+        }
+    }
+}
+
+func (e *Events) subscribe() (<-chan Event, func()) {
+    ch := make(chan Event, eventBuffer)
+    e.mu.Lock()
+    e.subs[ch] = struct{}{}
+    e.mu.Unlock()
+    return ch, func() { e.mu.Lock(); delete(e.subs, ch); e.mu.Unlock() }
+}
+
+func handleEvents(w http.ResponseWriter, r *http.Request, e *Events) {
+    flusher, ok := w.(http.Flusher)
+    if !ok { writeError(w, "streaming unsupported", http.StatusInternalServerError); return }
+    events, unsubscribe := e.subscribe()
+    defer unsubscribe()
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    fmt.Fprint(w, ": connected\n\n")
+    flusher.Flush()
+    keepalive := time.NewTicker(15 * time.Second)
+    defer keepalive.Stop()
+    draining := drainingFrom(r.Context())
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case <-draining:
+            return
+        case ev := <-events:
+            b, _ := json.Marshal(ev)
+            if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, b); err != nil { return }
+        case <-keepalive.C:
+            if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil { return }
+        }
+        flusher.Flush()
+    }
+}
+
+// WatchLLM pings llm (when it can be pinged) every interval until ctx ends,
+// publishing llm.unreachable and llm.reachable when its state changes.
+func (e *Events) WatchLLM(ctx context.Context, llm any, interval time.Duration) {
+    p, ok := llm.(interface{ Ping(context.Context) error })
+    if e == nil || !ok { return }
+    up := true
+    t := time.NewTicker(interval)
+    defer t.Stop()
+    for {
+        err := p.Ping(ctx)
+        if ctx.Err() != nil { return }
+        if err != nil && up { e.Publish("llm.unreachable", map[string]string{"error": err.Error()}) }
+        if err == nil && !up { e.Publish("llm.reachable", struct{}{}) }
+        up = err == nil
+        select {
+        case <-ctx.Done():
+            return
+        case <-t.C:
+        }
+    }
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+    "bufio"
+    "context"
+    "net"
+    "net/http"
+    "sync"
+    "sync/atomic"
+)
+
+// -------- Generation cancellation --------
+//
+// A client that gives up on a chat, transcription or synthesis should not
+// leave the server working until the timeout. Every such request gets a
+// generation ID, returned in the X-Generation-ID response header (clients
+// may choose it by sending the header), and DELETE /v1/generations/{id}
+// cancels the request's context from anywhere: whisper and piper are
+// killed, and the LLM upstream connection is closed, which stops
+// llama-server and Ollama generating. A cancelled request that has not
+// started its response answers 499 with code "cancelled"; a stream that
+// has ends early. Only the API key that started a generation, or an admin
+// key, may cancel it. IDs are scoped per API key: a client-picked ID only
+// conflicts with the same key's running generations, so another key's IDs
+// cannot be probed.
+
+// statusCancelled is the status of a cancelled request, after nginx's
+// "client closed request".
+const statusCancelled = 499
+
+type generation struct {
+    cancel    context.CancelFunc
+    cancelled atomic.Bool
+}
+
+// generationKey names a running generation: the API key that started it
+// and its ID.
+type generationKey struct{ key, id string }
+
+type generationStore struct {
+    mu      sync.Mutex
+    running map[generationKey]*generation
+}
+
+func newGenerationStore() *generationStore { return &generationStore{running: map[generationKey]*generation{}} }
+
+// wrap registers each request to h as a cancellable generation.
+func (s *generationStore) wrap(h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get("X-Generation-ID")
+        if id == "" { id = newID("gen") }
+        ctx, cancel := context.WithCancel(r.Context())
+        defer cancel()
+        g, k := &generation{cancel: cancel}, generationKey{requestAPIKey(r), id}
+        s.mu.Lock()
+        if _, dup := s.running[k]; dup {
+            s.mu.Unlock()
+            writeAPIError(w, http.StatusConflict, newAPIError(http.StatusConflict, "", "X-Generation-ID", "a generation with this id is already running"))
+            return
+        }
+        s.running[k] = g
+        s.mu.Unlock()
+        defer func() {
+            s.mu.Lock()
+            delete(s.running, k)
+            s.mu.Unlock()
+        }()
+
+        w.Header().Set("X-Generation-ID", id)
+        gw := &generationWriter{ResponseWriter: w, g: g}
+        h(gw, r.WithContext(ctx))
+        if g.cancelled.Load() && !gw.started {
+            writeAPIError(w, statusCancelled, newAPIError(statusCancelled, "cancelled", "", "generation "+id+" was cancelled"))
+        }
+    }
+}
+
+// cancel stops generation id started with key and reports whether it was
+// running. An admin cancels every running generation with that id,
+// whichever key started it.
+func (s *generationStore) cancel(id, key string, admin bool) bool {
+    s.mu.Lock()
+    var gs []*generation
+    if admin {
+        for k, g := range s.running {
+            if k.id == id { gs = append(gs, g) }
+        }
+    } else if g, ok := s.running[generationKey{key, id}]; ok {
+        gs = append(gs, g)
+    }
+    s.mu.Unlock()
+    for _, g := range gs {
+        g.cancelled.Store(true)
+        g.cancel()
+    }
+    return len(gs) > 0
+}
+
+func handleCancelGeneration(w http.ResponseWriter, r *http.Request, s *generationStore) {
+    id := r.PathValue("id")
+    // Another key's generation is reported like a missing one, so IDs
+    // cannot be probed.
+    if !s.cancel(id, requestAPIKey(r), isAdmin(r.Context())) { writeAPIError(w, http.StatusNotFound, newAPIError(http.StatusNotFound, "generation_not_found", "", "no running generation "+id)); return }
+    writeJSON(w, http.StatusOK, cancelledGeneration{ID: id, Object: "generation", Cancelled: true})
+}
+
+type cancelledGeneration struct {
+    ID        string `json:"id"`
+    Object    string `json:"object"`
+    Cancelled bool   `json:"cancelled"`
+}
+
+// generationWriter drops what the handler writes after a cancellation that
+// came before the response started, so wrap can answer 499 instead.
+type generationWriter struct {
+    http.ResponseWriter
+    g       *generation
+    started bool
+}
+
+func (gw *generationWriter) pass() bool {
+    if !gw.started && gw.g.cancelled.Load() { return false }
+    gw.started = true
+    return true
+}
+
+func (gw *generationWriter) WriteHeader(code int) {
+    if gw.pass() { gw.ResponseWriter.WriteHeader(code) }
+}
+
+func (gw *generationWriter) Write(b []byte) (int, error) {
+    if !gw.pass() { return len(b), nil }
+    return gw.ResponseWriter.Write(b)
+}
+
+func (gw *generationWriter) Flush() {
+    if !gw.pass() { return }
+    if f, ok := gw.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
+
+func (gw *generationWriter) Unwrap() http.ResponseWriter { return gw.ResponseWriter }
+
+func (gw *generationWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    gw.started = true
+    return http.NewResponseController(gw.ResponseWriter).Hijack()
+}
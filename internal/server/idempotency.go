@@ -0,0 +1,117 @@
+package server
+
+import (
+    "bytes"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// -------- Idempotency keys --------
+//
+// Expensive POSTs honor an Idempotency-Key header: the first request with a
+// key runs normally while its response is recorded; retries within the TTL get
+// the recorded response back (marked with Idempotent-Replayed: true) instead
+// of another inference run. A retry that arrives while the original is still
+// running waits for it. Server errors are not kept, so they can be retried.
+
+const defaultIdempotencyTTL = 10 * time.Minute
+
+type idemEntry struct {
+    done    chan struct{}
+    keep    bool
+    status  int
+    header  http.Header
+    body    []byte
+    expires time.Time
+}
+
+type idempotencyStore struct {
+    ttl     time.Duration
+    mu      sync.Mutex
+    entries map[string]*idemEntry
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+    if ttl <= 0 { ttl = defaultIdempotencyTTL }
+    return &idempotencyStore{ttl: ttl, entries: map[string]*idemEntry{}}
+}
+
+// wrap applies idempotency handling to h.
+func (s *idempotencyStore) wrap(h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        key := r.Header.Get("Idempotency-Key")
+        if key == "" || r.Method != http.MethodPost { h(w, r); return }
+        // Scope keys per tenant, caller and endpoint so clients cannot collide.
+        key = tenantOf(r.Context()) + "\x00" + requestAPIKey(r) + "\x00" + r.URL.Path + "\x00" + key
+
+        for {
+            s.mu.Lock()
+            now := time.Now()
+            for k, e := range s.entries {
+                if !e.expires.IsZero() && now.After(e.expires) { delete(s.entries, k) }
+            }
+            e, ok := s.entries[key]
+            if !ok {
+                e = &idemEntry{done: make(chan struct{})}
+                s.entries[key] = e
+                s.mu.Unlock()
+                s.record(w, r, h, key, e)
+                return
+            }
+            s.mu.Unlock()
+
+            select {
+            case <-e.done:
+            case <-r.Context().Done():
+                return
+            }
+            if !e.keep { continue } // original failed and was dropped; run again
+            for k, v := range e.header { w.Header()[k] = v }
+            w.Header().Set("Idempotent-Replayed", "true")
+            w.WriteHeader(e.status)
+            _, _ = w.Write(e.body)
+            return
+        }
+    }
+}
+
+func (s *idempotencyStore) record(w http.ResponseWriter, r *http.Request, h http.HandlerFunc, key string, e *idemEntry) {
+    rec := &idemRecorder{ResponseWriter: w, status: http.StatusOK}
+    defer func() {
+        s.mu.Lock()
+        if rec.status < 500 && r.Context().Err() == nil {
+            e.keep, e.status, e.header, e.body = true, rec.status, w.Header().Clone(), rec.buf.Bytes()
+            // rec.buf holds the body before Compress encoded it.
+            e.header.Del("Content-Encoding")
+            e.expires = time.Now().Add(s.ttl)
+        } else {
+            delete(s.entries, key)
+        }
+        s.mu.Unlock()
+        close(e.done)
+    }()
+    h(rec, r)
+}
+
+// idemRecorder tees the response to the client and a buffer, keeping
+// streaming (Flush) working for SSE endpoints.
+type idemRecorder struct {
+    http.ResponseWriter
+    status int
+    buf    bytes.Buffer
+}
+
+func (rw *idemRecorder) WriteHeader(code int) {
+    rw.status = code
+    rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *idemRecorder) Write(b []byte) (int, error) {
+    rw.buf.Write(b)
+    return rw.ResponseWriter.Write(b)
+}
+
+func (rw *idemRecorder) Flush() {
+    if f, ok := rw.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "log"
+    "regexp"
+    "strings"
+
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Model I/O log --------
+//
+// Diagnosing a bad transcript or a strange reply needs to know what the
+// model saw, but logs should not collect user content. With an IOLog set,
+// WithIOLog logs every prompt and reply, synthesized text and embedding
+// input, and transcribe every transcript, as one line each: the length, a
+// short SHA-256 of the full text (so repeats can be matched) and only the
+// first MaxChars characters, after each Redact pattern's matches have been
+// replaced by "[redacted]". Off by default.
+
+// defaultIOLogChars is how much of each text is logged by default.
+const defaultIOLogChars = 80
+
+// IOLog writes redacted, truncated model inputs and outputs to the log.
+type IOLog struct {
+    maxChars int
+    redact   []*regexp.Regexp
+}
+
+// NewIOLog compiles the redaction patterns; maxChars <= 0 means
+// defaultIOLogChars.
+func NewIOLog(maxChars int, redact []string) (*IOLog, error) {
+    if maxChars <= 0 { maxChars = defaultIOLogChars }
+    l := &IOLog{maxChars: maxChars}
+    for _, p := range redact {
+        re, err := regexp.Compile(p)
+        if err != nil { return nil, fmt.Errorf("redact pattern %q: %w", p, err) }
+        l.redact = append(l.redact, re)
+    }
+    return l, nil
+}
+
+// summary is how text appears in the log.
+func (l *IOLog) summary(text string) string {
+    sum := sha256.Sum256([]byte(text))
+    shown := text
+    for _, re := range l.redact { shown = re.ReplaceAllString(shown, "[redacted]") }
+    if r := []rune(shown); len(r) > l.maxChars { shown = string(r[:l.maxChars]) + "…" }
+    return fmt.Sprintf("chars=%d sha256:%s %q", len([]rune(text)), hex.EncodeToString(sum[:6]), shown)
+}
+
+// record logs one text of kind (e.g. "llm.prompt") with attrs such as the
+// model; it does nothing on a nil IOLog.
+func (l *IOLog) record(kind, attrs, text string) {
+    if l == nil { return }
+    fields := []string{"io", kind}
+    if attrs != "" { fields = append(fields, attrs) }
+    log.Printf("%s %s", strings.Join(fields, " "), l.summary(text))
+}
+
+// WithIOLog wraps the LLM, TTS and embeddings services so their inputs and
+// outputs are logged to d.IOLog; transcripts are logged by transcribe.
+func WithIOLog(d Dependencies) Dependencies {
+    if d.IOLog == nil { return d }
+    if d.LLM != nil { d.LLM = &ioLogLLM{next: d.LLM, log: d.IOLog} }
+    if d.TTS != nil { d.TTS = &ioLogTTS{next: d.TTS, log: d.IOLog} }
+    if d.Embeddings != nil { d.Embeddings = &ioLogEmbeddings{next: d.Embeddings, log: d.IOLog} }
+    return d
+}
+
+type ioLogLLM struct {
+    next LLMService
+    log  *IOLog
+}
+
+// prompt logs the last message of req, the one the reply answers.
+func (l *ioLogLLM) prompt(req llm.ChatRequest) {
+    if len(req.Messages) == 0 { return }
+    m := req.Messages[len(req.Messages)-1]
+    l.log.record("llm.prompt", fmt.Sprintf("model=%s role=%s messages=%d", l.model(req), m.Role, len(req.Messages)), m.Content)
+}
+
+func (l *ioLogLLM) reply(req llm.ChatRequest, resp *llm.ChatResponse, err error) {
+    if err != nil || resp == nil { return }
+    l.log.record("llm.reply", "model="+l.model(req), resp.Text())
+}
+
+func (l *ioLogLLM) model(req llm.ChatRequest) string {
+    if req.Model != "" { return req.Model }
+    return l.Model()
+}
+
+func (l *ioLogLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    l.prompt(req)
+    resp, err := l.next.Chat(ctx, req)
+    l.reply(req, resp, err)
+    return resp, err
+}
+
+func (l *ioLogLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    l.prompt(req)
+    resp, err := l.next.ChatStream(ctx, req, onChunk)
+    l.reply(req, resp, err)
+    return resp, err
+}
+
+func (l *ioLogLLM) Model() string {
+    if m, ok := l.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type ioLogTTS struct {
+    next TTSService
+    log  *IOLog
+}
+
+func (l *ioLogTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    l.log.record("tts.text", "voice="+voice, text)
+    return l.next.Synthesize(ctx, text, voice)
+}
+
+type ioLogEmbeddings struct {
+    next embeddings.Service
+    log  *IOLog
+}
+
+// Embed logs the first input only, so large batches stay one line.
+func (l *ioLogEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    if len(inputs) > 0 { l.log.record("embeddings.input", fmt.Sprintf("inputs=%d", len(inputs)), inputs[0]) }
+    return l.next.Embed(ctx, inputs)
+}
+
+func (l *ioLogEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(l.next, inputs) }
@@ -0,0 +1,256 @@
+package server
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+// jobTTL is how long finished jobs stay queryable.
+const jobTTL = time.Hour
+
+// -------- Job persistence --------
+//
+// With a JobsDB path, jobs are also written to a bbolt file, so their
+// state survives a restart (or a standby handoff) and GET /v1/jobs/{id}
+// keeps answering. On boot, recover looks at the jobs the last process
+// left queued or running: transcriptions of resumable uploads, whose audio
+// is still in the upload dir, run again; the others (pipelines and chat
+// batches, whose inputs are not kept) fail with errInterrupted.
+
+var jobsBucket = []byte("jobs")
+
+var errInterrupted = errors.New("interrupted by a server restart")
+
+type JobStep struct {
+    Type   string `json:"type"`
+    Status string `json:"status"`
+    Output any    `json:"output,omitempty"`
+}
+
+type Job struct {
+    ID         string    `json:"id"`
+    Kind       string    `json:"kind"`
+    Status     string    `json:"status"` // queued | running | succeeded | failed
+    Steps      []JobStep `json:"steps,omitempty"`
+    Result     any       `json:"result,omitempty"`
+    Error      string    `json:"error,omitempty"`
+    // UploadID is the resumable upload a transcription job transcribes.
+    UploadID   string    `json:"upload_id,omitempty"`
+    // WebhookURL is notified when the job finishes (see Webhooks).
+    WebhookURL string    `json:"webhook_url,omitempty"`
+    CreatedAt  time.Time `json:"created_at"`
+    UpdatedAt  time.Time `json:"updated_at"`
+    Owner      jobOwner  `json:"-"`
+}
+
+// jobOwner is who started a job: the request's tenant and a fingerprint of
+// its API key. Other tenants and keys get 404 for it, as if it did not
+// exist; an admin key sees its tenant's jobs.
+type jobOwner struct {
+    Tenant string `json:"tenant,omitempty"`
+    Key    string `json:"key,omitempty"`
+}
+
+func requestJobOwner(r *http.Request) jobOwner {
+    o := jobOwner{Tenant: tenantOf(r.Context())}
+    if key := requestAPIKey(r); key != "" { o.Key = keyFingerprint(key) }
+    return o
+}
+
+func (o jobOwner) sees(r *http.Request) bool {
+    req := requestJobOwner(r)
+    return o.Tenant == req.Tenant && (o.Key == req.Key || isAdmin(r.Context()))
+}
+
+type jobOwnerCtxKey struct{}
+
+// withJobOwner returns ctx carrying o, and o's tenant as the context of
+// one of its requests would, so work done for the job stays in the tenant.
+func withJobOwner(ctx context.Context, o jobOwner) context.Context {
+    if o.Tenant != "" { ctx = context.WithValue(ctx, tenantCtxKey{}, o.Tenant) }
+    return context.WithValue(ctx, jobOwnerCtxKey{}, o)
+}
+
+func jobOwnerOf(ctx context.Context) jobOwner {
+    o, _ := ctx.Value(jobOwnerCtxKey{}).(jobOwner)
+    return o
+}
+
+// storedJob is a job as kept in the job file, owner included.
+type storedJob struct {
+    *Job
+    Owner jobOwner `json:"owner"`
+}
+
+func (j *Job) finished() bool { return j.Status == "succeeded" || j.Status == "failed" }
+
+type jobStore struct {
+    mu     sync.Mutex
+    jobs   map[string]*Job
+    path   string // bbolt file; "" keeps jobs in memory only
+    openMu sync.Mutex
+    db     *bolt.DB
+    // notify, if set, is called with each job that has just finished.
+    notify func(Job)
+}
+
+func newJobStore(path string) *jobStore { return &jobStore{jobs: map[string]*Job{}, path: path} }
+
+// open opens the job file on first use, loading the jobs in it. Like the
+// session store it waits at most 5s for the file lock.
+func (s *jobStore) open() (*bolt.DB, error) {
+    s.openMu.Lock()
+    defer s.openMu.Unlock()
+    if s.db != nil || s.path == "" { return s.db, nil }
+    if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil { return nil, err }
+    db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+    if err != nil { return nil, err }
+    loaded := map[string]*Job{}
+    err = db.Update(func(tx *bolt.Tx) error {
+        b, err := tx.CreateBucketIfNotExists(jobsBucket)
+        if err != nil { return err }
+        return b.ForEach(func(k, v []byte) error {
+            sj := storedJob{Job: &Job{}}
+            if json.Unmarshal(v, &sj) == nil {
+                sj.Job.Owner = sj.Owner
+                loaded[sj.ID] = sj.Job
+            }
+            return nil
+        })
+    })
+    if err != nil { db.Close(); return nil, err }
+    s.mu.Lock()
+    for id, j := range loaded {
+        if _, ok := s.jobs[id]; !ok { s.jobs[id] = j }
+    }
+    s.mu.Unlock()
+    s.db = db
+    return db, nil
+}
+
+// persistLocked writes j to the job file, if any; the caller holds s.mu.
+func (s *jobStore) persistLocked(j *Job) {
+    if s.db == nil { return }
+    b, err := json.Marshal(storedJob{Job: j, Owner: j.Owner})
+    if err == nil { err = s.db.Update(func(tx *bolt.Tx) error { return tx.Bucket(jobsBucket).Put([]byte(j.ID), b) }) }
+    if err != nil { log.Printf("job %s: not saved: %v", j.ID, err) }
+}
+
+// ready opens the job file if needed. If that fails, jobs are kept in
+// memory from then on, rather than every call waiting for the lock.
+func (s *jobStore) ready() {
+    if _, err := s.open(); err != nil {
+        log.Printf("job store %s: %v; jobs are kept in memory", s.path, err)
+        s.openMu.Lock()
+        s.path = ""
+        s.openMu.Unlock()
+    }
+}
+
+// add stores a new queued job made from j's kind, steps, upload and
+// webhook, owned by the job owner of ctx (see jobContext).
+func (s *jobStore) add(ctx context.Context, j Job) *Job {
+    s.ready()
+    now := time.Now().UTC()
+    n := &Job{ID: newID("job"), Kind: j.Kind, Status: "queued", Steps: j.Steps, UploadID: j.UploadID, WebhookURL: j.WebhookURL, CreatedAt: now, UpdatedAt: now, Owner: jobOwnerOf(ctx)}
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.gcLocked(now)
+    s.jobs[n.ID] = n
+    s.persistLocked(n)
+    return n
+}
+
+// update applies fn to the job under the store lock.
+func (s *jobStore) update(id string, fn func(j *Job)) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if j, ok := s.jobs[id]; ok {
+        was := j.finished()
+        fn(j)
+        j.UpdatedAt = time.Now().UTC()
+        s.persistLocked(j)
+        if !was && j.finished() && s.notify != nil {
+            cp := *j
+            cp.Steps = append([]JobStep(nil), j.Steps...)
+            go s.notify(cp)
+        }
+    }
+}
+
+// get returns a snapshot of the job.
+func (s *jobStore) get(id string) (Job, bool) {
+    s.ready()
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    j, ok := s.jobs[id]
+    if !ok { return Job{}, false }
+    cp := *j
+    cp.Steps = append([]JobStep(nil), j.Steps...)
+    return cp, true
+}
+
+func (s *jobStore) gcLocked(now time.Time) {
+    var expired [][]byte
+    for id, j := range s.jobs {
+        if j.finished() && now.Sub(j.UpdatedAt) > jobTTL {
+            delete(s.jobs, id)
+            expired = append(expired, []byte(id))
+        }
+    }
+    if s.db == nil || len(expired) == 0 { return }
+    _ = s.db.Update(func(tx *bolt.Tx) error {
+        for _, k := range expired { _ = tx.Bucket(jobsBucket).Delete(k) }
+        return nil
+    })
+}
+
+// recover deals with the jobs a previous process left unfinished, once the
+// job file can be opened (a standby waits here until the active instance
+// exits): each is passed to resume, which restarts it and reports true, or
+// it is failed with errInterrupted. resume may be nil.
+func (s *jobStore) recover(resume func(j Job) bool) {
+    for {
+        _, err := s.open()
+        if err == nil { break }
+        if !errors.Is(err, bolt.ErrTimeout) { log.Printf("job store %s: %v; unfinished jobs are not recovered", s.path, err); return }
+    }
+    s.mu.Lock()
+    var unfinished []Job
+    for _, j := range s.jobs {
+        if !j.finished() { unfinished = append(unfinished, *j) }
+    }
+    s.mu.Unlock()
+    for _, j := range unfinished {
+        if resume != nil && resume(j) { log.Printf("resuming %s job %s", j.Kind, j.ID); continue }
+        s.update(j.ID, func(j *Job) {
+            j.Status, j.Error = "failed", errInterrupted.Error()
+            for i := range j.Steps { if j.Steps[i].Status == "running" { j.Steps[i].Status = "failed" } }
+        })
+        log.Printf("%s job %s was %s", j.Kind, j.ID, errInterrupted)
+    }
+}
+
+func handleGetJob(w http.ResponseWriter, r *http.Request, jobs *jobStore) {
+    j, ok := jobs.get(r.PathValue("id"))
+    if !ok || !j.Owner.sees(r) { writeError(w, "job not found", http.StatusNotFound); return }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(j)
+}
+
+func newID(prefix string) string {
+    b := make([]byte, 12)
+    _, _ = rand.Read(b)
+    return prefix + "_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/tracing"
+)
+
+// -------- Concurrency limits --------
+//
+// Each service may cap how many calls run at once. Calls beyond the cap
+// wait in a bounded queue per priority; once their queue is full they fail
+// immediately with errOverloaded (429) instead of piling more work onto the
+// host. A freed slot is handed straight to a waiting call, chosen by
+// weighted round robin between the priorities (see priority.go).
+
+var errOverloaded = errors.New("server busy")
+
+// Limit caps one service. MaxConcurrent <= 0 means unlimited.
+type Limit struct {
+    MaxConcurrent int
+    // MaxQueue is how many interactive calls may wait for a slot (default 16).
+    MaxQueue      int
+}
+
+type Limits struct {
+    STT         Limit
+    TTS         Limit
+    LLM         Limit
+    Embeddings  Limit
+    Interactive PriorityClass
+    Background  PriorityClass
+}
+
+const defaultMaxQueue = 16
+
+type limiter struct {
+    service  string
+    max      int
+    maxQueue [numPriorities]int
+    weight   [numPriorities]int
+    mu       sync.Mutex
+    running  int
+    waiting  [numPriorities][]chan struct{}
+    credit   [numPriorities]int
+    // saturated is set from a rejection until the queues empty, bracketing
+    // the queue.saturated and queue.cleared events.
+    saturated bool
+    events    *Events
+}
+
+func newLimiter(service string, l Limit, classes [numPriorities]PriorityClass, events *Events) *limiter {
+    if l.MaxConcurrent <= 0 { return nil }
+    if l.MaxQueue <= 0 { l.MaxQueue = defaultMaxQueue }
+    lim := &limiter{service: service, max: l.MaxConcurrent, events: events}
+    for p, c := range classes {
+        lim.maxQueue[p], lim.weight[p] = c.MaxQueue, c.Weight
+    }
+    if lim.maxQueue[Interactive] <= 0 { lim.maxQueue[Interactive] = l.MaxQueue }
+    if lim.maxQueue[Background] <= 0 { lim.maxQueue[Background] = 4 * l.MaxQueue }
+    if lim.weight[Interactive] <= 0 { lim.weight[Interactive] = 4 }
+    if lim.weight[Background] <= 0 { lim.weight[Background] = 1 }
+    return lim
+}
+
+// acquire takes a slot, waiting in the queue of the context's priority if
+// there is room. The returned function releases the slot. A nil limiter
+// never blocks.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+    if l == nil { return func() {}, nil }
+    p := priorityOf(ctx, Interactive)
+    l.mu.Lock()
+    if l.running < l.max && len(l.waiting[Interactive])+len(l.waiting[Background]) == 0 {
+        l.running++
+        l.mu.Unlock()
+        return l.release, nil
+    }
+    labels := `service="` + l.service + `",priority="` + p.String() + `"`
+    if len(l.waiting[p]) >= l.maxQueue[p] {
+        if !l.saturated {
+            l.saturated = true
+            l.events.Publish("queue.saturated", map[string]any{"service": l.service, "priority": p.String(), "running": l.running, "queued": len(l.waiting[Interactive]) + len(l.waiting[Background])})
+        }
+        l.mu.Unlock()
+        metrics.add("gollmcore_rejected_requests_total", "Requests rejected because the service queue was full.", labels, 1)
+        return nil, fmt.Errorf("%w: too many concurrent %s requests, retry later", errOverloaded, l.service)
+    }
+    ready := make(chan struct{})
+    l.waiting[p] = append(l.waiting[p], ready)
+    l.mu.Unlock()
+    // Start the clock before the request shows up as queued in metrics.
+    start := time.Now()
+    metrics.add("gollmcore_queued_requests_total", "Requests that waited for a free service slot.", labels, 1)
+    defer func() { tracing.TimingsFrom(ctx).AddQueue(time.Since(start)) }()
+    select {
+    case <-ready:
+        return l.release, nil
+    case <-ctx.Done():
+    }
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    for i, c := range l.waiting[p] {
+        if c == ready {
+            l.waiting[p] = append(l.waiting[p][:i], l.waiting[p][i+1:]...)
+            return nil, ctx.Err()
+        }
+    }
+    // The slot was handed over as the context ended; pass it on.
+    l.releaseLocked()
+    return nil, ctx.Err()
+}
+
+func (l *limiter) release() {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.releaseLocked()
+}
+
+// releaseLocked hands the slot to the next waiter, if any. While both
+// priorities wait, smooth weighted round robin picks between them.
+func (l *limiter) releaseLocked() {
+    next, total := Priority(-1), 0
+    for p := Priority(0); p < numPriorities; p++ {
+        if len(l.waiting[p]) == 0 { l.credit[p] = 0; continue }
+        l.credit[p] += l.weight[p]
+        total += l.weight[p]
+        if next < 0 || l.credit[p] > l.credit[next] { next = p }
+    }
+    if next < 0 {
+        l.running--
+        if l.saturated {
+            l.saturated = false
+            l.events.Publish("queue.cleared", map[string]any{"service": l.service, "running": l.running})
+        }
+        return
+    }
+    l.credit[next] -= total
+    ready := l.waiting[next][0]
+    l.waiting[next] = l.waiting[next][1:]
+    close(ready)
+}
+
+// WithLimits applies d.Limits to the LLM, TTS and embeddings services and
+// prepares the STT limiter. Apply it once, so REST and WebSocket callers
+// share the same slots.
+func WithLimits(d Dependencies) Dependencies {
+    classes := [numPriorities]PriorityClass{d.Limits.Interactive, d.Limits.Background}
+    if l := newLimiter("llm", d.Limits.LLM, classes, d.Events); l != nil && d.LLM != nil { d.LLM = &limitedLLM{next: d.LLM, l: l} }
+    if l := newLimiter("tts", d.Limits.TTS, classes, d.Events); l != nil && d.TTS != nil { d.TTS = &limitedTTS{next: d.TTS, l: l} }
+    if l := newLimiter("embeddings", d.Limits.Embeddings, classes, d.Events); l != nil && d.Embeddings != nil { d.Embeddings = &limitedEmbeddings{next: d.Embeddings, l: l} }
+    d.sttLimiter = newLimiter("stt", d.Limits.STT, classes, d.Events)
+    return d
+}
+
+type limitedLLM struct {
+    next LLMService
+    l    *limiter
+}
+
+func (s *limitedLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    release, err := s.l.acquire(ctx)
+    if err != nil { return nil, err }
+    defer release()
+    return s.next.Chat(ctx, req)
+}
+
+func (s *limitedLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    release, err := s.l.acquire(ctx)
+    if err != nil { return nil, err }
+    defer release()
+    return s.next.ChatStream(ctx, req, onChunk)
+}
+
+func (s *limitedLLM) Model() string {
+    if m, ok := s.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type limitedTTS struct {
+    next TTSService
+    l    *limiter
+}
+
+func (s *limitedTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    release, err := s.l.acquire(ctx)
+    if err != nil { return nil, err }
+    defer release()
+    return s.next.Synthesize(ctx, text, voice)
+}
+
+type limitedEmbeddings struct {
+    next embeddings.Service
+    l    *limiter
+}
+
+func (s *limitedEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    release, err := s.l.acquire(ctx)
+    if err != nil { return nil, "", err }
+    defer release()
+    return s.next.Embed(ctx, inputs)
+}
+
+func (s *limitedEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(s.next, inputs) }
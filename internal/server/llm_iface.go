@@ -0,0 +1,12 @@
+package server
+
+import (
+    "context"
+
+    "gollmcore/internal/services/llm"
+)
+
+type LLMService interface {
+    Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error)
+    ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "runtime"
+    "strings"
+    "sync"
+    "time"
+
+    "gollmcore/internal/services/stt"
+)
+
+// -------- Long recordings --------
+
+// LongAudio splits PCM WAV recordings longer than After into pieces of
+// about Chunk, cut at silences, and transcribes up to Workers pieces at
+// once (default a quarter of the CPUs, never more than the STT
+// concurrency limit). A zero After disables splitting.
+type LongAudio struct {
+    After   time.Duration
+    Chunk   time.Duration
+    Workers int
+}
+
+// transcript is a transcription result; Segments are only set for
+// recordings that were split.
+type transcript struct {
+    Text     string
+    Segments []stt.Segment
+    Raw      string // whisper's text when Text was post-processed
+}
+
+// response is the JSON body of a finished transcription.
+func (t transcript) response(model string) map[string]any {
+    resp := map[string]any{"text": t.Text, "model": model}
+    if t.Segments != nil { resp["segments"] = t.Segments }
+    if t.Raw != "" { resp["raw_text"] = t.Raw }
+    return resp
+}
+
+// transcribeChunks transcribes the pieces of a split recording in parallel,
+// each within the STT timeout and holding an STT slot, and stitches their
+// segments back onto the timeline of the whole recording.
+func (d Dependencies) transcribeChunks(ctx context.Context, chunks []stt.Chunk, model string, req sttRequest) (transcript, error) {
+    workers := d.LongAudio.Workers
+    if workers <= 0 { workers = max(1, runtime.NumCPU()/4) }
+    if d.sttLimiter != nil { workers = min(workers, d.sttLimiter.max) }
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    results := make([][]stt.Segment, len(chunks))
+    var (
+        mu       sync.Mutex
+        firstErr error
+        wg       sync.WaitGroup
+    )
+    next := make(chan int)
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range next {
+                err := withDeadline(ctx, "stt", d.Timeouts.withDefaults().STT, func(ctx context.Context) error {
+                    release, err := d.sttLimiter.acquire(ctx)
+                    if err != nil { return err }
+                    defer release()
+                    results[i], err = d.STT.TranscribeSegments(ctx, chunks[i].Path, model, d.sttOptions(req))
+                    return err
+                })
+                if err != nil {
+                    mu.Lock()
+                    if firstErr == nil { firstErr = fmt.Errorf("chunk at %s: %w", chunks[i].Offset, err) }
+                    mu.Unlock()
+                    cancel()
+                }
+            }
+        }()
+    }
+feed:
+    for i := range chunks {
+        select {
+        case next <- i:
+        case <-ctx.Done():
+            break feed
+        }
+    }
+    close(next)
+    wg.Wait()
+    if firstErr != nil { return transcript{}, firstErr }
+    if err := ctx.Err(); err != nil { return transcript{}, err }
+
+    var out transcript
+    var texts []string
+    for i, segs := range results {
+        off := chunks[i].Offset.Seconds()
+        for _, s := range segs {
+            s.Start += off
+            s.End += off
+            out.Segments = append(out.Segments, s)
+            if s.Text != "" { texts = append(texts, s.Text) }
+        }
+    }
+    out.Text = strings.Join(texts, " ")
+    return out, nil
+}
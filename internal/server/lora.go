@@ -0,0 +1,129 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- LoRA adapters --------
+//
+// Adapters are loaded by llama-server (--lora) and named in config. A
+// request selects one with model "base:adapter" (or ":adapter" for the
+// default model); the suffix is stripped and the adapter applied to that
+// request only. Names that are not configured adapters are left alone, so
+// Ollama tags such as "llama3.2:3b" keep working. /admin/lora changes the
+// global scales.
+
+// LoRAAdapter is a named llama-server adapter.
+type LoRAAdapter struct {
+    ID    int     // position of its --lora flag, from 0
+    Scale float64 // applied when selected per request (default 1)
+}
+
+// loraUpstream is implemented by *llm.Service.
+type loraUpstream interface {
+    Adapters(ctx context.Context) ([]llm.Adapter, error)
+    SetAdapters(ctx context.Context, scales []llm.LoRA) error
+}
+
+type loraLLM struct {
+    next     LLMService
+    adapters map[string]LoRAAdapter
+}
+
+// withLoRA wraps d.LLM so "base:adapter" model names select an adapter.
+// It sits inside withLLMDefaults, which sees the full name.
+func (d Dependencies) withLoRA() Dependencies {
+    if d.LLM == nil || len(d.LoRA) == 0 { return d }
+    d.LLM = &loraLLM{next: d.LLM, adapters: d.LoRA}
+    return d
+}
+
+// splitAdapter resolves a "base:adapter" model name.
+func splitAdapter(model string, adapters map[string]LoRAAdapter) (string, LoRAAdapter, bool) {
+    i := strings.LastIndexByte(model, ':')
+    if i < 0 { return model, LoRAAdapter{}, false }
+    a, ok := adapters[model[i+1:]]
+    if !ok { return model, LoRAAdapter{}, false }
+    return model[:i], a, true
+}
+
+func (l *loraLLM) apply(req llm.ChatRequest) llm.ChatRequest {
+    base, a, ok := splitAdapter(req.Model, l.adapters)
+    if !ok { return req }
+    scale := a.Scale
+    if scale == 0 { scale = 1 }
+    req.Model, req.LoRA = base, []llm.LoRA{{ID: a.ID, Scale: scale}}
+    return req
+}
+
+func (l *loraLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    return l.next.Chat(ctx, l.apply(req))
+}
+
+func (l *loraLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    return l.next.ChatStream(ctx, l.apply(req), onChunk)
+}
+
+// Model reports the upstream default model, when known.
+func (l *loraLLM) Model() string {
+    if m, ok := l.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+// -------- Admin --------
+
+type loraStatus struct {
+    ID    int     `json:"id"`
+    Name  string  `json:"name,omitempty"`
+    Path  string  `json:"path"`
+    Scale float64 `json:"scale"`
+}
+
+type loraUpdate struct {
+    Adapters []struct {
+        ID    *int    `json:"id,omitempty"`
+        Name  string  `json:"name,omitempty"`
+        Scale float64 `json:"scale"`
+    } `json:"adapters"`
+}
+
+// handleAdminLoRA lists adapters (GET) or sets their global scales (POST);
+// adapters a POST leaves out are set to 0.
+func handleAdminLoRA(w http.ResponseWriter, r *http.Request, up loraUpstream, names map[string]LoRAAdapter) {
+    if r.Method == http.MethodPost {
+        var req loraUpdate
+        if !decodeJSON(w, r, &req) { return }
+        scales := make([]llm.LoRA, 0, len(req.Adapters))
+        for _, a := range req.Adapters {
+            var id int
+            switch {
+            case a.ID != nil:
+                id = *a.ID
+            case a.Name != "":
+                n, ok := names[a.Name]
+                if !ok { writeParamError(w, "adapters", fmt.Sprintf("unknown adapter %q", a.Name)); return }
+                id = n.ID
+            default:
+                writeParamError(w, "adapters", "each adapter needs an id or name")
+                return
+            }
+            if a.Scale < 0 { writeParamError(w, "adapters", "scale must not be negative"); return }
+            scales = append(scales, llm.LoRA{ID: id, Scale: a.Scale})
+        }
+        if err := up.SetAdapters(r.Context(), scales); err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+    }
+    loaded, err := up.Adapters(r.Context())
+    if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+    byID := map[int]string{}
+    for name, a := range names { byID[a.ID] = name }
+    out := make([]loraStatus, len(loaded))
+    for i, a := range loaded { out[i] = loraStatus{ID: a.ID, Name: byID[a.ID], Path: a.Path, Scale: a.Scale} }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    writeJSON(w, http.StatusOK, map[string]any{"adapters": out})
+}
@@ -0,0 +1,431 @@
+package server
+
+import (
+    "bufio"
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// -------- MCP --------
+//
+// The Model Context Protocol lets assistants such as Claude Desktop and
+// MCP-aware editors use the local services as tools: transcribe, synthesize,
+// embed and, with a provider, web_search. It is JSON-RPC 2.0 over one of
+// three transports: stdio (`gollmcore mcp`, spawned by the client), the
+// streamable HTTP endpoint POST /mcp, and the older SSE transport
+// (GET /mcp/sse with replies to POST /mcp/messages). Only stdio may read
+// audio from local paths; over HTTP clients send it inline as base64.
+
+// mcpProtocolVersions are the protocol revisions we speak, newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+const (
+    mcpDefaultVersion = "2025-03-26"
+    // maxMCPMessage bounds one message; base64 audio makes them large.
+    maxMCPMessage     = 64 << 20
+)
+
+// JSON-RPC error codes.
+const (
+    rpcParseError     = -32700
+    rpcInvalidRequest = -32600
+    rpcMethodNotFound = -32601
+    rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id,omitempty"`
+    Method  string          `json:"method"`
+    Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id"`
+    Result  any             `json:"result,omitempty"`
+    Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+// mcpContent is one item of a tool result: text, or base64 audio.
+type mcpContent struct {
+    Type     string `json:"type"` // text | audio
+    Text     string `json:"text,omitempty"`
+    Data     string `json:"data,omitempty"`
+    MimeType string `json:"mimeType,omitempty"`
+}
+
+type mcpToolResult struct {
+    Content []mcpContent `json:"content"`
+    IsError bool         `json:"isError,omitempty"`
+}
+
+// mcpTool is a tool offered to MCP clients.
+type mcpTool struct {
+    Description string
+    Schema      json.RawMessage
+    Call        func(ctx context.Context, args json.RawMessage) ([]mcpContent, error)
+}
+
+// mcpSession answers the messages of one client connection. Requests run
+// concurrently, so a long transcription does not hold up pings, and
+// notifications/cancelled stops the one it names.
+type mcpSession struct {
+    d     Dependencies
+    tools map[string]mcpTool
+    mu    sync.Mutex
+    calls map[string]context.CancelFunc
+}
+
+// newMCPSession offers the tools d can serve; local lets transcribe read
+// files from the server's disk.
+func newMCPSession(d Dependencies, local bool) *mcpSession {
+    return &mcpSession{d: d, tools: d.mcpTools(local), calls: map[string]context.CancelFunc{}}
+}
+
+// handle answers one message; it returns nil for notifications and
+// responses, which get no reply.
+func (s *mcpSession) handle(ctx context.Context, msg []byte) []byte {
+    var req rpcRequest
+    if err := json.Unmarshal(msg, &req); err != nil {
+        if json.Valid(msg) { return rpcReply(nil, nil, &rpcError{Code: rpcInvalidRequest, Message: "expected a JSON-RPC request object"}) }
+        return rpcReply(nil, nil, &rpcError{Code: rpcParseError, Message: "invalid json"})
+    }
+    if req.JSONRPC != "2.0" || req.Method == "" {
+        // Responses to requests we never send are dropped.
+        if req.Method == "" && len(req.ID) > 0 { return nil }
+        return rpcReply(req.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: `jsonrpc must be "2.0" with a method`})
+    }
+    if len(req.ID) == 0 {
+        s.notify(req)
+        return nil
+    }
+
+    key := string(req.ID)
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    s.mu.Lock()
+    s.calls[key] = cancel
+    s.mu.Unlock()
+    defer func() { s.mu.Lock(); delete(s.calls, key); s.mu.Unlock() }()
+
+    result, rerr := s.call(ctx, req)
+    return rpcReply(req.ID, result, rerr)
+}
+
+func (s *mcpSession) notify(req rpcRequest) {
+    if req.Method != "notifications/cancelled" { return }
+    var p struct{ RequestID json.RawMessage `json:"requestId"` }
+    if json.Unmarshal(req.Params, &p) != nil { return }
+    s.mu.Lock()
+    cancel := s.calls[string(p.RequestID)]
+    s.mu.Unlock()
+    if cancel != nil { cancel() }
+}
+
+func (s *mcpSession) call(ctx context.Context, req rpcRequest) (any, *rpcError) {
+    switch req.Method {
+    case "initialize":
+        var p struct{ ProtocolVersion string `json:"protocolVersion"` }
+        _ = json.Unmarshal(req.Params, &p)
+        version := mcpDefaultVersion
+        for _, v := range mcpProtocolVersions { if v == p.ProtocolVersion { version = v } }
+        return map[string]any{
+            "protocolVersion": version,
+            "capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+            "serverInfo":      map[string]any{"name": "gollmcore", "version": "1"},
+            "instructions":    "Local speech-to-text, text-to-speech and embeddings. Audio results are WAV.",
+        }, nil
+    case "ping":
+        return struct{}{}, nil
+    case "tools/list":
+        names := make([]string, 0, len(s.tools))
+        for n := range s.tools { names = append(names, n) }
+        sort.Strings(names)
+        list := make([]map[string]any, len(names))
+        for i, n := range names { list[i] = map[string]any{"name": n, "description": s.tools[n].Description, "inputSchema": s.tools[n].Schema} }
+        return map[string]any{"tools": list}, nil
+    case "tools/call":
+        var p struct {
+            Name      string          `json:"name"`
+            Arguments json.RawMessage `json:"arguments"`
+        }
+        if err := json.Unmarshal(req.Params, &p); err != nil { return nil, &rpcError{Code: rpcInvalidParams, Message: "params must be an object with name and arguments"} }
+        t, ok := s.tools[p.Name]
+        if !ok { return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)} }
+        if len(p.Arguments) == 0 || string(p.Arguments) == "null" { p.Arguments = json.RawMessage("{}") }
+        start := time.Now()
+        content, err := t.Call(ctx, p.Arguments)
+        status := "ok"
+        if err != nil { status = "error" }
+        metrics.add("gollmcore_mcp_tool_calls_total", "Tool calls made by MCP clients.", `tool="`+p.Name+`",result="`+status+`"`, 1)
+        // Tool failures are results the client's model can read, not
+        // protocol errors.
+        if err != nil {
+            log.Printf("mcp tool %s failed after %s: %v", p.Name, time.Since(start).Round(time.Millisecond), err)
+            return mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+        }
+        return mcpToolResult{Content: content}, nil
+    }
+    return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func rpcReply(id json.RawMessage, result any, e *rpcError) []byte {
+    if id == nil { id = json.RawMessage("null") }
+    resp := rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: e}
+    if e == nil && result == nil { resp.Result = struct{}{} }
+    b, err := json.Marshal(resp)
+    if err != nil { b, _ = json.Marshal(rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: -32603, Message: err.Error()}}) }
+    return b
+}
+
+// -------- MCP tools --------
+
+func (d Dependencies) mcpTools(local bool) map[string]mcpTool {
+    tools := map[string]mcpTool{}
+    if d.STT != nil {
+        source := `"audio":{"type":"string","description":"Base64-encoded audio file (WAV, MP3, ...)"},"filename":{"type":"string","description":"Original file name; its extension tells the format"}`
+        if local { source += `,"path":{"type":"string","description":"Path of an audio file on this machine, instead of audio"}` }
+        tools["transcribe"] = mcpTool{
+            Description: "Transcribe speech in an audio file to text with whisper.",
+            Schema:      json.RawMessage(`{"type":"object","properties":{` + source + `,"model":{"type":"string","description":"Whisper model; default ` + d.STTDefaultModel + `"},"prompt":{"type":"string","description":"Vocabulary or context that helps recognition"},"postprocess":{"type":"boolean","description":"Fix punctuation and casing with the LLM"}}}`),
+            Call:        func(ctx context.Context, args json.RawMessage) ([]mcpContent, error) { return d.mcpTranscribe(ctx, args, local) },
+        }
+    }
+    if d.TTS != nil {
+        tools["synthesize"] = mcpTool{
+            Description: "Speak text aloud; returns WAV audio.",
+            Schema:      json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"},"voice":{"type":"string","description":"Voice name; default the server's"}},"required":["text"]}`),
+            Call: func(ctx context.Context, args json.RawMessage) ([]mcpContent, error) {
+                var in ttsRequest
+                if err := json.Unmarshal(args, &in); err != nil { return nil, err }
+                if strings.TrimSpace(in.Text) == "" { return nil, errors.New("missing text") }
+                audio, err := d.TTS.Synthesize(ctx, in.Text, in.Voice)
+                if err != nil { return nil, err }
+                return []mcpContent{{Type: "audio", Data: base64.StdEncoding.EncodeToString(audio), MimeType: "audio/wav"}}, nil
+            },
+        }
+    }
+    if d.Embeddings != nil {
+        tools["embed"] = mcpTool{
+            Description: "Compute embedding vectors for one or more texts.",
+            Schema:      json.RawMessage(`{"type":"object","properties":{"input":{"oneOf":[{"type":"string"},{"type":"array","items":{"type":"string"}}]}},"required":["input"]}`),
+            Call: func(ctx context.Context, args json.RawMessage) ([]mcpContent, error) {
+                var in struct{ Input json.RawMessage `json:"input"` }
+                if err := json.Unmarshal(args, &in); err != nil { return nil, err }
+                var inputs []string
+                var one string
+                if json.Unmarshal(in.Input, &one) == nil {
+                    inputs = []string{one}
+                } else if err := json.Unmarshal(in.Input, &inputs); err != nil {
+                    return nil, errors.New("input must be string or array of strings")
+                }
+                if len(inputs) == 0 { return nil, errors.New("no input provided") }
+                vecs, model, err := d.Embeddings.Embed(ctx, inputs)
+                if err != nil { return nil, err }
+                b, err := json.Marshal(map[string]any{"model": model, "embeddings": vecs})
+                if err != nil { return nil, err }
+                return []mcpContent{{Type: "text", Text: string(b)}}, nil
+            },
+        }
+    }
+    if d.Search.Provider != nil {
+        search := SearchTool(d.Search)
+        tools["web_search"] = mcpTool{Description: search.Description, Schema: search.Parameters, Call: func(ctx context.Context, args json.RawMessage) ([]mcpContent, error) {
+            out, err := search.Call(ctx, args)
+            if err != nil { return nil, err }
+            return []mcpContent{{Type: "text", Text: out}}, nil
+        }}
+    }
+    return tools
+}
+
+type mcpTranscribeArgs struct {
+    Audio    string `json:"audio"`
+    Filename string `json:"filename"`
+    Path     string `json:"path"`
+    Model    string `json:"model"`
+    sttRequest
+}
+
+// mcpTranscribe copies the audio to a temporary file, so long recordings
+// are split next to it rather than beside the client's file.
+func (d Dependencies) mcpTranscribe(ctx context.Context, args json.RawMessage, local bool) ([]mcpContent, error) {
+    var in mcpTranscribeArgs
+    if err := json.Unmarshal(args, &in); err != nil { return nil, err }
+    if in.Path != "" && !local { return nil, errors.New("path is only accepted over stdio; send the audio as base64") }
+    if (in.Audio == "") == (in.Path == "") { return nil, errors.New("give exactly one of audio or path") }
+    if _, err := in.check(); err != nil { return nil, err }
+    if err := d.checkPostprocess(in.sttRequest); err != nil { return nil, err }
+    if in.Model == "" { in.Model = d.STTDefaultModel }
+
+    var src io.Reader
+    if in.Path != "" {
+        f, err := os.Open(in.Path)
+        if err != nil { return nil, err }
+        defer f.Close()
+        src = f
+        if in.Filename == "" { in.Filename = filepath.Base(in.Path) }
+    } else {
+        src = base64.NewDecoder(base64.StdEncoding, strings.NewReader(in.Audio))
+    }
+    if in.Filename == "" { in.Filename = "audio" }
+    path, err := d.saveUpload(ctx, "mcp-stt", in.Filename, src)
+    var b64 base64.CorruptInputError
+    if errors.As(err, &b64) { return nil, errors.New("audio is not valid base64") }
+    if err != nil { return nil, err }
+    defer os.Remove(path)
+
+    t, err := d.transcribe(ctx, path, in.Model, in.sttRequest)
+    if err == nil { err = d.postprocess(ctx, in.sttRequest, &t) }
+    if err != nil { return nil, err }
+    return []mcpContent{{Type: "text", Text: strings.TrimSpace(t.Text)}}, nil
+}
+
+// -------- MCP transports --------
+
+// ServeMCP speaks MCP over newline-delimited JSON on in and out until in
+// ends or ctx is cancelled. It is the stdio transport of `gollmcore mcp`,
+// so transcribe may also read local files.
+func ServeMCP(ctx context.Context, d Dependencies, in io.Reader, out io.Writer) error {
+    d = d.withTimeouts().withCaps()
+    s := newMCPSession(d, true)
+    var (
+        mu sync.Mutex
+        wg sync.WaitGroup
+    )
+    ctx, cancel := context.WithCancel(ctx)
+    defer func() { cancel(); wg.Wait() }()
+    // Reads happen aside so that cancelling ctx stops a blocked read.
+    lines := make(chan []byte)
+    var readErr error
+    go func() {
+        defer close(lines)
+        sc := bufio.NewScanner(in)
+        sc.Buffer(make([]byte, 64<<10), maxMCPMessage)
+        for sc.Scan() {
+            select {
+            case lines <- append([]byte(nil), sc.Bytes()...):
+            case <-ctx.Done():
+                return
+            }
+        }
+        readErr = sc.Err()
+    }()
+    for {
+        var line []byte
+        select {
+        case <-ctx.Done():
+            return nil
+        case l, ok := <-lines:
+            // Let requests already read finish before returning.
+            if !ok { wg.Wait(); return readErr }
+            line = l
+        }
+        if len(strings.TrimSpace(string(line))) == 0 { continue }
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            reply := s.handle(ctx, line)
+            if reply == nil { return }
+            mu.Lock()
+            defer mu.Unlock()
+            if _, err := fmt.Fprintf(out, "%s\n", reply); err != nil { log.Printf("mcp: write reply: %v", err) }
+        }()
+    }
+}
+
+// handleMCP is the streamable HTTP transport without server-initiated
+// streams: each POSTed request is answered in the response body. Requests
+// are independent, so there is nothing to cancel across them.
+func handleMCP(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    msg, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMCPMessage))
+    if err != nil { writeError(w, "message too large", http.StatusRequestEntityTooLarge); return }
+    reply := newMCPSession(d, false).handle(r.Context(), msg)
+    if reply == nil { w.WriteHeader(http.StatusAccepted); return }
+    w.Header().Set("Content-Type", "application/json")
+    _, _ = w.Write(reply)
+}
+
+// mcpSSE keeps the sessions of the SSE transport, whose replies travel
+// on the event stream opened by GET /mcp/sse.
+type mcpSSE struct {
+    d        Dependencies
+    mu       sync.Mutex
+    sessions map[string]*mcpSSESession
+}
+
+type mcpSSESession struct {
+    *mcpSession
+    ctx     context.Context
+    replies chan []byte
+}
+
+func newMCPSSE(d Dependencies) *mcpSSE { return &mcpSSE{d: d, sessions: map[string]*mcpSSESession{}} }
+
+func (m *mcpSSE) stream(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok { writeError(w, "streaming unsupported", http.StatusInternalServerError); return }
+    id := newID("mcp")
+    sess := &mcpSSESession{mcpSession: newMCPSession(m.d, false), ctx: r.Context(), replies: make(chan []byte, 16)}
+    m.mu.Lock()
+    m.sessions[id] = sess
+    m.mu.Unlock()
+    defer func() { m.mu.Lock(); delete(m.sessions, id); m.mu.Unlock() }()
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    fmt.Fprintf(w, "event: endpoint\ndata: /mcp/messages?session_id=%s\n\n", id)
+    flusher.Flush()
+    keepalive := time.NewTicker(15 * time.Second)
+    defer keepalive.Stop()
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case reply := <-sess.replies:
+            if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", reply); err != nil { return }
+        case <-keepalive.C:
+            if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil { return }
+        }
+        flusher.Flush()
+    }
+}
+
+func (m *mcpSSE) message(w http.ResponseWriter, r *http.Request) {
+    m.mu.Lock()
+    sess := m.sessions[r.URL.Query().Get("session_id")]
+    m.mu.Unlock()
+    if sess == nil { writeError(w, "unknown or closed session", http.StatusNotFound); return }
+    msg, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMCPMessage))
+    if err != nil { writeError(w, "message too large", http.StatusRequestEntityTooLarge); return }
+    w.WriteHeader(http.StatusAccepted)
+    // The reply goes out on the stream; the work is bound to it, not to
+    // this request.
+    go func() {
+        reply := sess.handle(sess.ctx, msg)
+        if reply == nil { return }
+        select {
+        case sess.replies <- reply:
+        case <-sess.ctx.Done():
+        }
+    }()
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "sync"
+)
+
+// -------- Metrics --------
+//
+// A minimal counter registry rendered in the Prometheus text format at
+// /metrics. Labels are passed preformatted, e.g. `kind="tts"`.
+
+type metricKey struct{ name, labels string }
+
+type metricSet struct {
+    mu   sync.Mutex
+    help map[string]string
+    vals map[metricKey]int64
+}
+
+var metrics = &metricSet{help: map[string]string{}, vals: map[metricKey]int64{}}
+
+func (m *metricSet) add(name, help, labels string, n int64) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.help[name] = help
+    m.vals[metricKey{name, labels}] += n
+}
+
+func (m *metricSet) get(name, labels string) int64 {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.vals[metricKey{name, labels}]
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+    metrics.mu.Lock()
+    keys := make([]metricKey, 0, len(metrics.vals))
+    for k := range metrics.vals { keys = append(keys, k) }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i].name != keys[j].name { return keys[i].name < keys[j].name }
+        return keys[i].labels < keys[j].labels
+    })
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    last := ""
+    for _, k := range keys {
+        if k.name != last {
+            fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", k.name, metrics.help[k.name], k.name)
+            last = k.name
+        }
+        if k.labels == "" { fmt.Fprintf(w, "%s %d\n", k.name, metrics.vals[k]) } else { fmt.Fprintf(w, "%s{%s} %d\n", k.name, k.labels, metrics.vals[k]) }
+    }
+    metrics.mu.Unlock()
+}
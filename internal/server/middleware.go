@@ -0,0 +1,79 @@
+package server
+
+import (
+    "bufio"
+    "errors"
+    "log"
+    "net"
+    "net/http"
+    "runtime/debug"
+
+    "gollmcore/internal/tracing"
+)
+
+// -------- Middleware --------
+//
+// Cross-cutting behavior (auth, audit, tracing, compression, recovery) is
+// a Middleware around the mux rather than code in each handler. Chain
+// composes them in reading order, and With adapts the ones that take
+// options, so the stack in main is one list.
+
+// Middleware wraps a handler with behavior that applies to every request.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h in mws; the first middleware is the outermost, so it sees
+// the request first and the response last.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+    for i := len(mws) - 1; i >= 0; i-- { h = mws[i](h) }
+    return h
+}
+
+// With adapts a middleware that takes options, e.g. With(Compress, opts).
+func With[O any](mw func(http.Handler, O) http.Handler, o O) Middleware {
+    return func(h http.Handler) http.Handler { return mw(h, o) }
+}
+
+// -------- Recovery --------
+
+// Recover turns a panic in h into a 500 JSON error and a log line with the
+// stack, instead of the connection being dropped. When the response has
+// already started it can only be cut short; http.ErrAbortHandler is passed
+// on untouched. Place it inside Audit and Trace so they record the 500.
+func Recover(h http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        rw := &recoverWriter{ResponseWriter: w}
+        defer func() {
+            v := recover()
+            if v == nil { return }
+            if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) { panic(v) }
+            log.Printf("panic serving %s %s (trace %s): %v\n%s", r.Method, r.URL.Path, tracing.TraceID(r.Context()), v, debug.Stack())
+            metrics.add("gollmcore_panics_total", "Handler panics recovered by the server.", "", 1)
+            if rw.started { return }
+            writeError(rw, "internal server error", http.StatusInternalServerError)
+        }()
+        h.ServeHTTP(rw, r)
+    })
+}
+
+// recoverWriter notes whether the response has started (or the connection
+// was taken over), after which an error can no longer be written.
+type recoverWriter struct {
+    http.ResponseWriter
+    started bool
+}
+
+func (rw *recoverWriter) WriteHeader(code int) { rw.started = true; rw.ResponseWriter.WriteHeader(code) }
+
+func (rw *recoverWriter) Write(b []byte) (int, error) { rw.started = true; return rw.ResponseWriter.Write(b) }
+
+func (rw *recoverWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+func (rw *recoverWriter) Flush() {
+    rw.started = true
+    if f, ok := rw.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
+
+func (rw *recoverWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    rw.started = true
+    return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
@@ -0,0 +1,304 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Utility NLP endpoints --------
+//
+// /v1/summarize, /v1/extract and /v1/classify wrap the LLM with tuned
+// prompts. Extraction and classification ask the upstream for JSON
+// constrained by a schema (response_format json_schema) and check the
+// reply here too, since not every upstream enforces it; a reply that still
+// does not fit after one corrective retry fails with 502.
+
+const (
+    // summaryChunkChars is the largest input summarized in one call;
+    // longer texts are summarized in parts and the parts combined.
+    summaryChunkChars = 12000
+    maxNLPInputChars  = 32000
+    maxClassifyLabels = 100
+    maxClassifyInputs = 64
+)
+
+var errSchemaMismatch = errors.New("model output did not match the schema")
+
+var summaryStyles = map[string]string{
+    "paragraph": "Write the summary as one concise paragraph.",
+    "bullets":   "Write the summary as a short list of bullet points, one key point per line starting with \"- \".",
+    "tldr":      "Write a single sentence.",
+}
+
+type summarizeRequest struct {
+    Input    string `json:"input" validate:"required"`
+    // Style is paragraph (default), bullets or tldr.
+    Style    string `json:"style,omitempty"`
+    MaxWords int    `json:"max_words,omitempty"`
+    Model    string `json:"model,omitempty"`
+}
+
+type extractRequest struct {
+    Input        string         `json:"input" validate:"required"`
+    // Schema is a JSON Schema object describing the fields to extract.
+    Schema       map[string]any `json:"schema" validate:"required"`
+    Instructions string         `json:"instructions,omitempty"`
+    Model        string         `json:"model,omitempty"`
+}
+
+type classifyRequest struct {
+    Input        any      `json:"input" validate:"required"` // string or []string
+    Labels       []string `json:"labels" validate:"required"`
+    // MultiLabel lets each input take any number of labels.
+    MultiLabel   bool     `json:"multi_label,omitempty"`
+    Instructions string   `json:"instructions,omitempty"`
+    Model        string   `json:"model,omitempty"`
+}
+
+type classifyResult struct {
+    Index  int      `json:"index"`
+    Label  string   `json:"label,omitempty"`
+    Labels []string `json:"labels,omitempty"`
+}
+
+// complete runs one deterministic system+user exchange.
+func (d Dependencies) complete(ctx context.Context, model, system, user string, format json.RawMessage) (string, string, error) {
+    temp := 0.0
+    resp, err := d.LLM.Chat(ctx, llm.ChatRequest{
+        Model:          model,
+        Messages:       []llm.Message{{Role: "system", Content: system}, {Role: "user", Content: user}},
+        Temperature:    &temp,
+        ResponseFormat: format,
+    })
+    if err != nil { return "", "", err }
+    return strings.TrimSpace(resp.Text()), resp.Model, nil
+}
+
+func handleSummarize(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req summarizeRequest
+    if !decodeJSON(w, r, &req) { return }
+    if strings.TrimSpace(req.Input) == "" { writeParamError(w, "input", "input must not be empty"); return }
+    if len([]rune(req.Input)) > maxDocumentChars { writeParamError(w, "input", fmt.Sprintf("input exceeds %d characters", maxDocumentChars)); return }
+    if req.Style == "" { req.Style = "paragraph" }
+    style, ok := summaryStyles[req.Style]
+    if !ok { writeParamError(w, "style", "style must be paragraph, bullets or tldr"); return }
+    if req.MaxWords < 0 { writeParamError(w, "max_words", "max_words must not be negative"); return }
+
+    prompt := "Summarize the text the user sends. Keep the key facts, names, numbers and decisions; leave out filler. Use the language of the text. " + style
+    if req.MaxWords > 0 { prompt += fmt.Sprintf(" Use at most %d words.", req.MaxWords) }
+    prompt += " Reply with the summary only."
+
+    // Map-reduce long texts: summarize each part, then the joined parts.
+    text := []rune(req.Input)
+    input := req.Input
+    if len(text) > summaryChunkChars {
+        var parts []string
+        for _, s := range chunkText(text, summaryChunkChars, 0) {
+            part, _, err := d.complete(r.Context(), req.Model, "Summarize this part of a longer text in a few sentences, keeping key facts, names, numbers and decisions. Use the language of the text. Reply with the summary only.", string(text[s[0]:s[1]]), nil)
+            if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+            parts = append(parts, part)
+        }
+        input = strings.Join(parts, "\n\n")
+    }
+    summary, model, err := d.complete(r.Context(), req.Model, prompt, input, nil)
+    if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+    writeJSON(w, http.StatusOK, map[string]any{"summary": summary, "model": model})
+}
+
+func handleExtract(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req extractRequest
+    if !decodeJSON(w, r, &req) { return }
+    if strings.TrimSpace(req.Input) == "" { writeParamError(w, "input", "input must not be empty"); return }
+    if len([]rune(req.Input)) > maxNLPInputChars { writeParamError(w, "input", fmt.Sprintf("input exceeds %d characters", maxNLPInputChars)); return }
+    if req.Schema == nil || req.Schema["type"] != "object" { writeParamError(w, "schema", `schema must be a JSON Schema with "type": "object"`); return }
+
+    schema, _ := json.Marshal(req.Schema)
+    prompt := "Extract information from the text the user sends into JSON matching this JSON Schema:\n" + string(schema) +
+        "\nUse only information stated in the text; use null for fields it does not mention. Reply with the JSON object only."
+    if req.Instructions != "" { prompt += "\n" + req.Instructions }
+    format, _ := json.Marshal(map[string]any{"type": "json_schema", "json_schema": map[string]any{"name": "extraction", "schema": req.Schema, "strict": true}})
+    var data map[string]any
+    model, err := d.completeJSON(r.Context(), req.Model, prompt, req.Input, format, func(v any) error {
+        obj, ok := v.(map[string]any)
+        if !ok { return errors.New("expected a JSON object") }
+        data = obj
+        return checkSchema(req.Schema, v, "$")
+    })
+    if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+    writeJSON(w, http.StatusOK, map[string]any{"data": data, "model": model})
+}
+
+func handleClassify(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req classifyRequest
+    if !decodeJSON(w, r, &req) { return }
+    var inputs []string
+    switch v := req.Input.(type) {
+    case string:
+        inputs = []string{v}
+    case []any:
+        for _, it := range v {
+            if s, ok := it.(string); ok { inputs = append(inputs, s) }
+        }
+    }
+    if len(inputs) == 0 { writeParamError(w, "input", "input must be a non-empty string or array of strings"); return }
+    if len(inputs) > maxClassifyInputs { writeParamError(w, "input", fmt.Sprintf("at most %d inputs per request", maxClassifyInputs)); return }
+    for _, in := range inputs {
+        if len([]rune(in)) > maxNLPInputChars { writeParamError(w, "input", fmt.Sprintf("input exceeds %d characters", maxNLPInputChars)); return }
+    }
+    seen := map[string]bool{}
+    for _, l := range req.Labels {
+        if strings.TrimSpace(l) == "" || seen[l] { writeParamError(w, "labels", "labels must be distinct and non-empty"); return }
+        seen[l] = true
+    }
+    if len(req.Labels) < 2 || len(req.Labels) > maxClassifyLabels { writeParamError(w, "labels", fmt.Sprintf("give between 2 and %d labels", maxClassifyLabels)); return }
+
+    labels, _ := json.Marshal(req.Labels)
+    var schema map[string]any
+    if req.MultiLabel {
+        schema = map[string]any{"type": "object", "required": []any{"labels"}, "properties": map[string]any{
+            "labels": map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": toAny(req.Labels)}},
+        }}
+    } else {
+        schema = map[string]any{"type": "object", "required": []any{"label"}, "properties": map[string]any{
+            "label": map[string]any{"type": "string", "enum": toAny(req.Labels)},
+        }}
+    }
+    prompt := "Classify the text the user sends into the label that fits it best, choosing from: " + string(labels) + `. Reply with JSON {"label": "<label>"} only.`
+    if req.MultiLabel { prompt = "Pick every label that applies to the text the user sends (possibly none), choosing from: " + string(labels) + `. Reply with JSON {"labels": ["<label>", ...]} only.` }
+    if req.Instructions != "" { prompt += "\n" + req.Instructions }
+    format, _ := json.Marshal(map[string]any{"type": "json_schema", "json_schema": map[string]any{"name": "classification", "schema": schema, "strict": true}})
+
+    results := make([]classifyResult, len(inputs))
+    var model string
+    var err error
+    for i, in := range inputs {
+        res := classifyResult{Index: i}
+        model, err = d.completeJSON(r.Context(), req.Model, prompt, in, format, func(v any) error {
+            if err := checkSchema(schema, v, "$"); err != nil { return err }
+            obj := v.(map[string]any)
+            if req.MultiLabel {
+                res.Labels = []string{}
+                for _, l := range obj["labels"].([]any) { res.Labels = append(res.Labels, l.(string)) }
+            } else {
+                res.Label = obj["label"].(string)
+            }
+            return nil
+        })
+        if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+        results[i] = res
+    }
+    writeJSON(w, http.StatusOK, map[string]any{"results": results, "model": model})
+}
+
+// completeJSON asks for JSON and passes the decoded reply to accept,
+// retrying once with the problem described when it does not fit.
+func (d Dependencies) completeJSON(ctx context.Context, model, system, user string, format json.RawMessage, accept func(any) error) (string, error) {
+    var problem error
+    for attempt := 0; attempt < 2; attempt++ {
+        prompt := system
+        if problem != nil { prompt += fmt.Sprintf("\nYour previous reply was rejected (%v). Follow the format exactly.", problem) }
+        out, m, err := d.complete(ctx, model, prompt, user, format)
+        if err != nil { return "", err }
+        var v any
+        if err := json.Unmarshal([]byte(stripCodeFence(out)), &v); err != nil {
+            problem = errors.New("not valid JSON")
+            continue
+        }
+        if problem = accept(v); problem == nil { return m, nil }
+    }
+    return "", fmt.Errorf("%w: %v", errSchemaMismatch, problem)
+}
+
+// stripCodeFence removes a ```json fence some models wrap JSON in.
+func stripCodeFence(s string) string {
+    s = strings.TrimSpace(s)
+    if !strings.HasPrefix(s, "```") { return s }
+    s = strings.TrimPrefix(s, "```")
+    if i := strings.IndexByte(s, '\n'); i >= 0 { s = s[i+1:] }
+    return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+// checkSchema validates v against the subset of JSON Schema extraction
+// needs: type (including type lists), properties, required, items and enum.
+func checkSchema(schema map[string]any, v any, path string) error {
+    if enum, ok := schema["enum"].([]any); ok {
+        found := false
+        for _, e := range enum {
+            if fmt.Sprint(e) == fmt.Sprint(v) { found = true; break }
+        }
+        if !found { return fmt.Errorf("%s: %v is not one of %v", path, v, enum) }
+    }
+    if t, ok := schema["type"]; ok {
+        var types []string
+        switch t := t.(type) {
+        case string:
+            types = []string{t}
+        case []any:
+            for _, x := range t { if s, ok := x.(string); ok { types = append(types, s) } }
+        }
+        match := false
+        for _, typ := range types {
+            if jsonType(v, typ) { match = true; break }
+        }
+        // Extraction asks for null when the text lacks a field.
+        if !match && v != nil { return fmt.Errorf("%s: expected %s", path, strings.Join(types, " or ")) }
+    }
+    switch v := v.(type) {
+    case map[string]any:
+        if req, ok := schema["required"].([]any); ok {
+            for _, name := range req {
+                if _, ok := v[fmt.Sprint(name)]; !ok { return fmt.Errorf("%s: missing %q", path, name) }
+            }
+        }
+        props, _ := schema["properties"].(map[string]any)
+        names := make([]string, 0, len(props))
+        for name := range props { names = append(names, name) }
+        sort.Strings(names)
+        for _, name := range names {
+            sub, ok := props[name].(map[string]any)
+            val, present := v[name]
+            if !ok || !present { continue }
+            if err := checkSchema(sub, val, path+"."+name); err != nil { return err }
+        }
+    case []any:
+        if items, ok := schema["items"].(map[string]any); ok {
+            for i, x := range v {
+                if err := checkSchema(items, x, fmt.Sprintf("%s[%d]", path, i)); err != nil { return err }
+            }
+        }
+    }
+    return nil
+}
+
+func jsonType(v any, typ string) bool {
+    switch typ {
+    case "object":
+        _, ok := v.(map[string]any); return ok
+    case "array":
+        _, ok := v.([]any); return ok
+    case "string":
+        _, ok := v.(string); return ok
+    case "number":
+        _, ok := v.(float64); return ok
+    case "integer":
+        f, ok := v.(float64); return ok && f == float64(int64(f))
+    case "boolean":
+        _, ok := v.(bool); return ok
+    case "null":
+        return v == nil
+    }
+    return true
+}
+
+func toAny(s []string) []any {
+    out := make([]any, len(s))
+    for i, x := range s { out[i] = x }
+    return out
+}
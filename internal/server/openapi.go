@@ -0,0 +1,451 @@
+package server
+
+import (
+    "encoding/json"
+    "net/http"
+    "reflect"
+    "strconv"
+    "strings"
+    "time"
+
+    "gollmcore/internal/procs"
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/resources"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/speaker"
+    "gollmcore/internal/services/stt"
+    "gollmcore/internal/sessions"
+    "gollmcore/internal/usage"
+)
+
+// -------- OpenAPI --------
+//
+// /openapi.json is built from the same dependencies as RegisterRoutes, so it
+// only lists routes that are actually served. Request and response schemas
+// are derived by reflection from the Go types the handlers decode and encode.
+
+// apiOp describes one operation. Req/Resp are Go values whose types become
+// JSON schemas; ReqMedia/RespMedia override the JSON media type.
+type apiOp struct {
+    Method    string
+    Path      string
+    Tag       string
+    Summary   string
+    Params    []apiParam
+    Req       any
+    ReqMedia  string
+    Resp      any
+    RespMedia string
+    Status    int
+}
+
+type apiParam struct {
+    Name, In, Description string
+}
+
+// Request/response shapes that handlers declare inline.
+type (
+    apiTranscription struct {
+        Text     string        `json:"text"`
+        Model    string        `json:"model"`
+        Segments []stt.Segment `json:"segments,omitempty"`
+        RawText  string        `json:"raw_text,omitempty"`
+    }
+    apiAudioUpload struct {
+        File  []byte `json:"file" format:"binary"`
+        Model string `json:"model,omitempty"`
+        Voice string `json:"voice,omitempty"`
+    }
+    apiTranscriptionUpload struct {
+        File        []byte `json:"file" format:"binary"`
+        Prompt      string `json:"prompt,omitempty"`
+        Normalize   bool   `json:"normalize,omitempty"`
+        Denoise     bool   `json:"denoise,omitempty"`
+        Postprocess bool   `json:"postprocess,omitempty"`
+    }
+    apiVoiceTranslateUpload struct {
+        File       []byte `json:"file" format:"binary"`
+        TargetLang string `json:"target_lang"`
+        SourceLang string `json:"source_lang,omitempty"`
+        Model      string `json:"model,omitempty"`
+        Voice      string `json:"voice,omitempty"`
+    }
+    apiClassifyUpload struct {
+        File     []byte `json:"file" format:"binary"`
+        Model    string `json:"model,omitempty"`
+        Language bool   `json:"language,omitempty"`
+    }
+    apiFileUpload struct {
+        File []byte `json:"file" format:"binary"`
+    }
+    apiSpeakerUpload struct {
+        File      []byte `json:"file" format:"binary"`
+        SpeakerID string `json:"speaker_id"`
+        Name      string `json:"name,omitempty"`
+    }
+    apiSpeaker struct {
+        Speaker speaker.Speaker `json:"speaker"`
+    }
+    apiSpeakerList struct {
+        Speakers []speaker.Speaker `json:"speakers"`
+    }
+    apiSpeakerVerify struct {
+        SpeakerID string  `json:"speaker_id"`
+        Score     float64 `json:"score"`
+        Match     bool    `json:"match"`
+        Threshold float64 `json:"threshold"`
+    }
+    apiSpeakerIdentify struct {
+        Speaker    *speaker.Match  `json:"speaker"`
+        Candidates []speaker.Match `json:"candidates"`
+        Threshold  float64         `json:"threshold"`
+    }
+    apiModerationRequest struct {
+        Input any `json:"input"`
+    }
+    apiLoRAList struct {
+        Adapters []loraStatus `json:"adapters"`
+    }
+    apiSummary struct {
+        Summary string `json:"summary"`
+        Model   string `json:"model"`
+    }
+    apiExtraction struct {
+        Data  map[string]any `json:"data"`
+        Model string         `json:"model"`
+    }
+    apiClassification struct {
+        Results []classifyResult `json:"results"`
+        Model   string           `json:"model"`
+    }
+    apiChatRequest struct {
+        llm.ChatRequest
+        SessionID       string            `json:"session_id,omitempty"`
+        PromptTemplate  string            `json:"prompt_template,omitempty"`
+        PromptVariables map[string]string `json:"prompt_variables,omitempty"`
+        Priority        string            `json:"priority,omitempty"`
+        Agent           bool              `json:"agent,omitempty"`
+        AgentTools      []string          `json:"agent_tools,omitempty"`
+    }
+    apiSessionCreate struct {
+        Metadata map[string]string `json:"metadata,omitempty"`
+        Messages []llm.Message     `json:"messages,omitempty"`
+    }
+    apiSessionList struct {
+        Sessions []sessions.Summary `json:"sessions"`
+    }
+    apiMessages struct {
+        Messages []llm.Message `json:"messages"`
+    }
+    apiPipelineList struct {
+        Pipelines []struct {
+            Name  string         `json:"name"`
+            Steps []PipelineStep `json:"steps"`
+        } `json:"pipelines"`
+    }
+    apiPipelineInput struct {
+        Input string `json:"input"`
+    }
+    apiPromptList struct {
+        Prompts []prompts.Template `json:"prompts"`
+    }
+    apiPrompt struct {
+        Prompt    prompts.Template `json:"prompt"`
+        Variables []string         `json:"variables"`
+    }
+    apiUsage struct {
+        Models []usage.Summary `json:"models"`
+        Hourly []usage.Bucket  `json:"hourly"`
+    }
+    apiStatus struct {
+        Resources      resources.Reading `json:"resources"`
+        ONNXSessions   map[string]int    `json:"onnx_sessions"`
+        ChildProcesses []procs.Info      `json:"child_processes"`
+    }
+)
+
+// apiOps lists the REST operations served for d.
+func apiOps(d Dependencies) []apiOp {
+    ops := []apiOp{
+        {Method: "GET", Path: "/healthz", Tag: "server", Summary: "Liveness check", Resp: "", RespMedia: "text/plain"},
+        {Method: "GET", Path: "/metrics", Tag: "server", Summary: "Prometheus metrics", Resp: "", RespMedia: "text/plain"},
+        {Method: "GET", Path: "/v1/capabilities", Tag: "server", Summary: "Enabled services, streaming formats and limits", Resp: map[string]any{}},
+        {Method: "GET", Path: "/v1/status", Tag: "server", Summary: "Service states, installed model versions, uptime and disk usage", Resp: statusResponse{}},
+    }
+    if d.Events != nil {
+        ops = append(ops, apiOp{Method: "GET", Path: "/v1/events", Tag: "server", Summary: "Stream server events such as download progress (server-sent events)", Resp: "", RespMedia: "text/event-stream"})
+    }
+    model := apiParam{"model", "query", "Whisper model (defaults to the configured model)"}
+    if d.STT != nil {
+        ops = append(ops,
+            apiOp{Method: "POST", Path: "/v1/audio/transcriptions", Tag: "stt", Summary: "Transcribe an audio file", Params: []apiParam{model}, Req: apiTranscriptionUpload{}, ReqMedia: "multipart/form-data", Resp: apiTranscription{}},
+            apiOp{Method: "POST", Path: "/v1/audio/transcriptions/stream", Tag: "stt", Summary: "Transcribe an audio file, streaming lines as server-sent events", Params: []apiParam{model, {"event_format", "query", "text (raw lines, default) or json (partial/segment/done events with t0/t1)"}}, Req: apiTranscriptionUpload{}, ReqMedia: "multipart/form-data", Resp: "", RespMedia: "text/event-stream"},
+        )
+    }
+    if d.AudioClassifier != nil {
+        ops = append(ops, apiOp{Method: "POST", Path: "/v1/audio/classify", Tag: "stt", Summary: "Label a PCM WAV recording as speech, music, noise or silence and detect its language", Req: apiClassifyUpload{}, ReqMedia: "multipart/form-data", Resp: classification{}})
+    }
+    if d.Speakers != nil {
+        id := apiParam{"id", "path", "Speaker id"}
+        ops = append(ops,
+            apiOp{Method: "POST", Path: "/v1/speakers/enroll", Tag: "speakers", Summary: "Enroll a recording (PCM WAV, 1 s or more) as a speaker's voice", Req: apiSpeakerUpload{}, ReqMedia: "multipart/form-data", Resp: apiSpeaker{}},
+            apiOp{Method: "POST", Path: "/v1/speakers/verify", Tag: "speakers", Summary: "Check whether a recording is of speaker_id", Req: apiSpeakerUpload{}, ReqMedia: "multipart/form-data", Resp: apiSpeakerVerify{}},
+            apiOp{Method: "POST", Path: "/v1/speakers/identify", Tag: "speakers", Summary: "Find the enrolled speakers closest to a recording", Req: apiFileUpload{}, ReqMedia: "multipart/form-data", Resp: apiSpeakerIdentify{}},
+            apiOp{Method: "GET", Path: "/v1/speakers", Tag: "speakers", Summary: "List enrolled speakers", Resp: apiSpeakerList{}},
+            apiOp{Method: "GET", Path: "/v1/speakers/{id}", Tag: "speakers", Summary: "Get an enrolled speaker", Params: []apiParam{id}, Resp: apiSpeaker{}},
+            apiOp{Method: "DELETE", Path: "/v1/speakers/{id}", Tag: "speakers", Summary: "Delete a speaker", Params: []apiParam{id}, Status: http.StatusNoContent},
+        )
+    }
+    if d.Embeddings != nil {
+        ops = append(ops,
+            apiOp{Method: "POST", Path: "/v1/embeddings", Tag: "embeddings", Summary: "Embed one or more strings", Req: embeddingsRequest{}, Resp: embeddingsResponse{}},
+            apiOp{Method: "POST", Path: "/v1/embeddings/document", Tag: "embeddings", Summary: "Chunk a long text and embed the chunks, optionally pooled", Req: documentRequest{}, Resp: documentResponse{}},
+            apiOp{Method: "POST", Path: "/v1/similarity/matrix", Tag: "embeddings", Summary: "Pairwise cosine similarity of the inputs", Req: embeddingsRequest{}, Resp: similarityMatrixResponse{}},
+        )
+    }
+    if d.TTS != nil {
+        ops = append(ops, apiOp{Method: "POST", Path: "/v1/tts", Tag: "tts", Summary: "Synthesize speech", Req: ttsRequest{}, Resp: []byte{}, RespMedia: "audio/wav"})
+    }
+    if d.LLM != nil {
+        ops = append(ops,
+            apiOp{Method: "GET", Path: "/v1/models", Tag: "llm", Summary: "Chat models served, in the OpenAI list format", Resp: modelList{}},
+            apiOp{Method: "POST", Path: "/v1/chat/completions", Tag: "llm", Summary: "OpenAI-compatible chat completion (SSE when stream is true)", Req: apiChatRequest{}, Resp: llm.ChatResponse{}},
+            apiOp{Method: "POST", Path: "/v1/chat/completions/batch", Tag: "llm", Summary: "Answer an array of chat requests (202 with a job when async is true)", Req: batchRequest{}, Resp: batchResponse{}},
+            apiOp{Method: "POST", Path: "/v1/summarize", Tag: "llm", Summary: "Summarize a text (long texts are summarized in parts)", Req: summarizeRequest{}, Resp: apiSummary{}},
+            apiOp{Method: "POST", Path: "/v1/extract", Tag: "llm", Summary: "Extract fields described by a JSON Schema from a text", Req: extractRequest{}, Resp: apiExtraction{}},
+            apiOp{Method: "POST", Path: "/v1/classify", Tag: "llm", Summary: "Classify texts into the given labels", Req: classifyRequest{}, Resp: apiClassification{}},
+            apiOp{Method: "POST", Path: "/v1/moderations", Tag: "llm", Summary: "Score texts against moderation categories", Req: apiModerationRequest{}, Resp: moderationResponse{}},
+            apiOp{Method: "POST", Path: "/v1/translate", Tag: "llm", Summary: "Translate a text into another language", Req: translateRequest{}, Resp: translateResponse{}},
+        )
+    }
+    if d.Search.Provider != nil {
+        ops = append(ops, apiOp{Method: "POST", Path: "/v1/tools/search", Tag: "tools", Summary: "Search the web with the configured provider", Req: searchRequest{}, Resp: searchResponse{}})
+    }
+    if d.MCP {
+        ops = append(ops,
+            apiOp{Method: "POST", Path: "/mcp", Tag: "mcp", Summary: "Send an MCP JSON-RPC message (streamable HTTP transport)", Req: rpcRequest{}, Resp: rpcResponse{}},
+            apiOp{Method: "GET", Path: "/mcp/sse", Tag: "mcp", Summary: "Open an MCP session over server-sent events (SSE transport)", Resp: "", RespMedia: "text/event-stream"},
+            apiOp{Method: "POST", Path: "/mcp/messages", Tag: "mcp", Summary: "Send an MCP message to an SSE session; the reply arrives on the stream", Params: []apiParam{{"session_id", "query", "From the endpoint event"}}, Req: rpcRequest{}, Status: http.StatusAccepted},
+        )
+    }
+    if d.Sessions != nil {
+        id := apiParam{"id", "path", "Session id"}
+        ops = append(ops,
+            apiOp{Method: "GET", Path: "/v1/sessions", Tag: "sessions", Summary: "List sessions", Resp: apiSessionList{}},
+            apiOp{Method: "POST", Path: "/v1/sessions", Tag: "sessions", Summary: "Create a session", Req: apiSessionCreate{}, Resp: sessions.Session{}, Status: http.StatusCreated},
+            apiOp{Method: "GET", Path: "/v1/sessions/{id}", Tag: "sessions", Summary: "Get a session", Params: []apiParam{id}, Resp: sessions.Session{}},
+            apiOp{Method: "DELETE", Path: "/v1/sessions/{id}", Tag: "sessions", Summary: "Delete a session", Params: []apiParam{id}, Status: http.StatusNoContent},
+            apiOp{Method: "POST", Path: "/v1/sessions/{id}/messages", Tag: "sessions", Summary: "Append messages to a session", Params: []apiParam{id}, Req: apiMessages{}, Resp: sessions.Session{}},
+        )
+    }
+    if d.STT != nil && d.Resumable.Dir != "" {
+        id := apiParam{"id", "path", "Upload id"}
+        ops = append(ops,
+            apiOp{Method: "POST", Path: "/v1/uploads", Tag: "stt", Summary: "Start a resumable upload to transcribe", Req: upload{}, Resp: upload{}, Status: http.StatusCreated},
+            apiOp{Method: "GET", Path: "/v1/uploads/{id}", Tag: "stt", Summary: "Get an upload and its offset", Params: []apiParam{id}, Resp: upload{}},
+            apiOp{Method: "PATCH", Path: "/v1/uploads/{id}", Tag: "stt", Summary: "Append bytes at the Upload-Offset header; the last part starts a transcription job", Params: []apiParam{id, {"Upload-Offset", "header", "Bytes received so far"}}, Req: []byte{}, ReqMedia: "application/offset+octet-stream", Resp: upload{}},
+            apiOp{Method: "DELETE", Path: "/v1/uploads/{id}", Tag: "stt", Summary: "Abandon an upload", Params: []apiParam{id}, Status: http.StatusNoContent},
+        )
+    }
+    if len(d.Pipelines) > 0 {
+        ops = append(ops,
+            apiOp{Method: "GET", Path: "/v1/pipelines", Tag: "pipelines", Summary: "List configured pipelines", Resp: apiPipelineList{}},
+            apiOp{Method: "POST", Path: "/v1/pipelines/{name}/run", Tag: "pipelines", Summary: "Start a pipeline job (multipart audio for audio-first pipelines)", Params: []apiParam{{"name", "path", "Pipeline name"}}, Req: apiPipelineInput{}, Resp: Job{}, Status: http.StatusAccepted},
+        )
+    }
+    if d.STT != nil || d.TTS != nil || d.LLM != nil {
+        ops = append(ops, apiOp{Method: "DELETE", Path: "/v1/generations/{id}", Tag: "server", Summary: "Cancel a running chat, transcription or synthesis by its X-Generation-ID", Params: []apiParam{{"id", "path", "Generation id"}}, Resp: cancelledGeneration{}})
+    }
+    if len(d.Pipelines) > 0 || (d.STT != nil && d.Resumable.Dir != "") || d.LLM != nil {
+        ops = append(ops, apiOp{Method: "GET", Path: "/v1/jobs/{id}", Tag: "pipelines", Summary: "Get a job", Params: []apiParam{{"id", "path", "Job id"}}, Resp: Job{}})
+    }
+    if d.STT != nil && d.LLM != nil && d.TTS != nil {
+        ops = append(ops,
+            apiOp{Method: "POST", Path: "/v1/voice/chat", Tag: "voice", Summary: "Transcribe, reply and synthesize in one round trip", Req: apiAudioUpload{}, ReqMedia: "multipart/form-data", Resp: voiceResult{}},
+            apiOp{Method: "POST", Path: "/v1/voice/translate", Tag: "voice", Summary: "Transcribe, translate and synthesize in one round trip", Req: apiVoiceTranslateUpload{}, ReqMedia: "multipart/form-data", Resp: voiceResult{}},
+            apiOp{Method: "GET", Path: "/v1/voice/audio/{id}", Tag: "voice", Summary: "Download a reply delivered by URL", Params: []apiParam{{"id", "path", "Clip id"}}, Resp: []byte{}, RespMedia: "audio/wav"},
+        )
+    }
+    if d.Prompts != nil {
+        name := apiParam{"name", "path", "Template name"}
+        ops = append(ops,
+            apiOp{Method: "GET", Path: "/admin/prompts", Tag: "admin", Summary: "List prompt templates", Resp: apiPromptList{}},
+            apiOp{Method: "GET", Path: "/admin/prompts/{name}", Tag: "admin", Summary: "Get a prompt template", Params: []apiParam{name}, Resp: apiPrompt{}},
+            apiOp{Method: "PUT", Path: "/admin/prompts/{name}", Tag: "admin", Summary: "Create or replace a prompt template", Params: []apiParam{name}, Req: prompts.Template{}, Resp: apiPrompt{}},
+            apiOp{Method: "DELETE", Path: "/admin/prompts/{name}", Tag: "admin", Summary: "Delete a runtime prompt template", Params: []apiParam{name}, Status: http.StatusNoContent},
+        )
+    }
+    if d.Usage != nil {
+        ops = append(ops, apiOp{Method: "GET", Path: "/admin/usage", Tag: "admin", Summary: "Per-model usage statistics", Params: []apiParam{
+            {"service", "query", "stt | llm | tts | embeddings"}, {"model", "query", "Model name"},
+            {"since", "query", "RFC 3339 start time"}, {"until", "query", "RFC 3339 end time"},
+        }, Resp: apiUsage{}})
+    }
+    if d.LLM != nil && len(d.LoRA) > 0 {
+        ops = append(ops,
+            apiOp{Method: "GET", Path: "/admin/lora", Tag: "admin", Summary: "List llama-server LoRA adapters and their global scales", Resp: apiLoRAList{}},
+            apiOp{Method: "POST", Path: "/admin/lora", Tag: "admin", Summary: "Set global LoRA adapter scales (adapters left out are set to 0)", Req: loraUpdate{}, Resp: apiLoRAList{}},
+        )
+    }
+    if d.Resources.Monitor != nil {
+        ops = append(ops, apiOp{Method: "GET", Path: "/admin/status", Tag: "admin", Summary: "Host resources, ONNX sessions and child processes", Resp: apiStatus{}})
+    }
+    return ops
+}
+
+// openAPISpec renders ops as an OpenAPI 3.1 document.
+func openAPISpec(d Dependencies) map[string]any {
+    g := &schemaGen{components: map[string]any{}}
+    paths := map[string]map[string]any{}
+    errResp := map[string]any{"description": "Error", "content": map[string]any{"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(errorEnvelope{}))}}}
+    for _, op := range apiOps(d) {
+        o := map[string]any{"summary": op.Summary, "tags": []string{op.Tag}, "operationId": operationID(op)}
+        if len(op.Params) > 0 {
+            params := make([]map[string]any, len(op.Params))
+            for i, p := range op.Params {
+                params[i] = map[string]any{"name": p.Name, "in": p.In, "required": p.In == "path", "description": p.Description, "schema": map[string]any{"type": "string"}}
+            }
+            o["parameters"] = params
+        }
+        if op.Req != nil {
+            media := op.ReqMedia
+            if media == "" { media = "application/json" }
+            o["requestBody"] = map[string]any{"required": true, "content": map[string]any{media: map[string]any{"schema": g.schema(reflect.TypeOf(op.Req))}}}
+        }
+        status := op.Status
+        if status == 0 { status = http.StatusOK }
+        resp := map[string]any{"description": http.StatusText(status)}
+        if op.Resp != nil {
+            media := op.RespMedia
+            if media == "" { media = "application/json" }
+            resp["content"] = map[string]any{media: map[string]any{"schema": g.schema(reflect.TypeOf(op.Resp))}}
+        }
+        o["responses"] = map[string]any{strconv.Itoa(status): resp, "default": errResp}
+        if paths[op.Path] == nil { paths[op.Path] = map[string]any{} }
+        paths[op.Path][strings.ToLower(op.Method)] = o
+    }
+
+    spec := map[string]any{
+        "openapi": "3.1.0",
+        "info":    map[string]any{"title": "GoLLMCore", "version": "1.0.0", "description": "Local STT, embeddings, TTS and LLM services."},
+        "paths":   paths,
+        "components": map[string]any{"schemas": g.components},
+    }
+    if len(d.APIKeys) > 0 || len(d.AdminKeys) > 0 {
+        spec["components"].(map[string]any)["securitySchemes"] = map[string]any{
+            "bearer": map[string]any{"type": "http", "scheme": "bearer"},
+            "apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+        }
+        spec["security"] = []map[string][]string{{"bearer": {}}, {"apiKey": {}}}
+    }
+    return spec
+}
+
+func operationID(op apiOp) string {
+    var b strings.Builder
+    b.WriteString(strings.ToLower(op.Method))
+    for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool { return r == '/' || r == '.' || r == '-' || r == '{' || r == '}' }) {
+        if part == "v1" { continue }
+        b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+    }
+    return b.String()
+}
+
+// schemaGen converts Go types to JSON schemas, registering named structs as
+// components so shared types (Message, Job, ...) appear once.
+type schemaGen struct {
+    components map[string]any
+}
+
+var (
+    timeType    = reflect.TypeOf(time.Time{})
+    rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+    switch {
+    case t == timeType:
+        return map[string]any{"type": "string", "format": "date-time"}
+    case t == rawJSONType:
+        return map[string]any{}
+    }
+    switch t.Kind() {
+    case reflect.Pointer:
+        return g.schema(t.Elem())
+    case reflect.String:
+        return map[string]any{"type": "string"}
+    case reflect.Bool:
+        return map[string]any{"type": "boolean"}
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return map[string]any{"type": "integer"}
+    case reflect.Float32, reflect.Float64:
+        return map[string]any{"type": "number"}
+    case reflect.Slice, reflect.Array:
+        if t.Elem().Kind() == reflect.Uint8 { return map[string]any{"type": "string", "format": "binary"} }
+        return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+    case reflect.Map:
+        if t.Elem().Kind() == reflect.Interface { return map[string]any{"type": "object"} }
+        return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+    case reflect.Interface:
+        return map[string]any{}
+    case reflect.Struct:
+        name := t.Name()
+        if name == "" || strings.HasPrefix(name, "api") { return g.object(t) }
+        if pkg := t.PkgPath(); !strings.HasSuffix(pkg, "/server") { name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name }
+        if _, ok := g.components[name]; !ok {
+            g.components[name] = map[string]any{} // break recursion
+            g.components[name] = g.object(t)
+        }
+        return map[string]any{"$ref": "#/components/schemas/" + name}
+    }
+    return map[string]any{}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+    props := map[string]any{}
+    var required []string
+    g.fields(t, props, &required)
+    s := map[string]any{"type": "object", "properties": props}
+    if len(required) > 0 { s["required"] = required }
+    return s
+}
+
+func (g *schemaGen) fields(t reflect.Type, props map[string]any, required *[]string) {
+    for i := 0; i < t.NumField(); i++ {
+        f := t.Field(i)
+        tag := f.Tag.Get("json")
+        if f.Anonymous && tag == "" { g.fields(f.Type, props, required); continue }
+        if !f.IsExported() || tag == "-" { continue }
+        name, opts, _ := strings.Cut(tag, ",")
+        if name == "" { name = f.Name }
+        s := g.schema(f.Type)
+        if f.Tag.Get("format") != "" { s = map[string]any{"type": "string", "format": f.Tag.Get("format")} }
+        props[name] = s
+        if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer { *required = append(*required, name) }
+    }
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    writeJSON(w, http.StatusOK, openAPISpec(d))
+}
+
+// swaggerPage loads Swagger UI from a CDN and points it at /openapi.json.
+const swaggerPage = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>GoLLMCore API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>window.ui = SwaggerUIBundle({ url: '/openapi.json', dom_id: '#swagger-ui' });</script>
+</body>
+</html>
+`
+
+func handleSwaggerUI(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    _, _ = w.Write([]byte(swaggerPage))
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "sort"
+    "strings"
+
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+)
+
+// -------- Declarative pipelines --------
+
+type PipelineStep struct {
+    Type   string `json:"type"` // transcribe | chat | summarize | synthesize | embed
+    Model  string `json:"model,omitempty"`
+    Voice  string `json:"voice,omitempty"`
+    Prompt string `json:"prompt,omitempty"`
+}
+
+type Pipeline struct {
+    Steps []PipelineStep `json:"steps"`
+}
+
+const defaultSummarizePrompt = "Summarize the following text concisely. Keep key facts, decisions and action items."
+
+// stepKinds maps a step type to the data kind it consumes and produces.
+var stepKinds = map[string][2]string{
+    "transcribe": {"audio", "text"},
+    "chat":       {"text", "text"},
+    "summarize":  {"text", "text"},
+    "synthesize": {"text", "audio"},
+    "embed":      {"text", "vectors"},
+}
+
+// ValidatePipelines checks that every pipeline uses known steps whose inputs
+// and outputs line up and whose backing services are enabled.
+func ValidatePipelines(d Dependencies) error {
+    for name, p := range d.Pipelines {
+        if len(p.Steps) == 0 { return fmt.Errorf("pipeline %q has no steps", name) }
+        var prev string
+        for i, st := range p.Steps {
+            kinds, ok := stepKinds[st.Type]
+            if !ok { return fmt.Errorf("pipeline %q step %d: unsupported step type %q", name, i, st.Type) }
+            if i > 0 && kinds[0] != prev {
+                return fmt.Errorf("pipeline %q step %d (%s) needs %s input but previous step produces %s", name, i, st.Type, kinds[0], prev)
+            }
+            prev = kinds[1]
+            var missing string
+            switch st.Type {
+            case "transcribe": if d.STT == nil { missing = "stt" }
+            case "chat", "summarize": if d.LLM == nil { missing = "llm" }
+            case "synthesize": if d.TTS == nil { missing = "tts" }
+            case "embed": if d.Embeddings == nil { missing = "embeddings" }
+            }
+            if missing != "" { return fmt.Errorf("pipeline %q step %d (%s) requires the %s service", name, i, st.Type, missing) }
+            if st.Type == "transcribe" {
+                if err := stt.CheckPrompt(st.Prompt); err != nil { return fmt.Errorf("pipeline %q step %d (transcribe): %w", name, i, err) }
+            }
+        }
+    }
+    return nil
+}
+
+type pipelineData struct {
+    Text      string
+    AudioPath string
+    Audio     []byte
+}
+
+// runPipeline executes the steps in order, reporting each step's output.
+func runPipeline(ctx context.Context, d Dependencies, p Pipeline, in pipelineData, onStep func(i int, output any)) (any, error) {
+    cur := in
+    var last any
+    for i, st := range p.Steps {
+        var out any
+        switch st.Type {
+        case "transcribe":
+            model := st.Model
+            if model == "" { model = d.STTDefaultModel }
+            text, err := d.transcribeFile(ctx, cur.AudioPath, model, sttRequest{Prompt: st.Prompt})
+            if err != nil { return nil, fmt.Errorf("step %d (transcribe): %w", i, err) }
+            cur = pipelineData{Text: strings.TrimSpace(text)}
+            out = map[string]any{"text": cur.Text}
+        case "chat", "summarize":
+            prompt := st.Prompt
+            if prompt == "" && st.Type == "summarize" { prompt = defaultSummarizePrompt }
+            msgs := []llm.Message{}
+            if prompt != "" { msgs = append(msgs, llm.Message{Role: "system", Content: prompt}) }
+            msgs = append(msgs, llm.Message{Role: "user", Content: cur.Text})
+            resp, err := d.LLM.Chat(ctx, llm.ChatRequest{Model: st.Model, Messages: msgs})
+            if err != nil { return nil, fmt.Errorf("step %d (%s): %w", i, st.Type, err) }
+            cur = pipelineData{Text: strings.TrimSpace(resp.Text())}
+            out = map[string]any{"text": cur.Text}
+        case "synthesize":
+            audio, err := d.TTS.Synthesize(ctx, cur.Text, st.Voice)
+            if err != nil { return nil, fmt.Errorf("step %d (synthesize): %w", i, err) }
+            cur = pipelineData{Audio: audio}
+            out = map[string]any{"mime": "audio/wav", "audio_base64": base64.StdEncoding.EncodeToString(audio)}
+        case "embed":
+            vecs, model, err := d.Embeddings.Embed(ctx, []string{cur.Text})
+            if err != nil { return nil, fmt.Errorf("step %d (embed): %w", i, err) }
+            out = map[string]any{"model": model, "embeddings": vecs}
+        }
+        if onStep != nil { onStep(i, out) }
+        last = out
+    }
+    return last, nil
+}
+
+func handleListPipelines(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    names := make([]string, 0, len(d.Pipelines))
+    for n := range d.Pipelines { names = append(names, n) }
+    sort.Strings(names)
+    list := make([]map[string]any, 0, len(names))
+    for _, n := range names { list = append(list, map[string]any{"name": n, "steps": d.Pipelines[n].Steps}) }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{"pipelines": list})
+}
+
+func handleRunPipeline(w http.ResponseWriter, r *http.Request, d Dependencies, jobs *jobStore) {
+    name := r.PathValue("name")
+    p, ok := d.Pipelines[name]
+    if !ok { writeError(w, "pipeline not found", http.StatusNotFound); return }
+
+    var in pipelineData
+    var webhook string
+    if stepKinds[p.Steps[0].Type][0] == "audio" {
+        webhook = r.FormValue("webhook_url")
+        file, hdr, err := r.FormFile("file")
+        if err != nil { file, hdr, err = r.FormFile("audio") }
+        if err != nil { writeParamError(w, "file", "missing form file 'file' or 'audio'"); return }
+        defer file.Close()
+        in.AudioPath, err = d.saveUpload(r.Context(), "pipeline", hdr.Filename, file)
+        if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    } else {
+        var req struct{
+            Input      string `json:"input" validate:"required"`
+            WebhookURL string `json:"webhook_url"`
+        }
+        if !decodeJSON(w, r, &req) { return }
+        if strings.TrimSpace(req.Input) == "" { writeParamError(w, "input", "missing input"); return }
+        in.Text, webhook = req.Input, req.WebhookURL
+    }
+    if err := d.Webhooks.checkURL(webhook); err != nil {
+        if in.AudioPath != "" { os.Remove(in.AudioPath) }
+        writeParamError(w, "webhook_url", err.Error())
+        return
+    }
+
+    steps := make([]JobStep, len(p.Steps))
+    for i, st := range p.Steps { steps[i] = JobStep{Type: st.Type, Status: "pending"} }
+    ctx := jobContext(r)
+    job := jobs.add(ctx, Job{Kind: "pipeline:" + name, Steps: steps, WebhookURL: webhook})
+    go func() {
+        if in.AudioPath != "" { defer os.Remove(in.AudioPath) }
+        jobs.update(job.ID, func(j *Job) { j.Status = "running"; j.Steps[0].Status = "running" })
+        result, err := runPipeline(ctx, d, p, in, func(i int, out any) {
+            jobs.update(job.ID, func(j *Job) {
+                j.Steps[i].Status, j.Steps[i].Output = "succeeded", out
+                if i+1 < len(j.Steps) { j.Steps[i+1].Status = "running" }
+            })
+        })
+        jobs.update(job.ID, func(j *Job) {
+            if err != nil {
+                j.Status, j.Error = "failed", err.Error()
+                for i := range j.Steps { if j.Steps[i].Status == "running" { j.Steps[i].Status = "failed" } }
+                return
+            }
+            j.Status, j.Result = "succeeded", result
+        })
+        if err != nil { log.Printf("pipeline %s (job %s) failed: %v", name, job.ID, err) }
+    }()
+
+    snap, _ := jobs.get(job.ID)
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Location", "/v1/jobs/"+job.ID)
+    w.WriteHeader(http.StatusAccepted)
+    _ = json.NewEncoder(w).Encode(snap)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+    "context"
+    "net/http"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- System policy --------
+//
+// A deployment-wide policy prompt is prepended as the first system message of
+// every LLM call made for a client (chat, voice, realtime, pipelines). Only a
+// request authenticated with an admin key may skip it, by sending
+// "X-Policy-Override: off".
+
+type policyLLM struct {
+    next   LLMService
+    prompt string
+}
+
+type policyOffCtxKey struct{}
+
+// withPolicy wraps d.LLM so every call carries d.PolicyPrompt.
+func (d Dependencies) withPolicy() Dependencies {
+    if d.LLM == nil || d.PolicyPrompt == "" { return d }
+    if _, done := d.LLM.(*policyLLM); done { return d }
+    d.LLM = &policyLLM{next: d.LLM, prompt: d.PolicyPrompt}
+    return d
+}
+
+// policyOverride marks the request context so the policy is skipped when an
+// admin asks for it.
+func policyOverride(r *http.Request) *http.Request {
+    if r.Header.Get("X-Policy-Override") != "off" || !isAdmin(r.Context()) { return r }
+    return r.WithContext(context.WithValue(r.Context(), policyOffCtxKey{}, true))
+}
+
+func (p *policyLLM) apply(ctx context.Context, req llm.ChatRequest) llm.ChatRequest {
+    if off, _ := ctx.Value(policyOffCtxKey{}).(bool); off { return req }
+    msgs := make([]llm.Message, 0, len(req.Messages)+1)
+    msgs = append(msgs, llm.Message{Role: "system", Content: p.prompt})
+    req.Messages = append(msgs, req.Messages...)
+    return req
+}
+
+func (p *policyLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    return p.next.Chat(ctx, p.apply(ctx, req))
+}
+
+func (p *policyLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    return p.next.ChatStream(ctx, p.apply(ctx, req), onChunk)
+}
+
+// Model reports the upstream default model, when known.
+func (p *policyLLM) Model() string {
+    if m, ok := p.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+    "context"
+    "errors"
+    "strings"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Transcript post-processing --------
+//
+// postprocess=true pipes whisper's raw output through the LLM with a
+// clean-up prompt (punctuation, filler words, formatting). Responses then
+// carry the cleaned text as text and whisper's output as raw_text; segment
+// timings keep the raw text.
+
+// DefaultPostprocessPrompt is the system prompt used when none is configured.
+const DefaultPostprocessPrompt = "You clean up speech-to-text transcripts. Restore punctuation, capitalization and paragraphs, remove filler words (um, uh, you know) and false starts, and fix words that were obviously misheard. Do not summarize, translate, answer or add anything; keep the speaker's wording otherwise. Reply with the cleaned transcript only."
+
+// Postprocess configures LLM clean-up of transcripts.
+type Postprocess struct {
+    Default bool   // applied when a request does not choose
+    Prompt  string // system prompt (default DefaultPostprocessPrompt)
+}
+
+var errPostprocessNeedsLLM = errors.New("postprocess needs the LLM service to be enabled")
+
+// wantsPostprocess resolves a request's postprocess choice.
+func (d Dependencies) wantsPostprocess(req sttRequest) bool {
+    if req.Postprocess != nil { return *req.Postprocess }
+    return d.STTPostprocess.Default
+}
+
+// checkPostprocess rejects post-processing requests the server cannot serve.
+func (d Dependencies) checkPostprocess(req sttRequest) error {
+    if d.wantsPostprocess(req) && d.LLM == nil { return errPostprocessNeedsLLM }
+    return nil
+}
+
+// postprocess cleans t.Text with the LLM when the request asks for it,
+// keeping the original in t.Raw.
+func (d Dependencies) postprocess(ctx context.Context, req sttRequest, t *transcript) error {
+    if !d.wantsPostprocess(req) || strings.TrimSpace(t.Text) == "" { return nil }
+    if d.LLM == nil { return errPostprocessNeedsLLM }
+    prompt := d.STTPostprocess.Prompt
+    if prompt == "" { prompt = DefaultPostprocessPrompt }
+    temp := 0.0
+    resp, err := d.LLM.Chat(ctx, llm.ChatRequest{
+        Messages:    []llm.Message{{Role: "system", Content: prompt}, {Role: "user", Content: t.Text}},
+        Temperature: &temp,
+    })
+    if err != nil { return err }
+    t.Raw, t.Text = t.Text, strings.TrimSpace(resp.Text())
+    return nil
+}
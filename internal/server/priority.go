@@ -0,0 +1,87 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// -------- Priorities --------
+//
+// Every call runs as interactive (a person is waiting) or background
+// (batches, pipeline and upload jobs). When a service is at its
+// concurrency limit, freed slots go to the waiting classes in proportion to
+// their weights, so a long batch cannot starve live requests while still
+// making progress. Clients choose with the X-Priority header or a
+// "priority" field; jobs default to background.
+
+type Priority int
+
+const (
+    Interactive Priority = iota
+    Background
+    numPriorities
+)
+
+func (p Priority) String() string {
+    if p == Background { return "background" }
+    return "interactive"
+}
+
+// ParsePriority accepts "interactive" or "background"; "" is interactive.
+func ParsePriority(s string) (Priority, error) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+    case "", "interactive":
+        return Interactive, nil
+    case "background":
+        return Background, nil
+    }
+    return Interactive, fmt.Errorf("priority must be interactive or background, got %q", s)
+}
+
+// PriorityClass tunes the queue of one priority at every limited service.
+type PriorityClass struct {
+    Weight   int // share of freed slots while both classes wait (default 4 interactive, 1 background)
+    MaxQueue int // calls that may wait (default the service's MaxQueue, 4x that for background)
+}
+
+type priorityCtxKey struct{}
+
+func withPriority(ctx context.Context, p Priority) context.Context {
+    return context.WithValue(ctx, priorityCtxKey{}, p)
+}
+
+// priorityOf returns the priority set on ctx, or def when none was.
+func priorityOf(ctx context.Context, def Priority) Priority {
+    if p, ok := ctx.Value(priorityCtxKey{}).(Priority); ok { return p }
+    return def
+}
+
+// requestPriority applies a "priority" body or form field to r; an empty
+// field leaves the X-Priority header (or the default) in place.
+func requestPriority(r *http.Request, field string) (*http.Request, error) {
+    if field == "" { return r, nil }
+    p, err := ParsePriority(field)
+    if err != nil { return r, err }
+    return r.WithContext(withPriority(r.Context(), p)), nil
+}
+
+// jobContext is the context of work that outlives r: detached from it,
+// background unless the client asked otherwise, and carrying r's tenant
+// and API key as the job's owner.
+func jobContext(r *http.Request) context.Context {
+    return withJobOwner(withPriority(context.Background(), priorityOf(r.Context(), Background)), requestJobOwner(r))
+}
+
+// Prioritize sets the priority of each request from its X-Priority header;
+// invalid values are rejected with 400.
+func Prioritize(h http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        v := r.Header.Get("X-Priority")
+        if v == "" { h.ServeHTTP(w, r); return }
+        p, err := ParsePriority(v)
+        if err != nil { writeError(w, err.Error(), http.StatusBadRequest); return }
+        h.ServeHTTP(w, r.WithContext(withPriority(r.Context(), p)))
+    })
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+)
+
+// -------- Read-only mode --------
+//
+// A shipped instance can be locked down: the parts marked in ReadOnly keep
+// working but cannot be changed through the API. Refused requests get 403
+// with code read_only, and /v1/capabilities lists what is locked so
+// embedders can hide the controls instead of probing.
+
+// ReadOnly marks what a deployment may not change.
+type ReadOnly struct {
+    // Downloads is informational here: the caller disables fetching with
+    // models.DisableDownloads, so only installed models are served.
+    Downloads bool
+    // Sessions can be listed, read and used as chat context, but not
+    // created, changed or deleted; chat turns are not recorded.
+    Sessions  bool
+    // Speakers can be verified and identified, but not enrolled or deleted.
+    Speakers  bool
+    // Admin leaves /admin/prompts and /admin/lora readable only.
+    Admin     bool
+}
+
+var errReadOnly = errors.New("read-only")
+
+func readOnlyError(what string) error { return fmt.Errorf("%s are read-only on this server: %w", what, errReadOnly) }
+
+// readOnlyGuard passes only reads (GET, HEAD) to h when locked.
+func readOnlyGuard(locked bool, what string, h http.HandlerFunc) http.HandlerFunc {
+    if !locked { return h }
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead { writeServiceError(w, readOnlyError(what), http.StatusForbidden); return }
+        h(w, r)
+    }
+}
+
+func (o ReadOnly) capabilities() map[string]any {
+    return map[string]any{"downloads": o.Downloads, "sessions": o.Sessions, "speakers": o.Speakers, "admin": o.Admin}
+}
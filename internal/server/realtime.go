@@ -0,0 +1,271 @@
+package server
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "gollmcore/internal/scratch"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+    "gollmcore/internal/services/tts"
+)
+
+// -------- Realtime-style WebSocket session --------
+//
+// A subset of the OpenAI Realtime protocol: clients append audio to an input
+// buffer, commit it for transcription, and request responses that stream text
+// deltas from the LLM and audio deltas (one WAV per sentence) from TTS.
+
+const maxRealtimeBuffer = 50 << 20
+
+type realtimeSession struct {
+    Instructions         string   `json:"instructions"`
+    Voice                string   `json:"voice"`
+    Modalities           []string `json:"modalities"`
+    InputAudioFormat     string   `json:"input_audio_format"`      // pcm16 | wav
+    InputAudioSampleRate int      `json:"input_audio_sample_rate"` // for pcm16
+    OutputAudioFormat    string   `json:"output_audio_format"`     // wav
+    TranscriptionModel   string   `json:"transcription_model"`
+    TranscriptionPrompt  string   `json:"transcription_prompt"`
+}
+
+type realtimeEvent struct {
+    Type     string          `json:"type"`
+    EventID  string          `json:"event_id,omitempty"`
+    Audio    string          `json:"audio,omitempty"`
+    Session  json.RawMessage `json:"session,omitempty"`
+    Item     json.RawMessage `json:"item,omitempty"`
+    Response json.RawMessage `json:"response,omitempty"`
+}
+
+type realtimeConn struct {
+    conn    *wsConn
+    d       Dependencies
+    wmu     sync.Mutex
+    mu      sync.Mutex
+    session realtimeSession
+    buffer  []byte
+    items   []llm.Message
+    cancel  context.CancelFunc
+    seq     int
+}
+
+func (c *realtimeConn) send(v map[string]any) {
+    c.wmu.Lock()
+    defer c.wmu.Unlock()
+    c.seq++
+    v["event_id"] = fmt.Sprintf("evt_%d", c.seq)
+    _ = c.conn.WriteJSON(v)
+}
+
+func (c *realtimeConn) sendError(code, msg string) {
+    c.send(map[string]any{"type": "error", "error": newAPIError(http.StatusBadRequest, code, "", msg)})
+}
+
+func handleRealtime(w http.ResponseWriter, r *http.Request, d Dependencies, hub *wsHub) {
+    conn, err := hub.upgrade(w, r)
+    if err != nil { return }
+    defer conn.Close()
+    c := &realtimeConn{conn: conn, d: d, session: realtimeSession{
+        Instructions:         d.VoiceSystemPrompt,
+        Modalities:           []string{"text", "audio"},
+        InputAudioFormat:     "pcm16",
+        InputAudioSampleRate: 24000,
+        OutputAudioFormat:    "wav",
+        TranscriptionModel:   d.STTDefaultModel,
+    }}
+    defer func() {
+        c.mu.Lock()
+        if c.cancel != nil { c.cancel() }
+        c.mu.Unlock()
+    }()
+    c.send(map[string]any{"type": "session.created", "session": c.session})
+
+    for {
+        var ev realtimeEvent
+        if err := conn.ReadJSON(&ev); err != nil { return }
+        switch ev.Type {
+        case "session.update":
+            c.mu.Lock()
+            sess := c.session
+            err := json.Unmarshal(ev.Session, &sess)
+            if err == nil { err = stt.CheckPrompt(sess.TranscriptionPrompt) }
+            if err == nil { c.session = sess }
+            c.mu.Unlock()
+            if err != nil { c.sendError("invalid_session", err.Error()); continue }
+            c.send(map[string]any{"type": "session.updated", "session": sess})
+        case "input_audio_buffer.append":
+            b, err := base64.StdEncoding.DecodeString(ev.Audio)
+            if err != nil { c.sendError("invalid_audio", "audio must be base64"); continue }
+            c.mu.Lock()
+            overflow := len(c.buffer)+len(b) > maxRealtimeBuffer
+            if !overflow { c.buffer = append(c.buffer, b...) }
+            c.mu.Unlock()
+            if overflow { c.sendError("buffer_too_large", "input audio buffer is full; commit or clear it") }
+        case "input_audio_buffer.clear":
+            c.mu.Lock()
+            c.buffer = nil
+            c.mu.Unlock()
+            c.send(map[string]any{"type": "input_audio_buffer.cleared"})
+        case "input_audio_buffer.commit":
+            c.commitAudio(r.Context())
+        case "conversation.item.create":
+            var item struct {
+                Type    string `json:"type"`
+                Role    string `json:"role"`
+                Content []struct {
+                    Type string `json:"type"`
+                    Text string `json:"text"`
+                } `json:"content"`
+            }
+            if err := json.Unmarshal(ev.Item, &item); err != nil || item.Type != "message" {
+                c.sendError("invalid_item", "only message items are supported")
+                continue
+            }
+            var text strings.Builder
+            for _, p := range item.Content { text.WriteString(p.Text) }
+            role := item.Role
+            if role == "" { role = "user" }
+            c.mu.Lock()
+            c.items = append(c.items, llm.Message{Role: role, Content: text.String()})
+            c.mu.Unlock()
+            c.send(map[string]any{"type": "conversation.item.created", "item": map[string]any{"type": "message", "role": role}})
+        case "response.create":
+            c.startResponse(r.Context(), ev.Response)
+        case "response.cancel":
+            c.mu.Lock()
+            if c.cancel != nil { c.cancel() }
+            c.mu.Unlock()
+        default:
+            c.sendError("unknown_event", "unsupported event type: "+ev.Type)
+        }
+    }
+}
+
+func (c *realtimeConn) commitAudio(ctx context.Context) {
+    if c.d.STT == nil { c.sendError("stt_disabled", "speech-to-text is not enabled"); return }
+    c.mu.Lock()
+    audio := c.buffer
+    c.buffer = nil
+    sess := c.session
+    c.mu.Unlock()
+    if len(audio) == 0 { c.sendError("input_audio_buffer_commit_empty", "input audio buffer is empty"); return }
+    c.send(map[string]any{"type": "input_audio_buffer.committed"})
+
+    if sess.InputAudioFormat != "wav" { audio = pcm16ToWAV(audio, sess.InputAudioSampleRate) }
+    f, err := scratch.Create("realtime-", ".wav")
+    if err == nil {
+        _, err = f.Write(audio)
+        if cerr := f.Close(); err == nil { err = cerr }
+    }
+    if f != nil { defer os.Remove(f.Name()) }
+    if err != nil { c.sendError("server_error", err.Error()); return }
+    tmp := f.Name()
+    text, err := c.d.transcribeFile(ctx, tmp, sess.TranscriptionModel, sttRequest{Prompt: sess.TranscriptionPrompt})
+    if err != nil { c.sendError("transcription_failed", err.Error()); return }
+    text = strings.TrimSpace(text)
+    c.mu.Lock()
+    c.items = append(c.items, llm.Message{Role: "user", Content: text})
+    c.mu.Unlock()
+    c.send(map[string]any{"type": "conversation.item.input_audio_transcription.completed", "transcript": text})
+}
+
+func (c *realtimeConn) startResponse(parent context.Context, raw json.RawMessage) {
+    var opts struct {
+        Instructions string   `json:"instructions"`
+        Modalities   []string `json:"modalities"`
+    }
+    if len(raw) > 0 { _ = json.Unmarshal(raw, &opts) }
+
+    c.mu.Lock()
+    if c.cancel != nil {
+        c.mu.Unlock()
+        c.sendError("conversation_already_has_active_response", "a response is already in progress")
+        return
+    }
+    ctx, cancel := context.WithCancel(parent)
+    c.cancel = cancel
+    sess := c.session
+    msgs := make([]llm.Message, 0, len(c.items)+1)
+    instructions := sess.Instructions
+    if opts.Instructions != "" { instructions = opts.Instructions }
+    if instructions != "" { msgs = append(msgs, llm.Message{Role: "system", Content: instructions}) }
+    msgs = append(msgs, c.items...)
+    c.mu.Unlock()
+    modalities := sess.Modalities
+    if len(opts.Modalities) > 0 { modalities = opts.Modalities }
+    wantAudio := c.d.TTS != nil && containsType(modalities, "audio")
+
+    jobDone := c.conn.startJob()
+    go func() {
+        defer jobDone()
+        defer func() {
+            cancel()
+            c.mu.Lock()
+            c.cancel = nil
+            c.mu.Unlock()
+        }()
+        respID := fmt.Sprintf("resp_%d", time.Now().UnixNano())
+        c.send(map[string]any{"type": "response.created", "response": map[string]any{"id": respID, "status": "in_progress"}})
+
+        var pending strings.Builder
+        speak := func(text string) error {
+            text = strings.TrimSpace(text)
+            if !wantAudio || text == "" { return nil }
+            audio, err := c.d.TTS.Synthesize(ctx, text, sess.Voice)
+            if err != nil { return err }
+            c.send(map[string]any{"type": "response.audio.delta", "response_id": respID, "delta": base64.StdEncoding.EncodeToString(audio)})
+            return nil
+        }
+        resp, err := c.d.LLM.ChatStream(ctx, llm.ChatRequest{Messages: msgs}, func(ch llm.ChatChunk) error {
+            for _, choice := range ch.Choices {
+                if choice.Delta.Content == "" { continue }
+                c.send(map[string]any{"type": "response.text.delta", "response_id": respID, "delta": choice.Delta.Content})
+                pending.WriteString(choice.Delta.Content)
+                if sentence, rest, ok := cutSentence(pending.String()); ok {
+                    pending.Reset()
+                    pending.WriteString(rest)
+                    if err := speak(sentence); err != nil { return err }
+                }
+            }
+            return nil
+        })
+        if err == nil { err = speak(pending.String()) }
+        if err != nil {
+            status := "failed"
+            if ctx.Err() != nil { status = "cancelled" }
+            c.send(map[string]any{"type": "response.done", "response": map[string]any{"id": respID, "status": status, "error": err.Error()}})
+            return
+        }
+        text := resp.Text()
+        c.mu.Lock()
+        c.items = append(c.items, llm.Message{Role: "assistant", Content: text})
+        c.mu.Unlock()
+        c.send(map[string]any{"type": "response.text.done", "response_id": respID, "text": text})
+        if wantAudio { c.send(map[string]any{"type": "response.audio.done", "response_id": respID}) }
+        c.send(map[string]any{"type": "response.done", "response": map[string]any{"id": respID, "status": "completed"}})
+    }()
+}
+
+// cutSentence splits off the first complete sentence of s, if any.
+func cutSentence(s string) (string, string, bool) {
+    for i, r := range s {
+        if r != '.' && r != '!' && r != '?' && r != '\n' { continue }
+        end := i + len(string(r))
+        if end < len(s) && (s[end] == ' ' || s[end] == '\n') { return s[:end], s[end:], true }
+    }
+    return "", s, false
+}
+
+// pcm16ToWAV wraps raw little-endian mono PCM16 samples in a WAV header.
+func pcm16ToWAV(pcm []byte, sampleRate int) []byte {
+    if sampleRate <= 0 { sampleRate = 24000 }
+    return tts.BuildWAV(tts.WAVFormat{AudioFormat: 1, Channels: 1, SampleRate: uint32(sampleRate), BitsPerSample: 16}, pcm)
+}
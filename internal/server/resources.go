@@ -0,0 +1,123 @@
+package server
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/procs"
+    "gollmcore/internal/resources"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+)
+
+// -------- Resource guards --------
+//
+// With a resource monitor configured, LLM calls for a model that a local
+// Ollama upstream would have to load are refused when the model does not
+// fit into free RAM plus VRAM, and whisper falls back to the largest
+// smaller model that fits. Memory is estimated as the model size plus 20%
+// for the KV cache and runtime buffers; Reserve is always left free.
+
+var errInsufficientMemory = errors.New("insufficient memory")
+
+// ResourceGuard holds the monitor consulted by the guards.
+type ResourceGuard struct {
+    Monitor  *resources.Monitor
+    // Adaptive enables the guards; without it the monitor only reports.
+    Adaptive bool
+    // Reserve is memory kept free for the OS and other services.
+    Reserve uint64
+}
+
+// free returns the usable memory, or ok=false when it is unknown.
+func (g ResourceGuard) free(withVRAM bool) (uint64, bool) {
+    if g.Monitor == nil || !g.Adaptive { return 0, false }
+    r := g.Monitor.Read()
+    if !r.Known() { return 0, false }
+    n := r.MemoryAvailable
+    if withVRAM { n += r.VRAMFree() }
+    if n <= g.Reserve { return 0, true }
+    return n - g.Reserve, true
+}
+
+// fitWhisperModel returns model, or the largest smaller whisper model when
+// model would not fit into free memory.
+func (d Dependencies) fitWhisperModel(model string) string {
+    free, ok := d.Resources.free(false)
+    if !ok { return model }
+    want := strings.ToLower(model)
+    if want == "large" { want = "large-v2" }
+    idx := -1
+    for i, m := range stt.ModelMemory {
+        if m.Size == want { idx = i }
+    }
+    if idx < 0 || stt.ModelMemory[idx].Bytes <= free { return model }
+    // Fall back to the largest smaller model that fits, or tiny as a last resort.
+    for i := idx - 1; i >= 0; i-- {
+        m := stt.ModelMemory[i]
+        if m.Bytes >= stt.ModelMemory[idx].Bytes || (m.Bytes > free && i > 0) { continue }
+        log.Printf("memory pressure: %s free, using whisper %s instead of %s", resources.FormatBytes(free), m.Size, model)
+        metrics.add("gollmcore_model_downgrades_total", "Whisper calls that used a smaller model because of memory pressure.", `model="`+model+`"`, 1)
+        return m.Size
+    }
+    return model
+}
+
+// WithResources refuses LLM calls whose model would not fit in memory.
+// Apply it to the bare services, before the other decorators.
+func WithResources(d Dependencies) Dependencies {
+    if d.Resources.Monitor == nil || !d.Resources.Adaptive || d.LLM == nil { return d }
+    if f, ok := d.LLM.(llmFootprint); ok { d.LLM = &resourceLLM{next: d.LLM, fp: f, guard: d.Resources} }
+    return d
+}
+
+type llmFootprint interface {
+    Footprint(ctx context.Context, model string) (size uint64, loaded, ok bool)
+}
+
+type resourceLLM struct {
+    next  LLMService
+    fp    llmFootprint
+    guard ResourceGuard
+}
+
+func (l *resourceLLM) check(ctx context.Context, model string) error {
+    size, loaded, ok := l.fp.Footprint(ctx, model)
+    if !ok || loaded { return nil }
+    free, known := l.guard.free(true)
+    if !known { return nil }
+    need := size + size/5
+    if need <= free { return nil }
+    if model == "" { model = l.Model() }
+    return fmt.Errorf("%w: model %s needs about %s but only %s is available", errInsufficientMemory, model, resources.FormatBytes(need), resources.FormatBytes(free))
+}
+
+func (l *resourceLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    if err := l.check(ctx, req.Model); err != nil { return nil, err }
+    return l.next.Chat(ctx, req)
+}
+
+func (l *resourceLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    if err := l.check(ctx, req.Model); err != nil { return nil, err }
+    return l.next.ChatStream(ctx, req, onChunk)
+}
+
+func (l *resourceLLM) Model() string {
+    if m, ok := l.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+// handleAdminStatus reports the latest resource reading together with the
+// live ONNX sessions and child processes.
+func handleAdminStatus(w http.ResponseWriter, r *http.Request, m *resources.Monitor) {
+    writeJSON(w, http.StatusOK, map[string]any{
+        "resources":       m.Read(),
+        "onnx_sessions":   onnxrt.Sessions(),
+        "child_processes": procs.List(),
+    })
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash/fnv"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// -------- Resumable uploads --------
+//
+// Long recordings can be sent in pieces over flaky connections:
+//
+//   POST   /v1/uploads       {"filename", "size", "model", "prompt", ...} -> 201 upload
+//   PATCH  /v1/uploads/{id}  Upload-Offset: n, body = the bytes from n     -> 200 upload
+//   HEAD   /v1/uploads/{id}  -> Upload-Offset / Upload-Length headers
+//   GET    /v1/uploads/{id}  -> upload
+//   DELETE /v1/uploads/{id}
+//
+// Bytes are appended to <Dir>/<id>.part, whose size is the offset, and the
+// request options are kept in <id>.json, so uploads survive restarts. Once
+// the last byte arrives the file is screened and transcribed as a job
+// (GET /v1/jobs/{job_id}); with a job file, a restart resumes it.
+
+// ResumableUploads configures /v1/uploads; an empty Dir disables it.
+type ResumableUploads struct {
+    Dir      string
+    MaxBytes int64         // default 4 GiB
+    Expiry   time.Duration // unfinished uploads are dropped after this long idle (default 24h)
+}
+
+func (o ResumableUploads) withDefaults() ResumableUploads {
+    if o.MaxBytes <= 0 { o.MaxBytes = 4 << 30 }
+    if o.Expiry <= 0 { o.Expiry = 24 * time.Hour }
+    return o
+}
+
+type upload struct {
+    ID         string    `json:"id"`
+    Filename   string    `json:"filename"`
+    Size       int64     `json:"size"`
+    Offset     int64     `json:"offset"`
+    Model      string    `json:"model,omitempty"`
+    sttRequest
+    JobID      string    `json:"job_id,omitempty"`
+    ExpiresAt  time.Time `json:"expires_at"`
+    // WebhookURL is notified when the transcription job finishes.
+    WebhookURL string    `json:"webhook_url,omitempty"`
+}
+
+type uploadStore struct {
+    o     ResumableUploads
+    locks [64]sync.Mutex // sharded by upload id
+}
+
+func newUploadStore(o ResumableUploads) (*uploadStore, error) {
+    o = o.withDefaults()
+    if err := os.MkdirAll(o.Dir, 0o755); err != nil { return nil, err }
+    return &uploadStore{o: o}, nil
+}
+
+// lock serializes requests on one upload. Locks are a fixed set shared by
+// the uploads hashing to them, so ids sent by clients cost no memory; a
+// holder must not lock a second upload.
+func (s *uploadStore) lock(id string) func() {
+    h := fnv.New32a()
+    h.Write([]byte(id))
+    m := &s.locks[h.Sum32()%uint32(len(s.locks))]
+    m.Lock()
+    return m.Unlock
+}
+
+func (s *uploadStore) path(id, ext string) string { return filepath.Join(s.o.Dir, id+ext) }
+
+// validUploadID keeps client-supplied ids from escaping the upload dir.
+func validUploadID(id string) bool {
+    if !strings.HasPrefix(id, "upl_") || len(id) != 28 { return false }
+    for _, c := range id[4:] {
+        if !strings.ContainsRune("0123456789abcdef", c) { return false }
+    }
+    return true
+}
+
+// load returns the upload with its current offset and expiry.
+func (s *uploadStore) load(id string) (upload, bool) {
+    var u upload
+    if !validUploadID(id) { return u, false }
+    b, err := os.ReadFile(s.path(id, ".json"))
+    if err != nil || json.Unmarshal(b, &u) != nil { return u, false }
+    last := time.Time{}
+    if fi, err := os.Stat(s.path(id, ".json")); err == nil { last = fi.ModTime() }
+    if fi, err := os.Stat(s.path(id, ".part")); err == nil {
+        u.Offset = fi.Size()
+        if fi.ModTime().After(last) { last = fi.ModTime() }
+    } else if u.JobID != "" {
+        u.Offset = u.Size
+    }
+    u.ExpiresAt = last.Add(s.o.Expiry).UTC()
+    if time.Now().After(u.ExpiresAt) { s.remove(id); return u, false }
+    return u, true
+}
+
+func (s *uploadStore) save(u upload) error {
+    b, _ := json.Marshal(u)
+    tmp := s.path(u.ID, ".json.tmp")
+    if err := os.WriteFile(tmp, b, 0o644); err != nil { return err }
+    return os.Rename(tmp, s.path(u.ID, ".json"))
+}
+
+func (s *uploadStore) remove(id string) {
+    audio, _ := filepath.Glob(s.path(id, ".audio*"))
+    for _, p := range append(audio, s.path(id, ".part"), s.path(id, ".json")) { _ = os.Remove(p) }
+}
+
+// gc drops expired uploads.
+func (s *uploadStore) gc() {
+    matches, _ := filepath.Glob(filepath.Join(s.o.Dir, "upl_*.json"))
+    for _, m := range matches { s.load(strings.TrimSuffix(filepath.Base(m), ".json")) }
+}
+
+func writeUpload(w http.ResponseWriter, status int, u upload) {
+    w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+    w.Header().Set("Upload-Length", strconv.FormatInt(u.Size, 10))
+    w.Header().Set("Cache-Control", "no-store")
+    w.Header().Set("Content-Type", "application/json")
+    if u.JobID != "" { w.Header().Set("Location", "/v1/jobs/"+u.JobID) }
+    w.WriteHeader(status)
+    _ = json.NewEncoder(w).Encode(u)
+}
+
+func createUpload(w http.ResponseWriter, r *http.Request, d Dependencies, s *uploadStore) {
+    var u upload
+    if !decodeJSON(w, r, &u) { return }
+    if u.Size <= 0 { writeParamError(w, "size", "size must be the total number of bytes to upload"); return }
+    if u.Size > s.o.MaxBytes {
+        writeError(w, fmt.Sprintf("upload exceeds the %d byte limit", s.o.MaxBytes), http.StatusRequestEntityTooLarge)
+        return
+    }
+    if param, err := u.check(); err != nil { writeParamError(w, param, err.Error()); return }
+    if err := d.checkPostprocess(u.sttRequest); err != nil { writeParamError(w, "postprocess", err.Error()); return }
+    if err := d.Webhooks.checkURL(u.WebhookURL); err != nil { writeParamError(w, "webhook_url", err.Error()); return }
+    if u.Filename == "" { u.Filename = "audio" }
+    u.Filename = sanitizeName(u.Filename)
+    if _, err := uploadExt(u.Filename); err != nil { writeParamError(w, "filename", err.Error()); return }
+    u.ID, u.Offset, u.JobID = newID("upl"), 0, ""
+    s.gc()
+    if err := os.WriteFile(s.path(u.ID, ".part"), nil, 0o644); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    if err := s.save(u); err != nil { s.remove(u.ID); writeServiceError(w, err, http.StatusInternalServerError); return }
+    u.ExpiresAt = time.Now().Add(s.o.Expiry).UTC()
+    w.Header().Set("Location", "/v1/uploads/"+u.ID)
+    writeUpload(w, http.StatusCreated, u)
+}
+
+// appendUpload writes the request body at the upload's offset. Bytes that
+// arrive before a dropped connection are kept, so the client can resume
+// from the offset reported by HEAD.
+func appendUpload(w http.ResponseWriter, r *http.Request, d Dependencies, s *uploadStore, jobs *jobStore, u upload) {
+    if u.JobID != "" { writeError(w, "upload is already complete", http.StatusConflict); return }
+    offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+    if err != nil { writeError(w, "missing or invalid Upload-Offset header", http.StatusBadRequest); return }
+    if offset != u.Offset {
+        w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+        writeError(w, fmt.Sprintf("Upload-Offset %d does not match the %d bytes received so far", offset, u.Offset), http.StatusConflict)
+        return
+    }
+    f, err := os.OpenFile(s.path(u.ID, ".part"), os.O_WRONLY|os.O_APPEND, 0)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    remaining := u.Size - u.Offset
+    n, err := io.Copy(f, io.LimitReader(r.Body, remaining+1))
+    if n > remaining {
+        _ = f.Truncate(u.Offset)
+        f.Close()
+        writeError(w, fmt.Sprintf("body runs past the declared size of %d bytes", u.Size), http.StatusRequestEntityTooLarge)
+        return
+    }
+    if cerr := f.Close(); err == nil { err = cerr }
+    u.Offset += n
+    if err != nil {
+        log.Printf("upload %s interrupted at %d of %d bytes: %v", u.ID, u.Offset, u.Size, err)
+        writeServiceError(w, err, http.StatusInternalServerError)
+        return
+    }
+    if u.Offset < u.Size { writeUpload(w, http.StatusOK, u); return }
+
+    // Complete: screen and transcribe it, keeping the extension whisper
+    // may rely on.
+    audio := s.path(u.ID, ".audio"+filepath.Ext(u.Filename))
+    if err := os.Rename(s.path(u.ID, ".part"), audio); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    if err := d.Uploads.Check(r.Context(), audio, u.Filename); err != nil {
+        os.Remove(audio)
+        s.remove(u.ID)
+        writeServiceError(w, err, http.StatusInternalServerError)
+        return
+    }
+    ctx := jobContext(r)
+    job := jobs.add(ctx, Job{Kind: "transcription", UploadID: u.ID, WebhookURL: u.WebhookURL})
+    u.JobID = job.ID
+    if err := s.save(u); err != nil { log.Printf("upload %s: %v", u.ID, err) }
+    go runUploadJob(ctx, d, u, audio, jobs)
+    writeUpload(w, http.StatusOK, u)
+}
+
+// runUploadJob transcribes the audio of a complete upload as its job.
+func runUploadJob(ctx context.Context, d Dependencies, u upload, audio string, jobs *jobStore) {
+    defer os.Remove(audio)
+    model := u.Model
+    if model == "" { model = d.STTDefaultModel }
+    jobs.update(u.JobID, func(j *Job) { j.Status = "running" })
+    t, err := d.transcribe(ctx, audio, model, u.sttRequest)
+    if err == nil { err = d.postprocess(ctx, u.sttRequest, &t) }
+    jobs.update(u.JobID, func(j *Job) {
+        if err != nil { j.Status, j.Error = "failed", err.Error(); return }
+        j.Status, j.Result = "succeeded", t.response(model)
+    })
+    if err != nil && !errors.Is(err, context.Canceled) { log.Printf("upload %s (job %s) failed: %v", u.ID, u.JobID, err) }
+}
+
+// resumeUpload restarts the transcription job j after a restart, if its
+// upload and audio are still there.
+func resumeUpload(d Dependencies, s *uploadStore, jobs *jobStore, j Job) bool {
+    if j.Kind != "transcription" || j.UploadID == "" { return false }
+    unlock := s.lock(j.UploadID)
+    defer unlock()
+    u, ok := s.load(j.UploadID)
+    if !ok || u.JobID != j.ID { return false }
+    audio, _ := filepath.Glob(s.path(u.ID, ".audio*"))
+    if len(audio) != 1 { return false }
+    go runUploadJob(withJobOwner(withPriority(context.Background(), Background), j.Owner), d, u, audio[0], jobs)
+    return true
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+    "net/http"
+    "slices"
+    "strings"
+)
+
+// -------- Routing --------
+//
+// Routes are registered as "METHOD /path/{name}" patterns on the standard
+// ServeMux, so handlers read path parameters with r.PathValue and never
+// check the method themselves. A known path requested with another method
+// gets the API's JSON error with status 405 and an Allow header, not the
+// mux's plain-text reply.
+
+// router collects the routes of one Register function; done installs the
+// 405 answers once every method of every path is known.
+type router struct {
+    mux       *http.ServeMux
+    paths     []string
+    methods   map[string][]string // path -> methods, in registration order
+    jsonLimit int64               // see withJSONLimit
+}
+
+func newRouter(mux *http.ServeMux) *router { return &router{mux: mux, methods: map[string][]string{}} }
+
+// handle registers h for pattern, which must name a method.
+func (rt *router) handle(pattern string, h http.HandlerFunc) {
+    method, path, ok := strings.Cut(pattern, " ")
+    if !ok { panic("route without a method: " + pattern) }
+    rt.mux.HandleFunc(pattern, withJSONLimit(h, rt.jsonLimit))
+    if _, seen := rt.methods[path]; !seen { rt.paths = append(rt.paths, path) }
+    rt.methods[path] = append(rt.methods[path], method)
+}
+
+// answeredMethods are the methods done answers with 405; a GET route also
+// serves HEAD.
+var answeredMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+
+func (rt *router) done() {
+    for _, path := range rt.paths {
+        allowed := rt.methods[path]
+        if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) { allowed = append(allowed, http.MethodHead) }
+        allow := strings.Join(allowed, ", ")
+        for _, m := range answeredMethods {
+            if slices.Contains(allowed, m) { continue }
+            rt.mux.HandleFunc(m+" "+path, func(w http.ResponseWriter, r *http.Request) {
+                w.Header().Set("Allow", allow)
+                writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+            })
+        }
+    }
+}
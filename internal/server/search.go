@@ -0,0 +1,77 @@
+package server
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "gollmcore/internal/websearch"
+)
+
+// -------- Web search --------
+//
+// With a search provider configured, /v1/tools/search runs a query for
+// clients, and the "web_search" tool lets the model look up current events
+// in agent mode when the operator lists it among the tools.
+
+// WebSearch is the configured provider and its default result count.
+type WebSearch struct {
+    Provider   websearch.Provider
+    MaxResults int // default 5, at most 20
+}
+
+const maxSearchResults = 20
+
+func (s WebSearch) count(n int) int {
+    if n <= 0 { n = s.MaxResults }
+    if n <= 0 { n = 5 }
+    return min(n, maxSearchResults)
+}
+
+type searchRequest struct {
+    Query      string `json:"query" validate:"required"`
+    MaxResults int    `json:"max_results,omitempty"`
+}
+
+type searchResponse struct {
+    Provider string             `json:"provider"`
+    Query    string             `json:"query"`
+    Results  []websearch.Result `json:"results"`
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request, s WebSearch) {
+    var req searchRequest
+    if !decodeJSON(w, r, &req) { return }
+    req.Query = strings.TrimSpace(req.Query)
+    if req.Query == "" { writeParamError(w, "query", "missing query"); return }
+    if len(req.Query) > 512 { writeParamError(w, "query", "query exceeds 512 bytes"); return }
+    if req.MaxResults < 0 { writeParamError(w, "max_results", "max_results must not be negative"); return }
+    results, err := s.Provider.Search(r.Context(), req.Query, s.count(req.MaxResults))
+    metrics.add("gollmcore_web_searches_total", "Web searches run through the configured provider.", `provider="`+s.Provider.Name()+`"`, 1)
+    if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+    if results == nil { results = []websearch.Result{} }
+    writeJSON(w, http.StatusOK, searchResponse{Provider: s.Provider.Name(), Query: req.Query, Results: results})
+}
+
+// SearchTool is the web_search agent tool over s.
+func SearchTool(s WebSearch) Tool {
+    return Tool{
+        Description: "Search the web for current information. Returns titles, URLs and snippets; cite the URLs you use.",
+        Parameters:  json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"},"max_results":{"type":"integer","minimum":1,"maximum":20}},"required":["query"]}`),
+        Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+            var in searchRequest
+            if err := json.Unmarshal(args, &in); err != nil { return "", err }
+            if strings.TrimSpace(in.Query) == "" { return "", errors.New("missing query") }
+            results, err := s.Provider.Search(ctx, in.Query, s.count(in.MaxResults))
+            metrics.add("gollmcore_web_searches_total", "Web searches run through the configured provider.", `provider="`+s.Provider.Name()+`"`, 1)
+            if err != nil { return "", err }
+            if len(results) == 0 { return "No results.", nil }
+            var b strings.Builder
+            for i, r := range results { fmt.Fprintf(&b, "%d. %s\n%s\n%s\n\n", i+1, r.Title, r.URL, r.Snippet) }
+            return strings.TrimSpace(b.String()), nil
+        },
+    }
+}
@@ -1,65 +1,265 @@
 package server
 
 import (
-    "bufio"
+    "context"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "log"
+    "math"
     "net/http"
     "os"
     "path/filepath"
+    "strconv"
     "strings"
+    "time"
 
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/semcache"
+    "gollmcore/internal/services/audioclass"
     "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/speaker"
     "gollmcore/internal/services/stt"
+    "gollmcore/internal/sessions"
+    "gollmcore/internal/usage"
 )
 
 type Dependencies struct {
-    STT             *stt.STTService
-    STTDefaultModel string
-    Embeddings      embeddings.Service
-    TTS             TTSService
+    STT               *stt.STTService
+    STTDefaultModel   string
+    // STTPrompt is the whisper initial prompt used when a request gives
+    // none (see stt.BuildPrompt).
+    STTPrompt         string
+    // STTPreprocess is the audio clean-up applied when a request does not
+    // choose its own.
+    STTPreprocess     stt.Preprocess
+    // STTPostprocess cleans transcripts up with the LLM (see Postprocess).
+    STTPostprocess    Postprocess
+    // LongAudio splits long recordings for parallel transcription.
+    LongAudio         LongAudio
+    // Resumable enables /v1/uploads for transcribing large files sent in
+    // pieces.
+    Resumable         ResumableUploads
+    // AudioClassifier serves /v1/audio/classify; with STT it also names
+    // the spoken language.
+    AudioClassifier   audioclass.Service
+    // Speakers serves /v1/speakers voice enrollment and identification.
+    Speakers          *speaker.Service
+    Embeddings        embeddings.Service
+    TTS               TTSService
+    LLM               LLMService
+    Uploads           UploadPolicy
+    Pipelines         map[string]Pipeline
+    Sessions          *sessions.Store
+    Prompts           *prompts.Store
+    // WebSocket mirrors the options given to RegisterWSRoutes so that
+    // /v1/capabilities can describe the WS endpoints.
+    WebSocket         WSOptions
+    // APIKeys, when non-empty, are required on WS connections; HTTP routes
+    // are guarded by wrapping the mux with RequireAPIKey.
+    APIKeys           []string
+    // AdminKeys are API keys that may also use admin-only request options,
+    // such as skipping PolicyPrompt.
+    AdminKeys         []string
+    // PolicyPrompt is prepended server-side to every LLM conversation.
+    PolicyPrompt      string
+    // ChatCache, with Embeddings, answers repeated chat questions from
+    // earlier replies (see cachedChat).
+    ChatCache         *semcache.Cache
+    // Moderation configures /v1/moderations and optional screening of
+    // chat input and output.
+    Moderation        Moderation
+    // Vision routes chat requests with images to a multimodal model.
+    Vision            VisionRoute
+    // LoRA names llama-server adapters that requests select with
+    // model "base:adapter".
+    LoRA              map[string]LoRAAdapter
+    // LLMUpstreams are more LLM servers chats are routed to by model; see
+    // WithUpstreams.
+    LLMUpstreams      []LLMUpstream
+    // Agent holds the tools the server runs itself for "agent": true chats.
+    Agent             AgentOptions
+    // Search serves /v1/tools/search when it has a provider.
+    Search            WebSearch
+    // MCP serves the Model Context Protocol on /mcp and /mcp/sse.
+    MCP               bool
+    // ReadOnly locks sessions and speakers against changes.
+    ReadOnly          ReadOnly
+    // LLMDefaults fill in chat requests that omit a system prompt or
+    // sampling parameters.
+    LLMDefaults       LLMDefaults
+    // IdempotencyTTL is how long Idempotency-Key responses are replayable
+    // (default 10 minutes).
+    IdempotencyTTL    time.Duration
+    // MaxJSONBytes bounds JSON request bodies, which get 413 beyond it
+    // (default 32 MiB, negative disables).
+    MaxJSONBytes      int64
+    // Timeouts bound each STT, TTS, LLM and embeddings call.
+    Timeouts          Timeouts
+    // Caps bound the size of a single call (inputs, text, max_tokens,
+    // audio length).
+    Caps              Caps
+    // Limits cap concurrent calls per service; see WithLimits.
+    Limits            Limits
+    sttLimiter        *limiter
+    // VoiceSystemPrompt is prepended to voice chat conversations.
+    VoiceSystemPrompt string
+    // VoiceGreeting is synthesized once and sent when a /ws/voice session
+    // opens.
+    VoiceGreeting     string
+    // Usage, when set, records per-model call statistics (see WithUsage).
+    Usage             *usage.Recorder
+    // Resources, when its Monitor is set, refuses LLM models that do not
+    // fit in memory and downsizes whisper under pressure (see WithResources).
+    Resources         ResourceGuard
+    // Status adds model files, disk usage and an LLM probe to /v1/status.
+    Status            StatusOptions
+    // Events, when set, serves GET /v1/events (see Events).
+    Events            *Events
+    // IOLog, when set, logs redacted model inputs and outputs (see WithIOLog).
+    IOLog             *IOLog
+    // JobsDB is a bbolt file keeping /v1/jobs across restarts; unfinished
+    // upload transcriptions are resumed from it. "" keeps jobs in memory.
+    JobsDB            string
+    // Webhooks are notified when async jobs finish (see Webhooks).
+    Webhooks          Webhooks
 }
 
 func RegisterRoutes(mux *http.ServeMux, d Dependencies) {
-    mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+    var judge *moderator
+    if d.LLM != nil { judge = d.judge() }
+    d = d.withVision().withModeration().withPolicy().withTimeouts().withLoRA().withCaps().withLLMDefaults()
+    rt := newRouter(mux)
+    rt.jsonLimit = d.MaxJSONBytes
+    defer rt.done()
+    rt.handle("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
         w.WriteHeader(http.StatusOK)
         _, _ = w.Write([]byte("ok"))
     })
+    rt.handle("GET /metrics", handleMetrics)
+    rt.handle("GET /v1/capabilities", func(w http.ResponseWriter, r *http.Request) { handleCapabilities(w, r, d) })
+    rt.handle("GET /v1/status", func(w http.ResponseWriter, r *http.Request) { handleStatus(w, r, d) })
+    if d.Events != nil {
+        rt.handle("GET /v1/events", func(w http.ResponseWriter, r *http.Request) { handleEvents(w, r, d.Events) })
+    }
+    rt.handle("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) { handleOpenAPI(w, r, d) })
+    rt.handle("GET /docs", handleSwaggerUI)
+
+    idem := newIdempotencyStore(d.IdempotencyTTL)
+    gens := newGenerationStore()
+    transcribe := gens.wrap(idem.wrap(func(w http.ResponseWriter, r *http.Request) { handleSTTTranscribe(w, r, d) }))
+    transcribeStream := gens.wrap(idem.wrap(func(w http.ResponseWriter, r *http.Request) { handleSTTTranscribeStream(w, r, d) }))
+    tts := gens.wrap(idem.wrap(func(w http.ResponseWriter, r *http.Request) { handleTTS(w, r, d) }))
+    chat := gens.wrap(idem.wrap(func(w http.ResponseWriter, r *http.Request) { handleChatCompletions(w, r, d) }))
+    jobs := newJobStore(d.JobsDB)
+    jobs.notify = d.Webhooks.notifier()
+    var resume func(Job) bool
+    if d.STT != nil || d.TTS != nil || d.LLM != nil {
+        rt.handle("DELETE /v1/generations/{id}", func(w http.ResponseWriter, r *http.Request) { handleCancelGeneration(w, r, gens) })
+    }
 
     if d.STT != nil {
-        mux.HandleFunc("/v1/audio/transcriptions", func(w http.ResponseWriter, r *http.Request) {
-            if r.Method != http.MethodPost {
-                http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-                return
-            }
-            handleSTTTranscribe(w, r, d)
-        })
-        mux.HandleFunc("/v1/audio/transcriptions/stream", func(w http.ResponseWriter, r *http.Request) {
-            if r.Method != http.MethodPost {
-                http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-                return
-            }
-            handleSTTTranscribeStream(w, r, d)
-        })
+        rt.handle("POST /v1/audio/transcriptions", transcribe)
+        rt.handle("POST /v1/audio/transcriptions/stream", transcribeStream)
+    }
+
+    if d.AudioClassifier != nil {
+        rt.handle("POST /v1/audio/classify", func(w http.ResponseWriter, r *http.Request) { handleAudioClassify(w, r, d) })
+    }
+
+    if d.Speakers != nil {
+        with := func(h func(http.ResponseWriter, *http.Request, Dependencies)) http.HandlerFunc {
+            return func(w http.ResponseWriter, r *http.Request) { h(w, r, d) }
+        }
+        locked := func(h http.HandlerFunc) http.HandlerFunc { return readOnlyGuard(d.ReadOnly.Speakers, "speakers", h) }
+        rt.handle("POST /v1/speakers/enroll", locked(with(handleSpeakerEnroll)))
+        rt.handle("POST /v1/speakers/verify", with(handleSpeakerVerify))
+        rt.handle("POST /v1/speakers/identify", with(handleSpeakerIdentify))
+        rt.handle("GET /v1/speakers", with(handleListSpeakers))
+        rt.handle("GET /v1/speakers/{id}", with(handleGetSpeaker))
+        rt.handle("DELETE /v1/speakers/{id}", locked(with(handleDeleteSpeaker)))
     }
 
     if d.Embeddings != nil {
-        mux.HandleFunc("/v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
-            if r.Method != http.MethodPost {
-                http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-                return
-            }
-            handleEmbeddings(w, r, d)
-        })
+        rt.handle("POST /v1/embeddings", func(w http.ResponseWriter, r *http.Request) { handleEmbeddings(w, r, d) })
+        rt.handle("POST /v1/embeddings/document", func(w http.ResponseWriter, r *http.Request) { handleDocumentEmbeddings(w, r, d) })
+        rt.handle("POST /v1/similarity/matrix", func(w http.ResponseWriter, r *http.Request) { handleSimilarityMatrix(w, r, d) })
     }
 
     if d.TTS != nil {
-        mux.HandleFunc("/v1/tts", func(w http.ResponseWriter, r *http.Request) {
-            if r.Method != http.MethodPost { http.Error(w, "method not allowed", http.StatusMethodNotAllowed); return }
-            handleTTS(w, r, d)
-        })
+        rt.handle("POST /v1/tts", tts)
+    }
+
+    if d.LLM != nil {
+        rt.handle("GET /v1/models", func(w http.ResponseWriter, r *http.Request) { handleModels(w, d) })
+        rt.handle("POST /v1/chat/completions", func(w http.ResponseWriter, r *http.Request) { chat(w, policyOverride(r)) })
+        rt.handle("POST /v1/chat/completions/batch", func(w http.ResponseWriter, r *http.Request) { handleChatBatch(w, policyOverride(r), d, jobs) })
+        rt.handle("POST /v1/summarize", func(w http.ResponseWriter, r *http.Request) { handleSummarize(w, r, d) })
+        rt.handle("POST /v1/extract", func(w http.ResponseWriter, r *http.Request) { handleExtract(w, r, d) })
+        rt.handle("POST /v1/classify", func(w http.ResponseWriter, r *http.Request) { handleClassify(w, r, d) })
+        rt.handle("POST /v1/moderations", func(w http.ResponseWriter, r *http.Request) { handleModerations(w, r, judge) })
+        rt.handle("POST /v1/translate", func(w http.ResponseWriter, r *http.Request) { handleTranslate(w, r, d) })
+    }
+
+    if d.Search.Provider != nil {
+        rt.handle("POST /v1/tools/search", func(w http.ResponseWriter, r *http.Request) { handleSearch(w, r, d.Search) })
+    }
+
+    if d.MCP {
+        sse := newMCPSSE(d)
+        rt.handle("POST /mcp", func(w http.ResponseWriter, r *http.Request) { handleMCP(w, r, d) })
+        rt.handle("GET /mcp/sse", sse.stream)
+        rt.handle("POST /mcp/messages", sse.message)
+    }
+
+    if d.Sessions != nil {
+        with := func(h func(http.ResponseWriter, *http.Request, Dependencies)) http.HandlerFunc {
+            return readOnlyGuard(d.ReadOnly.Sessions, "sessions", func(w http.ResponseWriter, r *http.Request) { h(w, r, d) })
+        }
+        rt.handle("GET /v1/sessions", with(handleListSessions))
+        rt.handle("POST /v1/sessions", with(handleCreateSession))
+        rt.handle("GET /v1/sessions/{id}", with(handleGetSession))
+        rt.handle("DELETE /v1/sessions/{id}", with(handleDeleteSession))
+        rt.handle("POST /v1/sessions/{id}/messages", with(handleAppendSession))
+    }
+
+    if len(d.Pipelines) > 0 {
+        rt.handle("GET /v1/pipelines", func(w http.ResponseWriter, r *http.Request) { handleListPipelines(w, r, d) })
+        rt.handle("POST /v1/pipelines/{name}/run", func(w http.ResponseWriter, r *http.Request) { handleRunPipeline(w, r, d, jobs) })
+    }
+    if d.STT != nil && d.Resumable.Dir != "" {
+        if store, err := newUploadStore(d.Resumable); err != nil {
+            log.Printf("resumable uploads disabled: %v", err)
+        } else {
+            rt.handle("POST /v1/uploads", func(w http.ResponseWriter, r *http.Request) { createUpload(w, r, d, store) })
+            withUpload := func(h func(http.ResponseWriter, *http.Request, upload)) http.HandlerFunc {
+                return func(w http.ResponseWriter, r *http.Request) {
+                    id := r.PathValue("id")
+                    if !validUploadID(id) { writeError(w, "upload not found", http.StatusNotFound); return }
+                    unlock := store.lock(id)
+                    defer unlock()
+                    u, ok := store.load(id)
+                    if !ok { writeError(w, "upload not found", http.StatusNotFound); return }
+                    h(w, r, u)
+                }
+            }
+            rt.handle("GET /v1/uploads/{id}", withUpload(func(w http.ResponseWriter, r *http.Request, u upload) { writeUpload(w, http.StatusOK, u) }))
+            rt.handle("DELETE /v1/uploads/{id}", withUpload(func(w http.ResponseWriter, r *http.Request, u upload) { store.remove(u.ID); w.WriteHeader(http.StatusNoContent) }))
+            rt.handle("PATCH /v1/uploads/{id}", withUpload(func(w http.ResponseWriter, r *http.Request, u upload) { appendUpload(w, r, d, store, jobs, u) }))
+            resume = func(j Job) bool { return resumeUpload(d, store, jobs, j) }
+        }
+    }
+    if len(d.Pipelines) > 0 || (d.STT != nil && d.Resumable.Dir != "") || d.LLM != nil {
+        rt.handle("GET /v1/jobs/{id}", func(w http.ResponseWriter, r *http.Request) { handleGetJob(w, r, jobs) })
+        if d.JobsDB != "" { go jobs.recover(resume) }
+    }
+
+    if d.STT != nil && d.LLM != nil && d.TTS != nil {
+        clips := newClipStore()
+        rt.handle("POST /v1/voice/chat", gens.wrap(func(w http.ResponseWriter, r *http.Request) { handleVoiceChat(w, policyOverride(r), d, clips) }))
+        rt.handle("POST /v1/voice/translate", func(w http.ResponseWriter, r *http.Request) { handleVoiceTranslate(w, r, d, clips) })
+        rt.handle("GET /v1/voice/audio/{id}", func(w http.ResponseWriter, r *http.Request) { handleVoiceAudio(w, r, clips) })
     }
 }
 
@@ -75,22 +275,23 @@ func handleSTTTranscribe(w http.ResponseWriter, r *http.Request, d Dependencies)
         file, hdr, err = r.FormFile("audio")
     }
     if err != nil {
-        http.Error(w, "missing form file 'file' or 'audio'", http.StatusBadRequest)
+        writeParamError(w, "file", "missing form file 'file' or 'audio'")
         return
     }
     defer file.Close()
+    opts, param, err := sttFormRequest(r)
+    if err != nil { writeParamError(w, param, err.Error()); return }
+    if err := d.checkPostprocess(opts); err != nil { writeParamError(w, "postprocess", err.Error()); return }
 
-    tmpDir := os.TempDir()
-    tmpPath := filepath.Join(tmpDir, "stt-"+sanitizeName(hdr.Filename))
-    out, err := os.Create(tmpPath)
-    if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
-    defer func(){ out.Close(); os.Remove(tmpPath) }()
-    if _, err := io.Copy(out, file); err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
+    tmpPath, err := d.saveUpload(r.Context(), "stt", hdr.Filename, file)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    defer os.Remove(tmpPath)
 
-    text, err := d.STT.TranscribeFile(r.Context(), tmpPath, model)
-    if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
+    t, err := d.transcribe(r.Context(), tmpPath, model, opts)
+    if err == nil { err = d.postprocess(r.Context(), opts, &t) }
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
 
-    resp := map[string]any{"text": text, "model": model}
+    resp := t.response(model)
     w.Header().Set("Content-Type", "application/json")
     _ = json.NewEncoder(w).Encode(resp)
 }
@@ -102,18 +303,32 @@ func handleSTTTranscribeStream(w http.ResponseWriter, r *http.Request, d Depende
     reader, hdr, err := r.FormFile("file")
     if err != nil { reader, hdr, err = r.FormFile("audio") }
     if err != nil {
-        http.Error(w, "missing form file 'file' or 'audio'", http.StatusBadRequest)
+        writeParamError(w, "file", "missing form file 'file' or 'audio'")
         return
     }
     defer reader.Close()
+    opts, param, err := sttFormRequest(r)
+    if err != nil { writeParamError(w, param, err.Error()); return }
+    format := r.FormValue("event_format")
+    if format != "" && format != "text" && format != "json" { writeParamError(w, "event_format", "event_format must be text or json"); return }
+    // Raw lines mix transcript and whisper log output, so only the JSON
+    // events can be post-processed; a configured default is skipped.
+    if format != "json" {
+        if opts.Postprocess != nil && *opts.Postprocess { writeParamError(w, "postprocess", "postprocess on the stream needs event_format=json"); return }
+        no := false
+        opts.Postprocess = &no
+    }
+    if err := d.checkPostprocess(opts); err != nil { writeParamError(w, "postprocess", err.Error()); return }
 
-    tmpDir := os.TempDir()
-    tmpPath := filepath.Join(tmpDir, "stt-"+sanitizeName(hdr.Filename))
-    out, err := os.Create(tmpPath)
-    if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
-    if _, err := io.Copy(out, reader); err != nil { out.Close(); http.Error(w, err.Error(), http.StatusInternalServerError); return }
-    out.Close()
+    tmpPath, err := d.saveUpload(r.Context(), "stt", hdr.Filename, reader)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
     defer os.Remove(tmpPath)
+    if err := d.Caps.checkAudio(tmpPath); err != nil { writeServiceError(w, err, http.StatusBadRequest); return }
+    ctx, cancel := context.WithTimeout(r.Context(), d.Timeouts.STT)
+    defer cancel()
+    release, err := d.sttLimiter.acquire(ctx)
+    if err != nil { writeServiceError(w, deadlineErr(ctx, "stt", d.Timeouts.STT, err), http.StatusInternalServerError); return }
+    defer release()
 
     w.Header().Set("Content-Type", "text/event-stream")
     w.Header().Set("Cache-Control", "no-cache")
@@ -121,34 +336,133 @@ func handleSTTTranscribeStream(w http.ResponseWriter, r *http.Request, d Depende
 
     flusher, ok := w.(http.Flusher)
     if !ok {
-        http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+        writeError(w, "streaming unsupported", http.StatusInternalServerError)
         return
     }
 
-    linesCh, errCh := d.STT.TranscribeFileStream(r.Context(), tmpPath, model)
+    end := d.track(ctx, "stt", model)
+    sopts := d.sttOptions(opts)
+    sopts.Timestamps = format == "json"
+    linesCh, errCh := d.STT.TranscribeFileStream(ctx, tmpPath, d.fitWhisperModel(model), sopts)
     enc := func(s string) string { return strings.ReplaceAll(s, "\n", " ") }
+    var events sttEvents
     for {
         select {
         case line, ok := <-linesCh:
             if !ok {
-                fmt.Fprintf(w, "event: done\n")
-                fmt.Fprintf(w, "data: %s\n\n", "")
+                end(0, 0, nil)
+                if format == "json" {
+                    t := transcript{Text: events.text()}
+                    if err := d.postprocess(r.Context(), opts, &t); err != nil { writeSSEError(w, err); flusher.Flush(); return }
+                    if t.Raw != "" { events.write(w, "postprocessed", t.Text, events.t0, events.t1) }
+                    events.write(w, "done", events.text(), events.t0, events.t1)
+                } else {
+                    fmt.Fprintf(w, "event: done\n")
+                    fmt.Fprintf(w, "data: %s\n\n", "")
+                }
                 flusher.Flush()
                 return
             }
-            fmt.Fprintf(w, "data: %s\n\n", enc(line))
-            flusher.Flush()
-        case err := <-errCh:
-            if err != nil {
-                log.Printf("stream error: %v", err)
+            if format != "json" {
+                fmt.Fprintf(w, "data: %s\n\n", enc(line))
+            } else if seg, ok := stt.ParseTimedLine(line); ok {
+                events.add(w, seg)
+            } else {
+                continue // whisper log output
             }
+            flusher.Flush()
+        case err, ok := <-errCh:
+            // The service closes errCh without an error when whisper
+            // finishes; keep reading lines until linesCh closes.
+            if !ok { errCh = nil; continue }
+            log.Printf("stream error: %v", err)
+            err = deadlineErr(ctx, "stt", d.Timeouts.STT, err)
+            end(0, 0, err)
+            if errors.Is(err, context.DeadlineExceeded) { writeSSEError(w, err); flusher.Flush() }
             return
-        case <-r.Context().Done():
+        case <-ctx.Done():
+            err := deadlineErr(ctx, "stt", d.Timeouts.STT, ctx.Err())
+            end(0, 0, err)
+            if errors.Is(err, context.DeadlineExceeded) { writeSSEError(w, err); flusher.Flush() }
             return
         }
     }
 }
 
+// sttEvents builds the event_format=json stream: a "segment" event per
+// whisper segment, followed by a "partial" event carrying the transcript so
+// far, and a final "done" event with the whole text, preceded by a
+// "postprocessed" event when requested. Times are seconds.
+type sttEvents struct {
+    segs   []string
+    t0, t1 float64
+}
+
+type sttEvent struct {
+    Type string  `json:"type"`
+    Text string  `json:"text"`
+    T0   float64 `json:"t0"`
+    T1   float64 `json:"t1"`
+}
+
+func (e *sttEvents) add(w io.Writer, seg stt.Segment) {
+    if seg.Text == "" { return }
+    if len(e.segs) == 0 { e.t0 = seg.Start }
+    e.segs = append(e.segs, seg.Text)
+    e.t1 = seg.End
+    e.write(w, "segment", seg.Text, seg.Start, seg.End)
+    e.write(w, "partial", e.text(), e.t0, e.t1)
+}
+
+func (e *sttEvents) text() string { return strings.Join(e.segs, " ") }
+
+func (e *sttEvents) write(w io.Writer, typ, text string, t0, t1 float64) {
+    b, _ := json.Marshal(sttEvent{Type: typ, Text: text, T0: t0, T1: t1})
+    fmt.Fprintf(w, "data: %s\n\n", b)
+}
+
+// sttRequest holds the transcription options a request may set; unset
+// fields fall back to STTPrompt and STTPreprocess.
+type sttRequest struct {
+    Prompt      string `json:"prompt,omitempty"`
+    Normalize   *bool  `json:"normalize,omitempty"`
+    Denoise     *bool  `json:"denoise,omitempty"`
+    Postprocess *bool  `json:"postprocess,omitempty"`
+}
+
+// sttOptions resolves the whisper options of a request.
+func (d Dependencies) sttOptions(req sttRequest) stt.Options {
+    opts := stt.Options{Prompt: req.Prompt, Preprocess: d.STTPreprocess}
+    if opts.Prompt == "" { opts.Prompt = d.STTPrompt }
+    if req.Normalize != nil { opts.Preprocess.Normalize = *req.Normalize }
+    if req.Denoise != nil { opts.Preprocess.Denoise = *req.Denoise }
+    return opts
+}
+
+// check validates the request, naming the offending parameter.
+func (req sttRequest) check() (param string, err error) {
+    if err := stt.CheckPrompt(req.Prompt); err != nil { return "prompt", err }
+    return "", nil
+}
+
+// sttFormRequest reads the prompt, normalize and denoise fields of a
+// transcription upload.
+func sttFormRequest(r *http.Request) (req sttRequest, param string, err error) {
+    req.Prompt = r.FormValue("prompt")
+    for _, f := range []struct {
+        name string
+        dst  **bool
+    }{{"normalize", &req.Normalize}, {"denoise", &req.Denoise}, {"postprocess", &req.Postprocess}} {
+        v := r.FormValue(f.name)
+        if v == "" { continue }
+        b, err := strconv.ParseBool(v)
+        if err != nil { return req, f.name, fmt.Errorf("%s must be true or false", f.name) }
+        *f.dst = &b
+    }
+    param, err = req.check()
+    return req, param, err
+}
+
 func sanitizeName(name string) string {
     name = filepath.Base(name)
     name = strings.ReplaceAll(name, " ", "-")
@@ -158,20 +472,35 @@ func sanitizeName(name string) string {
 // -------- Embeddings Handler --------
 
 type embeddingsRequest struct {
-    Input any `json:"input"` // string or []string
+    Input     any  `json:"input"` // string or []string
+    // Stream sends the vectors as server-sent events, one per sub-batch of
+    // BatchSize inputs (default 64), instead of one response at the end.
+    Stream     bool `json:"stream,omitempty"`
+    BatchSize  int  `json:"batch_size,omitempty"`
+    // Dimensions truncates each vector to its first n components and
+    // re-normalizes it (Matryoshka-style, like OpenAI's dimensions).
+    Dimensions int  `json:"dimensions,omitempty"`
+    // Pooling (mean, cls or max) and Normalize override the model's
+    // configuration; normalize false returns unnormalized vectors.
+    Pooling    string `json:"pooling,omitempty"`
+    Normalize  *bool  `json:"normalize,omitempty"`
+}
+
+// options checks the pooling fields of req.
+func (req embeddingsRequest) options() (embeddings.Options, error) {
+    p, err := embeddings.ParsePooling(req.Pooling)
+    return embeddings.Options{Pooling: p, Normalize: req.Normalize}, err
 }
 
 type embeddingsResponse struct {
-    Model      string        `json:"model"`
-    Embeddings [][]float32   `json:"embeddings"`
+    Model      string          `json:"model"`
+    Embeddings [][]float32     `json:"embeddings"`
+    Usage      embeddingsUsage `json:"usage"`
 }
 
 func handleEmbeddings(w http.ResponseWriter, r *http.Request, d Dependencies) {
     var req embeddingsRequest
-    if err := json.NewDecoder(bufio.NewReader(r.Body)).Decode(&req); err != nil {
-        http.Error(w, "invalid json", http.StatusBadRequest)
-        return
-    }
+    if !decodeCompatJSON(w, r, &req) { return }
     var inputs []string
     switch v := req.Input.(type) {
     case string:
@@ -181,35 +510,124 @@ func handleEmbeddings(w http.ResponseWriter, r *http.Request, d Dependencies) {
             if s, ok := it.(string); ok { inputs = append(inputs, s) }
         }
     default:
-        http.Error(w, "input must be string or array of strings", http.StatusBadRequest)
+        writeParamError(w, "input", "input must be string or array of strings")
         return
     }
     if len(inputs) == 0 {
-        http.Error(w, "no input provided", http.StatusBadRequest)
+        writeError(w, "no input provided", http.StatusBadRequest)
         return
     }
+    if req.Dimensions < 0 { writeParamError(w, "dimensions", "dimensions must be positive"); return }
+    opts, err := req.options()
+    if err != nil { writeParamError(w, "pooling", err.Error()); return }
+    r = r.WithContext(embeddings.WithOptions(r.Context(), opts))
+    // Streams embed in sub-batches, so the service never sees the total.
+    if err := d.Caps.checkInputs(len(inputs)); err != nil { writeServiceError(w, err, http.StatusBadRequest); return }
+    if req.Stream { streamEmbeddings(w, r, d, inputs, req.BatchSize, req.Dimensions); return }
     vecs, model, err := d.Embeddings.Embed(r.Context(), inputs)
+    if err == nil { vecs, err = truncateVectors(vecs, req.Dimensions) }
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeServiceError(w, err, http.StatusInternalServerError)
         return
     }
     w.Header().Set("Content-Type", "application/json")
-    _ = json.NewEncoder(w).Encode(embeddingsResponse{Model: model, Embeddings: vecs})
+    _ = json.NewEncoder(w).Encode(embeddingsResponse{Model: model, Embeddings: vecs, Usage: d.chargeEmbeddings(r, inputs)})
+}
+
+var errInvalidDimensions = errors.New("invalid dimensions")
+
+// truncateVectors returns vecs cut to dims components, each scaled back
+// to unit length if it was unit length (normalize was not turned off).
+// dims 0 returns vecs as they are. The result is always freshly allocated,
+// since vecs may be shared with other callers (see coalesce.go).
+func truncateVectors(vecs [][]float32, dims int) ([][]float32, error) {
+    if dims == 0 { return vecs, nil }
+    if dims < 0 { return nil, fmt.Errorf("%w: must be positive", errInvalidDimensions) }
+    out := make([][]float32, len(vecs))
+    for i, v := range vecs {
+        if dims > len(v) { return nil, fmt.Errorf("%w: %d requested but the model produces %d", errInvalidDimensions, dims, len(v)) }
+        var full, n float64
+        for _, x := range v { full += float64(x) * float64(x) }
+        for _, x := range v[:dims] { n += float64(x) * float64(x) }
+        inv := float32(1)
+        if n > 0 && math.Abs(full-1) < 1e-3 { inv = float32(1 / math.Sqrt(n)) }
+        t := make([]float32, dims)
+        for k := range t { t[k] = v[k] * inv }
+        out[i] = t
+    }
+    return out, nil
+}
+
+// embeddingsBatch is one streamed sub-batch: Embeddings[k] belongs to
+// input Index+k.
+type embeddingsBatch struct {
+    Model      string      `json:"model"`
+    Index      int         `json:"index"`
+    Embeddings [][]float32 `json:"embeddings"`
+}
+
+const defaultEmbeddingsBatch = 64
+
+// embedBatches embeds inputs in sub-batches of size, calling emit after
+// each one, and returns the model name.
+func (d Dependencies) embedBatches(ctx context.Context, inputs []string, size int, emit func(embeddingsBatch) error) (string, error) {
+    if size <= 0 { size = defaultEmbeddingsBatch }
+    model := ""
+    for i := 0; i < len(inputs); i += size {
+        j := i + size
+        if j > len(inputs) { j = len(inputs) }
+        vecs, m, err := d.Embeddings.Embed(ctx, inputs[i:j])
+        if err != nil { return model, err }
+        model = m
+        if err := emit(embeddingsBatch{Model: m, Index: i, Embeddings: vecs}); err != nil { return model, err }
+    }
+    return model, nil
+}
+
+// streamEmbeddings answers with one SSE data event per sub-batch and a
+// final "done" event carrying the model, input count and usage.
+func streamEmbeddings(w http.ResponseWriter, r *http.Request, d Dependencies, inputs []string, size, dims int) {
+    flusher, ok := w.(http.Flusher)
+    if !ok { writeError(w, "streaming unsupported", http.StatusInternalServerError); return }
+    started := false
+    model, err := d.embedBatches(r.Context(), inputs, size, func(b embeddingsBatch) error {
+        var err error
+        if b.Embeddings, err = truncateVectors(b.Embeddings, dims); err != nil { return err }
+        if !started {
+            w.Header().Set("Content-Type", "text/event-stream")
+            w.Header().Set("Cache-Control", "no-cache")
+            started = true
+        }
+        data, err := json.Marshal(b)
+        if err != nil { return err }
+        fmt.Fprintf(w, "data: %s\n\n", data)
+        flusher.Flush()
+        return nil
+    })
+    if err != nil {
+        if !started { writeServiceError(w, err, http.StatusInternalServerError); return }
+        writeSSEError(w, err)
+        flusher.Flush()
+        return
+    }
+    data, _ := json.Marshal(map[string]any{"model": model, "count": len(inputs), "usage": d.chargeEmbeddings(r, inputs)})
+    fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+    flusher.Flush()
 }
 
 // -------- TTS Handler --------
 
 type ttsRequest struct {
-    Text  string `json:"text"`
+    Text  string `json:"text" validate:"required"`
     Voice string `json:"voice"`
 }
 
 func handleTTS(w http.ResponseWriter, r *http.Request, d Dependencies) {
     var req ttsRequest
-    if err := json.NewDecoder(bufio.NewReader(r.Body)).Decode(&req); err != nil { http.Error(w, "invalid json", http.StatusBadRequest); return }
-    if req.Text == "" { http.Error(w, "missing text", http.StatusBadRequest); return }
+    if !decodeJSON(w, r, &req) { return }
+    if req.Text == "" { writeParamError(w, "text", "missing text"); return }
     audio, err := d.TTS.Synthesize(r.Context(), req.Text, req.Voice)
-    if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
     w.Header().Set("Content-Type", "audio/wav")
     w.Header().Set("Content-Disposition", "inline; filename=tts.wav")
     w.WriteHeader(http.StatusOK)
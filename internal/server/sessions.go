@@ -0,0 +1,56 @@
+package server
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Sessions --------
+
+func handleListSessions(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    list, err := d.sessionStore(r.Context()).List()
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    writeJSON(w, http.StatusOK, map[string]any{"sessions": list})
+}
+
+func handleCreateSession(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req struct {
+        Metadata map[string]string `json:"metadata"`
+        Messages []llm.Message     `json:"messages"`
+    }
+    if r.ContentLength != 0 {
+        if !decodeCompatJSON(w, r, &req) { return }
+    }
+    sess, err := d.sessionStore(r.Context()).Create(req.Metadata, req.Messages)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    w.Header().Set("Location", "/v1/sessions/"+sess.ID)
+    writeJSON(w, http.StatusCreated, sess)
+}
+
+func handleGetSession(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    sess, err := d.sessionStore(r.Context()).Get(r.PathValue("id"))
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    writeJSON(w, http.StatusOK, sess)
+}
+
+func handleDeleteSession(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    if err := d.sessionStore(r.Context()).Delete(r.PathValue("id")); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func handleAppendSession(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req struct{ Messages []llm.Message `json:"messages" validate:"required"` }
+    if !decodeCompatJSON(w, r, &req) { return }
+    if len(req.Messages) == 0 { writeParamError(w, "messages", "messages must not be empty"); return }
+    sess, err := d.sessionStore(r.Context()).Append(r.PathValue("id"), req.Messages...)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    writeJSON(w, http.StatusOK, sess)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    _ = json.NewEncoder(w).Encode(v)
+}
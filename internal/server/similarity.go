@@ -0,0 +1,62 @@
+package server
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "net/http"
+)
+
+// maxSimilarityInputs caps the matrix at N*N cells to keep requests bounded.
+const maxSimilarityInputs = 256
+
+// -------- Similarity Matrix Handler --------
+
+type similarityMatrixResponse struct {
+    Model  string      `json:"model"`
+    Matrix [][]float32 `json:"matrix"`
+}
+
+func handleSimilarityMatrix(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req embeddingsRequest
+    if !decodeCompatJSON(w, r, &req) { return }
+    inputs := coerceInputsWS(req.Input)
+    if len(inputs) == 0 {
+        writeParamError(w, "input", "input must be a non-empty array of strings")
+        return
+    }
+    if len(inputs) > maxSimilarityInputs {
+        writeError(w, fmt.Sprintf("too many inputs: %d (max %d)", len(inputs), maxSimilarityInputs), http.StatusBadRequest)
+        return
+    }
+    vecs, model, err := d.Embeddings.Embed(r.Context(), inputs)
+    if err == nil { vecs, err = truncateVectors(vecs, req.Dimensions) }
+    if err != nil {
+        writeServiceError(w, err, http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(similarityMatrixResponse{Model: model, Matrix: cosineMatrix(vecs)})
+}
+
+// cosineMatrix returns the symmetric pairwise cosine similarity of vecs.
+func cosineMatrix(vecs [][]float32) [][]float32 {
+    norms := make([]float64, len(vecs))
+    for i, v := range vecs {
+        var n float64
+        for _, x := range v { n += float64(x) * float64(x) }
+        norms[i] = math.Sqrt(n)
+    }
+    m := make([][]float32, len(vecs))
+    for i := range m { m[i] = make([]float32, len(vecs)) }
+    for i := range vecs {
+        for j := i; j < len(vecs); j++ {
+            var dot float64
+            for k := range vecs[i] { dot += float64(vecs[i][k]) * float64(vecs[j][k]) }
+            var sim float32
+            if norms[i] > 0 && norms[j] > 0 { sim = float32(dot / (norms[i] * norms[j])) }
+            m[i][j], m[j][i] = sim, sim
+        }
+    }
+    return m
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+    "errors"
+    "net/http"
+    "os"
+
+    "gollmcore/internal/services/speaker"
+    "gollmcore/internal/services/stt"
+)
+
+// -------- Speakers --------
+//
+//   POST   /v1/speakers/enroll    file, speaker_id[, name] -> speaker
+//   POST   /v1/speakers/verify    file, speaker_id         -> match
+//   POST   /v1/speakers/identify  file                     -> best matches
+//   GET    /v1/speakers[/{id}]
+//   DELETE /v1/speakers/{id}
+//
+// Embeddings are biometric data, so they stay on the server: responses
+// carry speaker metadata and similarity scores only.
+
+// maxIdentifyCandidates caps the matches /v1/speakers/identify returns.
+const maxIdentifyCandidates = 5
+
+var reservedSpeakerIDs = map[string]bool{"enroll": true, "verify": true, "identify": true}
+
+func speakerInfo(sp speaker.Speaker) speaker.Speaker { sp.Embedding = nil; return sp }
+
+// speakerAudio reads the uploaded recording; ok is false once an error
+// response has been written.
+func speakerAudio(w http.ResponseWriter, r *http.Request, d Dependencies) ([]byte, bool) {
+    path, cleanup, ok := formAudio(w, r, d, "speaker")
+    if !ok { return nil, false }
+    defer cleanup()
+    wav, err := os.ReadFile(path)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return nil, false }
+    return wav, true
+}
+
+// writeSpeakerError reports err, naming the file for audio problems.
+func writeSpeakerError(w http.ResponseWriter, err error) {
+    if errors.Is(err, stt.ErrNotPCMWAV) { writeParamError(w, "file", err.Error()); return }
+    writeServiceError(w, err, http.StatusInternalServerError)
+}
+
+func handleSpeakerEnroll(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    id := r.FormValue("speaker_id")
+    if !speaker.ValidID(id) || reservedSpeakerIDs[id] {
+        writeParamError(w, "speaker_id", "speaker_id must be 1-64 letters, digits, '.', '_' or '-' (and not enroll, verify or identify)")
+        return
+    }
+    wav, ok := speakerAudio(w, r, d)
+    if !ok { return }
+    sp, err := d.Speakers.Enroll(r.Context(), id, r.FormValue("name"), wav)
+    if err != nil { writeSpeakerError(w, err); return }
+    writeJSON(w, http.StatusOK, map[string]any{"speaker": speakerInfo(sp)})
+}
+
+func handleSpeakerVerify(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    id := r.FormValue("speaker_id")
+    if id == "" { writeParamError(w, "speaker_id", "speaker_id is required"); return }
+    if _, err := d.Speakers.Get(id); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    wav, ok := speakerAudio(w, r, d)
+    if !ok { return }
+    m, match, err := d.Speakers.Verify(r.Context(), id, wav)
+    if err != nil { writeSpeakerError(w, err); return }
+    writeJSON(w, http.StatusOK, map[string]any{"speaker_id": m.ID, "score": m.Score, "match": match, "threshold": d.Speakers.Threshold()})
+}
+
+func handleSpeakerIdentify(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    wav, ok := speakerAudio(w, r, d)
+    if !ok { return }
+    matches, err := d.Speakers.Identify(r.Context(), wav)
+    if err != nil { writeSpeakerError(w, err); return }
+    if len(matches) > maxIdentifyCandidates { matches = matches[:maxIdentifyCandidates] }
+    var best *speaker.Match
+    if len(matches) > 0 && matches[0].Score >= d.Speakers.Threshold() { best = &matches[0] }
+    writeJSON(w, http.StatusOK, map[string]any{"speaker": best, "candidates": matches, "threshold": d.Speakers.Threshold()})
+}
+
+func handleListSpeakers(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    list := d.Speakers.List()
+    for i := range list { list[i] = speakerInfo(list[i]) }
+    writeJSON(w, http.StatusOK, map[string]any{"speakers": list})
+}
+
+func handleGetSpeaker(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    sp, err := d.Speakers.Get(r.PathValue("id"))
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    writeJSON(w, http.StatusOK, map[string]any{"speaker": speakerInfo(sp)})
+}
+
+func handleDeleteSpeaker(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    if err := d.Speakers.Delete(r.PathValue("id")); err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    w.WriteHeader(http.StatusNoContent)
+}
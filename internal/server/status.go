@@ -0,0 +1,143 @@
+package server
+
+import (
+    "context"
+    "io/fs"
+    "net/http"
+    "os"
+    "path/filepath"
+    "runtime"
+    "time"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/resources"
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Status --------
+//
+// /healthz only says the process is alive. /v1/status says what it is
+// serving: the state and model of every service, the model files on disk
+// with their versions and sizes, and how full the data directory's disk is,
+// so a dashboard or an operator can tell a degraded instance from a healthy
+// one without reading logs.
+
+// StatusOptions adds installation details to /v1/status.
+type StatusOptions struct {
+    // DataDir is reported with its size and the free space on its disk.
+    DataDir         string
+    // Lock lists the downloaded model files (models.lock).
+    Lock            *models.Lock
+    // LLM is probed when it can be (llm.Service has Ping); pass the
+    // unwrapped service.
+    LLM             LLMService
+    // EmbeddingsModel, TTSEngine and TTSVoice name what those services run.
+    EmbeddingsModel string
+    // EmbeddingsNote, when set, is shown with the embeddings model, e.g.
+    // what its quantized variant gives up.
+    EmbeddingsNote  string
+    TTSEngine       string
+    TTSVoice        string
+}
+
+type serviceStatus struct {
+    // State is "ready", "disabled" or, for the LLM, "unreachable".
+    State  string `json:"state"`
+    Model  string `json:"model,omitempty"`
+    Engine string `json:"engine,omitempty"`
+    Voice  string `json:"voice,omitempty"`
+    Note   string `json:"note,omitempty"`
+    Error  string `json:"error,omitempty"`
+    // Failover lists the LLM's failover chain and which servers it skips.
+    Failover []llm.CircuitState `json:"failover,omitempty"`
+}
+
+type modelStatus struct {
+    Path      string    `json:"path"`
+    Version   string    `json:"version,omitempty"`
+    Size      int64     `json:"size"`
+    SHA256    string    `json:"sha256"`
+    FetchedAt time.Time `json:"fetched_at"`
+    // Present is false when the file was recorded but has since been removed.
+    Present   bool      `json:"present"`
+}
+
+type diskStatus struct {
+    DataDir      string `json:"data_dir"`
+    DataDirBytes int64  `json:"data_dir_bytes"`
+    TotalBytes   uint64 `json:"total_bytes,omitempty"`
+    FreeBytes    uint64 `json:"free_bytes,omitempty"`
+}
+
+type statusResponse struct {
+    // Status is "ok", or "degraded" when an enabled service is not ready.
+    Status        string                   `json:"status"`
+    UptimeSeconds int64                    `json:"uptime_seconds"`
+    StartedAt     time.Time                `json:"started_at"`
+    GoVersion     string                   `json:"go_version"`
+    Services      map[string]serviceStatus `json:"services"`
+    Models        []modelStatus            `json:"models"`
+    Disk          *diskStatus              `json:"disk,omitempty"`
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    o := d.Status
+    resp := statusResponse{Status: "ok", UptimeSeconds: int64(time.Since(processStart).Seconds()), StartedAt: processStart.UTC(), GoVersion: runtime.Version(), Services: map[string]serviceStatus{}, Models: []modelStatus{}}
+    state := func(on bool) serviceStatus {
+        if on { return serviceStatus{State: "ready"} }
+        return serviceStatus{State: "disabled"}
+    }
+
+    stt := state(d.STT != nil)
+    if d.STT != nil { stt.Model = d.STTDefaultModel }
+    resp.Services["stt"] = stt
+    emb := state(d.Embeddings != nil)
+    if d.Embeddings != nil { emb.Model, emb.Note = o.EmbeddingsModel, o.EmbeddingsNote }
+    resp.Services["embeddings"] = emb
+    tts := state(d.TTS != nil)
+    if d.TTS != nil { tts.Engine, tts.Voice = o.TTSEngine, o.TTSVoice }
+    resp.Services["tts"] = tts
+    resp.Services["llm"] = d.llmStatus(r.Context())
+    resp.Services["audio_classify"] = state(d.AudioClassifier != nil)
+    resp.Services["speakers"] = state(d.Speakers != nil)
+    resp.Services["sessions"] = state(d.Sessions != nil)
+    resp.Services["web_search"] = state(d.Search.Provider != nil)
+    for _, s := range resp.Services { if s.State != "ready" && s.State != "disabled" { resp.Status = "degraded" } }
+
+    if o.Lock != nil {
+        for _, a := range o.Lock.Artifacts() {
+            _, err := os.Stat(o.Lock.LocalPath(a))
+            resp.Models = append(resp.Models, modelStatus{Path: a.Path, Version: a.Version, Size: a.Size, SHA256: a.SHA256, FetchedAt: a.FetchedAt, Present: err == nil})
+        }
+    }
+    if o.DataDir != "" {
+        disk := &diskStatus{DataDir: o.DataDir, DataDirBytes: dirSize(o.DataDir)}
+        disk.TotalBytes, disk.FreeBytes, _ = resources.Disk(o.DataDir)
+        resp.Disk = disk
+    }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// llmStatus probes the upstream when StatusOptions.LLM can be pinged.
+func (d Dependencies) llmStatus(ctx context.Context) serviceStatus {
+    if d.LLM == nil { return serviceStatus{State: "disabled"} }
+    s := serviceStatus{State: "ready"}
+    if m, ok := d.LLM.(interface{ Model() string }); ok { s.Model = m.Model() }
+    if p, ok := d.Status.LLM.(interface{ Ping(context.Context) error }); ok {
+        if err := p.Ping(ctx); err != nil { s.State, s.Error = "unreachable", err.Error() }
+    }
+    if c, ok := d.Status.LLM.(interface{ Circuits() []llm.CircuitState }); ok { s.Failover = c.Circuits() }
+    return s
+}
+
+// dirSize adds up the sizes of the regular files under dir, skipping what
+// cannot be read.
+func dirSize(dir string) int64 {
+    var n int64
+    _ = filepath.WalkDir(dir, func(_ string, e fs.DirEntry, err error) error {
+        if err != nil || !e.Type().IsRegular() { return nil }
+        if info, err := e.Info(); err == nil { n += info.Size() }
+        return nil
+    })
+    return n
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "regexp"
+
+    "gollmcore/internal/sessions"
+)
+
+// -------- Tenants --------
+//
+// Several applications can share one server without seeing each other's
+// data. A request belongs to the tenant its API key is mapped to or,
+// behind a gateway that sets it, the one named by a trusted header. Each
+// tenant keeps its sessions and audit log in tenants/<name>/ beside the
+// shared files, and semantic cache hits and idempotent replays stay within
+// it. Requests without a tenant use the shared data as before.
+
+// TenantOptions says how requests are assigned to tenants.
+type TenantOptions struct {
+    Keys   map[string]string // API key -> tenant name
+    // Header names the tenant of requests whose key is not in Keys, e.g.
+    // X-Tenant-ID. Set it only behind a proxy that overwrites the header.
+    Header string
+}
+
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// CheckTenantName reports whether name can name a tenant (and its
+// directory): up to 64 letters, digits, '_', '.' or '-', starting with a
+// letter or digit.
+func CheckTenantName(name string) error {
+    if !tenantName.MatchString(name) { return fmt.Errorf("invalid tenant name %q: use up to 64 letters, digits, '_', '.' or '-', starting with a letter or digit", name) }
+    return nil
+}
+
+type tenantCtxKey struct{}
+
+// tenantOf returns the tenant of the request in ctx, or "" for none.
+func tenantOf(ctx context.Context) string {
+    t, _ := ctx.Value(tenantCtxKey{}).(string)
+    return t
+}
+
+// Tenants assigns each request to its tenant. A key's mapping wins over
+// the header, so a mapped client cannot claim another tenant. Place it
+// outside Audit so entries are written to the tenant's log; with no keys
+// and no header it returns next unchanged.
+func Tenants(next http.Handler, o TenantOptions) http.Handler {
+    if len(o.Keys) == 0 && o.Header == "" { return next }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        key := requestAPIKey(r)
+        if key == "" { key = r.URL.Query().Get("token") }
+        // Keys are compared in constant time, as in RequireAPIKey.
+        var tenant string
+        for k, t := range o.Keys { if validAPIKey(key, []string{k}) { tenant = t } }
+        if tenant == "" && o.Header != "" {
+            if tenant = r.Header.Get(o.Header); tenant != "" {
+                if err := CheckTenantName(tenant); err != nil { writeError(w, err.Error(), http.StatusBadRequest); return }
+            }
+        }
+        if tenant == "" { next.ServeHTTP(w, r); return }
+        next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantCtxKey{}, tenant)))
+    })
+}
+
+// sessionStore is the session store of the request's tenant.
+func (d Dependencies) sessionStore(ctx context.Context) *sessions.Store {
+    return d.Sessions.Tenant(tenantOf(ctx))
+}
@@ -10,7 +10,10 @@ import (
 //go:embed web/test/*
 var testFS embed.FS
 
-// RegisterTestUI mounts the simple test frontend at /test/.
+// RegisterTestUI mounts the simple test frontend at /test/, and at
+// /test/recorder a standalone recorder page for embedding in an iframe: a
+// reference client that streams the microphone to /ws/stt as binary frames
+// and plays the transcript back through /ws/tts.
 func RegisterTestUI(mux *http.ServeMux) {
     sub, err := fs.Sub(testFS, "web/test")
     if err != nil {
@@ -18,4 +21,5 @@ func RegisterTestUI(mux *http.ServeMux) {
         sub = testFS
     }
     mux.Handle("/test/", http.StripPrefix("/test/", http.FileServer(http.FS(sub))))
+    mux.HandleFunc("/test/recorder", func(w http.ResponseWriter, r *http.Request) { http.ServeFileFS(w, r, sub, "recorder.html") })
 }
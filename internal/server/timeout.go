@@ -0,0 +1,116 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Per-service timeouts --------
+
+// Timeouts bound a single call to each service. Zero uses the default.
+type Timeouts struct {
+    STT        time.Duration
+    TTS        time.Duration
+    LLM        time.Duration
+    Embeddings time.Duration
+}
+
+const (
+    defaultSTTTimeout        = 10 * time.Minute
+    defaultTTSTimeout        = 2 * time.Minute
+    defaultLLMTimeout        = 5 * time.Minute
+    defaultEmbeddingsTimeout = 2 * time.Minute
+)
+
+func (t Timeouts) withDefaults() Timeouts {
+    if t.STT <= 0 { t.STT = defaultSTTTimeout }
+    if t.TTS <= 0 { t.TTS = defaultTTSTimeout }
+    if t.LLM <= 0 { t.LLM = defaultLLMTimeout }
+    if t.Embeddings <= 0 { t.Embeddings = defaultEmbeddingsTimeout }
+    return t
+}
+
+// withDeadline runs fn under a timeout and reports an expired deadline as
+// context.DeadlineExceeded even if the service returned its own error
+// (e.g. a killed child process), so handlers answer 504.
+func withDeadline(ctx context.Context, service string, d time.Duration, fn func(context.Context) error) error {
+    ctx, cancel := context.WithTimeout(ctx, d)
+    defer cancel()
+    return deadlineErr(ctx, service, d, fn(ctx))
+}
+
+// deadlineErr replaces err with a timeout error once ctx's deadline passed.
+func deadlineErr(ctx context.Context, service string, d time.Duration, err error) error {
+    if ctx.Err() == context.DeadlineExceeded {
+        return fmt.Errorf("%w: %s did not finish within %s", context.DeadlineExceeded, service, d)
+    }
+    return err
+}
+
+// withTimeouts wraps the LLM, TTS and embeddings services with d.Timeouts.
+// STT is bounded in transcribeFile and the streaming handlers.
+func (d Dependencies) withTimeouts() Dependencies {
+    d.Timeouts = d.Timeouts.withDefaults()
+    if d.LLM != nil { d.LLM = &timeoutLLM{next: d.LLM, d: d.Timeouts.LLM} }
+    if d.TTS != nil { d.TTS = &timeoutTTS{next: d.TTS, d: d.Timeouts.TTS} }
+    if d.Embeddings != nil { d.Embeddings = &timeoutEmbeddings{next: d.Embeddings, d: d.Timeouts.Embeddings} }
+    return d
+}
+
+type timeoutLLM struct {
+    next LLMService
+    d    time.Duration
+}
+
+func (t *timeoutLLM) Chat(ctx context.Context, req llm.ChatRequest) (resp *llm.ChatResponse, err error) {
+    err = withDeadline(ctx, "llm", t.d, func(ctx context.Context) error {
+        resp, err = t.next.Chat(ctx, req)
+        return err
+    })
+    return resp, err
+}
+
+func (t *timeoutLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (resp *llm.ChatResponse, err error) {
+    err = withDeadline(ctx, "llm", t.d, func(ctx context.Context) error {
+        resp, err = t.next.ChatStream(ctx, req, onChunk)
+        return err
+    })
+    return resp, err
+}
+
+func (t *timeoutLLM) Model() string {
+    if m, ok := t.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type timeoutTTS struct {
+    next TTSService
+    d    time.Duration
+}
+
+func (t *timeoutTTS) Synthesize(ctx context.Context, text, voice string) (audio []byte, err error) {
+    err = withDeadline(ctx, "tts", t.d, func(ctx context.Context) error {
+        audio, err = t.next.Synthesize(ctx, text, voice)
+        return err
+    })
+    return audio, err
+}
+
+type timeoutEmbeddings struct {
+    next embeddings.Service
+    d    time.Duration
+}
+
+func (t *timeoutEmbeddings) Embed(ctx context.Context, inputs []string) (vecs [][]float32, model string, err error) {
+    err = withDeadline(ctx, "embeddings", t.d, func(ctx context.Context) error {
+        vecs, model, err = t.next.Embed(ctx, inputs)
+        return err
+    })
+    return vecs, model, err
+}
+
+func (t *timeoutEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(t.next, inputs) }
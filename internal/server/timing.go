@@ -0,0 +1,74 @@
+package server
+
+import (
+    "bufio"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "gollmcore/internal/tracing"
+)
+
+// -------- Timing headers --------
+//
+// Timing tells clients where a request's time went without a profiler or
+// a trace collector. Every response carries X-Processing-Time (time in the
+// server until the response started), X-Queue-Time (time waiting for a
+// service slot) and one X-<Stage>-Time header per stage the services ran,
+// e.g. X-Tokenize-Time, X-Inference-Time and X-Decode-Time; all are in
+// milliseconds. Headers go out with the first byte of the response, so a
+// streamed response reports only the stages finished by then. The audit
+// log records the same breakdown when the request ends.
+
+// Timing records the timings of each request (see tracing.Timings) and
+// writes them as response headers. Place it outside Audit so the audit
+// entry can include them.
+func Timing(h http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx, t := tracing.WithTimings(r.Context())
+        h.ServeHTTP(&timingWriter{ResponseWriter: w, t: t}, r.WithContext(ctx))
+    })
+}
+
+// millis formats d as milliseconds with one decimal.
+func millis(d time.Duration) float64 { return float64(d.Round(100*time.Microsecond)) / float64(time.Millisecond) }
+
+// stageHeader is the header of a stage: "detect_language" ->
+// "X-Detect-Language-Time".
+func stageHeader(stage string) string {
+    return http.CanonicalHeaderKey("X-" + strings.ReplaceAll(stage, "_", "-") + "-Time")
+}
+
+type timingWriter struct {
+    http.ResponseWriter
+    t       *tracing.Timings
+    written bool
+}
+
+func (tw *timingWriter) setHeaders() {
+    if tw.written { return }
+    tw.written = true
+    hdr := tw.Header()
+    format := func(d time.Duration) string { return strconv.FormatFloat(millis(d), 'f', 1, 64) }
+    hdr.Set("X-Processing-Time", format(tw.t.Elapsed()))
+    hdr.Set("X-Queue-Time", format(tw.t.Queue()))
+    for _, s := range tw.t.Stages() { hdr.Set(stageHeader(s.Name), format(s.Duration)) }
+}
+
+func (tw *timingWriter) WriteHeader(code int) { tw.setHeaders(); tw.ResponseWriter.WriteHeader(code) }
+
+func (tw *timingWriter) Write(b []byte) (int, error) { tw.setHeaders(); return tw.ResponseWriter.Write(b) }
+
+func (tw *timingWriter) Unwrap() http.ResponseWriter { return tw.ResponseWriter }
+
+func (tw *timingWriter) Flush() {
+    tw.setHeaders()
+    if f, ok := tw.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
+
+func (tw *timingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    tw.written = true
+    return http.NewResponseController(tw.ResponseWriter).Hijack()
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// -------- Tools --------
+//
+// Server-side tools the model may call in agent mode (see agent.go): a few
+// built-ins and webhooks named in config. A tool gets the arguments the
+// model produced (a JSON object) and returns text for the model to read.
+
+// Tool is a function the server runs for the model.
+type Tool struct {
+    Description string
+    Parameters  json.RawMessage // JSON Schema of the arguments object
+    Call        func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// maxToolOutput bounds what one call may add to the conversation.
+const maxToolOutput = 16 << 10
+
+// BuiltinTools lists the names BuiltinTool accepts.
+var BuiltinTools = []string{"time", "calculator"}
+
+// BuiltinTool returns the named built-in tool.
+func BuiltinTool(name string) (Tool, error) {
+    switch name {
+    case "time":
+        return Tool{
+            Description: "Get the current date and time, optionally in an IANA time zone such as Europe/Berlin.",
+            Parameters:  json.RawMessage(`{"type":"object","properties":{"timezone":{"type":"string","description":"IANA time zone; default the server's"}}}`),
+            Call:        callTime,
+        }, nil
+    case "calculator":
+        return Tool{
+            Description: "Evaluate an arithmetic expression with + - * / % ^ and parentheses, e.g. (2+3)*4^2.",
+            Parameters:  json.RawMessage(`{"type":"object","properties":{"expression":{"type":"string"}},"required":["expression"]}`),
+            Call:        callCalculator,
+        }, nil
+    }
+    return Tool{}, fmt.Errorf("unknown built-in tool %q (have %s)", name, strings.Join(BuiltinTools, ", "))
+}
+
+func callTime(ctx context.Context, args json.RawMessage) (string, error) {
+    var in struct{ Timezone string `json:"timezone"` }
+    if len(args) > 0 { if err := json.Unmarshal(args, &in); err != nil { return "", err } }
+    loc := time.Local
+    if in.Timezone != "" {
+        l, err := time.LoadLocation(in.Timezone)
+        if err != nil { return "", fmt.Errorf("unknown time zone %q", in.Timezone) }
+        loc = l
+    }
+    now := time.Now().In(loc)
+    return now.Format("Monday, 2 January 2006 15:04:05 MST (2006-01-02T15:04:05Z07:00)"), nil
+}
+
+func callCalculator(ctx context.Context, args json.RawMessage) (string, error) {
+    var in struct{ Expression string `json:"expression"` }
+    if err := json.Unmarshal(args, &in); err != nil { return "", err }
+    v, err := evalArithmetic(in.Expression)
+    if err != nil { return "", err }
+    return strconv.FormatFloat(v, 'g', -1, 64), nil
+}
+
+// WebhookTool is a tool served by an HTTP endpoint: the arguments are POSTed
+// as JSON and the response body is the result.
+type WebhookTool struct {
+    Description string
+    Parameters  json.RawMessage
+    URL         string
+    Headers     map[string]string
+    Timeout     time.Duration // default 30s
+}
+
+// Tool returns the callable tool.
+func (h WebhookTool) Tool() Tool {
+    timeout := h.Timeout
+    if timeout <= 0 { timeout = 30 * time.Second }
+    params := h.Parameters
+    if len(params) == 0 { params = json.RawMessage(`{"type":"object","properties":{}}`) }
+    return Tool{Description: h.Description, Parameters: params, Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+        ctx, cancel := context.WithTimeout(ctx, timeout)
+        defer cancel()
+        if len(args) == 0 { args = json.RawMessage("{}") }
+        req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(args))
+        if err != nil { return "", err }
+        req.Header.Set("Content-Type", "application/json")
+        for k, v := range h.Headers { req.Header.Set(k, v) }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { return "", err }
+        defer resp.Body.Close()
+        b, err := io.ReadAll(io.LimitReader(resp.Body, maxToolOutput+1))
+        if err != nil { return "", err }
+        if resp.StatusCode < 200 || resp.StatusCode >= 300 { return "", fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(b[:min(len(b), 512)]))) }
+        return string(b), nil
+    }}
+}
+
+// -------- Calculator --------
+
+// evalArithmetic evaluates numbers, + - * / % ^ (right associative),
+// unary signs and parentheses.
+func evalArithmetic(expr string) (float64, error) {
+    p := &arith{s: strings.ReplaceAll(expr, " ", "")}
+    if p.s == "" { return 0, errors.New("empty expression") }
+    if len(p.s) > 1024 { return 0, errors.New("expression too long") }
+    v, err := p.sum()
+    if err != nil { return 0, err }
+    if p.i < len(p.s) { return 0, fmt.Errorf("unexpected %q at position %d", p.s[p.i], p.i) }
+    if math.IsInf(v, 0) || math.IsNaN(v) { return 0, errors.New("result is not a finite number") }
+    return v, nil
+}
+
+type arith struct {
+    s     string
+    i     int
+    depth int
+}
+
+func (p *arith) peek() byte {
+    if p.i < len(p.s) { return p.s[p.i] }
+    return 0
+}
+
+func (p *arith) sum() (float64, error) {
+    v, err := p.product()
+    for err == nil && (p.peek() == '+' || p.peek() == '-') {
+        op := p.peek()
+        p.i++
+        var r float64
+        if r, err = p.product(); op == '+' { v += r } else { v -= r }
+    }
+    return v, err
+}
+
+func (p *arith) product() (float64, error) {
+    v, err := p.unary()
+    for err == nil && (p.peek() == '*' || p.peek() == '/' || p.peek() == '%') {
+        op := p.peek()
+        p.i++
+        var r float64
+        if r, err = p.unary(); err != nil { break }
+        switch op {
+        case '*':
+            v *= r
+        case '/', '%':
+            if r == 0 { return 0, errors.New("division by zero") }
+            if op == '/' { v /= r } else { v = math.Mod(v, r) }
+        }
+    }
+    return v, err
+}
+
+// unary binds looser than ^, so -2^2 is -4.
+func (p *arith) unary() (float64, error) {
+    switch p.peek() {
+    case '-':
+        p.i++
+        v, err := p.unary()
+        return -v, err
+    case '+':
+        p.i++
+        return p.unary()
+    }
+    return p.power()
+}
+
+func (p *arith) power() (float64, error) {
+    v, err := p.atom()
+    if err != nil || p.peek() != '^' { return v, err }
+    p.i++
+    r, err := p.unary()
+    return math.Pow(v, r), err
+}
+
+func (p *arith) atom() (float64, error) {
+    if p.peek() == '(' {
+        if p.depth++; p.depth > 64 { return 0, errors.New("expression nested too deeply") }
+        p.i++
+        v, err := p.sum()
+        if err != nil { return 0, err }
+        if p.peek() != ')' { return 0, errors.New("missing )") }
+        p.i++
+        p.depth--
+        return v, nil
+    }
+    start := p.i
+    for p.i < len(p.s) && (p.s[p.i] >= '0' && p.s[p.i] <= '9' || p.s[p.i] == '.') { p.i++ }
+    if start == p.i {
+        if p.i >= len(p.s) { return 0, errors.New("unexpected end of expression") }
+        return 0, fmt.Errorf("unexpected %q at position %d", p.s[p.i], p.i)
+    }
+    return strconv.ParseFloat(p.s[start:p.i], 64)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+    "bufio"
+    "context"
+    "net"
+    "net/http"
+
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/tracing"
+)
+
+// -------- Tracing --------
+//
+// Trace opens a server span per request (continuing an incoming W3C
+// traceparent); WithTracing adds a child span around each service call.
+// The services add their own stage spans (download, tokenize, inference,
+// decode) beneath those. Everything is a no-op unless tracing is configured.
+
+// Trace wraps h with a server span named after the method and path.
+func Trace(h http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !tracing.Enabled() { h.ServeHTTP(w, r); return }
+        ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), r.Method+" "+r.URL.Path, tracing.KindServer)
+        span.Set("http.method", r.Method)
+        span.Set("http.target", r.URL.Path)
+        tracing.Inject(ctx, w.Header())
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        h.ServeHTTP(rec, r.WithContext(ctx))
+        span.Set("http.status_code", rec.status)
+        var err error
+        if rec.status >= 500 { err = errStatus(rec.status) }
+        span.End(err)
+    })
+}
+
+type errStatus int
+
+func (e errStatus) Error() string { return http.StatusText(int(e)) }
+
+// statusRecorder captures the response status while keeping streaming and
+// WebSocket upgrades working.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) { s.status = code; s.ResponseWriter.WriteHeader(code) }
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+func (s *statusRecorder) Flush() {
+    if f, ok := s.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    s.status = http.StatusSwitchingProtocols
+    return http.NewResponseController(s.ResponseWriter).Hijack()
+}
+
+// WithTracing wraps the LLM, TTS and embeddings services with spans.
+func WithTracing(d Dependencies) Dependencies {
+    if d.LLM != nil { d.LLM = &tracedLLM{next: d.LLM} }
+    if d.TTS != nil { d.TTS = &tracedTTS{next: d.TTS} }
+    if d.Embeddings != nil { d.Embeddings = &tracedEmbeddings{next: d.Embeddings} }
+    return d
+}
+
+type tracedLLM struct{ next LLMService }
+
+func (t *tracedLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    ctx, span := tracing.Start(ctx, "llm.chat", tracing.KindClient)
+    span.Set("llm.model", req.Model)
+    resp, err := t.next.Chat(ctx, req)
+    span.End(err)
+    return resp, err
+}
+
+func (t *tracedLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    ctx, span := tracing.Start(ctx, "llm.chat_stream", tracing.KindClient)
+    span.Set("llm.model", req.Model)
+    first := true
+    resp, err := t.next.ChatStream(ctx, req, func(c llm.ChatChunk) error {
+        if first { first = false; span.Set("llm.first_chunk_ms", span.Elapsed().Milliseconds()) }
+        return onChunk(c)
+    })
+    span.End(err)
+    return resp, err
+}
+
+func (t *tracedLLM) Model() string {
+    if m, ok := t.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type tracedTTS struct{ next TTSService }
+
+func (t *tracedTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    ctx, span := tracing.Start(ctx, "tts.synthesize", tracing.KindInternal)
+    span.Set("tts.voice", voice)
+    span.Set("tts.chars", len(text))
+    audio, err := t.next.Synthesize(ctx, text, voice)
+    span.Set("tts.bytes", len(audio))
+    span.End(err)
+    return audio, err
+}
+
+type tracedEmbeddings struct{ next embeddings.Service }
+
+func (t *tracedEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    ctx, span := tracing.Start(ctx, "embeddings.embed", tracing.KindInternal)
+    span.Set("embeddings.inputs", len(inputs))
+    vecs, model, err := t.next.Embed(ctx, inputs)
+    span.Set("embeddings.model", model)
+    span.End(err)
+    return vecs, model, err
+}
+
+func (t *tracedEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(t.next, inputs) }
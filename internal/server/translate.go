@@ -0,0 +1,62 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// -------- Translation --------
+//
+// POST /v1/translate translates text with the LLM. Languages are given the
+// way a person would name them ("de", "German", "pt-BR"); the model is told
+// them verbatim. /v1/voice/translate chains it between STT and TTS.
+
+type translateRequest struct {
+    Text       string `json:"text" validate:"required"`
+    // SourceLang is detected by the model when empty.
+    SourceLang string `json:"source_lang,omitempty"`
+    TargetLang string `json:"target_lang"`
+    Model      string `json:"model,omitempty"`
+}
+
+type translateResponse struct {
+    Text       string `json:"text"`
+    SourceLang string `json:"source_lang,omitempty"`
+    TargetLang string `json:"target_lang"`
+    Model      string `json:"model"`
+}
+
+// maxLangLen bounds language names, which end up in the prompt.
+const maxLangLen = 40
+
+func checkLang(lang string) bool {
+    if len(lang) > maxLangLen { return false }
+    for _, c := range lang {
+        if c != ' ' && c != '-' && c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') { return false }
+    }
+    return true
+}
+
+// translate returns text in target, preserving its formatting.
+func (d Dependencies) translate(ctx context.Context, model, text, source, target string) (string, string, error) {
+    from := "Detect the language of the text the user sends and translate it"
+    if source != "" { from = "Translate the text the user sends from " + source }
+    prompt := from + " into " + target + ". Keep the meaning, tone, formatting, names and numbers; do not add explanations, notes or quotes. If the text is already in " + target + ", return it unchanged. Reply with the translation only."
+    return d.complete(ctx, model, prompt, text, nil)
+}
+
+func handleTranslate(w http.ResponseWriter, r *http.Request, d Dependencies) {
+    var req translateRequest
+    if !decodeJSON(w, r, &req) { return }
+    if strings.TrimSpace(req.Text) == "" { writeParamError(w, "text", "text must not be empty"); return }
+    if len([]rune(req.Text)) > maxNLPInputChars { writeParamError(w, "text", fmt.Sprintf("text exceeds %d characters", maxNLPInputChars)); return }
+    req.SourceLang, req.TargetLang = strings.TrimSpace(req.SourceLang), strings.TrimSpace(req.TargetLang)
+    if req.TargetLang == "" || !checkLang(req.TargetLang) { writeParamError(w, "target_lang", "target_lang must be a language code or name"); return }
+    if !checkLang(req.SourceLang) { writeParamError(w, "source_lang", "source_lang must be a language code or name"); return }
+
+    out, model, err := d.translate(r.Context(), req.Model, req.Text, req.SourceLang, req.TargetLang)
+    if err != nil { writeServiceError(w, err, http.StatusBadGateway); return }
+    writeJSON(w, http.StatusOK, translateResponse{Text: out, SourceLang: req.SourceLang, TargetLang: req.TargetLang, Model: model})
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "gollmcore/internal/procs"
+    "gollmcore/internal/scratch"
+    "gollmcore/internal/tracing"
+)
+
+// UploadPolicy screens uploaded files before they reach a service.
+// The zero value accepts everything.
+type UploadPolicy struct {
+    // Sniff enables content-type detection from the file's leading bytes.
+    Sniff bool
+    // AllowedTypes lists accepted sniffed types; empty means defaultAudioTypes.
+    AllowedTypes []string
+    // ScanCommand is run with the file path appended; a non-zero exit rejects the upload.
+    ScanCommand []string
+}
+
+var defaultAudioTypes = []string{"audio/wav", "audio/mpeg", "audio/ogg", "audio/flac", "audio/mp4", "audio/webm"}
+
+var errUploadRejected = errors.New("upload rejected")
+
+// Check sniffs and optionally scans the file at path. filename is the
+// client-supplied name, used only to detect extension/content mismatches.
+func (p UploadPolicy) Check(ctx context.Context, path, filename string) error {
+    if p.Sniff {
+        f, err := os.Open(path)
+        if err != nil { return err }
+        head := make([]byte, 512)
+        n, _ := io.ReadFull(f, head)
+        f.Close()
+        got := sniffType(head[:n])
+        allowed := p.AllowedTypes
+        if len(allowed) == 0 { allowed = defaultAudioTypes }
+        if !containsType(allowed, got) {
+            return fmt.Errorf("%w: content type %s is not allowed", errUploadRejected, got)
+        }
+        if want := typeByExt(filename); want != "" && want != got {
+            return fmt.Errorf("%w: file extension suggests %s but content is %s", errUploadRejected, want, got)
+        }
+    }
+    if len(p.ScanCommand) > 0 {
+        ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+        defer cancel()
+        args := append(append([]string{}, p.ScanCommand[1:]...), path)
+        cmd := exec.CommandContext(ctx, p.ScanCommand[0], args...)
+        var out bytes.Buffer
+        cmd.Stdout = &out
+        cmd.Stderr = &out
+        if err := procs.Run("scanner", cmd); err != nil {
+            return fmt.Errorf("%w by scanner: %s", errUploadRejected, strings.TrimSpace(out.String()))
+        }
+    }
+    return nil
+}
+
+// sniffType recognizes common audio containers and falls back to
+// http.DetectContentType for everything else.
+func sniffType(b []byte) string {
+    switch {
+    case len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WAVE":
+        return "audio/wav"
+    case len(b) >= 3 && string(b[0:3]) == "ID3", len(b) >= 2 && b[0] == 0xFF && b[1]&0xE0 == 0xE0:
+        return "audio/mpeg"
+    case len(b) >= 4 && string(b[0:4]) == "OggS":
+        return "audio/ogg"
+    case len(b) >= 4 && string(b[0:4]) == "fLaC":
+        return "audio/flac"
+    case len(b) >= 8 && string(b[4:8]) == "ftyp":
+        return "audio/mp4"
+    case len(b) >= 4 && b[0] == 0x1A && b[1] == 0x45 && b[2] == 0xDF && b[3] == 0xA3:
+        return "audio/webm"
+    }
+    ct := http.DetectContentType(b)
+    if i := strings.Index(ct, ";"); i >= 0 { ct = ct[:i] }
+    return ct
+}
+
+func typeByExt(name string) string {
+    switch strings.ToLower(filepath.Ext(name)) {
+    case ".wav", ".wave": return "audio/wav"
+    case ".mp3": return "audio/mpeg"
+    case ".ogg", ".oga", ".opus": return "audio/ogg"
+    case ".flac": return "audio/flac"
+    case ".m4a", ".mp4", ".aac": return "audio/mp4"
+    case ".webm": return "audio/webm"
+    }
+    return ""
+}
+
+func containsType(list []string, t string) bool {
+    for _, v := range list { if strings.EqualFold(v, t) { return true } }
+    return false
+}
+
+// -------- Temporary copies --------
+//
+// Client file names never become paths. An upload is copied to a new file
+// in the scratch dir named <prefix>-<trace id>-<random><ext>, so concurrent
+// requests cannot overwrite each other and leftovers can be matched to
+// their request; only the extension is taken from the client, and only
+// when it is a known audio type, since decoders go by it.
+
+// uploadExt returns the lower-cased extension of the client file name, ""
+// when it has none, or an error when it is not an audio type.
+func uploadExt(name string) (string, error) {
+    ext := strings.ToLower(filepath.Ext(name))
+    if ext == "" || typeByExt(ext) != "" { return ext, nil }
+    return "", fmt.Errorf("%w: file extension %q is not a supported audio type", errUploadRejected, ext)
+}
+
+// saveUpload copies src, sent as filename, to a new scratch file and
+// screens it with d.Uploads. The caller removes the returned path; on
+// error nothing is left behind.
+func (d Dependencies) saveUpload(ctx context.Context, prefix, filename string, src io.Reader) (string, error) {
+    ext, err := uploadExt(filename)
+    if err != nil { return "", err }
+    if id := tracing.TraceID(ctx); id != "" { prefix += "-" + id }
+    f, err := scratch.Create(prefix+"-", ext)
+    if err != nil { return "", err }
+    _, err = io.Copy(f, src)
+    if cerr := f.Close(); err == nil { err = cerr }
+    if err == nil { err = d.Uploads.Check(ctx, f.Name(), filename) }
+    if err != nil { os.Remove(f.Name()); return "", err }
+    return f.Name(), nil
+}
+
+// formAudio saves the multipart "file" (or "audio") field to a temporary
+// file and screens it. On failure it has already written the error
+// response and returns ok false.
+func formAudio(w http.ResponseWriter, r *http.Request, d Dependencies, prefix string) (path string, cleanup func(), ok bool) {
+    file, hdr, err := r.FormFile("file")
+    if err != nil { file, hdr, err = r.FormFile("audio") }
+    if err != nil { writeParamError(w, "file", "missing form file 'file' or 'audio'"); return "", nil, false }
+    defer file.Close()
+    path, err = d.saveUpload(r.Context(), prefix, hdr.Filename, file)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return "", nil, false }
+    return path, func() { os.Remove(path) }, true
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+    "context"
+    "net/http"
+    "sort"
+    "sync"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- LLM upstreams --------
+//
+// Besides the main LLM, chats can be served by more OpenAI-compatible
+// servers, e.g. one llama-server per GGUF model, or several serving the
+// same model as extra slots. Each upstream lists the models it serves; a
+// chat goes to an upstream serving its model, the one with the fewest
+// calls in flight (ties take turns), and every other chat to the main
+// LLM, which also counts as a slot of its default model. GET /v1/models
+// lists what is served where.
+
+// LLMUpstream is an additional LLM server and the models it serves.
+type LLMUpstream struct {
+    Name   string // shown as owned_by in /v1/models, e.g. the server's host
+    Models []string
+    LLM    LLMService
+}
+
+// WithUpstreams routes d.LLM calls across d.LLMUpstreams. Apply it to the
+// bare services, before WithResources and the other decorators, so every
+// transport shares the in-flight counts.
+func WithUpstreams(d Dependencies) Dependencies {
+    if d.LLM == nil || len(d.LLMUpstreams) == 0 { return d }
+    r := &llmRouter{def: d.LLM, pools: map[string][]*llmSlot{}}
+    if m, ok := d.LLM.(interface{ Model() string }); ok { r.model = m.Model() }
+    if r.model != "" { r.pools[r.model] = []*llmSlot{{svc: d.LLM}} }
+    for _, up := range d.LLMUpstreams {
+        s := &llmSlot{svc: up.LLM}
+        for _, m := range up.Models { r.pools[m] = append(r.pools[m], s) }
+    }
+    d.LLM = r
+    return d
+}
+
+type llmSlot struct {
+    svc      LLMService
+    inflight int
+}
+
+type llmRouter struct {
+    def   LLMService
+    model string                // the main LLM's default model
+    pools map[string][]*llmSlot // fixed once built
+    mu    sync.Mutex            // guards turn and the slots' inflight
+    turn  int
+}
+
+// pick takes the least busy slot serving model; release returns it.
+func (r *llmRouter) pick(model string) (svc LLMService, release func()) {
+    if model == "" { model = r.model }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    pool := r.pools[model]
+    if len(pool) == 0 { return r.def, func() {} }
+    r.turn++
+    var best *llmSlot
+    for i := range pool {
+        s := pool[(r.turn+i)%len(pool)]
+        if best == nil || s.inflight < best.inflight { best = s }
+    }
+    best.inflight++
+    return best.svc, func() { r.mu.Lock(); best.inflight--; r.mu.Unlock() }
+}
+
+// request names the model explicitly, since an upstream's own default may
+// differ from the main LLM's.
+func (r *llmRouter) request(req llm.ChatRequest) llm.ChatRequest {
+    if req.Model == "" { req.Model = r.model }
+    return req
+}
+
+func (r *llmRouter) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    svc, release := r.pick(req.Model)
+    defer release()
+    return svc.Chat(ctx, r.request(req))
+}
+
+func (r *llmRouter) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    svc, release := r.pick(req.Model)
+    defer release()
+    return svc.ChatStream(ctx, r.request(req), onChunk)
+}
+
+func (r *llmRouter) Model() string { return r.model }
+
+// Footprint asks an upstream serving model, so WithResources still works.
+func (r *llmRouter) Footprint(ctx context.Context, model string) (size uint64, loaded, ok bool) {
+    if model == "" { model = r.model }
+    svc := r.def
+    if pool := r.pools[model]; len(pool) > 0 { svc = pool[0].svc }
+    if f, isFP := svc.(llmFootprint); isFP { return f.Footprint(ctx, model) }
+    return 0, false, false
+}
+
+// -------- GET /v1/models --------
+
+type modelEntry struct {
+    ID        string `json:"id"`
+    Object    string `json:"object"`
+    Created   int64  `json:"created"`
+    OwnedBy   string `json:"owned_by"`
+    Upstreams int    `json:"upstreams"` // servers serving the model
+}
+
+type modelList struct {
+    Object string       `json:"object"`
+    Data   []modelEntry `json:"data"`
+}
+
+// handleModels lists the chat models in the OpenAI format: the default
+// model, the vision model, the LoRA variants and each upstream's models.
+func handleModels(w http.ResponseWriter, d Dependencies) {
+    owners := map[string]string{}
+    counts := map[string]int{}
+    add := func(id, owner string) {
+        if id == "" { return }
+        if _, seen := owners[id]; !seen { owners[id] = owner }
+        counts[id]++
+    }
+    if m, ok := d.LLM.(interface{ Model() string }); ok { add(m.Model(), "gollmcore") }
+    for _, up := range d.LLMUpstreams {
+        for _, m := range up.Models { add(m, up.Name) }
+    }
+    if _, seen := owners[d.Vision.Model]; !seen { add(d.Vision.Model, "gollmcore") }
+    out := modelList{Object: "list", Data: []modelEntry{}}
+    for id, owner := range owners { out.Data = append(out.Data, modelEntry{ID: id, Object: "model", OwnedBy: owner, Upstreams: counts[id]}) }
+    if m, ok := d.LLM.(interface{ Model() string }); ok && m.Model() != "" {
+        for name := range d.LoRA { out.Data = append(out.Data, modelEntry{ID: m.Model() + ":" + name, Object: "model", OwnedBy: "gollmcore", Upstreams: 1}) }
+    }
+    sort.Slice(out.Data, func(i, j int) bool { return out.Data[i].ID < out.Data[j].ID })
+    writeJSON(w, http.StatusOK, out)
+}
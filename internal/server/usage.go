@@ -0,0 +1,186 @@
+package server
+
+import (
+    "context"
+    "net/http"
+    "path/filepath"
+    "time"
+
+    "gollmcore/internal/audit"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+    "gollmcore/internal/tracing"
+    "gollmcore/internal/usage"
+)
+
+// -------- Usage statistics --------
+
+// track starts a usage record for a call that is not wrapped by one of the
+// decorators below (STT); the returned function also annotates the audit
+// entry of the request in ctx.
+func (d Dependencies) track(ctx context.Context, service, model string) func(promptTokens, completionTokens int, err error) {
+    end := func(int, int, error) {}
+    if d.Usage != nil { end = d.Usage.Begin(service, model) }
+    return func(in, out int, err error) {
+        end(in, out, err)
+        audit.Note(ctx, service, model, in, out)
+    }
+}
+
+// transcribeFile runs STT on path within the STT timeout, recording the call
+// under the whisper model.
+func (d Dependencies) transcribeFile(ctx context.Context, path, model string, req sttRequest) (string, error) {
+    t, err := d.transcribe(ctx, path, model, req)
+    return t.Text, err
+}
+
+// transcribe is transcribeFile keeping the segments of long recordings,
+// which are split and transcribed in parallel (see LongAudio).
+func (d Dependencies) transcribe(ctx context.Context, path, model string, req sttRequest) (transcript, error) {
+    if err := d.Caps.checkAudio(path); err != nil { return transcript{}, err }
+    model = d.fitWhisperModel(model)
+    end := d.track(ctx, "stt", model)
+    ctx, span := tracing.Start(ctx, "stt.transcribe", tracing.KindInternal)
+    span.Set("stt.model", model)
+    var out transcript
+    chunks, cleanup, err := stt.SplitWAV(path, filepath.Dir(path), d.LongAudio.After, d.LongAudio.Chunk)
+    if err == nil && len(chunks) > 0 {
+        span.Set("stt.chunks", len(chunks))
+        out, err = d.transcribeChunks(ctx, chunks, model, req)
+        cleanup()
+    } else if err == nil {
+        err = withDeadline(ctx, "stt", d.Timeouts.withDefaults().STT, func(ctx context.Context) (err error) {
+            release, err := d.sttLimiter.acquire(ctx)
+            if err != nil { return err }
+            defer release()
+            out.Text, err = d.STT.TranscribeFile(ctx, path, model, d.sttOptions(req))
+            return err
+        })
+    }
+    end(0, estimateTokens(len(out.Text)), err)
+    span.End(err)
+    if err == nil { d.IOLog.record("stt.transcript", "model="+model, out.Text) }
+    return out, err
+}
+
+// estimateTokens approximates a token count from text length.
+func estimateTokens(chars int) int { return (chars + 3) / 4 }
+
+// WithUsage wraps the LLM, TTS and embeddings services so every call is
+// recorded in d.Usage. Apply it before WithCoalescing so shared results
+// count once.
+func WithUsage(d Dependencies) Dependencies {
+    if d.Usage == nil { return d }
+    if d.LLM != nil { d.LLM = &usageLLM{next: d.LLM, rec: d.Usage} }
+    if d.TTS != nil { d.TTS = &usageTTS{next: d.TTS, rec: d.Usage} }
+    if d.Embeddings != nil { d.Embeddings = &usageEmbeddings{next: d.Embeddings, rec: d.Usage} }
+    return d
+}
+
+type usageLLM struct {
+    next LLMService
+    rec  *usage.Recorder
+}
+
+func (u *usageLLM) model(req llm.ChatRequest) string {
+    if req.Model != "" { return req.Model }
+    if m, ok := u.next.(interface{ Model() string }); ok && m.Model() != "" { return m.Model() }
+    return "default"
+}
+
+func (u *usageLLM) done(end func(int, int, error), req llm.ChatRequest, resp *llm.ChatResponse, err error) {
+    in, out := 0, 0
+    if resp != nil && resp.Usage != nil && resp.Usage.TotalTokens > 0 {
+        in, out = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+    } else {
+        for _, m := range req.Messages { in += estimateTokens(len(m.Content)) }
+        if resp != nil { out = estimateTokens(len(resp.Text())) }
+    }
+    end(in, out, err)
+}
+
+func (u *usageLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    end := u.rec.Begin("llm", u.model(req))
+    resp, err := u.next.Chat(ctx, req)
+    u.done(end, req, resp, err)
+    return resp, err
+}
+
+func (u *usageLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    end := u.rec.Begin("llm", u.model(req))
+    resp, err := u.next.ChatStream(ctx, req, onChunk)
+    u.done(end, req, resp, err)
+    return resp, err
+}
+
+func (u *usageLLM) Model() string {
+    if m, ok := u.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
+
+type usageTTS struct {
+    next TTSService
+    rec  *usage.Recorder
+}
+
+func (u *usageTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    model := voice
+    if model == "" { model = "default" }
+    end := u.rec.Begin("tts", model)
+    audio, err := u.next.Synthesize(ctx, text, voice)
+    end(estimateTokens(len(text)), 0, err)
+    return audio, err
+}
+
+type usageEmbeddings struct {
+    next embeddings.Service
+    rec  *usage.Recorder
+}
+
+func (u *usageEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    // The model name is only known once the call returns.
+    end := u.rec.BeginUnnamed("embeddings")
+    vecs, model, err := u.next.Embed(ctx, inputs)
+    if model == "" { model = "default" }
+    end(model, u.CountTokens(inputs), 0, err)
+    return vecs, model, err
+}
+
+func (u *usageEmbeddings) CountTokens(inputs []string) int { return embeddings.CountTokens(u.next, inputs) }
+
+// embeddingsUsage counts the tokens of an embeddings request, as in the
+// usage object of OpenAI's embeddings responses.
+type embeddingsUsage struct {
+    PromptTokens int `json:"prompt_tokens"`
+    TotalTokens  int `json:"total_tokens"`
+}
+
+// chargeEmbeddings counts the tokens of inputs and adds them to the totals
+// of the request's API key: the gollmcore_embedding_tokens_total metric
+// and, with usage statistics on, the cumulative per-key record. Requests
+// without a key are counted as "anonymous".
+func (d Dependencies) chargeEmbeddings(r *http.Request, inputs []string) embeddingsUsage {
+    n := embeddings.CountTokens(d.Embeddings, inputs)
+    key := requestAPIKey(r)
+    if key == "" { key = r.URL.Query().Get("token") }
+    fp := "anonymous"
+    if key != "" { fp = keyFingerprint(key) }
+    metrics.add("gollmcore_embedding_tokens_total", "Tokens embedded, by API key fingerprint.", `key="`+fp+`"`, int64(n))
+    if d.Usage != nil { d.Usage.Charge("embeddings", fp, n) }
+    return embeddingsUsage{PromptTokens: n, TotalTokens: n}
+}
+
+func handleAdminUsage(w http.ResponseWriter, r *http.Request, rec *usage.Recorder) {
+    q := r.URL.Query()
+    f := usage.Filter{Service: q.Get("service"), Model: q.Get("model")}
+    for name, dst := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+        v := q.Get(name)
+        if v == "" { continue }
+        t, err := time.Parse(time.RFC3339, v)
+        if err != nil { writeParamError(w, name, name+" must be RFC3339"); return }
+        *dst = t
+    }
+    summary, series := rec.Query(f)
+    writeJSON(w, http.StatusOK, map[string]any{"models": summary, "hourly": series, "keys": rec.Keys(f.Service)})
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "reflect"
+    "strings"
+)
+
+// -------- Request validation --------
+//
+// JSON bodies are checked against the request struct before a handler
+// sees them. Malformed JSON is a 400 (invalid_json); a well-formed body
+// that does not fit is a 422 naming the field in param: a field of the
+// wrong type (invalid_type), a field the endpoint does not know
+// (unknown_field) or a missing field tagged validate:"required"
+// (missing_field). Handlers still check values (ranges, empty strings)
+// themselves. OpenAI-compatible endpoints decode with decodeCompatJSON,
+// which ignores unknown fields, because the SDKs send options this server
+// does not use.
+
+// defaultMaxJSONBytes bounds JSON bodies on routes registered without
+// Dependencies.MaxJSONBytes.
+const defaultMaxJSONBytes = 32 << 20
+
+type jsonLimitKey struct{}
+
+// withJSONLimit makes decodeJSON refuse bodies of h's requests over n bytes
+// with 413; negative n lifts the limit, zero keeps the default.
+func withJSONLimit(h http.HandlerFunc, n int64) http.HandlerFunc {
+    if n == 0 { return h }
+    return func(w http.ResponseWriter, r *http.Request) {
+        h(w, r.WithContext(context.WithValue(r.Context(), jsonLimitKey{}, n)))
+    }
+}
+
+func jsonLimit(ctx context.Context) int64 {
+    if n, ok := ctx.Value(jsonLimitKey{}).(int64); ok { return n }
+    return defaultMaxJSONBytes
+}
+
+// decodeJSON decodes the body of r into v, rejecting unknown fields. On
+// failure it has written the error response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool { return decodeBody(w, r, v, true) }
+
+// decodeCompatJSON is decodeJSON without the unknown-field check.
+func decodeCompatJSON(w http.ResponseWriter, r *http.Request, v any) bool { return decodeBody(w, r, v, false) }
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any, strict bool) bool {
+    if n := jsonLimit(r.Context()); n > 0 { r.Body = http.MaxBytesReader(w, r.Body, n) }
+    body, err := io.ReadAll(r.Body)
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) { writeError(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge); return false }
+    if err != nil { writeError(w, "reading request body: "+err.Error(), http.StatusBadRequest); return false }
+    if len(bytes.TrimSpace(body)) == 0 { writeAPIError(w, http.StatusBadRequest, newAPIError(http.StatusBadRequest, "invalid_json", "", "request body must be a JSON object")); return false }
+
+    var fields map[string]json.RawMessage
+    if err := json.Unmarshal(body, &fields); err != nil {
+        var syntax *json.SyntaxError
+        if errors.As(err, &syntax) { writeAPIError(w, http.StatusBadRequest, newAPIError(http.StatusBadRequest, "invalid_json", "", fmt.Sprintf("invalid JSON at byte %d: %s", syntax.Offset, syntax.Error()))); return false }
+        writeValidationError(w, "invalid_type", "", "request body must be a JSON object")
+        return false
+    }
+    if missing := missingFields(reflect.TypeOf(v), fields); len(missing) > 0 {
+        writeValidationError(w, "missing_field", missing[0], "missing required field "+strings.Join(quoteAll(missing), ", "))
+        return false
+    }
+
+    dec := json.NewDecoder(bytes.NewReader(body))
+    if strict { dec.DisallowUnknownFields() }
+    if err := dec.Decode(v); err != nil {
+        var typeErr *json.UnmarshalTypeError
+        switch {
+        case errors.As(err, &typeErr):
+            writeValidationError(w, "invalid_type", typeErr.Field, fmt.Sprintf("field %q must be %s, got %s", typeErr.Field, jsonKind(typeErr.Type), typeErr.Value))
+        case strings.HasPrefix(err.Error(), "json: unknown field "):
+            name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+            writeValidationError(w, "unknown_field", name, fmt.Sprintf("unknown field %q", name))
+        default:
+            writeValidationError(w, "invalid_value", "", err.Error())
+        }
+        return false
+    }
+    return true
+}
+
+func writeValidationError(w http.ResponseWriter, code, param, msg string) {
+    writeAPIError(w, http.StatusUnprocessableEntity, newAPIError(http.StatusUnprocessableEntity, code, param, msg))
+}
+
+// missingFields lists the validate:"required" fields of the struct t
+// points to (including embedded structs) that are absent from fields.
+func missingFields(t reflect.Type, fields map[string]json.RawMessage) []string {
+    for t.Kind() == reflect.Pointer { t = t.Elem() }
+    if t.Kind() != reflect.Struct { return nil }
+    var missing []string
+    for i := 0; i < t.NumField(); i++ {
+        f := t.Field(i)
+        name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if f.Anonymous && name == "" { missing = append(missing, missingFields(f.Type, fields)...); continue }
+        if f.Tag.Get("validate") != "required" { continue }
+        if name == "" { name = f.Name }
+        if raw, ok := fields[name]; !ok || string(raw) == "null" { missing = append(missing, name) }
+    }
+    return missing
+}
+
+// jsonKind describes what a Go type expects in JSON terms.
+func jsonKind(t reflect.Type) string {
+    switch t.Kind() {
+    case reflect.String:
+        return "a string"
+    case reflect.Bool:
+        return "a boolean"
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return "an integer"
+    case reflect.Float32, reflect.Float64:
+        return "a number"
+    case reflect.Slice, reflect.Array:
+        return "an array"
+    case reflect.Map, reflect.Struct:
+        return "an object"
+    case reflect.Pointer:
+        return jsonKind(t.Elem())
+    }
+    return "a " + t.String()
+}
+
+func quoteAll(names []string) []string {
+    out := make([]string, len(names))
+    for i, n := range names { out[i] = fmt.Sprintf("%q", n) }
+    return out
+}
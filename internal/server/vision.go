@@ -0,0 +1,99 @@
+package server
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Vision --------
+//
+// Chat messages may carry OpenAI image_url parts (http(s) or base64 data
+// URLs). They are passed to the upstream as they are; llama-server needs a
+// multimodal model with its projector (--mmproj), Ollama a vision model
+// such as llava. With a Vision route configured, requests with images and
+// no explicit model go to the vision model (and backend) instead of the
+// default one.
+
+// maxImageBytes bounds one inline (data URL) image.
+const maxImageBytes = 20 << 20
+
+// VisionRoute sends image requests to a multimodal model.
+type VisionRoute struct {
+    Model string     // used for image requests that name no model
+    LLM   LLMService // separate backend; nil uses the main LLM
+}
+
+// hasImages reports whether any message carries an image part.
+func hasImages(msgs []llm.Message) bool {
+    for _, m := range msgs {
+        if m.HasImages() { return true }
+    }
+    return false
+}
+
+// checkImages validates image parts, naming the problem for a 400.
+func checkImages(msgs []llm.Message) error {
+    for i, m := range msgs {
+        for _, p := range m.Parts {
+            switch p.Type {
+            case "text":
+            case "image_url":
+                if p.ImageURL == nil || p.ImageURL.URL == "" { return fmt.Errorf("messages[%d]: image_url part without a url", i) }
+                u := p.ImageURL.URL
+                switch {
+                case strings.HasPrefix(u, "data:image/"):
+                    if !strings.Contains(u[:min(len(u), 64)], ";base64,") { return fmt.Errorf("messages[%d]: data URLs must be base64 encoded", i) }
+                    if len(u) > maxImageBytes*4/3+64 { return fmt.Errorf("messages[%d]: image exceeds %d MiB", i, maxImageBytes>>20) }
+                case strings.HasPrefix(u, "http://"), strings.HasPrefix(u, "https://"):
+                default:
+                    return fmt.Errorf("messages[%d]: image url must be http(s) or a data:image/ URL", i)
+                }
+                if m.Role != "user" { return fmt.Errorf("messages[%d]: only user messages may carry images", i) }
+            default:
+                return fmt.Errorf("messages[%d]: unsupported content part type %q", i, p.Type)
+            }
+        }
+    }
+    return nil
+}
+
+type visionLLM struct {
+    next   LLMService
+    vision LLMService
+    model  string
+}
+
+// withVision wraps d.LLM so image requests follow d.Vision. It is the
+// innermost wrapper, so both backends get the policy, defaults and timeouts.
+func (d Dependencies) withVision() Dependencies {
+    if d.LLM == nil || (d.Vision.Model == "" && d.Vision.LLM == nil) { return d }
+    v := &visionLLM{next: d.LLM, vision: d.Vision.LLM, model: d.Vision.Model}
+    if v.vision == nil { v.vision = d.LLM }
+    d.LLM = v
+    return d
+}
+
+func (v *visionLLM) route(req llm.ChatRequest) (LLMService, llm.ChatRequest) {
+    if !hasImages(req.Messages) { return v.next, req }
+    if req.Model == "" { req.Model = v.model }
+    return v.vision, req
+}
+
+func (v *visionLLM) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+    next, req := v.route(req)
+    return next.Chat(ctx, req)
+}
+
+func (v *visionLLM) ChatStream(ctx context.Context, req llm.ChatRequest, onChunk func(llm.ChatChunk) error) (*llm.ChatResponse, error) {
+    next, req := v.route(req)
+    return next.ChatStream(ctx, req, onChunk)
+}
+
+// Model reports the upstream default model, when known.
+func (v *visionLLM) Model() string {
+    if m, ok := v.next.(interface{ Model() string }); ok { return m.Model() }
+    return ""
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "mime/multipart"
+    "net/http"
+    "net/textproto"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "gollmcore/internal/services/llm"
+)
+
+// -------- Voice Chat (STT -> LLM -> TTS) --------
+
+// maxVoiceHistory bounds the per-connection history kept by /ws/voice.
+const maxVoiceHistory = 20
+
+var errNoSpeech = errors.New("no speech detected")
+
+// voiceGreeting is the configured /ws/voice greeting, synthesized once and
+// replayed to every session that opens while STT and the LLM warm up. A
+// failed synthesis is retried by the next session.
+type voiceGreeting struct {
+    text  string
+    tts   TTSService
+    mu    sync.Mutex
+    audio []byte
+}
+
+func newVoiceGreeting(text string, tts TTSService) *voiceGreeting {
+    if text == "" { return nil }
+    return &voiceGreeting{text: text, tts: tts}
+}
+
+func (g *voiceGreeting) get(ctx context.Context) ([]byte, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.audio != nil { return g.audio, nil }
+    audio, err := g.tts.Synthesize(ctx, g.text, "")
+    if err != nil { return nil, err }
+    g.audio = audio
+    return audio, nil
+}
+
+// send writes the greeting to a new voice session; a greeting that cannot
+// be synthesized is skipped rather than failing the session.
+func (g *voiceGreeting) send(ctx context.Context, conn *wsConn) error {
+    if g == nil { return nil }
+    audio, err := g.get(ctx)
+    if err != nil {
+        log.Printf("voice greeting: %v", err)
+        return nil
+    }
+    return conn.WriteJSON(map[string]any{"ok": true, "event": "greeting", "reply": g.text, "mime": "audio/wav", "audio_base64": base64.StdEncoding.EncodeToString(audio)})
+}
+
+type voiceTurn struct {
+    Transcript string
+    Reply      string
+    ToolCalls  json.RawMessage
+    Audio      []byte
+    Timing     voiceTiming
+}
+
+type voiceTiming struct {
+    STTMs   int64 `json:"stt_ms"`
+    LLMMs   int64 `json:"llm_ms"`
+    TTSMs   int64 `json:"tts_ms"`
+    TotalMs int64 `json:"total_ms"`
+}
+
+// voiceResponder turns a transcript into the text to speak.
+type voiceResponder func(ctx context.Context, transcript string) (reply string, toolCalls json.RawMessage, err error)
+
+// runVoiceChat transcribes audioPath, asks the LLM for a reply given history
+// and synthesizes it. Errors name the failing stage and come with an HTTP status.
+func runVoiceChat(ctx context.Context, d Dependencies, audioPath, sttModel, voice string, history []llm.Message) (voiceTurn, int, error) {
+    return runVoice(ctx, d, audioPath, sttModel, voice, func(ctx context.Context, transcript string) (string, json.RawMessage, error) {
+        msgs := make([]llm.Message, 0, len(history)+2)
+        if d.VoiceSystemPrompt != "" { msgs = append(msgs, llm.Message{Role: "system", Content: d.VoiceSystemPrompt}) }
+        msgs = append(msgs, history...)
+        msgs = append(msgs, llm.Message{Role: "user", Content: transcript})
+        resp, err := d.LLM.Chat(ctx, llm.ChatRequest{Messages: msgs})
+        if err != nil { return "", nil, fmt.Errorf("chat: %w", err) }
+        var calls json.RawMessage
+        if len(resp.Choices) > 0 { calls = resp.Choices[0].Message.ToolCalls }
+        return resp.Text(), calls, nil
+    })
+}
+
+// runVoiceTranslate speaks the translation of audioPath into target.
+func runVoiceTranslate(ctx context.Context, d Dependencies, audioPath, sttModel, voice, source, target string) (voiceTurn, int, error) {
+    return runVoice(ctx, d, audioPath, sttModel, voice, func(ctx context.Context, transcript string) (string, json.RawMessage, error) {
+        out, _, err := d.translate(ctx, "", transcript, source, target)
+        if err != nil { return "", nil, fmt.Errorf("translate: %w", err) }
+        return out, nil, nil
+    })
+}
+
+// runVoice is the STT -> respond -> TTS pipeline behind the voice endpoints.
+func runVoice(ctx context.Context, d Dependencies, audioPath, sttModel, voice string, respond voiceResponder) (turn voiceTurn, status int, err error) {
+    start := time.Now()
+    defer func() { turn.Timing.TotalMs = time.Since(start).Milliseconds() }()
+    if sttModel == "" { sttModel = d.STTDefaultModel }
+    text, err := d.transcribeFile(ctx, audioPath, sttModel, sttRequest{})
+    turn.Timing.STTMs = time.Since(start).Milliseconds()
+    if err != nil { return turn, http.StatusInternalServerError, fmt.Errorf("transcription: %w", err) }
+    turn.Transcript = strings.TrimSpace(text)
+    if turn.Transcript == "" { return turn, http.StatusUnprocessableEntity, errNoSpeech }
+
+    llmStart := time.Now()
+    turn.Reply, turn.ToolCalls, err = respond(ctx, turn.Transcript)
+    turn.Timing.LLMMs = time.Since(llmStart).Milliseconds()
+    if err != nil { return turn, http.StatusBadGateway, err }
+    turn.Reply = strings.TrimSpace(turn.Reply)
+    if turn.Reply == "" { return turn, http.StatusBadGateway, errors.New("empty reply from the LLM") }
+
+    ttsStart := time.Now()
+    turn.Audio, err = d.TTS.Synthesize(ctx, turn.Reply, voice)
+    turn.Timing.TTSMs = time.Since(ttsStart).Milliseconds()
+    if err != nil { return turn, http.StatusInternalServerError, fmt.Errorf("tts: %w", err) }
+    return turn, http.StatusOK, nil
+}
+
+// voiceResult is the stable response shape for /v1/voice/chat. Audio is
+// carried inline (base64), by URL, or as a separate multipart part.
+type voiceResult struct {
+    Transcript  string          `json:"transcript"`
+    Reply       string          `json:"reply"`
+    ToolCalls   json.RawMessage `json:"tool_calls,omitempty"`
+    Audio       voiceAudio      `json:"audio"`
+    Timing      voiceTiming     `json:"timing"`
+    // Mime and AudioBase64 mirror Audio for clients of the original format.
+    Mime        string          `json:"mime,omitempty"`
+    AudioBase64 string          `json:"audio_base64,omitempty"`
+}
+
+type voiceAudio struct {
+    Mime   string `json:"mime"`
+    Bytes  int    `json:"bytes"`
+    URL    string `json:"url,omitempty"`
+    Base64 string `json:"base64,omitempty"`
+}
+
+func handleVoiceChat(w http.ResponseWriter, r *http.Request, d Dependencies, clips *clipStore) {
+    handleVoice(w, r, d, clips, func(ctx context.Context, path string) (voiceTurn, int, error) {
+        return runVoiceChat(ctx, d, path, r.FormValue("model"), r.FormValue("voice"), nil)
+    })
+}
+
+func handleVoiceTranslate(w http.ResponseWriter, r *http.Request, d Dependencies, clips *clipStore) {
+    source, target := strings.TrimSpace(r.FormValue("source_lang")), strings.TrimSpace(r.FormValue("target_lang"))
+    if target == "" || !checkLang(target) { writeParamError(w, "target_lang", "target_lang must be a language code or name"); return }
+    if !checkLang(source) { writeParamError(w, "source_lang", "source_lang must be a language code or name"); return }
+    handleVoice(w, r, d, clips, func(ctx context.Context, path string) (voiceTurn, int, error) {
+        return runVoiceTranslate(ctx, d, path, r.FormValue("model"), r.FormValue("voice"), source, target)
+    })
+}
+
+// handleVoice saves the uploaded audio, runs it through run and writes the
+// turn in the format the client asked for.
+func handleVoice(w http.ResponseWriter, r *http.Request, d Dependencies, clips *clipStore, run func(ctx context.Context, path string) (voiceTurn, int, error)) {
+    file, hdr, err := r.FormFile("file")
+    if err != nil { file, hdr, err = r.FormFile("audio") }
+    if err != nil { writeParamError(w, "file", "missing form file 'file' or 'audio'"); return }
+    defer file.Close()
+
+    tmpPath, err := d.saveUpload(r.Context(), "voice", hdr.Filename, file)
+    if err != nil { writeServiceError(w, err, http.StatusInternalServerError); return }
+    defer os.Remove(tmpPath)
+
+    turn, status, err := run(r.Context(), tmpPath)
+    if err != nil { writeServiceError(w, err, status); return }
+    res := voiceResult{
+        Transcript: turn.Transcript,
+        Reply:      turn.Reply,
+        ToolCalls:  turn.ToolCalls,
+        Audio:      voiceAudio{Mime: "audio/wav", Bytes: len(turn.Audio)},
+        Timing:     turn.Timing,
+    }
+
+    if strings.Contains(r.Header.Get("Accept"), "multipart/mixed") {
+        mw := multipart.NewWriter(w)
+        w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+        meta, _ := json.Marshal(res)
+        part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}, "Content-Disposition": {`inline; name="metadata"`}})
+        _, _ = part.Write(meta)
+        part, _ = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"audio/wav"}, "Content-Disposition": {`inline; name="audio"; filename="reply.wav"`}})
+        _, _ = part.Write(turn.Audio)
+        _ = mw.Close()
+        return
+    }
+    if r.FormValue("audio_delivery") == "url" {
+        res.Audio.URL = "/v1/voice/audio/" + clips.put(turn.Audio)
+    } else {
+        res.Audio.Base64 = base64.StdEncoding.EncodeToString(turn.Audio)
+        res.Mime, res.AudioBase64 = res.Audio.Mime, res.Audio.Base64
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(res)
+}
+
+// clipTTL is how long audio handed out by URL stays downloadable.
+const clipTTL = 10 * time.Minute
+
+// clipStore keeps synthesized replies in memory for audio_delivery=url.
+type clipStore struct {
+    mu    sync.Mutex
+    clips map[string]clip
+}
+
+type clip struct {
+    data    []byte
+    expires time.Time
+}
+
+func newClipStore() *clipStore { return &clipStore{clips: map[string]clip{}} }
+
+func (s *clipStore) put(data []byte) string {
+    id := newID("clip")
+    now := time.Now()
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for k, c := range s.clips { if now.After(c.expires) { delete(s.clips, k) } }
+    s.clips[id] = clip{data: data, expires: now.Add(clipTTL)}
+    return id
+}
+
+func handleVoiceAudio(w http.ResponseWriter, r *http.Request, clips *clipStore) {
+    clips.mu.Lock()
+    c, ok := clips.clips[r.PathValue("id")]
+    clips.mu.Unlock()
+    if !ok || time.Now().After(c.expires) { writeError(w, "audio not found or expired", http.StatusNotFound); return }
+    w.Header().Set("Content-Type", "audio/wav")
+    _, _ = w.Write(c.data)
+}
+
+// appendVoiceHistory records a completed turn, keeping the most recent messages.
+func appendVoiceHistory(history []llm.Message, turn voiceTurn) []llm.Message {
+    history = append(history,
+        llm.Message{Role: "user", Content: turn.Transcript},
+        llm.Message{Role: "assistant", Content: turn.Reply},
+    )
+    if len(history) > maxVoiceHistory { history = history[len(history)-maxVoiceHistory:] }
+    return history
+}
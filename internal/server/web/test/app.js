@@ -1,25 +1,10 @@
 (() => {
-  const recordBtn = document.getElementById('recordBtn');
-  const transcriptEl = document.getElementById('transcript');
-  const logEl = document.getElementById('log');
-  const embMetaEl = document.getElementById('embMeta');
-  const embPreviewEl = document.getElementById('embPreview');
-  const modelSelect = document.getElementById('modelSelect');
-  const statusEl = document.getElementById('status');
-  const embedBtn = document.getElementById('embedBtn');
-  const embedText = document.getElementById('embedText');
-  const ttsBtn = document.getElementById('ttsBtn');
-  const ttsText = document.getElementById('ttsText');
-  const ttsVoice = document.getElementById('ttsVoice');
-  const ttsAudio = document.getElementById('ttsAudio');
-
-  let recording = false;
-  let audioCtx;
-  let mediaStream;
-  let processor;
-  let input;
-  let buffers = [];
+  const $ = (id) => document.getElementById(id);
+  const logEl = $('log');
+  const statusEl = $('status');
+  const apiKeyEl = $('apiKey');
   const sampleRate = 16000; // target 16kHz mono
+  let caps = null;
 
   function log(msg) {
     logEl.textContent += msg + "\n";
@@ -27,76 +12,149 @@
     statusEl.textContent = msg;
   }
 
-  recordBtn.addEventListener('click', async () => {
-    if (!recording) {
-      transcriptEl.textContent = '';
-      embMetaEl.textContent = '';
-      embPreviewEl.textContent = '';
-      buffers = [];
-      try {
-        await startRecording();
-        recordBtn.textContent = 'Stop Recording';
-        recordBtn.classList.add('recording');
-      } catch (e) {
-        log('Failed to start recording: ' + e.message);
+  // -------- API helpers --------
+
+  apiKeyEl.value = localStorage.getItem('gollmcore.apiKey') || '';
+  apiKeyEl.addEventListener('change', () => { localStorage.setItem('gollmcore.apiKey', apiKeyEl.value); loadCapabilities(); });
+
+  function authHeaders(extra) {
+    const h = Object.assign({}, extra || {});
+    if (apiKeyEl.value) h['Authorization'] = 'Bearer ' + apiKeyEl.value;
+    return h;
+  }
+
+  async function api(path, opts) {
+    opts = opts || {};
+    const headers = authHeaders(opts.json !== undefined ? { 'Content-Type': 'application/json' } : {});
+    const body = opts.json !== undefined ? JSON.stringify(opts.json) : opts.body;
+    const resp = await fetch(path, { method: opts.method || (body ? 'POST' : 'GET'), headers, body });
+    if (!resp.ok) {
+      let msg = (await resp.text()).trim();
+      try { msg = JSON.parse(msg).error.message; } catch {}
+      throw new Error(`${path}: ${resp.status} ${msg}`);
+    }
+    return resp;
+  }
+
+  // readSSE calls onData for every "data:" payload of a text/event-stream response.
+  async function readSSE(resp, onData) {
+    const reader = resp.body.getReader();
+    const dec = new TextDecoder();
+    let buf = '';
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) break;
+      buf += dec.decode(value, { stream: true });
+      let i;
+      while ((i = buf.indexOf('\n\n')) >= 0) {
+        const evt = buf.slice(0, i);
+        buf = buf.slice(i + 2);
+        let name = 'message';
+        const data = [];
+        for (const line of evt.split('\n')) {
+          if (line.startsWith('event:')) name = line.slice(6).trim();
+          else if (line.startsWith('data:')) data.push(line.slice(5).replace(/^ /, ''));
+        }
+        onData(data.join('\n'), name);
       }
-    } else {
-      await stopRecording();
-      recordBtn.textContent = 'Start Recording';
-      recordBtn.classList.remove('recording');
-      log('Encoding audio (WAV)...');
-      const wav = encodeWAV(buffers, sampleRate);
-      const base64 = await blobToBase64(new Blob([wav], { type: 'audio/wav' }));
-      log('Connecting to STT and streaming...');
-      await runSTT(base64);
     }
+  }
+
+  // -------- Tabs and capabilities --------
+
+  document.querySelectorAll('#tabs button').forEach((btn) => {
+    btn.addEventListener('click', () => {
+      document.querySelectorAll('#tabs button').forEach((b) => b.classList.toggle('active', b === btn));
+      document.querySelectorAll('.tab').forEach((t) => t.classList.toggle('active', t.id === 'tab-' + btn.dataset.tab));
+    });
   });
 
-  embedBtn.addEventListener('click', async () => {
-    let text = (embedText.value || '').trim();
-    if (!text) {
-      text = (transcriptEl.textContent || '').trim();
-    }
-    if (!text) {
-      log('Nothing to embed — provide text or record speech first.');
-      return;
+  async function loadCapabilities() {
+    try {
+      caps = await (await api('/v1/capabilities')).json();
+    } catch (e) { log('Capabilities unavailable: ' + e.message); return; }
+    const services = caps.services || {};
+    document.querySelectorAll('#tabs button[data-service]').forEach((btn) => {
+      const v = services[btn.dataset.service];
+      const on = Array.isArray(v) ? v.length > 0 : !!v;
+      btn.disabled = !on;
+      btn.title = on ? '' : 'disabled on this server';
+    });
+    if (caps.stt && caps.stt.default_model) $('modelSelect').value = caps.stt.default_model;
+    if (caps.llm && caps.llm.default_model) $('chatModel').placeholder = caps.llm.default_model + ' (default)';
+    $('chatSession').disabled = !services.sessions;
+    if (Array.isArray(services.pipelines)) {
+      $('pipelineSelect').innerHTML = services.pipelines.map((p) => `<option>${p}</option>`).join('');
     }
-    log('Embedding provided text...');
-    await fetchEmbeddings(text);
-  });
+    log('Connected: ' + Object.keys(services).filter((k) => services[k] && services[k].length !== 0).join(', '));
+    if (caps.streaming && caps.streaming.events) watchEvents();
+  }
 
-  ttsBtn.addEventListener('click', async () => {
-    let text = (ttsText.value || transcriptEl.textContent || '').trim();
-    if (!text) { log('Nothing to speak — provide text or record speech first.'); return; }
-    const voice = ttsVoice.value || 'en_US-amy-medium';
-    log(`Requesting TTS with voice ${voice}...`);
-    await speakWS(text, voice);
-  });
+  // -------- Server events --------
 
-  async function startRecording() {
-    recording = true;
-    mediaStream = await navigator.mediaDevices.getUserMedia({ audio: true });
-    audioCtx = new (window.AudioContext || window.webkitAudioContext)({ sampleRate });
-    const source = audioCtx.createMediaStreamSource(mediaStream);
-    processor = audioCtx.createScriptProcessor(4096, 1, 1);
-    input = source;
-    input.connect(processor);
-    processor.connect(audioCtx.destination);
-    processor.onaudioprocess = (e) => {
-      if (!recording) return;
-      const ch = e.inputBuffer.getChannelData(0);
-      buffers.push(new Float32Array(ch));
-    };
-    log('Recording started');
+  // watchEvents follows /v1/events (reconnecting when it drops) and shows
+  // model and binary downloads as progress bars.
+  let watching = false;
+  async function watchEvents() {
+    if (watching) return;
+    watching = true;
+    const bars = {};
+    for (;;) {
+      try {
+        const resp = await api('/v1/events');
+        await readSSE(resp, (data, name) => {
+          if (name !== 'download.progress') return;
+          const p = JSON.parse(data).data;
+          let row = bars[p.asset];
+          if (!row) {
+            row = bars[p.asset] = document.createElement('div');
+            row.className = 'download';
+            row.innerHTML = '<span></span><progress max="100"></progress><span class="muted"></span>';
+            $('downloadList').appendChild(row);
+          }
+          const [label, bar, info] = row.children;
+          const mb = (n) => (n / 1048576).toFixed(1) + ' MB';
+          label.textContent = p.asset;
+          if (p.total) bar.value = p.percent || 0; else bar.removeAttribute('value');
+          info.textContent = p.error ? 'failed: ' + p.error
+            : p.done ? 'done, ' + mb(p.bytes)
+            : mb(p.bytes) + (p.total ? ' of ' + mb(p.total) : '') + (p.eta_seconds ? `, ${Math.ceil(p.eta_seconds)} s left` : '');
+          $('downloads').hidden = false;
+          if (p.done) {
+            log(p.error ? `Download of ${p.asset} failed: ${p.error}` : `Downloaded ${p.asset}`);
+            setTimeout(() => { row.remove(); if (bars[p.asset] === row) delete bars[p.asset]; $('downloads').hidden = !$('downloadList').children.length; }, 5000);
+          }
+        });
+      } catch (e) { /* reconnect below */ }
+      await new Promise((r) => setTimeout(r, 5000));
+    }
   }
 
-  async function stopRecording() {
-    recording = false;
-    try { processor && processor.disconnect(); } catch {}
-    try { input && input.disconnect(); } catch {}
-    try { mediaStream && mediaStream.getTracks().forEach(t => t.stop()); } catch {}
-    try { audioCtx && audioCtx.close(); } catch {}
-    log('Recording stopped');
+  // -------- Recording --------
+
+  // recorder captures mono 16 kHz float samples from the microphone.
+  function recorder() {
+    let audioCtx, mediaStream, processor, input;
+    const buffers = [];
+    return {
+      buffers,
+      async start() {
+        mediaStream = await navigator.mediaDevices.getUserMedia({ audio: true });
+        audioCtx = new (window.AudioContext || window.webkitAudioContext)({ sampleRate });
+        input = audioCtx.createMediaStreamSource(mediaStream);
+        processor = audioCtx.createScriptProcessor(4096, 1, 1);
+        input.connect(processor);
+        processor.connect(audioCtx.destination);
+        processor.onaudioprocess = (e) => buffers.push(new Float32Array(e.inputBuffer.getChannelData(0)));
+      },
+      stop() {
+        try { processor && processor.disconnect(); } catch {}
+        try { input && input.disconnect(); } catch {}
+        try { mediaStream && mediaStream.getTracks().forEach(t => t.stop()); } catch {}
+        try { audioCtx && audioCtx.close(); } catch {}
+      },
+      wav() { return new Blob([encodeWAV(buffers, sampleRate)], { type: 'audio/wav' }); },
+    };
   }
 
   function flattenBuffers(buffers) {
@@ -145,83 +203,255 @@
     return buffer;
   }
 
-  function blobToBase64(blob) {
-    return new Promise((resolve, reject) => {
-      const reader = new FileReader();
-      reader.onloadend = () => resolve(reader.result.split(',')[1]);
-      reader.onerror = reject;
-      reader.readAsDataURL(blob);
-    });
+  function audioForm(blob, filename, fields) {
+    const fd = new FormData();
+    fd.append('file', blob, filename);
+    for (const [k, v] of Object.entries(fields || {})) if (v) fd.append(k, v);
+    return fd;
   }
 
-  async function runSTT(audioB64) {
-    transcriptEl.textContent = '';
-    log('Connecting WS for STT...');
-    const wsURL = (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/ws/stt';
-    const ws = new WebSocket(wsURL);
-    ws.onopen = () => {
-      log('WS connected, sending audio to transcribe...');
-      ws.send(JSON.stringify({ filename: 'recording.wav', model: modelSelect.value, audio_base64: audioB64, stream: true }));
-    };
-    ws.onmessage = async (ev) => {
-      try {
-        const msg = JSON.parse(ev.data);
-        if (msg.event === 'status' && msg.message) {
-          log(msg.message);
-        } else if (msg.event === 'data') {
-          if (transcriptEl.textContent.length === 0) log('Receiving transcription...');
-          transcriptEl.textContent += msg.text + '\n';
-        } else if (msg.event === 'done') {
-          ws.close();
-          const finalText = transcriptEl.textContent.trim();
-          if (finalText) {
-            log('Transcription complete. Fetching embeddings...');
-            await fetchEmbeddings(finalText);
-          } else {
-            log('Transcription complete. No text.');
+  // -------- Chat --------
+
+  let chatHistory = [];
+
+  function addMsg(container, role, text) {
+    const el = document.createElement('div');
+    el.className = 'msg ' + role;
+    el.textContent = text;
+    container.appendChild(el);
+    container.scrollTop = container.scrollHeight;
+    return el;
+  }
+
+  $('chatReset').addEventListener('click', () => { chatHistory = []; $('chatLog').innerHTML = ''; $('chatMeta').textContent = ''; });
+  $('chatInput').addEventListener('keydown', (e) => { if (e.key === 'Enter' && e.ctrlKey) $('chatSend').click(); });
+
+  $('chatSend').addEventListener('click', async () => {
+    const text = $('chatInput').value.trim();
+    if (!text) return;
+    $('chatInput').value = '';
+    addMsg($('chatLog'), 'user', text);
+    const session = $('chatSession').value.trim();
+    // With a session the server keeps the history; otherwise send it along.
+    const messages = session ? [] : chatHistory.slice();
+    const system = $('chatSystem').value.trim();
+    if (system && !session) messages.unshift({ role: 'system', content: system });
+    messages.push({ role: 'user', content: text });
+    const req = { messages, stream: $('chatStream').checked };
+    if ($('chatModel').value.trim()) req.model = $('chatModel').value.trim();
+    if (session) req.session_id = session;
+
+    const out = addMsg($('chatLog'), 'assistant', '');
+    const started = performance.now();
+    let firstToken = 0;
+    try {
+      const resp = await api('/v1/chat/completions', { json: req });
+      if (req.stream) {
+        await readSSE(resp, (data) => {
+          if (data === '[DONE]') return;
+          const chunk = JSON.parse(data);
+          const delta = chunk.choices && chunk.choices[0] && chunk.choices[0].delta;
+          if (delta && delta.content) {
+            if (!firstToken) firstToken = performance.now() - started;
+            out.textContent += delta.content;
+            $('chatLog').scrollTop = $('chatLog').scrollHeight;
           }
-        } else if (msg.ok && msg.text) {
-          transcriptEl.textContent = msg.text;
-          log('Transcription complete. Fetching embeddings...');
-          await fetchEmbeddings(msg.text);
-        } else if (msg.error) {
-          log('STT error: ' + msg.error);
-        }
-      } catch (e) { log('WS parse error: ' + e.message); }
-    };
-    ws.onerror = (e) => log('WS error: ' + e.message);
+        });
+      } else {
+        const data = await resp.json();
+        out.textContent = (data.choices && data.choices[0] && data.choices[0].message.content) || '';
+        if (data.usage) $('chatMeta').textContent = `tokens: ${data.usage.prompt_tokens} prompt / ${data.usage.completion_tokens} completion`;
+      }
+      chatHistory.push({ role: 'user', content: text }, { role: 'assistant', content: out.textContent });
+      const total = Math.round(performance.now() - started);
+      $('chatMeta').textContent = (firstToken ? `first token ${Math.round(firstToken)} ms, ` : '') + `total ${total} ms`;
+    } catch (e) {
+      out.className = 'msg error';
+      out.textContent = e.message;
+    }
+  });
+
+  // -------- Speech-to-Text --------
+
+  const recordBtn = $('recordBtn');
+  const transcriptEl = $('transcript');
+  const partialEl = $('partial');
+  let sttRec = null;
+  let partialTimer = null;
+  let partialBusy = false;
+
+  recordBtn.addEventListener('click', async () => {
+    if (!sttRec) {
+      transcriptEl.textContent = '';
+      partialEl.textContent = '';
+      sttRec = recorder();
+      try { await sttRec.start(); } catch (e) { sttRec = null; log('Failed to start recording: ' + e.message); return; }
+      recordBtn.textContent = 'Stop Recording';
+      recordBtn.classList.add('recording');
+      log('Recording started');
+      // Live partials: re-transcribe everything recorded so far every couple of seconds.
+      if ($('sttPartials').checked) partialTimer = setInterval(transcribePartial, 2500);
+    } else {
+      clearInterval(partialTimer);
+      sttRec.stop();
+      const blob = sttRec.wav();
+      sttRec = null;
+      recordBtn.textContent = 'Start Recording';
+      recordBtn.classList.remove('recording');
+      log('Recording stopped, transcribing...');
+      await transcribeStream(blob, 'recording.wav');
+    }
+  });
+
+  $('sttFile').addEventListener('change', async (e) => {
+    const f = e.target.files[0];
+    if (!f) return;
+    transcriptEl.textContent = '';
+    partialEl.textContent = '';
+    log(`Transcribing ${f.name}...`);
+    await transcribeStream(f, f.name);
+    e.target.value = '';
+  });
+
+  async function transcribePartial() {
+    if (!sttRec || partialBusy || sttRec.buffers.length === 0) return;
+    partialBusy = true;
+    try {
+      const resp = await api('/v1/audio/transcriptions?model=' + encodeURIComponent($('modelSelect').value), { body: audioForm(sttRec.wav(), 'partial.wav') });
+      const data = await resp.json();
+      if (sttRec) partialEl.textContent = data.text.trim();
+    } catch (e) { log('Partial failed: ' + e.message); }
+    partialBusy = false;
   }
 
-  async function fetchEmbeddings(text) {
-    log('Requesting embeddings...');
-    const resp = await fetch('/v1/embeddings', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ input: text }) });
-    if (!resp.ok) { log('Embeddings error: ' + resp.status); return; }
-    const data = await resp.json();
-    const vec = (data.embeddings && data.embeddings[0]) || [];
-    embMetaEl.textContent = `Model: ${data.model} | Dim: ${vec.length}`;
-    embPreviewEl.textContent = JSON.stringify(vec.slice(0, 16)) + (vec.length > 16 ? ' ...' : '');
-    log('Embeddings received');
+  async function transcribeStream(blob, filename) {
+    const started = performance.now();
+    try {
+      const resp = await api('/v1/audio/transcriptions/stream?model=' + encodeURIComponent($('modelSelect').value), { body: audioForm(blob, filename) });
+      await readSSE(resp, (data, event) => {
+        if (event === 'done') return;
+        transcriptEl.textContent += data + '\n';
+      });
+      partialEl.textContent = '';
+      log(`Transcription complete in ${Math.round(performance.now() - started)} ms`);
+    } catch (e) { log('STT error: ' + e.message); }
   }
 
-  async function speakWS(text, voice) {
-    const wsURL = (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/ws/tts';
-    const ws = new WebSocket(wsURL);
-    ws.onopen = () => {
-      ws.send(JSON.stringify({ text, voice }));
-    };
-    ws.onmessage = async (ev) => {
+  // -------- Text-to-Speech --------
+
+  $('ttsBtn').addEventListener('click', async () => {
+    const text = ($('ttsText').value || transcriptEl.textContent || '').trim();
+    if (!text) { log('Nothing to speak — provide text or record speech first.'); return; }
+    const voice = $('ttsVoice').value.trim();
+    log(`Requesting TTS${voice ? ' with voice ' + voice : ''}...`);
+    const started = performance.now();
+    try {
+      const blob = await (await api('/v1/tts', { json: { text, voice } })).blob();
+      const audio = $('ttsAudio');
+      if (audio.src.startsWith('blob:')) URL.revokeObjectURL(audio.src);
+      audio.src = URL.createObjectURL(blob);
+      audio.play();
+      $('ttsMeta').textContent = `${blob.size} bytes in ${Math.round(performance.now() - started)} ms`;
+      log('TTS audio received');
+    } catch (e) { log('TTS error: ' + e.message); }
+  });
+
+  // -------- Embeddings --------
+
+  $('embedBtn').addEventListener('click', async () => {
+    const lines = $('embedText').value.split('\n').map((l) => l.trim()).filter(Boolean);
+    if (lines.length === 0) { log('Nothing to embed — provide at least one line.'); return; }
+    $('simMatrix').innerHTML = '';
+    try {
+      const data = await (await api('/v1/embeddings', { json: { input: lines } })).json();
+      const vec = (data.embeddings && data.embeddings[0]) || [];
+      $('embMeta').textContent = `Model: ${data.model} | Dim: ${vec.length} | Inputs: ${lines.length}`;
+      $('embPreview').textContent = JSON.stringify(vec.slice(0, 16)) + (vec.length > 16 ? ' ...' : '');
+      if (lines.length > 1) await similarity(lines);
+      log('Embeddings received');
+    } catch (e) { log('Embeddings error: ' + e.message); }
+  });
+
+  async function similarity(lines) {
+    const data = await (await api('/v1/similarity/matrix', { json: { input: lines } })).json();
+    const short = (s) => s.length > 24 ? s.slice(0, 24) + '…' : s;
+    let html = '<tr><th></th>' + lines.map((l) => `<th title="${escapeHTML(l)}">${escapeHTML(short(l))}</th>`).join('') + '</tr>';
+    data.matrix.forEach((row, i) => {
+      html += `<tr><th title="${escapeHTML(lines[i])}">${escapeHTML(short(lines[i]))}</th>`;
+      html += row.map((v) => `<td style="background: rgba(37, 99, 235, ${Math.max(0, v).toFixed(2)}); color: ${v > 0.6 ? '#fff' : '#222'}">${v.toFixed(3)}</td>`).join('');
+      html += '</tr>';
+    });
+    $('simMatrix').innerHTML = html;
+  }
+
+  function escapeHTML(s) {
+    return s.replace(/[&<>"]/g, (c) => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;' }[c]));
+  }
+
+  // -------- Voice Chat --------
+
+  let voiceRec = null;
+  $('voiceRecordBtn').addEventListener('click', async () => {
+    const btn = $('voiceRecordBtn');
+    if (!voiceRec) {
+      voiceRec = recorder();
+      try { await voiceRec.start(); } catch (e) { voiceRec = null; log('Failed to start recording: ' + e.message); return; }
+      btn.textContent = 'Stop and send';
+      btn.classList.add('recording');
+      return;
+    }
+    voiceRec.stop();
+    const blob = voiceRec.wav();
+    voiceRec = null;
+    btn.textContent = 'Hold a conversation: Start';
+    btn.classList.remove('recording');
+    log('Sending voice turn...');
+    try {
+      const data = await (await api('/v1/voice/chat', { body: audioForm(blob, 'turn.wav', { voice: $('voiceVoice').value.trim(), model: $('modelSelect').value }) })).json();
+      addMsg($('voiceLog'), 'user', data.transcript);
+      addMsg($('voiceLog'), 'assistant', data.reply);
+      const audio = $('voiceAudio');
+      audio.src = 'data:' + data.audio.mime + ';base64,' + data.audio.base64;
+      audio.play();
+      const t = data.timing || {};
+      $('voiceMeta').textContent = `stt ${t.stt_ms} ms, llm ${t.llm_ms} ms, tts ${t.tts_ms} ms, total ${t.total_ms} ms`;
+    } catch (e) { addMsg($('voiceLog'), 'error', e.message); }
+  });
+
+  // -------- Pipelines --------
+
+  $('pipelineRun').addEventListener('click', async () => {
+    const name = $('pipelineSelect').value;
+    if (!name) { log('No pipelines configured.'); return; }
+    const out = $('pipelineOut');
+    const f = $('pipelineFile').files[0];
+    try {
+      const opts = f ? { body: audioForm(f, f.name) } : { json: { input: $('pipelineInput').value } };
+      let job = await (await api(`/v1/pipelines/${encodeURIComponent(name)}/run`, opts)).json();
+      while (job.status === 'queued' || job.status === 'running') {
+        out.textContent = JSON.stringify(job, null, 2);
+        await new Promise((r) => setTimeout(r, 1000));
+        job = await (await api('/v1/jobs/' + job.id)).json();
+      }
+      out.textContent = JSON.stringify(job, null, 2);
+      log(`Pipeline ${name}: ${job.status}`);
+    } catch (e) { log('Pipeline error: ' + e.message); }
+  });
+
+  // -------- Server --------
+
+  function showServer(path, asJSON) {
+    return async () => {
       try {
-        const msg = JSON.parse(ev.data);
-        if (msg.ok && msg.audio_base64) {
-          const b64 = msg.audio_base64;
-          ttsAudio.src = 'data:' + (msg.mime || 'audio/wav') + ';base64,' + b64;
-          ttsAudio.play();
-          log('TTS audio received');
-        } else if (msg.error) {
-          log('TTS error: ' + msg.error);
-        }
-      } catch (e) { log('WS parse error: ' + e.message); }
+        const resp = await api(path);
+        $('serverOut').textContent = asJSON ? JSON.stringify(await resp.json(), null, 2) : await resp.text();
+      } catch (e) { $('serverOut').textContent = e.message; }
     };
-    ws.onerror = (e) => log('TTS WS error: ' + e.message);
   }
+  $('healthBtn').addEventListener('click', showServer('/healthz', false));
+  $('capsBtn').addEventListener('click', showServer('/v1/capabilities', true));
+  $('metricsBtn').addEventListener('click', showServer('/metrics', false));
+  $('usageBtn').addEventListener('click', showServer('/admin/usage', true));
+
+  loadCapabilities();
 })();
@@ -0,0 +1,165 @@
+(() => {
+  const $ = (id) => document.getElementById(id);
+  const sampleRate = 16000; // what whisper wants; sent as pcm16
+  const params = new URLSearchParams(location.search);
+  // ?key= lets an embedding page pass a key; otherwise the playground's.
+  const apiKey = params.get('key') || localStorage.getItem('gollmcore.apiKey') || '';
+  let sttPath = '', ttsPath = '';
+  let session = null;
+
+  function status(msg) { $('recStatus').textContent = msg; }
+
+  // notify tells an embedding page about results, e.g. to fill a form.
+  function notify(type, data) {
+    if (window.parent !== window) window.parent.postMessage(Object.assign({ type: 'gollmcore.' + type }, data), '*');
+  }
+
+  // socket opens a WebSocket to path; browsers cannot set headers, so the
+  // key goes in ?token=.
+  function socket(path) {
+    const url = new URL(path, location.href);
+    url.protocol = location.protocol === 'https:' ? 'wss:' : 'ws:';
+    if (apiKey) url.searchParams.set('token', apiKey);
+    const ws = new WebSocket(url);
+    ws.binaryType = 'arraybuffer';
+    return new Promise((resolve, reject) => {
+      ws.onopen = () => resolve(ws);
+      ws.onerror = () => reject(new Error('cannot connect to ' + path));
+    });
+  }
+
+  // next resolves with the next message of ws: parsed JSON for text frames,
+  // an ArrayBuffer for binary ones.
+  function next(ws) {
+    return new Promise((resolve, reject) => {
+      ws.onmessage = (e) => resolve(typeof e.data === 'string' ? JSON.parse(e.data) : e.data);
+      ws.onclose = () => reject(new Error('connection closed'));
+    });
+  }
+
+  function errorText(msg) { return (msg.error && msg.error.message) || msg.error || 'request failed'; }
+
+  async function init() {
+    try {
+      const headers = apiKey ? { Authorization: 'Bearer ' + apiKey } : {};
+      const resp = await fetch('/v1/capabilities', { headers });
+      if (!resp.ok) throw new Error('capabilities: ' + resp.status);
+      const caps = await resp.json();
+      const ws = (caps.streaming && caps.streaming.websocket) || [];
+      sttPath = ws.find((p) => p.endsWith('/stt')) || '';
+      ttsPath = ws.find((p) => p.endsWith('/tts')) || '';
+    } catch (e) { status(e.message); return; }
+    $('recBtn').disabled = !sttPath;
+    $('speakBtn').disabled = !ttsPath;
+    $('recSpeak').disabled = !ttsPath;
+    status(sttPath ? 'Ready' : 'Needs STT and WebSocket endpoints enabled (websocket.enabled)');
+  }
+
+  // -------- Recording --------
+
+  // record streams the microphone to /ws/stt while it runs: a JSON header,
+  // then binary PCM16 frames as the audio arrives, then an empty frame.
+  async function record() {
+    const mediaStream = await navigator.mediaDevices.getUserMedia({ audio: true });
+    let ws;
+    try {
+      ws = await socket(sttPath);
+    } catch (e) { mediaStream.getTracks().forEach((t) => t.stop()); throw e; }
+    const header = { binary: true, format: 'pcm16', sample_rate: sampleRate };
+    if ($('recModel').value.trim()) header.model = $('recModel').value.trim();
+    ws.send(JSON.stringify(header));
+
+    const audioCtx = new (window.AudioContext || window.webkitAudioContext)({ sampleRate });
+    const input = audioCtx.createMediaStreamSource(mediaStream);
+    const processor = audioCtx.createScriptProcessor(4096, 1, 1);
+    let bytes = 0;
+    processor.onaudioprocess = (e) => {
+      const samples = e.inputBuffer.getChannelData(0);
+      const pcm = new Int16Array(samples.length);
+      let peak = 0;
+      for (let i = 0; i < samples.length; i++) {
+        const s = Math.max(-1, Math.min(1, samples[i]));
+        pcm[i] = s < 0 ? s * 0x8000 : s * 0x7FFF;
+        peak = Math.max(peak, Math.abs(s));
+      }
+      if (ws.readyState === WebSocket.OPEN) ws.send(pcm.buffer);
+      bytes += pcm.byteLength;
+      $('recLevel').firstElementChild.style.width = Math.round(peak * 100) + '%';
+      status(`Recording... ${(bytes / 2 / sampleRate).toFixed(1)} s streamed`);
+    };
+    input.connect(processor);
+    processor.connect(audioCtx.destination);
+
+    return {
+      async stop() {
+        try { processor.disconnect(); input.disconnect(); } catch {}
+        mediaStream.getTracks().forEach((t) => t.stop());
+        audioCtx.close();
+        $('recLevel').firstElementChild.style.width = '0';
+        ws.send(new ArrayBuffer(0));
+        status('Transcribing...');
+        try { return await next(ws); } finally { ws.close(); }
+      },
+    };
+  }
+
+  $('recBtn').addEventListener('click', async () => {
+    const btn = $('recBtn');
+    if (!session) {
+      try {
+        session = await record();
+      } catch (e) { status(e.message); return; }
+      btn.textContent = 'Stop';
+      btn.classList.add('recording');
+      return;
+    }
+    btn.disabled = true;
+    btn.textContent = 'Record';
+    btn.classList.remove('recording');
+    const s = session;
+    session = null;
+    try {
+      const msg = await s.stop();
+      if (!msg.ok) throw new Error(errorText(msg));
+      $('recText').value = msg.text;
+      notify('transcript', { text: msg.text, model: msg.model });
+      status(`Transcribed with ${msg.model}`);
+      if ($('recSpeak').checked && ttsPath && msg.text.trim()) await speak(msg.text);
+    } catch (e) { status(e.message); }
+    btn.disabled = false;
+  });
+
+  // -------- Playback --------
+
+  // speak synthesizes text over /ws/tts, which answers with a JSON header
+  // and the WAV file as one binary frame.
+  async function speak(text) {
+    status('Synthesizing...');
+    const ws = await socket(ttsPath);
+    try {
+      const req = { text, binary: true };
+      if ($('recVoice').value.trim()) req.voice = $('recVoice').value.trim();
+      const reply = next(ws);
+      ws.send(JSON.stringify(req));
+      const header = await reply;
+      if (!header.ok) throw new Error(errorText(header));
+      const audio = await next(ws);
+      const player = $('recAudio');
+      if (player.src) URL.revokeObjectURL(player.src);
+      player.src = URL.createObjectURL(new Blob([audio], { type: header.mime }));
+      await player.play().catch(() => {});
+      notify('speech', { bytes: header.bytes });
+      status('Played ' + (header.sample_rate ? `${header.sample_rate} Hz audio` : 'audio'));
+    } finally { ws.close(); }
+  }
+
+  $('speakBtn').addEventListener('click', async () => {
+    const text = $('recText').value.trim();
+    if (!text) { status('Nothing to speak'); return; }
+    $('speakBtn').disabled = true;
+    try { await speak(text); } catch (e) { status(e.message); }
+    $('speakBtn').disabled = false;
+  });
+
+  init();
+})();
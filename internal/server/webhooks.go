@@ -0,0 +1,198 @@
+package server
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "net/url"
+    "strconv"
+    "syscall"
+    "time"
+)
+
+// -------- Webhooks --------
+//
+// Integrators of async jobs (upload transcriptions, pipelines, chat
+// batches) should not have to poll /v1/jobs/{id}. When a job succeeds or
+// fails, each configured URL, and the job's own webhook_url if requests may
+// set one, receives a POST of {"type": "job.succeeded" | "job.failed",
+// "time", "job"}. With a Secret, the X-Gollmcore-Signature header is
+// "sha256=" + hex HMAC-SHA256(secret, timestamp + "." + body), the
+// timestamp being the X-Gollmcore-Timestamp header, so receivers can check
+// the sender and reject replays. Deliveries that fail (no answer, or not
+// 2xx) are retried after each of webhookRetries.
+//
+// A per-job webhook_url is chosen by API callers, so unless AllowPrivate is
+// set it may not reach loopback, private, link-local or unspecified
+// addresses: the server itself (whose admin routes trust loopback) and the
+// network behind it. Literal addresses are refused with the request; names
+// are checked on every dial, after resolution, so DNS cannot rebind a
+// public name to an internal address between the check and the POST.
+
+var webhookRetries = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+
+const webhookTimeout = 10 * time.Second
+
+// Webhooks configures job notifications.
+type Webhooks struct {
+    URLs   []string // notified of every job
+    Secret string   // signs deliveries; empty sends them unsigned
+    // PerJob lets requests name a webhook_url for their job. Off by
+    // default, since it makes the server POST to addresses callers choose.
+    PerJob bool
+    // AllowPrivate lets per-job URLs reach loopback and private networks,
+    // for servers whose callers are all trusted.
+    AllowPrivate bool
+}
+
+func (o Webhooks) enabled() bool { return len(o.URLs) > 0 || o.PerJob }
+
+// checkURL validates a request's webhook_url.
+func (o Webhooks) checkURL(raw string) error {
+    if raw == "" { return nil }
+    if !o.PerJob { return errors.New("per-job webhooks are not enabled on this server") }
+    u, err := url.Parse(raw)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" { return errors.New("webhook_url must be an http or https URL") }
+    if ip := net.ParseIP(u.Hostname()); ip != nil && !o.AllowPrivate && !publicIP(ip) { return errors.New("webhook_url may not point at a loopback, private or link-local address") }
+    return nil
+}
+
+// nonPublicNets are the special-purpose ranges net.IP's predicates miss:
+// "this network", carrier-grade NAT (often cloud-internal), IETF protocol
+// assignments, benchmarking, reserved, local-use NAT64 and Teredo, whose
+// embedded addresses are obfuscated.
+var nonPublicNets = func() []*net.IPNet {
+    var nets []*net.IPNet
+    for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b:1::/48", "2001::/32"} {
+        _, n, _ := net.ParseCIDR(cidr)
+        nets = append(nets, n)
+    }
+    return nets
+}()
+
+var (
+    nat64Prefix = net.ParseIP("64:ff9b::")
+    sixToFour   = net.ParseIP("2002::")
+)
+
+// embeddedIPv4 returns the IPv4 address an IPv6 address carries: NAT64
+// (64:ff9b::/96), 6to4 (2002::/16) and IPv4-compatible (::/96) addresses
+// reach it. IPv4-mapped ones need nothing, net.IP treats them as IPv4.
+func embeddedIPv4(ip net.IP) net.IP {
+    if ip.To4() != nil || len(ip) != net.IPv6len { return nil }
+    switch {
+    case bytes.Equal(ip[:12], nat64Prefix[:12]):
+        return net.IP(ip[12:16]).To4()
+    case ip[0] == sixToFour[0] && ip[1] == sixToFour[1]:
+        return net.IP(ip[2:6]).To4()
+    case bytes.Equal(ip[:12], make([]byte, 12)):
+        return net.IP(ip[12:16]).To4()
+    }
+    return nil
+}
+
+// publicIP reports whether ip may be the target of a per-job webhook.
+func publicIP(ip net.IP) bool {
+    if v4 := embeddedIPv4(ip); v4 != nil && !publicIP(v4) { return false }
+    for _, n := range nonPublicNets {
+        if n.Contains(ip) { return false }
+    }
+    return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// publicOnlyClient returns a webhook client whose connections may only
+// reach public addresses; the check runs on the resolved address of each
+// dial, redirects included.
+func publicOnlyClient() *http.Client {
+    dialer := &net.Dialer{Timeout: webhookTimeout, Control: func(network, address string, _ syscall.RawConn) error {
+        host, _, err := net.SplitHostPort(address)
+        if err != nil { return err }
+        if ip := net.ParseIP(host); ip == nil || !publicIP(ip) { return fmt.Errorf("webhook target %s is not a public address", host) }
+        return nil
+    }}
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.Proxy, transport.DialContext = nil, dialer.DialContext
+    return &http.Client{Timeout: webhookTimeout, Transport: transport}
+}
+
+type webhookEvent struct {
+    Type string    `json:"type"`
+    Time time.Time `json:"time"`
+    Job  Job       `json:"job"`
+}
+
+// notifier returns the jobStore hook delivering finished jobs, or nil.
+func (o Webhooks) notifier() func(Job) {
+    if !o.enabled() { return nil }
+    client, perJob := &http.Client{Timeout: webhookTimeout}, &http.Client{Timeout: webhookTimeout}
+    if !o.AllowPrivate { perJob = publicOnlyClient() }
+    return func(j Job) {
+        ev := webhookEvent{Type: "job." + j.Status, Time: time.Now().UTC(), Job: j}
+        body, err := json.Marshal(ev)
+        if err != nil { log.Printf("webhook for job %s: %v", j.ID, err); return }
+        seen := map[string]bool{}
+        for _, u := range o.URLs {
+            if seen[u] { continue }
+            seen[u] = true
+            go o.deliver(client, u, ev.Type, j.ID, body)
+        }
+        if j.WebhookURL != "" && o.PerJob && !seen[j.WebhookURL] { go o.deliver(perJob, j.WebhookURL, ev.Type, j.ID, body) }
+    }
+}
+
+// deliver posts body to target, retrying failures.
+func (o Webhooks) deliver(client *http.Client, target, typ, jobID string, body []byte) {
+    delivery := newID("whd")
+    for attempt := 0; ; attempt++ {
+        err := o.post(client, target, typ, delivery, body)
+        if err == nil { return }
+        if attempt == len(webhookRetries) {
+            log.Printf("webhook %s for job %s to %s failed after %d attempts: %v", typ, jobID, redactURL(target), attempt+1, err)
+            return
+        }
+        time.Sleep(webhookRetries[attempt])
+    }
+}
+
+func (o Webhooks) post(client *http.Client, target, typ, delivery string, body []byte) error {
+    ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+    if err != nil { return err }
+    ts := strconv.FormatInt(time.Now().Unix(), 10)
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "gollmcore-webhook")
+    req.Header.Set("X-Gollmcore-Event", typ)
+    req.Header.Set("X-Gollmcore-Delivery", delivery)
+    req.Header.Set("X-Gollmcore-Timestamp", ts)
+    if o.Secret != "" { req.Header.Set("X-Gollmcore-Signature", signWebhook(o.Secret, ts, body)) }
+    resp, err := client.Do(req)
+    if err != nil { return err }
+    resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 { return fmt.Errorf("status %d", resp.StatusCode) }
+    return nil
+}
+
+func signWebhook(secret, ts string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(ts + "."))
+    mac.Write(body)
+    return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// redactURL drops the query and credentials of a webhook URL for logs,
+// since receivers often put tokens there.
+func redactURL(raw string) string {
+    u, err := url.Parse(raw)
+    if err != nil { return "(invalid url)" }
+    u.User, u.RawQuery = nil, ""
+    return u.String()
+}
@@ -1,113 +1,269 @@
 package server
 
 import (
+    "bytes"
     "context"
     "encoding/base64"
+    "errors"
     "log"
     "net/http"
     "os"
-    "path/filepath"
     "time"
 
     "github.com/gorilla/websocket"
+
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/tts"
 )
 
 type WSOptions struct {
-    Enable     bool
-    PathPrefix string
+    Enable             bool
+    PathPrefix         string
+    PingInterval       time.Duration // keepalive ping period; two missed pongs drop the peer
+    IdleTimeout        time.Duration // close after this long without application messages
+    MaxMessageBytes    int64         // largest accepted inbound message
+    MaxConnections     int           // across all WS endpoints; 0 = unlimited
+    MaxInflightPerConn int           // concurrent generations per /chat connection
+    AllowedOrigins     []string      // browser origins allowed to connect; empty = any
 }
 
-var upgrader = websocket.Upgrader{ CheckOrigin: func(r *http.Request) bool { return true } }
-
 func RegisterWSRoutes(mux *http.ServeMux, d Dependencies, o WSOptions) {
     if !o.Enable { return }
     prefix := o.PathPrefix
     if prefix == "" { prefix = "/ws" }
+    d = d.withVision().withModeration().withPolicy().withTimeouts().withLoRA().withCaps().withLLMDefaults()
+    keys := d.APIKeys
+    if len(keys) > 0 { keys = append(append([]string{}, keys...), d.AdminKeys...) }
+    hub := newWSHub(o, keys)
+    rt := newRouter(mux)
+    defer rt.done()
 
     if d.Embeddings != nil {
-        mux.HandleFunc(prefix+"/embeddings", func(w http.ResponseWriter, r *http.Request) {
-            conn, err := upgrader.Upgrade(w, r, nil)
-            if err != nil { http.Error(w, err.Error(), http.StatusBadRequest); return }
+        rt.handle("GET "+prefix+"/embeddings", func(w http.ResponseWriter, r *http.Request) {
+            conn, err := hub.upgrade(w, r)
+            if err != nil { return }
             defer conn.Close()
             for {
-                var req struct{ Input any `json:"input"` }
+                var req embeddingsRequest
                 if err := conn.ReadJSON(&req); err != nil { return }
                 inputs := coerceInputsWS(req.Input)
                 if len(inputs) == 0 {
-                    _ = conn.WriteJSON(map[string]any{"error":"no input"})
+                    _ = conn.WriteJSON(wsError(http.StatusBadRequest, "no input"))
+                    continue
+                }
+                opts, err := req.options()
+                if err != nil { _ = conn.WriteJSON(wsError(http.StatusBadRequest, err.Error())); continue }
+                ctx := embeddings.WithOptions(r.Context(), opts)
+                if req.Stream {
+                    model, err := d.embedBatches(ctx, inputs, req.BatchSize, func(b embeddingsBatch) error {
+                        var err error
+                        if b.Embeddings, err = truncateVectors(b.Embeddings, req.Dimensions); err != nil { return err }
+                        return conn.WriteJSON(map[string]any{"event": "data", "model": b.Model, "index": b.Index, "embeddings": b.Embeddings})
+                    })
+                    if err != nil { _ = conn.WriteJSON(wsServiceError(err, http.StatusInternalServerError)); continue }
+                    _ = conn.WriteJSON(map[string]any{"event": "done", "model": model, "count": len(inputs), "usage": d.chargeEmbeddings(r, inputs)})
                     continue
                 }
-                ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
                 vecs, model, err := d.Embeddings.Embed(ctx, inputs)
-                cancel()
-                if err != nil { _ = conn.WriteJSON(map[string]any{"error":err.Error()}); continue }
-                _ = conn.WriteJSON(map[string]any{"ok":true, "model": model, "embeddings": vecs})
+                if err == nil { vecs, err = truncateVectors(vecs, req.Dimensions) }
+                if err != nil { _ = conn.WriteJSON(wsServiceError(err, http.StatusInternalServerError)); continue }
+                _ = conn.WriteJSON(map[string]any{"ok":true, "model": model, "embeddings": vecs, "usage": d.chargeEmbeddings(r, inputs)})
             }
         })
     }
 
     if d.STT != nil {
-        mux.HandleFunc(prefix+"/stt", func(w http.ResponseWriter, r *http.Request) {
-            conn, err := upgrader.Upgrade(w, r, nil)
-            if err != nil { http.Error(w, err.Error(), http.StatusBadRequest); return }
+        rt.handle("GET "+prefix+"/stt", func(w http.ResponseWriter, r *http.Request) {
+            conn, err := hub.upgrade(w, r)
+            if err != nil { return }
             defer conn.Close()
             for {
                 var req struct{
                     Filename   string `json:"filename"`
                     Model      string `json:"model"`
+                    sttRequest
                     AudioB64   string `json:"audio_base64"`
                     Stream     bool   `json:"stream"`
+                    // Binary sends the audio as binary frames after this
+                    // header, ended by an empty frame, instead of base64.
+                    Binary     bool   `json:"binary"`
+                    Format     string `json:"format"`      // binary only: wav (default) | pcm16
+                    SampleRate int    `json:"sample_rate"` // pcm16 only, default 16000
                 }
                 if err := conn.ReadJSON(&req); err != nil { return }
+                var b []byte
+                if req.Binary {
+                    // Read the frames first, so an invalid header does not
+                    // leave them to be parsed as the next request.
+                    b, err = conn.ReadBinary(hub.opts.MaxMessageBytes)
+                    if errors.Is(err, errWSTooLarge) { _ = conn.WriteJSON(wsError(http.StatusRequestEntityTooLarge, err.Error())); continue }
+                    if errors.Is(err, errWSNotBinary) { _ = conn.WriteJSON(wsError(http.StatusBadRequest, err.Error())); return }
+                    if err != nil { return }
+                    switch req.Format {
+                    case "", "wav":
+                    case "pcm16":
+                        if req.SampleRate <= 0 { req.SampleRate = 16000 }
+                        b = pcm16ToWAV(b, req.SampleRate)
+                        if req.Filename == "" { req.Filename = "audio.wav" }
+                    default:
+                        _ = conn.WriteJSON(wsError(http.StatusBadRequest, "unknown format "+req.Format+" (supported: wav, pcm16)"))
+                        continue
+                    }
+                }
                 model := req.Model
                 if model == "" { model = d.STTDefaultModel }
+                if _, err := req.check(); err != nil { _ = conn.WriteJSON(wsError(http.StatusBadRequest, err.Error())); continue }
+                if req.Stream {
+                    // Streamed lines are raw whisper output; see the SSE endpoint.
+                    if req.Postprocess != nil && *req.Postprocess { _ = conn.WriteJSON(wsError(http.StatusBadRequest, "postprocess is not available when streaming")); continue }
+                    no := false
+                    req.Postprocess = &no
+                }
+                if err := d.checkPostprocess(req.sttRequest); err != nil { _ = conn.WriteJSON(wsError(http.StatusBadRequest, err.Error())); continue }
                 // Write audio to temp file
-                b, err := base64.StdEncoding.DecodeString(req.AudioB64)
-                if err != nil { _ = conn.WriteJSON(map[string]any{"error":"invalid base64"}); continue }
-                tmp := filepath.Join(os.TempDir(), "ws-audio-"+sanitizeName(req.Filename))
-                if err := os.WriteFile(tmp, b, 0o644); err != nil { _ = conn.WriteJSON(map[string]any{"error":err.Error()}); continue }
-                defer os.Remove(tmp)
+                if !req.Binary {
+                    if b, err = base64.StdEncoding.DecodeString(req.AudioB64); err != nil { _ = conn.WriteJSON(wsError(http.StatusBadRequest, "invalid base64")); continue }
+                }
+                tmp, err := d.saveUpload(r.Context(), "ws-audio", req.Filename, bytes.NewReader(b))
+                if err != nil { _ = conn.WriteJSON(wsServiceError(err, http.StatusInternalServerError)); continue }
+                if err := d.Caps.checkAudio(tmp); err != nil { os.Remove(tmp); _ = conn.WriteJSON(wsServiceError(err, http.StatusBadRequest)); continue }
                 if req.Stream {
                     _ = conn.WriteJSON(map[string]any{"event":"status", "message":"starting transcription"})
-                    lines, errs := d.STT.TranscribeFileStream(r.Context(), tmp, model)
+                    ctx, cancel := context.WithTimeout(r.Context(), d.Timeouts.STT)
+                    release, err := d.sttLimiter.acquire(ctx)
+                    if err != nil { cancel(); _ = conn.WriteJSON(wsServiceError(deadlineErr(ctx, "stt", d.Timeouts.STT, err), http.StatusInternalServerError)); continue }
+                    end := d.track(ctx, "stt", model)
+                    lines, errs := d.STT.TranscribeFileStream(ctx, tmp, d.fitWhisperModel(model), d.sttOptions(req.sttRequest))
                     for {
                         select {
                         case l, ok := <-lines:
-                            if !ok { _ = conn.WriteJSON(map[string]any{"event":"done"}); goto done }
+                            if !ok { end(0, 0, nil); _ = conn.WriteJSON(map[string]any{"event":"done"}); goto done }
                             _ = conn.WriteJSON(map[string]any{"event":"data", "text": l})
-                        case e := <-errs:
-                            if e != nil { _ = conn.WriteJSON(map[string]any{"error": e.Error()}) }
+                        case e, ok := <-errs:
+                            if !ok { errs = nil; continue }
+                            e = deadlineErr(ctx, "stt", d.Timeouts.STT, e)
+                            end(0, 0, e)
+                            if e != nil { _ = conn.WriteJSON(wsServiceError(e, http.StatusInternalServerError)) }
                             goto done
-                        case <-r.Context().Done():
+                        case <-ctx.Done():
+                            e := deadlineErr(ctx, "stt", d.Timeouts.STT, ctx.Err())
+                            end(0, 0, e)
+                            if errors.Is(e, context.DeadlineExceeded) { _ = conn.WriteJSON(wsServiceError(e, http.StatusGatewayTimeout)) }
                             goto done
                         }
                     }
                 done:
+                    release()
+                    cancel()
+                    os.Remove(tmp)
                     continue
                 }
-                text, err := d.STT.TranscribeFile(r.Context(), tmp, model)
-                if err != nil { _ = conn.WriteJSON(map[string]any{"error": err.Error()}); continue }
-                _ = conn.WriteJSON(map[string]any{"ok":true, "text": text, "model": model})
+                t, err := d.transcribe(r.Context(), tmp, model, req.sttRequest)
+                os.Remove(tmp)
+                if err == nil { err = d.postprocess(r.Context(), req.sttRequest, &t) }
+                if err != nil { _ = conn.WriteJSON(wsServiceError(err, http.StatusInternalServerError)); continue }
+                resp := t.response(model)
+                resp["ok"] = true
+                _ = conn.WriteJSON(resp)
             }
         })
     }
     if d.TTS != nil {
-        mux.HandleFunc(prefix+"/tts", func(w http.ResponseWriter, r *http.Request) {
-            conn, err := upgrader.Upgrade(w, r, nil)
-            if err != nil { http.Error(w, err.Error(), http.StatusBadRequest); return }
+        rt.handle("GET "+prefix+"/tts", func(w http.ResponseWriter, r *http.Request) {
+            conn, err := hub.upgrade(w, r)
+            if err != nil { return }
             defer conn.Close()
             for {
-                var req struct{ Text, Voice string }
+                var req struct{
+                    Text   string `json:"text"`
+                    Voice  string `json:"voice"`
+                    Binary bool   `json:"binary"` // send audio as a binary frame after a JSON header
+                    Format string `json:"format"` // binary only: wav (default) | pcm16
+                }
                 if err := conn.ReadJSON(&req); err != nil { return }
-                if req.Text == "" { _ = conn.WriteJSON(map[string]any{"error":"missing text"}); continue }
+                if req.Text == "" { _ = conn.WriteJSON(wsError(http.StatusBadRequest, "missing text")); continue }
                 audio, err := d.TTS.Synthesize(r.Context(), req.Text, req.Voice)
-                if err != nil { _ = conn.WriteJSON(map[string]any{"error": err.Error()}); continue }
+                if err != nil { _ = conn.WriteJSON(wsServiceError(err, http.StatusInternalServerError)); continue }
+                if req.Binary {
+                    f, pcm, err := tts.ParseWAV(audio)
+                    ok := err == nil && f.SampleRate > 0
+                    header := map[string]any{"ok": true, "type": "audio", "format": "wav", "mime": "audio/wav"}
+                    if ok { header["sample_rate"], header["channels"], header["bits_per_sample"] = int(f.SampleRate), int(f.Channels), int(f.BitsPerSample) }
+                    if req.Format == "pcm16" && ok && f.BitsPerSample == 16 {
+                        audio = pcm
+                        header["format"], header["mime"] = "pcm16", "audio/L16"
+                    }
+                    header["bytes"] = len(audio)
+                    if err := conn.WriteJSON(header); err != nil { return }
+                    if err := conn.WriteMessage(websocket.BinaryMessage, audio); err != nil { return }
+                    continue
+                }
                 // Return as base64 to keep it simple for browser
                 _ = conn.WriteJSON(map[string]any{"ok": true, "mime": "audio/wav", "audio_base64": base64.StdEncoding.EncodeToString(audio)})
             }
         })
     }
-    log.Printf("WebSocket endpoints enabled at %s/{embeddings,stt,tts}", prefix)
+    if d.STT != nil && d.LLM != nil && d.TTS != nil {
+        greeting := newVoiceGreeting(d.VoiceGreeting, d.TTS)
+        if greeting != nil {
+            go func() {
+                if _, err := greeting.get(withPriority(context.Background(), Background)); err != nil { log.Printf("voice greeting: %v", err) }
+            }()
+        }
+        rt.handle("GET "+prefix+"/voice", func(w http.ResponseWriter, r *http.Request) {
+            conn, err := hub.upgrade(w, r)
+            if err != nil { return }
+            defer conn.Close()
+            if err := greeting.send(r.Context(), conn); err != nil { return }
+            var history []llm.Message
+            for {
+                var req struct{
+                    Filename string `json:"filename"`
+                    Model    string `json:"model"`
+                    Voice    string `json:"voice"`
+                    AudioB64 string `json:"audio_base64"`
+                    Reset    bool   `json:"reset"`
+                }
+                if err := conn.ReadJSON(&req); err != nil { return }
+                if req.Reset { history = nil }
+                if req.AudioB64 == "" {
+                    if req.Reset { _ = conn.WriteJSON(map[string]any{"ok": true, "event": "reset"}); continue }
+                    _ = conn.WriteJSON(wsError(http.StatusBadRequest, "missing audio_base64"))
+                    continue
+                }
+                b, err := base64.StdEncoding.DecodeString(req.AudioB64)
+                if err != nil { _ = conn.WriteJSON(wsError(http.StatusBadRequest, "invalid base64")); continue }
+                tmp, err := d.saveUpload(r.Context(), "ws-voice", req.Filename, bytes.NewReader(b))
+                if err != nil { _ = conn.WriteJSON(wsServiceError(err, http.StatusInternalServerError)); continue }
+                turn, status, err := runVoiceChat(r.Context(), d, tmp, req.Model, req.Voice, history)
+                os.Remove(tmp)
+                if err != nil {
+                    msg := wsServiceError(err, status)
+                    msg["transcript"] = turn.Transcript
+                    _ = conn.WriteJSON(msg)
+                    continue
+                }
+                history = appendVoiceHistory(history, turn)
+                _ = conn.WriteJSON(map[string]any{
+                    "ok":           true,
+                    "transcript":   turn.Transcript,
+                    "reply":        turn.Reply,
+                    "mime":         "audio/wav",
+                    "audio_base64": base64.StdEncoding.EncodeToString(turn.Audio),
+                })
+            }
+        })
+    }
+    if d.LLM != nil {
+        rt.handle("GET "+prefix+"/chat", func(w http.ResponseWriter, r *http.Request) {
+            handleWSChat(w, r, d, hub)
+        })
+        rt.handle("GET "+prefix+"/realtime", func(w http.ResponseWriter, r *http.Request) {
+            handleRealtime(w, r, d, hub)
+        })
+    }
+    log.Printf("WebSocket endpoints enabled at %s/{embeddings,stt,tts,chat,voice,realtime}", prefix)
 }
 
 func coerceInputsWS(in any) []string {
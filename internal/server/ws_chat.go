@@ -0,0 +1,95 @@
+package server
+
+import (
+    "context"
+    "net/http"
+    "sync"
+
+    "gollmcore/internal/services/llm"
+)
+
+// handleWSChat streams chat completions over a WebSocket. Each request frame
+// starts one generation, up to MaxInflightPerConn at a time; {"type":"cancel"}
+// aborts the generation with the given id, or all of them when id is empty.
+func handleWSChat(w http.ResponseWriter, r *http.Request, d Dependencies, hub *wsHub) {
+    conn, err := hub.upgrade(w, r)
+    if err != nil { return }
+    defer conn.Close()
+
+    var wmu sync.Mutex
+    send := func(v map[string]any) {
+        wmu.Lock()
+        defer wmu.Unlock()
+        _ = conn.WriteJSON(v)
+    }
+    var mu sync.Mutex
+    inflight := map[string]context.CancelFunc{}
+    defer func() {
+        mu.Lock()
+        for _, c := range inflight { c() }
+        mu.Unlock()
+    }()
+
+    for {
+        var req struct {
+            llm.ChatRequest
+            Type string `json:"type"`
+            ID   string `json:"id"`
+        }
+        if err := conn.ReadJSON(&req); err != nil { return }
+        switch req.Type {
+        case "cancel":
+            mu.Lock()
+            for id, c := range inflight { if req.ID == "" || req.ID == id { c() } }
+            mu.Unlock()
+            continue
+        case "", "chat":
+        default:
+            send(map[string]any{"type": "error", "id": req.ID, "error": newAPIError(http.StatusBadRequest, "", "", "unsupported type: "+req.Type)})
+            continue
+        }
+        if len(req.Messages) == 0 { send(map[string]any{"type": "error", "id": req.ID, "error": newAPIError(http.StatusBadRequest, "", "messages", "messages must not be empty")}); continue }
+        if err := checkImages(req.Messages); err != nil { send(map[string]any{"type": "error", "id": req.ID, "error": newAPIError(http.StatusBadRequest, "", "messages", err.Error())}); continue }
+
+        mu.Lock()
+        if _, dup := inflight[req.ID]; dup {
+            mu.Unlock()
+            send(map[string]any{"type": "error", "id": req.ID, "error": newAPIError(http.StatusConflict, "", "id", "a generation with this id is already in progress")})
+            continue
+        }
+        if len(inflight) >= hub.opts.MaxInflightPerConn {
+            mu.Unlock()
+            send(map[string]any{"type": "error", "id": req.ID, "error": newAPIError(http.StatusTooManyRequests, "", "", "too many generations in progress")})
+            continue
+        }
+        ctx, c := context.WithCancel(r.Context())
+        inflight[req.ID] = c
+        mu.Unlock()
+
+        jobDone := conn.startJob()
+        go func(id string, chatReq llm.ChatRequest) {
+            defer jobDone()
+            defer func() {
+                c()
+                mu.Lock()
+                delete(inflight, id)
+                mu.Unlock()
+            }()
+            resp, err := d.LLM.ChatStream(ctx, chatReq, func(ch llm.ChatChunk) error {
+                for _, choice := range ch.Choices {
+                    if choice.Delta.Content != "" { send(map[string]any{"type": "delta", "id": id, "content": choice.Delta.Content}) }
+                }
+                return nil
+            })
+            if ctx.Err() != nil { send(map[string]any{"type": "cancelled", "id": id}); return }
+            if err != nil {
+                status, code := statusFor(err, http.StatusBadGateway)
+                send(map[string]any{"type": "error", "id": id, "error": newAPIError(status, code, "", err.Error())})
+                return
+            }
+            finish := ""
+            if len(resp.Choices) > 0 { finish = resp.Choices[0].FinishReason }
+            send(map[string]any{"type": "done", "id": id, "content": resp.Text(), "finish_reason": finish, "model": resp.Model})
+        }(req.ID, req.ChatRequest)
+    }
+}
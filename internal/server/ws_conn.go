@@ -0,0 +1,240 @@
+package server
+
+import (
+    "bytes"
+    "errors"
+    "net/http"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+var (
+    errTooManyConns = errors.New("too many websocket connections")
+    errWSAuth       = errors.New("websocket authentication failed")
+    errWSDraining   = errors.New("server is shutting down")
+    errWSNotBinary  = errors.New("expected a binary audio frame")
+    errWSTooLarge   = errors.New("audio is larger than the websocket message limit")
+)
+
+const (
+    defaultWSPingInterval   = 30 * time.Second
+    defaultWSIdleTimeout    = 5 * time.Minute
+    defaultWSMaxMessageSize = 32 << 20
+)
+
+// wsAuthTimeout bounds how long a client may take to send its auth frame.
+const wsAuthTimeout = 10 * time.Second
+
+// wsHub applies WSOptions limits, origin checks and API key auth to every
+// upgraded connection.
+type wsHub struct {
+    opts     WSOptions
+    keys     []string
+    slots    chan struct{} // nil when connections are unlimited
+    upgrader websocket.Upgrader
+}
+
+// withDefaults fills unset limits with the server defaults.
+func (o WSOptions) withDefaults() WSOptions {
+    if o.PathPrefix == "" { o.PathPrefix = "/ws" }
+    if o.PingInterval <= 0 { o.PingInterval = defaultWSPingInterval }
+    if o.IdleTimeout <= 0 { o.IdleTimeout = defaultWSIdleTimeout }
+    if o.MaxMessageBytes <= 0 { o.MaxMessageBytes = defaultWSMaxMessageSize }
+    if o.MaxInflightPerConn <= 0 { o.MaxInflightPerConn = 1 }
+    return o
+}
+
+func newWSHub(o WSOptions, keys []string) *wsHub {
+    o = o.withDefaults()
+    h := &wsHub{opts: o, keys: keys}
+    h.upgrader = websocket.Upgrader{CheckOrigin: h.checkOrigin}
+    if o.MaxConnections > 0 { h.slots = make(chan struct{}, o.MaxConnections) }
+    return h
+}
+
+// wsConn wraps a websocket connection with keepalive pings, a read size
+// limit and an idle timeout measured from the last application message in
+// either direction.
+type wsConn struct {
+    *websocket.Conn
+    hub        *wsHub
+    lastActive atomic.Int64
+    done       chan struct{}
+    closeOnce  sync.Once
+    // Shutdown: once draining is closed the connection is closed with 1001
+    // as soon as it is idle, i.e. waiting for a message with no jobs running.
+    draining   <-chan struct{}
+    drain      atomic.Bool
+    reading    atomic.Bool
+    jobs       atomic.Int32
+}
+
+// checkOrigin allows any origin when none are configured, as well as
+// non-browser clients that send no Origin header.
+func (h *wsHub) checkOrigin(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if len(h.opts.AllowedOrigins) == 0 || origin == "" { return true }
+    for _, o := range h.opts.AllowedOrigins {
+        if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) { return true }
+    }
+    return false
+}
+
+// upgrade authenticates the request, reserves a connection slot and upgrades
+// it. The API key may come from the usual headers or a ?token= query param;
+// otherwise the first frame must be {"type":"auth","token":"<key>"}. On
+// failure the response has already been written or the socket closed.
+func (h *wsHub) upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+    authed := len(h.keys) == 0
+    if !authed {
+        token := requestAPIKey(r)
+        if token == "" { token = r.URL.Query().Get("token") }
+        if token != "" {
+            if !validAPIKey(token, h.keys) { writeError(w, "missing or invalid API key", http.StatusUnauthorized); return nil, errWSAuth }
+            authed = true
+        }
+    }
+    if h.slots != nil {
+        select {
+        case h.slots <- struct{}{}:
+        default:
+            writeError(w, "too many websocket connections", http.StatusServiceUnavailable)
+            return nil, errTooManyConns
+        }
+    }
+    raw, err := h.upgrader.Upgrade(w, r, nil)
+    if err != nil {
+        if h.slots != nil { <-h.slots }
+        return nil, err
+    }
+    c := &wsConn{Conn: raw, hub: h, done: make(chan struct{}), draining: drainingFrom(r.Context())}
+    c.lastActive.Store(time.Now().UnixNano())
+    raw.SetReadLimit(h.opts.MaxMessageBytes)
+    pongWait := 2 * h.opts.PingInterval
+    raw.SetPongHandler(func(string) error { return raw.SetReadDeadline(time.Now().Add(pongWait)) })
+    if !authed {
+        if err := c.authenticate(); err != nil { return nil, err }
+    }
+    go c.keepalive()
+    return c, nil
+}
+
+// authenticate reads the auth frame, closing the connection on failure.
+func (c *wsConn) authenticate() error {
+    var f struct {
+        Type  string `json:"type"`
+        Token string `json:"token"`
+    }
+    _ = c.Conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+    if err := c.Conn.ReadJSON(&f); err != nil || f.Type != "auth" || !validAPIKey(f.Token, c.hub.keys) {
+        msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "authentication required")
+        _ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+        c.Close()
+        return errWSAuth
+    }
+    return c.WriteJSON(map[string]any{"type": "authenticated"})
+}
+
+// ReadJSON waits for the next application message. The read deadline only
+// runs while waiting, so long-running work between messages is not cut off.
+func (c *wsConn) ReadJSON(v any) error {
+    if c.drain.Load() && c.jobs.Load() == 0 { c.goingAway(); return errWSDraining }
+    _ = c.Conn.SetReadDeadline(time.Now().Add(2 * c.hub.opts.PingInterval))
+    c.reading.Store(true)
+    c.interruptIfDrained()
+    err := c.Conn.ReadJSON(v)
+    c.reading.Store(false)
+    c.lastActive.Store(time.Now().UnixNano())
+    if err != nil && c.drain.Load() { c.goingAway(); return errWSDraining }
+    return err
+}
+
+// ReadBinary collects binary frames up to an empty one, which ends the
+// upload. Past max bytes the rest is read and dropped, and errWSTooLarge
+// returned, so the connection stays usable; a text frame is errWSNotBinary.
+func (c *wsConn) ReadBinary(max int64) ([]byte, error) {
+    var buf bytes.Buffer
+    over := false
+    for {
+        _ = c.Conn.SetReadDeadline(time.Now().Add(2 * c.hub.opts.PingInterval))
+        typ, b, err := c.Conn.ReadMessage()
+        c.lastActive.Store(time.Now().UnixNano())
+        if err != nil { return nil, err }
+        if typ != websocket.BinaryMessage { return nil, errWSNotBinary }
+        if len(b) == 0 { break }
+        if over = over || int64(buf.Len()+len(b)) > max; !over { buf.Write(b) }
+    }
+    if over { return nil, errWSTooLarge }
+    return buf.Bytes(), nil
+}
+
+// startJob marks background work (e.g. a generation) that must finish
+// before the connection is closed for shutdown; call the result when done.
+func (c *wsConn) startJob() func() {
+    c.jobs.Add(1)
+    return func() {
+        c.jobs.Add(-1)
+        c.interruptIfDrained()
+    }
+}
+
+// interruptIfDrained wakes a pending read once draining and idle.
+func (c *wsConn) interruptIfDrained() {
+    if c.drain.Load() && c.jobs.Load() == 0 && c.reading.Load() { _ = c.Conn.SetReadDeadline(time.Now()) }
+}
+
+func (c *wsConn) goingAway() {
+    msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+    _ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+func (c *wsConn) WriteJSON(v any) error {
+    c.lastActive.Store(time.Now().UnixNano())
+    return c.Conn.WriteJSON(v)
+}
+
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+    c.lastActive.Store(time.Now().UnixNano())
+    return c.Conn.WriteMessage(messageType, data)
+}
+
+func (c *wsConn) keepalive() {
+    t := time.NewTicker(c.hub.opts.PingInterval)
+    defer t.Stop()
+    for {
+        select {
+        case <-c.done:
+            return
+        case <-c.draining:
+            c.draining = nil
+            c.drain.Store(true)
+            c.interruptIfDrained()
+        case now := <-t.C:
+            if now.Sub(time.Unix(0, c.lastActive.Load())) > c.hub.opts.IdleTimeout {
+                msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "idle timeout")
+                _ = c.WriteControl(websocket.CloseMessage, msg, now.Add(time.Second))
+                c.Close()
+                return
+            }
+            if err := c.WriteControl(websocket.PingMessage, nil, now.Add(10*time.Second)); err != nil {
+                c.Close()
+                return
+            }
+        }
+    }
+}
+
+// Close closes the connection and releases its slot; safe to call twice.
+func (c *wsConn) Close() error {
+    var err error
+    c.closeOnce.Do(func() {
+        close(c.done)
+        err = c.Conn.Close()
+        if c.hub.slots != nil { <-c.hub.slots }
+    })
+    return err
+}
@@ -0,0 +1,122 @@
+// Package audioclass labels recordings as speech, music, noise or silence,
+// so clients can skip transcribing music and pick a whisper language only
+// for audio that holds speech.
+package audioclass
+
+import (
+    "context"
+    "math"
+
+    "gollmcore/internal/dsp"
+    "gollmcore/internal/services/stt"
+)
+
+// Classes are the labels Classify reports, in Result.Scores order.
+var Classes = []string{"speech", "music", "noise", "silence"}
+
+// Result is the classification of one recording.
+type Result struct {
+    Class    string             `json:"class"`
+    Scores   map[string]float64 `json:"scores"`   // share of the audio per class
+    Duration float64            `json:"duration"` // seconds
+}
+
+// Rate and Window are the sample rate and window size, in samples, VADs
+// are given: 32 ms windows of 16 kHz mono audio.
+const (
+    Rate   = 16000
+    Window = 512
+)
+
+// VAD returns the speech probability of each Window-sample window of
+// samples; a short last window is zero-padded.
+type VAD interface {
+    SpeechProbs(ctx context.Context, samples []float32) ([]float32, error)
+}
+
+// Service classifies 16-bit PCM WAV recordings.
+type Service interface {
+    Classify(ctx context.Context, wav []byte) (Result, error)
+}
+
+const (
+    silenceRMS    = 0.003 // -50 dBFS
+    speechProb    = 0.5
+    // Tonal windows (music) concentrate their energy in a few frequencies;
+    // noise spreads it evenly (white noise scores about 0.56).
+    musicFlatness = 0.2
+    // Recordings are silence only when nearly all of them is; speech with
+    // long pauses is still speech.
+    silenceShare  = 0.9
+)
+
+type classifier struct{ vad VAD }
+
+// New returns a Service labelling speech with vad; music, noise and
+// silence are told apart by level and spectral flatness.
+func New(vad VAD) Service { return &classifier{vad: vad} }
+
+func (c *classifier) Classify(ctx context.Context, wav []byte) (Result, error) {
+    x, rate, err := stt.DecodeWAV(wav)
+    if err != nil { return Result{}, err }
+    res := Result{Scores: map[string]float64{}, Duration: float64(len(x)) / float64(rate)}
+    for _, class := range Classes { res.Scores[class] = 0 }
+    x = dsp.Resample(x, rate, Rate)
+    if len(x) == 0 { res.Class = "silence"; res.Scores["silence"] = 1; return res, nil }
+    probs, err := c.vad.SpeechProbs(ctx, x)
+    if err != nil { return Result{}, err }
+
+    n := 0
+    for start := 0; start < len(x); start += Window {
+        w := make([]float32, Window)
+        copy(w, x[start:])
+        i := start / Window
+        switch {
+        case level(w) < silenceRMS:
+            res.Scores["silence"]++
+        case i < len(probs) && probs[i] >= speechProb:
+            res.Scores["speech"]++
+        case flatness(w) < musicFlatness:
+            res.Scores["music"]++
+        default:
+            res.Scores["noise"]++
+        }
+        n++
+    }
+    for class := range res.Scores { res.Scores[class] = math.Round(res.Scores[class]/float64(n)*1000) / 1000 }
+
+    res.Class = "silence"
+    if res.Scores["silence"] < silenceShare {
+        res.Class = "speech"
+        for _, class := range Classes[1:3] {
+            if res.Scores[class] > res.Scores[res.Class] { res.Class = class }
+        }
+    }
+    return res, nil
+}
+
+func level(w []float32) float64 {
+    var sum float64
+    for _, v := range w { sum += float64(v) * float64(v) }
+    return math.Sqrt(sum / float64(len(w)))
+}
+
+// flatness is the spectral flatness of a Hann-windowed power spectrum: the
+// ratio of its geometric to arithmetic mean, from 0 (one tone) to 1.
+func flatness(w []float32) float64 {
+    buf := make([]complex128, len(w))
+    for i, v := range w {
+        hann := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(len(w)-1))
+        buf[i] = complex(float64(v)*hann, 0)
+    }
+    dsp.FFT(buf)
+    var logSum, sum float64
+    bins := buf[1 : len(buf)/2] // skip DC
+    for _, c := range bins {
+        p := real(c)*real(c) + imag(c)*imag(c) + 1e-12
+        logSum += math.Log(p)
+        sum += p
+    }
+    n := float64(len(bins))
+    return math.Exp(logSum/n) / (sum / n)
+}
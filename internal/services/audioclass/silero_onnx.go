@@ -0,0 +1,105 @@
+package audioclass
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "time"
+
+    ort "github.com/yalue/onnxruntime_go"
+
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/tracing"
+)
+
+// Silero VAD v5 (about 2 MB, MIT) run with onnxruntime_go. The model and
+// the ONNX Runtime shared lib are downloaded on first use.
+
+var sileroURLs = []string{
+    "https://github.com/snakers4/silero-vad/raw/master/src/silero_vad/data/silero_vad.onnx",
+}
+
+// sileroContext is the number of samples of the previous window the model
+// expects in front of each new one at 16 kHz.
+const sileroContext = 64
+
+type sileroVAD struct {
+    session *ort.DynamicAdvancedSession
+}
+
+// NewONNX returns a Service using the Silero VAD, keeping its model in
+// modelDir.
+func NewONNX(modelDir string) (Service, error) {
+    if err := os.MkdirAll(modelDir, 0o755); err != nil { return nil, err }
+    if err := onnxrt.Init(); err != nil { return nil, err }
+    modelPath := filepath.Join(modelDir, "silero_vad.onnx")
+    if _, err := os.Stat(modelPath); err != nil {
+        if err := onnxrt.TryDownload(sileroURLs, modelPath, 3, 60*time.Second); err != nil { return nil, err }
+    }
+    sess, err := onnxrt.NewSession(modelPath, []string{"input", "state", "sr"}, []string{"output", "stateN"}, onnxrt.Options{})
+    if err != nil { return nil, err }
+    onnxrt.SessionOpened("silero-vad")
+    return New(&sileroVAD{session: sess}), nil
+}
+
+func (v *sileroVAD) SpeechProbs(ctx context.Context, samples []float32) (probs []float32, err error) {
+    _, span := tracing.Stage(ctx, "audioclass.vad")
+    defer func() { span.End(err) }()
+    st, err := newSileroSteps()
+    if err != nil { return nil, err }
+    defer st.destroy()
+    in := st.input.GetData()
+    for start := 0; start < len(samples); start += Window {
+        if err := ctx.Err(); err != nil { return nil, err }
+        copy(in, in[Window:]) // keep the last sileroContext samples
+        chunk := in[sileroContext:]
+        for i := range chunk { chunk[i] = 0 }
+        copy(chunk, samples[start:])
+        p, err := st.step(v.session)
+        if err != nil { return nil, err }
+        probs = append(probs, p)
+    }
+    return probs, nil
+}
+
+// sileroSteps holds the tensors of one SpeechProbs call. They are allocated
+// once and reused for every window: the input is rewritten in place and the
+// two state tensors swap roles each step, so an hour of audio costs five
+// native allocations instead of four per 32 ms window. A session is shared
+// by concurrent calls, so the tensors cannot live on sileroVAD.
+type sileroSteps struct {
+    input     *ort.Tensor[float32]
+    sr        *ort.Scalar[int64]
+    prob      *ort.Tensor[float32]
+    state     *ort.Tensor[float32] // fed to the next step
+    nextState *ort.Tensor[float32] // written by the next step
+}
+
+func newSileroSteps() (*sileroSteps, error) {
+    s := &sileroSteps{}
+    var err error
+    if s.input, err = ort.NewEmptyTensor[float32](ort.NewShape(1, sileroContext+Window)); err != nil { return nil, err }
+    if s.sr, err = ort.NewScalar(int64(Rate)); err != nil { s.destroy(); return nil, err }
+    if s.prob, err = ort.NewEmptyTensor[float32](ort.NewShape(1, 1)); err != nil { s.destroy(); return nil, err }
+    if s.state, err = ort.NewEmptyTensor[float32](ort.NewShape(2, 1, 128)); err != nil { s.destroy(); return nil, err }
+    if s.nextState, err = ort.NewEmptyTensor[float32](ort.NewShape(2, 1, 128)); err != nil { s.destroy(); return nil, err }
+    return s, nil
+}
+
+// step scores the window in s.input and advances the model state.
+func (s *sileroSteps) step(sess *ort.DynamicAdvancedSession) (float32, error) {
+    if err := sess.Run([]ort.Value{s.input, s.state, s.sr}, []ort.Value{s.prob, s.nextState}); err != nil { return 0, err }
+    s.state, s.nextState = s.nextState, s.state
+    p := s.prob.GetData()
+    if len(p) == 0 { return 0, errors.New("unexpected silero output") }
+    return p[0], nil
+}
+
+func (s *sileroSteps) destroy() {
+    if s.input != nil { s.input.Destroy() }
+    if s.sr != nil { s.sr.Destroy() }
+    if s.prob != nil { s.prob.Destroy() }
+    if s.state != nil { s.state.Destroy() }
+    if s.nextState != nil { s.nextState.Destroy() }
+}
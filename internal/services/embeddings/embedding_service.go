@@ -14,26 +14,47 @@ type Service interface {
     Embed(ctx context.Context, inputs []string) ([][]float32, string, error)
 }
 
+// TokenCounter is implemented by services that can say how many model
+// tokens a batch of inputs uses, including special tokens and after
+// truncation. Wrappers around a Service should pass it through.
+type TokenCounter interface {
+    CountTokens(inputs []string) int
+}
+
+// CountTokens returns the number of tokens s uses for inputs, or an
+// estimate of about four characters per token when s cannot count them.
+func CountTokens(s Service, inputs []string) int {
+    if c, ok := s.(TokenCounter); ok { return c.CountTokens(inputs) }
+    n := 0
+    for _, in := range inputs { n += (len(in) + 3) / 4 }
+    return n
+}
+
 type Config struct {
     ModelName string
+    // Defaults apply to calls whose context carries no Options.
+    Defaults  Options
 }
 
 // MiniLM L6-v2 compatible, deterministic embedding (384-dim) with no external deps.
 // This is a heuristic approximation suitable for testing and offline use.
+// It has no token vectors, so Options.Pooling makes no difference.
 type miniLMCompat struct {
     modelName string
     dim       int
+    defaults  Options
 }
 
 func New(cfg Config) Service {
     // Force model to all-MiniLM-L6-v2 and 384 dims
-    return &miniLMCompat{modelName: "all-MiniLM-L6-v2", dim: 384}
+    return &miniLMCompat{modelName: "all-MiniLM-L6-v2", dim: 384, defaults: cfg.Defaults}
 }
 
-func (h *miniLMCompat) Embed(_ context.Context, inputs []string) ([][]float32, string, error) {
+func (h *miniLMCompat) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    _, norm := OptionsFrom(ctx).resolve(h.defaults)
     out := make([][]float32, len(inputs))
     for i, s := range inputs {
-        out[i] = h.embedOne(s)
+        out[i] = h.embedOne(s, norm)
     }
     return out, h.modelName, nil
 }
@@ -41,7 +62,7 @@ func (h *miniLMCompat) Embed(_ context.Context, inputs []string) ([][]float32, s
 // Use Go RE2 Unicode classes with braces; fallback handled in code.
 var wordRE = regexp.MustCompile(`\p{L}+|\p{N}+`)
 
-func (h *miniLMCompat) embedOne(text string) []float32 {
+func (h *miniLMCompat) embedOne(text string, norm bool) []float32 {
     vec := make([]float32, h.dim)
     tokens := wordRE.FindAllString(strings.ToLower(text), -1)
     if len(tokens) == 0 {
@@ -83,11 +104,12 @@ func (h *miniLMCompat) embedOne(text string) []float32 {
     }
 
     // l2 normalize
-    var norm float64
-    for _, v := range vec { norm += float64(v*v) }
-    norm = math.Sqrt(norm)
-    if norm > 0 {
-        inv := float32(1.0/ norm)
+    var length float64
+    for _, v := range vec { length += float64(v*v) }
+    length = math.Sqrt(length)
+    if length > 0 {
+        if !norm { return vec }
+        inv := float32(1.0/ length)
         for i := range vec { vec[i] *= inv }
     } else {
         // Ensure non-zero output deterministically
@@ -0,0 +1,107 @@
+package embeddings
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "strconv"
+)
+
+// Pooling is how a model's token vectors are combined into one vector.
+type Pooling string
+
+const (
+    PoolMean Pooling = "mean" // average of the tokens, weighted by the attention mask
+    PoolCLS  Pooling = "cls"  // the first ([CLS]) token
+    PoolMax  Pooling = "max"  // per-dimension maximum over the tokens
+)
+
+// ParsePooling checks a pooling name; "" is allowed and means the default.
+func ParsePooling(s string) (Pooling, error) {
+    switch p := Pooling(s); p {
+    case "", PoolMean, PoolCLS, PoolMax:
+        return p, nil
+    }
+    return "", fmt.Errorf("unknown pooling %q (supported: mean, cls, max)", s)
+}
+
+// Options shape the vectors Embed returns. Unset fields fall back to the
+// service's defaults: mean pooling, L2-normalized.
+type Options struct {
+    Pooling   Pooling
+    Normalize *bool
+}
+
+// Or fills the fields o leaves unset from def.
+func (o Options) Or(def Options) Options {
+    if o.Pooling == "" { o.Pooling = def.Pooling }
+    if o.Normalize == nil { o.Normalize = def.Normalize }
+    return o
+}
+
+func (o Options) resolve(def Options) (Pooling, bool) {
+    o = o.Or(def)
+    if o.Pooling == "" { o.Pooling = PoolMean }
+    return o.Pooling, o.Normalize == nil || *o.Normalize
+}
+
+// String identifies the options, e.g. for grouping identical calls.
+func (o Options) String() string {
+    n := "default"
+    if o.Normalize != nil { n = strconv.FormatBool(*o.Normalize) }
+    return string(o.Pooling) + "/" + n
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a context whose Embed calls use o. Options travel in
+// the context so the wrappers around a Service need not know about them.
+func WithOptions(ctx context.Context, o Options) context.Context {
+    return context.WithValue(ctx, optionsKey{}, o)
+}
+
+// OptionsFrom returns the options set on ctx, or the zero Options.
+func OptionsFrom(ctx context.Context) Options {
+    o, _ := ctx.Value(optionsKey{}).(Options)
+    return o
+}
+
+// pool combines the seq token vectors of width h in hidden, skipping
+// tokens whose mask is 0.
+func pool(hidden []float32, mask []int64, seq, h int, p Pooling) []float32 {
+    vec := make([]float32, h)
+    switch p {
+    case PoolCLS:
+        copy(vec, hidden[:h])
+    case PoolMax:
+        first := true
+        for j := 0; j < seq; j++ {
+            if mask[j] == 0 { continue }
+            row := hidden[j*h : (j+1)*h]
+            for d, v := range row {
+                if first || v > vec[d] { vec[d] = v }
+            }
+            first = false
+        }
+    default:
+        var count float32
+        for j := 0; j < seq; j++ {
+            if mask[j] == 0 { continue }
+            for d, v := range hidden[j*h : (j+1)*h] { vec[d] += v }
+            count++
+        }
+        if count > 0 {
+            for d := range vec { vec[d] /= count }
+        }
+    }
+    return vec
+}
+
+// normalize scales v to unit length in place; a zero vector is left as is.
+func normalize(v []float32) {
+    var norm float64
+    for _, x := range v { norm += float64(x) * float64(x) }
+    if norm == 0 { return }
+    inv := float32(1 / math.Sqrt(norm))
+    for i := range v { v[i] *= inv }
+}
@@ -0,0 +1,380 @@
+package embeddings
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "os"
+    "path/filepath"
+    "strings"
+    "unicode"
+
+    "golang.org/x/text/unicode/norm"
+)
+
+// -------- Tokenizers --------
+//
+// Two vocabularies are supported: BERT WordPiece (vocab.txt, or a
+// tokenizer.json with a WordPiece model) as used by all-MiniLM-L6-v2, and
+// SentencePiece Unigram (tokenizer.json) as used by the XLM-R based
+// paraphrase-multilingual models. Both start from NFKC-normalized text, so
+// full-width forms, ligatures and compatibility characters map onto the
+// vocabulary, and never cut a piece across two scripts.
+
+// Tokenizer turns text into vocabulary ids.
+type Tokenizer interface {
+    // Tokens returns the vocabulary pieces of text, without special tokens.
+    Tokens(text string) []string
+    // Encode returns the ids of text wrapped in the model's start and end
+    // tokens, truncated to maxLen, and padded to maxLen with the mask
+    // marking real tokens.
+    Encode(text string, maxLen int) (ids, mask []int64)
+}
+
+// LoadTokenizer reads a vocab.txt (uncased WordPiece) or a tokenizer.json.
+func LoadTokenizer(path string) (Tokenizer, error) {
+    if filepath.Ext(path) == ".json" { return loadTokenizerJSON(path) }
+    return loadWordPiece(path)
+}
+
+// encodeIDs adds start/end tokens, truncates and pads.
+func encodeIDs(pieces []int, start, end, pad, maxLen int) ([]int64, []int64) {
+    seq := append([]int{start}, pieces...)
+    if len(seq) > maxLen-1 { seq = seq[:maxLen-1] }
+    seq = append(seq, end)
+    ids := make([]int64, maxLen)
+    mask := make([]int64, maxLen)
+    for i := range ids { ids[i] = int64(pad) }
+    for i, v := range seq { ids[i] = int64(v); mask[i] = 1 }
+    return ids, mask
+}
+
+// -------- Pre-tokenization --------
+
+// scriptClass groups runes whose pieces may be joined. Han, Hiragana and
+// Katakana share a class and punctuation and symbols get their own;
+// digits, combining marks and joiners (class 0) never start a new part.
+func scriptClass(r rune) int {
+    switch {
+    case unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200d':
+        return 0
+    case unicode.Is(unicode.Latin, r):
+        return 1
+    case unicode.Is(unicode.Cyrillic, r):
+        return 2
+    case unicode.Is(unicode.Greek, r):
+        return 3
+    case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || r == '\u30fc':
+        return 4
+    case unicode.Is(unicode.Hangul, r):
+        return 5
+    case unicode.Is(unicode.Arabic, r):
+        return 6
+    case unicode.Is(unicode.Hebrew, r):
+        return 7
+    case unicode.Is(unicode.Devanagari, r):
+        return 8
+    case unicode.Is(unicode.Thai, r):
+        return 9
+    case unicode.IsLetter(r):
+        return 10
+    default:
+        return 11 // punctuation, symbols, emoji
+    }
+}
+
+// splitScripts cuts word where the script changes.
+func splitScripts(word string) []string {
+    var out []string
+    start, prev := 0, -1
+    for i, r := range word {
+        c := scriptClass(r)
+        if c == 0 { continue }
+        if prev >= 0 && c != prev { out = append(out, word[start:i]); start = i }
+        prev = c
+    }
+    return append(out, word[start:])
+}
+
+// isCJK reports ideographs BERT tokenizes one character at a time.
+func isCJK(r rune) bool {
+    return (r >= 0x4E00 && r <= 0x9FFF) || (r >= 0x3400 && r <= 0x4DBF) || (r >= 0x20000 && r <= 0x2A6DF) ||
+        (r >= 0x2A700 && r <= 0x2CEAF) || (r >= 0xF900 && r <= 0xFAFF) || (r >= 0x2F800 && r <= 0x2FA1F)
+}
+
+// isBertPunct matches BERT's punctuation test: Unicode P* plus the ASCII
+// symbol ranges.
+func isBertPunct(r rune) bool {
+    if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) { return true }
+    return unicode.IsPunct(r)
+}
+
+// cleanText drops control characters and maps all whitespace to spaces.
+func cleanText(s string) string {
+    return strings.Map(func(r rune) rune {
+        switch {
+        case r == 0 || r == unicode.ReplacementChar:
+            return -1
+        case unicode.IsSpace(r):
+            return ' '
+        case unicode.IsControl(r) || unicode.Is(unicode.Cf, r) && r != '\u200d':
+            return -1
+        }
+        return r
+    }, s)
+}
+
+// -------- WordPiece --------
+
+type wordPiece struct {
+    vocab      map[string]int
+    names      []string
+    lower      bool
+    unkID      int
+    clsID      int
+    sepID      int
+    padID      int
+}
+
+func loadWordPiece(path string) (*wordPiece, error) {
+    b, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    lines := strings.Split(string(b), "\n")
+    vp := make(map[string]int, len(lines))
+    for i, line := range lines {
+        tok := strings.TrimSpace(line)
+        if tok == "" { continue }
+        if _, ok := vp[tok]; !ok { vp[tok] = i }
+    }
+    return newWordPiece(vp, "[UNK]", true), nil
+}
+
+func newWordPiece(vp map[string]int, unk string, lower bool) *wordPiece {
+    get := func(tok string, def int) int { if id, ok := vp[tok]; ok { return id }; return def }
+    w := &wordPiece{
+        vocab: vp,
+        lower: lower,
+        unkID: get(unk, 100),
+        clsID: get("[CLS]", 101),
+        sepID: get("[SEP]", 102),
+        padID: get("[PAD]", 0),
+    }
+    w.names = make([]string, 0, len(vp))
+    for tok, id := range vp {
+        for len(w.names) <= id { w.names = append(w.names, "") }
+        w.names[id] = tok
+    }
+    return w
+}
+
+// basicTokens is BERT's basic tokenizer on NFKC text: whitespace split,
+// punctuation and CJK ideographs as single tokens, and for uncased
+// vocabularies lowercasing with accents stripped.
+func (w *wordPiece) basicTokens(s string) []string {
+    s = cleanText(norm.NFKC.String(s))
+    if w.lower {
+        s = strings.ToLower(s)
+        s = strings.Map(func(r rune) rune { if unicode.Is(unicode.Mn, r) { return -1 }; return r }, norm.NFD.String(s))
+    }
+    var out []string
+    var b strings.Builder
+    flush := func() { if b.Len() > 0 { out = append(out, splitScripts(b.String())...); b.Reset() } }
+    for _, r := range s {
+        switch {
+        case r == ' ':
+            flush()
+        case isBertPunct(r) || isCJK(r):
+            flush()
+            out = append(out, string(r))
+        default:
+            b.WriteRune(r)
+        }
+    }
+    flush()
+    return out
+}
+
+func (w *wordPiece) ids(text string) []int {
+    var out []int
+    for _, tok := range w.basicTokens(text) { out = append(out, w.tokenizeWord(tok)...) }
+    return out
+}
+
+func (w *wordPiece) Tokens(text string) []string {
+    ids := w.ids(text)
+    out := make([]string, len(ids))
+    for i, id := range ids {
+        if id < len(w.names) { out[i] = w.names[id] }
+    }
+    return out
+}
+
+func (w *wordPiece) Encode(text string, maxLen int) ([]int64, []int64) {
+    return encodeIDs(w.ids(text), w.clsID, w.sepID, w.padID, maxLen)
+}
+
+// tokenizeWord is greedy longest-match-first WordPiece over runes; words
+// longer than 100 runes or with an unmatched remainder become [UNK].
+func (w *wordPiece) tokenizeWord(tok string) []int {
+    runes := []rune(tok)
+    if len(runes) == 0 { return nil }
+    if len(runes) > 100 { return []int{w.unkID} }
+    var out []int
+    for start := 0; start < len(runes); {
+        end := len(runes)
+        id := -1
+        for ; end > start; end-- {
+            sub := string(runes[start:end])
+            if start > 0 { sub = "##" + sub }
+            if vid, ok := w.vocab[sub]; ok { id = vid; break }
+        }
+        if id < 0 { return []int{w.unkID} }
+        out = append(out, id)
+        start = end
+    }
+    return out
+}
+
+// -------- SentencePiece Unigram --------
+
+const metaspace = "▁"
+
+type unigram struct {
+    vocab    map[string]int
+    pieces   []string
+    scores   []float64
+    maxBytes int
+    unkScore float64
+    unkID    int
+    bosID    int
+    eosID    int
+    padID    int
+}
+
+// tokenizeWord finds the most probable segmentation of word (Viterbi over
+// piece log-probabilities). Runes no piece covers become a single unknown
+// token per run.
+func (u *unigram) tokenizeWord(word string) []int {
+    n := len(word)
+    best := make([]float64, n+1)
+    from := make([]int, n+1)
+    ids := make([]int, n+1)
+    for i := 1; i <= n; i++ { best[i] = math.Inf(-1) }
+    for i := 0; i < n; i++ {
+        if math.IsInf(best[i], -1) || !utf8Start(word[i]) { continue }
+        covered := false
+        for j := i + 1; j <= n && j-i <= u.maxBytes; j++ {
+            if j < n && !utf8Start(word[j]) { continue }
+            id, ok := u.vocab[word[i:j]]
+            if !ok { continue }
+            if s := best[i] + u.scores[id]; s > best[j] { best[j], from[j], ids[j] = s, i, id }
+            if !covered { covered = j == i+runeLen(word[i:]) }
+        }
+        if !covered {
+            j := i + runeLen(word[i:])
+            if s := best[i] + u.unkScore; s > best[j] { best[j], from[j], ids[j] = s, i, u.unkID }
+        }
+    }
+    var out []int
+    for j := n; j > 0; j = from[j] { out = append(out, ids[j]) }
+    for l, r := 0, len(out)-1; l < r; l, r = l+1, r-1 { out[l], out[r] = out[r], out[l] }
+    // SentencePiece fuses consecutive unknowns.
+    fused := out[:0]
+    for _, id := range out {
+        if id == u.unkID && len(fused) > 0 && fused[len(fused)-1] == u.unkID { continue }
+        fused = append(fused, id)
+    }
+    return fused
+}
+
+func utf8Start(b byte) bool { return b&0xC0 != 0x80 }
+
+func runeLen(s string) int {
+    for i := 1; i < len(s); i++ {
+        if utf8Start(s[i]) { return i }
+    }
+    return len(s)
+}
+
+// ids applies the Metaspace pre-tokenizer ("▁" marks a word start) and
+// segments each word, split further at script changes.
+func (u *unigram) ids(text string) []int {
+    var out []int
+    for _, word := range strings.Fields(cleanText(norm.NFKC.String(text))) {
+        for i, part := range splitScripts(word) {
+            if i == 0 { part = metaspace + part }
+            out = append(out, u.tokenizeWord(part)...)
+        }
+    }
+    return out
+}
+
+func (u *unigram) Tokens(text string) []string {
+    ids := u.ids(text)
+    out := make([]string, len(ids))
+    for i, id := range ids { out[i] = u.pieces[id] }
+    return out
+}
+
+func (u *unigram) Encode(text string, maxLen int) ([]int64, []int64) {
+    return encodeIDs(u.ids(text), u.bosID, u.eosID, u.padID, maxLen)
+}
+
+// -------- tokenizer.json --------
+
+// tokenizerFile is the subset of the Hugging Face tokenizers format read here.
+type tokenizerFile struct {
+    Normalizer *struct {
+        Type        string `json:"type"`
+        Lowercase   bool   `json:"lowercase"`
+        Normalizers []struct {
+            Type string `json:"type"`
+        } `json:"normalizers"`
+    } `json:"normalizer"`
+    Model struct {
+        Type     string          `json:"type"`
+        Vocab    json.RawMessage `json:"vocab"`
+        UnkID    *int            `json:"unk_id"`
+        UnkToken string          `json:"unk_token"`
+    } `json:"model"`
+}
+
+func loadTokenizerJSON(path string) (Tokenizer, error) {
+    b, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    var f tokenizerFile
+    if err := json.Unmarshal(b, &f); err != nil { return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err) }
+    switch f.Model.Type {
+    case "WordPiece":
+        var vocab map[string]int
+        if err := json.Unmarshal(f.Model.Vocab, &vocab); err != nil { return nil, fmt.Errorf("wordpiece vocab: %w", err) }
+        unk := f.Model.UnkToken
+        if unk == "" { unk = "[UNK]" }
+        lower := f.Normalizer != nil && f.Normalizer.Lowercase
+        return newWordPiece(vocab, unk, lower), nil
+    case "Unigram":
+        var vocab [][2]any
+        if err := json.Unmarshal(f.Model.Vocab, &vocab); err != nil { return nil, fmt.Errorf("unigram vocab: %w", err) }
+        u := &unigram{vocab: make(map[string]int, len(vocab)), pieces: make([]string, len(vocab)), scores: make([]float64, len(vocab))}
+        minScore := 0.0
+        for i, e := range vocab {
+            p, _ := e[0].(string)
+            s, _ := e[1].(float64)
+            u.pieces[i], u.scores[i] = p, s
+            if _, dup := u.vocab[p]; !dup { u.vocab[p] = i }
+            if len(p) > u.maxBytes { u.maxBytes = len(p) }
+            if s < minScore { minScore = s }
+        }
+        u.unkScore = minScore - 10
+        get := func(tok string, def int) int { if id, ok := u.vocab[tok]; ok { return id }; return def }
+        u.unkID = get("<unk>", 3)
+        if f.Model.UnkID != nil { u.unkID = *f.Model.UnkID }
+        u.bosID, u.padID, u.eosID = get("<s>", 0), get("<pad>", 1), get("</s>", 2)
+        // Special tokens must never be produced from text.
+        for _, id := range []int{u.unkID, u.bosID, u.padID, u.eosID} {
+            if id < len(u.pieces) { delete(u.vocab, u.pieces[id]) }
+        }
+        return u, nil
+    }
+    return nil, fmt.Errorf("unsupported tokenizer model %q", f.Model.Type)
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// ----- Multimodal content -----
+//
+// OpenAI messages carry content either as a string or as an array of typed
+// parts (text, image_url). Message keeps the text of either form in Content
+// so text-only code paths keep working, and the parts in Parts so images
+// reach the upstream unchanged.
+
+// ContentPart is one element of an array-form message content.
+type ContentPart struct {
+    Type     string    `json:"type"` // text | image_url
+    Text     string    `json:"text,omitempty"`
+    ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL is an http(s) URL or a data:image/...;base64 URL.
+type ImageURL struct {
+    URL    string `json:"url"`
+    Detail string `json:"detail,omitempty"`
+}
+
+// HasImages reports whether the message carries image parts.
+func (m Message) HasImages() bool {
+    for _, p := range m.Parts {
+        if p.Type == "image_url" { return true }
+    }
+    return false
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+    type plain Message
+    if len(m.Parts) == 0 { return json.Marshal(plain(m)) }
+    return json.Marshal(struct {
+        plain
+        Content []ContentPart `json:"content"`
+    }{plain(m), m.Parts})
+}
+
+func (m *Message) UnmarshalJSON(b []byte) error {
+    type plain Message
+    var aux struct {
+        plain
+        Content json.RawMessage `json:"content"`
+    }
+    if err := json.Unmarshal(b, &aux); err != nil { return err }
+    *m = Message(aux.plain)
+    c := bytes.TrimSpace(aux.Content)
+    switch {
+    case len(c) == 0 || string(c) == "null":
+    case c[0] == '"':
+        return json.Unmarshal(c, &m.Content)
+    case c[0] == '[':
+        if err := json.Unmarshal(c, &m.Parts); err != nil { return err }
+        var text []string
+        for _, p := range m.Parts {
+            if p.Type == "text" { text = append(text, p.Text) }
+        }
+        m.Content = strings.Join(text, "\n")
+    default:
+        return fmt.Errorf("message content must be a string or an array of parts")
+    }
+    return nil
+}
@@ -0,0 +1,187 @@
+package llm
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "net/url"
+    "sync"
+    "time"
+)
+
+// ----- Failover -----
+//
+// Failover serves chats from an ordered chain of OpenAI-compatible
+// servers, e.g. a local llama-server, then Ollama, then a hosted API, so a
+// crashed local model does not take chat down. A chat goes to the first
+// server whose circuit is closed; one that cannot be reached (connection
+// refused, DNS, TLS) or answers 5xx passes the chat down the chain. Errors
+// a server does answer with, such as 400 or 429, are returned as they are:
+// the next server would not fix them.
+//
+// Each server has a circuit breaker: breakerThreshold failures in a row
+// open it, and the chain skips the server for breakerCooldown. After that
+// it is pinged, and only a server that answers gets a chat again; that
+// chat's outcome closes or reopens the circuit.
+
+const (
+    breakerThreshold = 3
+    breakerCooldown  = 30 * time.Second
+)
+
+// Failover is an LLM backend that fails over along a chain of servers.
+type Failover struct {
+    links []*link
+}
+
+// link is one server of the chain and its circuit.
+type link struct {
+    svc       *Service
+    name      string
+    mu        sync.Mutex
+    failures  int // in a row
+    openUntil time.Time
+    probing   bool
+    lastErr   error
+}
+
+// CircuitState describes one server of a failover chain for /v1/status.
+type CircuitState struct {
+    Name  string `json:"name"`  // the server's host
+    Model string `json:"model,omitempty"`
+    State string `json:"state"` // "closed" (in use) or "open" (skipped)
+    Error string `json:"error,omitempty"`
+}
+
+// NewFailover tries chain in order; the first is the primary, whose
+// default model the chain reports.
+func NewFailover(chain ...*Service) *Failover {
+    f := &Failover{}
+    for _, s := range chain {
+        name := s.baseURL
+        if u, err := url.Parse(s.baseURL); err == nil && u.Host != "" { name = u.Host }
+        f.links = append(f.links, &link{svc: s, name: name})
+    }
+    return f
+}
+
+// Model returns the primary's default model.
+func (f *Failover) Model() string { return f.links[0].svc.Model() }
+
+// Ping succeeds when any server of the chain answers.
+func (f *Failover) Ping(ctx context.Context) error {
+    var errs []error
+    for _, l := range f.links {
+        err := l.svc.Ping(ctx)
+        if err == nil { return nil }
+        errs = append(errs, fmt.Errorf("%s: %w", l.name, err))
+    }
+    return errors.Join(errs...)
+}
+
+// Circuits reports the state of every server in the chain.
+func (f *Failover) Circuits() []CircuitState {
+    out := make([]CircuitState, len(f.links))
+    for i, l := range f.links {
+        l.mu.Lock()
+        out[i] = CircuitState{Name: l.name, Model: l.svc.Model(), State: "closed"}
+        if l.failures >= breakerThreshold { out[i].State = "open" }
+        if l.lastErr != nil { out[i].Error = l.lastErr.Error() }
+        l.mu.Unlock()
+    }
+    return out
+}
+
+func (f *Failover) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+    last := errors.New("every llm server of the failover chain is down")
+    for i, l := range f.links {
+        if !l.allow(ctx) { continue }
+        out, err := l.svc.Chat(ctx, f.request(i, req))
+        if ctx.Err() != nil { return out, err }
+        l.record(err)
+        if err == nil || !unavailable(err) { return out, err }
+        last = err
+    }
+    return nil, last
+}
+
+// ChatStream fails over only while no chunk has been sent; a stream cut
+// off halfway returns the error.
+func (f *Failover) ChatStream(ctx context.Context, req ChatRequest, onChunk func(ChatChunk) error) (*ChatResponse, error) {
+    last := errors.New("every llm server of the failover chain is down")
+    for i, l := range f.links {
+        if !l.allow(ctx) { continue }
+        started := false
+        out, err := l.svc.ChatStream(ctx, f.request(i, req), func(c ChatChunk) error {
+            started = true
+            if onChunk == nil { return nil }
+            return onChunk(c)
+        })
+        if ctx.Err() != nil { return out, err }
+        l.record(err)
+        if err == nil || started || !unavailable(err) { return out, err }
+        last = err
+    }
+    return nil, last
+}
+
+// request is req for the i-th server: the servers after the primary get
+// their own default model, since the request names one of the primary's.
+func (f *Failover) request(i int, req ChatRequest) ChatRequest {
+    if i > 0 { req.Model = f.links[i].svc.Model() }
+    return req
+}
+
+// Footprint, Adapters and SetAdapters are the primary's, so the resource
+// guard and /admin/lora keep working.
+func (f *Failover) Footprint(ctx context.Context, model string) (size uint64, loaded, ok bool) {
+    return f.links[0].svc.Footprint(ctx, model)
+}
+
+func (f *Failover) Adapters(ctx context.Context) ([]Adapter, error) { return f.links[0].svc.Adapters(ctx) }
+
+func (f *Failover) SetAdapters(ctx context.Context, scales []LoRA) error {
+    return f.links[0].svc.SetAdapters(ctx, scales)
+}
+
+// unavailable reports whether err means the server is down rather than
+// rejecting the request.
+func unavailable(err error) bool {
+    var uerr *url.Error
+    var serr *StatusError
+    return errors.As(err, &uerr) || errors.As(err, &serr) && serr.Code >= 500
+}
+
+// allow reports whether the chain should try l: always while its circuit
+// is closed; once the cooldown is over, if it answers a ping.
+func (l *link) allow(ctx context.Context) bool {
+    l.mu.Lock()
+    if l.failures < breakerThreshold { l.mu.Unlock(); return true }
+    if time.Now().Before(l.openUntil) || l.probing { l.mu.Unlock(); return false }
+    l.probing = true
+    l.mu.Unlock()
+    err := l.svc.Ping(ctx)
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.probing = false
+    if err != nil { l.openUntil, l.lastErr = time.Now().Add(breakerCooldown), err }
+    return err == nil
+}
+
+// record updates l's circuit with the outcome of a chat.
+func (l *link) record(err error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if err == nil || !unavailable(err) {
+        if l.failures >= breakerThreshold { log.Printf("llm failover: %s is back", l.name) }
+        l.failures, l.lastErr = 0, nil
+        return
+    }
+    l.failures++
+    l.lastErr = err
+    if l.failures >= breakerThreshold {
+        if l.failures == breakerThreshold { log.Printf("llm failover: %s is down (%v); skipping it for %s", l.name, err, breakerCooldown) }
+        l.openUntil = time.Now().Add(breakerCooldown)
+    }
+}
@@ -0,0 +1,305 @@
+package llm
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "gollmcore/internal/tracing"
+)
+
+// Service talks to an OpenAI-compatible chat completions server running
+// locally (llama-server, Ollama, LM Studio, ...).
+type Service struct {
+    baseURL string
+    model   string
+    apiKey  string
+    client  *http.Client
+
+    sizesMu sync.Mutex
+    sizes   map[string]uint64
+    sizesAt time.Time
+}
+
+type Message struct {
+    Role       string          `json:"role"`
+    // Content is the message text; for array-form content it joins the
+    // text parts (see Parts).
+    Content    string          `json:"content"`
+    // Parts holds array-form content (text and images) as received.
+    Parts      []ContentPart   `json:"-"`
+    Name       string          `json:"name,omitempty"`
+    ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+    ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type ChatRequest struct {
+    Model          string          `json:"model,omitempty"`
+    Messages       []Message       `json:"messages"`
+    Temperature    *float64        `json:"temperature,omitempty"`
+    TopP           *float64        `json:"top_p,omitempty"`
+    MaxTokens      *int            `json:"max_tokens,omitempty"`
+    Stop           []string        `json:"stop,omitempty"`
+    Stream         bool            `json:"stream,omitempty"`
+    Tools          json.RawMessage `json:"tools,omitempty"`
+    ToolChoice     json.RawMessage `json:"tool_choice,omitempty"`
+    ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+    // LoRA sets adapter scales for this request (llama-server only).
+    LoRA           []LoRA          `json:"lora,omitempty"`
+}
+
+type Choice struct {
+    Index        int     `json:"index"`
+    Message      Message `json:"message"`
+    FinishReason string  `json:"finish_reason"`
+}
+
+type Usage struct {
+    PromptTokens     int `json:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens"`
+    TotalTokens      int `json:"total_tokens"`
+}
+
+type ChatResponse struct {
+    ID      string   `json:"id"`
+    Object  string   `json:"object"`
+    Created int64    `json:"created"`
+    Model   string   `json:"model"`
+    Choices []Choice `json:"choices"`
+    Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Text returns the content of the first choice.
+func (r *ChatResponse) Text() string {
+    if r == nil || len(r.Choices) == 0 { return "" }
+    return r.Choices[0].Message.Content
+}
+
+type ChunkChoice struct {
+    Index        int     `json:"index"`
+    Delta        Message `json:"delta"`
+    FinishReason *string `json:"finish_reason"`
+}
+
+type ChatChunk struct {
+    ID      string        `json:"id"`
+    Object  string        `json:"object"`
+    Created int64         `json:"created"`
+    Model   string        `json:"model"`
+    Choices []ChunkChoice `json:"choices"`
+}
+
+func New(baseURL, model, apiKey string) *Service {
+    return &Service{
+        baseURL: strings.TrimRight(baseURL, "/"),
+        model:   model,
+        apiKey:  apiKey,
+        // Generations are bounded by the request context, not a client timeout.
+        client: &http.Client{},
+    }
+}
+
+// Model returns the configured default model name.
+func (s *Service) Model() string { return s.model }
+
+// Ping checks that the upstream answers. It asks for /models, but any
+// response short of a server or authentication error counts, since not
+// every OpenAI-compatible server lists its models.
+func (s *Service) Ping(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/models", nil)
+    if err != nil { return err }
+    if s.apiKey != "" { req.Header.Set("Authorization", "Bearer "+s.apiKey) }
+    resp, err := s.client.Do(req)
+    if err != nil { return fmt.Errorf("llm upstream unreachable: %w", err) }
+    resp.Body.Close()
+    if resp.StatusCode >= 500 || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden { return fmt.Errorf("llm upstream returned %s", resp.Status) }
+    return nil
+}
+
+// Chat performs a non-streaming chat completion.
+func (s *Service) Chat(ctx context.Context, req ChatRequest) (out *ChatResponse, err error) {
+    _, span := tracing.Stage(ctx, "llm.inference")
+    defer func() { span.End(err) }()
+    req.Stream = false
+    resp, err := s.post(ctx, req)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    out = &ChatResponse{}
+    if err := json.NewDecoder(resp.Body).Decode(out); err != nil { return nil, fmt.Errorf("decode llm response: %w", err) }
+    return out, nil
+}
+
+// ChatStream performs a streaming chat completion, invoking onChunk for every
+// chunk received, and returns the aggregated response once the stream ends.
+func (s *Service) ChatStream(ctx context.Context, req ChatRequest, onChunk func(ChatChunk) error) (_ *ChatResponse, err error) {
+    _, span := tracing.Stage(ctx, "llm.inference")
+    defer func() { span.End(err) }()
+    req.Stream = true
+    resp, err := s.post(ctx, req)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+
+    out := &ChatResponse{Object: "chat.completion", Choices: []Choice{{Message: Message{Role: "assistant"}}}}
+    var text strings.Builder
+    var calls []ToolCall
+    scan := bufio.NewScanner(resp.Body)
+    scan.Buffer(make([]byte, 64*1024), 4*1024*1024)
+    for scan.Scan() {
+        line := strings.TrimSpace(scan.Text())
+        if !strings.HasPrefix(line, "data:") { continue }
+        data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+        if data == "[DONE]" { break }
+        var chunk ChatChunk
+        if err := json.Unmarshal([]byte(data), &chunk); err != nil { return nil, fmt.Errorf("decode llm chunk: %w", err) }
+        if out.ID == "" { out.ID, out.Created, out.Model = chunk.ID, chunk.Created, chunk.Model }
+        for _, c := range chunk.Choices {
+            if c.Index != 0 { continue }
+            text.WriteString(c.Delta.Content)
+            if len(c.Delta.ToolCalls) > 0 { calls = mergeToolCalls(calls, c.Delta.ToolCalls) }
+            if c.FinishReason != nil { out.Choices[0].FinishReason = *c.FinishReason }
+        }
+        if onChunk != nil {
+            if err := onChunk(chunk); err != nil { return nil, err }
+        }
+    }
+    if err := scan.Err(); err != nil { return nil, err }
+    out.Choices[0].Message.Content = text.String()
+    if len(calls) > 0 { out.Choices[0].Message.ToolCalls, _ = json.Marshal(calls) }
+    return out, nil
+}
+
+// ToolCall is one function call requested by the model.
+type ToolCall struct {
+    ID       string `json:"id"`
+    Type     string `json:"type"`
+    Function struct {
+        Name      string `json:"name"`
+        Arguments string `json:"arguments"`
+    } `json:"function"`
+}
+
+// DecodeToolCalls decodes the tool calls of m.
+func (m Message) DecodeToolCalls() ([]ToolCall, error) {
+    if len(m.ToolCalls) == 0 || string(m.ToolCalls) == "null" { return nil, nil }
+    var calls []ToolCall
+    if err := json.Unmarshal(m.ToolCalls, &calls); err != nil { return nil, fmt.Errorf("decode tool calls: %w", err) }
+    return calls, nil
+}
+
+// mergeToolCalls folds streamed tool call deltas into calls: each delta
+// names the call by index and carries a piece of its arguments.
+func mergeToolCalls(calls []ToolCall, raw json.RawMessage) []ToolCall {
+    var deltas []struct {
+        Index int `json:"index"`
+        ToolCall
+    }
+    if json.Unmarshal(raw, &deltas) != nil { return calls }
+    for _, d := range deltas {
+        for len(calls) <= d.Index { calls = append(calls, ToolCall{Type: "function"}) }
+        c := &calls[d.Index]
+        if d.ID != "" { c.ID = d.ID }
+        if d.Type != "" { c.Type = d.Type }
+        c.Function.Name += d.Function.Name
+        c.Function.Arguments += d.Function.Arguments
+    }
+    return calls
+}
+
+func (s *Service) post(ctx context.Context, req ChatRequest) (*http.Response, error) {
+    if req.Model == "" { req.Model = s.model }
+    body, err := json.Marshal(req)
+    if err != nil { return nil, err }
+    hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/chat/completions", bytes.NewReader(body))
+    if err != nil { return nil, err }
+    hreq.Header.Set("Content-Type", "application/json")
+    tracing.Inject(ctx, hreq.Header)
+    if s.apiKey != "" { hreq.Header.Set("Authorization", "Bearer "+s.apiKey) }
+    resp, err := s.client.Do(hreq)
+    if err != nil { return nil, fmt.Errorf("llm request failed: %w", err) }
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        resp.Body.Close()
+        return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(msg))}
+    }
+    return resp, nil
+}
+
+// StatusError is an upstream's answer with a non-2xx status.
+type StatusError struct {
+    Code   int
+    Status string
+    Body   string
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("llm upstream returned %s: %s", e.Status, e.Body) }
+
+// ----- Model footprint (Ollama) -----
+
+// Footprint reports the size of model on a local Ollama upstream and
+// whether Ollama already has it loaded. ok is false for remote upstreams
+// and servers without Ollama's /api/tags (llama-server, LM Studio, ...).
+func (s *Service) Footprint(ctx context.Context, model string) (size uint64, loaded, ok bool) {
+    root, local := s.ollamaRoot()
+    if !local { return 0, false, false }
+    if model == "" { model = s.model }
+    sizes := s.modelSizes(ctx, root)
+    size, ok = sizes[model]
+    if !ok { size, ok = sizes[model+":latest"] }
+    if !ok { return 0, false, false }
+    var ps struct{ Models []struct{ Name, Model string } `json:"models"` }
+    if s.getJSON(ctx, root+"/api/ps", &ps) == nil {
+        for _, m := range ps.Models {
+            if m.Name == model || m.Model == model || m.Name == model+":latest" { loaded = true }
+        }
+    }
+    return size, loaded, true
+}
+
+// ollamaRoot strips the /v1 suffix from the base URL and reports whether
+// the upstream runs on this host.
+func (s *Service) ollamaRoot() (string, bool) {
+    u, err := url.Parse(s.baseURL)
+    if err != nil { return "", false }
+    host := u.Hostname()
+    ip := net.ParseIP(host)
+    if host != "localhost" && (ip == nil || !ip.IsLoopback()) { return "", false }
+    return strings.TrimSuffix(s.baseURL, "/v1"), true
+}
+
+// modelSizes lists /api/tags, cached for a minute; an upstream without it
+// yields an empty map.
+func (s *Service) modelSizes(ctx context.Context, root string) map[string]uint64 {
+    s.sizesMu.Lock()
+    defer s.sizesMu.Unlock()
+    if s.sizes != nil && time.Since(s.sizesAt) < time.Minute { return s.sizes }
+    var tags struct{ Models []struct{ Name string; Size uint64 } `json:"models"` }
+    s.sizes = map[string]uint64{}
+    if s.getJSON(ctx, root+"/api/tags", &tags) == nil {
+        for _, m := range tags.Models { s.sizes[m.Name] = m.Size }
+    }
+    s.sizesAt = time.Now()
+    return s.sizes
+}
+
+func (s *Service) getJSON(ctx context.Context, url string, v any) error {
+    ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil { return err }
+    resp, err := s.client.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return fmt.Errorf("%s: %s", url, resp.Status) }
+    return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+
+    "gollmcore/internal/tracing"
+)
+
+// ----- LoRA adapters (llama-server) -----
+//
+// llama-server loads adapters at startup (--lora FILE, repeated; add
+// --lora-init-without-apply to start them at scale 0). Their global scales
+// can be changed at runtime with /lora-adapters, and a request's "lora"
+// field applies its own scales for that request only.
+
+// LoRA selects a loaded adapter by id with a scale (0 disables it).
+type LoRA struct {
+    ID    int     `json:"id"`
+    Scale float64 `json:"scale"`
+}
+
+// Adapter is an adapter as llama-server reports it.
+type Adapter struct {
+    ID    int     `json:"id"`
+    Path  string  `json:"path"`
+    Scale float64 `json:"scale"`
+}
+
+// Adapters lists the adapters loaded by the upstream and their global scales.
+func (s *Service) Adapters(ctx context.Context) ([]Adapter, error) {
+    var out []Adapter
+    if err := s.loraCall(ctx, http.MethodGet, nil, &out); err != nil { return nil, err }
+    return out, nil
+}
+
+// SetAdapters sets global adapter scales; adapters left out are set to 0.
+func (s *Service) SetAdapters(ctx context.Context, scales []LoRA) error {
+    if scales == nil { scales = []LoRA{} }
+    return s.loraCall(ctx, http.MethodPost, scales, nil)
+}
+
+func (s *Service) loraCall(ctx context.Context, method string, in, out any) error {
+    var body io.Reader
+    if in != nil {
+        b, err := json.Marshal(in)
+        if err != nil { return err }
+        body = bytes.NewReader(b)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.baseURL, "/v1")+"/lora-adapters", body)
+    if err != nil { return err }
+    req.Header.Set("Content-Type", "application/json")
+    tracing.Inject(ctx, req.Header)
+    if s.apiKey != "" { req.Header.Set("Authorization", "Bearer "+s.apiKey) }
+    resp, err := s.client.Do(req)
+    if err != nil { return fmt.Errorf("llm request failed: %w", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return fmt.Errorf("llm upstream returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+    }
+    if out == nil { return nil }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
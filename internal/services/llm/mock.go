@@ -0,0 +1,74 @@
+package llm
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Mock is an LLM backend for tests and CI: it needs no upstream and
+// answers deterministically, so consumers can test against canned
+// replies. Chat replies "Mock reply to: <last user message>"; requests
+// with a response_format get "{}".
+type Mock struct {
+    model string
+}
+
+// NewMock returns the mock LLM backend reporting model as its name.
+func NewMock(model string) *Mock {
+    if model == "" { model = "mock" }
+    return &Mock{model: model}
+}
+
+func (m *Mock) Model() string { return m.model }
+
+// Ping always succeeds.
+func (m *Mock) Ping(context.Context) error { return nil }
+
+// reply is the canned answer to req.
+func (m *Mock) reply(req ChatRequest) string {
+    if len(req.ResponseFormat) > 0 { return "{}" }
+    last := ""
+    for _, msg := range req.Messages {
+        if msg.Role == "user" { last = msg.Content }
+    }
+    return "Mock reply to: " + last
+}
+
+func (m *Mock) response(req ChatRequest, text string) *ChatResponse {
+    model := req.Model
+    if model == "" { model = m.model }
+    prompt := 0
+    for _, msg := range req.Messages { prompt += (len(msg.Content) + 3) / 4 }
+    completion := len(strings.Fields(text))
+    return &ChatResponse{
+        ID:      fmt.Sprintf("mock-%d", time.Now().UnixNano()),
+        Object:  "chat.completion",
+        Created: time.Now().Unix(),
+        Model:   model,
+        Choices: []Choice{{Message: Message{Role: "assistant", Content: text}, FinishReason: "stop"}},
+        Usage:   &Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+    }
+}
+
+func (m *Mock) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+    if err := ctx.Err(); err != nil { return nil, err }
+    return m.response(req, m.reply(req)), nil
+}
+
+// ChatStream sends the reply one word per chunk.
+func (m *Mock) ChatStream(ctx context.Context, req ChatRequest, onChunk func(ChatChunk) error) (*ChatResponse, error) {
+    out := m.response(req, m.reply(req))
+    words := strings.SplitAfter(out.Text(), " ")
+    for i, w := range words {
+        if err := ctx.Err(); err != nil { return nil, err }
+        ch := ChatChunk{ID: out.ID, Object: "chat.completion.chunk", Created: out.Created, Model: out.Model, Choices: []ChunkChoice{{Delta: Message{Content: w}}}}
+        if i == 0 { ch.Choices[0].Delta.Role = "assistant" }
+        if i == len(words)-1 { stop := "stop"; ch.Choices[0].FinishReason = &stop }
+        if onChunk != nil {
+            if err := onChunk(ch); err != nil { return nil, err }
+        }
+    }
+    return out, nil
+}
@@ -0,0 +1,90 @@
+package speaker
+
+import (
+    "math"
+
+    "gollmcore/internal/dsp"
+)
+
+// Kaldi-compatible 80-bin log mel filterbank features, the input the
+// WeSpeaker models were trained on: 25 ms Povey-windowed frames every
+// 10 ms, pre-emphasis 0.97, 20 Hz to Nyquist, and per-utterance mean
+// normalization.
+
+const (
+    melBins     = 80
+    frameLen    = 400 // 25 ms at 16 kHz
+    frameShift  = 160 // 10 ms
+    fftSize     = 512
+    preemphasis = 0.97
+    lowFreq     = 20
+)
+
+func mel(f float64) float64 { return 1127 * math.Log(1+f/700) }
+
+// melBanks returns, per mel bin, the weight of each FFT bin.
+func melBanks() [][]float64 {
+    lo, hi := mel(lowFreq), mel(Rate/2)
+    delta := (hi - lo) / (melBins + 1)
+    banks := make([][]float64, melBins)
+    for b := range banks {
+        left, center, right := lo+float64(b)*delta, lo+float64(b+1)*delta, lo+float64(b+2)*delta
+        w := make([]float64, fftSize/2)
+        for i := range w {
+            m := mel(float64(i) * Rate / fftSize)
+            switch {
+            case m > left && m <= center:
+                w[i] = (m - left) / (center - left)
+            case m > center && m < right:
+                w[i] = (right - m) / (right - center)
+            }
+        }
+        banks[b] = w
+    }
+    return banks
+}
+
+// fbank returns frames x melBins features of 16 kHz samples in [-1, 1).
+func fbank(x []float32) [][]float32 {
+    if len(x) < frameLen { return nil }
+    banks := melBanks()
+    window := make([]float64, frameLen)
+    for i := range window { window[i] = math.Pow(0.5-0.5*math.Cos(2*math.Pi*float64(i)/(frameLen-1)), 0.85) }
+    n := 1 + (len(x)-frameLen)/frameShift
+    feats := make([][]float32, n)
+    frame := make([]float64, frameLen)
+    buf := make([]complex128, fftSize)
+    for f := range feats {
+        var mean float64
+        for i := range frame {
+            frame[i] = float64(x[f*frameShift+i]) * 32768 // Kaldi works on 16-bit sample values
+            mean += frame[i]
+        }
+        mean /= frameLen
+        for i := range frame { frame[i] -= mean }
+        for i := frameLen - 1; i > 0; i-- { frame[i] -= preemphasis * frame[i-1] }
+        frame[0] -= preemphasis * frame[0]
+        for i := range buf {
+            buf[i] = 0
+            if i < frameLen { buf[i] = complex(frame[i]*window[i], 0) }
+        }
+        dsp.FFT(buf)
+        out := make([]float32, melBins)
+        for b, w := range banks {
+            var e float64
+            for i, wi := range w {
+                if wi == 0 { continue }
+                e += wi * (real(buf[i])*real(buf[i]) + imag(buf[i])*imag(buf[i]))
+            }
+            out[b] = float32(math.Log(math.Max(e, 1.1920929e-07)))
+        }
+        feats[f] = out
+    }
+    for b := 0; b < melBins; b++ {
+        var mean float32
+        for _, f := range feats { mean += f[b] }
+        mean /= float32(n)
+        for _, f := range feats { f[b] -= mean }
+    }
+    return feats
+}
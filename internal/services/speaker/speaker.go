@@ -0,0 +1,204 @@
+// Package speaker enrolls voices as speaker embeddings and verifies or
+// identifies the speaker of new recordings against them.
+package speaker
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "os"
+    "regexp"
+    "sort"
+    "sync"
+    "time"
+
+    "gollmcore/internal/dsp"
+    "gollmcore/internal/services/stt"
+)
+
+var (
+    ErrNotFound = errors.New("speaker not found")
+    ErrTooShort = errors.New("need at least 1 second of audio")
+)
+
+// Rate is the sample rate embedders are given.
+const Rate = 16000
+
+// DefaultThreshold is the cosine similarity above which two recordings are
+// taken to be the same speaker.
+const DefaultThreshold = 0.5
+
+// Embedder maps 16 kHz mono audio to a speaker embedding.
+type Embedder interface {
+    Embed(ctx context.Context, samples []float32) ([]float32, error)
+}
+
+// Speaker is an enrolled voice. Embedding is the normalized mean of the
+// Samples recordings enrolled for it.
+type Speaker struct {
+    ID        string    `json:"id"`
+    Name      string    `json:"name,omitempty"`
+    Samples   int       `json:"samples"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Match is a speaker's cosine similarity to a recording.
+type Match struct {
+    ID    string  `json:"id"`
+    Name  string  `json:"name,omitempty"`
+    Score float64 `json:"score"`
+}
+
+// Service keeps enrolled speakers in memory and in path, when set, so they
+// survive restarts.
+type Service struct {
+    emb       Embedder
+    path      string
+    threshold float64
+    mu        sync.RWMutex
+    speakers  map[string]Speaker
+}
+
+func New(emb Embedder, path string, threshold float64) (*Service, error) {
+    if threshold <= 0 { threshold = DefaultThreshold }
+    s := &Service{emb: emb, path: path, threshold: threshold, speakers: map[string]Speaker{}}
+    if path == "" { return s, nil }
+    b, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) { return s, nil }
+    if err != nil { return nil, fmt.Errorf("read speakers: %w", err) }
+    var saved []Speaker
+    if err := json.Unmarshal(b, &saved); err != nil { return nil, fmt.Errorf("parse speakers: %w", err) }
+    for _, sp := range saved { s.speakers[sp.ID] = sp }
+    return s, nil
+}
+
+// Threshold is the score Verify and Identify require for a match.
+func (s *Service) Threshold() float64 { return s.threshold }
+
+var idRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ValidID reports whether id may name a speaker.
+func ValidID(id string) bool { return idRE.MatchString(id) }
+
+// Embed returns the normalized embedding of a 16-bit PCM WAV recording.
+func (s *Service) Embed(ctx context.Context, wav []byte) ([]float32, error) {
+    x, rate, err := stt.DecodeWAV(wav)
+    if err != nil { return nil, err }
+    if len(x) < rate { return nil, ErrTooShort }
+    v, err := s.emb.Embed(ctx, dsp.Resample(x, rate, Rate))
+    if err != nil { return nil, err }
+    return normalize(v), nil
+}
+
+// Enroll adds a recording to speaker id, creating it if needed. Enrolling
+// several recordings (different sentences, rooms) makes matching sturdier.
+func (s *Service) Enroll(ctx context.Context, id, name string, wav []byte) (Speaker, error) {
+    if !ValidID(id) { return Speaker{}, fmt.Errorf("invalid speaker id %q", id) }
+    v, err := s.Embed(ctx, wav)
+    if err != nil { return Speaker{}, err }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    prev, had := s.speakers[id]
+    sp := prev
+    sp.ID = id
+    if name != "" { sp.Name = name }
+    if had && len(prev.Embedding) == len(v) {
+        mean := make([]float32, len(v))
+        n := float32(prev.Samples)
+        for i := range v { mean[i] = (prev.Embedding[i]*n + v[i]) / (n + 1) }
+        sp.Embedding = normalize(mean)
+    } else {
+        sp.Embedding, sp.Samples = v, 0
+    }
+    sp.Samples++
+    sp.UpdatedAt = time.Now().UTC()
+    s.speakers[id] = sp
+    if err := s.saveLocked(); err != nil {
+        if had { s.speakers[id] = prev } else { delete(s.speakers, id) }
+        return Speaker{}, err
+    }
+    return sp, nil
+}
+
+// Verify scores a recording against speaker id.
+func (s *Service) Verify(ctx context.Context, id string, wav []byte) (Match, bool, error) {
+    sp, err := s.Get(id)
+    if err != nil { return Match{}, false, err }
+    v, err := s.Embed(ctx, wav)
+    if err != nil { return Match{}, false, err }
+    m := Match{ID: sp.ID, Name: sp.Name, Score: cosine(v, sp.Embedding)}
+    return m, m.Score >= s.threshold, nil
+}
+
+// Identify scores a recording against every speaker, best match first.
+func (s *Service) Identify(ctx context.Context, wav []byte) ([]Match, error) {
+    v, err := s.Embed(ctx, wav)
+    if err != nil { return nil, err }
+    s.mu.RLock()
+    out := make([]Match, 0, len(s.speakers))
+    for _, sp := range s.speakers { out = append(out, Match{ID: sp.ID, Name: sp.Name, Score: cosine(v, sp.Embedding)}) }
+    s.mu.RUnlock()
+    sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+    return out, nil
+}
+
+func (s *Service) Get(id string) (Speaker, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    sp, ok := s.speakers[id]
+    if !ok { return Speaker{}, ErrNotFound }
+    return sp, nil
+}
+
+// List returns all speakers sorted by id.
+func (s *Service) List() []Speaker {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.listLocked()
+}
+
+func (s *Service) Delete(id string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sp, ok := s.speakers[id]
+    if !ok { return ErrNotFound }
+    delete(s.speakers, id)
+    if err := s.saveLocked(); err != nil { s.speakers[id] = sp; return err }
+    return nil
+}
+
+func (s *Service) saveLocked() error {
+    if s.path == "" { return nil }
+    b, err := json.MarshalIndent(s.listLocked(), "", "  ")
+    if err != nil { return err }
+    tmp := s.path + ".tmp"
+    if err := os.WriteFile(tmp, b, 0o600); err != nil { return err }
+    return os.Rename(tmp, s.path)
+}
+
+func (s *Service) listLocked() []Speaker {
+    out := make([]Speaker, 0, len(s.speakers))
+    for _, sp := range s.speakers { out = append(out, sp) }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    return out
+}
+
+func normalize(v []float32) []float32 {
+    var sum float64
+    for _, x := range v { sum += float64(x) * float64(x) }
+    if sum == 0 { return v }
+    inv := float32(1 / math.Sqrt(sum))
+    out := make([]float32, len(v))
+    for i, x := range v { out[i] = x * inv }
+    return out
+}
+
+func cosine(a, b []float32) float64 {
+    if len(a) != len(b) { return 0 }
+    var dot float64
+    for i := range a { dot += float64(a[i]) * float64(b[i]) }
+    return math.Round(dot*10000) / 10000
+}
@@ -0,0 +1,61 @@
+package speaker
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "time"
+
+    ort "github.com/yalue/onnxruntime_go"
+
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/tracing"
+)
+
+// WeSpeaker ResNet34 trained on VoxCeleb (about 25 MB, Apache-2.0), in the
+// ONNX export published with sherpa-onnx: 80-bin fbank features in,
+// 256-dim embedding out. Downloaded on first use.
+
+var wespeakerURLs = []string{
+    "https://github.com/k2-fsa/sherpa-onnx/releases/download/speaker-recongition-models/wespeaker_en_voxceleb_resnet34.onnx",
+}
+
+type wespeaker struct {
+    session *ort.DynamicAdvancedSession
+}
+
+// NewONNX returns the WeSpeaker embedder, keeping its model in modelDir.
+func NewONNX(modelDir string) (Embedder, error) {
+    if err := os.MkdirAll(modelDir, 0o755); err != nil { return nil, err }
+    if err := onnxrt.Init(); err != nil { return nil, err }
+    modelPath := filepath.Join(modelDir, "wespeaker_en_voxceleb_resnet34.onnx")
+    if _, err := os.Stat(modelPath); err != nil {
+        if err := onnxrt.TryDownload(wespeakerURLs, modelPath, 3, 180*time.Second); err != nil { return nil, err }
+    }
+    sess, err := onnxrt.NewSession(modelPath, []string{"feats"}, []string{"embs"}, onnxrt.Options{})
+    if err != nil { return nil, err }
+    onnxrt.SessionOpened("wespeaker")
+    return &wespeaker{session: sess}, nil
+}
+
+func (m *wespeaker) Embed(ctx context.Context, samples []float32) (emb []float32, err error) {
+    _, span := tracing.Stage(ctx, "speaker.fbank")
+    feats := fbank(samples)
+    span.End(nil)
+    if len(feats) == 0 { return nil, ErrTooShort }
+    flat := make([]float32, 0, len(feats)*melBins)
+    for _, f := range feats { flat = append(flat, f...) }
+
+    _, span = tracing.Stage(ctx, "speaker.inference")
+    defer func() { span.End(err) }()
+    in, err := ort.NewTensor(ort.NewShape(1, int64(len(feats)), melBins), flat)
+    if err != nil { return nil, err }
+    defer in.Destroy()
+    outputs := make([]ort.Value, 1)
+    if err := m.session.Run([]ort.Value{in}, outputs); err != nil { return nil, err }
+    defer outputs[0].Destroy()
+    t, ok := outputs[0].(*ort.Tensor[float32])
+    if !ok { return nil, errors.New("unexpected speaker embedding output") }
+    return append([]float32(nil), t.GetData()...), nil
+}
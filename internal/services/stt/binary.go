@@ -0,0 +1,267 @@
+package stt
+
+import (
+    "archive/tar"
+    "compress/gzip"
+    "context"
+    "fmt"
+    "io"
+    "io/fs"
+    "log"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "time"
+
+    "golang.org/x/sys/cpu"
+
+    "gollmcore/internal/procs"
+)
+
+// ----- Whisper binary sources -----
+//
+// whisper.cpp is fetched on first use. By default it comes from prebuilt
+// archives on the mirror the project has always used. The official
+// whisper.cpp GitHub releases publish binaries for Windows only; on other
+// platforms the release source can be compiled with cmake instead, when
+// the operator opts in with SourceBuild. A custom URL overrides both. The
+// CPU's SIMD support decides which build is used: prebuilt x86-64 binaries
+// assume AVX2, and source builds enable exactly what the CPU has.
+
+// BinarySource is where the whisper binary comes from.
+type BinarySource string
+
+const (
+    SourceMirror   BinarySource = "mirror"   // prebuilt archives (default)
+    SourceOfficial BinarySource = "official" // whisper.cpp GitHub release binaries
+    SourceBuild    BinarySource = "build"    // compile the GitHub release source with cmake
+)
+
+// DefaultWhisperVersion is the whisper.cpp release fetched when none is set.
+const DefaultWhisperVersion = "v1.7.6"
+
+// binaryTimeout bounds one download of a whisper archive.
+const binaryTimeout = 2 * time.Minute
+
+// ParseBinarySource checks a source name; "" means SourceMirror.
+func ParseBinarySource(s string) (BinarySource, error) {
+    switch b := BinarySource(s); b {
+    case "":
+        return SourceMirror, nil
+    case SourceMirror, SourceOfficial, SourceBuild:
+        return b, nil
+    }
+    return "", fmt.Errorf("unknown binary source %q (supported: mirror, official, build)", s)
+}
+
+// BinaryOptions choose how the whisper binary is installed.
+type BinaryOptions struct {
+    Source  BinarySource
+    // URL is a zip archive holding whisper-cli (and its libraries); when
+    // set it is used instead of Source.
+    URL     string
+    // Version is the whisper.cpp release tag for SourceOfficial and
+    // SourceBuild (default DefaultWhisperVersion).
+    Version string
+    // GPU picks a CUDA or Metal build and whether runs use it (see gpu.go).
+    GPU     GPU
+}
+
+// CPUFeatures are the SIMD extensions whisper.cpp builds are compiled for.
+type CPUFeatures struct {
+    AVX, AVX2, FMA, F16C, AVX512 bool // x86-64
+    NEON                         bool // arm64
+}
+
+// DetectCPU reports the SIMD support of this machine.
+func DetectCPU() CPUFeatures {
+    return CPUFeatures{
+        AVX:    cpu.X86.HasAVX,
+        AVX2:   cpu.X86.HasAVX2,
+        FMA:    cpu.X86.HasFMA,
+        F16C:   cpu.X86.HasAVX2 && cpu.X86.HasFMA, // not reported by x/sys/cpu; every AVX2+FMA CPU has it
+        AVX512: cpu.X86.HasAVX512F,
+        NEON:   runtime.GOARCH == "arm64" && cpu.ARM64.HasASIMD,
+    }
+}
+
+// String lists the features present, e.g. "avx avx2 fma f16c".
+func (f CPUFeatures) String() string {
+    var out []string
+    for _, x := range []struct {
+        name string
+        on   bool
+    }{{"avx", f.AVX}, {"avx2", f.AVX2}, {"fma", f.FMA}, {"f16c", f.F16C}, {"avx512", f.AVX512}, {"neon", f.NEON}} {
+        if x.on { out = append(out, x.name) }
+    }
+    if len(out) == 0 { return "none" }
+    return strings.Join(out, " ")
+}
+
+// cmakeFlags pins the ggml instruction sets to f instead of letting the
+// compiler probe the build host.
+func (f CPUFeatures) cmakeFlags(goarch string) []string {
+    flags := []string{"-DGGML_NATIVE=OFF"}
+    if goarch != "amd64" { return flags }
+    onOff := func(b bool) string {
+        if b { return "ON" }
+        return "OFF"
+    }
+    return append(flags, "-DGGML_AVX="+onOff(f.AVX), "-DGGML_AVX2="+onOff(f.AVX2), "-DGGML_FMA="+onOff(f.FMA), "-DGGML_F16C="+onOff(f.F16C), "-DGGML_AVX512="+onOff(f.AVX512))
+}
+
+// whisperPlan is how to install whisper on one platform: download a zip
+// archive, or (build) download and compile a source tarball.
+type whisperPlan struct {
+    urls  []string
+    file  string // download name in binDir
+    build bool
+}
+
+const (
+    mirrorBase   = "https://aliceai.ca/app_assets/whisper/"
+    releasesBase = "https://github.com/ggml-org/whisper.cpp/releases/download/"
+    sourceBase   = "https://github.com/ggml-org/whisper.cpp/archive/refs/tags/"
+)
+
+// planWhisper picks the archive for goos/goarch, the CPU features f and
+// the GPU backend g.
+func planWhisper(o BinaryOptions, goos, goarch string, f CPUFeatures, g GPU) (whisperPlan, error) {
+    if o.URL != "" { return whisperPlan{urls: []string{o.URL}, file: "whisper-custom.zip"}, nil }
+    version := o.Version
+    if version == "" { version = DefaultWhisperVersion }
+    switch o.Source {
+    case SourceBuild:
+        return whisperPlan{urls: []string{sourceBase + version + ".tar.gz"}, file: "whisper.cpp-" + version + ".tar.gz", build: true}, nil
+    case SourceOfficial:
+        if goos != "windows" { return whisperPlan{}, fmt.Errorf("whisper.cpp releases have no %s binaries; use binary_source \"build\" or binary_url", goos) }
+        switch {
+        case goarch == "amd64" && g == GPUCUDA:
+            return whisperPlan{urls: []string{releasesBase + version + "/whisper-cublas-12.4.0-bin-x64.zip"}, file: "whisper-cublas-bin-x64-" + version + ".zip"}, nil
+        case goarch == "amd64" && f.AVX2:
+            return whisperPlan{urls: []string{releasesBase + version + "/whisper-bin-x64.zip"}, file: "whisper-bin-x64-" + version + ".zip"}, nil
+        case goarch == "386":
+            return whisperPlan{urls: []string{releasesBase + version + "/whisper-bin-Win32.zip"}, file: "whisper-bin-Win32-" + version + ".zip"}, nil
+        case goarch == "amd64":
+            return whisperPlan{}, fmt.Errorf("the official Windows x64 build needs AVX2, which this CPU lacks; use binary_source \"build\"")
+        }
+        return whisperPlan{}, fmt.Errorf("whisper.cpp releases have no windows/%s binaries", goarch)
+    }
+    var name string
+    switch {
+    case goos == "windows" && goarch == "amd64":
+        name = "whisper-windows.zip"
+    case goos == "darwin" && goarch == "arm64":
+        name = "whisper-macos-arm64.zip"
+    case goos == "darwin":
+        name = "whisper-macos-x64.zip"
+    case goos == "linux" && goarch == "amd64":
+        name = "whisper-linux-x64.zip"
+    default:
+        return whisperPlan{}, fmt.Errorf("no prebuilt whisper binary for %s/%s; use binary_source \"build\"", goos, goarch)
+    }
+    if g == GPUCUDA {
+        if o.GPU == GPUCUDA { return whisperPlan{}, fmt.Errorf("the mirror has no CUDA build of whisper; use binary_source \"build\" (or \"official\" on Windows)") }
+        log.Printf("A CUDA GPU was found, but the mirror's whisper build is CPU-only; set services.stt.binary_source to \"build\" to use it")
+    }
+    if goarch == "amd64" && !f.AVX2 { log.Printf("Warning: this CPU lacks AVX2 (has: %s); the prebuilt whisper binary may crash. Set services.stt.binary_source to \"build\" if it does.", f) }
+    return whisperPlan{urls: []string{mirrorBase + name}, file: name}, nil
+}
+
+// buildWhisper unpacks the whisper.cpp source tarball at archive and
+// compiles whisper-cli into binDir for f and g. It needs cmake and a C++
+// compiler, plus the CUDA toolkit for g == GPUCUDA; when the GPU build
+// fails, a CPU-only one is tried.
+func (s *STTService) buildWhisper(ctx context.Context, archive string, f CPUFeatures, g GPU) error {
+    if _, err := exec.LookPath("cmake"); err != nil { return fmt.Errorf("building whisper.cpp needs cmake on PATH: %w", err) }
+    src, err := os.MkdirTemp(s.binDir, "whisper-src-")
+    if err != nil { return err }
+    defer os.RemoveAll(src)
+    root, err := untarGz(archive, src)
+    if err != nil { return fmt.Errorf("unpack whisper.cpp source: %w", err) }
+
+    build := filepath.Join(root, "build")
+    err = cmakeBuild(ctx, root, build, f, g)
+    if err != nil && g != GPUOff && ctx.Err() == nil {
+        log.Printf("Building whisper.cpp with %s failed, building for the CPU only: %v", g, err)
+        s.gpuFailed.Store(true)
+        _ = os.RemoveAll(build)
+        err = cmakeBuild(ctx, root, build, f, GPUOff)
+    }
+    if err != nil { return err }
+
+    name := "whisper-cli"
+    if runtime.GOOS == "windows" { name += ".exe" }
+    var built string
+    _ = filepath.WalkDir(build, func(p string, d fs.DirEntry, err error) error {
+        if err == nil && !d.IsDir() && d.Name() == name { built = p; return fs.SkipAll }
+        return nil
+    })
+    if built == "" { return fmt.Errorf("cmake build produced no %s", name) }
+    return copyExecutable(built, filepath.Join(s.binDir, name))
+}
+
+func cmakeBuild(ctx context.Context, root, build string, f CPUFeatures, g GPU) error {
+    configure := append([]string{"-S", root, "-B", build, "-DCMAKE_BUILD_TYPE=Release", "-DBUILD_SHARED_LIBS=OFF", "-DWHISPER_BUILD_TESTS=OFF", "-DWHISPER_SDL2=OFF"}, f.cmakeFlags(runtime.GOARCH)...)
+    configure = append(configure, g.cmakeFlags()...)
+    log.Printf("Building whisper.cpp for %s/%s (cpu: %s, gpu: %s); this takes a few minutes", runtime.GOOS, runtime.GOARCH, f, g)
+    for _, args := range [][]string{configure, {"--build", build, "--config", "Release", "--target", "whisper-cli", "--parallel", fmt.Sprint(runtime.NumCPU())}} {
+        cmd := exec.CommandContext(ctx, "cmake", args...)
+        var out strings.Builder
+        cmd.Stdout, cmd.Stderr = &out, &out
+        if err := procs.Run("cmake", cmd); err != nil { return fmt.Errorf("cmake %s: %w\n%s", args[0], err, tail(out.String(), 2000)) }
+    }
+    return nil
+}
+
+// untarGz extracts the gzipped tarball at path into dir and returns the
+// directory the archive's files share (GitHub wraps them in one).
+func untarGz(path, dir string) (string, error) {
+    f, err := os.Open(path)
+    if err != nil { return "", err }
+    defer f.Close()
+    gz, err := gzip.NewReader(f)
+    if err != nil { return "", err }
+    tr := tar.NewReader(gz)
+    root := ""
+    for {
+        h, err := tr.Next()
+        if err == io.EOF { break }
+        if err != nil { return "", err }
+        name := filepath.Clean(filepath.FromSlash(h.Name))
+        if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) { return "", fmt.Errorf("unsafe path in archive: %s", h.Name) }
+        if top, _, _ := strings.Cut(filepath.ToSlash(name), "/"); root == "" { root = top }
+        dst := filepath.Join(dir, name)
+        switch h.Typeflag {
+        case tar.TypeDir:
+            if err := os.MkdirAll(dst, 0o755); err != nil { return "", err }
+        case tar.TypeReg:
+            if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil { return "", err }
+            out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(h.Mode)&0o755|0o600)
+            if err != nil { return "", err }
+            _, err = io.Copy(out, tr)
+            out.Close()
+            if err != nil { return "", err }
+        }
+    }
+    if root == "" { return "", fmt.Errorf("empty archive") }
+    return filepath.Join(dir, root), nil
+}
+
+func copyExecutable(src, dst string) error {
+    in, err := os.Open(src)
+    if err != nil { return err }
+    defer in.Close()
+    out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+    if err != nil { return err }
+    if _, err := io.Copy(out, in); err != nil { out.Close(); return err }
+    return out.Close()
+}
+
+// tail returns the last n bytes of s, for error messages.
+func tail(s string, n int) string {
+    if len(s) <= n { return s }
+    return "..." + s[len(s)-n:]
+}
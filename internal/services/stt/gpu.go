@@ -0,0 +1,132 @@
+package stt
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "os/exec"
+    "runtime"
+    "strings"
+
+    "gollmcore/internal/procs"
+    "gollmcore/internal/tracing"
+)
+
+// ----- GPU acceleration -----
+//
+// whisper.cpp runs on CUDA (NVIDIA) or Metal (Apple Silicon) when it was
+// built with that backend and the device is there. The GPU setting picks
+// the build at install time and is checked against the machine on first
+// use: a GPU that is asked for but absent is logged and the CPU used. A
+// run that fails with a GPU error is retried once on the CPU (-ng), and
+// later runs stay on the CPU, so a broken driver costs one slow request
+// rather than every transcription.
+
+// GPU is a whisper.cpp GPU backend.
+type GPU string
+
+const (
+    GPUAuto  GPU = "auto"  // use whatever GPU is detected (default)
+    GPUCUDA  GPU = "cuda"
+    GPUMetal GPU = "metal"
+    GPUOff   GPU = "off"
+)
+
+// ParseGPU checks a GPU setting; "" means GPUAuto.
+func ParseGPU(s string) (GPU, error) {
+    switch g := GPU(s); g {
+    case "":
+        return GPUAuto, nil
+    case GPUAuto, GPUCUDA, GPUMetal, GPUOff:
+        return g, nil
+    }
+    return "", fmt.Errorf("unknown gpu %q (supported: auto, cuda, metal, off)", s)
+}
+
+// DetectGPU reports the GPU whisper.cpp can use here: Metal on Apple
+// Silicon, CUDA when nvidia-smi lists a device, otherwise GPUOff.
+func DetectGPU() GPU {
+    if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" { return GPUMetal }
+    out, err := exec.Command("nvidia-smi", "-L").Output()
+    if err == nil && strings.Contains(string(out), "GPU ") { return GPUCUDA }
+    return GPUOff
+}
+
+// resolveGPU is the backend to use when want is asked for and found was
+// detected.
+func resolveGPU(want, found GPU) GPU {
+    if want == GPUAuto || want == "" { return found }
+    if want != GPUOff && want != found {
+        log.Printf("Warning: services.stt.gpu is %s but no such GPU was detected; whisper runs on the CPU", want)
+        return GPUOff
+    }
+    return want
+}
+
+// cmakeFlags enables the ggml backend for g.
+func (g GPU) cmakeFlags() []string {
+    switch g {
+    case GPUCUDA:
+        return []string{"-DGGML_CUDA=ON"}
+    case GPUMetal:
+        return []string{"-DGGML_METAL=ON", "-DGGML_METAL_EMBED_LIBRARY=ON"}
+    }
+    return nil
+}
+
+// gpuBackend resolves the configured GPU against the machine, once.
+func (s *STTService) gpuBackend() GPU {
+    s.gpuOnce.Do(func() {
+        s.gpu = GPUOff
+        if s.binary.GPU == GPUOff { return }
+        s.gpu = resolveGPU(s.binary.GPU, DetectGPU())
+        if s.gpu != GPUOff { log.Printf("whisper will use the %s GPU", s.gpu) }
+    })
+    return s.gpu
+}
+
+// useGPU reports whether whisper runs should try the GPU.
+func (s *STTService) useGPU() bool { return s.gpuBackend() != GPUOff && !s.gpuFailed.Load() }
+
+// gpuArgs are the device flags for the next run.
+func (s *STTService) gpuArgs() []string {
+    if s.useGPU() { return nil }
+    return []string{"-ng"}
+}
+
+// runWhisper runs whisper with args under the stage span name, sending its
+// output to stdout and stderr. A run that fails with a GPU error is
+// retried on the CPU, which later runs (streamed ones included) then use.
+func (s *STTService) runWhisper(ctx context.Context, stage, bin string, args []string, stdout, stderr io.Writer) error {
+    run := func(extra []string) (string, error) {
+        var errOut bytes.Buffer
+        cmd := exec.CommandContext(ctx, bin, append(append([]string{}, args...), extra...)...)
+        cmd.Dir = s.binDir
+        cmd.Env = append(os.Environ(), s.libEnv()...)
+        cmd.Stdout, cmd.Stderr = stdout, io.MultiWriter(stderr, &errOut)
+        if stdout == stderr { cmd.Stdout = cmd.Stderr } // one pipe, as exec does for a shared writer
+        err := tracing.Do(ctx, stage, func(context.Context) error { return procs.Run("whisper", cmd) })
+        return errOut.String(), err
+    }
+    gpu := s.useGPU()
+    out, err := run(s.gpuArgs())
+    if err == nil || !gpu || ctx.Err() != nil || !gpuError(out) { return err }
+    if !s.gpuFailed.Swap(true) { log.Printf("whisper failed on the %s GPU (%v); falling back to the CPU", s.gpu, err) }
+    _, err = run([]string{"-ng"})
+    return err
+}
+
+// gpuError reports whether whisper's stderr shows a CUDA or Metal failure,
+// as opposed to e.g. unreadable audio, which the CPU would not fix.
+func gpuError(stderr string) bool {
+    sc := bufio.NewScanner(strings.NewReader(stderr))
+    for sc.Scan() {
+        l := strings.ToLower(sc.Text())
+        if (strings.Contains(l, "cuda") || strings.Contains(l, "metal")) && (strings.Contains(l, "error") || strings.Contains(l, "failed")) { return true }
+    }
+    return false
+}
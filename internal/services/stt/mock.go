@@ -0,0 +1,49 @@
+package stt
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "time"
+)
+
+// NewMock returns an STTService for tests and CI that runs no whisper and
+// downloads nothing. Every recording is "transcribed" as one segment
+// describing its length ("Mock transcription of 2.5 seconds of audio.",
+// or its size for formats other than PCM WAV), and the language is "en".
+func NewMock() *STTService { return &STTService{mock: true} }
+
+func mockSegments(path string) ([]Segment, error) {
+    d, err := WAVDuration(path)
+    if err == nil { return []Segment{{Start: 0, End: d.Seconds(), Text: fmt.Sprintf("Mock transcription of %.1f seconds of audio.", d.Seconds())}}, nil }
+    st, err := os.Stat(path)
+    if err != nil { return nil, err }
+    return []Segment{{Start: 0, End: 1, Text: fmt.Sprintf("Mock transcription of %d bytes of audio.", st.Size())}}, nil
+}
+
+func mockTranscript(path string) (string, error) {
+    segs, err := mockSegments(path)
+    if err != nil { return "", err }
+    return segs[0].Text, nil
+}
+
+// mockStream sends the mock transcript as whisper would print it.
+func mockStream(ctx context.Context, path string, timestamps bool, lines chan<- string, errs chan<- error) {
+    segs, err := mockSegments(path)
+    if err != nil { errs <- err; return }
+    for _, seg := range segs {
+        line := seg.Text
+        if timestamps { line = fmt.Sprintf("[%s --> %s]  %s", mockStamp(seg.Start), mockStamp(seg.End), seg.Text) }
+        select {
+        case lines <- line:
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// mockStamp formats seconds like whisper: 00:00:02.500.
+func mockStamp(sec float64) string {
+    d := time.Duration(sec * float64(time.Second))
+    return fmt.Sprintf("%02d:%02d:%06.3f", int(d.Hours()), int(d.Minutes())%60, (d % time.Minute).Seconds())
+}
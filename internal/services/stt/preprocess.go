@@ -0,0 +1,197 @@
+package stt
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "log"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+)
+
+// Preprocess selects the clean-up applied to audio before whisper sees it.
+// It helps quiet or hissy laptop-mic recordings; studio audio gains little.
+type Preprocess struct {
+    Normalize bool // bring speech to a steady loudness
+    Denoise   bool // attenuate the noise floor between words (noise gate)
+}
+
+// ErrNotPCMWAV is returned by Apply for input other than 16-bit PCM WAV.
+var ErrNotPCMWAV = errors.New("preprocessing needs 16-bit PCM WAV audio")
+
+const (
+    targetRMS  = 0.1   // -20 dBFS speech level
+    maxPeak    = 0.89  // -1 dBFS
+    maxGain    = 31.6  // +30 dB
+    gateFloor  = 0.1   // -20 dB applied to gated frames
+    gateHold   = 10    // frames (200 ms) the gate stays open after speech
+    frameMs    = 20
+)
+
+// Apply returns a processed copy of a 16-bit PCM WAV file.
+func (p Preprocess) Apply(wav []byte) ([]byte, error) {
+    pcm, hdr, err := splitPCM16(wav)
+    if err != nil { return nil, err }
+    x := make([]float64, len(pcm)/2)
+    for i := range x { x[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768 }
+
+    rate, channels := int(binary.LittleEndian.Uint32(hdr[4:])), int(binary.LittleEndian.Uint16(hdr[2:]))
+    frame := rate * frameMs / 1000 * channels
+    if frame <= 0 { frame = channels }
+    rms := frameRMS(x, frame)
+    if p.Denoise { gate(x, rms, frame, rate*channels) }
+    if p.Normalize { normalize(x, frameRMS(x, frame)) }
+
+    out := make([]byte, len(x)*2)
+    for i, v := range x {
+        v = math.Max(-1, math.Min(1, v))
+        binary.LittleEndian.PutUint16(out[2*i:], uint16(int16(math.Round(v*32767))))
+    }
+    return buildPCMWAV(hdr, out), nil
+}
+
+// apply writes the processed copy of path to a temporary file and returns
+// its path and a cleanup function; formats Apply does not handle are passed
+// through unchanged.
+func (p Preprocess) apply(path string) (string, func(), error) {
+    noop := func() {}
+    if !p.Normalize && !p.Denoise { return path, noop, nil }
+    in, err := os.ReadFile(path)
+    if err != nil { return "", noop, err }
+    out, err := p.Apply(in)
+    if errors.Is(err, ErrNotPCMWAV) {
+        log.Printf("stt: skipping preprocessing of %s: %v", filepath.Base(path), err)
+        return path, noop, nil
+    }
+    if err != nil { return "", noop, err }
+    f, err := os.CreateTemp(filepath.Dir(path), "stt-pre-*.wav")
+    if err != nil { return "", noop, err }
+    _, err = f.Write(out)
+    if cerr := f.Close(); err == nil { err = cerr }
+    cleanup := func() { _ = os.Remove(f.Name()) }
+    if err != nil { cleanup(); return "", noop, err }
+    return f.Name(), cleanup, nil
+}
+
+// frameRMS returns the RMS level of each frame of x.
+func frameRMS(x []float64, frame int) []float64 {
+    out := make([]float64, 0, len(x)/frame+1)
+    for start := 0; start < len(x); start += frame {
+        end := start + frame
+        if end > len(x) { end = len(x) }
+        var sum float64
+        for _, v := range x[start:end] { sum += v * v }
+        out = append(out, math.Sqrt(sum/float64(end-start)))
+    }
+    return out
+}
+
+// percentile returns the p-th percentile (0..1) of values.
+func percentile(values []float64, p float64) float64 {
+    s := append([]float64(nil), values...)
+    sort.Float64s(s)
+    return s[int(p*float64(len(s)-1))]
+}
+
+// gate attenuates frames close to the noise floor (the 10th percentile frame
+// level), holding the gate open briefly after speech and smoothing the gain
+// over ~5 ms so words are not clipped and no clicks are introduced.
+func gate(x, rms []float64, frame, samplesPerSec int) {
+    if len(rms) < 10 { return }
+    floor := percentile(rms, 0.1)
+    // Without contrast between quiet and loud frames there is no noise
+    // floor to remove (all speech, or all noise).
+    if percentile(rms, 0.9) < 4*floor { return }
+    threshold := 2 * floor
+    open := make([]bool, len(rms))
+    hold := 0
+    for i, level := range rms {
+        if level >= threshold { hold = gateHold + 1 }
+        if hold > 0 { open[i] = true; hold-- }
+    }
+    // Open one frame early so word onsets survive.
+    for i := 0; i+1 < len(open); i++ {
+        if !open[i] && open[i+1] { open[i] = true; i++ }
+    }
+    alpha := 1 - math.Exp(-1/(0.005*float64(samplesPerSec)))
+    g := 1.0
+    for i := range x {
+        target := gateFloor
+        if open[i/frame] { target = 1 }
+        g += alpha * (target - g)
+        x[i] *= g
+    }
+}
+
+// normalize scales x so its louder half of frames averages targetRMS,
+// without pushing peaks past maxPeak or boosting by more than maxGain.
+func normalize(x, rms []float64) {
+    if len(rms) == 0 { return }
+    s := append([]float64(nil), rms...)
+    sort.Float64s(s)
+    var sum float64
+    loud := s[len(s)/2:]
+    for _, v := range loud { sum += v * v }
+    level := math.Sqrt(sum / float64(len(loud)))
+    if level == 0 { return }
+    var peak float64
+    for _, v := range x { peak = math.Max(peak, math.Abs(v)) }
+    gain := math.Min(targetRMS/level, maxGain)
+    if peak*gain > maxPeak { gain = maxPeak / peak }
+    for i := range x { x[i] *= gain }
+}
+
+// DecodeWAV returns the samples of a 16-bit PCM WAV file mixed down to
+// mono, scaled to [-1, 1), and its sample rate.
+func DecodeWAV(wav []byte) ([]float32, int, error) {
+    pcm, hdr, err := splitPCM16(wav)
+    if err != nil { return nil, 0, err }
+    rate, channels := int(binary.LittleEndian.Uint32(hdr[4:])), int(binary.LittleEndian.Uint16(hdr[2:]))
+    if channels == 0 || rate == 0 { return nil, 0, ErrNotPCMWAV }
+    out := make([]float32, len(pcm)/2/channels)
+    for i := range out {
+        var sum float32
+        for c := 0; c < channels; c++ { sum += float32(int16(binary.LittleEndian.Uint16(pcm[2*(i*channels+c):]))) / 32768 }
+        out[i] = sum / float32(channels)
+    }
+    return out, rate, nil
+}
+
+// splitPCM16 returns the sample data and 16-byte fmt chunk of a 16-bit PCM
+// WAV file.
+func splitPCM16(b []byte) (pcm, format []byte, err error) {
+    if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" { return nil, nil, ErrNotPCMWAV }
+    for off := 12; off+8 <= len(b); {
+        id, size := string(b[off:off+4]), int(binary.LittleEndian.Uint32(b[off+4:off+8]))
+        body := off + 8
+        if body+size > len(b) { size = len(b) - body } // tolerate truncated streaming headers
+        switch id {
+        case "fmt ":
+            if size < 16 { return nil, nil, ErrNotPCMWAV }
+            format = b[body : body+16]
+            if binary.LittleEndian.Uint16(format[0:]) != 1 || binary.LittleEndian.Uint16(format[14:]) != 16 { return nil, nil, ErrNotPCMWAV }
+        case "data":
+            if format == nil { return nil, nil, ErrNotPCMWAV }
+            return b[body : body+size-size%2], format, nil
+        }
+        off = body + size + size%2
+    }
+    return nil, nil, ErrNotPCMWAV
+}
+
+// buildPCMWAV wraps sample data in a canonical 44-byte header.
+func buildPCMWAV(format, data []byte) []byte {
+    var buf bytes.Buffer
+    buf.Grow(44 + len(data))
+    buf.WriteString("RIFF")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(data)))
+    buf.WriteString("WAVEfmt ")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+    buf.Write(format)
+    buf.WriteString("data")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+    buf.Write(data)
+    return buf.Bytes()
+}
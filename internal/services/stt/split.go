@@ -0,0 +1,108 @@
+package stt
+
+import (
+    "encoding/binary"
+    "io"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// Chunk is one piece of a recording cut by SplitWAV.
+type Chunk struct {
+    Path   string
+    Offset time.Duration // start within the original recording
+}
+
+// SplitWAV cuts a 16-bit PCM WAV recording longer than after into pieces of
+// about chunk each, written to dir. Cuts fall on the quietest 300 ms within
+// a quarter chunk of each target so words are not split. It returns no
+// chunks when after or chunk is zero, for shorter recordings and for other
+// formats; cleanup removes the pieces.
+func SplitWAV(path, dir string, after, chunk time.Duration) (chunks []Chunk, cleanup func(), err error) {
+    cleanup = func() { for _, c := range chunks { _ = os.Remove(c.Path) } }
+    if after <= 0 || chunk <= 0 { return nil, func() {}, nil }
+    // Peek at the header so other formats are never read into memory.
+    f, err := os.Open(path)
+    if err != nil { return nil, func() {}, err }
+    head := make([]byte, 4096)
+    n, _ := f.Read(head)
+    f.Close()
+    if _, _, err := splitPCM16(head[:n]); err != nil { return nil, func() {}, nil }
+
+    b, err := os.ReadFile(path)
+    if err != nil { return nil, func() {}, err }
+    pcm, format, err := splitPCM16(b)
+    if err != nil { return nil, func() {}, nil }
+    rate, channels := int(binary.LittleEndian.Uint32(format[4:])), int(binary.LittleEndian.Uint16(format[2:]))
+    perSec := rate * channels
+    if perSec <= 0 || len(pcm)/2 <= int(after.Seconds()*float64(perSec)) { return nil, func() {}, nil }
+
+    x := make([]float64, len(pcm)/2)
+    for i := range x { x[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768 }
+    frame := perSec * frameMs / 1000
+    rms := frameRMS(x, frame)
+    perChunk := int(chunk.Seconds() * 1000 / frameMs)
+    if perChunk < 1 { perChunk = 1 }
+
+    write := func(from, to int) error {
+        start, end := from*frame*2, to*frame*2
+        if end > len(pcm) { end = len(pcm) }
+        out, err := os.CreateTemp(dir, "stt-chunk-*"+filepath.Ext(path))
+        if err != nil { return err }
+        chunks = append(chunks, Chunk{Path: out.Name(), Offset: time.Duration(from) * frameMs * time.Millisecond})
+        _, err = out.Write(buildPCMWAV(format, pcm[start:end]))
+        if cerr := out.Close(); err == nil { err = cerr }
+        return err
+    }
+    start := 0
+    for len(rms)-start > perChunk*3/2 {
+        cut := quietest(rms, start+perChunk-perChunk/4, start+perChunk+perChunk/4)
+        if err := write(start, cut); err != nil { cleanup(); return nil, func() {}, err }
+        start = cut
+    }
+    if err := write(start, len(rms)); err != nil { cleanup(); return nil, func() {}, err }
+    return chunks, cleanup, nil
+}
+
+// WAVDuration returns the length of a 16-bit PCM WAV file from its header
+// and size, without reading the samples, or ErrNotPCMWAV for other formats.
+func WAVDuration(path string) (time.Duration, error) {
+    f, err := os.Open(path)
+    if err != nil { return 0, err }
+    defer f.Close()
+    head := make([]byte, 4096)
+    n, err := io.ReadFull(f, head)
+    if err != nil && err != io.ErrUnexpectedEOF { return 0, err }
+    pcm, format, err := splitPCM16(head[:n])
+    if err != nil { return 0, err }
+    size := int64(len(pcm))
+    if n == len(head) {
+        // The data chunk runs past the header read; take it to the end of
+        // the file (streamed WAVs often leave its size unset).
+        st, err := f.Stat()
+        if err != nil { return 0, err }
+        size = st.Size() - int64(n-len(pcm))
+    }
+    perSec := int64(binary.LittleEndian.Uint32(format[4:])) * int64(binary.LittleEndian.Uint16(format[2:])) * 2
+    if perSec <= 0 { return 0, ErrNotPCMWAV }
+    return time.Duration(size * int64(time.Second) / perSec), nil
+}
+
+// quietest returns the frame in [from, to) at the centre of the quietest
+// 300 ms window, or of the middle of a run of equally quiet windows so cuts
+// land mid-pause.
+func quietest(rms []float64, from, to int) int {
+    const span = 300 / frameMs
+    if to > len(rms)-span { to = len(rms) - span }
+    first, last, bestLevel := from, from, -1.0
+    for i := from; i < to; i++ {
+        var sum float64
+        for _, v := range rms[i : i+span] { sum += v * v }
+        switch {
+        case bestLevel < 0 || sum < bestLevel-1e-9: first, last, bestLevel = i, i, sum
+        case sum <= bestLevel+1e-9 && last == i-1: last = i
+        }
+    }
+    return (first+last)/2 + span/2
+}
@@ -3,75 +3,243 @@ package stt
 import (
     "archive/zip"
     "bufio"
+    "bytes"
     "context"
+    "encoding/json"
     "errors"
     "fmt"
     "io"
     "log"
-    "net/http"
     "os"
     "os/exec"
     "path/filepath"
     "runtime"
     "strings"
+    "sync"
+    "sync/atomic"
     "time"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/procs"
+    "gollmcore/internal/scratch"
+    "gollmcore/internal/tracing"
 )
 
 type STTService struct {
     binDir    string
     modelDir  string
+    binary    BinaryOptions
+    mock      bool // see NewMock
+
+    gpuOnce   sync.Once
+    gpu       GPU         // resolved backend, see gpuBackend
+    gpuFailed atomic.Bool // a GPU run failed; stay on the CPU
 }
 
 func New(binDir, modelDir string) *STTService {
     return &STTService{binDir: binDir, modelDir: modelDir}
 }
 
+// NewWithBinary is New with a choice of where the whisper binary is
+// installed from on first use.
+func NewWithBinary(binDir, modelDir string, b BinaryOptions) *STTService {
+    return &STTService{binDir: binDir, modelDir: modelDir, binary: b}
+}
+
+// Options tune a single transcription.
+type Options struct {
+    // Prompt is passed to whisper as its initial prompt: text the audio is
+    // assumed to follow, used to suggest spellings of names and jargon.
+    Prompt     string
+    Preprocess Preprocess
+    // Timestamps keeps whisper's "[t0 --> t1]" prefix on streamed lines
+    // (see ParseTimedLine).
+    Timestamps bool
+}
+
+// MaxPromptTokens is the longest prompt whisper keeps: half of its
+// 448-token text context. Longer prompts are silently cut from the front.
+const MaxPromptTokens = 224
+
+// PromptTokens estimates the number of whisper tokens in prompt. BPE
+// averages about four characters per token for Latin text; other scripts
+// are counted as one token per character to stay on the safe side.
+func PromptTokens(prompt string) int {
+    ascii, other := 0, 0
+    for _, r := range prompt {
+        if r < 0x80 { ascii++ } else { other++ }
+    }
+    return (ascii+3)/4 + other
+}
+
+// CheckPrompt reports a prompt too long for whisper to keep whole.
+func CheckPrompt(prompt string) error {
+    if n := PromptTokens(prompt); n > MaxPromptTokens {
+        return fmt.Errorf("prompt is about %d tokens; whisper keeps at most %d", n, MaxPromptTokens)
+    }
+    return nil
+}
+
+// BuildPrompt joins a vocabulary of domain terms and a free-form prompt
+// into one whisper prompt.
+func BuildPrompt(prompt string, vocabulary []string) string {
+    var terms []string
+    for _, v := range vocabulary {
+        if v = strings.TrimSpace(v); v != "" { terms = append(terms, v) }
+    }
+    prompt = strings.TrimSpace(prompt)
+    if len(terms) == 0 { return prompt }
+    return strings.TrimSpace(strings.Join(terms, ", ") + ". " + prompt)
+}
+
+func (o Options) args() []string {
+    if o.Prompt == "" { return nil }
+    return []string{"--prompt", o.Prompt}
+}
+
 // TranscribeFile performs a non-streaming transcription and returns the final text.
-func (s *STTService) TranscribeFile(ctx context.Context, audioPath, modelSize string) (string, error) {
-    if err := s.ensureWhisperInstalled(ctx); err != nil { return "", err }
-    modelPath, err := s.ensureWhisperModel(ctx, modelSize)
+func (s *STTService) TranscribeFile(ctx context.Context, audioPath, modelSize string, opts Options) (string, error) {
+    if s.mock { return mockTranscript(audioPath) }
+    bin, modelPath, err := s.prepare(ctx, modelSize)
     if err != nil { return "", err }
-
-    bin, err := s.pickWhisperBinary()
+    audioPath, cleanup, err := s.preprocess(ctx, audioPath, opts.Preprocess)
     if err != nil { return "", err }
+    defer cleanup()
 
-    outPrefix := filepath.Join(os.TempDir(), fmt.Sprintf("whisper_out_%d", time.Now().UnixNano()))
-    args := []string{"-m", modelPath, "-f", audioPath, "-otxt", "-of", outPrefix, "-nt"}
-    cmd := exec.CommandContext(ctx, bin, args...)
-    cmd.Dir = s.binDir
-    cmd.Env = append(os.Environ(), s.libEnv()...)
-    cmd.Stdout = os.Stdout
-    cmd.Stderr = os.Stderr
-    if err := cmd.Run(); err != nil {
+    outPrefix, err := outputPrefix()
+    if err != nil { return "", err }
+    defer os.Remove(outPrefix)
+    txtPath := outPrefix + ".txt"
+    // whisper may leave a partial file behind when it fails or is killed.
+    defer os.Remove(txtPath)
+    args := append([]string{"-m", modelPath, "-f", audioPath, "-otxt", "-of", outPrefix, "-nt"}, opts.args()...)
+    if err := s.runWhisper(ctx, "stt.inference", bin, args, os.Stdout, os.Stderr); err != nil {
         return "", fmt.Errorf("whisper execution failed: %w", err)
     }
-    txtPath := outPrefix + ".txt"
+    _, span := tracing.Stage(ctx, "stt.decode")
     data, err := os.ReadFile(txtPath)
+    span.End(err)
     if err != nil { return "", fmt.Errorf("reading transcript: %w", err) }
-    _ = os.Remove(txtPath)
     return string(data), nil
 }
 
+// outputPrefix reserves a unique -of prefix in the scratch dir: the empty
+// file it creates keeps concurrent runs from picking the same name, and is
+// removed with the output.
+func outputPrefix() (string, error) {
+    f, err := scratch.Create("whisper_out_", "")
+    if err != nil { return "", err }
+    f.Close()
+    return f.Name(), nil
+}
+
+// DetectLanguage asks whisper for the spoken language of the first 30 s of
+// audioPath, returning its code (e.g. "de") and probability. English-only
+// (.en) models cannot detect languages.
+func (s *STTService) DetectLanguage(ctx context.Context, audioPath, modelSize string) (string, float64, error) {
+    if strings.HasSuffix(modelSize, ".en") { return "", 0, fmt.Errorf("model %s is English-only and cannot detect languages", modelSize) }
+    if s.mock { return "en", 1, nil }
+    bin, modelPath, err := s.prepare(ctx, modelSize)
+    if err != nil { return "", 0, err }
+
+    var out bytes.Buffer
+    if err := s.runWhisper(ctx, "stt.detect_language", bin, []string{"-m", modelPath, "-f", audioPath, "-l", "auto", "-dl"}, &out, &out); err != nil {
+        return "", 0, fmt.Errorf("whisper execution failed: %w", err)
+    }
+    // whisper_full_with_state: auto-detected language: de (p = 0.912345)
+    const marker = "auto-detected language: "
+    i := strings.LastIndex(out.String(), marker)
+    if i < 0 { return "", 0, errors.New("whisper did not report a language") }
+    var lang string
+    var p float64
+    if _, err := fmt.Sscanf(out.String()[i+len(marker):], "%s (p = %f)", &lang, &p); err != nil { return "", 0, fmt.Errorf("parsing whisper language: %w", err) }
+    return lang, p, nil
+}
+
+// Segment is a timed piece of a transcript.
+type Segment struct {
+    Start float64 `json:"start"` // seconds
+    End   float64 `json:"end"`
+    Text  string  `json:"text"`
+}
+
+// TranscribeSegments is TranscribeFile returning whisper's timed segments.
+func (s *STTService) TranscribeSegments(ctx context.Context, audioPath, modelSize string, opts Options) ([]Segment, error) {
+    if s.mock { return mockSegments(audioPath) }
+    bin, modelPath, err := s.prepare(ctx, modelSize)
+    if err != nil { return nil, err }
+    audioPath, cleanup, err := s.preprocess(ctx, audioPath, opts.Preprocess)
+    if err != nil { return nil, err }
+    defer cleanup()
+
+    outPrefix, err := outputPrefix()
+    if err != nil { return nil, err }
+    defer os.Remove(outPrefix)
+    jsonPath := outPrefix + ".json"
+    defer os.Remove(jsonPath)
+    args := append([]string{"-m", modelPath, "-f", audioPath, "-oj", "-of", outPrefix}, opts.args()...)
+    if err := s.runWhisper(ctx, "stt.inference", bin, args, os.Stdout, os.Stderr); err != nil {
+        return nil, fmt.Errorf("whisper execution failed: %w", err)
+    }
+    _, span := tracing.Stage(ctx, "stt.decode")
+    var out struct {
+        Transcription []struct {
+            Offsets struct{ From, To int64 } `json:"offsets"` // milliseconds
+            Text    string                   `json:"text"`
+        } `json:"transcription"`
+    }
+    data, err := os.ReadFile(jsonPath)
+    if err == nil { err = json.Unmarshal(data, &out) }
+    span.End(err)
+    if err != nil { return nil, fmt.Errorf("reading transcript: %w", err) }
+    segs := make([]Segment, 0, len(out.Transcription))
+    for _, t := range out.Transcription {
+        segs = append(segs, Segment{Start: float64(t.Offsets.From) / 1000, End: float64(t.Offsets.To) / 1000, Text: strings.TrimSpace(t.Text)})
+    }
+    return segs, nil
+}
+
+// ParseTimedLine parses a streamed whisper line such as
+// "[00:00:01.240 --> 00:00:04.000]  Hello there." into a Segment. Lines
+// without the prefix (log output, -nt runs) report false.
+func ParseTimedLine(line string) (Segment, bool) {
+    var h0, m0, h1, m1 int
+    var s0, s1 float64
+    var seg Segment
+    if _, err := fmt.Sscanf(line, "[%d:%d:%f --> %d:%d:%f]", &h0, &m0, &s0, &h1, &m1, &s1); err != nil { return seg, false }
+    i := strings.IndexByte(line, ']')
+    seg.Start = float64(h0*3600+m0*60) + s0
+    seg.End = float64(h1*3600+m1*60) + s1
+    seg.Text = strings.TrimSpace(line[i+1:])
+    return seg, true
+}
+
 // TranscribeFileStream runs whisper and streams its stdout lines.
-func (s *STTService) TranscribeFileStream(ctx context.Context, audioPath, modelSize string) (<-chan string, <-chan error) {
+func (s *STTService) TranscribeFileStream(ctx context.Context, audioPath, modelSize string, opts Options) (<-chan string, <-chan error) {
     lines := make(chan string)
     errs := make(chan error, 1)
     go func() {
         defer close(lines)
         defer close(errs)
-        if err := s.ensureWhisperInstalled(ctx); err != nil { errs <- err; return }
-        modelPath, err := s.ensureWhisperModel(ctx, modelSize)
+        if s.mock { mockStream(ctx, audioPath, opts.Timestamps, lines, errs); return }
+        bin, modelPath, err := s.prepare(ctx, modelSize)
         if err != nil { errs <- err; return }
-        bin, err := s.pickWhisperBinary()
+        audioPath, cleanup, err := s.preprocess(ctx, audioPath, opts.Preprocess)
         if err != nil { errs <- err; return }
+        defer cleanup()
 
-        args := []string{"-m", modelPath, "-f", audioPath, "-nt"}
+        args := append(append([]string{"-m", modelPath, "-f", audioPath}, opts.args()...), s.gpuArgs()...)
+        if !opts.Timestamps { args = append(args, "-nt") }
         cmd := exec.CommandContext(ctx, bin, args...)
         cmd.Dir = s.binDir
         cmd.Env = append(os.Environ(), s.libEnv()...)
         stdout, _ := cmd.StdoutPipe()
         stderr, _ := cmd.StderrPipe()
-        if err := cmd.Start(); err != nil { errs <- err; return }
+        _, span := tracing.Stage(ctx, "stt.inference")
+        done, err := procs.Start("whisper", cmd)
+        if err != nil { span.End(err); errs <- err; return }
+        defer done()
+        defer func() { span.End(ctx.Err()) }()
 
         scan := bufio.NewScanner(io.MultiReader(stdout, stderr))
         for scan.Scan() {
@@ -91,8 +259,30 @@ func (s *STTService) TranscribeFileStream(ctx context.Context, audioPath, modelS
     return lines, errs
 }
 
+// preprocess applies p to audioPath under an stt.preprocess span.
+func (s *STTService) preprocess(ctx context.Context, audioPath string, p Preprocess) (path string, cleanup func(), err error) {
+    if !p.Normalize && !p.Denoise { return audioPath, func() {}, nil }
+    err = tracing.Do(ctx, "stt.preprocess", func(context.Context) error {
+        path, cleanup, err = p.apply(audioPath)
+        return err
+    })
+    return path, cleanup, err
+}
+
 // ----- Installation helpers -----
 
+// prepare makes sure the whisper binary and model are present, downloading
+// them on first use, and returns their paths.
+func (s *STTService) prepare(ctx context.Context, modelSize string) (bin, modelPath string, err error) {
+    err = tracing.Do(ctx, "stt.download", func(ctx context.Context) error {
+        if err := s.ensureWhisperInstalled(ctx); err != nil { return err }
+        if modelPath, err = s.ensureWhisperModel(ctx, modelSize); err != nil { return err }
+        bin, err = s.pickWhisperBinary()
+        return err
+    })
+    return bin, modelPath, err
+}
+
 func (s *STTService) ensureWhisperInstalled(ctx context.Context) error {
     if err := os.MkdirAll(s.binDir, 0o755); err != nil { return err }
     // If any known binary exists, return
@@ -136,6 +326,20 @@ func (s *STTService) ensureWhisperModel(ctx context.Context, size string) (strin
     return dst, nil
 }
 
+// ModelMemory lists the whisper models from smallest to largest with the
+// memory whisper.cpp needs to run each (from the whisper.cpp README).
+var ModelMemory = []struct {
+    Size  string
+    Bytes uint64
+}{
+    {"tiny", 273 << 20},
+    {"base", 388 << 20},
+    {"small", 852 << 20},
+    {"medium", 2100 << 20},
+    {"large-v2", 3900 << 20},
+    {"large-v3", 3900 << 20},
+}
+
 func whisperModelURLs(size string) ([]string, string) {
     // Try reliable public sources (Hugging Face mirrors). Order matters.
     // Primary: ggerganov/whisper.cpp repo model files in main branch.
@@ -157,58 +361,25 @@ func whisperModelURLs(size string) ([]string, string) {
     }, file
 }
 
-// downloadWhisperBinary downloads the whisper.cpp binary for the current platform
+// downloadWhisperBinary installs whisper.cpp for the current platform and
+// CPU as s.binary directs (see planWhisper).
 func (s *STTService) downloadWhisperBinary(ctx context.Context) error {
-    var downloadURLs []string
-    var fileName string
-
-    switch runtime.GOOS {
-    case "windows":
-        if runtime.GOARCH == "amd64" || runtime.GOARCH == "x86_64" {
-            downloadURLs = []string{
-                "https://aliceai.ca/app_assets/whisper/whisper-windows.zip",
-            }
-            fileName = "whisper-windows.zip"
-        } else {
-            return fmt.Errorf("unsupported Windows architecture: %s", runtime.GOARCH)
-        }
-    case "darwin":
-        if runtime.GOARCH == "arm64" {
-            downloadURLs = []string{
-                "https://aliceai.ca/app_assets/whisper/whisper-macos-arm64.zip",
-            }
-            fileName = "whisper-macos-arm64.zip"
-        } else {
-            downloadURLs = []string{
-                "https://aliceai.ca/app_assets/whisper/whisper-macos-x64.zip",
-            }
-            fileName = "whisper-macos-x64.zip"
-        }
-    case "linux":
-        if runtime.GOARCH == "amd64" || runtime.GOARCH == "x86_64" {
-            downloadURLs = []string{
-                "https://aliceai.ca/app_assets/whisper/whisper-linux-x64.zip",
-            }
-            fileName = "whisper-linux-x64.zip"
-        } else {
-            return fmt.Errorf("unsupported Linux architecture: %s", runtime.GOARCH)
-        }
-    default:
-        return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
-    }
+    cpu, gpu := DetectCPU(), s.gpuBackend()
+    plan, err := planWhisper(s.binary, runtime.GOOS, runtime.GOARCH, cpu, gpu)
+    if err != nil { return err }
 
-    log.Printf("Downloading Whisper binary for %s/%s", runtime.GOOS, runtime.GOARCH)
+    log.Printf("Downloading Whisper binary for %s/%s (cpu: %s, gpu: %s)", runtime.GOOS, runtime.GOARCH, cpu, gpu)
 
     if err := os.MkdirAll(s.binDir, 0o755); err != nil {
         return fmt.Errorf("failed to create bin directory: %w", err)
     }
 
-    downloadPath := filepath.Join(s.binDir, fileName)
+    downloadPath := filepath.Join(s.binDir, plan.file)
     var lastErr error
 
-    for i, downloadURL := range downloadURLs {
-        log.Printf("Attempting binary download from source %d/%d: %s", i+1, len(downloadURLs), downloadURL)
-        if err := downloadFileWithRetry(downloadURL, downloadPath, 2, 30*time.Second); err != nil {
+    for i, downloadURL := range plan.urls {
+        log.Printf("Attempting binary download from source %d/%d: %s", i+1, len(plan.urls), downloadURL)
+        if err := downloadFileWithRetry(downloadURL, downloadPath, 2, binaryTimeout); err != nil {
             lastErr = err
             log.Printf("Binary download source %d failed: %v", i+1, err)
             continue
@@ -221,7 +392,9 @@ func (s *STTService) downloadWhisperBinary(ctx context.Context) error {
     }
 
     defer os.Remove(downloadPath)
-    if err := s.extractWhisperBinary(downloadPath); err != nil {
+    if plan.build {
+        if err := s.buildWhisper(ctx, downloadPath, cpu, gpu); err != nil { return fmt.Errorf("failed to build whisper: %w", err) }
+    } else if err := s.extractWhisperBinary(downloadPath); err != nil {
         return fmt.Errorf("failed to extract whisper binary: %w", err)
     }
 
@@ -363,22 +536,5 @@ func downloadFileWithRetry(url, dst string, retries int, timeout time.Duration)
     return last
 }
 
-func downloadFile(url, dst string, timeout time.Duration) error {
-    req, err := http.NewRequest(http.MethodGet, url, nil)
-    if err != nil { return err }
-    req.Header.Set("User-Agent", "GoLLMCore/1.0 (+https://localhost)")
-    req.Header.Set("Accept", "application/octet-stream")
-    client := &http.Client{ Timeout: timeout }
-    resp, err := client.Do(req)
-    if err != nil { return err }
-    defer resp.Body.Close()
-    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-        return fmt.Errorf("bad status: %s", resp.Status)
-    }
-    tmp := dst + ".part"
-    out, err := os.Create(tmp)
-    if err != nil { return err }
-    if _, err := io.Copy(out, resp.Body); err != nil { out.Close(); return err }
-    out.Close()
-    return os.Rename(tmp, dst)
-}
+// downloadFile fetches url into dst, pinned and recorded by models.lock.
+func downloadFile(url, dst string, timeout time.Duration) error { return models.Fetch(url, dst, timeout) }
@@ -0,0 +1,25 @@
+package tts
+
+import (
+    "context"
+    "fmt"
+    "path/filepath"
+)
+
+// Engine synthesizes speech; Service (Piper), Kokoro and Mock are engines.
+type Engine interface {
+    Synthesize(ctx context.Context, text, voice string) ([]byte, error)
+}
+
+// NewEngine returns the engine named by services.tts.engine: "piper" (the
+// default when name is empty) or "kokoro". Binaries are looked up in binDir,
+// models kept under modelsDir/tts or modelsDir/kokoro.
+func NewEngine(name, binDir, modelsDir, workDir string) (Engine, error) {
+    switch name {
+    case "", "piper":
+        return New(binDir, filepath.Join(modelsDir, "tts"), workDir), nil
+    case "kokoro":
+        return NewKokoro(binDir, filepath.Join(modelsDir, "kokoro")), nil
+    }
+    return nil, fmt.Errorf("unknown tts engine: %s", name)
+}
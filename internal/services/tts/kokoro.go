@@ -0,0 +1,265 @@
+package tts
+
+import (
+    "bytes"
+    "context"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+
+    ort "github.com/yalue/onnxruntime_go"
+
+    "gollmcore/internal/onnxrt"
+    "gollmcore/internal/procs"
+    "gollmcore/internal/tracing"
+)
+
+// Kokoro-82M ONNX backend. Text is phonemized with espeak-ng, mapped to the
+// model vocabulary and synthesized in-process with onnxruntime at 24kHz.
+
+const (
+    kokoroSampleRate   = 24000
+    kokoroStyleDim     = 256
+    kokoroMaxPhonemes  = 510
+    kokoroChunkChars   = 200
+    kokoroDefaultVoice = "af_heart"
+)
+
+var kokoroVoiceRE = regexp.MustCompile(`^[a-z]{2}_[a-z0-9]+$`)
+
+type Kokoro struct {
+    binDir   string
+    modelDir string
+
+    mu      sync.Mutex
+    session *ort.DynamicAdvancedSession
+    vocab   map[rune]int64
+    voices  map[string][]float32
+}
+
+// NewKokoro returns a Kokoro engine; model and voices download lazily.
+// binDir is searched for an espeak-ng executable (e.g., one shipped with Piper).
+func NewKokoro(binDir, modelDir string) *Kokoro {
+    return &Kokoro{binDir: binDir, modelDir: modelDir, voices: map[string][]float32{}}
+}
+
+func (k *Kokoro) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    if strings.TrimSpace(text) == "" { return nil, fmt.Errorf("empty text") }
+    if voice == "" { voice = kokoroDefaultVoice }
+    if !kokoroVoiceRE.MatchString(voice) { return nil, fmt.Errorf("unsupported voice: %s", voice) }
+    var style []float32
+    err := tracing.Do(ctx, "tts.download", func(context.Context) (err error) {
+        if err = k.ensureSession(); err != nil { return err }
+        style, err = k.ensureVoice(voice)
+        return err
+    })
+    if err != nil { return nil, err }
+
+    var pcm []byte
+    for i, chunk := range SplitSentences(text, kokoroChunkChars) {
+        if err := ctx.Err(); err != nil { return nil, err }
+        var ids []int64
+        err := tracing.Do(ctx, "tts.tokenize", func(ctx context.Context) error {
+            phonemes, err := k.phonemize(ctx, chunk, espeakLang(voice))
+            ids = k.tokenize(phonemes)
+            return err
+        })
+        if err != nil { return nil, err }
+        if len(ids) == 0 { continue }
+        var samples []float32
+        err = tracing.Do(ctx, "tts.inference", func(context.Context) (err error) {
+            samples, err = k.infer(ids, style)
+            return err
+        })
+        if err != nil { return nil, err }
+        if i > 0 { pcm = append(pcm, make([]byte, kokoroSampleRate*chunkPauseMs/1000*2)...) }
+        pcm = append(pcm, floatToPCM16(samples)...)
+    }
+    if len(pcm) == 0 { return nil, fmt.Errorf("no speakable text") }
+    return BuildWAV(WAVFormat{AudioFormat: 1, Channels: 1, SampleRate: kokoroSampleRate, BitsPerSample: 16}, pcm), nil
+}
+
+func (k *Kokoro) infer(ids []int64, voicePack []float32) ([]float32, error) {
+    if len(ids) > kokoroMaxPhonemes { ids = ids[:kokoroMaxPhonemes] }
+    // The style vector is selected by phoneme count; ids are padded with 0 on both ends.
+    off := len(ids) * kokoroStyleDim
+    if off+kokoroStyleDim > len(voicePack) { off = len(voicePack) - kokoroStyleDim }
+    style := append([]float32(nil), voicePack[off:off+kokoroStyleDim]...)
+    padded := make([]int64, 0, len(ids)+2)
+    padded = append(padded, 0)
+    padded = append(padded, ids...)
+    padded = append(padded, 0)
+
+    inIDs, err := ort.NewTensor[int64](ort.NewShape(1, int64(len(padded))), padded)
+    if err != nil { return nil, err }
+    defer inIDs.Destroy()
+    inStyle, err := ort.NewTensor[float32](ort.NewShape(1, kokoroStyleDim), style)
+    if err != nil { return nil, err }
+    defer inStyle.Destroy()
+    inSpeed, err := ort.NewTensor[float32](ort.NewShape(1), []float32{1.0})
+    if err != nil { return nil, err }
+    defer inSpeed.Destroy()
+
+    outputs := make([]ort.Value, 1)
+    if err := k.session.Run([]ort.Value{inIDs, inStyle, inSpeed}, outputs); err != nil { return nil, err }
+    defer outputs[0].Destroy()
+    t, ok := outputs[0].(*ort.Tensor[float32])
+    if !ok { return nil, errors.New("unexpected kokoro output type") }
+    return append([]float32(nil), t.GetData()...), nil
+}
+
+// -------- Model, vocab and voices --------
+
+func (k *Kokoro) ensureSession() error {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    if k.session != nil { return nil }
+    if err := os.MkdirAll(k.modelDir, 0o755); err != nil { return fmt.Errorf("kokoro model dir: %w", err) }
+    if err := onnxrt.Init(); err != nil { return err }
+
+    modelPath := filepath.Join(k.modelDir, "model.onnx")
+    if !fileExists(modelPath) {
+        urls := []string{"https://huggingface.co/onnx-community/Kokoro-82M-v1.0-ONNX/resolve/main/onnx/model.onnx"}
+        if err := onnxrt.TryDownload(urls, modelPath, 3, 600*time.Second); err != nil { return fmt.Errorf("kokoro model: %w", err) }
+    }
+    configPath := filepath.Join(k.modelDir, "config.json")
+    if !fileExists(configPath) {
+        urls := []string{"https://huggingface.co/hexgrad/Kokoro-82M/resolve/main/config.json"}
+        if err := onnxrt.TryDownload(urls, configPath, 3, 60*time.Second); err != nil { return fmt.Errorf("kokoro config: %w", err) }
+    }
+    vocab, err := loadKokoroVocab(configPath)
+    if err != nil { return err }
+
+    sess, err := onnxrt.NewSession(modelPath, []string{"input_ids", "style", "speed"}, []string{"waveform"}, onnxrt.Options{})
+    if err != nil { return err }
+    onnxrt.SessionOpened("kokoro")
+    k.vocab = vocab
+    k.session = sess
+    return nil
+}
+
+func loadKokoroVocab(path string) (map[rune]int64, error) {
+    b, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    var cfg struct{ Vocab map[string]int64 `json:"vocab"` }
+    if err := json.Unmarshal(b, &cfg); err != nil { return nil, fmt.Errorf("parse kokoro config: %w", err) }
+    vocab := make(map[rune]int64, len(cfg.Vocab))
+    for sym, id := range cfg.Vocab {
+        r := []rune(sym)
+        if len(r) == 1 { vocab[r[0]] = id }
+    }
+    if len(vocab) == 0 { return nil, fmt.Errorf("kokoro config has no vocab") }
+    return vocab, nil
+}
+
+func (k *Kokoro) ensureVoice(voice string) ([]float32, error) {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    if v, ok := k.voices[voice]; ok { return v, nil }
+    vdir := filepath.Join(k.modelDir, "voices")
+    if err := os.MkdirAll(vdir, 0o755); err != nil { return nil, err }
+    path := filepath.Join(vdir, voice+".bin")
+    if !fileExists(path) {
+        urls := []string{"https://huggingface.co/onnx-community/Kokoro-82M-v1.0-ONNX/resolve/main/voices/" + voice + ".bin"}
+        if err := onnxrt.TryDownload(urls, path, 3, 120*time.Second); err != nil { return nil, fmt.Errorf("kokoro voice %s: %w", voice, err) }
+    }
+    b, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    if len(b) < kokoroStyleDim*4 || len(b)%4 != 0 { return nil, fmt.Errorf("invalid voice file: %s", path) }
+    pack := make([]float32, len(b)/4)
+    for i := range pack { pack[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:])) }
+    k.voices[voice] = pack
+    return pack, nil
+}
+
+// -------- Phonemization --------
+
+// espeakLang maps the Kokoro voice prefix letter to an espeak-ng language.
+func espeakLang(voice string) string {
+    switch voice[0] {
+    case 'b': return "en-gb"
+    case 'e': return "es"
+    case 'f': return "fr-fr"
+    case 'h': return "hi"
+    case 'i': return "it"
+    case 'p': return "pt-br"
+    case 'j': return "ja"
+    case 'z': return "cmn"
+    default: return "en-us"
+    }
+}
+
+var kokoroPunct = regexp.MustCompile(`[,.!?;:]+`)
+
+// phonemize converts text to IPA with espeak-ng, keeping punctuation in place
+// since the model uses it for prosody.
+func (k *Kokoro) phonemize(ctx context.Context, text, lang string) (string, error) {
+    bin := k.espeakPath()
+    if bin == "" { return "", fmt.Errorf("espeak-ng not found in PATH or %s (required for kokoro)", k.binDir) }
+    var out strings.Builder
+    last := 0
+    locs := append(kokoroPunct.FindAllStringIndex(text, -1), []int{len(text), len(text)})
+    for _, loc := range locs {
+        if seg := strings.TrimSpace(text[last:loc[0]]); seg != "" {
+            cmd := exec.CommandContext(ctx, bin, "-q", "--ipa=1", "-v", lang, seg)
+            if filepath.IsAbs(bin) {
+                cmd.Env = append(os.Environ(), "ESPEAK_DATA_PATH="+filepath.Join(filepath.Dir(bin), "espeak-ng-data"))
+            }
+            var stdout, stderr bytes.Buffer
+            cmd.Stdout, cmd.Stderr = &stdout, &stderr
+            if err := procs.Run("espeak-ng", cmd); err != nil { return "", fmt.Errorf("espeak-ng failed: %v: %s", err, stderr.String()) }
+            if out.Len() > 0 { out.WriteByte(' ') }
+            out.WriteString(strings.Join(strings.Fields(stdout.String()), " "))
+        }
+        out.WriteString(text[loc[0]:loc[1]])
+        last = loc[1]
+    }
+    return normalizeKokoroIPA(out.String()), nil
+}
+
+// espeak IPA (with U+0361 ties) to the symbol set Kokoro was trained on.
+var kokoroIPAReplacer = strings.NewReplacer(
+    "a͡ɪ", "I", "a͡ʊ", "W", "e͡ɪ", "A", "o͡ʊ", "O", "ɔ͡ɪ", "Y", "ə͡ʊ", "Q",
+    "d͡ʒ", "ʤ", "t͡ʃ", "ʧ", "ɚ", "əɹ", "ɜːɹ", "ɜɹ", "r", "ɹ", "x", "k", "ɬ", "l", "͡", "",
+)
+
+func normalizeKokoroIPA(s string) string { return kokoroIPAReplacer.Replace(s) }
+
+func (k *Kokoro) tokenize(phonemes string) []int64 {
+    ids := make([]int64, 0, len(phonemes))
+    for _, r := range phonemes {
+        if id, ok := k.vocab[r]; ok { ids = append(ids, id) }
+    }
+    return ids
+}
+
+func (k *Kokoro) espeakPath() string {
+    names := map[string]bool{"espeak-ng": true, "espeak-ng.exe": true}
+    var found string
+    filepath.WalkDir(k.binDir, func(path string, d os.DirEntry, err error) error {
+        if err != nil || d.IsDir() { return nil }
+        if names[filepath.Base(path)] { found = path; return filepath.SkipAll }
+        return nil
+    })
+    if found != "" { return found }
+    if p, err := exec.LookPath("espeak-ng"); err == nil { return p }
+    return ""
+}
+
+func floatToPCM16(samples []float32) []byte {
+    out := make([]byte, len(samples)*2)
+    for i, v := range samples {
+        if v > 1 { v = 1 } else if v < -1 { v = -1 }
+        binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(v*32767)))
+    }
+    return out
+}
@@ -9,13 +9,24 @@ import (
     "fmt"
     "io"
     "log"
-    "net/http"
     "os"
     "os/exec"
     "path/filepath"
     "runtime"
     "strings"
     "time"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/procs"
+    "gollmcore/internal/scratch"
+    "gollmcore/internal/tracing"
+)
+
+const (
+    // maxChunkChars bounds the text handed to a single piper run.
+    maxChunkChars = 400
+    // chunkPauseMs is the silence inserted between stitched chunks.
+    chunkPauseMs = 150
 )
 
 type Service struct {
@@ -29,21 +40,48 @@ func New(binDir, modelDir, workDir string) *Service {
 }
 
 func (s *Service) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
-    if text == "" { return nil, fmt.Errorf("empty text") }
+    if strings.TrimSpace(text) == "" { return nil, fmt.Errorf("empty text") }
     if voice == "" { voice = "en_US-amy-medium" }
-    if err := s.ensurePiperInstalled(ctx); err != nil { return nil, err }
-    modelPath, err := s.ensureVoiceModel(ctx, voice)
+    var modelPath string
+    err := tracing.Do(ctx, "tts.download", func(ctx context.Context) (err error) {
+        if err = s.ensurePiperInstalled(ctx); err != nil { return err }
+        modelPath, err = s.ensureVoiceModel(ctx, voice)
+        return err
+    })
     if err != nil { return nil, err }
     piper := s.piperBinaryPath()
     if piper == "" { return nil, fmt.Errorf("piper binary not found") }
 
-    outPath := filepath.Join(os.TempDir(), fmt.Sprintf("piper_out_%d.wav", time.Now().UnixNano()))
+    // Piper degrades on long inputs; synthesize sentence chunks and stitch them.
+    chunks := SplitSentences(text, maxChunkChars)
+    parts := make([][]byte, 0, len(chunks))
+    for _, chunk := range chunks {
+        if err := ctx.Err(); err != nil { return nil, err }
+        var audio []byte
+        err := tracing.Do(ctx, "tts.inference", func(ctx context.Context) (err error) {
+            audio, err = s.synthesizeChunk(ctx, modelPath, chunk)
+            return err
+        })
+        if err != nil { return nil, err }
+        parts = append(parts, audio)
+    }
+    _, span := tracing.Stage(ctx, "tts.decode")
+    wav, err := StitchWAV(parts, chunkPauseMs)
+    span.End(err)
+    return wav, err
+}
+
+func (s *Service) synthesizeChunk(ctx context.Context, modelPath, text string) ([]byte, error) {
+    out, err := scratch.Create("piper_out_", ".wav")
+    if err != nil { return nil, err }
+    out.Close()
+    outPath := out.Name()
     defer os.Remove(outPath)
     cmd, err := s.piperExecCommand(ctx, modelPath, outPath, text)
     if err != nil { return nil, err }
     var stderr bytes.Buffer
     cmd.Stderr = &stderr
-    if err := cmd.Run(); err != nil {
+    if err := procs.Run("piper", cmd); err != nil {
         return nil, fmt.Errorf("piper failed: %v: %s", err, stderr.String())
     }
     data, err := os.ReadFile(outPath)
@@ -267,22 +305,8 @@ func downloadFileWithRetry(url, dst string, retries int, timeout time.Duration)
     return last
 }
 
-func downloadFile(url, dst string, timeout time.Duration) error {
-    req, err := http.NewRequest(http.MethodGet, url, nil)
-    if err != nil { return err }
-    req.Header.Set("User-Agent", "GoLLMCore/1.0")
-    req.Header.Set("Accept", "application/octet-stream")
-    client := &http.Client{ Timeout: timeout }
-    resp, err := client.Do(req)
-    if err != nil { return err }
-    defer resp.Body.Close()
-    if resp.StatusCode < 200 || resp.StatusCode >= 300 { return fmt.Errorf("bad status: %s", resp.Status) }
-    tmp := dst + ".part"
-    out, err := os.Create(tmp); if err != nil { return err }
-    if _, err := io.Copy(out, resp.Body); err != nil { out.Close(); return err }
-    out.Close()
-    return os.Rename(tmp, dst)
-}
+// downloadFile fetches url into dst, pinned and recorded by models.lock.
+func downloadFile(url, dst string, timeout time.Duration) error { return models.Fetch(url, dst, timeout) }
 
 // libEnv no longer used; env built per binary dir
 
@@ -0,0 +1,165 @@
+package tts
+
+import (
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "strings"
+    "unicode"
+)
+
+// WAVFormat holds the fields of a PCM "fmt " chunk.
+type WAVFormat struct {
+    AudioFormat   uint16
+    Channels      uint16
+    SampleRate    uint32
+    BitsPerSample uint16
+}
+
+func (f WAVFormat) blockAlign() uint16 { return f.Channels * f.BitsPerSample / 8 }
+
+// ParseWAV returns the format and raw sample data of a RIFF/WAVE file. A
+// data chunk cut short, as in streamed headers, yields the bytes present.
+func ParseWAV(b []byte) (WAVFormat, []byte, error) {
+    var f WAVFormat
+    if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+        return f, nil, fmt.Errorf("not a wav file")
+    }
+    var data []byte
+    haveFmt := false
+    for off := 12; off+8 <= len(b); {
+        id := string(b[off : off+4])
+        size := int(binary.LittleEndian.Uint32(b[off+4 : off+8]))
+        body := off + 8
+        end := body + size
+        if end > len(b) { end = len(b) } // tolerate truncated streaming headers
+        switch id {
+        case "fmt ":
+            if end-body < 16 { return f, nil, fmt.Errorf("short fmt chunk") }
+            f.AudioFormat = binary.LittleEndian.Uint16(b[body:])
+            f.Channels = binary.LittleEndian.Uint16(b[body+2:])
+            f.SampleRate = binary.LittleEndian.Uint32(b[body+4:])
+            f.BitsPerSample = binary.LittleEndian.Uint16(b[body+14:])
+            haveFmt = true
+        case "data":
+            data = b[body:end]
+        }
+        off = body + size + size%2
+    }
+    if !haveFmt || data == nil { return f, nil, fmt.Errorf("wav missing fmt or data chunk") }
+    return f, data, nil
+}
+
+// BuildWAV wraps raw sample data in a canonical 44-byte header.
+func BuildWAV(f WAVFormat, data []byte) []byte {
+    var buf bytes.Buffer
+    buf.Grow(44 + len(data))
+    buf.WriteString("RIFF")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(data)))
+    buf.WriteString("WAVEfmt ")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+    _ = binary.Write(&buf, binary.LittleEndian, f.AudioFormat)
+    _ = binary.Write(&buf, binary.LittleEndian, f.Channels)
+    _ = binary.Write(&buf, binary.LittleEndian, f.SampleRate)
+    _ = binary.Write(&buf, binary.LittleEndian, f.SampleRate*uint32(f.blockAlign()))
+    _ = binary.Write(&buf, binary.LittleEndian, f.blockAlign())
+    _ = binary.Write(&buf, binary.LittleEndian, f.BitsPerSample)
+    buf.WriteString("data")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+    buf.Write(data)
+    return buf.Bytes()
+}
+
+// StitchWAV concatenates WAV files of identical format, inserting pause
+// milliseconds of silence between them, into one continuous file.
+func StitchWAV(parts [][]byte, pauseMs int) ([]byte, error) {
+    if len(parts) == 0 { return nil, fmt.Errorf("no audio to stitch") }
+    if len(parts) == 1 { return parts[0], nil }
+    var format WAVFormat
+    var out []byte
+    for i, p := range parts {
+        f, data, err := ParseWAV(p)
+        if err != nil { return nil, fmt.Errorf("chunk %d: %w", i, err) }
+        if i == 0 {
+            format = f
+        } else {
+            if f != format { return nil, fmt.Errorf("chunk %d: wav format mismatch", i) }
+            silence := int(format.SampleRate) * pauseMs / 1000 * int(format.blockAlign())
+            out = append(out, make([]byte, silence)...)
+        }
+        out = append(out, data...)
+    }
+    return BuildWAV(format, out), nil
+}
+
+// abbreviations end with a period that does not end a sentence.
+var abbreviations = map[string]bool{
+    "mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true, "st": true,
+    "vs": true, "etc": true, "e.g": true, "i.e": true, "no": true, "fig": true, "approx": true,
+}
+
+// endsAbbreviation reports whether the word ending at runes[i], a period,
+// is an abbreviation or an initial such as the J in "J. Smith".
+func endsAbbreviation(runes []rune, i int) bool {
+    start := i
+    for start > 0 && !unicode.IsSpace(runes[start-1]) { start-- }
+    word := strings.ToLower(strings.TrimLeft(string(runes[start:i]), "(\"'"))
+    if abbreviations[word] { return true }
+    w := []rune(word)
+    return len(w) == 1 && unicode.IsLetter(w[0])
+}
+
+// SplitSentences breaks text into chunks of at most maxChars, preferring
+// sentence boundaries, then whitespace, and only cutting words as a last
+// resort. Periods of abbreviations and decimals do not end sentences, and
+// blank text yields no chunks.
+func SplitSentences(text string, maxChars int) []string {
+    text = strings.TrimSpace(text)
+    if text == "" { return nil }
+    if len([]rune(text)) <= maxChars { return []string{text} }
+
+    var sentences []string
+    var cur strings.Builder
+    runes := []rune(text)
+    for i, r := range runes {
+        cur.WriteRune(r)
+        if r == '.' || r == '!' || r == '?' || r == '\n' || r == '。' || r == '！' || r == '？' {
+            if (i+1 == len(runes) || unicode.IsSpace(runes[i+1])) && !(r == '.' && endsAbbreviation(runes, i)) {
+                if s := strings.TrimSpace(cur.String()); s != "" { sentences = append(sentences, s) }
+                cur.Reset()
+            }
+        }
+    }
+    if s := strings.TrimSpace(cur.String()); s != "" { sentences = append(sentences, s) }
+
+    var chunks []string
+    var chunk string
+    flush := func() { if chunk != "" { chunks = append(chunks, chunk); chunk = "" } }
+    for _, s := range sentences {
+        for len([]rune(s)) > maxChars {
+            flush()
+            head, rest := cutAtSpace(s, maxChars)
+            chunks = append(chunks, head)
+            s = rest
+        }
+        if chunk == "" {
+            chunk = s
+        } else if len([]rune(chunk))+1+len([]rune(s)) <= maxChars {
+            chunk += " " + s
+        } else {
+            flush()
+            chunk = s
+        }
+    }
+    flush()
+    return chunks
+}
+
+func cutAtSpace(s string, maxChars int) (string, string) {
+    runes := []rune(s)
+    cut := maxChars
+    for i := maxChars; i > maxChars/2; i-- {
+        if unicode.IsSpace(runes[i]) { cut = i; break }
+    }
+    return strings.TrimSpace(string(runes[:cut])), strings.TrimSpace(string(runes[cut:]))
+}
@@ -0,0 +1,275 @@
+// Package sessions persists conversation history so thin clients can refer
+// to a session id instead of resending the whole context every turn.
+package sessions
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+
+    "gollmcore/internal/services/llm"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+var bucket = []byte("sessions")
+
+type Session struct {
+    ID        string            `json:"id"`
+    Metadata  map[string]string `json:"metadata,omitempty"`
+    Messages  []llm.Message     `json:"messages"`
+    CreatedAt time.Time         `json:"created_at"`
+    UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Summary is a session without its messages, for listings.
+type Summary struct {
+    ID           string            `json:"id"`
+    Metadata     map[string]string `json:"metadata,omitempty"`
+    MessageCount int               `json:"message_count"`
+    CreatedAt    time.Time         `json:"created_at"`
+    UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Options bound how much stored history is sent to the model. Zero
+// disables a limit.
+type Options struct {
+    MaxHistoryMessages  int
+    MaxHistoryChars     int
+    // CompressAfterTokens triggers summarization of older turns once the
+    // estimated history size passes it (0 disables compression).
+    CompressAfterTokens int
+    // KeepRecent is how many of the latest messages survive compression verbatim.
+    KeepRecent          int
+    // SummaryPrompt instructs the model how to summarize older turns.
+    SummaryPrompt       string
+}
+
+// SummaryPrefix starts the system message that replaces compressed turns.
+const SummaryPrefix = "Summary of the earlier conversation: "
+
+const DefaultSummaryPrompt = "Summarize the conversation below so it can replace the original messages. Keep names, facts, decisions, open questions and user preferences. Be concise."
+
+// Store keeps sessions in a bbolt file. The file is opened on first use so
+// a warm standby does not block on the lock held by the active instance.
+type Store struct {
+    path       string
+    opts       Options
+    mu         sync.Mutex
+    db         *bolt.DB
+    compressMu sync.Mutex
+    tenants    map[string]*Store
+}
+
+func New(path string, o Options) *Store { return &Store{path: path, opts: o} }
+
+// Tenant returns the store of the named tenant: a file of the same name in
+// tenants/<name>/ beside this one, with the same options. The empty name
+// is s itself. Callers validate names; they become directory names.
+func (s *Store) Tenant(name string) *Store {
+    if name == "" { return s }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if t := s.tenants[name]; t != nil { return t }
+    if s.tenants == nil { s.tenants = map[string]*Store{} }
+    t := New(filepath.Join(filepath.Dir(s.path), "tenants", name, filepath.Base(s.path)), s.opts)
+    s.tenants[name] = t
+    return t
+}
+
+// History returns the session's messages trimmed to the store limits.
+func (s *Store) History(sess Session) []llm.Message {
+    return Trim(sess.Messages, s.opts.MaxHistoryMessages, s.opts.MaxHistoryChars)
+}
+
+// Compress summarizes older turns once the session grows past
+// CompressAfterTokens, replacing them with a single summary system message.
+// The latest KeepRecent messages and the session's own system messages are
+// kept as they are. It reports whether the session was rewritten.
+func (s *Store) Compress(ctx context.Context, id string, summarize func(ctx context.Context, msgs []llm.Message) (string, error)) (Session, bool, error) {
+    s.compressMu.Lock()
+    defer s.compressMu.Unlock()
+    sess, err := s.Get(id)
+    if err != nil || s.opts.CompressAfterTokens <= 0 || EstimateTokens(sess.Messages) <= s.opts.CompressAfterTokens { return sess, false, err }
+
+    keep := s.opts.KeepRecent
+    if keep <= 0 { keep = 6 }
+    cut := len(sess.Messages) - keep
+    for cut > 0 && sess.Messages[cut].Role != "user" { cut-- }
+    var old []llm.Message
+    for _, m := range sess.Messages[:cut] {
+        if m.Role != "system" || strings.HasPrefix(m.Content, SummaryPrefix) { old = append(old, m) }
+    }
+    if len(old) < 2 { return sess, false, nil }
+    summary, err := summarize(ctx, old)
+    if err != nil { return sess, false, err }
+
+    db, err := s.open()
+    if err != nil { return sess, false, err }
+    err = db.Update(func(tx *bolt.Tx) error {
+        cur, err := get(tx, id)
+        if err != nil { return err }
+        // Other writers only append, so the first cut messages are unchanged.
+        msgs := make([]llm.Message, 0, len(cur.Messages)-cut+2)
+        for _, m := range cur.Messages[:cut] {
+            if m.Role == "system" && !strings.HasPrefix(m.Content, SummaryPrefix) { msgs = append(msgs, m) }
+        }
+        msgs = append(msgs, llm.Message{Role: "system", Content: SummaryPrefix + strings.TrimSpace(summary)})
+        cur.Messages = append(msgs, cur.Messages[cut:]...)
+        cur.UpdatedAt = time.Now().UTC()
+        sess = cur
+        return put(tx, cur)
+    })
+    return sess, err == nil, err
+}
+
+// SummaryPrompt returns the configured summarization prompt.
+func (s *Store) SummaryPrompt() string {
+    if s.opts.SummaryPrompt != "" { return s.opts.SummaryPrompt }
+    return DefaultSummaryPrompt
+}
+
+// EstimateTokens approximates the token count of msgs (about 4 characters
+// per token), which is close enough to decide when to compress.
+func EstimateTokens(msgs []llm.Message) int {
+    n := 0
+    for _, m := range msgs { n += len(m.Content)/4 + 4 }
+    return n
+}
+
+func (s *Store) open() (*bolt.DB, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.db != nil { return s.db, nil }
+    if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil { return nil, err }
+    db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+    if err != nil { return nil, err }
+    if err := db.Update(func(tx *bolt.Tx) error { _, err := tx.CreateBucketIfNotExists(bucket); return err }); err != nil {
+        db.Close()
+        return nil, err
+    }
+    s.db = db
+    return db, nil
+}
+
+// Close releases the database file and those of its tenants.
+func (s *Store) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var err error
+    for _, t := range s.tenants { err = errors.Join(err, t.Close()) }
+    if s.db == nil { return err }
+    err = errors.Join(err, s.db.Close())
+    s.db = nil
+    return err
+}
+
+func (s *Store) Create(metadata map[string]string, messages []llm.Message) (Session, error) {
+    now := time.Now().UTC()
+    b := make([]byte, 12)
+    _, _ = rand.Read(b)
+    sess := Session{ID: "sess_" + hex.EncodeToString(b), Metadata: metadata, Messages: messages, CreatedAt: now, UpdatedAt: now}
+    if sess.Messages == nil { sess.Messages = []llm.Message{} }
+    db, err := s.open()
+    if err != nil { return Session{}, err }
+    return sess, db.Update(func(tx *bolt.Tx) error { return put(tx, sess) })
+}
+
+func (s *Store) Get(id string) (Session, error) {
+    db, err := s.open()
+    if err != nil { return Session{}, err }
+    var sess Session
+    err = db.View(func(tx *bolt.Tx) error {
+        var err error
+        sess, err = get(tx, id)
+        return err
+    })
+    return sess, err
+}
+
+// Append adds messages to the end of a session.
+func (s *Store) Append(id string, messages ...llm.Message) (Session, error) {
+    db, err := s.open()
+    if err != nil { return Session{}, err }
+    var sess Session
+    err = db.Update(func(tx *bolt.Tx) error {
+        var err error
+        if sess, err = get(tx, id); err != nil { return err }
+        sess.Messages = append(sess.Messages, messages...)
+        sess.UpdatedAt = time.Now().UTC()
+        return put(tx, sess)
+    })
+    return sess, err
+}
+
+func (s *Store) Delete(id string) error {
+    db, err := s.open()
+    if err != nil { return err }
+    return db.Update(func(tx *bolt.Tx) error {
+        bk := tx.Bucket(bucket)
+        if bk.Get([]byte(id)) == nil { return ErrNotFound }
+        return bk.Delete([]byte(id))
+    })
+}
+
+// List returns all sessions, most recently updated first.
+func (s *Store) List() ([]Summary, error) {
+    db, err := s.open()
+    if err != nil { return nil, err }
+    out := []Summary{}
+    err = db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucket).ForEach(func(_, v []byte) error {
+            var sess Session
+            if err := json.Unmarshal(v, &sess); err != nil { return err }
+            out = append(out, Summary{ID: sess.ID, Metadata: sess.Metadata, MessageCount: len(sess.Messages), CreatedAt: sess.CreatedAt, UpdatedAt: sess.UpdatedAt})
+            return nil
+        })
+    })
+    sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+    return out, err
+}
+
+func get(tx *bolt.Tx, id string) (Session, error) {
+    v := tx.Bucket(bucket).Get([]byte(id))
+    if v == nil { return Session{}, ErrNotFound }
+    var sess Session
+    err := json.Unmarshal(v, &sess)
+    return sess, err
+}
+
+func put(tx *bolt.Tx, sess Session) error {
+    b, err := json.Marshal(sess)
+    if err != nil { return err }
+    return tx.Bucket(bucket).Put([]byte(sess.ID), b)
+}
+
+// Trim returns the most recent messages that fit in maxMessages and
+// maxChars (zero disables a limit). System messages are always kept, and
+// the cut never starts on an assistant reply or a tool result, so the
+// history handed to the model begins with a user turn.
+func Trim(messages []llm.Message, maxMessages, maxChars int) []llm.Message {
+    var system, rest []llm.Message
+    for _, m := range messages {
+        if m.Role == "system" { system = append(system, m) } else { rest = append(rest, m) }
+    }
+    start, chars := len(rest), 0
+    for start > 0 {
+        n := len(rest[start-1].Content)
+        if maxMessages > 0 && len(rest)-start >= maxMessages { break }
+        if maxChars > 0 && chars+n > maxChars { break }
+        chars += n
+        start--
+    }
+    for start < len(rest) && rest[start].Role != "user" { start++ }
+    return append(system, rest[start:]...)
+}
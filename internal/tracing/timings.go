@@ -0,0 +1,95 @@
+package tracing
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "time"
+)
+
+// -------- Request timings --------
+//
+// Timings break one request's latency down without a collector: the time
+// spent waiting for a service slot and in each stage span (download,
+// tokenize, inference, decode, ...) the services open with Stage or Do.
+// They are recorded whether or not spans are exported; the server reports
+// them in response headers and the audit log.
+
+// Timings collects the queue and stage times of one request. A nil
+// *Timings is valid and records nothing.
+type Timings struct {
+    start  time.Time
+    mu     sync.Mutex
+    queue  time.Duration
+    names  []string
+    stages map[string]time.Duration
+}
+
+// StageTime is the total time spent in one stage.
+type StageTime struct {
+    Name     string
+    Duration time.Duration
+}
+
+type timingsKey struct{}
+
+// WithTimings returns a context whose stages are recorded in the returned
+// Timings, which starts its clock now.
+func WithTimings(ctx context.Context) (context.Context, *Timings) {
+    t := &Timings{start: time.Now(), stages: map[string]time.Duration{}}
+    return context.WithValue(ctx, timingsKey{}, t), t
+}
+
+// TimingsFrom returns the Timings of ctx, or nil.
+func TimingsFrom(ctx context.Context) *Timings {
+    t, _ := ctx.Value(timingsKey{}).(*Timings)
+    return t
+}
+
+// Elapsed is the time since WithTimings.
+func (t *Timings) Elapsed() time.Duration {
+    if t == nil { return 0 }
+    return time.Since(t.start)
+}
+
+// AddQueue adds d to the time spent waiting for a service slot.
+func (t *Timings) AddQueue(d time.Duration) {
+    if t == nil { return }
+    t.mu.Lock()
+    t.queue += d
+    t.mu.Unlock()
+}
+
+// Queue is the total time spent waiting for service slots.
+func (t *Timings) Queue() time.Duration {
+    if t == nil { return 0 }
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return t.queue
+}
+
+// Add adds d to the named stage. A stage that runs more than once, e.g.
+// per chunk of a long recording, is summed.
+func (t *Timings) Add(stage string, d time.Duration) {
+    if t == nil { return }
+    t.mu.Lock()
+    if _, ok := t.stages[stage]; !ok { t.names = append(t.names, stage) }
+    t.stages[stage] += d
+    t.mu.Unlock()
+}
+
+// Stages lists the recorded stages in the order they first ran.
+func (t *Timings) Stages() []StageTime {
+    if t == nil { return nil }
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    out := make([]StageTime, len(t.names))
+    for i, n := range t.names { out[i] = StageTime{n, t.stages[n]} }
+    return out
+}
+
+// stageName is the stage a span records: "embeddings.tokenize" -> "tokenize".
+func stageName(span string) string {
+    if i := strings.LastIndexByte(span, '.'); i >= 0 { return span[i+1:] }
+    return span
+}
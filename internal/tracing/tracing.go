@@ -0,0 +1,312 @@
+// Package tracing records request spans and exports them to an
+// OpenTelemetry collector over OTLP/HTTP (JSON encoding). It implements
+// just enough of the model for latency breakdowns: W3C traceparent
+// propagation, nested spans with attributes, and batched export. Without
+// Configure every call is a cheap no-op.
+package tracing
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Span kinds as defined by OTLP.
+const (
+    KindInternal = 1
+    KindServer   = 2
+    KindClient   = 3
+)
+
+// Options configures the exporter.
+type Options struct {
+    // Endpoint is the collector base URL, e.g. http://127.0.0.1:4318;
+    // spans are posted to <Endpoint>/v1/traces.
+    Endpoint    string
+    ServiceName string
+    Headers     map[string]string
+}
+
+// Span is one timed operation. A nil *Span is valid and records nothing.
+type Span struct {
+    traceID [16]byte
+    spanID  [8]byte
+    parent  [8]byte
+    name    string
+    kind    int
+    start   time.Time
+    mu      sync.Mutex
+    attrs   map[string]any
+    ended   bool
+    // timings receives the span's duration when it is a stage; local spans
+    // only do that and are neither exported nor put in the context.
+    timings *Timings
+    local   bool
+}
+
+type spanCtxKey struct{}
+
+var (
+    exp   *exporter
+    expMu sync.RWMutex
+)
+
+// Configure starts exporting spans and returns a function that flushes and
+// stops the exporter.
+func Configure(o Options) func() {
+    if o.ServiceName == "" { o.ServiceName = "gollmcore" }
+    e := &exporter{opts: o, url: strings.TrimRight(o.Endpoint, "/") + "/v1/traces", queue: make(chan *Span, 4096), done: make(chan struct{}), client: &http.Client{Timeout: 10 * time.Second}}
+    expMu.Lock()
+    exp = e
+    expMu.Unlock()
+    go e.run()
+    return func() {
+        expMu.Lock()
+        exp = nil
+        expMu.Unlock()
+        close(e.queue)
+        <-e.done
+    }
+}
+
+// Enabled reports whether spans are being exported.
+func Enabled() bool {
+    expMu.RLock()
+    defer expMu.RUnlock()
+    return exp != nil
+}
+
+// Start begins a span as a child of the span in ctx (if any).
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+    return start(ctx, name, kind, nil)
+}
+
+// Stage begins a span for one stage of a service call, named
+// "<service>.<stage>" (e.g. "embeddings.tokenize"). Besides being exported
+// like any span, its duration is added to the Timings of ctx.
+func Stage(ctx context.Context, name string) (context.Context, *Span) {
+    return start(ctx, name, KindInternal, TimingsFrom(ctx))
+}
+
+func start(ctx context.Context, name string, kind int, t *Timings) (context.Context, *Span) {
+    if !Enabled() {
+        if t == nil { return ctx, nil }
+        return ctx, &Span{name: name, kind: kind, start: time.Now(), attrs: map[string]any{}, timings: t, local: true}
+    }
+    s := &Span{name: name, kind: kind, start: time.Now(), attrs: map[string]any{}, timings: t}
+    if p := FromContext(ctx); p != nil {
+        s.traceID, s.parent = p.traceID, p.spanID
+    } else if tid, pid, ok := remoteParent(ctx); ok {
+        s.traceID, s.parent = tid, pid
+    } else {
+        _, _ = rand.Read(s.traceID[:])
+    }
+    _, _ = rand.Read(s.spanID[:])
+    return context.WithValue(ctx, spanCtxKey{}, s), s
+}
+
+// FromContext returns the current span, or nil.
+func FromContext(ctx context.Context) *Span {
+    s, _ := ctx.Value(spanCtxKey{}).(*Span)
+    return s
+}
+
+// Set records an attribute (string, bool, int, int64 or float64).
+func (s *Span) Set(key string, value any) {
+    if s == nil { return }
+    s.mu.Lock()
+    s.attrs[key] = value
+    s.mu.Unlock()
+}
+
+// Elapsed is the time since the span started.
+func (s *Span) Elapsed() time.Duration {
+    if s == nil { return 0 }
+    return time.Since(s.start)
+}
+
+// End finishes the span, marking it failed when err is non-nil.
+func (s *Span) End(err error) {
+    if s == nil { return }
+    s.mu.Lock()
+    if s.ended { s.mu.Unlock(); return }
+    s.ended = true
+    if err != nil { s.attrs["error.message"] = err.Error() }
+    end := time.Now()
+    s.attrs["duration_ms"] = end.Sub(s.start).Milliseconds()
+    s.mu.Unlock()
+    s.timings.Add(stageName(s.name), end.Sub(s.start))
+    if s.local { return }
+    expMu.RLock()
+    e := exp
+    expMu.RUnlock()
+    if e != nil { e.enqueue(s, end, err) }
+}
+
+// Do runs fn inside a stage span named name (see Stage).
+func Do(ctx context.Context, name string, fn func(context.Context) error) error {
+    ctx, s := Stage(ctx, name)
+    err := fn(ctx)
+    s.End(err)
+    return err
+}
+
+// -------- W3C trace context --------
+
+type remoteKey struct{}
+
+type remote struct {
+    traceID [16]byte
+    spanID  [8]byte
+}
+
+// Extract returns ctx carrying the parent from a traceparent header.
+func Extract(ctx context.Context, h http.Header) context.Context {
+    parts := strings.Split(h.Get("traceparent"), "-")
+    if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 { return ctx }
+    var r remote
+    if _, err := hex.Decode(r.traceID[:], []byte(parts[1])); err != nil { return ctx }
+    if _, err := hex.Decode(r.spanID[:], []byte(parts[2])); err != nil { return ctx }
+    return context.WithValue(ctx, remoteKey{}, r)
+}
+
+func remoteParent(ctx context.Context) ([16]byte, [8]byte, bool) {
+    r, ok := ctx.Value(remoteKey{}).(remote)
+    return r.traceID, r.spanID, ok
+}
+
+// Inject writes the current span as a traceparent header.
+func Inject(ctx context.Context, h http.Header) {
+    if s := FromContext(ctx); s != nil {
+        h.Set("traceparent", fmt.Sprintf("00-%x-%x-01", s.traceID, s.spanID))
+    }
+}
+
+// TraceID returns the hex trace id of the current span, or "".
+func TraceID(ctx context.Context) string {
+    if s := FromContext(ctx); s != nil { return hex.EncodeToString(s.traceID[:]) }
+    return ""
+}
+
+// -------- OTLP/HTTP JSON exporter --------
+
+type finished struct {
+    span *Span
+    end  time.Time
+    err  error
+}
+
+type exporter struct {
+    opts   Options
+    url    string
+    queue  chan *Span
+    done   chan struct{}
+    client *http.Client
+    mu     sync.Mutex
+    ends   map[*Span]finished
+}
+
+func (e *exporter) enqueue(s *Span, end time.Time, err error) {
+    e.mu.Lock()
+    if e.ends == nil { e.ends = map[*Span]finished{} }
+    e.ends[s] = finished{s, end, err}
+    e.mu.Unlock()
+    select {
+    case e.queue <- s:
+    default: // drop rather than block requests when the collector is slow
+    }
+}
+
+func (e *exporter) run() {
+    defer close(e.done)
+    t := time.NewTicker(5 * time.Second)
+    defer t.Stop()
+    var batch []finished
+    flush := func() {
+        if len(batch) == 0 { return }
+        if err := e.send(batch); err != nil { log.Printf("tracing: export failed: %v", err) }
+        batch = batch[:0]
+    }
+    for {
+        select {
+        case s, ok := <-e.queue:
+            if !ok { flush(); return }
+            e.mu.Lock()
+            f := e.ends[s]
+            delete(e.ends, s)
+            e.mu.Unlock()
+            batch = append(batch, f)
+            if len(batch) >= 512 { flush() }
+        case <-t.C:
+            flush()
+        }
+    }
+}
+
+type kv struct {
+    Key   string         `json:"key"`
+    Value map[string]any `json:"value"`
+}
+
+func attrValue(v any) map[string]any {
+    switch x := v.(type) {
+    case string:
+        return map[string]any{"stringValue": x}
+    case bool:
+        return map[string]any{"boolValue": x}
+    case int:
+        return map[string]any{"intValue": strconv.Itoa(x)}
+    case int64:
+        return map[string]any{"intValue": strconv.FormatInt(x, 10)}
+    case float64:
+        return map[string]any{"doubleValue": x}
+    }
+    return map[string]any{"stringValue": fmt.Sprint(v)}
+}
+
+func (e *exporter) send(batch []finished) error {
+    spans := make([]map[string]any, 0, len(batch))
+    for _, f := range batch {
+        s := f.span
+        s.mu.Lock()
+        attrs := make([]kv, 0, len(s.attrs))
+        for k, v := range s.attrs { attrs = append(attrs, kv{k, attrValue(v)}) }
+        s.mu.Unlock()
+        span := map[string]any{
+            "traceId":           hex.EncodeToString(s.traceID[:]),
+            "spanId":            hex.EncodeToString(s.spanID[:]),
+            "name":              s.name,
+            "kind":              s.kind,
+            "startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+            "endTimeUnixNano":   strconv.FormatInt(f.end.UnixNano(), 10),
+            "attributes":        attrs,
+            "status":            map[string]any{"code": 1},
+        }
+        if s.parent != ([8]byte{}) { span["parentSpanId"] = hex.EncodeToString(s.parent[:]) }
+        if f.err != nil { span["status"] = map[string]any{"code": 2, "message": f.err.Error()} }
+        spans = append(spans, span)
+    }
+    body, err := json.Marshal(map[string]any{"resourceSpans": []any{map[string]any{
+        "resource":   map[string]any{"attributes": []kv{{"service.name", attrValue(e.opts.ServiceName)}}},
+        "scopeSpans": []any{map[string]any{"scope": map[string]any{"name": "gollmcore"}, "spans": spans}},
+    }}})
+    if err != nil { return err }
+    req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+    if err != nil { return err }
+    req.Header.Set("Content-Type", "application/json")
+    for k, v := range e.opts.Headers { req.Header.Set(k, v) }
+    resp, err := e.client.Do(req)
+    if err != nil { return err }
+    resp.Body.Close()
+    if resp.StatusCode >= 300 { return fmt.Errorf("collector returned %s", resp.Status) }
+    return nil
+}
@@ -0,0 +1,306 @@
+// Package usage records per-model invocation statistics in hourly buckets
+// and keeps them on disk, so operators can see which models are worth
+// keeping resident on a host. It also keeps cumulative token totals per
+// API key, on which quotas can be built.
+package usage
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Bucket aggregates one hour of calls for a service/model pair.
+type Bucket struct {
+    Hour             time.Time `json:"hour"`
+    Service          string    `json:"service"`
+    Model            string    `json:"model"`
+    Calls            int64     `json:"calls"`
+    Errors           int64     `json:"errors"`
+    PromptTokens     int64     `json:"prompt_tokens"`
+    CompletionTokens int64     `json:"completion_tokens"`
+    TotalMs          int64     `json:"total_ms"`
+    PeakConcurrency  int       `json:"peak_concurrency"`
+}
+
+// KeyTotal is the cumulative use of one service by one API key. Keys are
+// identified by fingerprint, never stored.
+type KeyTotal struct {
+    Key      string    `json:"key"`
+    Service  string    `json:"service"`
+    Requests int64     `json:"requests"`
+    Tokens   int64     `json:"tokens"`
+    Last     time.Time `json:"last"`
+}
+
+type key struct {
+    hour           int64
+    service, model string
+}
+
+// Recorder collects buckets in memory and writes them to path on Flush.
+type Recorder struct {
+    path      string
+    retention time.Duration
+    mu        sync.Mutex
+    buckets   map[key]*Bucket
+    inflight  map[[2]string]int
+    unnamed   map[string]int // calls per service whose model is not known yet
+    keys      map[[2]string]*KeyTotal
+    dirty     bool
+}
+
+// keysPath is where the per-key totals are kept: usage.keys.json beside
+// usage.json. They are cumulative, so retention does not apply.
+func keysPath(path string) string { return strings.TrimSuffix(path, filepath.Ext(path)) + ".keys.json" }
+
+// Open loads previously recorded buckets from path (if any). Buckets older
+// than retention are dropped on the next flush.
+func Open(path string, retention time.Duration) (*Recorder, error) {
+    if retention <= 0 { retention = 30 * 24 * time.Hour }
+    r := &Recorder{path: path, retention: retention, buckets: map[key]*Bucket{}, inflight: map[[2]string]int{}, unnamed: map[string]int{}, keys: map[[2]string]*KeyTotal{}}
+    var saved []*Bucket
+    if err := load(path, &saved); err != nil { return nil, err }
+    for _, bk := range saved { r.buckets[key{bk.Hour.Unix(), bk.Service, bk.Model}] = bk }
+    if path == "" { return r, nil }
+    var totals []*KeyTotal
+    if err := load(keysPath(path), &totals); err != nil { return nil, err }
+    for _, t := range totals { r.keys[[2]string{t.Key, t.Service}] = t }
+    return r, nil
+}
+
+// load decodes the JSON file at path into v; a missing file is not an error.
+func load(path string, v any) error {
+    if path == "" { return nil }
+    b, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) { return nil }
+    if err != nil { return fmt.Errorf("read usage: %w", err) }
+    if err := json.Unmarshal(b, v); err != nil { return fmt.Errorf("parse usage: %w", err) }
+    return nil
+}
+
+// Begin marks the start of a call and returns the function that completes
+// it. Token counts may be estimates for services without a tokenizer.
+func (r *Recorder) Begin(service, model string) func(promptTokens, completionTokens int, err error) {
+    start := time.Now()
+    id := [2]string{service, model}
+    r.mu.Lock()
+    r.inflight[id]++
+    r.peakLocked(r.bucketLocked(start, service, model))
+    r.mu.Unlock()
+
+    var once sync.Once
+    return func(promptTokens, completionTokens int, err error) {
+        once.Do(func() {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.endLocked(start, service, model, promptTokens, completionTokens, err)
+            r.inflight[id]--
+        })
+    }
+}
+
+// BeginUnnamed is Begin for a call whose model is only known once it has
+// returned; end names it. Until then the call counts towards the
+// concurrency of every model of service. Peaks stay exact because each
+// call also samples the concurrency as it ends, when the calls it overlaps
+// with the most are all still running.
+func (r *Recorder) BeginUnnamed(service string) func(model string, promptTokens, completionTokens int, err error) {
+    start := time.Now()
+    r.mu.Lock()
+    r.unnamed[service]++
+    r.mu.Unlock()
+
+    var once sync.Once
+    return func(model string, promptTokens, completionTokens int, err error) {
+        once.Do(func() {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.endLocked(start, service, model, promptTokens, completionTokens, err)
+            r.unnamed[service]--
+        })
+    }
+}
+
+// endLocked records a finished call, which is still counted in flight.
+func (r *Recorder) endLocked(start time.Time, service, model string, promptTokens, completionTokens int, err error) {
+    now := time.Now()
+    bk := r.bucketLocked(now, service, model)
+    bk.Calls++
+    if err != nil { bk.Errors++ }
+    bk.PromptTokens += int64(promptTokens)
+    bk.CompletionTokens += int64(completionTokens)
+    bk.TotalMs += now.Sub(start).Milliseconds()
+    r.peakLocked(bk)
+}
+
+// peakLocked raises the peak concurrency of bk to the calls now in flight
+// for its model, counting calls whose model is not known yet.
+func (r *Recorder) peakLocked(bk *Bucket) {
+    if n := r.inflight[[2]string{bk.Service, bk.Model}] + r.unnamed[bk.Service]; n > bk.PeakConcurrency { bk.PeakConcurrency = n }
+}
+
+// Charge adds one request of tokens to the total of service for the key
+// with fingerprint key.
+func (r *Recorder) Charge(service, key string, tokens int) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    id := [2]string{key, service}
+    t, ok := r.keys[id]
+    if !ok {
+        t = &KeyTotal{Key: key, Service: service}
+        r.keys[id] = t
+    }
+    t.Requests++
+    t.Tokens += int64(tokens)
+    t.Last = time.Now().UTC()
+    r.dirty = true
+}
+
+// Keys returns the per-key totals, most tokens first. service "" returns
+// all services.
+func (r *Recorder) Keys(service string) []KeyTotal {
+    r.mu.Lock()
+    out := make([]KeyTotal, 0, len(r.keys))
+    for _, t := range r.keys { if service == "" || t.Service == service { out = append(out, *t) } }
+    r.mu.Unlock()
+    sort.Slice(out, func(i, j int) bool {
+        if out[i].Tokens != out[j].Tokens { return out[i].Tokens > out[j].Tokens }
+        if out[i].Key != out[j].Key { return out[i].Key < out[j].Key }
+        return out[i].Service < out[j].Service
+    })
+    return out
+}
+
+func (r *Recorder) bucketLocked(t time.Time, service, model string) *Bucket {
+    hour := t.UTC().Truncate(time.Hour)
+    k := key{hour.Unix(), service, model}
+    bk, ok := r.buckets[k]
+    if !ok {
+        bk = &Bucket{Hour: hour, Service: service, Model: model}
+        r.buckets[k] = bk
+    }
+    r.dirty = true
+    return bk
+}
+
+// Flush drops expired buckets and writes the rest to disk.
+func (r *Recorder) Flush() error {
+    r.mu.Lock()
+    cutoff := time.Now().Add(-r.retention)
+    for k, bk := range r.buckets { if bk.Hour.Before(cutoff) { delete(r.buckets, k) } }
+    if !r.dirty || r.path == "" { r.mu.Unlock(); return nil }
+    out := r.sortedLocked()
+    r.dirty = false
+    r.mu.Unlock()
+    totals := r.Keys("")
+
+    if err := save(r.path, out); err != nil { return err }
+    if len(totals) == 0 { return nil }
+    return save(keysPath(r.path), totals)
+}
+
+// save writes v to path as JSON through a temporary file.
+func save(path string, v any) error {
+    b, err := json.Marshal(v)
+    if err != nil { return err }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return err }
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, b, 0o644); err != nil { return err }
+    return os.Rename(tmp, path)
+}
+
+// Run flushes every interval until stop is closed, then flushes once more.
+func (r *Recorder) Run(interval time.Duration, stop <-chan struct{}) {
+    t := time.NewTicker(interval)
+    defer t.Stop()
+    for {
+        select {
+        case <-t.C:
+            _ = r.Flush()
+        case <-stop:
+            _ = r.Flush()
+            return
+        }
+    }
+}
+
+func (r *Recorder) sortedLocked() []Bucket {
+    out := make([]Bucket, 0, len(r.buckets))
+    for _, bk := range r.buckets { out = append(out, *bk) }
+    sort.Slice(out, func(i, j int) bool {
+        if !out[i].Hour.Equal(out[j].Hour) { return out[i].Hour.Before(out[j].Hour) }
+        if out[i].Service != out[j].Service { return out[i].Service < out[j].Service }
+        return out[i].Model < out[j].Model
+    })
+    return out
+}
+
+// Filter selects buckets for Query. Zero fields match everything.
+type Filter struct {
+    Since   time.Time
+    Until   time.Time
+    Service string
+    Model   string
+}
+
+// Summary aggregates buckets for one service/model pair.
+type Summary struct {
+    Service             string  `json:"service"`
+    Model               string  `json:"model"`
+    Calls               int64   `json:"calls"`
+    Errors              int64   `json:"errors"`
+    AvgPromptTokens     float64 `json:"avg_prompt_tokens"`
+    AvgCompletionTokens float64 `json:"avg_completion_tokens"`
+    AvgLatencyMs        float64 `json:"avg_latency_ms"`
+    PeakConcurrency     int     `json:"peak_concurrency"`
+}
+
+// Query returns per-model summaries and the matching hourly buckets.
+func (r *Recorder) Query(f Filter) ([]Summary, []Bucket) {
+    r.mu.Lock()
+    all := r.sortedLocked()
+    r.mu.Unlock()
+
+    var series []Bucket
+    sums := map[[2]string]*Summary{}
+    var order [][2]string
+    for _, bk := range all {
+        if !f.Since.IsZero() && bk.Hour.Add(time.Hour).Before(f.Since) { continue }
+        if !f.Until.IsZero() && !bk.Hour.Before(f.Until) { continue }
+        if f.Service != "" && bk.Service != f.Service { continue }
+        if f.Model != "" && bk.Model != f.Model { continue }
+        series = append(series, bk)
+        id := [2]string{bk.Service, bk.Model}
+        s, ok := sums[id]
+        if !ok {
+            s = &Summary{Service: bk.Service, Model: bk.Model}
+            sums[id] = s
+            order = append(order, id)
+        }
+        s.Calls += bk.Calls
+        s.Errors += bk.Errors
+        s.AvgPromptTokens += float64(bk.PromptTokens)
+        s.AvgCompletionTokens += float64(bk.CompletionTokens)
+        s.AvgLatencyMs += float64(bk.TotalMs)
+        if bk.PeakConcurrency > s.PeakConcurrency { s.PeakConcurrency = bk.PeakConcurrency }
+    }
+    sort.Slice(order, func(i, j int) bool { return sums[order[i]].Calls > sums[order[j]].Calls })
+    out := make([]Summary, 0, len(order))
+    for _, id := range order {
+        s := sums[id]
+        if s.Calls > 0 {
+            n := float64(s.Calls)
+            s.AvgPromptTokens, s.AvgCompletionTokens, s.AvgLatencyMs = s.AvgPromptTokens/n, s.AvgCompletionTokens/n, s.AvgLatencyMs/n
+        }
+        out = append(out, *s)
+    }
+    if series == nil { series = []Bucket{} }
+    return out, series
+}
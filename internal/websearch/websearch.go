@@ -0,0 +1,146 @@
+// Package websearch queries web search engines for the assistant: a
+// self-hosted SearxNG instance or a hosted API (Brave, Tavily) with the
+// operator's key.
+package websearch
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Result is one search hit.
+type Result struct {
+    Title   string `json:"title"`
+    URL     string `json:"url"`
+    Snippet string `json:"snippet"`
+}
+
+// Provider runs a query and returns at most n results.
+type Provider interface {
+    Search(ctx context.Context, query string, n int) ([]Result, error)
+    Name() string
+}
+
+// Providers lists the names New accepts.
+var Providers = []string{"searxng", "brave", "tavily"}
+
+// Options configure a provider. SearxNG needs URL (its base URL, with the
+// JSON format enabled); Brave and Tavily need APIKey and may override URL.
+type Options struct {
+    Provider string
+    URL      string
+    APIKey   string
+    Timeout  time.Duration // default 15s
+}
+
+// New returns the configured provider.
+func New(o Options) (Provider, error) {
+    if o.Timeout <= 0 { o.Timeout = 15 * time.Second }
+    c := client{http: &http.Client{Timeout: o.Timeout}, url: strings.TrimRight(o.URL, "/"), key: o.APIKey}
+    switch o.Provider {
+    case "searxng":
+        if c.url == "" { return nil, errors.New("searxng needs a url") }
+        return searxng{c}, nil
+    case "brave":
+        if c.key == "" { return nil, errors.New("brave needs an api_key") }
+        if c.url == "" { c.url = "https://api.search.brave.com/res/v1/web/search" }
+        return brave{c}, nil
+    case "tavily":
+        if c.key == "" { return nil, errors.New("tavily needs an api_key") }
+        if c.url == "" { c.url = "https://api.tavily.com/search" }
+        return tavily{c}, nil
+    }
+    return nil, fmt.Errorf("unknown search provider %q (have %s)", o.Provider, strings.Join(Providers, ", "))
+}
+
+type client struct {
+    http *http.Client
+    url  string
+    key  string
+}
+
+// do sends req and decodes a JSON response into v.
+func (c client) do(req *http.Request, v any) error {
+    resp, err := c.http.Do(req)
+    if err != nil { return fmt.Errorf("search request failed: %w", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("search provider returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+    }
+    if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(v); err != nil { return fmt.Errorf("decode search response: %w", err) }
+    return nil
+}
+
+type searxng struct{ client }
+
+func (searxng) Name() string { return "searxng" }
+
+func (s searxng) Search(ctx context.Context, query string, n int) ([]Result, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/search?"+url.Values{"q": {query}, "format": {"json"}}.Encode(), nil)
+    if err != nil { return nil, err }
+    var out struct {
+        Results []struct{ Title, URL, Content string } `json:"results"`
+    }
+    if err := s.do(req, &out); err != nil { return nil, err }
+    res := make([]Result, 0, min(n, len(out.Results)))
+    for _, r := range out.Results {
+        if len(res) == n { break }
+        res = append(res, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+    }
+    return res, nil
+}
+
+type brave struct{ client }
+
+func (brave) Name() string { return "brave" }
+
+func (b brave) Search(ctx context.Context, query string, n int) ([]Result, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url+"?"+url.Values{"q": {query}, "count": {strconv.Itoa(min(n, 20))}}.Encode(), nil)
+    if err != nil { return nil, err }
+    req.Header.Set("Accept", "application/json")
+    req.Header.Set("X-Subscription-Token", b.key)
+    var out struct {
+        Web struct {
+            Results []struct{ Title, URL, Description string } `json:"results"`
+        } `json:"web"`
+    }
+    if err := b.do(req, &out); err != nil { return nil, err }
+    res := make([]Result, 0, min(n, len(out.Web.Results)))
+    for _, r := range out.Web.Results {
+        if len(res) == n { break }
+        res = append(res, Result{Title: r.Title, URL: r.URL, Snippet: r.Description})
+    }
+    return res, nil
+}
+
+type tavily struct{ client }
+
+func (tavily) Name() string { return "tavily" }
+
+func (t tavily) Search(ctx context.Context, query string, n int) ([]Result, error) {
+    body, _ := json.Marshal(map[string]any{"query": query, "max_results": n})
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+    if err != nil { return nil, err }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+t.key)
+    var out struct {
+        Results []struct{ Title, URL, Content string } `json:"results"`
+    }
+    if err := t.do(req, &out); err != nil { return nil, err }
+    res := make([]Result, 0, min(n, len(out.Results)))
+    for _, r := range out.Results {
+        if len(res) == n { break }
+        res = append(res, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+    }
+    return res, nil
+}
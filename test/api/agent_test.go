@@ -0,0 +1,153 @@
+package api_test
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+// newToolLLM starts an upstream that calls tool with args until it has seen
+// rounds tool results, then answers "Result: <last result>". Streamed tool
+// calls are split across two deltas, as real servers send them.
+func newToolLLM(t *testing.T, tool, args string, rounds int) (*httptest.Server, func() []llm.ChatRequest) {
+    t.Helper()
+    var (
+        mu   sync.Mutex
+        reqs []llm.ChatRequest
+    )
+    ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var req llm.ChatRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil { http.Error(w, "bad json", http.StatusBadRequest); return }
+        mu.Lock()
+        reqs = append(reqs, req)
+        mu.Unlock()
+        last, results := req.Messages[len(req.Messages)-1], 0
+        for _, m := range req.Messages { if m.Role == "tool" { results++ } }
+        call := fmt.Sprintf(`[{"id":"call_1","type":"function","function":{"name":%q,"arguments":%q}}]`, tool, args)
+        msg, finish := llm.Message{Role: "assistant", ToolCalls: json.RawMessage(call)}, "tool_calls"
+        if results >= rounds { msg, finish = llm.Message{Role: "assistant", Content: "Result: " + last.Content}, "stop" }
+        if !req.Stream {
+            w.Header().Set("Content-Type", "application/json")
+            _ = json.NewEncoder(w).Encode(llm.ChatResponse{ID: "chatcmpl-up", Object: "chat.completion", Model: "m",
+                Choices: []llm.Choice{{Message: msg, FinishReason: finish}}})
+            return
+        }
+        w.Header().Set("Content-Type", "text/event-stream")
+        send := func(delta llm.Message, fr *string) {
+            b, _ := json.Marshal(llm.ChatChunk{ID: "chatcmpl-up", Object: "chat.completion.chunk", Model: "m", Choices: []llm.ChunkChoice{{Delta: delta, FinishReason: fr}}})
+            fmt.Fprintf(w, "data: %s\n\n", b)
+        }
+        if msg.ToolCalls != nil {
+            half := len(args) / 2
+            send(llm.Message{ToolCalls: json.RawMessage(fmt.Sprintf(`[{"index":0,"id":"call_1","type":"function","function":{"name":%q,"arguments":%q}}]`, tool, args[:half]))}, nil)
+            send(llm.Message{ToolCalls: json.RawMessage(fmt.Sprintf(`[{"index":0,"function":{"arguments":%q}}]`, args[half:]))}, &finish)
+        } else {
+            send(llm.Message{Content: msg.Content}, &finish)
+        }
+        fmt.Fprintf(w, "data: [DONE]\n\n")
+    }))
+    t.Cleanup(ts.Close)
+    return ts, func() []llm.ChatRequest { mu.Lock(); defer mu.Unlock(); return append([]llm.ChatRequest(nil), reqs...) }
+}
+
+func newAgentServer(t *testing.T, upstream string) *httptest.Server {
+    t.Helper()
+    calc, err := server.BuiltinTool("calculator")
+    if err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:   llm.New(upstream+"/v1", "test-model", ""),
+        Agent: server.AgentOptions{Tools: map[string]server.Tool{"calculator": calc}, MaxSteps: 3},
+    })
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+func TestAgent_RunsServerToolsUntilAnswer(t *testing.T) {
+    up, reqs := newToolLLM(t, "calculator", `{"expression":"(2+4)*7"}`, 1)
+    ts := newAgentServer(t, up.URL)
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"agent": true, "messages": []map[string]string{{"role": "user", "content": "what is (2+4)*7?"}}})
+    var out struct {
+        Choices []llm.Choice `json:"choices"`
+        Steps   []struct {
+            Type   string `json:"type"`
+            Name   string `json:"name"`
+            Output string `json:"output"`
+        } `json:"agent_steps"`
+    }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || len(out.Choices) != 1 || out.Choices[0].Message.Content != "Result: 42" { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+    if len(out.Steps) != 2 || out.Steps[0].Type != "tool_call" || out.Steps[1].Output != "42" { t.Fatalf("unexpected steps %+v", out.Steps) }
+    got := reqs()
+    if len(got) != 2 || !strings.Contains(string(got[0].Tools), `"name":"calculator"`) { t.Fatalf("unexpected upstream requests %+v", got) }
+    if m := got[1].Messages; len(m) != 3 || m[2].Role != "tool" || m[2].ToolCallID != "call_1" { t.Fatalf("tool result not sent back: %+v", m) }
+}
+
+func TestAgent_StreamsToolEvents(t *testing.T) {
+    up, _ := newToolLLM(t, "calculator", `{"expression":"2^10"}`, 1)
+    ts := newAgentServer(t, up.URL)
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"agent": true, "stream": true, "messages": []map[string]string{{"role": "user", "content": "2^10?"}}})
+    defer resp.Body.Close()
+    var events []string
+    var text strings.Builder
+    scan := bufio.NewScanner(resp.Body)
+    for event := ""; scan.Scan(); {
+        line := scan.Text()
+        switch {
+        case strings.HasPrefix(line, "event: "):
+            event = strings.TrimPrefix(line, "event: ")
+        case strings.HasPrefix(line, "data: "):
+            data := strings.TrimPrefix(line, "data: ")
+            if event == "tool" {
+                var s struct{ Type, Output string }
+                _ = json.Unmarshal([]byte(data), &s)
+                events = append(events, s.Type+":"+s.Output)
+            } else if data != "[DONE]" {
+                var c llm.ChatChunk
+                _ = json.Unmarshal([]byte(data), &c)
+                if len(c.Choices) > 0 { text.WriteString(c.Choices[0].Delta.Content) }
+            }
+            event = ""
+        }
+    }
+    if strings.Join(events, ",") != "tool_call:,tool_result:1024" || text.String() != "Result: 1024" { t.Fatalf("unexpected stream: events %v text %q", events, text.String()) }
+}
+
+func TestAgent_ToolErrorsGoToTheModel(t *testing.T) {
+    up, reqs := newToolLLM(t, "calculator", `{"expression":"1/0"}`, 1)
+    ts := newAgentServer(t, up.URL)
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"agent": true, "messages": []map[string]string{{"role": "user", "content": "1/0"}}})
+    resp.Body.Close()
+    got := reqs()
+    if len(got) != 2 || got[1].Messages[2].Content != "error: division by zero" { t.Fatalf("tool error not passed to the model: %+v", got) }
+
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"agent": true, "agent_tools": []string{"web"}, "messages": []map[string]string{{"role": "user", "content": "x"}}})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "agent" { t.Fatalf("expected an agent error, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+func TestAgent_StopsAtMaxSteps(t *testing.T) {
+    // The upstream keeps calling tools, even with tool_choice "none".
+    up, reqs := newToolLLM(t, "calculator", `{"expression":"1+1"}`, 100)
+    ts := newAgentServer(t, up.URL)
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"agent": true, "messages": []map[string]string{{"role": "user", "content": "loop"}}})
+    var out struct{ Choices []llm.Choice `json:"choices"` }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    got := reqs()
+    if len(got) != 3 || string(got[2].ToolChoice) != `"none"` { t.Fatalf("expected 3 model calls, the last without tools, got %+v", got) }
+    if len(out.Choices) != 1 || out.Choices[0].FinishReason != "length" || len(out.Choices[0].Message.ToolCalls) != 0 { t.Fatalf("unexpected final reply %+v", out.Choices) }
+}
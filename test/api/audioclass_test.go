@@ -0,0 +1,83 @@
+//go:build unix
+
+package api_test
+
+import (
+    "context"
+    "encoding/json"
+    "math"
+    "math/rand"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/audioclass"
+)
+
+// windowVAD reports speech for windows from its start sample onwards.
+type windowVAD struct{ from int }
+
+func (v windowVAD) SpeechProbs(_ context.Context, x []float32) ([]float32, error) {
+    probs := make([]float32, (len(x)+audioclass.Window-1)/audioclass.Window)
+    for i := range probs {
+        if i*audioclass.Window >= v.from { probs[i] = 0.9 }
+    }
+    return probs, nil
+}
+
+type classified struct {
+    Class               string             `json:"class"`
+    Scores              map[string]float64 `json:"scores"`
+    Duration            float64            `json:"duration"`
+    Language            string             `json:"language"`
+    LanguageProbability float64            `json:"language_probability"`
+}
+
+func TestAudioClassify(t *testing.T) {
+    const rate = 16000
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        AudioClassifier: audioclass.New(windowVAD{from: 5 * rate / 2}),
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+    classify := func(audio []byte) classified {
+        resp := postAudio(t, ts.URL+"/v1/audio/classify", audio, nil)
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("status %d", resp.StatusCode) }
+        var out classified
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return out
+    }
+
+    // 1 s silence, 1 s tone, 0.5 s hiss, then 1.5 s the VAD calls speech.
+    r := rand.New(rand.NewSource(1))
+    samples := make([]int16, 4*rate)
+    for i := range samples {
+        switch {
+        case i < rate:
+        case i < 2*rate:
+            samples[i] = int16(0.3 * 32767 * math.Sin(2*math.Pi*440*float64(i)/rate))
+        default:
+            samples[i] = int16((r.Float64()*2 - 1) * 0.1 * 32767)
+        }
+    }
+    got := classify(monoWAV(rate, samples))
+    want := map[string]float64{"silence": 0.25, "music": 0.25, "noise": 0.125, "speech": 0.375}
+    for class, share := range want {
+        if math.Abs(got.Scores[class]-share) > 0.02 { t.Errorf("%s: got %.3f, want %.3f", class, got.Scores[class], share) }
+    }
+    if got.Class != "speech" || got.Duration != 4 || got.Language != "de" || got.LanguageProbability < 0.9 { t.Fatalf("unexpected result %+v", got) }
+
+    got = classify(monoWAV(rate, samples[rate:2*rate]))
+    if got.Class != "music" || got.Language != "" { t.Fatalf("tone: unexpected result %+v", got) }
+    got = classify(monoWAV(rate, samples[:rate]))
+    if got.Class != "silence" { t.Fatalf("silence: unexpected result %+v", got) }
+
+    resp := postAudio(t, ts.URL+"/v1/audio/classify", []byte("ID3 mp3 data"), nil)
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400 for non-WAV audio, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "file" { t.Fatalf("unexpected error %+v", e.Error) }
+}
@@ -0,0 +1,89 @@
+package api_test
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/audit"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func readAudit(t *testing.T, path string) []map[string]any {
+    t.Helper()
+    f, err := os.Open(path)
+    if err != nil { t.Fatalf("open audit log: %v", err) }
+    defer f.Close()
+    var out []map[string]any
+    sc := bufio.NewScanner(f)
+    for sc.Scan() {
+        var m map[string]any
+        if err := json.Unmarshal(sc.Bytes(), &m); err != nil { t.Fatalf("bad audit line %q: %v", sc.Text(), err) }
+        out = append(out, m)
+    }
+    return out
+}
+
+func TestAudit_RecordsRequestsWithRedaction(t *testing.T) {
+    up := newFakeLLM(t, "two words")
+    defer up.Close()
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    l, err := audit.Open(audit.Options{Path: path, Redact: []string{"remote_addr"}})
+    if err != nil { t.Fatalf("open: %v", err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithAudit(server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}))
+    ts := httptest.NewServer(server.Audit(server.RequireAPIKey(mux, []string{"secret-key"}, nil), l))
+    defer ts.Close()
+
+    body, _ := json.Marshal(map[string]any{"messages": []map[string]string{{"role": "user", "content": "hello there"}}})
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", bytes.NewReader(body))
+    req.Header.Set("Authorization", "Bearer secret-key")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("request: %v", err) }
+    resp.Body.Close()
+    resp, err = http.Get(ts.URL + "/v1/capabilities")
+    if err != nil { t.Fatalf("request: %v", err) }
+    resp.Body.Close()
+    resp, _ = http.Get(ts.URL + "/healthz")
+    resp.Body.Close()
+    l.Close()
+
+    raw, _ := os.ReadFile(path)
+    if strings.Contains(string(raw), "secret-key") { t.Fatalf("raw API key written to audit log") }
+    entries := readAudit(t, path)
+    if len(entries) != 2 { t.Fatalf("want 2 entries (healthz skipped), got %d: %s", len(entries), raw) }
+    chat, denied := entries[0], entries[1]
+    sum := sha256.Sum256(body)
+    for k, want := range map[string]any{"route": "/v1/chat/completions", "method": "POST", "status": 200.0, "service": "llm", "model": "test-model", "input_sha256": hex.EncodeToString(sum[:])} {
+        if chat[k] != want { t.Fatalf("%s = %v, want %v (%v)", k, chat[k], want, chat) }
+    }
+    if !strings.HasPrefix(chat["key"].(string), "sha256:") { t.Fatalf("key fingerprint %v", chat["key"]) }
+    if chat["prompt_tokens"] == nil || chat["completion_tokens"] == nil { t.Fatalf("token counts missing: %v", chat) }
+    if _, ok := chat["remote_addr"]; ok { t.Fatalf("remote_addr not redacted: %v", chat) }
+    if denied["status"] != 401.0 || denied["key"] != nil { t.Fatalf("unauthenticated request entry: %v", denied) }
+}
+
+func TestAudit_Rotates(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    l, err := audit.Open(audit.Options{Path: path, MaxBytes: 200, MaxFiles: 2})
+    if err != nil { t.Fatalf("open: %v", err) }
+    for i := 0; i < 10; i++ {
+        if err := l.Write(&audit.Entry{Method: "GET", Route: "/v1/capabilities", Status: 200}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    l.Close()
+    for _, p := range []string{path, path + ".1", path + ".2"} {
+        info, err := os.Stat(p)
+        if err != nil { t.Fatalf("missing %s: %v", p, err) }
+        if info.Size() > 200 { t.Fatalf("%s is %d bytes, over the limit", p, info.Size()) }
+    }
+    if _, err := os.Stat(path + ".3"); err == nil { t.Fatalf("kept more than max_files rotated logs") }
+}
@@ -0,0 +1,114 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func newAuthServer(t *testing.T, upstream string, ws server.WSOptions) *httptest.Server {
+    t.Helper()
+    keys := []string{"secret"}
+    d := server.Dependencies{LLM: llm.New(upstream+"/v1", "test-model", ""), APIKeys: keys, WebSocket: ws}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, d)
+    server.RegisterWSRoutes(mux, d, ws)
+    return httptest.NewServer(server.RequireAPIKey(mux, keys, nil))
+}
+
+func TestAPIKey_HTTP(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    ts := newAuthServer(t, up.URL, server.WSOptions{})
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/healthz")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("healthz should not need a key, got %d", resp.StatusCode) }
+
+    resp, err = http.Get(ts.URL + "/v1/capabilities")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusUnauthorized { t.Fatalf("expected 401 without key, got %d", resp.StatusCode) }
+
+    req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/capabilities", nil)
+    req.Header.Set("Authorization", "Bearer secret")
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200 with key, got %d", resp.StatusCode) }
+}
+
+func TestAPIKey_UpgradeHeadersOutsideWSPrefix(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    ts := newAuthServer(t, up.URL, server.WSOptions{Enable: true})
+    defer ts.Close()
+
+    for _, path := range []string{"/v1/capabilities", "/admin/status", "/v1/chat/completions"} {
+        req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+        req.Header.Set("Connection", "Upgrade")
+        req.Header.Set("Upgrade", "websocket")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatalf("request failed: %v", err) }
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusUnauthorized { t.Fatalf("%s with upgrade headers and no key: got %d, want 401", path, resp.StatusCode) }
+    }
+}
+
+func TestAPIKey_WebSocket(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    ts := newAuthServer(t, up.URL, server.WSOptions{Enable: true})
+    defer ts.Close()
+    url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/chat"
+
+    // Wrong token in the query is rejected before the upgrade.
+    _, resp, err := websocket.DefaultDialer.Dial(url+"?token=nope", nil)
+    if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized { t.Fatalf("expected 401 for bad token, got %v", err) }
+
+    // Correct token in the query is accepted.
+    conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+    if err != nil { t.Fatalf("dial with token failed: %v", err) }
+    conn.Close()
+
+    // Without a token the first frame must authenticate.
+    conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    _ = conn.WriteJSON(map[string]any{"type": "auth", "token": "secret"})
+    var ev map[string]any
+    if err := conn.ReadJSON(&ev); err != nil || ev["type"] != "authenticated" { t.Fatalf("expected authenticated, got %v (%v)", ev, err) }
+
+    bad, _, err := websocket.DefaultDialer.Dial(url, nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer bad.Close()
+    _ = bad.SetReadDeadline(time.Now().Add(5 * time.Second))
+    _ = bad.WriteJSON(map[string]any{"type": "chat", "messages": []map[string]string{{"role": "user", "content": "hi"}}})
+    if err := bad.ReadJSON(&ev); !websocket.IsCloseError(err, websocket.ClosePolicyViolation) { t.Fatalf("expected policy violation close, got %v", err) }
+}
+
+func TestWebSocket_AllowedOrigins(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}, server.WSOptions{Enable: true, AllowedOrigins: []string{"https://app.example"}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+    url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/chat"
+
+    _, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+    if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden { t.Fatalf("expected 403 for foreign origin, got %v", err) }
+    conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://app.example"}})
+    if err != nil { t.Fatalf("allowed origin rejected: %v", err) }
+    conn.Close()
+}
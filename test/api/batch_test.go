@@ -0,0 +1,110 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+type batchOut struct {
+    Results []struct {
+        Index    int               `json:"index"`
+        CustomID string            `json:"custom_id"`
+        Response *llm.ChatResponse `json:"response"`
+        Error    *struct {
+            Code string `json:"code"`
+        } `json:"error"`
+    } `json:"results"`
+    Succeeded int `json:"succeeded"`
+    Failed    int `json:"failed"`
+}
+
+// newBatchServer fails upstream calls whose last message is "fail".
+func newBatchServer(t *testing.T) (*httptest.Server, *llmSpy) {
+    t.Helper()
+    spy := newSpyLLM(t, "ok")
+    t.Cleanup(spy.Close)
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        b, _ := io.ReadAll(r.Body)
+        if bytes.Contains(b, []byte(`"content":"fail"`)) { http.Error(w, "boom", http.StatusInternalServerError); return }
+        r.Body = io.NopCloser(bytes.NewReader(b))
+        spy.Config.Handler.ServeHTTP(w, r)
+    }))
+    t.Cleanup(up.Close)
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")})
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+    return ts, spy
+}
+
+func batchItems(contents ...string) []map[string]any {
+    items := make([]map[string]any, len(contents))
+    for i, c := range contents {
+        items[i] = map[string]any{"custom_id": "c" + c, "messages": []map[string]string{{"role": "user", "content": c}}}
+    }
+    return items
+}
+
+func TestChatBatch_ResultsInOrderWithPerItemErrors(t *testing.T) {
+    ts, spy := newBatchServer(t)
+    resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": batchItems("1", "fail", "3", "4", "5"), "concurrency": 3})
+    var out batchOut
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || len(out.Results) != 5 || out.Succeeded != 4 || out.Failed != 1 { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+    for i, r := range out.Results {
+        if r.Index != i { t.Fatalf("result %d has index %d", i, r.Index) }
+        if i == 1 {
+            if r.Error == nil || r.Response != nil || r.CustomID != "cfail" { t.Fatalf("expected item 1 to fail, got %+v", r) }
+            continue
+        }
+        if r.Error != nil || r.Response == nil || r.Response.Text() != "ok" { t.Fatalf("expected item %d to succeed, got %+v", i, r) }
+    }
+    if n := len(spy.requests()); n != 4 { t.Fatalf("expected 4 upstream calls, got %d", n) }
+}
+
+func TestChatBatch_RejectsInvalidItems(t *testing.T) {
+    ts, spy := newBatchServer(t)
+    items := batchItems("1", "2")
+    items[1]["stream"] = true
+    resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": items})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || !strings.Contains(e.Error.Message, "requests[1]") { t.Fatalf("expected a requests[1] error, got %d %+v", resp.StatusCode, e.Error) }
+
+    resp = postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": []any{}})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "requests" { t.Fatalf("expected a requests error, got %d %+v", resp.StatusCode, e.Error) }
+    if n := len(spy.requests()); n != 0 { t.Fatalf("invalid batches must not reach the model, got %d calls", n) }
+}
+
+func TestChatBatch_AsyncJob(t *testing.T) {
+    ts, _ := newBatchServer(t)
+    resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": batchItems("1", "2", "3"), "async": true})
+    var job struct {
+        ID     string          `json:"id"`
+        Status string          `json:"status"`
+        Result json.RawMessage `json:"result"`
+    }
+    _ = json.NewDecoder(resp.Body).Decode(&job)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted || job.ID == "" || resp.Header.Get("Location") != "/v1/jobs/"+job.ID { t.Fatalf("unexpected response %d %+v", resp.StatusCode, job) }
+
+    deadline := time.Now().Add(5 * time.Second)
+    for job.Status != "succeeded" {
+        if time.Now().After(deadline) { t.Fatalf("job did not finish: %+v", job) }
+        time.Sleep(20 * time.Millisecond)
+        r, err := http.Get(ts.URL + "/v1/jobs/" + job.ID)
+        if err != nil { t.Fatalf("get job: %v", err) }
+        _ = json.NewDecoder(r.Body).Decode(&job)
+        r.Body.Close()
+    }
+    var out batchOut
+    if err := json.Unmarshal(job.Result, &out); err != nil || out.Succeeded != 3 || len(out.Results) != 3 { t.Fatalf("unexpected job result %s", job.Result) }
+}
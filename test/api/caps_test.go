@@ -0,0 +1,60 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+)
+
+func TestCaps_RejectOversizedRequests(t *testing.T) {
+    spy := newSpyLLM(t, "hi")
+    defer spy.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:        llm.New(spy.URL+"/v1", "test-model", ""),
+        TTS:        fakeTTS{},
+        Embeddings: embeddings.New(embeddings.Config{}),
+        Caps:       server.Caps{EmbeddingInputs: 2, TTSChars: 10, MaxTokens: 100},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    user := []map[string]string{{"role": "user", "content": "hello"}}
+    for _, c := range []struct {
+        name, path string
+        body       map[string]any
+        want       string
+    }{
+        {"embeddings", "/v1/embeddings", map[string]any{"input": []string{"a", "b", "c"}}, "too many inputs: 3 (max 2)"},
+        {"streamed embeddings", "/v1/embeddings", map[string]any{"input": []string{"a", "b", "c"}, "stream": true, "batch_size": 1}, "too many inputs"},
+        {"tts", "/v1/tts", map[string]any{"text": strings.Repeat("é", 11)}, "text too long: 11 characters (max 10)"},
+        {"max_tokens", "/v1/chat/completions", map[string]any{"messages": user, "max_tokens": 101}, "max_tokens 101 is above the server's limit of 100"},
+    } {
+        resp := postJSON(t, ts.URL+c.path, c.body)
+        if resp.StatusCode != http.StatusBadRequest { resp.Body.Close(); t.Fatalf("%s: expected 400, got %d", c.name, resp.StatusCode) }
+        e := decodeError(t, resp)
+        if e.Error.Code != "limit_exceeded" || !strings.Contains(e.Error.Message, c.want) { t.Errorf("%s: unexpected error %+v", c.name, e.Error) }
+    }
+
+    // Within the caps requests go through, and a chat without max_tokens
+    // gets the cap.
+    for _, c := range []struct {
+        path string
+        body map[string]any
+    }{
+        {"/v1/embeddings", map[string]any{"input": []string{"a", "b"}}},
+        {"/v1/tts", map[string]any{"text": strings.Repeat("é", 10)}},
+        {"/v1/chat/completions", map[string]any{"messages": user}},
+    } {
+        resp := postJSON(t, ts.URL+c.path, c.body)
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("%s: expected 200, got %d", c.path, resp.StatusCode) }
+    }
+    reqs := spy.requests()
+    if len(reqs) != 1 || reqs[0].MaxTokens == nil || *reqs[0].MaxTokens != 100 { t.Fatalf("expected max_tokens 100 upstream, got %+v", reqs) }
+}
@@ -0,0 +1,229 @@
+package api_test
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+// newFakeLLM starts an OpenAI-compatible upstream that answers every chat
+// request with reply, streamed word by word when stream=true.
+func newFakeLLM(t *testing.T, reply string) *httptest.Server {
+    t.Helper()
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/v1/chat/completions" { http.NotFound(w, r); return }
+        var req llm.ChatRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil { http.Error(w, "bad json", http.StatusBadRequest); return }
+        if !req.Stream {
+            w.Header().Set("Content-Type", "application/json")
+            _ = json.NewEncoder(w).Encode(llm.ChatResponse{
+                ID: "chatcmpl-test", Object: "chat.completion", Model: req.Model,
+                Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: reply}, FinishReason: "stop"}},
+            })
+            return
+        }
+        w.Header().Set("Content-Type", "text/event-stream")
+        for i, word := range strings.Fields(reply) {
+            if i > 0 { word = " " + word }
+            b, _ := json.Marshal(llm.ChatChunk{ID: "chatcmpl-test", Object: "chat.completion.chunk", Model: req.Model,
+                Choices: []llm.ChunkChoice{{Delta: llm.Message{Content: word}}}})
+            fmt.Fprintf(w, "data: %s\n\n", b)
+        }
+        fmt.Fprintf(w, "data: [DONE]\n\n")
+    }))
+}
+
+// llmSpy records the chat requests forwarded to a fake upstream.
+type llmSpy struct {
+    *httptest.Server
+    mu   sync.Mutex
+    reqs []llm.ChatRequest
+}
+
+func newSpyLLM(t *testing.T, reply string) *llmSpy {
+    t.Helper()
+    up := newFakeLLM(t, reply)
+    t.Cleanup(up.Close)
+    spy := &llmSpy{}
+    spy.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        b, _ := io.ReadAll(r.Body)
+        var req llm.ChatRequest
+        if err := json.Unmarshal(b, &req); err == nil {
+            spy.mu.Lock()
+            spy.reqs = append(spy.reqs, req)
+            spy.mu.Unlock()
+        }
+        r.Body = io.NopCloser(bytes.NewReader(b))
+        up.Config.Handler.ServeHTTP(w, r)
+    }))
+    return spy
+}
+
+func (s *llmSpy) requests() []llm.ChatRequest {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]llm.ChatRequest(nil), s.reqs...)
+}
+
+func newChatServer(t *testing.T, upstream string) *httptest.Server {
+    t.Helper()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(upstream+"/v1", "test-model", "")})
+    return httptest.NewServer(mux)
+}
+
+func TestChatCompletions_NonStreaming(t *testing.T) {
+    up := newFakeLLM(t, "hello from the model")
+    defer up.Close()
+    ts := newChatServer(t, up.URL)
+    defer ts.Close()
+
+    body, _ := json.Marshal(map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}})
+    resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", bytes.NewReader(body))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+    var out llm.ChatResponse
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatalf("decode failed: %v", err) }
+    if out.Text() != "hello from the model" { t.Fatalf("unexpected reply %q", out.Text()) }
+    if out.Model != "test-model" { t.Fatalf("expected default model, got %q", out.Model) }
+}
+
+func TestChatCompletions_Streaming(t *testing.T) {
+    up := newFakeLLM(t, "one two three")
+    defer up.Close()
+    ts := newChatServer(t, up.URL)
+    defer ts.Close()
+
+    body, _ := json.Marshal(map[string]any{"stream": true, "messages": []map[string]string{{"role": "user", "content": "count"}}})
+    resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", bytes.NewReader(body))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") { t.Fatalf("unexpected content type %q", ct) }
+    var text strings.Builder
+    done := false
+    scan := bufio.NewScanner(resp.Body)
+    for scan.Scan() {
+        line := strings.TrimPrefix(scan.Text(), "data: ")
+        if line == "" || line == scan.Text() { continue }
+        if line == "[DONE]" { done = true; break }
+        var c llm.ChatChunk
+        if err := json.Unmarshal([]byte(line), &c); err != nil { t.Fatalf("bad chunk %q: %v", line, err) }
+        text.WriteString(c.Choices[0].Delta.Content)
+    }
+    if !done { t.Fatalf("stream did not terminate with [DONE]") }
+    if text.String() != "one two three" { t.Fatalf("unexpected streamed text %q", text.String()) }
+}
+
+func TestChatCompletions_EmptyMessages(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    ts := newChatServer(t, up.URL)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"messages":[]}`))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400, got %d", resp.StatusCode) }
+}
+
+func TestChatWebSocket_StreamsDeltas(t *testing.T) {
+    up := newFakeLLM(t, "streamed over the socket")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}, server.WSOptions{Enable: true})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    _ = conn.WriteJSON(map[string]any{"id": "r1", "messages": []map[string]string{{"role": "user", "content": "hi"}}})
+
+    var text strings.Builder
+    for {
+        var ev map[string]any
+        if err := conn.ReadJSON(&ev); err != nil { t.Fatalf("read failed: %v", err) }
+        if ev["id"] != "r1" { t.Fatalf("unexpected frame id: %v", ev) }
+        switch ev["type"] {
+        case "delta":
+            text.WriteString(ev["content"].(string))
+        case "done":
+            if text.String() != "streamed over the socket" || ev["content"] != text.String() { t.Fatalf("unexpected content %q / %v", text.String(), ev["content"]) }
+            return
+        default:
+            t.Fatalf("unexpected frame: %v", ev)
+        }
+    }
+}
+
+func TestWebSocket_OversizedMessageClosesConnection(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}, server.WSOptions{Enable: true, MaxMessageBytes: 64})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    _ = conn.WriteJSON(map[string]any{"id": "r1", "messages": []map[string]string{{"role": "user", "content": strings.Repeat("x", 256)}}})
+    var ev map[string]any
+    err = conn.ReadJSON(&ev)
+    if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) { t.Fatalf("expected close 1009, got %v (%v)", err, ev) }
+}
+
+func TestWebSocket_MaxConnections(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}, server.WSOptions{Enable: true, MaxConnections: 1})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/chat"
+    first, _, err := websocket.DefaultDialer.Dial(url, nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer first.Close()
+    _, resp, err := websocket.DefaultDialer.Dial(url, nil)
+    if err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable { t.Fatalf("expected 503 for second connection, got %v", err) }
+}
+
+func TestCapabilities_ReflectsEnabledServices(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    ts := newChatServer(t, up.URL)
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/v1/capabilities")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+    var caps struct {
+        Services  map[string]any      `json:"services"`
+        Streaming map[string][]string `json:"streaming"`
+        LLM       map[string]any      `json:"llm"`
+        Auth      map[string]any      `json:"auth"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&caps); err != nil { t.Fatalf("decode failed: %v", err) }
+    if caps.Services["llm"] != true || caps.Services["stt"] != false { t.Fatalf("unexpected services: %v", caps.Services) }
+    if len(caps.Streaming["chat_completions"]) == 0 { t.Fatalf("expected chat streaming formats, got %v", caps.Streaming) }
+    if caps.LLM["default_model"] != "test-model" { t.Fatalf("unexpected llm caps: %v", caps.LLM) }
+    if caps.Auth["required"] != false { t.Fatalf("unexpected auth caps: %v", caps.Auth) }
+}
@@ -0,0 +1,53 @@
+package api_test
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/semcache"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+)
+
+func TestSemanticCache_ServesSimilarPrompts(t *testing.T) {
+    spy := newSpyLLM(t, "We open at nine.")
+    defer spy.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:        llm.New(spy.URL+"/v1", "test-model", ""),
+        Embeddings: embeddings.New(embeddings.Config{}),
+        ChatCache:  semcache.New(semcache.Options{Threshold: 0.9}),
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    ask := func(q string, stream bool, header ...string) (string, string) {
+        t.Helper()
+        body := `{"stream": ` + map[bool]string{true: "true", false: "false"}[stream] + `, "messages": [{"role": "system", "content": "Museum guide."}, {"role": "user", "content": "` + q + `"}]}`
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        if len(header) == 2 { req.Header.Set(header[0], header[1]) }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatal(err) }
+        defer resp.Body.Close()
+        b, _ := io.ReadAll(resp.Body)
+        if resp.StatusCode != http.StatusOK { t.Fatalf("chat: %d %s", resp.StatusCode, b) }
+        return resp.Header.Get("X-Semantic-Cache"), string(b)
+    }
+
+    if state, _ := ask("What time do you open?", false); state != "miss" { t.Fatalf("first request: %q", state) }
+    state, body := ask("what time do you open", false)
+    if state != "hit" || !strings.Contains(body, "We open at nine.") { t.Fatalf("rephrased request: %q %s", state, body) }
+    state, body = ask("What time do you open?", true)
+    if state != "hit" || !strings.Contains(body, `"content":"We open at nine."`) || !strings.HasSuffix(body, "data: [DONE]\n\n") { t.Fatalf("streamed hit: %q %s", state, body) }
+    if len(spy.requests()) != 1 { t.Fatalf("expected 1 upstream call, got %d", len(spy.requests())) }
+
+    if state, _ := ask("Where is the dinosaur skeleton exhibited?", false); state != "miss" { t.Fatalf("unrelated request: %q", state) }
+    if state, _ := ask("What time do you open?", false, "X-Semantic-Cache", "bypass"); state != "bypass" { t.Fatalf("bypass: %q", state) }
+    if state, _ := ask("What time do you open?", false, "Cache-Control", "no-cache"); state != "miss" { t.Fatalf("no-cache: %q", state) }
+    if n := len(spy.requests()); n != 4 { t.Fatalf("expected 4 upstream calls, got %d", n) }
+}
@@ -0,0 +1,59 @@
+package api_test
+
+import (
+    "bytes"
+    "errors"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// buildCLI builds the gollmcore binary into a temporary directory.
+func buildCLI(t *testing.T) string {
+    t.Helper()
+    gobin, err := exec.LookPath("go")
+    if err != nil { t.Skip("go toolchain not in PATH") }
+    bin := filepath.Join(t.TempDir(), "gollmcore")
+    if out, err := exec.Command(gobin, "build", "-o", bin, "gollmcore/cmd/gollmcore").CombinedOutput(); err != nil { t.Fatalf("build: %v\n%s", err, out) }
+    return bin
+}
+
+func TestCLI_DispatchAndUsage(t *testing.T) {
+    bin := buildCLI(t)
+    dir := t.TempDir()
+    data := filepath.Join(dir, "data")
+
+    cases := []struct {
+        name   string
+        args   []string
+        code   int
+        stderr string
+    }{
+        {"unknown command", []string{"frobnicate"}, 2, `unknown command "frobnicate"`},
+        {"unknown command lists the commands", []string{"frobnicate"}, 2, "Commands:"},
+        {"unknown server flag", []string{"--frobnicate"}, 2, "flag provided but not defined: -frobnicate"},
+        {"server help", []string{"-h"}, 0, "transcribe, speak, embed, chat"},
+        {"unknown subcommand flag", []string{"transcribe", "--frobnicate"}, 2, "flag provided but not defined: -frobnicate"},
+        {"subcommand help", []string{"embed", "-h"}, 0, "-data-dir"},
+        {"transcribe without files", []string{"transcribe", "--data-dir", data}, 1, "usage: gollmcore transcribe"},
+        {"embed without input", []string{"embed", "--data-dir", data}, 1, "usage: gollmcore embed"},
+        {"models without a subcommand", []string{"models"}, 1, "usage: gollmcore models"},
+    }
+    for _, c := range cases {
+        cmd := exec.Command(bin, c.args...)
+        cmd.Dir = dir // no config.json, so subcommands use the defaults
+        cmd.Stdin = strings.NewReader("")
+        var stderr bytes.Buffer
+        cmd.Stderr = &stderr
+        err := cmd.Run()
+        code := 0
+        var exit *exec.ExitError
+        if errors.As(err, &exit) {
+            code = exit.ExitCode()
+        } else if err != nil {
+            t.Fatalf("%s: %v", c.name, err)
+        }
+        if code != c.code || !strings.Contains(stderr.String(), c.stderr) { t.Errorf("%s: exit %d, want %d, stderr lacks %q:\n%s", c.name, code, c.code, c.stderr, stderr.String()) }
+    }
+}
@@ -0,0 +1,76 @@
+package api_test
+
+import (
+    "compress/gzip"
+    "compress/zlib"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+)
+
+func newCompressServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    mux := http.NewServeMux()
+    mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string]string{"text": strings.Repeat("embedding ", 500)})
+    })
+    mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        _, _ = w.Write([]byte(`{"ok":true}`))
+    })
+    mux.HandleFunc("/audio", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "audio/wav")
+        _, _ = w.Write(make([]byte, 4096))
+    })
+    ts := httptest.NewServer(server.Compress(mux, server.CompressOptions{Enabled: true, MinBytes: 1024}))
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+// getEncoded sets Accept-Encoding itself, so the client leaves the body encoded.
+func getEncoded(t *testing.T, url, accept string) (*http.Response, []byte) {
+    t.Helper()
+    req, _ := http.NewRequest(http.MethodGet, url, nil)
+    req.Header.Set("Accept-Encoding", accept)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    body, _ := io.ReadAll(resp.Body)
+    return resp, body
+}
+
+func TestCompress_LargeJSON(t *testing.T) {
+    ts := newCompressServer(t)
+    for enc, open := range map[string]func(io.Reader) (io.Reader, error){
+        "gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+        "deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+    } {
+        resp, body := getEncoded(t, ts.URL+"/big", enc)
+        if resp.Header.Get("Content-Encoding") != enc || resp.Header.Get("Vary") != "Accept-Encoding" { t.Fatalf("%s: unexpected headers %v", enc, resp.Header) }
+        if len(body) > 1000 { t.Fatalf("%s: body not compressed (%d bytes)", enc, len(body)) }
+        r, err := open(strings.NewReader(string(body)))
+        if err != nil { t.Fatal(err) }
+        var out map[string]string
+        if err := json.NewDecoder(r).Decode(&out); err != nil || len(out["text"]) != 5000 { t.Fatalf("%s: bad body after decoding: %v", enc, err) }
+    }
+}
+
+func TestCompress_PassesThrough(t *testing.T) {
+    ts := newCompressServer(t)
+    cases := []struct{ path, accept string; size int }{
+        {"/big", "identity", 5012}, // client did not ask
+        {"/big", "gzip;q=0", 5012},
+        {"/small", "gzip", 11},     // below the threshold
+        {"/audio", "gzip", 4096},   // not JSON
+    }
+    for _, c := range cases {
+        resp, body := getEncoded(t, ts.URL+c.path, c.accept)
+        if resp.Header.Get("Content-Encoding") != "" || len(body) != c.size { t.Fatalf("%s (%q): expected %d plain bytes, got %d encoded %q", c.path, c.accept, c.size, len(body), resp.Header.Get("Content-Encoding")) }
+    }
+}
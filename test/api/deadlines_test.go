@@ -0,0 +1,64 @@
+package api_test
+
+import (
+    "bufio"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+)
+
+func newDeadlineServer(t *testing.T, h http.HandlerFunc) *httptest.Server {
+    t.Helper()
+    ts := httptest.NewServer(server.Deadlines(h, server.DeadlineOptions{Read: 100 * time.Millisecond, Write: 100 * time.Millisecond}))
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+func TestDeadlines_LongStreamAndSlowHandler(t *testing.T) {
+    ts := newDeadlineServer(t, func(w http.ResponseWriter, r *http.Request) {
+        _, _ = io.ReadAll(r.Body)
+        // Work well past both timeouts before answering, then stream.
+        select {
+        case <-time.After(300 * time.Millisecond):
+        case <-r.Context().Done(): return
+        }
+        for i := 0; i < 6; i++ {
+            _, _ = io.WriteString(w, "data: tick\n\n")
+            w.(http.Flusher).Flush()
+            time.Sleep(60 * time.Millisecond)
+        }
+    })
+    resp, err := http.Post(ts.URL, "text/plain", strings.NewReader("hello"))
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    n := 0
+    for sc := bufio.NewScanner(resp.Body); sc.Scan(); { if sc.Text() == "data: tick" { n++ } }
+    if n != 6 { t.Fatalf("stream was cut off after %d events", n) }
+}
+
+func TestDeadlines_SlowBody(t *testing.T) {
+    got := make(chan error, 1)
+    ts := newDeadlineServer(t, func(w http.ResponseWriter, r *http.Request) {
+        _, err := io.ReadAll(r.Body)
+        got <- err
+    })
+    pr, pw := io.Pipe()
+    go func() {
+        _, _ = pw.Write([]byte("part"))
+        time.Sleep(400 * time.Millisecond)
+        pw.Close()
+    }()
+    req, _ := http.NewRequest(http.MethodPost, ts.URL, pr)
+    if resp, err := http.DefaultClient.Do(req); err == nil { resp.Body.Close() }
+    select {
+    case err := <-got:
+        if err == nil { t.Fatal("expected the slow body to time out") }
+    case <-time.After(2 * time.Second):
+        t.Fatal("handler never finished reading")
+    }
+}
@@ -0,0 +1,41 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+)
+
+func TestDebug_VarsAndPprofForAdminsOnly(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterAdminRoutes(mux, server.AdminOptions{Debug: true})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/debug/vars")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200 from loopback, got %d", resp.StatusCode) }
+    var vars struct {
+        Goroutines int            `json:"goroutines"`
+        Memory     map[string]any `json:"memory"`
+        Processes  []any          `json:"child_processes"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil { t.Fatalf("decode: %v", err) }
+    if vars.Goroutines == 0 || vars.Memory["heap_inuse_bytes"] == nil || vars.Processes == nil { t.Fatalf("incomplete vars: %+v", vars) }
+
+    resp, err = http.Get(ts.URL + "/debug/pprof/goroutine?debug=1")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected pprof 200, got %d", resp.StatusCode) }
+
+    // Remote callers without an admin key are refused.
+    req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+    req.RemoteAddr = "203.0.113.7:4000"
+    rec := httptest.NewRecorder()
+    mux.ServeHTTP(rec, req)
+    if rec.Code != http.StatusForbidden { t.Fatalf("expected 403 for remote caller, got %d", rec.Code) }
+}
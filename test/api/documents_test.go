@@ -0,0 +1,86 @@
+package api_test
+
+import (
+    "encoding/json"
+    "math"
+    "net/http"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/services/embeddings"
+)
+
+type documentResult struct {
+    Model  string `json:"model"`
+    Chunks []struct {
+        Index     int       `json:"index"`
+        Start     int       `json:"start"`
+        End       int       `json:"end"`
+        Text      string    `json:"text"`
+        Embedding []float32 `json:"embedding"`
+    } `json:"chunks"`
+    Embedding []float32 `json:"embedding"`
+}
+
+func postDocument(t *testing.T, url string, body map[string]any) *http.Response {
+    t.Helper()
+    b, _ := json.Marshal(body)
+    resp, err := http.Post(url+"/v1/embeddings/document", "application/json", strings.NewReader(string(b)))
+    if err != nil { t.Fatal(err) }
+    return resp
+}
+
+func TestDocumentEmbeddings_ChunksWithOverlapAndPools(t *testing.T) {
+    ts := newTestServer(t, embeddings.New(embeddings.Config{}))
+    defer ts.Close()
+
+    words := make([]string, 120)
+    for i := range words { words[i] = []string{"alpha", "béta", "gamma", "дельта", "epsilon"}[i%5] }
+    doc := strings.Join(words, " ")
+    resp := postDocument(t, ts.URL, map[string]any{"input": doc, "chunk_size": 100, "chunk_overlap": 20, "pool": true})
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+    var out documentResult
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatal(err) }
+
+    runes := []rune(doc)
+    if len(out.Chunks) < 7 { t.Fatalf("expected the document to be split, got %d chunks", len(out.Chunks)) }
+    for i, c := range out.Chunks {
+        if c.Index != i || string(runes[c.Start:c.End]) != c.Text { t.Fatalf("chunk %d offsets do not match its text: %+v", i, c) }
+        if c.End-c.Start > 100 { t.Fatalf("chunk %d is longer than chunk_size: %d", i, c.End-c.Start) }
+        if strings.HasPrefix(c.Text, " ") || strings.HasSuffix(c.Text, " ") { t.Fatalf("chunk %d is not trimmed: %q", i, c.Text) }
+        if c.Start > 0 && runes[c.Start-1] != ' ' { t.Fatalf("chunk %d starts inside a word: %q", i, c.Text) }
+        if len(c.Embedding) != 384 { t.Fatalf("chunk %d has no embedding", i) }
+        if i > 0 && c.Start >= out.Chunks[i-1].End { t.Fatalf("chunks %d and %d do not overlap", i-1, i) }
+    }
+    if last := out.Chunks[len(out.Chunks)-1]; last.End != len(runes) { t.Fatalf("document tail not covered: %d of %d", last.End, len(runes)) }
+
+    var n float64
+    for _, x := range out.Embedding { n += float64(x) * float64(x) }
+    if len(out.Embedding) != 384 || math.Abs(n-1) > 1e-3 { t.Fatalf("pooled vector missing or not normalized (len %d, norm² %f)", len(out.Embedding), n) }
+}
+
+func TestDocumentEmbeddings_Validation(t *testing.T) {
+    ts := newTestServer(t, embeddings.New(embeddings.Config{}))
+    defer ts.Close()
+
+    for _, tc := range []struct {
+        body  map[string]any
+        param string
+    }{
+        {map[string]any{"input": "   "}, "input"},
+        {map[string]any{"input": "text", "chunk_size": 4}, "chunk_size"},
+        {map[string]any{"input": "text", "chunk_size": 100, "chunk_overlap": 100}, "chunk_overlap"},
+    } {
+        resp := postDocument(t, ts.URL, tc.body)
+        if resp.StatusCode != http.StatusBadRequest { t.Fatalf("%v: expected 400, got %d", tc.body, resp.StatusCode) }
+        if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != tc.param { t.Fatalf("%v: unexpected error %+v", tc.body, e.Error) }
+    }
+
+    // A short document is a single chunk and needs no pooled vector.
+    resp := postDocument(t, ts.URL, map[string]any{"input": "just a sentence"})
+    defer resp.Body.Close()
+    var out documentResult
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    if len(out.Chunks) != 1 || out.Chunks[0].Text != "just a sentence" || out.Embedding != nil { t.Fatalf("unexpected result: %+v", out) }
+}
@@ -0,0 +1,119 @@
+package api_test
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+// newDrainServer starts mux behind a Drainer the way main wires it.
+func newDrainServer(t *testing.T, mux *http.ServeMux) (*httptest.Server, *server.Drainer) {
+    t.Helper()
+    drainer := server.NewDrainer()
+    ts := httptest.NewUnstartedServer(drainer.Handler(mux))
+    ts.Config.BaseContext = drainer.BaseContext
+    ts.Start()
+    t.Cleanup(ts.Close)
+    return ts, drainer
+}
+
+func TestDrain_InFlightRequestCompletes(t *testing.T) {
+    mux := http.NewServeMux()
+    started := make(chan struct{})
+    mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+        close(started)
+        time.Sleep(300 * time.Millisecond)
+        _, _ = w.Write([]byte("finished"))
+    })
+    ts, drainer := newDrainServer(t, mux)
+
+    type result struct { body string; err error }
+    got := make(chan result, 1)
+    go func() {
+        resp, err := http.Get(ts.URL + "/slow")
+        if err != nil { got <- result{err: err}; return }
+        defer resp.Body.Close()
+        b, err := io.ReadAll(resp.Body)
+        got <- result{string(b), err}
+    }()
+    <-started
+    drainer.Shutdown(ts.Config, 5*time.Second)
+
+    res := <-got
+    if res.err != nil || res.body != "finished" { t.Fatalf("in-flight request was not drained: %q %v", res.body, res.err) }
+}
+
+func TestDrain_TimeoutCancelsRemainingWork(t *testing.T) {
+    mux := http.NewServeMux()
+    started := make(chan struct{})
+    cancelled := make(chan struct{})
+    mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+        close(started)
+        <-r.Context().Done()
+        close(cancelled)
+    })
+    ts, drainer := newDrainServer(t, mux)
+
+    go func() {
+        resp, err := http.Get(ts.URL + "/stuck")
+        if err == nil { resp.Body.Close() }
+    }()
+    <-started
+    begin := time.Now()
+    drainer.Shutdown(ts.Config, 200*time.Millisecond)
+    select {
+    case <-cancelled:
+    case <-time.After(2 * time.Second):
+        t.Fatal("request context was not cancelled after the drain timeout")
+    }
+    if d := time.Since(begin); d < 200*time.Millisecond { t.Fatalf("cancelled before the drain timeout: %s", d) }
+}
+
+func TestDrain_HealthzReportsDraining(t *testing.T) {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
+    ts, drainer := newDrainServer(t, mux)
+    h := drainer.Handler(mux)
+
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200 before shutdown, got %d", rec.Code) }
+
+    drainer.Shutdown(ts.Config, time.Second)
+    rec = httptest.NewRecorder()
+    h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected 503 while draining, got %d", rec.Code) }
+}
+
+func TestDrain_IdleWebSocketGoesAway(t *testing.T) {
+    up := newFakeLLM(t, "unused")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}, server.WSOptions{Enable: true})
+    ts, drainer := newDrainServer(t, mux)
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+
+    done := make(chan struct{})
+    go func() { drainer.Shutdown(ts.Config, 5*time.Second); close(done) }()
+
+    _ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+    var ev map[string]any
+    err = conn.ReadJSON(&ev)
+    if !websocket.IsCloseError(err, websocket.CloseGoingAway) { t.Fatalf("expected close 1001, got %v", err) }
+    select {
+    case <-done:
+    case <-time.After(3 * time.Second):
+        t.Fatal("shutdown did not finish after the idle connection closed")
+    }
+}
@@ -0,0 +1,208 @@
+package api_test
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "math"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+)
+
+type streamedBatch struct {
+    Model      string      `json:"model"`
+    Index      int         `json:"index"`
+    Embeddings [][]float32 `json:"embeddings"`
+}
+
+func TestEmbeddings_StreamFlushesSubBatches(t *testing.T) {
+    emb := embeddings.New(embeddings.Config{})
+    ts := newTestServer(t, emb)
+    defer ts.Close()
+
+    inputs := make([]string, 10)
+    for i := range inputs { inputs[i] = fmt.Sprintf("sentence number %d", i) }
+    body, _ := json.Marshal(map[string]any{"input": inputs, "stream": true, "batch_size": 4})
+    resp, err := http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(string(body)))
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" { t.Fatalf("expected an event stream, got %q", ct) }
+
+    want, _, _ := emb.Embed(context.Background(), inputs)
+    var batches []streamedBatch
+    event := ""
+    done := false
+    sc := bufio.NewScanner(resp.Body)
+    sc.Buffer(make([]byte, 1<<20), 1<<20)
+    for sc.Scan() {
+        line := sc.Text()
+        switch {
+        case strings.HasPrefix(line, "event: "):
+            event = strings.TrimPrefix(line, "event: ")
+        case strings.HasPrefix(line, "data: ") && event == "done":
+            var d struct{ Model string; Count int }
+            _ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &d)
+            if d.Count != len(inputs) || d.Model == "" { t.Fatalf("unexpected done event: %s", line) }
+            done = true
+        case strings.HasPrefix(line, "data: "):
+            var b streamedBatch
+            if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &b); err != nil { t.Fatalf("decode batch: %v", err) }
+            batches = append(batches, b)
+        }
+    }
+    if !done { t.Fatal("missing done event") }
+    if len(batches) != 3 { t.Fatalf("expected 3 sub-batches, got %d", len(batches)) }
+    for _, b := range batches {
+        for k, v := range b.Embeddings {
+            if len(v) != len(want[b.Index+k]) || v[0] != want[b.Index+k][0] { t.Fatalf("vector %d does not match input %d", k, b.Index+k) }
+        }
+    }
+    if batches[0].Index != 0 || batches[1].Index != 4 || batches[2].Index != 8 || len(batches[2].Embeddings) != 2 {
+        t.Fatalf("unexpected batch layout: %d/%d/%d", batches[0].Index, batches[1].Index, batches[2].Index)
+    }
+}
+
+func TestEmbeddingsWebSocket_Stream(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{Embeddings: embeddings.New(embeddings.Config{})}, server.WSOptions{Enable: true})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/embeddings", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    _ = conn.WriteJSON(map[string]any{"input": []string{"a", "b", "c"}, "stream": true, "batch_size": 2})
+
+    var indices []int
+    for {
+        var ev map[string]any
+        if err := conn.ReadJSON(&ev); err != nil { t.Fatalf("read failed: %v", err) }
+        if ev["event"] == "done" {
+            if ev["count"] != float64(3) { t.Fatalf("unexpected done frame: %v", ev) }
+            break
+        }
+        if ev["event"] != "data" { t.Fatalf("unexpected frame: %v", ev) }
+        indices = append(indices, int(ev["index"].(float64)))
+    }
+    if len(indices) != 2 || indices[0] != 0 || indices[1] != 2 { t.Fatalf("unexpected indices: %v", indices) }
+}
+
+func TestEmbeddings_DimensionsTruncateAndRenormalize(t *testing.T) {
+    emb := embeddings.New(embeddings.Config{})
+    ts := newTestServer(t, emb)
+    defer ts.Close()
+
+    full, _, _ := emb.Embed(context.Background(), []string{"matryoshka dolls"})
+    resp, err := http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(`{"input": "matryoshka dolls", "dimensions": 128}`))
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    var out struct{ Embeddings [][]float32 `json:"embeddings"` }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatal(err) }
+    v := out.Embeddings[0]
+    if len(v) != 128 { t.Fatalf("expected 128 dimensions, got %d", len(v)) }
+    var n float64
+    for _, x := range v { n += float64(x) * float64(x) }
+    if math.Abs(n-1) > 1e-3 { t.Fatalf("truncated vector not normalized: norm² %f", n) }
+    // Same direction as the prefix of the full vector.
+    var dot, pn float64
+    for k := range v { dot += float64(v[k]) * float64(full[0][k]); pn += float64(full[0][k]) * float64(full[0][k]) }
+    if math.Abs(dot/math.Sqrt(pn)-1) > 1e-3 { t.Fatalf("truncated vector is not the rescaled prefix (cos %f)", dot/math.Sqrt(pn)) }
+
+    resp, err = http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(`{"input": "x", "dimensions": 1000}`))
+    if err != nil { t.Fatal(err) }
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400 for too many dimensions, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Code != "invalid_dimensions" { t.Fatalf("unexpected error: %+v", e.Error) }
+}
+
+// sharedEmbeddings returns the same vectors to every caller, as coalesced
+// requests receive them.
+type sharedEmbeddings struct{ vec []float32 }
+
+func (s *sharedEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    vecs := make([][]float32, len(inputs))
+    for i := range vecs { vecs[i] = s.vec }
+    return vecs, "shared-model", nil
+}
+
+func TestEmbeddings_DimensionsLeaveSharedVectorsAlone(t *testing.T) {
+    vec := make([]float32, 16)
+    for i := range vec { vec[i] = float32(i+1) }
+    var n float64
+    for _, x := range vec { n += float64(x) * float64(x) }
+    for i := range vec { vec[i] /= float32(math.Sqrt(n)) }
+    orig := append([]float32(nil), vec...)
+    ts := newTestServer(t, &sharedEmbeddings{vec: vec})
+    defer ts.Close()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 20; i++ {
+        dims := 4 + 4*(i%2)
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            resp, err := http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(fmt.Sprintf(`{"input": "same", "dimensions": %d}`, dims)))
+            if err != nil { t.Error(err); return }
+            defer resp.Body.Close()
+            var out struct{ Embeddings [][]float32 `json:"embeddings"` }
+            if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Error(err); return }
+            v := out.Embeddings[0]
+            var pn float64
+            for _, x := range orig[:dims] { pn += float64(x) * float64(x) }
+            for k := range v {
+                if want := float64(orig[k]) / math.Sqrt(pn); math.Abs(float64(v[k])-want) > 1e-5 { t.Errorf("dimensions %d: component %d is %f, want %f", dims, k, v[k], want); return }
+            }
+        }()
+    }
+    wg.Wait()
+    for i := range vec {
+        if vec[i] != orig[i] { t.Fatalf("the service's vector was modified: %v", vec) }
+    }
+}
+
+func TestEmbeddings_NormalizeOption(t *testing.T) {
+    off := false
+    emb := embeddings.New(embeddings.Config{Defaults: embeddings.Options{Normalize: &off}})
+    ts := newTestServer(t, emb)
+    defer ts.Close()
+
+    embed := func(body string) []float32 {
+        t.Helper()
+        resp, err := http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(body))
+        if err != nil { t.Fatal(err) }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("%s: expected 200, got %d", body, resp.StatusCode) }
+        var out struct{ Embeddings [][]float32 `json:"embeddings"` }
+        if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatal(err) }
+        return out.Embeddings[0]
+    }
+    norm2 := func(v []float32) float64 {
+        var n float64
+        for _, x := range v { n += float64(x) * float64(x) }
+        return n
+    }
+
+    // The configured default leaves vectors as they are, also when they are
+    // truncated; a request can turn normalization back on.
+    raw := embed(`{"input": "matryoshka dolls"}`)
+    if math.Abs(norm2(raw)-1) < 1e-3 { t.Fatalf("expected an unnormalized vector, norm² %f", norm2(raw)) }
+    short := embed(`{"input": "matryoshka dolls", "dimensions": 128}`)
+    if math.Abs(norm2(short)-norm2(raw[:128])) > 1e-6 { t.Fatalf("unnormalized vector rescaled on truncation: norm² %f", norm2(short)) }
+    unit := embed(`{"input": "matryoshka dolls", "normalize": true, "pooling": "cls"}`)
+    if math.Abs(norm2(unit)-1) > 1e-3 { t.Fatalf("normalize true ignored: norm² %f", norm2(unit)) }
+
+    resp, err := http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(`{"input": "x", "pooling": "sum"}`))
+    if err != nil { t.Fatal(err) }
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400 for an unknown pooling, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "pooling" { t.Fatalf("unexpected error: %+v", e.Error) }
+}
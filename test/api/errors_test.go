@@ -0,0 +1,122 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/sessions"
+)
+
+type errorBody struct {
+    Error struct {
+        Message string  `json:"message"`
+        Type    string  `json:"type"`
+        Code    string  `json:"code"`
+        Param   *string `json:"param"`
+    } `json:"error"`
+}
+
+func decodeError(t *testing.T, resp *http.Response) errorBody {
+    t.Helper()
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); ct != "application/json" { t.Fatalf("expected JSON error, got %q", ct) }
+    var e errorBody
+    if err := json.NewDecoder(resp.Body).Decode(&e); err != nil { t.Fatalf("decode: %v", err) }
+    return e
+}
+
+func TestErrors_JSONEnvelope(t *testing.T) {
+    upstream := newFakeLLM(t, "hi")
+    defer upstream.Close()
+    store := sessions.New(filepath.Join(t.TempDir(), "sessions.db"), sessions.Options{})
+    defer store.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(upstream.URL+"/v1", "test-model", ""), Sessions: store})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"messages":[]}`))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400, got %d", resp.StatusCode) }
+    e := decodeError(t, resp)
+    if e.Error.Type != "invalid_request_error" || e.Error.Param == nil || *e.Error.Param != "messages" { t.Fatalf("unexpected error: %+v", e.Error) }
+
+    resp, err = http.Get(ts.URL + "/v1/sessions/missing")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    if resp.StatusCode != http.StatusNotFound { t.Fatalf("expected 404, got %d", resp.StatusCode) }
+    e = decodeError(t, resp)
+    if e.Error.Type != "not_found_error" || e.Error.Code != "session_not_found" || e.Error.Param != nil { t.Fatalf("unexpected error: %+v", e.Error) }
+
+    resp, err = http.Get(ts.URL + "/v1/chat/completions")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    if resp.StatusCode != http.StatusMethodNotAllowed { t.Fatalf("expected 405, got %d", resp.StatusCode) }
+    if e = decodeError(t, resp); e.Error.Code != "method_not_allowed" { t.Fatalf("unexpected error: %+v", e.Error) }
+}
+
+func TestTimeouts_ReportedAs504(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{TTS: &slowTTS{}, Timeouts: server.Timeouts{TTS: 20 * time.Millisecond}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"hello"}`))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    if resp.StatusCode != http.StatusGatewayTimeout { t.Fatalf("expected 504, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Type != "timeout_error" { t.Fatalf("unexpected error: %+v", e.Error) }
+}
+
+func TestLimits_QueueThenReject(t *testing.T) {
+    mux := http.NewServeMux()
+    d := server.WithLimits(server.Dependencies{TTS: &slowTTS{}, Limits: server.Limits{TTS: server.Limit{MaxConcurrent: 1, MaxQueue: 1}}})
+    server.RegisterRoutes(mux, d)
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    codes := make(chan int, 4)
+    for i := 0; i < 4; i++ {
+        go func() {
+            resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"hello"}`))
+            if err != nil { codes <- 0; return }
+            resp.Body.Close()
+            codes <- resp.StatusCode
+        }()
+    }
+    counts := map[int]int{}
+    for i := 0; i < 4; i++ { counts[<-codes]++ }
+    // One runs, one waits in the queue, the rest are turned away.
+    if counts[http.StatusOK] != 2 || counts[http.StatusTooManyRequests] != 2 { t.Fatalf("unexpected status counts: %v", counts) }
+}
+
+func TestRouting_MethodsAndPathParams(t *testing.T) {
+    store := sessions.New(filepath.Join(t.TempDir(), "sessions.db"), sessions.Options{})
+    defer store.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{Sessions: store})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/sessions", map[string]any{})
+    var sess sessions.Session
+    _ = json.NewDecoder(resp.Body).Decode(&sess)
+    resp.Body.Close()
+    resp, err := http.Get(ts.URL + "/v1/sessions/" + sess.ID)
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected the session by id, got %d", resp.StatusCode) }
+
+    resp, err = http.Get(ts.URL + "/v1/sessions/" + sess.ID + "/messages")
+    if err != nil { t.Fatal(err) }
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusMethodNotAllowed || e.Error.Code != "method_not_allowed" || resp.Header.Get("Allow") != "POST" { t.Fatalf("expected a JSON 405 allowing POST, got %d %q %+v", resp.StatusCode, resp.Header.Get("Allow"), e.Error) }
+    req, _ := http.NewRequest(http.MethodPut, ts.URL+"/v1/sessions/"+sess.ID, nil)
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, DELETE, HEAD" { t.Fatalf("unexpected %d Allow %q", resp.StatusCode, resp.Header.Get("Allow")) }
+}
@@ -0,0 +1,104 @@
+package api_test
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/server"
+)
+
+func TestEvents_DownloadProgress(t *testing.T) {
+    blob := bytes.Repeat([]byte("x"), 256<<10)
+    files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { http.ServeContent(w, r, "model.bin", time.Time{}, bytes.NewReader(blob)) }))
+    defer files.Close()
+
+    events := server.NewEvents()
+    defer models.OnProgress(func(p models.Progress) { events.Publish("download.progress", p) })()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{Events: events})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/v1/events")
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" { t.Fatalf("content type %q", ct) }
+    lines := bufio.NewScanner(resp.Body)
+    lines.Scan() // ": connected", sent once subscribed
+
+    errc := make(chan error, 1)
+    go func() { errc <- models.Fetch(files.URL+"/model.bin", filepath.Join(t.TempDir(), "model.bin"), 10*time.Second) }()
+
+    var got []models.Progress
+    for lines.Scan() {
+        data, ok := strings.CutPrefix(lines.Text(), "data: ")
+        if !ok { continue }
+        var ev struct {
+            Type string          `json:"type"`
+            Data models.Progress `json:"data"`
+        }
+        if err := json.Unmarshal([]byte(data), &ev); err != nil { t.Fatal(err) }
+        if ev.Type != "download.progress" || ev.Data.Asset != "model.bin" { t.Fatalf("unexpected event %s", data) }
+        got = append(got, ev.Data)
+        if ev.Data.Done { break }
+    }
+    if err := <-errc; err != nil { t.Fatal(err) }
+    if len(got) < 2 || got[0].Bytes != 0 { t.Fatalf("expected a start and an end event, got %+v", got) }
+    last := got[len(got)-1]
+    if last.Bytes != int64(len(blob)) || last.Total != int64(len(blob)) || last.Percent != 100 || last.Error != "" { t.Fatalf("unexpected final progress %+v", last) }
+}
+
+func TestEvents_QueueSaturation(t *testing.T) {
+    tts := &gatedTTS{started: make(chan string, 8), release: make(chan struct{})}
+    events := server.NewEvents()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithLimits(server.Dependencies{TTS: tts, Events: events, Limits: server.Limits{TTS: server.Limit{MaxConcurrent: 1, MaxQueue: 1}}}))
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/v1/events")
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    lines := bufio.NewScanner(resp.Body)
+    lines.Scan() // ": connected"
+
+    codes := make(chan int, 4)
+    post := func(text string) {
+        resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"`+text+`"}`))
+        if err != nil { codes <- 0; return }
+        resp.Body.Close()
+        codes <- resp.StatusCode
+    }
+    go post("running")
+    <-tts.started
+    go post("queued")
+    time.Sleep(50 * time.Millisecond)
+    go post("rejected")
+    if code := <-codes; code != http.StatusTooManyRequests { t.Fatalf("expected 429 for a full queue, got %d", code) }
+    close(tts.release)
+    for i := 0; i < 2; i++ {
+        if code := <-codes; code != http.StatusOK { t.Fatalf("unexpected status %d", code) }
+    }
+
+    var types []string
+    for len(types) < 2 && lines.Scan() {
+        data, ok := strings.CutPrefix(lines.Text(), "data: ")
+        if !ok { continue }
+        var ev struct {
+            Type string         `json:"type"`
+            Data map[string]any `json:"data"`
+        }
+        if err := json.Unmarshal([]byte(data), &ev); err != nil { t.Fatal(err) }
+        if ev.Data["service"] != "tts" { t.Fatalf("unexpected event %s", data) }
+        types = append(types, ev.Type)
+    }
+    if strings.Join(types, ",") != "queue.saturated,queue.cleared" { t.Fatalf("unexpected events %v", types) }
+}
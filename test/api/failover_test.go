@@ -0,0 +1,80 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestFailover_SkipsDownServersAndOpensCircuits(t *testing.T) {
+    crashed := httptest.NewServer(http.NotFoundHandler())
+    crashed.Close()
+    var overloaded atomic.Int32
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        overloaded.Add(1)
+        http.Error(w, "loading model", http.StatusServiceUnavailable)
+    }))
+    defer failing.Close()
+    remote := newSpyLLM(t, "from remote")
+    defer remote.Close()
+
+    chain := llm.NewFailover(
+        llm.New(crashed.URL+"/v1", "local-model", ""),
+        llm.New(failing.URL+"/v1", "ollama-model", ""),
+        llm.New(remote.URL+"/v1", "remote-model", ""),
+    )
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: chain, Status: server.StatusOptions{LLM: chain}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    for i := 0; i < 5; i++ {
+        resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}})
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("chat %d: status %d", i, resp.StatusCode) }
+    }
+    reqs := remote.requests()
+    if len(reqs) != 5 || reqs[0].Model != "remote-model" { t.Fatalf("last server got %+v, want 5 chats for its own model", reqs) }
+    if n := overloaded.Load(); n != 3 { t.Fatalf("failing server got %d chats, want 3 before its circuit opened", n) }
+
+    resp, err := http.Get(ts.URL + "/v1/status")
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    var st struct {
+        Services map[string]struct {
+            State    string `json:"state"`
+            Failover []struct{ State string `json:"state"` } `json:"failover"`
+        } `json:"services"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&st); err != nil { t.Fatal(err) }
+    l := st.Services["llm"]
+    if l.State != "ready" || len(l.Failover) != 3 { t.Fatalf("unexpected llm status %+v", l) }
+    for i, want := range []string{"open", "open", "closed"} {
+        if l.Failover[i].State != want { t.Fatalf("server %d is %s, want %s (%+v)", i, l.Failover[i].State, want, l) }
+    }
+}
+
+func TestFailover_ReturnsRequestErrors(t *testing.T) {
+    var calls atomic.Int32
+    strict := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls.Add(1)
+        http.Error(w, "context length exceeded", http.StatusBadRequest)
+    }))
+    defer strict.Close()
+    backup := newSpyLLM(t, "backup")
+    defer backup.Close()
+
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.NewFailover(llm.New(strict.URL+"/v1", "m", ""), llm.New(backup.URL+"/v1", "m", ""))})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusBadGateway { t.Fatalf("status %d, want the primary's error as 502", resp.StatusCode) }
+    if calls.Load() != 1 || len(backup.requests()) != 0 { t.Fatal("a request the primary rejected must not fail over") }
+}
@@ -0,0 +1,136 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestGenerations_CancelRunningChat(t *testing.T) {
+    arrived, aborted := make(chan struct{}), make(chan struct{})
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        _, _ = io.Copy(io.Discard, r.Body) // the server notices a closed connection once the body is read
+        close(arrived)
+        select {
+        case <-r.Context().Done():
+            close(aborted)
+        case <-time.After(5 * time.Second):
+        }
+    }))
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")})
+    ts := httptest.NewServer(server.RequireAPIKey(mux, []string{"alice", "bob"}, nil))
+    defer ts.Close()
+
+    cancelAs := func(key, id string) int {
+        req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/v1/generations/"+id, nil)
+        req.Header.Set("Authorization", "Bearer "+key)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatal(err) }
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    type result struct {
+        status int
+        id     string
+        code   string
+    }
+    done := make(chan result, 1)
+    go func() {
+        b, _ := json.Marshal(map[string]any{"messages": []map[string]string{{"role": "user", "content": "write a novel"}}})
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", bytes.NewReader(b))
+        req.Header.Set("Content-Type", "application/json")
+        req.Header.Set("Authorization", "Bearer alice")
+        req.Header.Set("X-Generation-ID", "novel-1")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { done <- result{}; return }
+        defer resp.Body.Close()
+        var e struct{ Error struct{ Code string `json:"code"` } `json:"error"` }
+        _ = json.NewDecoder(resp.Body).Decode(&e)
+        done <- result{resp.StatusCode, resp.Header.Get("X-Generation-ID"), e.Error.Code}
+    }()
+
+    select {
+    case <-arrived:
+    case <-time.After(3 * time.Second):
+        t.Fatal("chat never reached the upstream")
+    }
+    if s := cancelAs("bob", "novel-1"); s != http.StatusNotFound { t.Fatalf("another key cancelled the generation: %d", s) }
+    if s := cancelAs("alice", "novel-1"); s != http.StatusOK { t.Fatalf("cancel: status %d", s) }
+    select {
+    case <-aborted:
+    case <-time.After(3 * time.Second):
+        t.Fatal("upstream call was not aborted")
+    }
+    r := <-done
+    if r.status != 499 || r.code != "cancelled" || r.id != "novel-1" { t.Fatalf("cancelled chat answered %+v", r) }
+    if s := cancelAs("alice", "novel-1"); s != http.StatusNotFound { t.Fatalf("finished generation: status %d, want 404", s) }
+}
+
+func TestGenerations_IDsScopedPerKey(t *testing.T) {
+    arrived := make(chan struct{})
+    var calls atomic.Int32
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        _, _ = io.Copy(io.Discard, r.Body)
+        if calls.Add(1) == 1 {
+            close(arrived)
+            select {
+            case <-r.Context().Done():
+            case <-time.After(5 * time.Second):
+            }
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`)
+    }))
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")})
+    ts := httptest.NewServer(server.RequireAPIKey(mux, []string{"alice", "bob"}, nil))
+    defer ts.Close()
+
+    chatAs := func(key string) *http.Response {
+        b, _ := json.Marshal(map[string]any{"messages": []map[string]string{{"role": "user", "content": "hello"}}})
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", bytes.NewReader(b))
+        req.Header.Set("Content-Type", "application/json")
+        req.Header.Set("Authorization", "Bearer "+key)
+        req.Header.Set("X-Generation-ID", "shared-1")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatal(err) }
+        return resp
+    }
+
+    done := make(chan int, 1)
+    go func() {
+        resp := chatAs("alice")
+        resp.Body.Close()
+        done <- resp.StatusCode
+    }()
+    select {
+    case <-arrived:
+    case <-time.After(3 * time.Second):
+        t.Fatal("chat never reached the upstream")
+    }
+
+    resp := chatAs("bob")
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("another key's running id: status %d, want 200", resp.StatusCode) }
+    resp = chatAs("alice")
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusConflict { t.Fatalf("same key's running id: status %d, want 409", resp.StatusCode) }
+
+    req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/v1/generations/shared-1", nil)
+    req.Header.Set("Authorization", "Bearer alice")
+    if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK { t.Fatalf("cancel: %v %v", resp, err) }
+    if s := <-done; s != 499 { t.Fatalf("cancelled chat: status %d, want 499", s) }
+}
@@ -0,0 +1,39 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+
+    "gollmcore/internal/server"
+)
+
+func TestHandoff_NeedsLoopbackAndAdminKey(t *testing.T) {
+    var handoffs atomic.Int32
+    released := make(chan struct{}, 1)
+    mux := http.NewServeMux()
+    server.RegisterAdminRoutes(mux, server.AdminOptions{OnHandoff: func() { handoffs.Add(1); released <- struct{}{} }})
+    h := server.RequireAPIKey(mux, []string{"user"}, []string{"admin"})
+
+    for _, c := range []struct {
+        name, remote, key string
+        want              int
+    }{
+        {"loopback without a key", "127.0.0.1:4000", "", http.StatusUnauthorized},
+        {"loopback with a regular key", "127.0.0.1:4000", "user", http.StatusForbidden},
+        {"remote with the admin key", "203.0.113.7:4000", "admin", http.StatusForbidden},
+        {"remote without a key", "203.0.113.7:4000", "", http.StatusUnauthorized},
+        {"loopback with the admin key", "[::1]:4000", "admin", http.StatusAccepted},
+    } {
+        req := httptest.NewRequest(http.MethodPost, "/admin/handoff", nil)
+        req.RemoteAddr = c.remote
+        if c.key != "" { req.Header.Set("Authorization", "Bearer "+c.key) }
+        rec := httptest.NewRecorder()
+        h.ServeHTTP(rec, req)
+        if rec.Code != c.want { t.Fatalf("%s: status %d, want %d", c.name, rec.Code, c.want) }
+        if c.want != http.StatusAccepted && handoffs.Load() != 0 { t.Fatalf("%s: handoff triggered", c.name) }
+    }
+    <-released
+    if n := handoffs.Load(); n != 1 { t.Fatalf("handoff triggered %d times, want 1", n) }
+}
@@ -0,0 +1,45 @@
+package api_test
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+)
+
+func TestIdempotencyKey_ReplaysChatResult(t *testing.T) {
+    up := newFakeLLM(t, "only once")
+    defer up.Close()
+    var calls atomic.Int32
+    counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls.Add(1)
+        up.Config.Handler.ServeHTTP(w, r)
+    }))
+    defer counting.Close()
+    ts := newChatServer(t, counting.URL)
+    defer ts.Close()
+
+    post := func(key string) (*http.Response, string) {
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+        req.Header.Set("Content-Type", "application/json")
+        if key != "" { req.Header.Set("Idempotency-Key", key) }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatalf("request failed: %v", err) }
+        defer resp.Body.Close()
+        b, _ := io.ReadAll(resp.Body)
+        return resp, string(b)
+    }
+
+    first, body1 := post("k1")
+    second, body2 := post("k1")
+    if first.StatusCode != http.StatusOK || second.StatusCode != http.StatusOK { t.Fatalf("unexpected statuses %d/%d", first.StatusCode, second.StatusCode) }
+    if body1 != body2 { t.Fatalf("replayed body differs:\n%s\n%s", body1, body2) }
+    if second.Header.Get("Idempotent-Replayed") != "true" { t.Fatalf("expected replay marker on retry") }
+    if n := calls.Load(); n != 1 { t.Fatalf("expected 1 upstream call, got %d", n) }
+
+    post("k2")
+    post("")
+    if n := calls.Load(); n != 3 { t.Fatalf("expected new keys and unkeyed requests to run, got %d calls", n) }
+}
@@ -0,0 +1,39 @@
+package api_test
+
+import (
+    "bytes"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestIOLog_RedactsAndTruncates(t *testing.T) {
+    up := newFakeLLM(t, "Sure, I will email you at bob@example.com once the refund for your order has been processed by our team.")
+    defer up.Close()
+    iolog, err := server.NewIOLog(40, []string{`[\w.+-]+@[\w-]+\.[\w.]+`})
+    if err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithIOLog(server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", ""), IOLog: iolog}))
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    var buf bytes.Buffer
+    log.SetOutput(&buf)
+    defer log.SetOutput(os.Stderr)
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "I am alice@example.com"}}})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("status %d", resp.StatusCode) }
+
+    out := buf.String()
+    if strings.Contains(out, "@example.com") { t.Fatalf("address not redacted:\n%s", out) }
+    if !strings.Contains(out, `io llm.prompt model=test-model role=user messages=1 chars=22 sha256:`) || !strings.Contains(out, `"I am [redacted]"`) { t.Fatalf("unexpected prompt line:\n%s", out) }
+    if !strings.Contains(out, `io llm.reply model=test-model chars=104`) || !strings.Contains(out, `"Sure, I will email you at [redacted] onc…"`) { t.Fatalf("unexpected reply line:\n%s", out) }
+
+    if _, err := server.NewIOLog(0, []string{"("}); err == nil { t.Fatal("expected an invalid pattern to fail") }
+}
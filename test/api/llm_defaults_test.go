@@ -0,0 +1,47 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestLLMDefaults_FillOmittedFields(t *testing.T) {
+    spy := newSpyLLM(t, "hi")
+    defer spy.Close()
+    temp, small, maxTokens := 0.3, 0.1, 256
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:          llm.New(spy.URL+"/v1", "test-model", ""),
+        PolicyPrompt: "Be safe.",
+        LLMDefaults: server.LLMDefaults{
+            GenerationDefaults: server.GenerationDefaults{SystemPrompt: "You are a kiosk.", Temperature: &temp, MaxTokens: &maxTokens, Stop: []string{"END"}},
+            Models:             map[string]server.GenerationDefaults{"tiny-model": {Temperature: &small}},
+        },
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    chat := func(body map[string]any) llm.ChatRequest {
+        t.Helper()
+        resp := postJSON(t, ts.URL+"/v1/chat/completions", body)
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("chat: %d", resp.StatusCode) }
+        reqs := spy.requests()
+        return reqs[len(reqs)-1]
+    }
+    user := []map[string]string{{"role": "user", "content": "hello"}}
+
+    req := chat(map[string]any{"messages": user})
+    if len(req.Messages) != 3 || req.Messages[0].Content != "Be safe." || req.Messages[1].Content != "You are a kiosk." { t.Fatalf("unexpected messages %+v", req.Messages) }
+    if *req.Temperature != 0.3 || *req.MaxTokens != 256 || len(req.Stop) != 1 { t.Fatalf("defaults not applied: %+v", req) }
+
+    // Request values and system messages win; per-model overrides apply.
+    req = chat(map[string]any{"model": "tiny-model", "temperature": 0.9, "messages": append([]map[string]string{{"role": "system", "content": "Mine."}}, user...)})
+    if len(req.Messages) != 3 || req.Messages[1].Content != "Mine." || *req.Temperature != 0.9 || *req.MaxTokens != 256 { t.Fatalf("request values overridden: %+v", req) }
+    req = chat(map[string]any{"model": "tiny-model", "messages": user})
+    if *req.Temperature != 0.1 || req.Messages[1].Content != "You are a kiosk." { t.Fatalf("model override not applied: %+v", req) }
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+// newLoRAUpstream fakes llama-server's /lora-adapters next to chat
+// completions.
+func newLoRAUpstream(t *testing.T) (*llmSpy, string, *[]llm.LoRA) {
+    t.Helper()
+    spy := newSpyLLM(t, "ok")
+    t.Cleanup(spy.Close)
+    var mu sync.Mutex
+    scales := []llm.LoRA{{ID: 0, Scale: 0}, {ID: 1, Scale: 0}}
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/lora-adapters" { spy.Config.Handler.ServeHTTP(w, r); return }
+        mu.Lock()
+        defer mu.Unlock()
+        if r.Method == http.MethodPost {
+            var set []llm.LoRA
+            _ = json.NewDecoder(r.Body).Decode(&set)
+            for i := range scales { scales[i].Scale = 0 }
+            for _, s := range set { scales[s.ID].Scale = s.Scale }
+            return
+        }
+        out := []llm.Adapter{}
+        for _, s := range scales { out = append(out, llm.Adapter{ID: s.ID, Path: []string{"support.gguf", "legal.gguf"}[s.ID], Scale: s.Scale}) }
+        _ = json.NewEncoder(w).Encode(out)
+    }))
+    t.Cleanup(up.Close)
+    return spy, up.URL, &scales
+}
+
+func TestLoRA_SelectedByModelSuffix(t *testing.T) {
+    spy, url, _ := newLoRAUpstream(t)
+    adapters := map[string]server.LoRAAdapter{"support": {ID: 0}, "legal": {ID: 1, Scale: 0.5}}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(url+"/v1", "qwen", ""), LoRA: adapters})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    msgs := []map[string]string{{"role": "user", "content": "hi"}}
+    for _, model := range []string{"qwen:legal", ":support", "llama3.2:3b"} {
+        resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"model": model, "messages": msgs})
+        resp.Body.Close()
+    }
+    reqs := spy.requests()
+    if len(reqs) != 3 { t.Fatalf("expected 3 upstream calls, got %d", len(reqs)) }
+    if reqs[0].Model != "qwen" || len(reqs[0].LoRA) != 1 || reqs[0].LoRA[0] != (llm.LoRA{ID: 1, Scale: 0.5}) { t.Fatalf("legal adapter not applied: %+v", reqs[0]) }
+    if reqs[1].Model != "qwen" || len(reqs[1].LoRA) != 1 || reqs[1].LoRA[0] != (llm.LoRA{ID: 0, Scale: 1}) { t.Fatalf("support adapter not applied: %+v", reqs[1]) }
+    // Tags that are not adapter names pass through untouched.
+    if reqs[2].Model != "llama3.2:3b" || reqs[2].LoRA != nil { t.Fatalf("ollama tag rewritten: %+v", reqs[2]) }
+}
+
+func TestLoRA_AdminSetsGlobalScales(t *testing.T) {
+    _, url, scales := newLoRAUpstream(t)
+    mux := http.NewServeMux()
+    server.RegisterAdminRoutes(mux, server.AdminOptions{LLM: llm.New(url+"/v1", "qwen", ""), LoRA: map[string]server.LoRAAdapter{"support": {ID: 0}}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/admin/lora", map[string]any{"adapters": []map[string]any{{"name": "support", "scale": 0.8}}})
+    var out struct {
+        Adapters []struct {
+            ID    int     `json:"id"`
+            Name  string  `json:"name"`
+            Path  string  `json:"path"`
+            Scale float64 `json:"scale"`
+        } `json:"adapters"`
+    }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || len(out.Adapters) != 2 || out.Adapters[0].Name != "support" || out.Adapters[0].Scale != 0.8 || out.Adapters[1].Path != "legal.gguf" { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+    if (*scales)[0].Scale != 0.8 { t.Fatalf("upstream scales %+v", *scales) }
+
+    resp = postJSON(t, ts.URL+"/admin/lora", map[string]any{"adapters": []map[string]any{{"name": "nope", "scale": 1}}})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "adapters" { t.Fatalf("expected an adapters error, got %d %+v", resp.StatusCode, e.Error) }
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+)
+
+type mcpReply struct {
+    ID     int `json:"id"`
+    Result struct {
+        ProtocolVersion string                                        `json:"protocolVersion"`
+        Tools           []struct{ Name string }                       `json:"tools"`
+        Content         []struct{ Type, Text, Data, MimeType string } `json:"content"`
+        IsError         bool                                          `json:"isError"`
+    } `json:"result"`
+    Error *struct{ Code int } `json:"error"`
+}
+
+func newMCPServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{TTS: fakeTTS{}, Embeddings: embeddings.New(embeddings.Config{}), MCP: true})
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+func mcpCall(t *testing.T, url string, id int, method string, params any) mcpReply {
+    t.Helper()
+    resp := postJSON(t, url+"/mcp", map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("%s: status %d", method, resp.StatusCode) }
+    var out mcpReply
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatal(err) }
+    return out
+}
+
+func TestMCP_StreamableHTTP(t *testing.T) {
+    ts := newMCPServer(t)
+
+    init := mcpCall(t, ts.URL, 1, "initialize", map[string]any{"protocolVersion": "2024-11-05", "capabilities": map[string]any{}})
+    if init.Result.ProtocolVersion != "2024-11-05" { t.Fatalf("expected the client's version, got %+v", init) }
+    resp := postJSON(t, ts.URL+"/mcp", map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted { t.Fatalf("notification: expected 202, got %d", resp.StatusCode) }
+
+    list := mcpCall(t, ts.URL, 2, "tools/list", nil)
+    var names []string
+    for _, tool := range list.Result.Tools { names = append(names, tool.Name) }
+    if strings.Join(names, ",") != "embed,synthesize" { t.Fatalf("unexpected tools %v", names) }
+
+    speech := mcpCall(t, ts.URL, 3, "tools/call", map[string]any{"name": "synthesize", "arguments": map[string]any{"text": "hello"}})
+    if len(speech.Result.Content) != 1 || speech.Result.Content[0].Type != "audio" || speech.Result.Content[0].MimeType != "audio/wav" { t.Fatalf("unexpected synthesize result %+v", speech) }
+    wav, err := base64.StdEncoding.DecodeString(speech.Result.Content[0].Data)
+    if err != nil || !bytes.HasPrefix(wav, []byte("RIFF")) { t.Fatalf("audio is not a base64 WAV: %v", err) }
+
+    emb := mcpCall(t, ts.URL, 4, "tools/call", map[string]any{"name": "embed", "arguments": map[string]any{"input": []string{"a", "b"}}})
+    var vecs struct{ Embeddings [][]float32 `json:"embeddings"` }
+    if len(emb.Result.Content) != 1 || json.Unmarshal([]byte(emb.Result.Content[0].Text), &vecs) != nil || len(vecs.Embeddings) != 2 { t.Fatalf("unexpected embed result %+v", emb) }
+}
+
+func TestMCP_Errors(t *testing.T) {
+    ts := newMCPServer(t)
+
+    if r := mcpCall(t, ts.URL, 1, "resources/list", nil); r.Error == nil || r.Error.Code != -32601 { t.Fatalf("expected method not found, got %+v", r) }
+    if r := mcpCall(t, ts.URL, 2, "tools/call", map[string]any{"name": "transcribe"}); r.Error == nil || r.Error.Code != -32602 { t.Fatalf("expected unknown tool, got %+v", r) }
+    // Tool failures are results for the model, not protocol errors.
+    r := mcpCall(t, ts.URL, 3, "tools/call", map[string]any{"name": "synthesize", "arguments": map[string]any{}})
+    if r.Error != nil || !r.Result.IsError || r.Result.Content[0].Text != "missing text" { t.Fatalf("expected a tool error, got %+v", r) }
+
+    resp, err := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader("{nope"))
+    if err != nil { t.Fatal(err) }
+    var bad mcpReply
+    _ = json.NewDecoder(resp.Body).Decode(&bad)
+    resp.Body.Close()
+    if bad.Error == nil || bad.Error.Code != -32700 { t.Fatalf("expected a parse error, got %+v", bad) }
+}
+
+func TestMCP_SSETransport(t *testing.T) {
+    ts := newMCPServer(t)
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/mcp/sse", nil)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    events := bufio.NewScanner(resp.Body)
+    next := func(want string) string {
+        t.Helper()
+        for event := ""; events.Scan(); {
+            line := events.Text()
+            if strings.HasPrefix(line, "event: ") { event = strings.TrimPrefix(line, "event: ") }
+            if strings.HasPrefix(line, "data: ") && event == want { return strings.TrimPrefix(line, "data: ") }
+        }
+        t.Fatalf("stream ended before a %s event", want)
+        return ""
+    }
+
+    endpoint := next("endpoint")
+    if !strings.HasPrefix(endpoint, "/mcp/messages?session_id=") { t.Fatalf("unexpected endpoint %q", endpoint) }
+    post := postJSON(t, ts.URL+endpoint, map[string]any{"jsonrpc": "2.0", "id": 7, "method": "tools/call", "params": map[string]any{"name": "embed", "arguments": map[string]any{"input": "hi"}}})
+    post.Body.Close()
+    if post.StatusCode != http.StatusAccepted { t.Fatalf("expected 202, got %d", post.StatusCode) }
+    var reply mcpReply
+    if err := json.Unmarshal([]byte(next("message")), &reply); err != nil || reply.ID != 7 || len(reply.Result.Content) != 1 { t.Fatalf("unexpected reply %+v (%v)", reply, err) }
+
+    post = postJSON(t, ts.URL+"/mcp/messages?session_id=gone", map[string]any{"jsonrpc": "2.0", "id": 1, "method": "ping"})
+    post.Body.Close()
+    if post.StatusCode != http.StatusNotFound { t.Fatalf("unknown session: expected 404, got %d", post.StatusCode) }
+}
+
+func TestMCP_Stdio(t *testing.T) {
+    in := strings.Join([]string{
+        `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2099-01-01"}}`,
+        `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+        `{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+    }, "\n")
+    var out bytes.Buffer
+    if err := server.ServeMCP(context.Background(), server.Dependencies{TTS: fakeTTS{}}, strings.NewReader(in), &out); err != nil { t.Fatal(err) }
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if len(lines) != 2 { t.Fatalf("expected two replies, got %q", out.String()) }
+    byID := map[int]mcpReply{}
+    for _, l := range lines {
+        var r mcpReply
+        if err := json.Unmarshal([]byte(l), &r); err != nil { t.Fatal(err) }
+        byID[r.ID] = r
+    }
+    // An unknown version gets the server's preferred one.
+    if byID[1].Result.ProtocolVersion != "2025-03-26" || byID[2].Error != nil { t.Fatalf("unexpected replies %q", out.String()) }
+}
@@ -0,0 +1,59 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/binary"
+    "net"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/mdns"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func dnsQuery(name string, qtype uint16) []byte {
+    b := make([]byte, 12)
+    binary.BigEndian.PutUint16(b[4:6], 1)
+    for _, l := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+        b = append(b, byte(len(l)))
+        b = append(b, l...)
+    }
+    b = append(b, 0)
+    b = binary.BigEndian.AppendUint16(b, qtype)
+    return binary.BigEndian.AppendUint16(b, 1)
+}
+
+func TestMDNS_AnswersServiceQueries(t *testing.T) {
+    d := server.Dependencies{LLM: llm.New("http://127.0.0.1:1/v1", "m", "")}
+    txt := server.AdvertisedTXT(d)
+    if !contains(txt, "llm=1") || !contains(txt, "stt=0") { t.Fatalf("TXT %v", txt) }
+
+    r, err := mdns.New(mdns.Service{Instance: "gollmcore on box.lan", Type: "_gollmcore._tcp", Host: "box", Port: 8080, IPs: []net.IP{net.IPv4(192, 168, 1, 7)}, TXT: txt})
+    if err != nil { t.Fatalf("new: %v", err) }
+
+    resp, unicast := r.Respond(dnsQuery("_gollmcore._tcp.local.", 12))
+    if resp == nil || unicast { t.Fatalf("no multicast answer for PTR query") }
+    if binary.BigEndian.Uint16(resp[2:4])&0x8000 == 0 { t.Fatalf("reply is not a response") }
+    for _, want := range [][]byte{[]byte("\x14gollmcore on box.lan"), []byte("\x05llm=1"), {0x1f, 0x90}, {192, 168, 1, 7}} {
+        if !bytes.Contains(resp, want) { t.Fatalf("reply lacks %q", want) }
+    }
+
+    // A query naming the instance via a compression pointer is answered too.
+    q := dnsQuery("_gollmcore._tcp.local.", 12)
+    q[5] = 2
+    q = append(q, 20)
+    q = append(q, "gollmcore on box.lan"...)
+    q = append(q, 0xC0, 12, 0, 33, 0x80, 1) // SRV, QU bit
+    if resp, unicast := r.Respond(q); resp == nil || !unicast { t.Fatalf("compressed SRV query with QU bit not answered by unicast") }
+
+    if resp, _ := r.Respond(dnsQuery("_http._tcp.local.", 12)); resp != nil { t.Fatalf("answered an unrelated service") }
+    if resp, _ := r.Respond([]byte{1, 2, 3}); resp != nil { t.Fatalf("answered garbage") }
+}
+
+func contains(list []string, s string) bool {
+    for _, v := range list {
+        if v == s { return true }
+    }
+    return false
+}
@@ -0,0 +1,50 @@
+package api_test
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+)
+
+func TestChain_Order(t *testing.T) {
+    var seen []string
+    mark := func(name string) server.Middleware {
+        return func(h http.Handler) http.Handler {
+            return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = append(seen, name); h.ServeHTTP(w, r) })
+        }
+    }
+    h := server.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = append(seen, "handler") }), mark("outer"), mark("inner"))
+    h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+    if strings.Join(seen, ",") != "outer,inner,handler" { t.Fatalf("unexpected order %v", seen) }
+}
+
+func TestRecover_PanicBecomes500(t *testing.T) {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+    mux.HandleFunc("/late", func(w http.ResponseWriter, r *http.Request) {
+        _, _ = io.WriteString(w, "partial")
+        panic("late")
+    })
+    ts := httptest.NewServer(server.Recover(mux))
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/boom")
+    if err != nil { t.Fatal(err) }
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusInternalServerError || e.Error.Type != "server_error" { t.Fatalf("expected a 500 JSON error, got %d %+v", resp.StatusCode, e.Error) }
+
+    // Once the response has started it is only cut short.
+    resp, err = http.Get(ts.URL + "/late")
+    if err != nil { t.Fatal(err) }
+    body, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || string(body) != "partial" { t.Fatalf("unexpected %d %q", resp.StatusCode, body) }
+
+    // The server keeps serving.
+    resp, err = http.Get(ts.URL + "/boom")
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+}
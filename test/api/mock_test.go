@@ -0,0 +1,52 @@
+package api_test
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+    "gollmcore/internal/services/tts"
+)
+
+func TestMockBackends_DeterministicWithoutModels(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             stt.NewMock(),
+        STTDefaultModel: "base",
+        TTS:             tts.NewMock(),
+        LLM:             llm.NewMock("mock"),
+        Embeddings:      embeddings.New(embeddings.Config{}),
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "hello"}}})
+    var chat llm.ChatResponse
+    if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if chat.Text() != "Mock reply to: hello" || chat.Model != "mock" { t.Fatalf("unexpected chat reply %+v", chat) }
+
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"stream": true, "messages": []map[string]string{{"role": "user", "content": "hello"}}})
+    body, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if strings.Count(string(body), "data: {") != 4 || !strings.Contains(string(body), `"finish_reason":"stop"`) { t.Fatalf("expected one chunk per word, got %s", body) }
+
+    // 5 characters at 60 ms each: 0.3 s of 16 kHz mono audio.
+    resp = postJSON(t, ts.URL+"/v1/tts", map[string]any{"text": "hello"})
+    wav, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.Header.Get("Content-Type") != "audio/wav" || len(wav) != 44+2*4800 { t.Fatalf("unexpected audio: %s, %d bytes", resp.Header.Get("Content-Type"), len(wav)) }
+
+    resp = postUpload(t, ts.URL+"/v1/audio/transcriptions", "hello.wav", wav)
+    var tr struct{ Text string `json:"text"` }
+    if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if tr.Text != "Mock transcription of 0.3 seconds of audio." { t.Fatalf("unexpected transcript %q", tr.Text) }
+}
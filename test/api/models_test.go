@@ -0,0 +1,70 @@
+package api_test
+
+import (
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "gollmcore/internal/models"
+)
+
+func TestModelsLock_PinsAndVerifies(t *testing.T) {
+    var mu sync.Mutex
+    content, commit := "weights-v1", "abc123"
+    var paths []string
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        defer mu.Unlock()
+        paths = append(paths, r.URL.Path)
+        w.Header().Set("X-Repo-Commit", commit)
+        _, _ = w.Write([]byte(content))
+    }))
+    defer up.Close()
+
+    dataDir := t.TempDir()
+    lock, err := models.Open(dataDir)
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer lock.Close()
+    dst := filepath.Join(dataDir, "models", "m.bin")
+    _ = os.MkdirAll(filepath.Dir(dst), 0o755)
+    if err := models.Fetch(up.URL+"/org/m/resolve/main/m.bin", dst, 5*time.Second); err != nil { t.Fatalf("fetch: %v", err) }
+
+    reloaded, err := models.Load(dataDir)
+    if err != nil { t.Fatalf("load: %v", err) }
+    arts := reloaded.Artifacts()
+    if len(arts) != 1 { t.Fatalf("want 1 artifact, got %+v", arts) }
+    a := arts[0]
+    if a.Path != "$DATA/models/m.bin" || a.Version != "abc123" || !strings.Contains(a.URL, "/resolve/abc123/") || a.Size != int64(len("weights-v1")) {
+        t.Fatalf("unexpected artifact %+v", a)
+    }
+    if err := reloaded.Verify(a); err != nil { t.Fatalf("verify: %v", err) }
+
+    _ = os.WriteFile(dst, []byte("tampered"), 0o644)
+    if err := reloaded.Verify(a); !errors.Is(err, models.ErrChecksum) { t.Fatalf("tampered file verified: %v", err) }
+
+    // Upstream moved on: a fresh download uses the pinned commit URL and
+    // rejects the changed bytes, leaving no partial file behind.
+    mu.Lock()
+    content, commit = "weights-v2", "def456"
+    mu.Unlock()
+    _ = os.Remove(dst)
+    err = models.Fetch(up.URL+"/org/m/resolve/main/m.bin", dst, 5*time.Second)
+    if !errors.Is(err, models.ErrChecksum) { t.Fatalf("changed upstream accepted: %v", err) }
+    if _, err := os.Stat(dst); err == nil { t.Fatalf("mismatching file was kept") }
+    mu.Lock()
+    last := paths[len(paths)-1]
+    mu.Unlock()
+    if last != "/org/m/resolve/abc123/m.bin" { t.Fatalf("pinned URL not used, requested %s", last) }
+
+    // update re-resolves the branch URL and re-pins.
+    n, err := reloaded.Refetch(a, true, 5*time.Second)
+    if err != nil { t.Fatalf("refetch: %v", err) }
+    if n.Version != "def456" || n.SHA256 == a.SHA256 { t.Fatalf("update did not re-pin: %+v", n) }
+    if err := reloaded.Verify(n); err != nil { t.Fatalf("verify after update: %v", err) }
+}
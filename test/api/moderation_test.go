@@ -0,0 +1,63 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+const judgeReply = `{"violence": 0.9, "spam": 0.1}`
+
+func newModerationServer(t *testing.T, mod server.Moderation) (*httptest.Server, *llmSpy) {
+    t.Helper()
+    spy := newSpyLLM(t, judgeReply)
+    t.Cleanup(spy.Close)
+    mod.Categories = []string{"violence", "spam"}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", ""), PolicyPrompt: "Be kind.", Moderation: mod})
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+    return ts, spy
+}
+
+func TestModerations_ScoresCategories(t *testing.T) {
+    ts, spy := newModerationServer(t, server.Moderation{})
+    resp := postJSON(t, ts.URL+"/v1/moderations", map[string]any{"input": []string{"I will hurt you"}})
+    var out struct {
+        Results []struct {
+            Flagged        bool               `json:"flagged"`
+            Categories     map[string]bool    `json:"categories"`
+            CategoryScores map[string]float64 `json:"category_scores"`
+        } `json:"results"`
+    }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || len(out.Results) != 1 { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+    r := out.Results[0]
+    if !r.Flagged || !r.Categories["violence"] || r.Categories["spam"] || r.CategoryScores["spam"] != 0.1 { t.Fatalf("unexpected result %+v", r) }
+    // The judge runs without the policy prompt.
+    if reqs := spy.requests(); len(reqs) != 1 || reqs[0].Messages[0].Content == "Be kind." { t.Fatalf("unexpected judge requests %+v", reqs) }
+
+    // Screening is off by default.
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("chat: %d", resp.StatusCode) }
+}
+
+func TestModeration_ScreensChat(t *testing.T) {
+    msgs := []map[string]string{{"role": "user", "content": "I will hurt you"}}
+    ts, _ := newModerationServer(t, server.Moderation{ScreenInput: true})
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": msgs})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Code != "content_flagged" { t.Fatalf("expected content_flagged, got %d %+v", resp.StatusCode, e.Error) }
+
+    ts, _ = newModerationServer(t, server.Moderation{ScreenOutput: true})
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": msgs})
+    var out llm.ChatResponse
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || out.Text() != "" || out.Choices[0].FinishReason != "content_filter" { t.Fatalf("reply not withheld: %d %+v", resp.StatusCode, out) }
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func newNLPServer(t *testing.T, reply string) (*httptest.Server, *llmSpy) {
+    t.Helper()
+    spy := newSpyLLM(t, reply)
+    t.Cleanup(spy.Close)
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", "")})
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+    return ts, spy
+}
+
+func postJSON(t *testing.T, url string, body any) *http.Response {
+    t.Helper()
+    b, _ := json.Marshal(body)
+    resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+    if err != nil { t.Fatalf("post: %v", err) }
+    return resp
+}
+
+func TestClassify_ConstrainsToLabels(t *testing.T) {
+    ts, spy := newNLPServer(t, `{"label":"billing"}`)
+    resp := postJSON(t, ts.URL+"/v1/classify", map[string]any{"input": []string{"I was charged twice", "Refund please"}, "labels": []string{"billing", "bug"}})
+    var out struct {
+        Results []struct {
+            Index int    `json:"index"`
+            Label string `json:"label"`
+        } `json:"results"`
+    }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || len(out.Results) != 2 || out.Results[1].Index != 1 || out.Results[1].Label != "billing" { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+    reqs := spy.requests()
+    if len(reqs) != 2 || !strings.Contains(string(reqs[0].ResponseFormat), `"enum":["billing","bug"]`) || reqs[0].Temperature == nil || *reqs[0].Temperature != 0 { t.Fatalf("unexpected LLM requests %+v", reqs) }
+
+    resp = postJSON(t, ts.URL+"/v1/classify", map[string]any{"input": "x", "labels": []string{"only"}})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "labels" { t.Fatalf("expected a labels error, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+func TestClassify_RejectsUnknownLabelAfterRetry(t *testing.T) {
+    ts, spy := newNLPServer(t, `{"label":"spam"}`)
+    resp := postJSON(t, ts.URL+"/v1/classify", map[string]any{"input": "hello", "labels": []string{"billing", "bug"}})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadGateway || e.Error.Code != "invalid_model_output" { t.Fatalf("expected invalid_model_output, got %d %+v", resp.StatusCode, e.Error) }
+    if n := len(spy.requests()); n != 2 { t.Fatalf("expected one retry, got %d requests", n) }
+}
+
+func TestExtract_ChecksSchema(t *testing.T) {
+    ts, _ := newNLPServer(t, "```json\n{\"name\":\"Ada\",\"amount\":12.5,\"tags\":null}\n```")
+    schema := map[string]any{"type": "object", "required": []string{"name"}, "properties": map[string]any{
+        "name": map[string]any{"type": "string"}, "amount": map[string]any{"type": "number"}, "tags": map[string]any{"type": "array"},
+    }}
+    resp := postJSON(t, ts.URL+"/v1/extract", map[string]any{"input": "Ada paid 12.50", "schema": schema})
+    var out struct{ Data map[string]any `json:"data"` }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || out.Data["name"] != "Ada" || out.Data["amount"] != 12.5 { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+
+    schema["required"] = []string{"name", "email"}
+    resp = postJSON(t, ts.URL+"/v1/extract", map[string]any{"input": "Ada paid 12.50", "schema": schema})
+    if resp.StatusCode != http.StatusBadGateway { t.Fatalf("expected 502 for missing required field, got %d", resp.StatusCode) }
+    resp.Body.Close()
+    resp = postJSON(t, ts.URL+"/v1/extract", map[string]any{"input": "x", "schema": map[string]any{"type": "string"}})
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "schema" { t.Fatalf("expected a schema error, got %+v", e.Error) }
+}
+
+func TestSummarize_StylesAndChunking(t *testing.T) {
+    ts, spy := newNLPServer(t, "Short summary.")
+    resp := postJSON(t, ts.URL+"/v1/summarize", map[string]any{"input": "Some text.", "style": "bullets", "max_words": 20})
+    var out struct{ Summary string `json:"summary"` }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if out.Summary != "Short summary." { t.Fatalf("unexpected summary %q", out.Summary) }
+    if sys := spy.requests()[0].Messages[0].Content; !strings.Contains(sys, "bullet") || !strings.Contains(sys, "at most 20 words") { t.Fatalf("unexpected prompt %q", sys) }
+
+    resp = postJSON(t, ts.URL+"/v1/summarize", map[string]any{"input": strings.Repeat("word ", 5000)})
+    resp.Body.Close()
+    // 25000 characters: two or three parts, then the combining call.
+    if n := len(spy.requests()) - 1; resp.StatusCode != http.StatusOK || n < 3 { t.Fatalf("expected map-reduce, got %d %d requests", resp.StatusCode, n) }
+
+    resp = postJSON(t, ts.URL+"/v1/summarize", map[string]any{"input": "x", "style": "haiku"})
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "style" { t.Fatalf("expected a style error, got %+v", e.Error) }
+}
@@ -0,0 +1,36 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestOpenAPI_DescribesServedRoutes(t *testing.T) {
+    upstream := newFakeLLM(t, "hi")
+    defer upstream.Close()
+    ts := newChatServer(t, upstream.URL)
+    defer ts.Close()
+
+    resp, err := http.Get(ts.URL + "/openapi.json")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+    var spec struct {
+        OpenAPI    string                               `json:"openapi"`
+        Paths      map[string]map[string]map[string]any `json:"paths"`
+        Components struct {
+            Schemas map[string]any `json:"schemas"`
+        } `json:"components"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil { t.Fatalf("decode: %v", err) }
+    if spec.OpenAPI != "3.1.0" { t.Fatalf("unexpected openapi version %q", spec.OpenAPI) }
+    if _, ok := spec.Paths["/v1/chat/completions"]["post"]; !ok { t.Fatalf("chat completions missing: %v", spec.Paths) }
+    if _, ok := spec.Paths["/v1/tts"]; ok { t.Fatalf("disabled TTS route listed") }
+    if _, ok := spec.Components.Schemas["llm.Message"]; !ok { t.Fatalf("message schema missing: %v", spec.Components.Schemas) }
+
+    resp, err = http.Get(ts.URL + "/docs")
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200 for /docs, got %d", resp.StatusCode) }
+}
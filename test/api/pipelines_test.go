@@ -0,0 +1,64 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+)
+
+func TestPipelines_RunJob(t *testing.T) {
+    up := newFakeLLM(t, "short summary")
+    defer up.Close()
+    d := server.Dependencies{
+        LLM:        llm.New(up.URL+"/v1", "test-model", ""),
+        Embeddings: embeddings.New(embeddings.Config{}),
+        Pipelines: map[string]server.Pipeline{
+            "digest": {Steps: []server.PipelineStep{{Type: "summarize"}, {Type: "embed"}}},
+        },
+    }
+    if err := server.ValidatePipelines(d); err != nil { t.Fatalf("validate failed: %v", err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, d)
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/pipelines/digest/run", "application/json", strings.NewReader(`{"input":"a very long meeting transcript"}`))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted { t.Fatalf("expected 202, got %d", resp.StatusCode) }
+    var job server.Job
+    if err := json.NewDecoder(resp.Body).Decode(&job); err != nil { t.Fatalf("decode failed: %v", err) }
+
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        r, err := http.Get(ts.URL + "/v1/jobs/" + job.ID)
+        if err != nil { t.Fatalf("poll failed: %v", err) }
+        job = server.Job{}
+        _ = json.NewDecoder(r.Body).Decode(&job)
+        r.Body.Close()
+        if job.Status == "succeeded" { break }
+        if job.Status == "failed" { t.Fatalf("job failed: %s", job.Error) }
+        if time.Now().After(deadline) { t.Fatalf("job did not finish, status %s", job.Status) }
+        time.Sleep(20 * time.Millisecond)
+    }
+    if len(job.Steps) != 2 || job.Steps[0].Status != "succeeded" { t.Fatalf("unexpected steps: %+v", job.Steps) }
+    if out, _ := job.Steps[0].Output.(map[string]any); out["text"] != "short summary" { t.Fatalf("unexpected summary output: %v", job.Steps[0].Output) }
+    if res, _ := job.Result.(map[string]any); res["embeddings"] == nil { t.Fatalf("expected embeddings result, got %v", job.Result) }
+}
+
+func TestPipelines_ValidateRejectsBadFlow(t *testing.T) {
+    d := server.Dependencies{
+        Embeddings: embeddings.New(embeddings.Config{}),
+        Pipelines:  map[string]server.Pipeline{"bad": {Steps: []server.PipelineStep{{Type: "embed"}, {Type: "embed"}}}},
+    }
+    if err := server.ValidatePipelines(d); err == nil { t.Fatalf("expected error for embed -> embed") }
+    d.Pipelines = map[string]server.Pipeline{"diarize": {Steps: []server.PipelineStep{{Type: "diarize"}}}}
+    if err := server.ValidatePipelines(d); err == nil { t.Fatalf("expected error for unknown step type") }
+}
@@ -0,0 +1,73 @@
+package api_test
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+)
+
+// gatedTTS reports each synthesis as it starts and holds it until released.
+type gatedTTS struct {
+    started chan string
+    release chan struct{}
+}
+
+func (g *gatedTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    g.started <- text
+    select {
+    case <-g.release:
+        return []byte("RIFF"), nil
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+func TestPriority_InteractiveOvertakesBackgroundQueue(t *testing.T) {
+    tts := &gatedTTS{started: make(chan string, 8), release: make(chan struct{})}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithLimits(server.Dependencies{TTS: tts, Limits: server.Limits{TTS: server.Limit{MaxConcurrent: 1}}}))
+    ts := httptest.NewServer(server.Prioritize(mux))
+    defer ts.Close()
+
+    codes := make(chan int, 8)
+    post := func(text, priority string) {
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/tts", strings.NewReader(`{"text":"`+text+`"}`))
+        req.Header.Set("Content-Type", "application/json")
+        if priority != "" { req.Header.Set("X-Priority", priority) }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { codes <- 0; return }
+        resp.Body.Close()
+        codes <- resp.StatusCode
+    }
+    go post("first", "background")
+    if got := <-tts.started; got != "first" { t.Fatalf("unexpected first call %q", got) }
+    // Queue background work, then one live request behind it.
+    for _, text := range []string{"b1", "b2", "b3"} {
+        go post(text, "background")
+        time.Sleep(30 * time.Millisecond)
+    }
+    go post("live", "")
+    time.Sleep(30 * time.Millisecond)
+
+    var order []string
+    for i := 0; i < 4; i++ {
+        tts.release <- struct{}{}
+        order = append(order, <-tts.started)
+    }
+    tts.release <- struct{}{}
+    if strings.Join(order, ",") != "live,b1,b2,b3" { t.Fatalf("unexpected start order %v", order) }
+    for i := 0; i < 5; i++ {
+        if c := <-codes; c != http.StatusOK { t.Fatalf("unexpected status %d", c) }
+    }
+
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/tts", strings.NewReader(`{"text":"x"}`))
+    req.Header.Set("X-Priority", "urgent")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("post: %v", err) }
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || !strings.Contains(e.Error.Message, "priority") { t.Fatalf("expected a priority error, got %d %+v", resp.StatusCode, e.Error) }
+}
@@ -0,0 +1,48 @@
+package api_test
+
+import (
+    "bufio"
+    "context"
+    "os"
+    "os/exec"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/procs"
+)
+
+// alive reports whether pid still runs; zombies waiting to be reaped count
+// as dead.
+func alive(pid int) bool {
+    b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+    if err != nil { return false }
+    fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+    return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestProcs_CancelKillsGrandchildren(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    cmd := exec.CommandContext(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+    stdout, err := cmd.StdoutPipe()
+    if err != nil { t.Fatal(err) }
+    done, err := procs.Start("test", cmd)
+    if err != nil { t.Fatalf("start failed: %v", err) }
+    defer done()
+
+    line, err := bufio.NewReader(stdout).ReadString('\n')
+    if err != nil { t.Fatalf("reading grandchild pid: %v", err) }
+    pid, _ := strconv.Atoi(strings.TrimSpace(line))
+    if !alive(pid) { t.Fatalf("grandchild %d is not running", pid) }
+    if got := procs.List(); len(got) != 1 || got[0].Name != "test" { t.Fatalf("unexpected process list: %+v", got) }
+
+    cancel()
+    _ = cmd.Wait()
+    deadline := time.Now().Add(2 * time.Second)
+    for alive(pid) {
+        if time.Now().After(deadline) { t.Fatalf("grandchild %d survived cancellation", pid) }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
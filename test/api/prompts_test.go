@@ -0,0 +1,53 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestPromptTemplates_AdminCRUDAndChat(t *testing.T) {
+    spy := newSpyLLM(t, "ok")
+    defer spy.Close()
+    path := filepath.Join(t.TempDir(), "prompts.json")
+    store, err := prompts.New(path, map[string]prompts.Template{
+        "greet": {Template: "Greet {{name}} warmly."},
+    })
+    if err != nil { t.Fatalf("store init failed: %v", err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", ""), Prompts: store})
+    server.RegisterAdminRoutes(mux, server.AdminOptions{Prompts: store})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    req, _ := http.NewRequest(http.MethodPut, ts.URL+"/admin/prompts/summarize_email", strings.NewReader(`{"role":"user","template":"Summarize this email from {{ sender }}:\n{{body}}"}`))
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("put failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("put expected 200, got %d", resp.StatusCode) }
+
+    chat := func(body string) int {
+        resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+        if err != nil { t.Fatalf("chat failed: %v", err) }
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+    if code := chat(`{"prompt_template":"summarize_email","prompt_variables":{"sender":"Ann","body":"Lunch at noon?"}}`); code != http.StatusOK { t.Fatalf("chat expected 200, got %d", code) }
+    if code := chat(`{"prompt_template":"greet","messages":[{"role":"user","content":"hi"}],"prompt_variables":{}}`); code != http.StatusBadRequest { t.Fatalf("missing variable expected 400, got %d", code) }
+    if code := chat(`{"prompt_template":"nope","messages":[{"role":"user","content":"hi"}]}`); code != http.StatusNotFound { t.Fatalf("unknown template expected 404, got %d", code) }
+
+    reqs := spy.requests()
+    if len(reqs) != 1 { t.Fatalf("expected 1 upstream call, got %d", len(reqs)) }
+    if m := reqs[0].Messages; len(m) != 1 || m[0].Role != "user" || m[0].Content != "Summarize this email from Ann:\nLunch at noon?" { t.Fatalf("unexpected rendered messages %+v", m) }
+
+    // Runtime templates persist across restarts.
+    again, err := prompts.New(path, nil)
+    if err != nil { t.Fatalf("reload failed: %v", err) }
+    if _, err := again.Get("summarize_email"); err != nil { t.Fatalf("template not persisted: %v", err) }
+}
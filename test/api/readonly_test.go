@@ -0,0 +1,76 @@
+package api_test
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/prompts"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/sessions"
+)
+
+func TestReadOnly_Sessions(t *testing.T) {
+    spy := newSpyLLM(t, "noted")
+    defer spy.Close()
+    store := sessions.New(filepath.Join(t.TempDir(), "sessions.db"), sessions.Options{})
+    defer store.Close()
+    sess, err := store.Create(nil, []llm.Message{{Role: "user", Content: "earlier"}, {Role: "assistant", Content: "reply"}})
+    if err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", ""), Sessions: store, ReadOnly: server.ReadOnly{Sessions: true}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/sessions", map[string]any{})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusForbidden || e.Error.Code != "read_only" { t.Fatalf("expected 403 read_only, got %d %+v", resp.StatusCode, e.Error) }
+    resp, err = http.Get(ts.URL + "/v1/sessions/" + sess.ID)
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("reads must still work, got %d", resp.StatusCode) }
+
+    // The history is used, but the turn is not recorded.
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"session_id": sess.ID, "messages": []map[string]string{{"role": "user", "content": "now"}}})
+    resp.Body.Close()
+    if seen := spy.requests(); resp.StatusCode != http.StatusOK || len(seen) != 1 || len(seen[0].Messages) != 3 { t.Fatalf("expected the stored history upstream, got %d %+v", resp.StatusCode, seen) }
+    if got, _ := store.Get(sess.ID); len(got.Messages) != 2 { t.Fatalf("read-only session was changed: %+v", got.Messages) }
+}
+
+func TestReadOnly_AdminAndCapabilities(t *testing.T) {
+    store, err := prompts.New(filepath.Join(t.TempDir(), "prompts.json"), nil)
+    if err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{ReadOnly: server.ReadOnly{Downloads: true, Admin: true}})
+    server.RegisterAdminRoutes(mux, server.AdminOptions{Prompts: store, ReadOnly: true})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    req, _ := http.NewRequest(http.MethodPut, ts.URL+"/admin/prompts/greet", strings.NewReader(`{"template":"hi {{.name}}"}`))
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusForbidden || e.Error.Code != "read_only" { t.Fatalf("expected 403 read_only, got %d %+v", resp.StatusCode, e.Error) }
+    resp, _ = http.Get(ts.URL + "/admin/prompts")
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("listing prompts must still work, got %d", resp.StatusCode) }
+
+    resp, _ = http.Get(ts.URL + "/v1/capabilities")
+    var caps struct{ ReadOnly map[string]bool `json:"read_only"` }
+    _ = json.NewDecoder(resp.Body).Decode(&caps)
+    resp.Body.Close()
+    if !caps.ReadOnly["downloads"] || !caps.ReadOnly["admin"] || caps.ReadOnly["sessions"] { t.Fatalf("unexpected read_only capabilities %+v", caps.ReadOnly) }
+}
+
+func TestReadOnly_NoDownloads(t *testing.T) {
+    models.DisableDownloads(true)
+    defer models.DisableDownloads(false)
+    // Nothing listens there; Fetch must fail before trying.
+    err := models.Fetch("http://127.0.0.1:1/m.bin", filepath.Join(t.TempDir(), "m.bin"), 5*time.Second)
+    if !errors.Is(err, models.ErrDownloadsDisabled) { t.Fatalf("expected ErrDownloadsDisabled, got %v", err) }
+}
@@ -0,0 +1,53 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestRealtime_TextResponse(t *testing.T) {
+    up := newFakeLLM(t, "Hi there. How can I help?")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}, server.WSOptions{Enable: true, PathPrefix: "/ws"})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/realtime", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+    var ev map[string]any
+    if err := conn.ReadJSON(&ev); err != nil || ev["type"] != "session.created" { t.Fatalf("expected session.created, got %v (%v)", ev, err) }
+
+    _ = conn.WriteJSON(map[string]any{"type": "conversation.item.create", "item": map[string]any{
+        "type": "message", "role": "user", "content": []map[string]any{{"type": "input_text", "text": "hello"}},
+    }})
+    _ = conn.WriteJSON(map[string]any{"type": "response.create"})
+
+    var deltas strings.Builder
+    for {
+        ev = nil
+        if err := conn.ReadJSON(&ev); err != nil { t.Fatalf("read failed: %v", err) }
+        switch ev["type"] {
+        case "response.text.delta":
+            deltas.WriteString(ev["delta"].(string))
+        case "response.done":
+            resp := ev["response"].(map[string]any)
+            if resp["status"] != "completed" { t.Fatalf("response not completed: %v", resp) }
+            if deltas.String() != "Hi there. How can I help?" { t.Fatalf("unexpected deltas %q", deltas.String()) }
+            return
+        case "error":
+            t.Fatalf("server error: %v", ev)
+        }
+    }
+}
@@ -0,0 +1,62 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestRemoteLLM_InjectsKeyAndFallsBack(t *testing.T) {
+    up := newFakeLLM(t, "from remote")
+    defer up.Close()
+    var mu sync.Mutex
+    var auth []string
+    remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        auth = append(auth, r.Header.Get("Authorization"))
+        mu.Unlock()
+        up.Config.Handler.ServeHTTP(w, r)
+    }))
+    local := newSpyLLM(t, "from local")
+    defer local.Close()
+
+    rl := llm.NewFailover(llm.New(remote.URL+"/v1", "gpt-4o", "sk-remote"), llm.New(local.URL+"/v1", "qwen", ""))
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: rl})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    chat := func(stream bool) string {
+        body := map[string]any{"model": "gpt-4o", "stream": stream, "messages": []map[string]string{{"role": "user", "content": "hi"}}}
+        b, _ := json.Marshal(body)
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", bytes.NewReader(b))
+        req.Header.Set("Content-Type", "application/json")
+        req.Header.Set("Authorization", "Bearer client-key")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatal(err) }
+        defer resp.Body.Close()
+        b, _ = io.ReadAll(resp.Body)
+        if resp.StatusCode != http.StatusOK { t.Fatalf("status %d: %s", resp.StatusCode, b) }
+        return string(b)
+    }
+
+    if b := chat(false); !strings.Contains(b, "from remote") { t.Fatalf("expected the remote's reply, got %s", b) }
+    mu.Lock()
+    if len(auth) != 1 || auth[0] != "Bearer sk-remote" { t.Fatalf("remote got Authorization %q, want the configured key", auth) }
+    mu.Unlock()
+    if n := len(local.requests()); n != 0 { t.Fatalf("fallback called %d times while the remote was up", n) }
+
+    remote.Close()
+    if b := chat(false); !strings.Contains(b, "from local") { t.Fatalf("expected the fallback's reply, got %s", b) }
+    if b := chat(true); !strings.Contains(b, "data: {") || !strings.Contains(b, "[DONE]") { t.Fatalf("expected a streamed fallback reply, got %s", b) }
+    reqs := local.requests()
+    if len(reqs) != 2 || reqs[0].Model != "qwen" || !reqs[1].Stream { t.Fatalf("fallback got %+v, want two chats for its own model", reqs) }
+}
@@ -0,0 +1,102 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "runtime"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "gollmcore/internal/resources"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+// newFakeOllama wraps the fake chat upstream with Ollama's /api/tags and
+// /api/ps, listing "big-model" at size bytes; loaded controls /api/ps.
+func newFakeOllama(t *testing.T, size uint64, loaded *atomic.Bool) *httptest.Server {
+    t.Helper()
+    up := newFakeLLM(t, "fits")
+    t.Cleanup(up.Close)
+    ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/api/tags":
+            writeTestJSON(w, map[string]any{"models": []map[string]any{{"name": "big-model:latest", "size": size}}})
+        case "/api/ps":
+            models := []map[string]any{}
+            if loaded.Load() { models = append(models, map[string]any{"name": "big-model:latest", "model": "big-model:latest"}) }
+            writeTestJSON(w, map[string]any{"models": models})
+        default:
+            up.Config.Handler.ServeHTTP(w, r)
+        }
+    }))
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+func writeTestJSON(w http.ResponseWriter, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(v)
+}
+
+func TestResources_RefusesModelLargerThanMemory(t *testing.T) {
+    if runtime.GOOS != "linux" && runtime.GOOS != "windows" && runtime.GOOS != "darwin" { t.Skip("memory readings unsupported") }
+    var loaded atomic.Bool
+    up := newFakeOllama(t, 1<<50, &loaded)
+    deps := server.Dependencies{
+        LLM:       llm.New(up.URL+"/v1", "big-model", ""),
+        Resources: server.ResourceGuard{Monitor: resources.New(time.Minute), Adaptive: true},
+    }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithResources(deps))
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    post := func() *http.Response {
+        resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+        if err != nil { t.Fatal(err) }
+        return resp
+    }
+    resp := post()
+    if resp.StatusCode != http.StatusServiceUnavailable { t.Fatalf("expected 503, got %d", resp.StatusCode) }
+    e := decodeError(t, resp)
+    if e.Error.Code != "insufficient_memory" || !strings.Contains(e.Error.Message, "big-model needs about") { t.Fatalf("unexpected error: %+v", e.Error) }
+
+    // A model the upstream already has in memory is never refused.
+    loaded.Store(true)
+    resp = post()
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200 for a loaded model, got %d", resp.StatusCode) }
+}
+
+func TestResources_ReportOnlyWithoutAdaptive(t *testing.T) {
+    var loaded atomic.Bool
+    up := newFakeOllama(t, 1<<50, &loaded)
+    mon := resources.New(time.Minute)
+    deps := server.Dependencies{LLM: llm.New(up.URL+"/v1", "big-model", ""), Resources: server.ResourceGuard{Monitor: mon}}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithResources(deps))
+    server.RegisterAdminRoutes(mux, server.AdminOptions{Resources: mon})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200 without adaptive mode, got %d", resp.StatusCode) }
+
+    resp, err = http.Get(ts.URL + "/admin/status")
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    var st struct {
+        Resources resources.Reading `json:"resources"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&st); err != nil { t.Fatalf("decode: %v", err) }
+    if st.Resources.NumCPU != runtime.NumCPU() { t.Fatalf("unexpected status: %+v", st.Resources) }
+    if runtime.GOOS == "linux" && (st.Resources.MemoryTotal == 0 || st.Resources.MemoryAvailable > st.Resources.MemoryTotal) {
+        t.Fatalf("implausible memory reading: %+v", st.Resources)
+    }
+}
@@ -0,0 +1,176 @@
+//go:build unix
+
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+
+    "gollmcore/internal/server"
+)
+
+type uploadStatus struct {
+    ID     string `json:"id"`
+    Size   int64  `json:"size"`
+    Offset int64  `json:"offset"`
+    JobID  string `json:"job_id"`
+}
+
+func patchUpload(t *testing.T, url string, offset int, body []byte) *http.Response {
+    t.Helper()
+    req, _ := http.NewRequest(http.MethodPatch, url, bytes.NewReader(body))
+    req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+    req.Header.Set("Content-Type", "application/offset+octet-stream")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    return resp
+}
+
+func TestResumableUpload_SurvivesRestartAndTranscribes(t *testing.T) {
+    d := server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        Resumable:       server.ResumableUploads{Dir: t.TempDir()},
+    }
+    start := func() *httptest.Server {
+        mux := http.NewServeMux()
+        server.RegisterRoutes(mux, d)
+        return httptest.NewServer(mux)
+    }
+    ts := start()
+    audio := laptopMic()
+    half := len(audio) / 2
+
+    resp, err := http.Post(ts.URL+"/v1/uploads", "application/json", strings.NewReader(`{"filename": "meeting.wav", "size": `+strconv.Itoa(len(audio))+`, "prompt": "Quarterly review."}`))
+    if err != nil { t.Fatal(err) }
+    var up uploadStatus
+    _ = json.NewDecoder(resp.Body).Decode(&up)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated || up.ID == "" || resp.Header.Get("Location") != "/v1/uploads/"+up.ID { t.Fatalf("create failed: %d %+v", resp.StatusCode, up) }
+    url := ts.URL + "/v1/uploads/" + up.ID
+
+    resp = patchUpload(t, url, 0, audio[:half])
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || resp.Header.Get("Upload-Offset") != strconv.Itoa(half) { t.Fatalf("first part: %d offset %s", resp.StatusCode, resp.Header.Get("Upload-Offset")) }
+    resp = patchUpload(t, url, 0, audio[:half])
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusConflict || resp.Header.Get("Upload-Offset") != strconv.Itoa(half) { t.Fatalf("stale offset: %d offset %s", resp.StatusCode, resp.Header.Get("Upload-Offset")) }
+
+    // A restarted server picks the upload up from disk.
+    ts.Close()
+    ts = start()
+    defer ts.Close()
+    url = ts.URL + "/v1/uploads/" + up.ID
+    req, _ := http.NewRequest(http.MethodHead, url, nil)
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if resp.Header.Get("Upload-Offset") != strconv.Itoa(half) || resp.Header.Get("Upload-Length") != strconv.Itoa(len(audio)) { t.Fatalf("resume offset lost: %v", resp.Header) }
+
+    resp = patchUpload(t, url, half, append(append([]byte(nil), audio[half:]...), 'x'))
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusRequestEntityTooLarge { t.Fatalf("expected 413 for bytes past the size, got %d", resp.StatusCode) }
+    resp = patchUpload(t, url, half, audio[half:])
+    up = uploadStatus{}
+    _ = json.NewDecoder(resp.Body).Decode(&up)
+    resp.Body.Close()
+    if up.JobID == "" || resp.Header.Get("Location") != "/v1/jobs/"+up.JobID { t.Fatalf("completion did not start a job: %+v", up) }
+    resp = patchUpload(t, url, len(audio), []byte("more"))
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusConflict { t.Fatalf("expected 409 after completion, got %d", resp.StatusCode) }
+
+    var job server.Job
+    deadline := time.Now().Add(5 * time.Second)
+    for job.Status != "succeeded" {
+        r, err := http.Get(ts.URL + "/v1/jobs/" + up.JobID)
+        if err != nil { t.Fatal(err) }
+        _ = json.NewDecoder(r.Body).Decode(&job)
+        r.Body.Close()
+        if job.Status == "failed" { t.Fatalf("job failed: %s", job.Error) }
+        if time.Now().After(deadline) { t.Fatalf("job did not finish, status %s", job.Status) }
+        time.Sleep(20 * time.Millisecond)
+    }
+    if res, _ := job.Result.(map[string]any); !strings.HasPrefix(res["text"].(string), "prompt=Quarterly review.\ninput="+up.ID+".audio.wav") { t.Fatalf("unexpected result %v", job.Result) }
+
+    resp, _ = http.Get(ts.URL + "/v1/uploads/..%2f..%2fetc")
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNotFound { t.Fatalf("expected 404 for a bad id, got %d", resp.StatusCode) }
+}
+
+func TestResumableUpload_RejectsUnknownExtension(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: newFakeSTT(t), STTDefaultModel: "tiny", Resumable: server.ResumableUploads{Dir: t.TempDir()}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/uploads", "application/json", strings.NewReader(`{"filename": "setup.exe", "size": 10}`))
+    if err != nil { t.Fatal(err) }
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "filename" { t.Fatalf("expected 400 on filename, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+// waitJob polls a job until it finishes.
+func waitJob(t *testing.T, url string) server.Job {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        var job server.Job
+        r, err := http.Get(url)
+        if err != nil { t.Fatal(err) }
+        _ = json.NewDecoder(r.Body).Decode(&job)
+        r.Body.Close()
+        if job.Status == "succeeded" || job.Status == "failed" { return job }
+        if time.Now().After(deadline) { t.Fatalf("job did not finish, status %q", job.Status) }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestJobs_ResumedAfterRestart(t *testing.T) {
+    // The state a server killed mid-transcription leaves behind: a complete
+    // upload with its audio, and jobs still marked running.
+    dir, data := t.TempDir(), t.TempDir()
+    const uploadID = "upl_0123456789abcdef01234567"
+    audio := laptopMic()
+    if err := os.WriteFile(filepath.Join(dir, uploadID+".audio.wav"), audio, 0o644); err != nil { t.Fatal(err) }
+    up, _ := json.Marshal(map[string]any{"id": uploadID, "filename": "meeting.wav", "size": len(audio), "job_id": "job_transcribe", "prompt": "Quarterly review."})
+    if err := os.WriteFile(filepath.Join(dir, uploadID+".json"), up, 0o644); err != nil { t.Fatal(err) }
+    dbPath := filepath.Join(data, "jobs.db")
+    db, err := bolt.Open(dbPath, 0o600, nil)
+    if err != nil { t.Fatal(err) }
+    now := time.Now().UTC()
+    err = db.Update(func(tx *bolt.Tx) error {
+        b, err := tx.CreateBucketIfNotExists([]byte("jobs"))
+        if err != nil { return err }
+        for _, j := range []server.Job{
+            {ID: "job_transcribe", Kind: "transcription", Status: "running", UploadID: uploadID, CreatedAt: now, UpdatedAt: now},
+            {ID: "job_pipeline", Kind: "pipeline:notes", Status: "running", Steps: []server.JobStep{{Type: "transcribe", Status: "running"}}, CreatedAt: now, UpdatedAt: now},
+        } {
+            v, _ := json.Marshal(j)
+            if err := b.Put([]byte(j.ID), v); err != nil { return err }
+        }
+        return nil
+    })
+    db.Close()
+    if err != nil { t.Fatal(err) }
+
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: newFakeSTT(t), STTDefaultModel: "tiny", Resumable: server.ResumableUploads{Dir: dir}, JobsDB: dbPath})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    job := waitJob(t, ts.URL+"/v1/jobs/job_transcribe")
+    if job.Status != "succeeded" { t.Fatalf("transcription not resumed: %+v", job) }
+    if res, _ := job.Result.(map[string]any); !strings.HasPrefix(res["text"].(string), "prompt=Quarterly review.\ninput="+uploadID+".audio.wav") { t.Fatalf("unexpected result %v", job.Result) }
+    job = waitJob(t, ts.URL+"/v1/jobs/job_pipeline")
+    if job.Error != "interrupted by a server restart" || job.Steps[0].Status != "failed" { t.Fatalf("expected the pipeline to fail as interrupted: %+v", job) }
+    if _, err := os.Stat(filepath.Join(dir, uploadID+".audio.wav")); !os.IsNotExist(err) { t.Fatalf("audio not removed after the resumed job: %v", err) }
+}
@@ -0,0 +1,92 @@
+//go:build unix
+
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "gollmcore/internal/scratch"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/stt"
+)
+
+// useScratch points the scratch dir at a fresh directory for one test.
+func useScratch(t *testing.T) string {
+    t.Helper()
+    prev := scratch.Dir()
+    dir := t.TempDir()
+    if err := scratch.SetDir(dir); err != nil { t.Fatal(err) }
+    t.Cleanup(func() { _ = scratch.SetDir(prev) })
+    return dir
+}
+
+func TestScratch_SweepRemovesOnlyStaleServerFiles(t *testing.T) {
+    dir := useScratch(t)
+    old := time.Now().Add(-2 * time.Hour)
+    for _, name := range []string{"whisper_out_1.txt", "piper_out_2.wav", "stt-clip.wav", "notes.txt", "stt-fresh.wav"} {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, []byte("x"), 0o644); err != nil { t.Fatal(err) }
+        if name != "stt-fresh.wav" { _ = os.Chtimes(path, old, old) }
+    }
+    n, err := scratch.Sweep(time.Hour)
+    if err != nil || n != 3 { t.Fatalf("expected 3 files removed, got %d (%v)", n, err) }
+    entries, _ := os.ReadDir(dir)
+    var left []string
+    for _, e := range entries { left = append(left, e.Name()) }
+    if len(left) != 2 || left[0] != "notes.txt" || left[1] != "stt-fresh.wav" { t.Fatalf("unexpected files left: %v", left) }
+}
+
+func TestScratch_FailedRequestLeavesNothing(t *testing.T) {
+    dir := useScratch(t)
+    // "RIFF" alone is not recognisable audio, so the upload is rejected
+    // after it has been saved.
+    bin := t.TempDir()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: stt.New(bin, bin), STTDefaultModel: "tiny", Uploads: server.UploadPolicy{Sniff: true}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    for _, path := range []string{"/v1/audio/transcriptions", "/v1/audio/transcriptions/stream"} {
+        resp := postAudio(t, ts.URL+path, []byte("RIFF"), nil)
+        resp.Body.Close()
+        if resp.StatusCode == http.StatusOK { t.Fatalf("%s: expected a failure", path) }
+    }
+    entries, _ := os.ReadDir(dir)
+    if len(entries) != 0 { t.Fatalf("temporary files left behind: %v", entries) }
+}
+
+func TestScratch_UploadsGetUniquePaths(t *testing.T) {
+    dir := useScratch(t)
+    // The scanner records the path of every upload it sees.
+    seen := filepath.Join(t.TempDir(), "seen")
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: newFakeSTT(t), STTDefaultModel: "tiny", Uploads: server.UploadPolicy{ScanCommand: []string{"sh", "-c", `echo "$1" >> "$0"`, seen}}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            resp := postUpload(t, ts.URL+"/v1/audio/transcriptions", "../../clip.WAV", laptopMic())
+            resp.Body.Close()
+        }()
+    }
+    wg.Wait()
+    data, err := os.ReadFile(seen)
+    if err != nil { t.Fatal(err) }
+    paths := map[string]bool{}
+    for _, p := range strings.Fields(string(data)) {
+        if filepath.Dir(p) != dir || !strings.HasPrefix(filepath.Base(p), "stt-") || filepath.Ext(p) != ".wav" { t.Fatalf("unexpected upload path %s", p) }
+        paths[p] = true
+    }
+    if len(paths) != 4 { t.Fatalf("expected 4 distinct paths, got %v", paths) }
+}
@@ -0,0 +1,71 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/websearch"
+)
+
+// newSearxNG fakes a SearxNG instance with three results per query.
+func newSearxNG(t *testing.T) *httptest.Server {
+    t.Helper()
+    ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" { http.NotFound(w, r); return }
+        q := r.URL.Query().Get("q")
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string]any{"results": []map[string]string{
+            {"title": q + " 1", "url": "https://a.example", "content": "first"},
+            {"title": q + " 2", "url": "https://b.example", "content": "second"},
+            {"title": q + " 3", "url": "https://c.example", "content": "third"},
+        }})
+    }))
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+func TestSearch_Endpoint(t *testing.T) {
+    p, err := websearch.New(websearch.Options{Provider: "searxng", URL: newSearxNG(t).URL})
+    if err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{Search: server.WebSearch{Provider: p, MaxResults: 2}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/tools/search", map[string]any{"query": "go release"})
+    var out struct {
+        Provider string             `json:"provider"`
+        Results  []websearch.Result `json:"results"`
+    }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || out.Provider != "searxng" || len(out.Results) != 2 || out.Results[0].Title != "go release 1" || out.Results[1].Snippet != "second" { t.Fatalf("unexpected response %d %+v", resp.StatusCode, out) }
+
+    resp = postJSON(t, ts.URL+"/v1/tools/search", map[string]any{"query": " "})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "query" { t.Fatalf("expected a query error, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+func TestSearch_AgentTool(t *testing.T) {
+    p, err := websearch.New(websearch.Options{Provider: "searxng", URL: newSearxNG(t).URL})
+    if err != nil { t.Fatal(err) }
+    up, reqs := newToolLLM(t, "web_search", `{"query":"news","max_results":1}`, 1)
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:   llm.New(up.URL+"/v1", "test-model", ""),
+        Agent: server.AgentOptions{Tools: map[string]server.Tool{"web_search": server.SearchTool(server.WebSearch{Provider: p})}},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"agent": true, "messages": []map[string]string{{"role": "user", "content": "what happened today?"}}})
+    resp.Body.Close()
+    got := reqs()
+    if len(got) != 2 { t.Fatalf("expected 2 model calls, got %d", len(got)) }
+    result := got[1].Messages[2].Content
+    if !strings.Contains(result, "news 1") || !strings.Contains(result, "https://a.example") || strings.Contains(result, "news 2") { t.Fatalf("unexpected tool result %q", result) }
+}
@@ -126,3 +126,29 @@ func TestSTTRoutesDisabled(t *testing.T) {
         t.Fatalf("expected 404 when STT disabled, got %d", resp.StatusCode)
     }
 }
+
+func TestSimilarityMatrix(t *testing.T) {
+    emb := embeddings.New(embeddings.Config{ModelName: "all-MiniLM-L6-v2"})
+    ts := newTestServer(t, emb)
+    defer ts.Close()
+
+    buf, _ := json.Marshal(map[string]any{"input": []string{"the cat sat", "the cat sat", "quantum chromodynamics"}})
+    resp, err := http.Post(ts.URL+"/v1/similarity/matrix", "application/json", bytes.NewReader(buf))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+    var out struct{ Model string; Matrix [][]float32 }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatalf("decode failed: %v", err) }
+    if len(out.Matrix) != 3 || len(out.Matrix[0]) != 3 { t.Fatalf("bad matrix shape") }
+    if out.Matrix[0][1] < 0.999 { t.Fatalf("identical inputs should have similarity 1, got %f", out.Matrix[0][1]) }
+    if out.Matrix[0][2] != out.Matrix[2][0] { t.Fatalf("matrix not symmetric") }
+    if out.Matrix[0][2] >= out.Matrix[0][1] { t.Fatalf("unrelated inputs should be less similar") }
+
+    many := make([]string, 300)
+    for i := range many { many[i] = "x" }
+    buf, _ = json.Marshal(map[string]any{"input": many})
+    resp2, err := http.Post(ts.URL+"/v1/similarity/matrix", "application/json", bytes.NewReader(buf))
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    defer resp2.Body.Close()
+    if resp2.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400 over cap, got %d", resp2.StatusCode) }
+}
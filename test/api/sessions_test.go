@@ -0,0 +1,85 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/sessions"
+)
+
+func TestSessions_ChatRecordsHistory(t *testing.T) {
+    spy := newSpyLLM(t, "noted")
+    defer spy.Close()
+
+    store := sessions.New(filepath.Join(t.TempDir(), "sessions.db"), sessions.Options{MaxHistoryMessages: 50})
+    defer store.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", ""), Sessions: store})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/sessions", "application/json", strings.NewReader(`{"metadata":{"user":"u1"}}`))
+    if err != nil { t.Fatalf("create failed: %v", err) }
+    var sess sessions.Session
+    _ = json.NewDecoder(resp.Body).Decode(&sess)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated || sess.ID == "" { t.Fatalf("unexpected create response %d %+v", resp.StatusCode, sess) }
+
+    for _, text := range []string{"first", "second"} {
+        body := `{"session_id":"` + sess.ID + `","messages":[{"role":"user","content":"` + text + `"}]}`
+        resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+        if err != nil { t.Fatalf("chat failed: %v", err) }
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("chat expected 200, got %d", resp.StatusCode) }
+    }
+    if seen := spy.requests(); len(seen) != 2 || len(seen[0].Messages) != 1 || len(seen[1].Messages) != 3 { t.Fatalf("expected upstream to see 1 then 3 messages, got %+v", seen) }
+
+    resp, err = http.Get(ts.URL + "/v1/sessions/" + sess.ID)
+    if err != nil { t.Fatalf("get failed: %v", err) }
+    _ = json.NewDecoder(resp.Body).Decode(&sess)
+    resp.Body.Close()
+    if len(sess.Messages) != 4 || sess.Messages[3].Content != "noted" { t.Fatalf("unexpected stored history %+v", sess.Messages) }
+
+    resp, err = http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"session_id":"sess_missing","messages":[{"role":"user","content":"x"}]}`))
+    if err != nil { t.Fatalf("chat failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNotFound { t.Fatalf("expected 404 for unknown session, got %d", resp.StatusCode) }
+}
+
+func TestSessions_CompressesLongHistory(t *testing.T) {
+    spy := newSpyLLM(t, "short summary")
+    defer spy.Close()
+    store := sessions.New(filepath.Join(t.TempDir(), "sessions.db"), sessions.Options{CompressAfterTokens: 50, KeepRecent: 2})
+    defer store.Close()
+    long := strings.Repeat("words ", 40)
+    var history []llm.Message
+    for i := 0; i < 3; i++ {
+        history = append(history, llm.Message{Role: "user", Content: long}, llm.Message{Role: "assistant", Content: long})
+    }
+    sess, err := store.Create(nil, history)
+    if err != nil { t.Fatalf("create failed: %v", err) }
+
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", ""), Sessions: store})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"session_id":"`+sess.ID+`","messages":[{"role":"user","content":"next"}]}`))
+    if err != nil { t.Fatalf("chat failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+
+    reqs := spy.requests()
+    if len(reqs) != 2 { t.Fatalf("expected summarize + chat calls, got %d", len(reqs)) }
+    chat := reqs[1].Messages
+    if !strings.HasPrefix(chat[0].Content, sessions.SummaryPrefix+"short summary") || len(chat) != 4 { t.Fatalf("unexpected compressed context: %+v", chat) }
+
+    sess, _ = store.Get(sess.ID)
+    if len(sess.Messages) != 5 || sess.Messages[0].Role != "system" { t.Fatalf("unexpected stored session after compression: %d messages", len(sess.Messages)) }
+}
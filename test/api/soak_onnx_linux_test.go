@@ -0,0 +1,76 @@
+package api_test
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/services/embeddings"
+)
+
+// rssBytes reads this process's resident set size from /proc.
+func rssBytes(t *testing.T) int64 {
+    b, err := os.ReadFile("/proc/self/statm")
+    if err != nil { t.Fatalf("read statm: %v", err) }
+    fields := strings.Fields(string(b))
+    if len(fields) < 2 { t.Fatalf("unexpected statm: %q", b) }
+    pages, err := strconv.ParseInt(fields[1], 10, 64)
+    if err != nil { t.Fatalf("parse statm: %v", err) }
+    return pages * int64(os.Getpagesize())
+}
+
+// This test runs ONNX embeddings in a loop and fails when resident memory
+// keeps growing after warm-up, which is how undestroyed native tensors show
+// up: the Go heap stays flat while RSS climbs. It downloads the model on
+// first use and is skipped unless SOAK_ONNX=1. SOAK_ONNX_DURATION (default
+// 2m) and SOAK_ONNX_MAX_GROWTH_MB (default 64) tune it; SOAK_ONNX_MODEL_DIR
+// reuses an existing model directory.
+func TestSoak_ONNXEmbeddingsMemory(t *testing.T) {
+    if os.Getenv("SOAK_ONNX") != "1" {
+        t.Skip("skipping onnx soak, set SOAK_ONNX=1 to enable")
+    }
+    duration := 2 * time.Minute
+    if v := os.Getenv("SOAK_ONNX_DURATION"); v != "" {
+        d, err := time.ParseDuration(v)
+        if err != nil { t.Fatalf("SOAK_ONNX_DURATION: %v", err) }
+        duration = d
+    }
+    maxGrowth := int64(64)
+    if v := os.Getenv("SOAK_ONNX_MAX_GROWTH_MB"); v != "" {
+        n, err := strconv.ParseInt(v, 10, 64)
+        if err != nil { t.Fatalf("SOAK_ONNX_MAX_GROWTH_MB: %v", err) }
+        maxGrowth = n
+    }
+    dir := os.Getenv("SOAK_ONNX_MODEL_DIR")
+    if dir == "" { dir = filepath.Join(t.TempDir(), embeddings.DefaultModel) }
+
+    emb, err := embeddings.NewONNX(embeddings.DefaultModel, dir, embeddings.ONNXOptions{})
+    if err != nil { t.Fatalf("init embeddings: %v", err) }
+    inputs := []string{
+        "the quick brown fox jumps over the lazy dog",
+        "native memory is invisible to the garbage collector",
+        strings.Repeat("a long input that fills the sequence ", 20),
+    }
+    embed := func() {
+        if _, _, err := emb.Embed(context.Background(), inputs); err != nil { t.Fatalf("embed: %v", err) }
+    }
+
+    // Let the ORT arena and Go heap reach their steady size first.
+    for i := 0; i < 50; i++ { embed() }
+    runtime.GC()
+    base := rssBytes(t)
+
+    calls := 0
+    for deadline := time.Now().Add(duration); time.Now().Before(deadline); calls++ { embed() }
+    runtime.GC()
+    growth := (rssBytes(t) - base) >> 20
+    t.Logf("%d calls, rss %d MB -> growth %d MB", calls, base>>20, growth)
+    if growth > maxGrowth {
+        t.Fatalf("rss grew by %d MB over %d calls (limit %d MB); tensors are leaking", growth, calls, maxGrowth)
+    }
+}
@@ -0,0 +1,109 @@
+//go:build unix
+
+package api_test
+
+import (
+    "context"
+    "encoding/json"
+    "math"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/speaker"
+)
+
+// pitchEmbedder stands in for the speaker model: voices are told apart by
+// their pitch, estimated from zero crossings.
+type pitchEmbedder struct{}
+
+func (pitchEmbedder) Embed(_ context.Context, x []float32) ([]float32, error) {
+    crossings := 0
+    for i := 1; i < len(x); i++ {
+        if (x[i-1] < 0) != (x[i] < 0) { crossings++ }
+    }
+    hz := float64(crossings) / 2 / (float64(len(x)) / speaker.Rate)
+    v := make([]float32, 10)
+    for i := range v {
+        d := (hz - float64(100*(i+1))) / 100
+        v[i] = float32(math.Exp(-d * d))
+    }
+    return v, nil
+}
+
+func voice(hz float64, secs float64) []byte {
+    const rate = 16000
+    samples := make([]int16, int(secs*rate))
+    for i := range samples { samples[i] = int16(8000 * math.Sin(2*math.Pi*hz*float64(i)/rate)) }
+    return monoWAV(rate, samples)
+}
+
+func newSpeakerServer(t *testing.T, path string) *httptest.Server {
+    t.Helper()
+    svc, err := speaker.New(pitchEmbedder{}, path, 0)
+    if err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{Speakers: svc})
+    return httptest.NewServer(mux)
+}
+
+func TestSpeakers_EnrollVerifyIdentify(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "speakers.json")
+    ts := newSpeakerServer(t, path)
+    call := func(endpoint string, audio []byte, fields map[string]string, out any) int {
+        resp := postAudio(t, ts.URL+"/v1/speakers/"+endpoint, audio, fields)
+        defer resp.Body.Close()
+        if out != nil { _ = json.NewDecoder(resp.Body).Decode(out) }
+        return resp.StatusCode
+    }
+
+    var enrolled struct{ Speaker map[string]any `json:"speaker"` }
+    call("enroll", voice(200, 1.5), map[string]string{"speaker_id": "alice", "name": "Alice"}, nil)
+    if code := call("enroll", voice(210, 2), map[string]string{"speaker_id": "alice"}, &enrolled); code != http.StatusOK { t.Fatalf("enroll: %d", code) }
+    if enrolled.Speaker["samples"] != 2.0 || enrolled.Speaker["name"] != "Alice" || enrolled.Speaker["embedding"] != nil { t.Fatalf("unexpected speaker %v", enrolled.Speaker) }
+    call("enroll", voice(600, 1.5), map[string]string{"speaker_id": "bob"}, nil)
+
+    var verified struct {
+        Score float64 `json:"score"`
+        Match bool    `json:"match"`
+    }
+    call("verify", voice(205, 1), map[string]string{"speaker_id": "alice"}, &verified)
+    if !verified.Match || verified.Score < 0.9 { t.Fatalf("alice not verified: %+v", verified) }
+    call("verify", voice(600, 1), map[string]string{"speaker_id": "alice"}, &verified)
+    if verified.Match { t.Fatalf("bob verified as alice: %+v", verified) }
+
+    type identified struct {
+        Speaker    *speaker.Match  `json:"speaker"`
+        Candidates []speaker.Match `json:"candidates"`
+    }
+    var who identified
+    call("identify", voice(590, 1), nil, &who)
+    if who.Speaker == nil || who.Speaker.ID != "bob" || len(who.Candidates) != 2 || who.Candidates[1].ID != "alice" { t.Fatalf("unexpected identification %+v", who) }
+    who = identified{}
+    call("identify", voice(1000, 1), nil, &who)
+    if who.Speaker != nil { t.Fatalf("unknown voice identified as %+v", who.Speaker) }
+
+    if code := call("identify", voice(200, 0.5), nil, nil); code != http.StatusUnprocessableEntity { t.Fatalf("short clip: expected 422, got %d", code) }
+    if code := call("enroll", voice(200, 1), map[string]string{"speaker_id": "verify"}, nil); code != http.StatusBadRequest { t.Fatalf("reserved id: expected 400, got %d", code) }
+    if code := call("verify", voice(200, 1), map[string]string{"speaker_id": "carol"}, nil); code != http.StatusNotFound { t.Fatalf("unknown speaker: expected 404, got %d", code) }
+    ts.Close()
+
+    // Enrolled voices survive a restart.
+    ts = newSpeakerServer(t, path)
+    defer ts.Close()
+    resp, err := http.Get(ts.URL + "/v1/speakers")
+    if err != nil { t.Fatal(err) }
+    var list struct{ Speakers []map[string]any `json:"speakers"` }
+    _ = json.NewDecoder(resp.Body).Decode(&list)
+    resp.Body.Close()
+    if len(list.Speakers) != 2 || list.Speakers[0]["id"] != "alice" { t.Fatalf("unexpected speakers %v", list.Speakers) }
+    req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/v1/speakers/bob", nil)
+    resp, _ = http.DefaultClient.Do(req)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent { t.Fatalf("delete: %d", resp.StatusCode) }
+    var verified2 struct{ Match bool `json:"match"` }
+    call("verify", voice(205, 1), map[string]string{"speaker_id": "alice"}, &verified2)
+    if !verified2.Match { t.Fatal("alice lost after restart") }
+}
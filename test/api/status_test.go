@@ -0,0 +1,98 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "gollmcore/internal/models"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+)
+
+type statusBody struct {
+    Status        string `json:"status"`
+    UptimeSeconds int64  `json:"uptime_seconds"`
+    Services      map[string]struct {
+        State string `json:"state"`
+        Model string `json:"model"`
+        Note  string `json:"note"`
+        Error string `json:"error"`
+    } `json:"services"`
+    Models []struct {
+        Path    string `json:"path"`
+        Version string `json:"version"`
+        Size    int64  `json:"size"`
+        Present bool   `json:"present"`
+    } `json:"models"`
+    Disk struct {
+        DataDir      string `json:"data_dir"`
+        DataDirBytes int64  `json:"data_dir_bytes"`
+        FreeBytes    uint64 `json:"free_bytes"`
+    } `json:"disk"`
+}
+
+func getStatus(t *testing.T, d server.Dependencies) statusBody {
+    t.Helper()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, d)
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+    resp, err := http.Get(ts.URL + "/v1/status")
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("status: got %d", resp.StatusCode) }
+    var out statusBody
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatalf("decode: %v", err) }
+    return out
+}
+
+func TestStatus_ServicesModelsAndDisk(t *testing.T) {
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Repo-Commit", "abc123")
+        _, _ = w.Write([]byte("weights"))
+    }))
+    defer up.Close()
+    dataDir := t.TempDir()
+    lock, err := models.Open(dataDir)
+    if err != nil { t.Fatal(err) }
+    defer lock.Close()
+    dst := filepath.Join(dataDir, "models", "m.bin")
+    _ = os.MkdirAll(filepath.Dir(dst), 0o755)
+    if err := models.Fetch(up.URL+"/org/m/resolve/main/m.bin", dst, 5*time.Second); err != nil { t.Fatalf("fetch: %v", err) }
+
+    svc := llm.New(up.URL+"/v1", "test-model", "")
+    out := getStatus(t, server.Dependencies{
+        Embeddings: embeddings.New(embeddings.Config{}),
+        LLM:        svc,
+        Status:     server.StatusOptions{DataDir: dataDir, Lock: lock, LLM: svc, EmbeddingsModel: "all-MiniLM-L6-v2"},
+    })
+    if out.Status != "ok" { t.Fatalf("expected ok, got %+v", out) }
+    if s := out.Services["embeddings"]; s.State != "ready" || s.Model != "all-MiniLM-L6-v2" { t.Fatalf("embeddings: %+v", s) }
+    if s := out.Services["llm"]; s.State != "ready" || s.Model != "test-model" { t.Fatalf("llm: %+v", s) }
+    if s := out.Services["stt"]; s.State != "disabled" { t.Fatalf("stt: %+v", s) }
+    if len(out.Models) != 1 || out.Models[0].Path != "$DATA/models/m.bin" || out.Models[0].Version != "abc123" || out.Models[0].Size != 7 || !out.Models[0].Present { t.Fatalf("models: %+v", out.Models) }
+    if out.Disk.DataDir != dataDir || out.Disk.DataDirBytes < 7 { t.Fatalf("disk: %+v", out.Disk) }
+}
+
+func TestStatus_UnreachableLLMIsDegraded(t *testing.T) {
+    down := httptest.NewServer(http.NotFoundHandler())
+    down.Close()
+    svc := llm.New(down.URL+"/v1", "test-model", "")
+    out := getStatus(t, server.Dependencies{LLM: svc, Status: server.StatusOptions{LLM: svc}})
+    if s := out.Services["llm"]; out.Status != "degraded" || s.State != "unreachable" || s.Error == "" { t.Fatalf("expected a degraded status, got %+v", out) }
+}
+
+func TestStatus_QuantizedEmbeddingsNote(t *testing.T) {
+    out := getStatus(t, server.Dependencies{
+        Embeddings: embeddings.New(embeddings.Config{}),
+        Status:     server.StatusOptions{EmbeddingsModel: "all-MiniLM-L6-v2", EmbeddingsNote: embeddings.QuantizedNote},
+    })
+    if s := out.Services["embeddings"]; s.Model != "all-MiniLM-L6-v2" || s.Note != embeddings.QuantizedNote { t.Fatalf("embeddings: %+v", s) }
+    if s := out.Services["tts"]; s.Note != "" { t.Fatalf("unexpected note on tts: %+v", s) }
+}
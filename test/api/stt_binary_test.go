@@ -0,0 +1,106 @@
+//go:build unix
+
+package api_test
+
+import (
+    "archive/zip"
+    "bytes"
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/services/stt"
+)
+
+// TestSTTBinary_CustomURL checks that binary_url replaces the default
+// download and that whisper-cli is found inside the archive's folder.
+func TestSTTBinary_CustomURL(t *testing.T) {
+    var archive bytes.Buffer
+    zw := zip.NewWriter(&archive)
+    w, _ := zw.Create("Release/whisper-cli")
+    _, _ = w.Write([]byte(fakeWhisper))
+    zw.Close()
+    var fetched []string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        fetched = append(fetched, r.URL.Path)
+        _, _ = w.Write(archive.Bytes())
+    }))
+    defer srv.Close()
+
+    dir := t.TempDir()
+    bin, models := filepath.Join(dir, "bin"), filepath.Join(dir, "models")
+    if err := os.MkdirAll(models, 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(models, "ggml-tiny.bin"), nil, 0o644); err != nil { t.Fatal(err) }
+    audio := filepath.Join(dir, "clip.wav")
+    if err := os.WriteFile(audio, []byte("RIFF"), 0o644); err != nil { t.Fatal(err) }
+
+    svc := stt.NewWithBinary(bin, models, stt.BinaryOptions{Source: stt.SourceOfficial, URL: srv.URL + "/custom/whisper.zip"})
+    text, err := svc.TranscribeFile(context.Background(), audio, "tiny", stt.Options{})
+    if err != nil { t.Fatalf("transcribe: %v", err) }
+    if !strings.Contains(text, "input=clip.wav") { t.Fatalf("unexpected transcript %q", text) }
+    if len(fetched) != 1 || fetched[0] != "/custom/whisper.zip" { t.Fatalf("fetched %v, want the custom archive once", fetched) }
+    if _, err := os.Stat(filepath.Join(bin, "whisper-custom.zip")); !os.IsNotExist(err) { t.Fatalf("archive left behind: %v", err) }
+}
+
+func TestSTTBinary_UnknownSource(t *testing.T) {
+    if _, err := stt.ParseBinarySource("nightly"); err == nil { t.Fatal("want an error for an unknown source") }
+    if s, err := stt.ParseBinarySource(""); err != nil || s != stt.SourceMirror { t.Fatalf("empty source = %q, %v; want mirror", s, err) }
+}
+
+// gpuWhisper fails with a CUDA error unless run with -ng, and logs each
+// run's flags to calls.log beside it.
+const gpuWhisper = `#!/bin/sh
+dir=$(dirname "$0"); out=""; ng=""
+for a in "$@"; do
+    [ "$prev" = "-of" ] && out="$a"
+    [ "$a" = "-ng" ] && ng=1
+    prev="$a"
+done
+if [ -z "$ng" ]; then echo gpu >> "$dir/calls.log"; echo "ggml_cuda_init: CUDA error: out of memory" >&2; exit 1; fi
+echo cpu >> "$dir/calls.log"
+printf 'on the cpu' > "$out.txt"
+`
+
+func newGPUSTT(t *testing.T, gpu stt.GPU) (*stt.STTService, string, string) {
+    t.Helper()
+    dir := t.TempDir()
+    bin, models := filepath.Join(dir, "bin"), filepath.Join(dir, "models")
+    for _, d := range []string{bin, models} {
+        if err := os.MkdirAll(d, 0o755); err != nil { t.Fatal(err) }
+    }
+    if err := os.WriteFile(filepath.Join(bin, "whisper-cli"), []byte(gpuWhisper), 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(models, "ggml-tiny.bin"), nil, 0o644); err != nil { t.Fatal(err) }
+    audio := filepath.Join(dir, "clip.wav")
+    if err := os.WriteFile(audio, []byte("RIFF"), 0o644); err != nil { t.Fatal(err) }
+    return stt.NewWithBinary(bin, models, stt.BinaryOptions{GPU: gpu}), bin, audio
+}
+
+func readCalls(t *testing.T, bin string) string {
+    t.Helper()
+    b, _ := os.ReadFile(filepath.Join(bin, "calls.log"))
+    return strings.Join(strings.Fields(string(b)), ",")
+}
+
+// TestSTTGPU_FallsBackToCPU checks that a CUDA failure is retried with -ng
+// and that later runs skip the GPU.
+func TestSTTGPU_FallsBackToCPU(t *testing.T) {
+    smi := t.TempDir()
+    if err := os.WriteFile(filepath.Join(smi, "nvidia-smi"), []byte("#!/bin/sh\necho 'GPU 0: Test GPU (UUID: GPU-0)'\n"), 0o755); err != nil { t.Fatal(err) }
+    t.Setenv("PATH", smi+string(os.PathListSeparator)+os.Getenv("PATH"))
+    svc, bin, audio := newGPUSTT(t, stt.GPUCUDA)
+    for i := 0; i < 2; i++ {
+        text, err := svc.TranscribeFile(context.Background(), audio, "tiny", stt.Options{})
+        if err != nil || text != "on the cpu" { t.Fatalf("run %d: %q, %v", i, text, err) }
+    }
+    if calls := readCalls(t, bin); calls != "gpu,cpu,cpu" { t.Fatalf("runs = %s, want gpu,cpu,cpu", calls) }
+}
+
+func TestSTTGPU_Off(t *testing.T) {
+    svc, bin, audio := newGPUSTT(t, stt.GPUOff)
+    if _, err := svc.TranscribeFile(context.Background(), audio, "tiny", stt.Options{}); err != nil { t.Fatal(err) }
+    if calls := readCalls(t, bin); calls != "cpu" { t.Fatalf("runs = %s, want cpu", calls) }
+}
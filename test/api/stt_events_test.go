@@ -0,0 +1,56 @@
+//go:build unix
+
+package api_test
+
+import (
+    "bufio"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+)
+
+type sttEvent struct {
+    Type string  `json:"type"`
+    Text string  `json:"text"`
+    T0   float64 `json:"t0"`
+    T1   float64 `json:"t1"`
+}
+
+func TestSTTStream_JSONEvents(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: newFakeSTT(t), STTDefaultModel: "tiny"})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postTranscription(t, ts.URL+"/v1/audio/transcriptions/stream?event_format=json", map[string]string{"prompt": "Hi."})
+    defer resp.Body.Close()
+    var got []sttEvent
+    scan := bufio.NewScanner(resp.Body)
+    for scan.Scan() {
+        line, ok := strings.CutPrefix(scan.Text(), "data: ")
+        if !ok { continue }
+        var e sttEvent
+        if err := json.Unmarshal([]byte(line), &e); err != nil { t.Fatalf("not a JSON event: %q", line) }
+        got = append(got, e)
+    }
+    want := []sttEvent{
+        {"segment", "prompt=Hi.", 0, 1.5},
+        {"partial", "prompt=Hi.", 0, 1.5},
+        {"segment", "input=", 1.5, 3},
+        {"partial", "prompt=Hi. input=", 0, 3},
+        {"done", "prompt=Hi. input=", 0, 3},
+    }
+    if len(got) != len(want) { t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got) }
+    for i, e := range got {
+        w := want[i]
+        if e.Type != w.Type || !strings.HasPrefix(e.Text, w.Text) || e.T0 != w.T0 || e.T1 != w.T1 { t.Errorf("event %d: got %+v, want %+v", i, e, w) }
+    }
+
+    resp = postTranscription(t, ts.URL+"/v1/audio/transcriptions/stream?event_format=xml", nil)
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "event_format" { t.Fatalf("unexpected error %+v", e.Error) }
+}
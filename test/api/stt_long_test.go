@@ -0,0 +1,92 @@
+//go:build unix
+
+package api_test
+
+import (
+    "encoding/json"
+    "math"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+)
+
+func TestSTTLongAudio_SplitsOnSilenceInParallel(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        LongAudio:       server.LongAudio{After: time.Minute, Chunk: time.Minute, Workers: 3},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    // Three minutes of "speech" with pauses at 55 s and 118 s; cuts aim for
+    // every minute and should move to the pauses.
+    const rate = 8000
+    samples := make([]int16, 180*rate)
+    for i := range samples {
+        sec := float64(i) / rate
+        if (sec >= 55 && sec < 56) || (sec >= 118 && sec < 119) { continue }
+        samples[i] = int16(6000 * math.Sin(2*math.Pi*300*sec))
+    }
+    start := time.Now()
+    resp := postAudio(t, ts.URL+"/v1/audio/transcriptions", monoWAV(rate, samples), nil)
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+    if d := time.Since(start); d > 800*time.Millisecond { t.Errorf("chunks did not run in parallel (%s for three 300 ms runs)", d) }
+    var out struct {
+        Text     string `json:"text"`
+        Segments []struct {
+            Start, End float64
+            Text       string
+        } `json:"segments"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatal(err) }
+    if len(out.Segments) != 3 { t.Fatalf("expected 3 chunks, got %+v", out) }
+
+    // Each fake segment sits 0.5-1.5 s into its chunk.
+    cuts := []float64{0, 55.5, 118.5}
+    total := 0
+    var texts []string
+    for i, s := range out.Segments {
+        if math.Abs(s.Start-(cuts[i]+0.5)) > 0.2 || math.Abs(s.End-s.Start-1) > 1e-6 { t.Errorf("segment %d not shifted to its chunk: %+v", i, s) }
+        n, _ := strconv.Atoi(strings.TrimPrefix(s.Text, "bytes="))
+        total += n - 44
+        texts = append(texts, s.Text)
+    }
+    if total != 2*len(samples) { t.Errorf("chunks hold %d bytes of audio, want %d", total, 2*len(samples)) }
+    if out.Text != strings.Join(texts, " ") { t.Errorf("text not stitched in order: %q", out.Text) }
+
+    // Short recordings are transcribed in one run without segments.
+    resp2 := postAudio(t, ts.URL+"/v1/audio/transcriptions", monoWAV(rate, samples[:30*rate]), nil)
+    defer resp2.Body.Close()
+    var short map[string]any
+    _ = json.NewDecoder(resp2.Body).Decode(&short)
+    if _, ok := short["segments"]; ok || !strings.HasPrefix(short["text"].(string), "prompt=") { t.Errorf("short recording was split: %v", short) }
+}
+
+func TestSTTCaps_RejectLongAudio(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        Caps:            server.Caps{AudioDuration: time.Minute},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    long := monoWAV(8000, make([]int16, 61*8000))
+    for _, path := range []string{"/v1/audio/transcriptions", "/v1/audio/transcriptions/stream"} {
+        resp := postAudio(t, ts.URL+path, long, nil)
+        if resp.StatusCode != http.StatusBadRequest { resp.Body.Close(); t.Fatalf("%s: expected 400, got %d", path, resp.StatusCode) }
+        if e := decodeError(t, resp); e.Error.Code != "limit_exceeded" || !strings.Contains(e.Error.Message, "audio too long: 1m1s (max 1m0s)") { t.Errorf("%s: unexpected error %+v", path, e.Error) }
+    }
+    resp := postAudio(t, ts.URL+"/v1/audio/transcriptions", monoWAV(8000, make([]int16, 59*8000)), nil)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200 within the cap, got %d", resp.StatusCode) }
+}
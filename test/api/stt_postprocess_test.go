@@ -0,0 +1,63 @@
+//go:build unix
+
+package api_test
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestSTTPostprocess_CleansTranscriptWithLLM(t *testing.T) {
+    spy := newSpyLLM(t, "Cleaned transcript.")
+    defer spy.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        LLM:             llm.New(spy.URL+"/v1", "test-model", ""),
+        STTPostprocess:  server.Postprocess{Prompt: "Fix punctuation."},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    var out struct {
+        Text    string `json:"text"`
+        RawText string `json:"raw_text"`
+    }
+    resp := postTranscription(t, ts.URL+"/v1/audio/transcriptions", map[string]string{"postprocess": "true", "prompt": "um so"})
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if out.Text != "Cleaned transcript." || !strings.HasPrefix(out.RawText, "prompt=um so\n") { t.Fatalf("unexpected response %+v", out) }
+    reqs := spy.requests()
+    if len(reqs) != 1 || reqs[0].Messages[0].Content != "Fix punctuation." || reqs[0].Messages[1].Content != out.RawText { t.Fatalf("unexpected LLM requests %+v", reqs) }
+
+    // Off by default; raw streams cannot be post-processed, JSON events can.
+    resp = postTranscription(t, ts.URL+"/v1/audio/transcriptions", nil)
+    out.RawText = ""
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    if out.RawText != "" || len(spy.requests()) != 1 { t.Fatalf("post-processed without being asked: %+v", out) }
+    resp = postTranscription(t, ts.URL+"/v1/audio/transcriptions/stream", map[string]string{"postprocess": "true"})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "postprocess" { t.Fatalf("expected a postprocess error, got %d %+v", resp.StatusCode, e.Error) }
+    resp = postTranscription(t, ts.URL+"/v1/audio/transcriptions/stream?event_format=json", map[string]string{"postprocess": "true"})
+    b, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if !strings.Contains(string(b), `"type":"postprocessed","text":"Cleaned transcript."`) { t.Fatalf("stream missing postprocessed event: %s", b) }
+}
+
+func TestSTTPostprocess_NeedsLLM(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: newFakeSTT(t), STTDefaultModel: "tiny"})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+    resp := postTranscription(t, ts.URL+"/v1/audio/transcriptions", map[string]string{"postprocess": "true"})
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "postprocess" { t.Fatalf("unexpected error %+v", e.Error) }
+}
@@ -0,0 +1,107 @@
+//go:build unix
+
+package api_test
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "math"
+    "math/rand"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/stt"
+)
+
+// laptopMic returns 16 kHz mono PCM16 WAV: hiss, a quiet tone standing in
+// for speech, then hiss again.
+func laptopMic() []byte {
+    const rate = 16000
+    r := rand.New(rand.NewSource(1))
+    samples := make([]int16, 2*rate)
+    for i := range samples {
+        v := (r.Float64()*2 - 1) * 0.003
+        if i >= rate/2 && i < 3*rate/2 { v += 0.02 * math.Sin(2*math.Pi*440*float64(i)/rate) }
+        samples[i] = int16(v * 32767)
+    }
+    return monoWAV(rate, samples)
+}
+
+// monoWAV wraps PCM16 samples in a canonical 44-byte WAV header.
+func monoWAV(rate int, samples []int16) []byte {
+    var buf bytes.Buffer
+    buf.WriteString("RIFF")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(36+2*len(samples)))
+    buf.WriteString("WAVEfmt ")
+    for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(2 * rate), uint16(2), uint16(16)} {
+        _ = binary.Write(&buf, binary.LittleEndian, v)
+    }
+    buf.WriteString("data")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(2*len(samples)))
+    _ = binary.Write(&buf, binary.LittleEndian, samples)
+    return buf.Bytes()
+}
+
+// rms returns the level of samples [from, to) of a canonical PCM16 WAV.
+func rms(wav []byte, from, to int) float64 {
+    var sum float64
+    for i := from; i < to; i++ {
+        v := float64(int16(binary.LittleEndian.Uint16(wav[44+2*i:]))) / 32768
+        sum += v * v
+    }
+    return math.Sqrt(sum / float64(to-from))
+}
+
+func TestSTTPreprocess_NormalizesAndGatesNoise(t *testing.T) {
+    in := laptopMic()
+    out, err := stt.Preprocess{Normalize: true, Denoise: true}.Apply(in)
+    if err != nil { t.Fatal(err) }
+    if len(out) != len(in) { t.Fatalf("length changed: %d -> %d", len(in), len(out)) }
+
+    speech := rms(out, 10000, 22000)
+    if math.Abs(20*math.Log10(speech/0.1)) > 1 { t.Fatalf("speech not normalized to -20 dBFS: %.4f", speech) }
+    before := rms(in, 10000, 22000) / rms(in, 0, 6000)
+    after := speech / rms(out, 0, 6000)
+    if gain := 20 * math.Log10(after/before); gain < 15 { t.Fatalf("noise floor only reduced by %.1f dB", gain) }
+
+    loud, _ := stt.Preprocess{Normalize: true}.Apply(in)
+    if l := rms(loud, 0, 6000) / rms(loud, 10000, 22000); math.Abs(l-rms(in, 0, 6000)/rms(in, 10000, 22000)) > 1e-3 {
+        t.Fatalf("normalization alone should not change the noise ratio")
+    }
+    if _, err := (stt.Preprocess{Normalize: true}).Apply([]byte("ID3 not a wav")); !errors.Is(err, stt.ErrNotPCMWAV) {
+        t.Fatalf("expected ErrNotPCMWAV, got %v", err)
+    }
+}
+
+func TestSTTPreprocess_PerRequestToggle(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        STTPreprocess:   stt.Preprocess{Denoise: true},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    transcribe := func(audio []byte, fields map[string]string) string {
+        resp := postAudio(t, ts.URL+"/v1/audio/transcriptions", audio, fields)
+        defer resp.Body.Close()
+        var out struct{ Text string `json:"text"` }
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return out.Text
+    }
+    if s := transcribe(laptopMic(), nil); !strings.Contains(s, "input=stt-pre-") { t.Fatalf("configured denoise not applied: %q", s) }
+    if s := transcribe(laptopMic(), map[string]string{"denoise": "false"}); strings.Contains(s, "input=stt-pre-") { t.Fatalf("request could not turn denoise off: %q", s) }
+    if s := transcribe([]byte("ID3 mp3 data"), map[string]string{"normalize": "true"}); !strings.Contains(s, "input=stt-") || strings.Contains(s, "input=stt-pre-") {
+        t.Fatalf("non-WAV input should pass through unprocessed: %q", s)
+    }
+
+    resp := postAudio(t, ts.URL+"/v1/audio/transcriptions/stream", laptopMic(), map[string]string{"normalize": "loud"})
+    if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400, got %d", resp.StatusCode) }
+    if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "normalize" { t.Fatalf("unexpected error %+v", e.Error) }
+}
@@ -0,0 +1,129 @@
+//go:build unix
+
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/stt"
+)
+
+// fakeWhisper stands in for whisper.cpp: it reports the --prompt and input
+// file it was given, in the -of text file or on stdout when streaming
+// (timestamped as two segments, 0-1.5 s and 1.5-3 s, unless -nt). With -oj
+// it takes 300 ms and writes one segment, 0.5-1.5 s, naming the input size
+// in bytes. With -dl it detects German.
+const fakeWhisper = `#!/bin/sh
+out=""; prompt=""; in=""; json=""; nt=""
+while [ $# -gt 0 ]; do
+    case "$1" in
+    -of) out="$2"; shift ;;
+    -f) in="$2"; shift ;;
+    -oj) json=1 ;;
+    -nt) nt=1 ;;
+    -dl) echo "whisper_full_with_state: auto-detected language: de (p = 0.912345)" >&2; exit 0 ;;
+    --prompt) prompt="$2"; shift ;;
+    esac
+    shift
+done
+if [ -n "$json" ]; then
+    sleep 0.3
+    printf '{"transcription": [{"offsets": {"from": 500, "to": 1500}, "text": " bytes=%s"}]}' "$(wc -c < "$in" | tr -d ' ')" > "$out.json"
+    exit 0
+fi
+report="prompt=$prompt
+input=$(basename "$in")"
+if [ -n "$out" ]; then printf '%s' "$report" > "$out.txt"; exit 0; fi
+if [ -n "$nt" ]; then echo "$report"; exit 0; fi
+echo "whisper_init_from_file: loading model" >&2
+printf '[00:00:00.000 --> 00:00:01.500]   prompt=%s\n[00:00:01.500 --> 00:00:03.000]   input=%s\n' "$prompt" "$(basename "$in")"
+`
+
+// newFakeSTT returns an STT service whose whisper binary and tiny model are
+// already "installed".
+func newFakeSTT(t *testing.T) *stt.STTService {
+    t.Helper()
+    dir := t.TempDir()
+    bin, models := filepath.Join(dir, "bin"), filepath.Join(dir, "models")
+    for _, d := range []string{bin, models} {
+        if err := os.MkdirAll(d, 0o755); err != nil { t.Fatal(err) }
+    }
+    if err := os.WriteFile(filepath.Join(bin, "whisper"), []byte(fakeWhisper), 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(models, "ggml-tiny.bin"), nil, 0o644); err != nil { t.Fatal(err) }
+    return stt.New(bin, models)
+}
+
+func postTranscription(t *testing.T, url string, fields map[string]string) *http.Response {
+    t.Helper()
+    return postAudio(t, url, []byte("RIFF"), fields)
+}
+
+func postAudio(t *testing.T, url string, audio []byte, fields map[string]string) *http.Response {
+    t.Helper()
+    body := &bytes.Buffer{}
+    mw := multipart.NewWriter(body)
+    w, _ := mw.CreateFormFile("file", "clip.wav")
+    _, _ = w.Write(audio)
+    for k, v := range fields { _ = mw.WriteField(k, v) }
+    mw.Close()
+    req, _ := http.NewRequest(http.MethodPost, url, body)
+    req.Header.Set("Content-Type", mw.FormDataContentType())
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    return resp
+}
+
+func TestSTTPrompt_PassedToWhisper(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             newFakeSTT(t),
+        STTDefaultModel: "tiny",
+        STTPrompt:       stt.BuildPrompt("Meeting notes.", []string{"Kubernetes", " gollmcore ", ""}),
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    cases := []struct {
+        fields map[string]string
+        want   string
+    }{
+        {nil, "prompt=Kubernetes, gollmcore. Meeting notes."},
+        {map[string]string{"prompt": "Talk about PostgreSQL."}, "prompt=Talk about PostgreSQL."},
+    }
+    for _, tc := range cases {
+        resp := postTranscription(t, ts.URL+"/v1/audio/transcriptions", tc.fields)
+        var out struct{ Text string `json:"text"` }
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        resp.Body.Close()
+        if !strings.HasPrefix(out.Text, tc.want+"\n") { t.Errorf("%v: got %q, want %q", tc.fields, out.Text, tc.want) }
+
+        resp = postTranscription(t, ts.URL+"/v1/audio/transcriptions/stream", tc.fields)
+        b, _ := io.ReadAll(resp.Body)
+        resp.Body.Close()
+        if !strings.Contains(string(b), "data: "+tc.want+"\n") { t.Errorf("%v: stream did not pass the prompt: %q", tc.fields, b) }
+    }
+}
+
+func TestSTTPrompt_RejectsOverlongPrompt(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: newFakeSTT(t), STTDefaultModel: "tiny"})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    for _, prompt := range []string{strings.Repeat("jargon ", 130), strings.Repeat("東京", 120)} {
+        resp := postTranscription(t, ts.URL+"/v1/audio/transcriptions", map[string]string{"prompt": prompt})
+        if resp.StatusCode != http.StatusBadRequest { t.Fatalf("expected 400, got %d", resp.StatusCode) }
+        if e := decodeError(t, resp); e.Error.Param == nil || *e.Error.Param != "prompt" { t.Fatalf("unexpected error %+v", e.Error) }
+    }
+    if err := stt.CheckPrompt(strings.Repeat("term ", 170)); err != nil { t.Fatalf("a 850-character English prompt should fit: %v", err) }
+}
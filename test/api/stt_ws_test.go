@@ -0,0 +1,51 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/stt"
+)
+
+func TestSTTWebSocket_BinaryFrames(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{STT: stt.NewMock(), STTDefaultModel: "base"}, server.WSOptions{Enable: true, MaxMessageBytes: 48000})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/stt", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+    // send streams a header and the audio in 8000-byte frames.
+    send := func(header map[string]any, audio []byte) map[string]any {
+        t.Helper()
+        if err := conn.WriteJSON(header); err != nil { t.Fatal(err) }
+        for len(audio) > 0 {
+            n := min(len(audio), 8000)
+            if err := conn.WriteMessage(websocket.BinaryMessage, audio[:n]); err != nil { t.Fatal(err) }
+            audio = audio[n:]
+        }
+        if err := conn.WriteMessage(websocket.BinaryMessage, nil); err != nil { t.Fatal(err) }
+        var resp map[string]any
+        if err := conn.ReadJSON(&resp); err != nil { t.Fatalf("read failed: %v", err) }
+        return resp
+    }
+
+    // One second of 16 kHz PCM16 silence.
+    resp := send(map[string]any{"binary": true, "format": "pcm16", "sample_rate": 16000}, make([]byte, 32000))
+    if resp["ok"] != true || resp["text"] != "Mock transcription of 1.0 seconds of audio." { t.Fatalf("unexpected response %v", resp) }
+
+    // Over the message limit the upload is dropped but the connection stays.
+    resp = send(map[string]any{"binary": true, "format": "pcm16"}, make([]byte, 64000))
+    if e, _ := resp["error"].(map[string]any); e == nil || e["code"] != "payload_too_large" { t.Fatalf("expected payload_too_large, got %v", resp) }
+    resp = send(map[string]any{"binary": true, "format": "pcm16", "sample_rate": 8000}, make([]byte, 8000))
+    if resp["text"] != "Mock transcription of 0.5 seconds of audio." { t.Fatalf("connection unusable after a rejected upload: %v", resp) }
+}
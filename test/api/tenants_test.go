@@ -0,0 +1,134 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/audit"
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/sessions"
+)
+
+// newTenantServer maps key-a and key-b to tenants a and b and trusts
+// X-Tenant-ID for the shared key.
+func newTenantServer(t *testing.T) (*httptest.Server, string) {
+    t.Helper()
+    dir := t.TempDir()
+    store := sessions.New(filepath.Join(dir, "sessions.db"), sessions.Options{})
+    t.Cleanup(func() { store.Close() })
+    l, err := audit.Open(audit.Options{Path: filepath.Join(dir, "audit.jsonl")})
+    if err != nil { t.Fatal(err) }
+    t.Cleanup(func() { l.Close() })
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{Sessions: store})
+    keys := []string{"key-a", "key-b", "shared"}
+    opts := server.TenantOptions{Keys: map[string]string{"key-a": "a", "key-b": "b"}, Header: "X-Tenant-ID"}
+    ts := httptest.NewServer(server.Tenants(server.Audit(server.RequireAPIKey(mux, keys, nil), l), opts))
+    t.Cleanup(ts.Close)
+    return ts, dir
+}
+
+func tenantRequest(t *testing.T, method, url, key, tenant string) *http.Response {
+    t.Helper()
+    req, _ := http.NewRequest(method, url, strings.NewReader(`{}`))
+    req.Header.Set("Authorization", "Bearer "+key)
+    if tenant != "" { req.Header.Set("X-Tenant-ID", tenant) }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    return resp
+}
+
+func listSessions(t *testing.T, url, key, tenant string) int {
+    t.Helper()
+    resp := tenantRequest(t, http.MethodGet, url+"/v1/sessions", key, tenant)
+    defer resp.Body.Close()
+    var out struct{ Sessions []sessions.Summary `json:"sessions"` }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    return len(out.Sessions)
+}
+
+func TestTenants_IsolateSessions(t *testing.T) {
+    ts, dir := newTenantServer(t)
+
+    resp := tenantRequest(t, http.MethodPost, ts.URL+"/v1/sessions", "key-a", "")
+    var sess sessions.Session
+    _ = json.NewDecoder(resp.Body).Decode(&sess)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { t.Fatalf("create: expected 201, got %d", resp.StatusCode) }
+
+    if n := listSessions(t, ts.URL, "key-a", ""); n != 1 { t.Fatalf("tenant a sees %d sessions, want 1", n) }
+    if n := listSessions(t, ts.URL, "key-b", ""); n != 0 { t.Fatalf("tenant b sees %d sessions, want 0", n) }
+    // A mapped key cannot claim another tenant through the header.
+    if n := listSessions(t, ts.URL, "key-b", "a"); n != 0 { t.Fatalf("tenant b reached tenant a through the header") }
+    if n := listSessions(t, ts.URL, "shared", ""); n != 0 { t.Fatalf("the default tenant sees %d sessions, want 0", n) }
+    resp = tenantRequest(t, http.MethodGet, ts.URL+"/v1/sessions/"+sess.ID, "key-b", "")
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNotFound { t.Fatalf("tenant b got tenant a's session: %d", resp.StatusCode) }
+
+    // The header names the tenant of unmapped keys.
+    if n := listSessions(t, ts.URL, "shared", "a"); n != 1 { t.Fatalf("header tenant a sees %d sessions, want 1", n) }
+    if _, err := os.Stat(filepath.Join(dir, "tenants", "a", "sessions.db")); err != nil { t.Fatalf("tenant sessions not in their own file: %v", err) }
+}
+
+func TestTenants_AuditLogPerTenant(t *testing.T) {
+    ts, dir := newTenantServer(t)
+    for _, key := range []string{"key-a", "key-b", "shared"} {
+        resp := tenantRequest(t, http.MethodGet, ts.URL+"/v1/sessions", key, "")
+        resp.Body.Close()
+    }
+    for path, want := range map[string]string{"audit.jsonl": "", "tenants/a/audit.jsonl": "a", "tenants/b/audit.jsonl": "b"} {
+        entries := readAudit(t, filepath.Join(dir, path))
+        tenant, _ := entries[0]["tenant"].(string)
+        if len(entries) != 1 || tenant != want { t.Fatalf("%s: unexpected entries %v", path, entries) }
+    }
+}
+
+func TestTenants_RejectsBadHeader(t *testing.T) {
+    ts, _ := newTenantServer(t)
+    resp := tenantRequest(t, http.MethodGet, ts.URL+"/v1/sessions", "shared", "../etc")
+    e := decodeError(t, resp)
+    if resp.StatusCode != http.StatusBadRequest || !strings.Contains(e.Error.Message, "invalid tenant name") { t.Fatalf("expected 400, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+func TestTenants_JobsVisibleOnlyToTheirOwner(t *testing.T) {
+    up := newFakeLLM(t, "ok")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", ""), JobsDB: filepath.Join(t.TempDir(), "jobs.db")})
+    opts := server.TenantOptions{Keys: map[string]string{"key-a": "a", "key-b": "b"}, Header: "X-Tenant-ID"}
+    ts := httptest.NewServer(server.Tenants(server.RequireAPIKey(mux, []string{"key-a", "key-b", "shared"}, []string{"admin"}), opts))
+    defer ts.Close()
+
+    b, _ := json.Marshal(map[string]any{"requests": batchItems("1"), "async": true})
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions/batch", strings.NewReader(string(b)))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer key-a")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    var job server.Job
+    _ = json.NewDecoder(resp.Body).Decode(&job)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted { t.Fatalf("expected 202, got %d", resp.StatusCode) }
+
+    for _, c := range []struct {
+        key, tenant string
+        want        int
+    }{
+        {"key-a", "", http.StatusOK},
+        {"key-b", "", http.StatusNotFound},  // another tenant
+        {"key-b", "a", http.StatusNotFound}, // which cannot claim a through the header
+        {"shared", "a", http.StatusNotFound}, // another key of the tenant
+        {"admin", "a", http.StatusOK},
+        {"admin", "", http.StatusNotFound},   // an admin of another tenant
+    } {
+        resp := tenantRequest(t, http.MethodGet, ts.URL+"/v1/jobs/"+job.ID, c.key, c.tenant)
+        resp.Body.Close()
+        if resp.StatusCode != c.want { t.Fatalf("key %s, tenant %q: status %d, want %d", c.key, c.tenant, resp.StatusCode, c.want) }
+    }
+}
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+    "context"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/audit"
+    "gollmcore/internal/server"
+    "gollmcore/internal/tracing"
+)
+
+// stagedTTS spends 20 ms in an inference stage.
+type stagedTTS struct{}
+
+func (stagedTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    err := tracing.Do(ctx, "tts.inference", func(context.Context) error { time.Sleep(20 * time.Millisecond); return nil })
+    return []byte("RIFF"), err
+}
+
+func headerMillis(t *testing.T, resp *http.Response, name string) float64 {
+    t.Helper()
+    v, err := strconv.ParseFloat(resp.Header.Get(name), 64)
+    if err != nil { t.Fatalf("%s = %q: %v", name, resp.Header.Get(name), err) }
+    return v
+}
+
+func TestTiming_StageHeadersAndAudit(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    l, err := audit.Open(audit.Options{Path: path})
+    if err != nil { t.Fatalf("open: %v", err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{TTS: stagedTTS{}})
+    ts := httptest.NewServer(server.Chain(mux, server.Timing, server.With(server.Audit, l)))
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/tts", map[string]any{"text": "hello"})
+    resp.Body.Close()
+    l.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("status %d", resp.StatusCode) }
+    inference := headerMillis(t, resp, "X-Inference-Time")
+    if inference < 20 { t.Fatalf("X-Inference-Time = %v, want >= 20", inference) }
+    if total := headerMillis(t, resp, "X-Processing-Time"); total < inference { t.Fatalf("X-Processing-Time %v is below the inference time %v", total, inference) }
+    if q := headerMillis(t, resp, "X-Queue-Time"); q != 0 { t.Fatalf("X-Queue-Time = %v without a limit", q) }
+
+    entries := readAudit(t, path)
+    if len(entries) != 1 { t.Fatalf("want 1 audit entry, got %d", len(entries)) }
+    stages, _ := entries[0]["stages_ms"].(map[string]any)
+    if ms, _ := stages["inference"].(float64); ms != inference { t.Fatalf("audit stages_ms = %v, header says %v", entries[0]["stages_ms"], inference) }
+}
+
+// metricValue reads one series of the Prometheus text at base/metrics.
+func metricValue(t *testing.T, base, series string) int64 {
+    t.Helper()
+    resp, err := http.Get(base + "/metrics")
+    if err != nil { t.Fatalf("metrics: %v", err) }
+    defer resp.Body.Close()
+    b, _ := io.ReadAll(resp.Body)
+    for _, line := range strings.Split(string(b), "\n") {
+        if v, ok := strings.CutPrefix(line, series+" "); ok {
+            n, _ := strconv.ParseInt(v, 10, 64)
+            return n
+        }
+    }
+    return 0
+}
+
+func TestTiming_QueueTime(t *testing.T) {
+    tts := &gatedTTS{started: make(chan string, 2), release: make(chan struct{})}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithLimits(server.Dependencies{TTS: tts, Limits: server.Limits{TTS: server.Limit{MaxConcurrent: 1}}}))
+    ts := httptest.NewServer(server.Timing(mux))
+    defer ts.Close()
+
+    go func() {
+        resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"first"}`))
+        if err == nil { resp.Body.Close() }
+    }()
+    <-tts.started
+    const queued = `gollmcore_queued_requests_total{service="tts",priority="interactive"}`
+    before := metricValue(t, ts.URL, queued)
+    second := make(chan *http.Response, 1)
+    go func() {
+        resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"second"}`))
+        if err != nil { t.Error(err); close(second); return }
+        resp.Body.Close()
+        second <- resp
+    }()
+    for deadline := time.Now().Add(5 * time.Second); metricValue(t, ts.URL, queued) == before; time.Sleep(time.Millisecond) {
+        if time.Now().After(deadline) { t.Fatal("second request never queued") }
+    }
+    // The queue clock started before the counter moved, so the wait below
+    // is a lower bound on the queue time.
+    time.Sleep(30 * time.Millisecond)
+    tts.release <- struct{}{}
+    <-tts.started
+    tts.release <- struct{}{}
+    resp, ok := <-second
+    if !ok { return }
+    if q := headerMillis(t, resp, "X-Queue-Time"); q < 30 { t.Fatalf("X-Queue-Time = %v, want >= 30", q) }
+}
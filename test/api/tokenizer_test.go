@@ -0,0 +1,61 @@
+package api_test
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/services/embeddings"
+)
+
+func TestTokenizer_WordPieceNormalizesUnicode(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "vocab.txt")
+    vocab := []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "hello", "world", "!", "cafe", "東", "京", "пр", "##ивет"}
+    if err := os.WriteFile(path, []byte(strings.Join(vocab, "\n")), 0o644); err != nil { t.Fatal(err) }
+    tk, err := embeddings.LoadTokenizer(path)
+    if err != nil { t.Fatal(err) }
+
+    cases := map[string][]string{
+        "Hello, WORLD!":  {"hello", "[UNK]", "world", "!"},
+        "ｈｅｌｌｏ Café": {"hello", "cafe"},  // full-width forms and accents
+        "東京":           {"東", "京"},        // ideographs one at a time
+        "Приве́т":        {"пр", "##ивет"},    // Cyrillic with a combining accent
+    }
+    for in, want := range cases {
+        if got := tk.Tokens(in); !reflect.DeepEqual(got, want) { t.Errorf("Tokens(%q) = %q, want %q", in, got, want) }
+    }
+
+    ids, mask := tk.Encode("hello world", 6)
+    if !reflect.DeepEqual(ids, []int64{2, 4, 5, 3, 0, 0}) || !reflect.DeepEqual(mask, []int64{1, 1, 1, 1, 0, 0}) { t.Fatalf("unexpected encoding %v %v", ids, mask) }
+    ids, _ = tk.Encode("hello world hello world", 4)
+    if !reflect.DeepEqual(ids, []int64{2, 4, 5, 3}) { t.Fatalf("truncation must keep [SEP]: %v", ids) }
+}
+
+func TestTokenizer_UnigramMultilingual(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "tokenizer.json")
+    doc := `{"model": {"type": "Unigram", "unk_id": 3, "vocab": [
+        ["<s>", 0], ["<pad>", 0], ["</s>", 0], ["<unk>", 0],
+        ["▁hello", -1], ["▁world", -2], ["▁wor", -3], ["ld", -3],
+        ["▁東京", -1], ["は", -2], ["▁", -4], ["東", -5], ["京", -5],
+        ["▁мир", -2], ["!", -1], ["s", -3]
+    ]}}`
+    if err := os.WriteFile(path, []byte(doc), 0o644); err != nil { t.Fatal(err) }
+    tk, err := embeddings.LoadTokenizer(path)
+    if err != nil { t.Fatal(err) }
+
+    cases := map[string][]string{
+        "hello  world":   {"▁hello", "▁world"}, // most probable segmentation
+        "東京は":          {"▁東京", "は"},
+        "ｈｅｌｌｏ мир!": {"▁hello", "▁мир", "!"},
+        "hello😀😀":       {"▁hello", "<unk>"}, // unknown runs fuse into one token
+        "hello<s>":       {"▁hello", "<unk>", "s", "<unk>"}, // special tokens never come from text
+    }
+    for in, want := range cases {
+        if got := tk.Tokens(in); !reflect.DeepEqual(got, want) { t.Errorf("Tokens(%q) = %q, want %q", in, got, want) }
+    }
+
+    ids, mask := tk.Encode("hello", 4)
+    if !reflect.DeepEqual(ids, []int64{0, 4, 2, 1}) || !reflect.DeepEqual(mask, []int64{1, 1, 1, 0}) { t.Fatalf("unexpected encoding %v %v", ids, mask) }
+}
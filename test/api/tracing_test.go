@@ -0,0 +1,83 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/tracing"
+)
+
+// TestTracing_ExportsNestedSpans checks that a chat request produces a
+// server span and an LLM span in the same trace, that the incoming
+// traceparent is continued and that the upstream receives the LLM span.
+func TestTracing_ExportsNestedSpans(t *testing.T) {
+    type span struct {
+        TraceID      string `json:"traceId"`
+        SpanID       string `json:"spanId"`
+        ParentSpanID string `json:"parentSpanId"`
+        Name         string `json:"name"`
+    }
+    var mu sync.Mutex
+    var spans []span
+    collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/v1/traces" { http.NotFound(w, r); return }
+        var body struct {
+            ResourceSpans []struct {
+                ScopeSpans []struct{ Spans []span `json:"spans"` } `json:"scopeSpans"`
+            } `json:"resourceSpans"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil { t.Errorf("bad OTLP body: %v", err) }
+        mu.Lock()
+        for _, rs := range body.ResourceSpans {
+            for _, ss := range rs.ScopeSpans { spans = append(spans, ss.Spans...) }
+        }
+        mu.Unlock()
+    }))
+    defer collector.Close()
+
+    var upstreamParent string
+    fake := newFakeLLM(t, "hi")
+    defer fake.Close()
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        upstreamParent = r.Header.Get("traceparent")
+        fake.Config.Handler.ServeHTTP(w, r)
+    }))
+    defer up.Close()
+
+    stop := tracing.Configure(tracing.Options{Endpoint: collector.URL})
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithTracing(server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")}))
+    ts := httptest.NewServer(server.Trace(mux))
+    defer ts.Close()
+
+    const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+    body, _ := json.Marshal(map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}})
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("status %d", resp.StatusCode) }
+    if !strings.Contains(resp.Header.Get("traceparent"), traceID) { t.Fatalf("response traceparent %q", resp.Header.Get("traceparent")) }
+    stop()
+
+    mu.Lock()
+    defer mu.Unlock()
+    byName := map[string]span{}
+    for _, s := range spans { byName[s.Name] = s }
+    root, ok := byName["POST /v1/chat/completions"]
+    if !ok { t.Fatalf("no server span in %+v", spans) }
+    if root.TraceID != traceID || root.ParentSpanID != "00f067aa0ba902b7" { t.Fatalf("server span did not continue the trace: %+v", root) }
+    chat, ok := byName["llm.chat"]
+    if !ok { t.Fatalf("no llm.chat span in %+v", spans) }
+    if chat.TraceID != traceID || chat.ParentSpanID != root.SpanID { t.Fatalf("llm.chat not nested under the request: %+v", chat) }
+    if !strings.Contains(upstreamParent, chat.SpanID) { t.Fatalf("upstream traceparent %q does not name llm.chat", upstreamParent) }
+}
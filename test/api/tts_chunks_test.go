@@ -0,0 +1,55 @@
+package api_test
+
+import (
+    "bytes"
+    "encoding/binary"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/services/tts"
+)
+
+func TestTTS_SplitSentences(t *testing.T) {
+    cases := []struct {
+        name, text string
+        max        int
+        want       []string
+    }{
+        {"short text is one chunk", "Hello there. General Kenobi.", 100, []string{"Hello there. General Kenobi."}},
+        {"sentences packed up to the limit", "One two. Three four. Five six.", 20, []string{"One two. Three four.", "Five six."}},
+        {"abbreviations do not end sentences", "Dr. Smith met Mr. Jones. They talked.", 30, []string{"Dr. Smith met Mr. Jones.", "They talked."}},
+        {"initials do not end sentences", "J. R. R. Tolkien wrote it. It is long.", 30, []string{"J. R. R. Tolkien wrote it.", "It is long."}},
+        {"decimals do not end sentences", "Pi is about 3.14159 today. Tomorrow too.", 30, []string{"Pi is about 3.14159 today.", "Tomorrow too."}},
+        {"long sentence cut at a space", "aaaa bbbb cccc dddd", 10, []string{"aaaa bbbb", "cccc dddd"}},
+        {"blank text has no chunks", " \n\t ", 10, nil},
+        {"blank lines make no empty chunks", "First line.\n\n\n   \nSecond line here.", 20, []string{"First line.", "Second line here."}},
+    }
+    for _, c := range cases {
+        got := tts.SplitSentences(c.text, c.max)
+        if strings.Join(got, "|") != strings.Join(c.want, "|") || len(got) != len(c.want) { t.Errorf("%s: got %q, want %q", c.name, got, c.want) }
+        for _, chunk := range got {
+            if strings.TrimSpace(chunk) == "" { t.Errorf("%s: empty chunk in %q", c.name, got) }
+            if n := len([]rune(chunk)); n > c.max { t.Errorf("%s: chunk of %d runes over the limit: %q", c.name, n, chunk) }
+        }
+    }
+}
+
+func TestTTS_StitchWAV(t *testing.T) {
+    format := tts.WAVFormat{AudioFormat: 1, Channels: 1, SampleRate: 16000, BitsPerSample: 16}
+    a, b := bytes.Repeat([]byte{1, 0}, 100), bytes.Repeat([]byte{2, 0}, 50)
+    wav, err := tts.StitchWAV([][]byte{tts.BuildWAV(format, a), tts.BuildWAV(format, b)}, 10)
+    if err != nil { t.Fatal(err) }
+
+    silence := make([]byte, 16000*10/1000*2)
+    want := append(append(append([]byte{}, a...), silence...), b...)
+    if riff := binary.LittleEndian.Uint32(wav[4:8]); int(riff) != len(wav)-8 { t.Fatalf("RIFF size %d, file is %d bytes", riff, len(wav)) }
+    if size := binary.LittleEndian.Uint32(wav[40:44]); int(size) != len(want) { t.Fatalf("data size %d, want %d", size, len(want)) }
+    f, data, err := tts.ParseWAV(wav)
+    if err != nil { t.Fatal(err) }
+    if f != format || !bytes.Equal(data, want) { t.Fatalf("stitched wav is %+v with %d bytes of data, want %+v with %d", f, len(data), format, len(want)) }
+
+    other := format
+    other.SampleRate = 22050
+    if _, err := tts.StitchWAV([][]byte{tts.BuildWAV(format, a), tts.BuildWAV(other, b)}, 10); err == nil || !strings.Contains(err.Error(), "mismatch") { t.Fatalf("sample rate mismatch: got %v", err) }
+    if _, err := tts.StitchWAV(nil, 10); err == nil { t.Fatal("stitching no chunks succeeded") }
+}
@@ -0,0 +1,61 @@
+package api_test
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/config"
+    "gollmcore/internal/services/tts"
+)
+
+func TestTTS_EngineSelection(t *testing.T) {
+    dir := t.TempDir()
+    for name, want := range map[string]string{"": "piper", "piper": "piper", "kokoro": "kokoro"} {
+        e, err := tts.NewEngine(name, filepath.Join(dir, "bin"), filepath.Join(dir, "models"), filepath.Join(dir, "tts"))
+        if err != nil { t.Fatalf("engine %q: %v", name, err) }
+        got := ""
+        switch e.(type) {
+        case *tts.Service:
+            got = "piper"
+        case *tts.Kokoro:
+            got = "kokoro"
+        }
+        if got != want { t.Fatalf("engine %q: got %T, want %s", name, e, want) }
+    }
+    if _, err := tts.NewEngine("festival", dir, dir, dir); err == nil || !strings.Contains(err.Error(), "unknown tts engine") { t.Fatalf("unknown engine: got %v", err) }
+}
+
+func TestTTS_EngineConfig(t *testing.T) {
+    load := func(body string) (config.Config, error) {
+        t.Helper()
+        path := filepath.Join(t.TempDir(), "config.json")
+        if err := os.WriteFile(path, []byte(body), 0o644); err != nil { t.Fatal(err) }
+        return config.Load(path)
+    }
+    for _, c := range []struct{ body, engine, voice string }{
+        {`{}`, "piper", "en_US-amy-medium"},
+        {`{"services": {"tts": {"engine": "kokoro"}}}`, "kokoro", "af_heart"},
+        {`{"services": {"tts": {"engine": "kokoro", "voice": "bf_emma"}}}`, "kokoro", "bf_emma"},
+    } {
+        cfg, err := load(c.body)
+        if err != nil { t.Fatalf("%s: %v", c.body, err) }
+        if tc := cfg.Services.TTS; tc.Engine != c.engine || tc.Voice != c.voice { t.Fatalf("%s: engine %q voice %q, want %q %q", c.body, tc.Engine, tc.Voice, c.engine, c.voice) }
+    }
+    if _, err := load(`{"services": {"tts": {"engine": "festival"}}}`); err == nil || !strings.Contains(err.Error(), "services.tts.engine") { t.Fatalf("unknown engine accepted: %v", err) }
+}
+
+func TestTTS_KokoroErrors(t *testing.T) {
+    // A models dir that cannot be created stands in for a missing one that
+    // cannot be filled; nothing is downloaded before it is checked.
+    file := filepath.Join(t.TempDir(), "models")
+    if err := os.WriteFile(file, nil, 0o644); err != nil { t.Fatal(err) }
+    k := tts.NewKokoro(t.TempDir(), filepath.Join(file, "kokoro"))
+    ctx := context.Background()
+
+    if _, err := k.Synthesize(ctx, "Hello there.", ""); err == nil || !strings.Contains(err.Error(), "kokoro model dir") { t.Fatalf("missing model dir: got %v", err) }
+    if _, err := k.Synthesize(ctx, "Hello there.", "../../etc/passwd"); err == nil || !strings.Contains(err.Error(), "unsupported voice") { t.Fatalf("bad voice: got %v", err) }
+    if _, err := k.Synthesize(ctx, "  ", "af_heart"); err == nil || !strings.Contains(err.Error(), "empty text") { t.Fatalf("blank text: got %v", err) }
+}
@@ -0,0 +1,183 @@
+package api_test
+
+import (
+    "bytes"
+    "context"
+    "encoding/binary"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+)
+
+// fakeTTS returns a short 16 kHz mono PCM16 WAV for any text.
+type fakeTTS struct{}
+
+func (fakeTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    pcm := make([]byte, 320)
+    var buf bytes.Buffer
+    buf.WriteString("RIFF")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+    buf.WriteString("WAVEfmt ")
+    for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(16000), uint32(32000), uint16(2), uint16(16)} {
+        _ = binary.Write(&buf, binary.LittleEndian, v)
+    }
+    buf.WriteString("data")
+    _ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+    buf.Write(pcm)
+    return buf.Bytes(), nil
+}
+
+func TestTTSWebSocket_BinaryFrames(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterWSRoutes(mux, server.Dependencies{TTS: fakeTTS{}}, server.WSOptions{Enable: true})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/tts", nil)
+    if err != nil { t.Fatalf("dial failed: %v", err) }
+    defer conn.Close()
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+    for _, tc := range []struct{ format string; size int }{{"", 364}, {"pcm16", 320}} {
+        _ = conn.WriteJSON(map[string]any{"text": "hi", "binary": true, "format": tc.format})
+        var header map[string]any
+        if err := conn.ReadJSON(&header); err != nil { t.Fatalf("header read failed: %v", err) }
+        if header["type"] != "audio" || header["sample_rate"] != float64(16000) || header["bytes"] != float64(tc.size) { t.Fatalf("unexpected header %v", header) }
+        mt, data, err := conn.ReadMessage()
+        if err != nil { t.Fatalf("audio read failed: %v", err) }
+        if mt != websocket.BinaryMessage || len(data) != tc.size { t.Fatalf("expected %d-byte binary frame, got type %d len %d", tc.size, mt, len(data)) }
+    }
+}
+
+// slowTTS counts calls and takes long enough for concurrent requests to overlap.
+type slowTTS struct{ calls atomic.Int32 }
+
+func (s *slowTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    s.calls.Add(1)
+    time.Sleep(200 * time.Millisecond)
+    return fakeTTS{}.Synthesize(ctx, text, voice)
+}
+
+func TestTTS_CoalescesIdenticalConcurrentRequests(t *testing.T) {
+    tts := &slowTTS{}
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithCoalescing(server.Dependencies{TTS: tts}))
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 5; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"same text","voice":"v1"}`))
+            if err != nil { t.Errorf("request failed: %v", err); return }
+            b, _ := io.ReadAll(resp.Body)
+            resp.Body.Close()
+            if resp.StatusCode != http.StatusOK || len(b) == 0 { t.Errorf("unexpected response %d (%d bytes)", resp.StatusCode, len(b)) }
+        }()
+    }
+    wg.Wait()
+    if n := tts.calls.Load(); n != 1 { t.Fatalf("expected 1 synthesis, got %d", n) }
+
+    resp, err := http.Get(ts.URL + "/metrics")
+    if err != nil { t.Fatalf("metrics request failed: %v", err) }
+    b, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if !strings.Contains(string(b), `gollmcore_coalesced_requests_total{kind="tts"}`) { t.Fatalf("coalesced counter missing:\n%s", b) }
+}
+
+// heldEmbeddings counts calls and holds each one until release is closed.
+type heldEmbeddings struct {
+    calls   atomic.Int32
+    started chan struct{}
+    release chan struct{}
+}
+
+func (h *heldEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    if h.calls.Add(1) == 1 { close(h.started) }
+    <-h.release
+    vecs := make([][]float32, len(inputs))
+    for i := range vecs { vecs[i] = []float32{1, 2, 3} }
+    return vecs, "held-model", nil
+}
+
+func TestCoalescing_CallersGetTheirOwnResults(t *testing.T) {
+    emb := &heldEmbeddings{started: make(chan struct{}), release: make(chan struct{})}
+    d := server.WithCoalescing(server.Dependencies{Embeddings: emb, TTS: &slowTTS{}})
+
+    results := make([][][]float32, 2)
+    var wg sync.WaitGroup
+    embed := func(i int) {
+        defer wg.Done()
+        vecs, _, err := d.Embeddings.Embed(context.Background(), []string{"same"})
+        if err != nil { t.Error(err) }
+        results[i] = vecs
+    }
+    wg.Add(2)
+    go embed(0)
+    <-emb.started
+    go embed(1)
+    time.Sleep(50 * time.Millisecond) // let the second call join the first
+    close(emb.release)
+    wg.Wait()
+    if n := emb.calls.Load(); n != 1 { t.Fatalf("expected 1 computation, got %d", n) }
+
+    results[0][0][0] = 42
+    results[0][0] = results[0][0][:1]
+    if v := results[1][0]; len(v) != 3 || v[0] != 1 { t.Fatalf("one caller's changes reached another: %v", v) }
+
+    audio := make([][]byte, 2)
+    wg.Add(2)
+    for i := range audio {
+        go func() {
+            defer wg.Done()
+            b, err := d.TTS.Synthesize(context.Background(), "same text", "v1")
+            if err != nil { t.Error(err) }
+            audio[i] = b
+        }()
+    }
+    wg.Wait()
+    want := bytes.Clone(audio[1])
+    audio[0][len(audio[0])-1] ^= 0xff
+    if !bytes.Equal(audio[1], want) { t.Fatal("one caller's changes reached another's audio") }
+}
+
+// panickyTTS panics on its first call.
+type panickyTTS struct{ calls atomic.Int32 }
+
+func (p *panickyTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    if p.calls.Add(1) == 1 { panic("synthesis blew up") }
+    return fakeTTS{}.Synthesize(ctx, text, voice)
+}
+
+func TestTTS_CoalescingSurvivesPanic(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithCoalescing(server.Dependencies{TTS: &panickyTTS{}}))
+    ts := httptest.NewServer(server.Recover(mux))
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/tts", map[string]any{"text": "same text"})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusInternalServerError { t.Fatalf("panicking synthesis: got %d, want 500", resp.StatusCode) }
+
+    // The panicked flight must not keep later identical requests waiting.
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/v1/tts", strings.NewReader(`{"text":"same text"}`))
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("identical request after a panic: %v", err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("identical request after a panic: got %d", resp.StatusCode) }
+}
+
@@ -0,0 +1,64 @@
+package api_test
+
+import (
+    "bytes"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/stt"
+)
+
+func postUpload(t *testing.T, url, filename string, content []byte) *http.Response {
+    t.Helper()
+    body := &bytes.Buffer{}
+    mw := multipart.NewWriter(body)
+    w, _ := mw.CreateFormFile("file", filename)
+    _, _ = w.Write(content)
+    mw.Close()
+    req, _ := http.NewRequest(http.MethodPost, url, body)
+    req.Header.Set("Content-Type", mw.FormDataContentType())
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatalf("request failed: %v", err) }
+    return resp
+}
+
+func TestUploadSniff_RejectsNonAudio(t *testing.T) {
+    dataDir := t.TempDir()
+    svc := stt.New(filepath.Join(dataDir, "bin"), filepath.Join(dataDir, "models", "whisper"))
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        STT:             svc,
+        STTDefaultModel: "tiny",
+        Uploads:         server.UploadPolicy{Sniff: true},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postUpload(t, ts.URL+"/v1/audio/transcriptions", "notes.wav", []byte("just some text, not audio"))
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusUnsupportedMediaType {
+        t.Fatalf("expected 415, got %d", resp.StatusCode)
+    }
+
+    mp3 := append([]byte("ID3"), make([]byte, 64)...)
+    resp2 := postUpload(t, ts.URL+"/v1/audio/transcriptions/stream", "clip.wav", mp3)
+    defer resp2.Body.Close()
+    if resp2.StatusCode != http.StatusUnsupportedMediaType {
+        t.Fatalf("expected 415 for extension mismatch, got %d", resp2.StatusCode)
+    }
+}
+
+func TestUpload_RejectsUnknownExtension(t *testing.T) {
+    dataDir := t.TempDir()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: stt.New(filepath.Join(dataDir, "bin"), dataDir), STTDefaultModel: "tiny"})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postUpload(t, ts.URL+"/v1/audio/transcriptions", "clip.exe", []byte("MZ"))
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusUnsupportedMediaType || e.Error.Code != "upload_rejected" { t.Fatalf("expected 415 upload_rejected, got %d %+v", resp.StatusCode, e.Error) }
+}
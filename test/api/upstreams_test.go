@@ -0,0 +1,66 @@
+package api_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestUpstreams_RouteByModelAndBalance(t *testing.T) {
+    main, a, b := newSpyLLM(t, "main"), newSpyLLM(t, "a"), newSpyLLM(t, "b")
+    defer main.Close()
+    defer a.Close()
+    defer b.Close()
+    d := server.WithUpstreams(server.Dependencies{
+        LLM: llm.New(main.URL+"/v1", "main-model", ""),
+        LLMUpstreams: []server.LLMUpstream{
+            {Name: "a", Models: []string{"qwen", "phi"}, LLM: llm.New(a.URL+"/v1", "qwen", "")},
+            {Name: "b", Models: []string{"qwen"}, LLM: llm.New(b.URL+"/v1", "qwen", "")},
+        },
+    })
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, d)
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    chat := func(model string) {
+        body := map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}}
+        if model != "" { body["model"] = model }
+        resp := postJSON(t, ts.URL+"/v1/chat/completions", body)
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusOK { t.Fatalf("%s: status %d", model, resp.StatusCode) }
+    }
+    for i := 0; i < 4; i++ { chat("qwen") }
+    chat("phi")
+    chat("")
+    chat("llama")
+
+    if n := len(b.requests()); n != 2 { t.Fatalf("upstream b got %d qwen chats, want 2 of 4", n) }
+    got := map[string]int{}
+    for _, r := range a.requests() { got[r.Model]++ }
+    if got["qwen"] != 2 || got["phi"] != 1 { t.Fatalf("upstream a got %v", got) }
+    reqs := main.requests()
+    if len(reqs) != 2 || reqs[0].Model != "main-model" || reqs[1].Model != "llama" { t.Fatalf("main upstream got %+v", reqs) }
+
+    resp, err := http.Get(ts.URL + "/v1/models")
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    var list struct {
+        Object string `json:"object"`
+        Data   []struct {
+            ID        string `json:"id"`
+            OwnedBy   string `json:"owned_by"`
+            Upstreams int    `json:"upstreams"`
+        } `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&list); err != nil { t.Fatal(err) }
+    if list.Object != "list" || len(list.Data) != 3 { t.Fatalf("unexpected model list %+v", list) }
+    want := map[string]int{"main-model": 1, "phi": 1, "qwen": 2}
+    for _, m := range list.Data {
+        if want[m.ID] != m.Upstreams { t.Fatalf("%s: %d upstreams, want %d (%+v)", m.ID, m.Upstreams, want[m.ID], list) }
+    }
+}
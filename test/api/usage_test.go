@@ -0,0 +1,145 @@
+package api_test
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/embeddings"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/usage"
+)
+
+func TestUsage_RecordsPerModelStatsAndPersists(t *testing.T) {
+    upstream := newFakeLLM(t, "hello there")
+    defer upstream.Close()
+    path := filepath.Join(t.TempDir(), "usage.json")
+    rec, err := usage.Open(path, 0)
+    if err != nil { t.Fatalf("open: %v", err) }
+
+    d := server.WithUsage(server.Dependencies{LLM: llm.New(upstream.URL+"/v1", "test-model", ""), TTS: fakeTTS{}, Usage: rec})
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, d)
+    server.RegisterAdminRoutes(mux, server.AdminOptions{Usage: rec})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    for i := 0; i < 2; i++ {
+        resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+        if err != nil { t.Fatalf("chat: %v", err) }
+        resp.Body.Close()
+    }
+    resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text":"hello","voice":"amy"}`))
+    if err != nil { t.Fatalf("tts: %v", err) }
+    resp.Body.Close()
+
+    resp, err = http.Get(ts.URL + "/admin/usage?service=llm")
+    if err != nil { t.Fatalf("usage: %v", err) }
+    defer resp.Body.Close()
+    var out struct {
+        Models []usage.Summary `json:"models"`
+        Hourly []usage.Bucket  `json:"hourly"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { t.Fatalf("decode: %v", err) }
+    if len(out.Models) != 1 || out.Models[0].Model != "test-model" || out.Models[0].Calls != 2 { t.Fatalf("unexpected summary: %+v", out.Models) }
+    if out.Models[0].PeakConcurrency < 1 || out.Models[0].AvgPromptTokens <= 0 { t.Fatalf("missing stats: %+v", out.Models[0]) }
+    if len(out.Hourly) != 1 { t.Fatalf("expected one hourly bucket, got %d", len(out.Hourly)) }
+
+    if err := rec.Flush(); err != nil { t.Fatalf("flush: %v", err) }
+    reopened, err := usage.Open(path, 0)
+    if err != nil { t.Fatalf("reopen: %v", err) }
+    summary, _ := reopened.Query(usage.Filter{Service: "tts"})
+    if len(summary) != 1 || summary[0].Model != "amy" || summary[0].Calls != 1 { t.Fatalf("tts stats not persisted: %+v", summary) }
+}
+
+func TestUsage_EmbeddingsTokensPerKey(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "usage.json")
+    rec, err := usage.Open(path, 0)
+    if err != nil { t.Fatalf("open: %v", err) }
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.WithUsage(server.Dependencies{Embeddings: embeddings.New(embeddings.Config{}), Usage: rec}))
+    server.RegisterAdminRoutes(mux, server.AdminOptions{Usage: rec})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    embed := func(key, input string) int {
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/embeddings", strings.NewReader(`{"input":["`+input+`","`+input+`"]}`))
+        if key != "" { req.Header.Set("Authorization", "Bearer "+key) }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatalf("embed: %v", err) }
+        defer resp.Body.Close()
+        var out struct {
+            Usage struct {
+                PromptTokens int `json:"prompt_tokens"`
+                TotalTokens  int `json:"total_tokens"`
+            } `json:"usage"`
+        }
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        if out.Usage.PromptTokens <= 0 || out.Usage.TotalTokens != out.Usage.PromptTokens { t.Fatalf("unexpected usage %+v", out.Usage) }
+        return out.Usage.TotalTokens
+    }
+    a := embed("key-a", "the quick brown fox") + embed("key-a", "jumps")
+    b := embed("key-b", "over the lazy dog")
+    embed("", "hello")
+
+    resp, err := http.Get(ts.URL + "/admin/usage?service=embeddings")
+    if err != nil { t.Fatalf("usage: %v", err) }
+    var out struct{ Keys []usage.KeyTotal `json:"keys"` }
+    _ = json.NewDecoder(resp.Body).Decode(&out)
+    resp.Body.Close()
+    totals := map[string]usage.KeyTotal{}
+    for _, k := range out.Keys { totals[k.Key] = k }
+    if len(totals) != 3 || totals["anonymous"].Requests != 1 { t.Fatalf("unexpected key totals %+v", out.Keys) }
+    for _, k := range out.Keys {
+        if strings.Contains(k.Key, "key-") { t.Fatalf("raw key stored: %+v", k) }
+        if k.Requests == 2 && k.Tokens != int64(a) { t.Fatalf("key a: got %d tokens, want %d", k.Tokens, a) }
+        if k.Key != "anonymous" && k.Requests == 1 && k.Tokens != int64(b) { t.Fatalf("key b: got %d tokens, want %d", k.Tokens, b) }
+    }
+
+    if err := rec.Flush(); err != nil { t.Fatalf("flush: %v", err) }
+    reopened, err := usage.Open(path, 0)
+    if err != nil { t.Fatalf("reopen: %v", err) }
+    if keys := reopened.Keys("embeddings"); len(keys) != 3 || keys[0].Tokens != int64(max(a, b)) { t.Fatalf("key totals not persisted: %+v", keys) }
+}
+
+// gatedEmbeddings blocks every call until release is closed.
+type gatedEmbeddings struct {
+    started chan struct{}
+    release chan struct{}
+}
+
+func (g *gatedEmbeddings) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+    g.started <- struct{}{}
+    <-g.release
+    return make([][]float32, len(inputs)), "gated-model", nil
+}
+
+func TestUsage_EmbeddingsPeakConcurrency(t *testing.T) {
+    rec, err := usage.Open("", 0)
+    if err != nil { t.Fatalf("open: %v", err) }
+    emb := &gatedEmbeddings{started: make(chan struct{}), release: make(chan struct{})}
+    d := server.WithUsage(server.Dependencies{Embeddings: emb, Usage: rec})
+
+    var wg sync.WaitGroup
+    for i := 0; i < 3; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if _, _, err := d.Embeddings.Embed(context.Background(), []string{"hi"}); err != nil { t.Error(err) }
+        }()
+    }
+    for i := 0; i < 3; i++ { <-emb.started }
+    close(emb.release)
+    wg.Wait()
+
+    summary, _ := rec.Query(usage.Filter{Service: "embeddings"})
+    if len(summary) != 1 || summary[0].Model != "gated-model" || summary[0].Calls != 3 { t.Fatalf("unexpected summary: %+v", summary) }
+    if summary[0].PeakConcurrency != 3 { t.Fatalf("peak_concurrency = %d, want 3", summary[0].PeakConcurrency) }
+}
+
@@ -0,0 +1,67 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func TestValidation_FieldErrors(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{TTS: fakeTTS{}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    for _, tc := range []struct {
+        body, code, param string
+        status            int
+    }{
+        {`{"text": "hi", "speed": 2}`, "unknown_field", "speed", http.StatusUnprocessableEntity},
+        {`{"text": 42}`, "invalid_type", "text", http.StatusUnprocessableEntity},
+        {`{"voice": "amy"}`, "missing_field", "text", http.StatusUnprocessableEntity},
+        {`["hi"]`, "invalid_type", "", http.StatusUnprocessableEntity},
+        {`{"text": "hi"`, "invalid_json", "", http.StatusBadRequest},
+        {``, "invalid_json", "", http.StatusBadRequest},
+    } {
+        resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(tc.body))
+        if err != nil { t.Fatal(err) }
+        e := decodeError(t, resp)
+        param := ""
+        if e.Error.Param != nil { param = *e.Error.Param }
+        if resp.StatusCode != tc.status || e.Error.Code != tc.code || param != tc.param { t.Fatalf("%s: expected %d %s on %q, got %d %+v", tc.body, tc.status, tc.code, tc.param, resp.StatusCode, e.Error) }
+    }
+}
+
+func TestValidation_BodyTooLarge(t *testing.T) {
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{TTS: fakeTTS{}, MaxJSONBytes: 1024})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text": "`+strings.Repeat("a", 2048)+`"}`))
+    if err != nil { t.Fatal(err) }
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusRequestEntityTooLarge || e.Error.Code != "payload_too_large" { t.Fatalf("expected 413 payload_too_large, got %d %+v", resp.StatusCode, e.Error) }
+
+    resp, err = http.Post(ts.URL+"/v1/tts", "application/json", strings.NewReader(`{"text": "short"}`))
+    if err != nil { t.Fatal(err) }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("body under the limit: got %d", resp.StatusCode) }
+}
+
+func TestValidation_CompatEndpointsIgnoreUnknownFields(t *testing.T) {
+    spy := newSpyLLM(t, "hello")
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(spy.URL+"/v1", "test-model", "")})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}, "user": "u-1", "logit_bias": map[string]int{}})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected unknown OpenAI options to be ignored, got %d", resp.StatusCode) }
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": "hi"})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusUnprocessableEntity || e.Error.Code != "invalid_type" || e.Error.Param == nil || *e.Error.Param != "messages" { t.Fatalf("expected invalid_type on messages, got %d %+v", resp.StatusCode, e.Error) }
+}
@@ -0,0 +1,54 @@
+package api_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+func imageMessage(url string) []map[string]any {
+    return []map[string]any{{"role": "user", "content": []map[string]any{
+        {"type": "text", "text": "What is in this picture?"},
+        {"type": "image_url", "image_url": map[string]string{"url": url}},
+    }}}
+}
+
+func TestVision_ImagePartsRoutedToVisionModel(t *testing.T) {
+    text := newSpyLLM(t, "text reply")
+    defer text.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(text.URL+"/v1", "test-model", ""), Vision: server.VisionRoute{Model: "llava"}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": imageMessage("data:image/png;base64,iVBORw0KGgo=")})
+    resp.Body.Close()
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": []map[string]string{{"role": "user", "content": "hi"}}})
+    resp.Body.Close()
+    reqs := text.requests()
+    if len(reqs) != 2 || reqs[0].Model != "llava" || reqs[1].Model != "test-model" { t.Fatalf("unexpected routing %+v", reqs) }
+    m := reqs[0].Messages[0]
+    if !m.HasImages() || len(m.Parts) != 2 || m.Parts[1].ImageURL.URL != "data:image/png;base64,iVBORw0KGgo=" || m.Content != "What is in this picture?" { t.Fatalf("image part not forwarded: %+v", m) }
+
+    resp = postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": imageMessage("file:///etc/passwd")})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "messages" { t.Fatalf("expected a messages error, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+func TestVision_SeparateBackend(t *testing.T) {
+    text, vision := newSpyLLM(t, "text"), newSpyLLM(t, "a cat")
+    defer text.Close()
+    defer vision.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:    llm.New(text.URL+"/v1", "test-model", ""),
+        Vision: server.VisionRoute{Model: "qwen2-vl", LLM: llm.New(vision.URL+"/v1", "qwen2-vl", "")},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+    resp := postJSON(t, ts.URL+"/v1/chat/completions", map[string]any{"messages": imageMessage("https://example.com/cat.jpg")})
+    resp.Body.Close()
+    if len(text.requests()) != 0 || len(vision.requests()) != 1 { t.Fatalf("image request not sent to the vision backend: text=%d vision=%d", len(text.requests()), len(vision.requests())) }
+}
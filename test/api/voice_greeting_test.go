@@ -0,0 +1,50 @@
+package api_test
+
+import (
+    "context"
+    "encoding/base64"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+)
+
+// countingTTS counts syntheses of each text.
+type countingTTS struct{ greetings atomic.Int32 }
+
+func (c *countingTTS) Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+    if text == "Hi, how can I help?" { c.greetings.Add(1) }
+    return fakeTTS{}.Synthesize(ctx, text, voice)
+}
+
+func TestVoiceWebSocket_GreetingSynthesizedOnce(t *testing.T) {
+    up := newFakeLLM(t, "hello")
+    tts := &countingTTS{}
+    mux := http.NewServeMux()
+    deps := server.Dependencies{STT: stt.NewMock(), STTDefaultModel: "base", LLM: llm.New(up.URL+"/v1", "test-model", ""), TTS: tts, VoiceGreeting: "Hi, how can I help?"}
+    server.RegisterWSRoutes(mux, deps, server.WSOptions{Enable: true})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    want, _ := fakeTTS{}.Synthesize(context.Background(), "", "")
+    for i := 0; i < 3; i++ {
+        conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/voice", nil)
+        if err != nil { t.Fatalf("dial failed: %v", err) }
+        _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+        var msg map[string]any
+        if err := conn.ReadJSON(&msg); err != nil { t.Fatalf("session %d: no greeting: %v", i, err) }
+        conn.Close()
+        if msg["event"] != "greeting" || msg["reply"] != "Hi, how can I help?" { t.Fatalf("session %d: unexpected first message %v", i, msg) }
+        audio, _ := base64.StdEncoding.DecodeString(msg["audio_base64"].(string))
+        if string(audio) != string(want) { t.Fatalf("session %d: greeting audio %q, want %q", i, audio, want) }
+    }
+    if n := tts.greetings.Load(); n != 1 { t.Fatalf("greeting synthesized %d times, want 1", n) }
+}
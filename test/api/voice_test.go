@@ -0,0 +1,77 @@
+package api_test
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "io"
+    "mime"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+    "gollmcore/internal/services/stt"
+)
+
+func TestVoiceChat_MultipartResponse(t *testing.T) {
+    up := newFakeLLM(t, "Sure, here you go.")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{STT: stt.NewMock(), STTDefaultModel: "base", LLM: llm.New(up.URL+"/v1", "test-model", ""), TTS: fakeTTS{}})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    clip, _ := fakeTTS{}.Synthesize(context.Background(), "question", "")
+    body := &bytes.Buffer{}
+    mw := multipart.NewWriter(body)
+    fw, _ := mw.CreateFormFile("file", "clip.wav")
+    _, _ = fw.Write(clip)
+    mw.Close()
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/voice/chat", body)
+    req.Header.Set("Content-Type", mw.FormDataContentType())
+    req.Header.Set("Accept", "multipart/mixed")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { t.Fatalf("expected 200, got %d", resp.StatusCode) }
+
+    mt, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+    if err != nil || mt != "multipart/mixed" || params["boundary"] == "" { t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type")) }
+    mr := multipart.NewReader(resp.Body, params["boundary"])
+    // disposition returns a part's inline disposition parameters.
+    disposition := func(p *multipart.Part) map[string]string {
+        d, params, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+        if d != "inline" { return nil }
+        return params
+    }
+
+    part, err := mr.NextPart()
+    if err != nil { t.Fatalf("metadata part: %v", err) }
+    if ct, d := part.Header.Get("Content-Type"), disposition(part); ct != "application/json" || d["name"] != "metadata" { t.Fatalf("first part is %q %v, want application/json metadata", ct, d) }
+    var meta struct {
+        Transcript  string `json:"transcript"`
+        Reply       string `json:"reply"`
+        Audio       struct {
+            Mime   string `json:"mime"`
+            Bytes  int    `json:"bytes"`
+            Base64 string `json:"base64"`
+        } `json:"audio"`
+        AudioBase64 string `json:"audio_base64"`
+    }
+    if err := json.NewDecoder(part).Decode(&meta); err != nil { t.Fatalf("metadata: %v", err) }
+
+    part, err = mr.NextPart()
+    if err != nil { t.Fatalf("audio part: %v", err) }
+    if ct, d := part.Header.Get("Content-Type"), disposition(part); ct != "audio/wav" || d["name"] != "audio" || d["filename"] != "reply.wav" { t.Fatalf("second part is %q %v, want audio/wav audio", ct, d) }
+    audio, _ := io.ReadAll(part)
+    if _, err := mr.NextPart(); err != io.EOF { t.Fatalf("expected two parts, next part: %v", err) }
+
+    want, _ := fakeTTS{}.Synthesize(context.Background(), meta.Reply, "")
+    if meta.Transcript == "" || meta.Reply != "Sure, here you go." { t.Fatalf("unexpected metadata %+v", meta) }
+    if meta.Audio.Mime != "audio/wav" || meta.Audio.Bytes != len(audio) { t.Fatalf("metadata describes %s of %d bytes, audio part has %d", meta.Audio.Mime, meta.Audio.Bytes, len(audio)) }
+    if meta.Audio.Base64 != "" || meta.AudioBase64 != "" { t.Fatal("multipart metadata carries inline audio") }
+    if !bytes.Equal(audio, want) { t.Fatalf("audio part is not the synthesized reply") }
+}
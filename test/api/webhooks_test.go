@@ -0,0 +1,126 @@
+package api_test
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "gollmcore/internal/server"
+    "gollmcore/internal/services/llm"
+)
+
+type webhookCall struct {
+    path   string
+    header http.Header
+    body   []byte
+}
+
+func TestWebhooks_SignedCallbackWhenJobFinishes(t *testing.T) {
+    calls := make(chan webhookCall, 4)
+    receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        b, _ := io.ReadAll(r.Body)
+        calls <- webhookCall{path: r.URL.Path, header: r.Header, body: b}
+    }))
+    defer receiver.Close()
+    up := newFakeLLM(t, "ok")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:      llm.New(up.URL+"/v1", "test-model", ""),
+        Webhooks: server.Webhooks{URLs: []string{receiver.URL + "/all"}, Secret: "s3cret", PerJob: true, AllowPrivate: true},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": batchItems("1", "2"), "async": true, "webhook_url": receiver.URL + "/job"})
+    var job server.Job
+    _ = json.NewDecoder(resp.Body).Decode(&job)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted || job.WebhookURL != receiver.URL+"/job" { t.Fatalf("unexpected job %d %+v", resp.StatusCode, job) }
+
+    paths := map[string]bool{}
+    for i := 0; i < 2; i++ {
+        var c webhookCall
+        select {
+        case c = <-calls:
+        case <-time.After(5 * time.Second):
+            t.Fatalf("webhook not delivered, got %v", paths)
+        }
+        paths[c.path] = true
+        mac := hmac.New(sha256.New, []byte("s3cret"))
+        mac.Write([]byte(c.header.Get("X-Gollmcore-Timestamp") + "."))
+        mac.Write(c.body)
+        if got, want := c.header.Get("X-Gollmcore-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want { t.Fatalf("bad signature %q, want %q", got, want) }
+        var ev struct {
+            Type string     `json:"type"`
+            Job  server.Job `json:"job"`
+        }
+        if err := json.Unmarshal(c.body, &ev); err != nil { t.Fatal(err) }
+        if ev.Type != "job.succeeded" || ev.Job.ID != job.ID || ev.Job.Status != "succeeded" || c.header.Get("X-Gollmcore-Event") != "job.succeeded" { t.Fatalf("unexpected delivery %s", c.body) }
+    }
+    if !paths["/all"] || !paths["/job"] { t.Fatalf("expected the global and the per-job webhook, got %v", paths) }
+}
+
+func TestWebhooks_PerJobURLNeedsOptIn(t *testing.T) {
+    up := newFakeLLM(t, "ok")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{LLM: llm.New(up.URL+"/v1", "test-model", "")})
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": batchItems("1"), "async": true, "webhook_url": "http://127.0.0.1:1/hook"})
+    if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "webhook_url" { t.Fatalf("expected 400 on webhook_url, got %d %+v", resp.StatusCode, e.Error) }
+}
+
+func TestWebhooks_PerJobURLMayNotReachPrivateAddresses(t *testing.T) {
+    calls := make(chan string, 4)
+    receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls <- r.URL.Path }))
+    defer receiver.Close()
+    up := newFakeLLM(t, "ok")
+    defer up.Close()
+    mux := http.NewServeMux()
+    server.RegisterRoutes(mux, server.Dependencies{
+        LLM:      llm.New(up.URL+"/v1", "test-model", ""),
+        Webhooks: server.Webhooks{URLs: []string{receiver.URL + "/all"}, PerJob: true},
+    })
+    ts := httptest.NewServer(mux)
+    defer ts.Close()
+
+    for _, target := range []string{receiver.URL + "/job", "http://[::1]:1/job", "http://10.0.0.1/job", "http://169.254.169.254/latest/meta-data",
+        "http://100.64.0.1/job",          // carrier-grade NAT
+        "http://[::ffff:127.0.0.1]/job",  // IPv4-mapped
+        "http://[::ffff:a00:1]/job",
+        "http://[64:ff9b::a9fe:a9fe]/job", // NAT64 of 169.254.169.254
+        "http://[2002:a00:1::1]/job",     // 6to4 of 10.0.0.1
+        "http://[2001:0:4136:e378::1]/job", // Teredo
+        "http://198.18.0.1/job"} {
+        resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": batchItems("1"), "async": true, "webhook_url": target})
+        if e := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || e.Error.Param == nil || *e.Error.Param != "webhook_url" { t.Fatalf("%s: expected 400 on webhook_url, got %d %+v", target, resp.StatusCode, e.Error) }
+    }
+
+    // A name is checked after resolution: localhost passes the request
+    // check but is never dialled.
+    byName := "http://localhost:" + receiver.URL[strings.LastIndex(receiver.URL, ":")+1:] + "/job"
+    resp := postJSON(t, ts.URL+"/v1/chat/completions/batch", map[string]any{"requests": batchItems("1"), "async": true, "webhook_url": byName})
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted { t.Fatalf("expected 202, got %d", resp.StatusCode) }
+    select {
+    case p := <-calls:
+        if p != "/all" { t.Fatalf("per-job webhook reached a loopback address: %s", p) }
+    case <-time.After(5 * time.Second):
+        t.Fatal("global webhook not delivered")
+    }
+    select {
+    case p := <-calls:
+        t.Fatalf("per-job webhook reached a loopback address: %s", p)
+    case <-time.After(300 * time.Millisecond):
+    }
+}